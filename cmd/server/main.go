@@ -2,13 +2,22 @@ package main
 
 import (
 	"log"
+	"net/http"
+	"time"
 
 	"dalabio/internal/framework/driver/db"
+	"dalabio/internal/framework/job"
+	"dalabio/internal/framework/mail"
+	"dalabio/internal/framework/oidc"
+	"dalabio/internal/framework/payment"
+	"dalabio/internal/framework/realtime"
 	"dalabio/internal/interface_adapter/controller"
 	"dalabio/internal/interface_adapter/gateway"
 	"dalabio/internal/interface_adapter/routes"
 	"dalabio/internal/service"
 	"dalabio/pkg/config"
+	"dalabio/pkg/jwtauth"
+	"dalabio/pkg/middleware"
 
 	"github.com/gin-contrib/cors" // Import CORS package
 	"github.com/gin-gonic/gin"
@@ -26,11 +35,23 @@ func init() {
 func main() {
 	// Load the database configuration from environment variables or .env
 	dbConfig := config.LoadDBConfig()
+	invoiceConfig := config.LoadInvoiceConfig()
+	payoutConfig := config.LoadPayoutConfig()
+	mailConfig := config.LoadMailConfig()
+	authConfig := config.LoadAuthConfig()
+	oidcProviderConfigs := config.LoadOIDCProviders()
+	serverConfig := config.LoadServerConfig()
 
 	// Debug: Print the loaded database configuration
 	log.Printf("DB Config: Host=%s, Port=%s, User=%s, Password=%s, DBName=%s, SSLMode=%s",
 		dbConfig.Host, dbConfig.Port, dbConfig.User, dbConfig.Password, dbConfig.DBName, dbConfig.SSLMode)
 
+	// Emailed links are signed and two-factor secrets encrypted with this secret; a random one would break the links
+	// and lock every user with two-factor authentication out of their account when the server restarts
+	if authConfig.TokenSecret == "" {
+		log.Fatal("AUTH_TOKEN_SECRET is not set")
+	}
+
 	// Connect to the database
 	database, err := db.ConnectDB(dbConfig)
 	if err != nil {
@@ -46,46 +67,231 @@ func main() {
 	// Initialize the repositories
 	userRepository := gateway.NewUserRepository(database)
 	tokenRepository := gateway.NewTokenRepository(database)
+	// In JWT mode the middlewares verify access tokens by their signature and the table holds the refresh tokens.
+	// API keys are accepted in both modes.
+	var signingKeys *jwtauth.KeySet
+	accessTokenRepository := tokenRepository
+	if authConfig.TokenMode == config.TokenModeJWT {
+		signingKeys = loadSigningKeys(authConfig)
+		accessTokenRepository = gateway.NewSignedTokenRepository(tokenRepository, signingKeys)
+	}
+	apiKeyRepository := gateway.NewAPIKeyRepository(database)
+	accessTokenRepository = gateway.NewAPIKeyTokenRepository(accessTokenRepository, apiKeyRepository)
+	roleRepository := gateway.NewRoleRepository(database)
 	courseRepository := gateway.NewCourseRepository(database)
 	SpaceRepository := gateway.NewSpaceRepository(database)
+	spaceMemberRepository := gateway.NewSpaceMemberRepository(database)
 	meetingRepository := gateway.NewMeetingRepository(database)
 	paymentRepository := gateway.NewPaymentRepository(database)
+	refundRepository := gateway.NewRefundRepository(database)
+	orderRepository := gateway.NewOrderRepository(database)
+	enrollmentRepository := gateway.NewEnrollmentRepository(database)
+	planRepository := gateway.NewPlanRepository(database)
+	subscriptionRepository := gateway.NewSubscriptionRepository(database)
+	couponRepository := gateway.NewCouponRepository(database)
+	invoiceRepository := gateway.NewInvoiceRepository(database)
+	ledgerRepository := gateway.NewLedgerRepository(database)
+	payoutRepository := gateway.NewPayoutRepository(database)
+	reconciliationRepository := gateway.NewReconciliationRepository(database)
+	communityRepository := gateway.NewCommunityRepository(database)
+	notificationRepository := gateway.NewNotificationRepository(database)
+	conversationRepository := gateway.NewConversationRepository(database)
+	emailRepository := gateway.NewEmailRepository(database)
+	oneTimeTokenRepository := gateway.NewOneTimeTokenRepository(database)
+	loginThrottleRepository := gateway.NewLoginThrottleRepository(database)
+	twoFactorRepository := gateway.NewTwoFactorRepository(database)
+	userIdentityRepository := gateway.NewUserIdentityRepository(database)
+
+	// Share domain events with the other instances through the database
+	eventBus := realtime.NewBus(database)
+	if err := eventBus.Listen(dbConfig.ConnectionString()); err != nil {
+		log.Printf("Warning: events of other instances will not be received: %v", err)
+	}
+	defer eventBus.Close()
+
+	// Initialize the payment gateways; payments from unknown gateways are handled manually
+	paymentGateways := payment.NewGateways(payment.NewManualProcessor())
+
+	// Initialize the mailer; the file driver writes emails to a local directory for development
+	var mailer mail.Mailer
+	if mailConfig.Driver == "smtp" {
+		mailer = mail.NewSMTPMailer(mailConfig.Host, mailConfig.Port, mailConfig.Username, mailConfig.Password)
+	} else {
+		fileMailer, err := mail.NewFileMailer(mailConfig.Dir)
+		if err != nil {
+			log.Fatal("Error initializing the mailer:", err)
+		}
+		mailer = fileMailer
+	}
+	mailTemplates, err := mail.NewTemplates(mailConfig.DefaultLocale)
+	if err != nil {
+		log.Fatal("Error loading the email templates:", err)
+	}
 
 	// Initialize the services
-	userService := service.NewUserService(userRepository, tokenRepository)
-	courseService := service.NewCourseService(courseRepository, tokenRepository)
-	spaceService := service.NewSpaceService(SpaceRepository, tokenRepository)
-	meetingService := service.NewMeetingService(meetingRepository, tokenRepository)
-	paymentService := service.NewPaymentService(paymentRepository, tokenRepository)
+	emailService := service.NewEmailService(emailRepository, userRepository, mailer, mailTemplates, mailConfig)
+	notificationService := service.NewNotificationService(notificationRepository, userRepository, eventBus)
+	twoFactorService := service.NewTwoFactorService(twoFactorRepository, userRepository, roleRepository, authConfig)
+	tokenService := service.NewTokenService(tokenRepository, roleRepository, signingKeys, authConfig)
+	oidcProviders := loadOIDCProviders(oidcProviderConfigs)
+	userService := service.NewUserService(userRepository, tokenRepository, apiKeyRepository, tokenService, oneTimeTokenRepository, loginThrottleRepository, twoFactorService, emailService, authConfig)
+	oidcService := service.NewOIDCService(oidcProviders, userIdentityRepository, userRepository, userService)
+	apiKeyService := service.NewAPIKeyService(apiKeyRepository)
+	sessionService := service.NewSessionService(tokenRepository)
+	courseService := service.NewCourseService(courseRepository, spaceMemberRepository, tokenRepository)
+	spaceService := service.NewSpaceService(SpaceRepository, spaceMemberRepository, tokenRepository)
+	meetingService := service.NewMeetingService(meetingRepository, spaceMemberRepository, tokenRepository, eventBus, notificationService, emailService)
+	paymentService := service.NewPaymentService(paymentRepository, refundRepository, tokenRepository, paymentGateways, eventBus, notificationService)
+	couponService := service.NewCouponService(couponRepository)
+	orderService := service.NewOrderService(orderRepository, paymentRepository, enrollmentRepository, courseRepository, SpaceRepository, spaceMemberRepository, couponService, paymentGateways, eventBus, notificationService)
+
+	invoiceService := service.NewInvoiceService(invoiceRepository, paymentRepository, orderRepository, userRepository, roleRepository, invoiceConfig)
+	payoutService := service.NewPayoutService(payoutRepository, ledgerRepository, orderRepository, courseRepository, SpaceRepository, payoutConfig)
+	reconciliationService := service.NewReconciliationService(reconciliationRepository, paymentRepository)
+	communityService := service.NewCommunityService(communityRepository, spaceMemberRepository, userRepository, roleRepository, notificationService, eventBus)
+	eventService := service.NewEventService(eventBus, spaceMemberRepository, roleRepository)
+	messageService := service.NewMessageService(conversationRepository, userRepository, spaceMemberRepository, eventBus)
+	spaceMemberService := service.NewSpaceMemberService(spaceMemberRepository, SpaceRepository, planRepository, enrollmentRepository, subscriptionRepository, notificationService)
+	subscriptionService := service.NewSubscriptionService(planRepository, subscriptionRepository, SpaceRepository, orderRepository, spaceMemberRepository, paymentService, paymentGateways)
+
+	// Grant access to purchased items, issue the invoice and share the revenue once their payment completes
+	paymentService.AddPaymentListener(orderService)
+	paymentService.AddPaymentListener(invoiceService)
+	paymentService.AddPaymentListener(payoutService)
+	paymentService.AddPaymentListener(emailService)
+	paymentService.AddRefundListener(payoutService)
+
+	// Email the notifications users chose to receive by email
+	notificationService.SetMailer(emailService)
+
+	// Start the background jobs
+	scheduler := job.NewScheduler()
+	defer scheduler.Stop()
+	scheduler.Every("subscription-renewals", time.Hour, func() error {
+		return subscriptionService.ProcessRenewals(time.Now())
+	})
+	scheduler.Every("creator-payouts", 24*time.Hour, func() error {
+		return payoutService.SchedulePayouts(time.Now())
+	})
+	scheduler.Every("order-expiry", time.Hour, func() error {
+		return orderService.ExpireOrders(time.Now())
+	})
+	scheduler.Every("email-outbox", time.Minute, func() error {
+		return emailService.ProcessOutbox(time.Now())
+	})
 
 	// Initialize the controllers
 	userController := controller.NewUserController(userService)
+	twoFactorController := controller.NewTwoFactorController(twoFactorService)
+	tokenController := controller.NewTokenController(tokenService)
+	oidcController := controller.NewOIDCController(oidcService)
+	apiKeyController := controller.NewAPIKeyController(apiKeyService)
+	sessionController := controller.NewSessionController(sessionService)
 	courseController := controller.NewCourseController(courseService)
 	spaceController := controller.NewSpaceController(spaceService)
+	spaceMemberController := controller.NewSpaceMemberController(spaceMemberService)
 	meetingController := controller.NewMeetingController(meetingService)
+	communityController := controller.NewCommunityController(communityService)
+	messageController := controller.NewMessageController(messageService)
+	eventController := controller.NewEventController(eventService)
+	notificationController := controller.NewNotificationController(notificationService)
+	emailController := controller.NewEmailController(emailService, mailConfig.WebhookSecret)
 	paymentController := controller.NewPaymentController(paymentService)
+	orderController := controller.NewOrderController(orderService)
+	subscriptionController := controller.NewSubscriptionController(subscriptionService)
+	couponController := controller.NewCouponController(couponService)
+	invoiceController := controller.NewInvoiceController(invoiceService)
+	payoutController := controller.NewPayoutController(payoutService)
+	reconciliationController := controller.NewReconciliationController(reconciliationService)
 
 	// Initialize Gin router
 	r := gin.Default()
 
+	// Only believe the client IP forwarded by the configured proxies, so it cannot be spoofed past the login throttle
+	if err := r.SetTrustedProxies(serverConfig.TrustedProxies); err != nil {
+		log.Fatal("Error configuring the trusted proxies:", err)
+	}
+
 	// Apply CORS middleware
 	r.Use(cors.New(cors.Config{
 		AllowOrigins:     []string{"http://localhost:3000", "https://your-frontend-domain.com"},
 		AllowMethods:     []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
-		AllowHeaders:     []string{"Origin", "Content-Type", "Authorization"},
+		AllowHeaders:     []string{"Origin", "Content-Type", "Authorization", "X-API-Key"},
 		ExposeHeaders:    []string{"Content-Length"},
 		AllowCredentials: true,
 	}))
 
+	// Render the errors recorded by the handlers as problem+json
+	r.Use(middleware.ErrorMiddleware())
+
 	// Register user-related routes with token repository for middleware
-	routes.RegisterUserRoutes(r, userController, tokenRepository)
-	routes.RegisterCoursesRoutes(r, courseController, tokenRepository)
-	routes.RegisterSpacesRoutes(r, spaceController, tokenRepository)
-	routes.RegisterMeetingRoutes(r, meetingController, tokenRepository)
-	routes.RegisterPaymentRoutes(r, paymentController, tokenRepository)
+	routes.RegisterUserRoutes(r, userController, accessTokenRepository, roleRepository)
+	routes.RegisterTokenRoutes(r, tokenController)
+	routes.RegisterOIDCRoutes(r, oidcController, accessTokenRepository)
+	routes.RegisterAPIKeyRoutes(r, apiKeyController, accessTokenRepository)
+	routes.RegisterSessionRoutes(r, sessionController, accessTokenRepository)
+	routes.RegisterTwoFactorRoutes(r, twoFactorController, accessTokenRepository, roleRepository)
+	routes.RegisterCoursesRoutes(r, courseController, accessTokenRepository)
+	routes.RegisterSpacesRoutes(r, spaceController, accessTokenRepository)
+	routes.RegisterSpaceMemberRoutes(r, spaceMemberController, accessTokenRepository)
+	routes.RegisterMeetingRoutes(r, meetingController, accessTokenRepository)
+	routes.RegisterCommunityRoutes(r, communityController, accessTokenRepository)
+	routes.RegisterMessageRoutes(r, messageController, accessTokenRepository)
+	routes.RegisterEventRoutes(r, eventController, accessTokenRepository)
+	routes.RegisterNotificationRoutes(r, notificationController, accessTokenRepository)
+	routes.RegisterEmailRoutes(r, emailController, accessTokenRepository, roleRepository)
+	routes.RegisterPaymentRoutes(r, paymentController, accessTokenRepository, roleRepository)
+	routes.RegisterOrderRoutes(r, orderController, accessTokenRepository)
+	routes.RegisterSubscriptionRoutes(r, subscriptionController, accessTokenRepository)
+	routes.RegisterCouponRoutes(r, couponController, accessTokenRepository, roleRepository)
+	routes.RegisterInvoiceRoutes(r, invoiceController, accessTokenRepository)
+	routes.RegisterPayoutRoutes(r, payoutController, accessTokenRepository, roleRepository)
+	routes.RegisterReconciliationRoutes(r, reconciliationController, accessTokenRepository, roleRepository)
 
 	// Start the server
 	if err := r.Run(":8080"); err != nil {
 		log.Fatal("Error starting the server:", err)
 	}
 }
+
+// loadSigningKeys loads the keys signing access tokens. Without key files a key is generated, and the tokens
+// it signs stop working when the server restarts.
+func loadSigningKeys(authConfig *config.AuthConfig) *jwtauth.KeySet {
+	var keys []*jwtauth.Key
+	for _, file := range authConfig.JWTKeyFiles {
+		key, err := jwtauth.LoadKey(file)
+		if err != nil {
+			log.Fatal("Error loading the JWT signing key:", err)
+		}
+		keys = append(keys, key)
+	}
+	if len(keys) == 0 {
+		log.Println("Warning: AUTH_JWT_KEY_FILES is not set. Using a random signing key.")
+		key, err := jwtauth.GenerateKey(authConfig.JWTAlgorithm)
+		if err != nil {
+			log.Fatal("Error generating the JWT signing key:", err)
+		}
+		keys = append(keys, key)
+	}
+
+	keySet, err := jwtauth.NewKeySet(authConfig.JWTIssuer, keys...)
+	if err != nil {
+		log.Fatal("Error loading the JWT signing keys:", err)
+	}
+	log.Printf("Signing access tokens with key %s (%s)", keys[0].ID, keys[0].Algorithm)
+	return keySet
+}
+
+// loadOIDCProviders creates the identity providers users can sign in with, skipping the incomplete ones
+func loadOIDCProviders(configs []config.OIDCProviderConfig) *oidc.Providers {
+	client := &http.Client{Timeout: 10 * time.Second}
+	var providers []*oidc.Provider
+	for _, cfg := range configs {
+		if cfg.Issuer == "" || cfg.ClientID == "" {
+			log.Printf("Warning: identity provider %s has no issuer or client ID and is disabled.", cfg.Name)
+			continue
+		}
+		providers = append(providers, oidc.NewProvider(cfg, client))
+	}
+	return oidc.NewProviders(providers...)
+}
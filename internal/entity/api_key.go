@@ -0,0 +1,31 @@
+package entity
+
+import (
+	"time"
+
+	"github.com/gofrs/uuid"
+)
+
+// APIKeyPrefix starts every API key, which tells them apart from session tokens
+const APIKeyPrefix = "dlb_"
+
+// Scopes granted to API keys
+const (
+	APIKeyScopeRead  = "read"  // GET requests
+	APIKeyScopeWrite = "write" // Every request, reads included
+	APIKeyScopeAdmin = "admin" // The admin routes, if the user is an admin
+)
+
+// APIKey is a personal key a user gives to their scripts and integrations instead of signing in.
+// Only its hash is stored; the prefix identifies it in listings.
+type APIKey struct {
+	ID         uuid.UUID  `json:"id"`
+	UserID     uuid.UUID  `json:"user_id"`
+	Name       string     `json:"name"`
+	Prefix     string     `json:"prefix"`
+	KeyHash    string     `json:"-"`
+	Scopes     []string   `json:"scopes"`
+	ExpiresAt  time.Time  `json:"expires_at"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
@@ -1 +1,72 @@
 package entity
+
+import (
+	"time"
+
+	"github.com/gofrs/uuid"
+)
+
+// Kinds of community content that can be reacted to and edited
+const (
+	CommunityTargetPost    = "post"
+	CommunityTargetComment = "comment"
+)
+
+// Post is a discussion thread started by a member in the community area of a space
+type Post struct {
+	ID             uuid.UUID      `json:"id"`
+	SpaceID        uuid.UUID      `json:"space_id"`
+	AuthorID       uuid.UUID      `json:"author_id"`
+	AuthorUsername string         `json:"author_username"`
+	Title          string         `json:"title"`
+	Body           string         `json:"body"`
+	PinnedAt       *time.Time     `json:"pinned_at,omitempty"`     // Set while the coach keeps the post on top
+	HiddenAt       *time.Time     `json:"hidden_at,omitempty"`     // Set when a moderator hid the post
+	HiddenBy       *uuid.UUID     `json:"hidden_by,omitempty"`     // Moderator who hid the post
+	HiddenReason   string         `json:"hidden_reason,omitempty"` // Why the post was hidden
+	CommentCount   int            `json:"comment_count"`           // Number of visible comments
+	Reactions      map[string]int `json:"reactions"`               // Number of reactions per emoji
+	EditedAt       *time.Time     `json:"edited_at,omitempty"`     // Time of the last edit, see the revisions
+	CreatedAt      time.Time      `json:"created_at"`
+	UpdatedAt      time.Time      `json:"updated_at"`
+	DeletedAt      *time.Time     `json:"deleted_at,omitempty"`
+}
+
+// Comment is a reply to a post or, through ParentID, to another comment
+type Comment struct {
+	ID             uuid.UUID      `json:"id"`
+	PostID         uuid.UUID      `json:"post_id"`
+	ParentID       *uuid.UUID     `json:"parent_id,omitempty"` // Comment replied to, nil for top-level comments
+	AuthorID       uuid.UUID      `json:"author_id"`
+	AuthorUsername string         `json:"author_username"`
+	Body           string         `json:"body"`
+	HiddenAt       *time.Time     `json:"hidden_at,omitempty"`
+	HiddenBy       *uuid.UUID     `json:"hidden_by,omitempty"`
+	HiddenReason   string         `json:"hidden_reason,omitempty"`
+	Reactions      map[string]int `json:"reactions"`
+	EditedAt       *time.Time     `json:"edited_at,omitempty"`
+	CreatedAt      time.Time      `json:"created_at"`
+	UpdatedAt      time.Time      `json:"updated_at"`
+	DeletedAt      *time.Time     `json:"deleted_at,omitempty"` // Deleted comments stay as placeholders while they have replies
+	Replies        []*Comment     `json:"replies,omitempty"`
+}
+
+// Reaction is an emoji a user put on a post or a comment
+type Reaction struct {
+	TargetType string    `json:"target_type"` // "post" or "comment"
+	TargetID   uuid.UUID `json:"target_id"`
+	UserID     uuid.UUID `json:"user_id"`
+	Emoji      string    `json:"emoji"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// Revision keeps the content a post or a comment had before one of its edits
+type Revision struct {
+	ID         uuid.UUID `json:"id"`
+	TargetType string    `json:"target_type"` // "post" or "comment"
+	TargetID   uuid.UUID `json:"target_id"`
+	Title      string    `json:"title,omitempty"` // Only posts have a title
+	Body       string    `json:"body"`
+	EditedBy   uuid.UUID `json:"edited_by"`
+	EditedAt   time.Time `json:"edited_at"`
+}
@@ -0,0 +1,38 @@
+package entity
+
+import (
+	"time"
+
+	"github.com/gofrs/uuid"
+)
+
+// Conversation is a private exchange of messages between two users or a small group
+type Conversation struct {
+	ID           uuid.UUID                  `json:"id"`
+	Title        string                     `json:"title,omitempty"` // Optional name of a group conversation
+	IsGroup      bool                       `json:"is_group"`
+	CreatedBy    uuid.UUID                  `json:"created_by"`
+	Participants []*ConversationParticipant `json:"participants"`
+	LastMessage  *Message                   `json:"last_message,omitempty"`
+	UnreadCount  int                        `json:"unread_count"` // Messages the requesting user has not read yet
+	CreatedAt    time.Time                  `json:"created_at"`
+	UpdatedAt    time.Time                  `json:"updated_at"` // Time of the last message
+}
+
+// ConversationParticipant is a user taking part in a conversation
+type ConversationParticipant struct {
+	ConversationID uuid.UUID  `json:"conversation_id"`
+	UserID         uuid.UUID  `json:"user_id"`
+	Username       string     `json:"username"`
+	LastReadAt     *time.Time `json:"last_read_at,omitempty"` // Read receipt: messages sent until then were read
+	JoinedAt       time.Time  `json:"joined_at"`
+}
+
+// Message is sent by a participant to the other participants of a conversation
+type Message struct {
+	ID             uuid.UUID `json:"id"`
+	ConversationID uuid.UUID `json:"conversation_id"`
+	SenderID       uuid.UUID `json:"sender_id"`
+	Body           string    `json:"body"`
+	CreatedAt      time.Time `json:"created_at"`
+}
@@ -0,0 +1,35 @@
+package entity
+
+import (
+	"time"
+
+	"github.com/gofrs/uuid"
+)
+
+// Coupon is a discount code redeemable at checkout
+type Coupon struct {
+	ID              uuid.UUID   `json:"id"`
+	Code            string      `json:"code"`                 // Code typed by the user, stored upper-case
+	DiscountType    string      `json:"discount_type"`        // "percentage" or "fixed"
+	Value           float64     `json:"value"`                // Percentage (0-100] or fixed amount
+	Currency        string      `json:"currency,omitempty"`   // Currency of a fixed amount discount
+	ExpiresAt       *time.Time  `json:"expires_at,omitempty"` // No expiry when empty
+	MaxRedemptions  int         `json:"max_redemptions"`      // Total redemptions allowed, 0 for unlimited
+	PerUserLimit    int         `json:"per_user_limit"`       // Redemptions allowed per user, 0 for unlimited
+	RedemptionCount int         `json:"redemption_count"`     // Number of times the coupon was redeemed
+	CourseIDs       []uuid.UUID `json:"course_ids,omitempty"` // Courses the coupon applies to
+	SpaceIDs        []uuid.UUID `json:"space_ids,omitempty"`  // Spaces the coupon applies to; both empty means every item
+	Active          bool        `json:"active"`
+	CreatedAt       time.Time   `json:"created_at"`
+	UpdatedAt       time.Time   `json:"updated_at"`
+}
+
+// CouponRedemption records a coupon used on an order
+type CouponRedemption struct {
+	ID        uuid.UUID `json:"id"`
+	CouponID  uuid.UUID `json:"coupon_id"`
+	UserID    uuid.UUID `json:"user_id"`
+	OrderID   uuid.UUID `json:"order_id"`
+	Discount  float64   `json:"discount"` // Amount taken off the order
+	CreatedAt time.Time `json:"created_at"`
+}
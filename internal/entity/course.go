@@ -9,16 +9,20 @@ import (
 // Course represents the structure of a course entity
 type Course struct {
 	ID            uuid.UUID  `json:"id,omitempty"`
-	Title         string     `json:"title" binding:"required"`
+	Title         string     `json:"title"`
 	Description   string     `json:"description"`
 	Duration      string     `json:"duration"`
 	Version       uuid.UUID  `json:"version,omitempty"`
 	Category      string     `json:"category"`
 	InstructorID  uuid.UUID  `json:"instructor_id,omitempty"` // Added InstructorID field
+	SpaceID       *uuid.UUID `json:"space_id,omitempty"`      // Space the course belongs to, if any
+	MembersOnly   bool       `json:"members_only"`            // Only members of the space can see the course
 	EnrolledCount int        `json:"enrolled_count,omitempty"`
 	ContentURL    []string   `json:"content_url"` // Changed to a slice of strings
 	Outline       string     `json:"outline,omitempty"`
 	Status        string     `json:"status"`
+	Price         float64    `json:"price"`    // Price of the course, 0 for free courses
+	Currency      string     `json:"currency"` // Currency of the price (e.g., USD, EUR)
 	CreatedAt     time.Time  `json:"created_at"`
 	UpdatedAt     time.Time  `json:"updated_at"`
 	DeletedAt     *time.Time `json:"deleted_at,omitempty"`
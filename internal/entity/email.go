@@ -0,0 +1,32 @@
+package entity
+
+import (
+	"time"
+
+	"github.com/gofrs/uuid"
+)
+
+// OutboxEmail is an email waiting to be sent, or the record of one that was
+type OutboxEmail struct {
+	ID            uuid.UUID  `json:"id"`
+	Recipient     string     `json:"recipient"`
+	Subject       string     `json:"subject"`
+	HTMLBody      string     `json:"-"`
+	TextBody      string     `json:"-"`
+	Template      string     `json:"template"` // Name of the template the email was rendered from
+	Status        string     `json:"status"`   // "pending", "sent", "failed" or "suppressed"
+	Attempts      int        `json:"attempts"`
+	LastError     string     `json:"last_error,omitempty"`
+	NextAttemptAt time.Time  `json:"next_attempt_at"`
+	SentAt        *time.Time `json:"sent_at,omitempty"`
+	CreatedAt     time.Time  `json:"created_at"`
+	UpdatedAt     time.Time  `json:"updated_at"`
+}
+
+// EmailSuppression is an address no email is sent to anymore
+type EmailSuppression struct {
+	Email     string    `json:"email"`
+	Reason    string    `json:"reason"` // "bounce", "complaint" or "manual"
+	Detail    string    `json:"detail,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
@@ -0,0 +1,17 @@
+package entity
+
+import (
+	"time"
+
+	"github.com/gofrs/uuid"
+)
+
+// Enrollment grants a user access to a purchased course or space
+type Enrollment struct {
+	ID        uuid.UUID `json:"id"`
+	UserID    uuid.UUID `json:"user_id"`
+	ItemType  string    `json:"item_type"` // "course" or "space_membership"
+	ItemID    uuid.UUID `json:"item_id"`   // ID of the course or space
+	OrderID   uuid.UUID `json:"order_id"`  // Order that granted the access
+	CreatedAt time.Time `json:"created_at"`
+}
@@ -0,0 +1,34 @@
+package entity
+
+import (
+	"time"
+
+	"github.com/gofrs/uuid"
+)
+
+// Invoice is the receipt issued for a completed payment
+type Invoice struct {
+	ID             uuid.UUID      `json:"id"`
+	Number         string         `json:"number"` // Sequential, gap-free number (e.g., INV-2026-000042)
+	PaymentID      uuid.UUID      `json:"payment_id"`
+	OrderID        uuid.UUID      `json:"order_id,omitempty"`
+	UserID         uuid.UUID      `json:"user_id"`
+	BillingName    string         `json:"billing_name"`
+	BillingAddress string         `json:"billing_address,omitempty"`
+	Currency       string         `json:"currency"`
+	Lines          []*InvoiceLine `json:"lines"`
+	Subtotal       float64        `json:"subtotal"` // Total before tax
+	Discount       float64        `json:"discount"` // Coupon discount included in the lines
+	TaxRate        float64        `json:"tax_rate"` // Tax percentage included in the total
+	Tax            float64        `json:"tax"`
+	Total          float64        `json:"total"` // Amount paid
+	IssuedAt       time.Time      `json:"issued_at"`
+}
+
+// InvoiceLine is a single billed item of an invoice
+type InvoiceLine struct {
+	Description string  `json:"description"`
+	Quantity    int     `json:"quantity"`
+	UnitPrice   float64 `json:"unit_price"`
+	Amount      float64 `json:"amount"`
+}
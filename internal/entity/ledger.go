@@ -0,0 +1,55 @@
+package entity
+
+import (
+	"time"
+
+	"github.com/gofrs/uuid"
+)
+
+// LedgerTransaction is a balanced journal entry: the debits of its entries equal their credits
+type LedgerTransaction struct {
+	ID            uuid.UUID      `json:"id"`
+	ReferenceType string         `json:"reference_type"` // "payment", "refund", "payout" or "payout_reversal"
+	ReferenceID   uuid.UUID      `json:"reference_id"`   // ID of the payment, refund or payout that was posted
+	Description   string         `json:"description"`
+	Entries       []*LedgerEntry `json:"entries"`
+	CreatedAt     time.Time      `json:"created_at"`
+}
+
+// LedgerEntry debits or credits a single account of a ledger transaction
+type LedgerEntry struct {
+	ID            uuid.UUID `json:"id"`
+	TransactionID uuid.UUID `json:"transaction_id"`
+	Account       string    `json:"account"`  // "cash", "platform_fees" or "creator_payable"
+	OwnerID       uuid.UUID `json:"owner_id"` // Coach or instructor the entry is attributed to, nil for the platform
+	Currency      string    `json:"currency"`
+	Debit         float64   `json:"debit"`
+	Credit        float64   `json:"credit"`
+}
+
+// EarningsBalance is what the platform owes a coach or instructor in one currency
+type EarningsBalance struct {
+	UserID    uuid.UUID `json:"-"`
+	Currency  string    `json:"currency"`
+	Balance   float64   `json:"balance"`   // Earned and not paid out yet
+	OnHold    float64   `json:"on_hold"`   // Part of the balance still within the refund hold period
+	Available float64   `json:"available"` // Part of the balance that can be paid out
+}
+
+// EarningsPeriod summarises the ledger activity of a coach or instructor over one period
+type EarningsPeriod struct {
+	Period   time.Time `json:"period"` // Start of the day, week, month or year
+	Currency string    `json:"currency"`
+	Gross    float64   `json:"gross"`    // Sales attributed to the creator
+	Fees     float64   `json:"fees"`     // Platform fees net of refunded fees
+	Refunds  float64   `json:"refunds"`  // Sales refunded to the buyers
+	Net      float64   `json:"net"`      // Creator share after fees and refunds
+	PaidOut  float64   `json:"paid_out"` // Payouts scheduled in the period
+}
+
+// Earnings is the earnings report of a coach or instructor
+type Earnings struct {
+	Balances []*EarningsBalance `json:"balances"`
+	Periods  []*EarningsPeriod  `json:"periods"`
+	Payouts  []*Payout          `json:"payouts"`
+}
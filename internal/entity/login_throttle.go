@@ -0,0 +1,18 @@
+package entity
+
+import "time"
+
+// Scopes of login throttles
+const (
+	LoginScopeAccount = "account" // Subject is the email the user signs in with, lower-cased
+	LoginScopeIP      = "ip"      // Subject is the IP address of the client
+)
+
+// LoginThrottle counts the consecutive failed sign-ins of an account or an IP address
+type LoginThrottle struct {
+	Scope        string     `json:"scope"`
+	Subject      string     `json:"subject"`
+	Failures     int        `json:"failures"`
+	LastFailedAt time.Time  `json:"last_failed_at"`
+	LockedUntil  *time.Time `json:"locked_until,omitempty"` // Set once the failures reached the lockout threshold
+}
@@ -8,11 +8,11 @@ import (
 
 type Meeting struct {
 	ID              uuid.UUID   `json:"id"`
-	Title           string      `json:"title" binding:"required"`
+	Title           string      `json:"title"`
 	Description     string      `json:"description,omitempty"`
-	Duration        string      `json:"duration" binding:"required"`
-	StartTime       time.Time   `json:"start_time" binding:"required"`
-	EndTime         time.Time   `json:"end_time" binding:"required"`
+	Duration        string      `json:"duration"`
+	StartTime       time.Time   `json:"start_time"`
+	EndTime         time.Time   `json:"end_time"`
 	Location        string      `json:"location,omitempty"`
 	AttendeeIDs     []uuid.UUID `json:"attendee_ids,omitempty"`     // List of attendee user IDs
 	AttendeeNames   []string    `json:"attendee_names,omitempty"`   // List of attendee names
@@ -22,6 +22,8 @@ type Meeting struct {
 	Status          string      `json:"status"`                     // e.g., "scheduled", "ongoing", "completed", "cancelled"
 	JoinURL         []string    `json:"join_url,omitempty"`         // Virtual meeting link if applicable
 	MaximumCapacity int         `json:"maximum_capacity,omitempty"` // Maximum number of attendees allowed
+	SpaceID         *uuid.UUID  `json:"space_id,omitempty"`         // Space hosting the meeting, if any
+	MembersOnly     bool        `json:"members_only"`               // Only members of the space can see the meeting
 	CreatedAt       time.Time   `json:"created_at"`
 	UpdatedAt       time.Time   `json:"updated_at"`
 	DeletedAt       *time.Time  `json:"deleted_at,omitempty"` // For soft deletes
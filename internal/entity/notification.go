@@ -0,0 +1,65 @@
+package entity
+
+import (
+	"time"
+
+	"github.com/gofrs/uuid"
+)
+
+// Notification categories, the unit users choose their notifications by
+const (
+	NotificationCategoryMeeting    = "meeting"
+	NotificationCategoryPayment    = "payment"
+	NotificationCategoryEnrollment = "enrollment"
+	NotificationCategorySpace      = "space"
+)
+
+// NotificationCategories lists every notification category
+var NotificationCategories = []string{
+	NotificationCategoryMeeting,
+	NotificationCategoryPayment,
+	NotificationCategoryEnrollment,
+	NotificationCategorySpace,
+}
+
+// Notification channels
+const (
+	NotificationChannelInApp = "in_app" // Inbox of the notification center
+	NotificationChannelEmail = "email"
+)
+
+// NotificationChannels lists every notification channel
+var NotificationChannels = []string{NotificationChannelInApp, NotificationChannelEmail}
+
+// Notification types
+const (
+	NotificationMention          = "mention"           // The user was mentioned with @username
+	NotificationMeetingUpdated   = "meeting_updated"   // A meeting the user attends changed
+	NotificationMeetingCancelled = "meeting_cancelled" // A meeting the user attends was called off
+	NotificationPaymentStatus    = "payment_status"    // A payment of the user changed status
+	NotificationEnrollment       = "enrollment"        // The user got access to a course or a space
+	NotificationSpacePost        = "space_post"        // A discussion started in a space of the user
+	NotificationSpaceMemberJoin  = "space_member_join" // Someone joined a space the user runs
+)
+
+// Notification tells a user about something that happened in the application
+type Notification struct {
+	ID        uuid.UUID  `json:"id"`
+	UserID    uuid.UUID  `json:"user_id"`
+	Category  string     `json:"category"` // e.g., "meeting", "payment", "enrollment", "space"
+	Type      string     `json:"type"`
+	Title     string     `json:"title"`
+	Body      string     `json:"body"`
+	Link      string     `json:"link,omitempty"` // Path of the resource the notification is about
+	ReadAt    *time.Time `json:"read_at,omitempty"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+// NotificationPreference records whether a user receives a category of notifications on a channel
+type NotificationPreference struct {
+	UserID    uuid.UUID `json:"-"`
+	Category  string    `json:"category"`
+	Channel   string    `json:"channel"` // "in_app" or "email"
+	Enabled   bool      `json:"enabled"`
+	UpdatedAt time.Time `json:"updated_at,omitempty"`
+}
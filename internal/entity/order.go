@@ -0,0 +1,37 @@
+package entity
+
+import (
+	"time"
+
+	"github.com/gofrs/uuid"
+)
+
+// Order groups the items a user buys in a single checkout
+type Order struct {
+	ID         uuid.UUID `json:"id"`
+	UserID     uuid.UUID `json:"user_id"`               // ID of the user placing the order
+	Status     string    `json:"status"`                // e.g., "pending", "paid", "failed", "cancelled"
+	Currency   string    `json:"currency"`              // Currency shared by every line of the order
+	Subtotal   float64   `json:"subtotal"`              // Sum of the line amounts
+	Discount   float64   `json:"discount"`              // Amount taken off by the coupon
+	CouponCode string    `json:"coupon_code,omitempty"` // Coupon applied at checkout
+	Total      float64   `json:"total"`                 // Subtotal minus discount
+	// Billing details printed on the invoice; the user's name is used when empty
+	BillingName    string       `json:"billing_name,omitempty"`
+	BillingAddress string       `json:"billing_address,omitempty"`
+	Lines          []*OrderLine `json:"lines"` // Items bought in the order
+	CreatedAt      time.Time    `json:"created_at"`
+	UpdatedAt      time.Time    `json:"updated_at"`
+}
+
+// OrderLine is a single purchasable item of an order
+type OrderLine struct {
+	ID          uuid.UUID `json:"id"`
+	OrderID     uuid.UUID `json:"order_id"`
+	ItemType    string    `json:"item_type"`   // "course" or "space_membership"
+	ItemID      uuid.UUID `json:"item_id"`     // ID of the course or space
+	Description string    `json:"description"` // Title of the item at checkout time
+	UnitPrice   float64   `json:"unit_price"`  // Price of the item at checkout time
+	Quantity    int       `json:"quantity"`
+	Amount      float64   `json:"amount"` // UnitPrice * Quantity
+}
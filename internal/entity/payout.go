@@ -0,0 +1,21 @@
+package entity
+
+import (
+	"time"
+
+	"github.com/gofrs/uuid"
+)
+
+// Payout transfers the available balance of a coach or instructor out of the platform
+type Payout struct {
+	ID        uuid.UUID  `json:"id"`
+	UserID    uuid.UUID  `json:"user_id"` // Coach or instructor being paid
+	Amount    float64    `json:"amount"`
+	Currency  string     `json:"currency"`
+	Status    string     `json:"status"`              // e.g., "pending", "paid", "failed"
+	Reference string     `json:"reference,omitempty"` // Transfer reference recorded when the payout is sent
+	Period    string     `json:"period,omitempty"`    // Day the payout was scheduled, as "2006-01-02"; one payout per creator, currency and day
+	PaidAt    *time.Time `json:"paid_at,omitempty"`
+	CreatedAt time.Time  `json:"created_at"`
+	UpdatedAt time.Time  `json:"updated_at"`
+}
@@ -0,0 +1,21 @@
+package entity
+
+import (
+	"time"
+
+	"github.com/gofrs/uuid"
+)
+
+// Plan is a recurring membership offer attached to a space
+type Plan struct {
+	ID        uuid.UUID `json:"id"`
+	SpaceID   uuid.UUID `json:"space_id"`   // Space the plan grants membership to
+	Name      string    `json:"name"`       // e.g., "Monthly coaching"
+	Price     float64   `json:"price"`      // Price charged every interval
+	Currency  string    `json:"currency"`   // Currency of the price (e.g., USD, EUR)
+	Interval  string    `json:"interval"`   // Billing interval: "week", "month" or "year"
+	TrialDays int       `json:"trial_days"` // Free days before the first charge
+	Active    bool      `json:"active"`     // Inactive plans accept no new subscribers
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
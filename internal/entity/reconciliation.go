@@ -0,0 +1,36 @@
+package entity
+
+import (
+	"time"
+
+	"github.com/gofrs/uuid"
+)
+
+// Reconciliation is a run comparing a gateway settlement file with the payments table
+type Reconciliation struct {
+	ID               uuid.UUID                    `json:"id"`
+	Gateway          string                       `json:"gateway"`   // Gateway the settlement file comes from, empty for every gateway
+	FileName         string                       `json:"file_name"` // Name of the imported settlement file
+	PeriodStart      time.Time                    `json:"period_start"`
+	PeriodEnd        time.Time                    `json:"period_end"`
+	RowCount         int                          `json:"row_count"`         // Rows read from the settlement file
+	MatchedCount     int                          `json:"matched_count"`     // Rows matching a payment exactly
+	DiscrepancyCount int                          `json:"discrepancy_count"` // Problems flagged by the run
+	CreatedBy        uuid.UUID                    `json:"created_by"`
+	CreatedAt        time.Time                    `json:"created_at"`
+	Discrepancies    []*ReconciliationDiscrepancy `json:"discrepancies,omitempty"`
+}
+
+// ReconciliationDiscrepancy is a mismatch between a settlement row and a payment
+type ReconciliationDiscrepancy struct {
+	ID               uuid.UUID  `json:"id"`
+	ReconciliationID uuid.UUID  `json:"reconciliation_id"`
+	Type             string     `json:"type"` // "missing_payment", "missing_settlement", "duplicated" or "amount_mismatch"
+	TransactionID    string     `json:"transaction_id"`
+	PaymentID        *uuid.UUID `json:"payment_id,omitempty"` // Matching payment, nil when the payment is missing
+	ExpectedAmount   float64    `json:"expected_amount"`      // Amount recorded in the payments table
+	SettledAmount    float64    `json:"settled_amount"`       // Amount reported by the gateway
+	Currency         string     `json:"currency"`
+	OccurredAt       time.Time  `json:"occurred_at"` // Settlement or payment date
+	Details          string     `json:"details,omitempty"`
+}
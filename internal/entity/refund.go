@@ -0,0 +1,21 @@
+package entity
+
+import (
+	"time"
+
+	"github.com/gofrs/uuid"
+)
+
+// Refund represents a full or partial refund issued against a payment
+type Refund struct {
+	ID              uuid.UUID  `json:"id"`
+	PaymentID       uuid.UUID  `json:"payment_id"`                  // Payment being refunded
+	Amount          float64    `json:"amount"`                      // Amount returned to the payer
+	Currency        string     `json:"currency"`                    // Always the currency of the payment
+	Reason          string     `json:"reason"`                      // Why the refund was issued
+	Status          string     `json:"status"`                      // e.g., "pending", "succeeded", "failed"
+	GatewayRefundID string     `json:"gateway_refund_id,omitempty"` // Refund reference returned by the payment gateway
+	RequestedBy     *uuid.UUID `json:"requested_by"`                // User who issued the refund; nil once they are deleted
+	CreatedAt       time.Time  `json:"created_at"`
+	UpdatedAt       time.Time  `json:"updated_at"`
+}
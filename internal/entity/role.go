@@ -1 +1,10 @@
 package entity
+
+// Role represents a named set of privileges granted to users (e.g., "admin")
+type Role struct {
+	ID   int    `json:"id"`
+	Name string `json:"name"`
+}
+
+// RoleAdmin is the role allowed to manage platform-wide resources
+const RoleAdmin = "admin"
@@ -0,0 +1,38 @@
+package entity
+
+import (
+	"time"
+
+	"github.com/gofrs/uuid"
+)
+
+// Space member roles
+const (
+	SpaceRoleOwner   = "owner"    // Coach who owns the space
+	SpaceRoleCoCoach = "co_coach" // Helps the owner run the space and manage its members
+	SpaceRoleMember  = "member"
+)
+
+// SpaceMember records that a user belongs to a space
+type SpaceMember struct {
+	SpaceID   uuid.UUID  `json:"space_id"`
+	UserID    uuid.UUID  `json:"user_id"`
+	Username  string     `json:"username,omitempty"` // Populated when the members of a space are listed
+	Role      string     `json:"role"`               // "owner", "co_coach" or "member"
+	InvitedBy *uuid.UUID `json:"invited_by,omitempty"`
+	JoinedAt  time.Time  `json:"joined_at"`
+}
+
+// SpaceInvitation is a link letting users join a space until it expires or runs out of uses
+type SpaceInvitation struct {
+	ID        uuid.UUID  `json:"id"`
+	SpaceID   uuid.UUID  `json:"space_id"`
+	Token     string     `json:"token"`    // Secret part of the invitation link
+	Role      string     `json:"role"`     // Role granted on joining, "member" or "co_coach"
+	MaxUses   int        `json:"max_uses"` // 0 for unlimited
+	UseCount  int        `json:"use_count"`
+	CreatedBy uuid.UUID  `json:"created_by"`
+	ExpiresAt time.Time  `json:"expires_at"`
+	RevokedAt *time.Time `json:"revoked_at,omitempty"`
+	CreatedAt time.Time  `json:"created_at"`
+}
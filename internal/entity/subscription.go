@@ -0,0 +1,27 @@
+package entity
+
+import (
+	"time"
+
+	"github.com/gofrs/uuid"
+)
+
+// Subscription is a user's recurring membership of a space through a plan
+type Subscription struct {
+	ID                 uuid.UUID  `json:"id"`
+	UserID             uuid.UUID  `json:"user_id"`
+	PlanID             uuid.UUID  `json:"plan_id"`
+	SpaceID            uuid.UUID  `json:"space_id"`
+	Status             string     `json:"status"` // e.g., "incomplete", "trialing", "active", "past_due", "cancelled", "unpaid"
+	PaymentMethod      string     `json:"payment_method"`
+	PaymentGateway     string     `json:"payment_gateway"`
+	CurrentPeriodStart time.Time  `json:"current_period_start"`
+	CurrentPeriodEnd   time.Time  `json:"current_period_end"` // Renewal date
+	TrialEnd           *time.Time `json:"trial_end,omitempty"`
+	CancelAtPeriodEnd  bool       `json:"cancel_at_period_end"` // Stop renewing once the current period ends
+	CancelledAt        *time.Time `json:"cancelled_at,omitempty"`
+	FailedAttempts     int        `json:"failed_attempts"`         // Consecutive failed renewal charges
+	NextRetryAt        *time.Time `json:"next_retry_at,omitempty"` // When the next dunning retry is due
+	CreatedAt          time.Time  `json:"created_at"`
+	UpdatedAt          time.Time  `json:"updated_at"`
+}
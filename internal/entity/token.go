@@ -15,4 +15,13 @@ type Token struct {
 	CreatedAt time.Time  `json:"created_at"`
 	UpdatedAt time.Time  `json:"updated_at"`
 	DeletedAt *time.Time `json:"deleted_at,omitempty"`
+
+	// The device the token was last used from, recorded by AuthMiddleware
+	UserAgent   string     `json:"user_agent"`
+	IP          string     `json:"ip"`
+	DeviceLabel string     `json:"device_label"` // Browser and operating system read from the user agent, such as "Firefox on Linux"
+	LastSeenAt  *time.Time `json:"last_seen_at,omitempty"`
+
+	Roles  []string `json:"-"` // Carried by signed access tokens; nil for tokens looked up in the database
+	Scopes []string `json:"-"` // Granted to API keys; nil for the tokens of signed in users, which may do anything
 }
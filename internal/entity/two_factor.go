@@ -0,0 +1,25 @@
+package entity
+
+import (
+	"time"
+
+	"github.com/gofrs/uuid"
+)
+
+// TwoFactor is the TOTP authenticator of a user. It only protects sign-ins once confirmed with a first code.
+type TwoFactor struct {
+	UserID       uuid.UUID  `json:"user_id"`
+	Secret       string     `json:"-"` // Encrypted, only decrypted to check codes
+	ConfirmedAt  *time.Time `json:"confirmed_at,omitempty"`
+	LastUsedStep int64      `json:"-"` // Period of the last accepted code, which cannot be used again
+	CreatedAt    time.Time  `json:"created_at"`
+}
+
+// RecoveryCode is a single-use code signing a user in when they lost their authenticator. Only its hash is stored.
+type RecoveryCode struct {
+	ID        uuid.UUID  `json:"id"`
+	UserID    uuid.UUID  `json:"user_id"`
+	CodeHash  string     `json:"-"`
+	UsedAt    *time.Time `json:"used_at,omitempty"`
+	CreatedAt time.Time  `json:"created_at"`
+}
@@ -8,15 +8,17 @@ import (
 
 // User represents a user entity
 type User struct {
-	ID        uuid.UUID  `json:"id"`
-	Username  string     `json:"username"`
-	Email     string     `json:"email"`
-	Password  string     `json:"password"`
-	FirstName string     `json:"first_name"`
-	LastName  string     `json:"last_name"`
-	IsActive  bool       `json:"is_active"`
-	LastLogin time.Time  `json:"last_login"`
-	CreatedAt time.Time  `json:"created_at"`
-	UpdatedAt time.Time  `json:"updated_at"`
-	DeletedAt *time.Time `json:"deleted_at,omitempty"`
+	ID              uuid.UUID  `json:"id"`
+	Username        string     `json:"username"`
+	Email           string     `json:"email"`
+	Password        string     `json:"-"` // bcrypt hash, never sent to clients
+	FirstName       string     `json:"first_name"`
+	LastName        string     `json:"last_name"`
+	IsActive        bool       `json:"is_active"`
+	Locale          string     `json:"locale"`                      // Language of the emails sent to the user, such as "fr"; the default one when empty
+	EmailVerifiedAt *time.Time `json:"email_verified_at,omitempty"` // Nil until the user followed the verification link
+	LastLogin       time.Time  `json:"last_login"`
+	CreatedAt       time.Time  `json:"created_at"`
+	UpdatedAt       time.Time  `json:"updated_at"`
+	DeletedAt       *time.Time `json:"deleted_at,omitempty"`
 }
@@ -0,0 +1,29 @@
+package entity
+
+import (
+	"time"
+
+	"github.com/gofrs/uuid"
+)
+
+// UserIdentity links a user to their account at an OpenID Connect identity provider they sign in with
+type UserIdentity struct {
+	ID          uuid.UUID  `json:"id"`
+	UserID      uuid.UUID  `json:"user_id"`
+	Provider    string     `json:"provider"`
+	Subject     string     `json:"-"` // Stable ID of the account at the provider
+	Email       string     `json:"email"`
+	LastLoginAt *time.Time `json:"last_login_at,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+}
+
+// OIDCState is an OpenID Connect sign-in waiting for the user to come back from the provider.
+// Only the hash of the state sent to the provider is stored.
+type OIDCState struct {
+	StateHash    string    `json:"-"`
+	Provider     string    `json:"provider"`
+	Nonce        string    `json:"-"`
+	CodeVerifier string    `json:"-"`
+	ExpiresAt    time.Time `json:"expires_at"`
+	CreatedAt    time.Time `json:"created_at"`
+}
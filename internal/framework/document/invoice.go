@@ -0,0 +1,93 @@
+package document
+
+import (
+	"bytes"
+	"dalabio/internal/entity"
+	"fmt"
+	"html/template"
+)
+
+// Seller identifies the business issuing the invoices
+type Seller struct {
+	Name    string
+	Address string
+}
+
+var invoiceTemplate = template.Must(template.New("invoice").Funcs(template.FuncMap{
+	"money": func(amount float64) string { return fmt.Sprintf("%.2f", amount) },
+	"date":  func(inv *entity.Invoice) string { return inv.IssuedAt.Format("2006-01-02") },
+}).Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>Invoice {{.Invoice.Number}}</title>
+<style>
+body { font-family: Helvetica, Arial, sans-serif; margin: 40px; color: #222; }
+table { width: 100%; border-collapse: collapse; margin-top: 24px; }
+th, td { padding: 6px 8px; border-bottom: 1px solid #ddd; text-align: left; }
+td.num, th.num { text-align: right; }
+.totals td { border: none; }
+</style>
+</head>
+<body>
+<h1>Invoice {{.Invoice.Number}}</h1>
+<p>Issued {{date .Invoice}}</p>
+<p><strong>{{.Seller.Name}}</strong><br>{{.Seller.Address}}</p>
+<p>Billed to:<br><strong>{{.Invoice.BillingName}}</strong><br>{{.Invoice.BillingAddress}}</p>
+<table>
+<tr><th>Description</th><th class="num">Qty</th><th class="num">Unit price</th><th class="num">Amount</th></tr>
+{{range .Invoice.Lines}}<tr><td>{{.Description}}</td><td class="num">{{.Quantity}}</td><td class="num">{{money .UnitPrice}}</td><td class="num">{{money .Amount}}</td></tr>
+{{end}}</table>
+<table class="totals">
+{{if .Invoice.Discount}}<tr><td class="num">Discount</td><td class="num">-{{money .Invoice.Discount}} {{.Invoice.Currency}}</td></tr>
+{{end}}<tr><td class="num">Subtotal</td><td class="num">{{money .Invoice.Subtotal}} {{.Invoice.Currency}}</td></tr>
+<tr><td class="num">Tax ({{.Invoice.TaxRate}}%)</td><td class="num">{{money .Invoice.Tax}} {{.Invoice.Currency}}</td></tr>
+<tr><td class="num"><strong>Total paid</strong></td><td class="num"><strong>{{money .Invoice.Total}} {{.Invoice.Currency}}</strong></td></tr>
+</table>
+</body>
+</html>
+`))
+
+// RenderInvoiceHTML renders an invoice as a standalone HTML page
+func RenderInvoiceHTML(invoice *entity.Invoice, seller Seller) ([]byte, error) {
+	var buf bytes.Buffer
+	err := invoiceTemplate.Execute(&buf, struct {
+		Invoice *entity.Invoice
+		Seller  Seller
+	}{invoice, seller})
+	if err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// RenderInvoicePDF renders an invoice as an A4 PDF document
+func RenderInvoicePDF(invoice *entity.Invoice, seller Seller) ([]byte, error) {
+	doc := newPDF()
+
+	doc.text(50, 16, true, "Invoice "+invoice.Number)
+	doc.text(50, 10, false, "Issued "+invoice.IssuedAt.Format("2006-01-02"))
+	doc.space(10)
+	doc.text(50, 10, true, seller.Name)
+	doc.text(50, 10, false, seller.Address)
+	doc.space(10)
+	doc.text(50, 10, false, "Billed to:")
+	doc.text(50, 10, true, invoice.BillingName)
+	doc.text(50, 10, false, invoice.BillingAddress)
+	doc.space(16)
+
+	doc.row(true, "Description", "Qty", "Unit price", "Amount")
+	for _, line := range invoice.Lines {
+		doc.row(false, line.Description, fmt.Sprintf("%d", line.Quantity), fmt.Sprintf("%.2f", line.UnitPrice), fmt.Sprintf("%.2f", line.Amount))
+	}
+	doc.space(16)
+
+	if invoice.Discount > 0 {
+		doc.row(false, "", "", "Discount", fmt.Sprintf("-%.2f %s", invoice.Discount, invoice.Currency))
+	}
+	doc.row(false, "", "", "Subtotal", fmt.Sprintf("%.2f %s", invoice.Subtotal, invoice.Currency))
+	doc.row(false, "", "", fmt.Sprintf("Tax (%g%%)", invoice.TaxRate), fmt.Sprintf("%.2f %s", invoice.Tax, invoice.Currency))
+	doc.row(true, "", "", "Total paid", fmt.Sprintf("%.2f %s", invoice.Total, invoice.Currency))
+
+	return doc.bytes(), nil
+}
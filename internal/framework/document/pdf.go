@@ -0,0 +1,169 @@
+package document
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+// A4 page size and margins in PDF points
+const (
+	pageWidth    = 595.0
+	pageHeight   = 842.0
+	pageMargin   = 50.0
+	lineSpacing  = 1.4
+	rowFontSize  = 10.0
+	maxLineChars = 95
+)
+
+// tableColumns are the x positions of the four columns written by row
+var tableColumns = []float64{50, 330, 390, 480}
+
+// pdf is a minimal single-font PDF writer producing text-only documents.
+// It supports the Latin-1 range with the standard Helvetica fonts, which every
+// PDF reader provides, so no font needs to be embedded.
+type pdf struct {
+	pages [][]string // content stream operators of each page
+	y     float64    // baseline of the next line on the current page
+}
+
+func newPDF() *pdf {
+	d := &pdf{}
+	d.newPage()
+	return d
+}
+
+func (d *pdf) newPage() {
+	d.pages = append(d.pages, nil)
+	d.y = pageHeight - pageMargin
+}
+
+// advance moves the cursor down by height, starting a new page when the bottom margin is reached
+func (d *pdf) advance(height float64) {
+	if d.y-height < pageMargin {
+		d.newPage()
+	}
+	d.y -= height
+}
+
+func (d *pdf) space(height float64) {
+	d.y -= height
+}
+
+func (d *pdf) write(x, size float64, bold bool, text string) {
+	font := "F1"
+	if bold {
+		font = "F2"
+	}
+	page := len(d.pages) - 1
+	d.pages[page] = append(d.pages[page], fmt.Sprintf("BT /%s %.1f Tf %.1f %.1f Td (%s) Tj ET", font, size, x, d.y, escapePDF(text)))
+}
+
+// text writes a line of text at x, wrapping long text over several lines
+func (d *pdf) text(x, size float64, bold bool, text string) {
+	if text == "" {
+		return
+	}
+	for _, line := range wrap(text, maxLineChars) {
+		d.advance(size * lineSpacing)
+		d.write(x, size, bold, line)
+	}
+}
+
+// row writes a table row; the first column is truncated to fit before the next one
+func (d *pdf) row(bold bool, cells ...string) {
+	d.advance(rowFontSize * lineSpacing)
+	for i, cell := range cells {
+		// Truncate on characters, as cutting bytes could split a multi-byte character
+		if runes := []rune(cell); i == 0 && len(cells) > 1 && len(runes) > 50 {
+			cell = string(runes[:47]) + "..."
+		}
+		if cell != "" {
+			d.write(tableColumns[i], rowFontSize, bold, cell)
+		}
+	}
+}
+
+// bytes serialises the document with its cross-reference table
+func (d *pdf) bytes() []byte {
+	var objects []string
+	pageRefs := make([]string, len(d.pages))
+
+	// Objects 1-4 are the catalog, the page tree and the two fonts; pages and their contents follow
+	for i, ops := range d.pages {
+		pageObj := 5 + 2*i
+		contentObj := pageObj + 1
+		pageRefs[i] = fmt.Sprintf("%d 0 R", pageObj)
+
+		stream := strings.Join(ops, "\n")
+		objects = append(objects,
+			fmt.Sprintf("<< /Type /Page /Parent 2 0 R /MediaBox [0 0 %.0f %.0f] /Contents %d 0 R /Resources << /Font << /F1 3 0 R /F2 4 0 R >> >> >>", pageWidth, pageHeight, contentObj),
+			fmt.Sprintf("<< /Length %d >>\nstream\n%s\nendstream", len(stream), stream),
+		)
+	}
+
+	objects = append([]string{
+		"<< /Type /Catalog /Pages 2 0 R >>",
+		fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(pageRefs, " "), len(d.pages)),
+		"<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica /Encoding /WinAnsiEncoding >>",
+		"<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica-Bold /Encoding /WinAnsiEncoding >>",
+	}, objects...)
+
+	var buf bytes.Buffer
+	buf.WriteString("%PDF-1.4\n")
+
+	offsets := make([]int, len(objects))
+	for i, obj := range objects {
+		offsets[i] = buf.Len()
+		fmt.Fprintf(&buf, "%d 0 obj\n%s\nendobj\n", i+1, obj)
+	}
+
+	xref := buf.Len()
+	fmt.Fprintf(&buf, "xref\n0 %d\n0000000000 65535 f \n", len(objects)+1)
+	for _, offset := range offsets {
+		fmt.Fprintf(&buf, "%010d 00000 n \n", offset)
+	}
+	fmt.Fprintf(&buf, "trailer\n<< /Size %d /Root 1 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(objects)+1, xref)
+
+	return buf.Bytes()
+}
+
+// escapePDF escapes a string for a PDF literal, replacing characters outside Latin-1
+func escapePDF(text string) string {
+	var b strings.Builder
+	for _, r := range text {
+		switch {
+		case r == '\\' || r == '(' || r == ')':
+			b.WriteByte('\\')
+			b.WriteRune(r)
+		case r == '\n' || r == '\r' || r == '\t':
+			b.WriteByte(' ')
+		case r < 32 || r > 255:
+			b.WriteByte('?')
+		default:
+			b.WriteByte(byte(r))
+		}
+	}
+	return b.String()
+}
+
+// wrap splits text into lines of at most width characters at word boundaries
+func wrap(text string, width int) []string {
+	var lines []string
+	for _, paragraph := range strings.Split(text, "\n") {
+		line := ""
+		for _, word := range strings.Fields(paragraph) {
+			if line != "" && utf8.RuneCountInString(line)+1+utf8.RuneCountInString(word) > width {
+				lines = append(lines, line)
+				line = ""
+			}
+			if line != "" {
+				line += " "
+			}
+			line += word
+		}
+		lines = append(lines, line)
+	}
+	return lines
+}
@@ -37,6 +37,8 @@ func CreateTables(db *sql.DB) error {
 		first_name VARCHAR(255),
 		last_name VARCHAR(255),
 		is_active BOOLEAN DEFAULT TRUE,
+		locale VARCHAR(35) NOT NULL DEFAULT '',
+		email_verified_at TIMESTAMP,
 		last_login TIMESTAMP,
 		created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
 		updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
@@ -55,6 +57,8 @@ func CreateTables(db *sql.DB) error {
     content_url TEXT[],  -- Change to TEXT[] for array of URLs
     outline TEXT,
     status VARCHAR(50) NOT NULL,
+    price DECIMAL(10, 2) NOT NULL DEFAULT 0,  -- 0 for free courses
+    currency VARCHAR(10) NOT NULL DEFAULT 'USD',
     created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
     updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
     deleted_at TIMESTAMP NULL
@@ -65,10 +69,9 @@ func CreateTables(db *sql.DB) error {
     name VARCHAR(255) NOT NULL,                         -- The name of the space
     description TEXT,                                   -- A brief description of the space
     coach_id UUID REFERENCES users(id) ON DELETE CASCADE, -- Foreign key to the users table (coach)
-    member_count UUID,                                  -- UUID for tracking the number of members (this can be adjusted if needed)
-    session_count UUID,                                 -- UUID for tracking the number of sessions (this can be adjusted if needed)
-    course_count UUID,                                  -- UUID for tracking the number of courses (this can be adjusted if needed)
     active BOOLEAN DEFAULT TRUE,                        -- Indicates if the space is active or disabled
+    membership_price DECIMAL(10, 2) NOT NULL DEFAULT 0, -- One-off price of joining the space
+    currency VARCHAR(10) NOT NULL DEFAULT 'USD',        -- Currency of the membership price
     created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,  -- Time of creation
     updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,  -- Time of last update
     deleted_at TIMESTAMP NULL                           -- Soft deletion timestamp
@@ -97,7 +100,7 @@ func CreateTables(db *sql.DB) error {
 `
 	paymentTable := `CREATE TABLE IF NOT EXISTS payments (
     id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
-    user_id UUID NOT NULL REFERENCES courses(id) ON DELETE CASCADE,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
     order_id UUID, 
     amount DECIMAL(10, 2) NOT NULL,
     currency VARCHAR(10) NOT NULL,
@@ -110,6 +113,389 @@ func CreateTables(db *sql.DB) error {
     created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
     updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
 );
+`
+
+	refundTable := `CREATE TABLE IF NOT EXISTS refunds (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    payment_id UUID NOT NULL REFERENCES payments(id) ON DELETE CASCADE,
+    amount DECIMAL(10, 2) NOT NULL CHECK (amount > 0),
+    currency VARCHAR(10) NOT NULL,
+    reason TEXT NOT NULL,
+    status VARCHAR(20) NOT NULL,
+    gateway_refund_id VARCHAR(100),
+    requested_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+`
+
+	orderTable := `CREATE TABLE IF NOT EXISTS orders (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    status VARCHAR(20) NOT NULL,
+    currency VARCHAR(10) NOT NULL,
+    subtotal DECIMAL(10, 2) NOT NULL DEFAULT 0,
+    discount DECIMAL(10, 2) NOT NULL DEFAULT 0,
+    coupon_code VARCHAR(50) NOT NULL DEFAULT '',
+    billing_name VARCHAR(255) NOT NULL DEFAULT '',
+    billing_address TEXT NOT NULL DEFAULT '',
+    total DECIMAL(10, 2) NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+`
+
+	orderLineTable := `CREATE TABLE IF NOT EXISTS order_lines (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    order_id UUID NOT NULL REFERENCES orders(id) ON DELETE CASCADE,
+    item_type VARCHAR(50) NOT NULL,      -- "course" or "space_membership"
+    item_id UUID NOT NULL,               -- ID of the course or space
+    description VARCHAR(255),
+    unit_price DECIMAL(10, 2) NOT NULL,
+    quantity INT NOT NULL DEFAULT 1,
+    amount DECIMAL(10, 2) NOT NULL
+);
+`
+
+	enrollmentTable := `CREATE TABLE IF NOT EXISTS enrollments (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    item_type VARCHAR(50) NOT NULL,      -- "course" or "space_membership"
+    item_id UUID NOT NULL,               -- ID of the course or space
+    order_id UUID REFERENCES orders(id) ON DELETE SET NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (user_id, item_type, item_id)
+);
+`
+
+	planTable := `CREATE TABLE IF NOT EXISTS plans (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    space_id UUID NOT NULL REFERENCES spaces(id) ON DELETE CASCADE,
+    name VARCHAR(255) NOT NULL,
+    price DECIMAL(10, 2) NOT NULL,
+    currency VARCHAR(10) NOT NULL,
+    interval VARCHAR(10) NOT NULL,       -- "week", "month" or "year"
+    trial_days INT NOT NULL DEFAULT 0,
+    active BOOLEAN NOT NULL DEFAULT TRUE,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+`
+
+	subscriptionTable := `CREATE TABLE IF NOT EXISTS subscriptions (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    plan_id UUID NOT NULL REFERENCES plans(id),
+    space_id UUID NOT NULL REFERENCES spaces(id) ON DELETE CASCADE,
+    status VARCHAR(20) NOT NULL,
+    payment_method VARCHAR(50) NOT NULL,
+    payment_gateway VARCHAR(50),
+    current_period_start TIMESTAMP NOT NULL,
+    current_period_end TIMESTAMP NOT NULL,  -- Renewal date
+    trial_end TIMESTAMP,
+    cancel_at_period_end BOOLEAN NOT NULL DEFAULT FALSE,
+    cancelled_at TIMESTAMP,
+    failed_attempts INT NOT NULL DEFAULT 0,
+    next_retry_at TIMESTAMP,                -- Next dunning retry when past due
+    locked_until TIMESTAMP,                 -- Lease of the instance renewing the subscription
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+`
+
+	couponTable := `CREATE TABLE IF NOT EXISTS coupons (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    code VARCHAR(50) UNIQUE NOT NULL,
+    discount_type VARCHAR(20) NOT NULL,  -- "percentage" or "fixed"
+    value DECIMAL(10, 2) NOT NULL,
+    currency VARCHAR(10) NOT NULL DEFAULT '',
+    expires_at TIMESTAMP,
+    max_redemptions INT NOT NULL DEFAULT 0,  -- 0 for unlimited
+    per_user_limit INT NOT NULL DEFAULT 0,   -- 0 for unlimited
+    redemption_count INT NOT NULL DEFAULT 0,
+    course_ids UUID[],
+    space_ids UUID[],
+    active BOOLEAN NOT NULL DEFAULT TRUE,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+`
+
+	couponRedemptionTable := `CREATE TABLE IF NOT EXISTS coupon_redemptions (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    coupon_id UUID NOT NULL REFERENCES coupons(id) ON DELETE CASCADE,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    order_id UUID NOT NULL REFERENCES orders(id) ON DELETE CASCADE,
+    discount DECIMAL(10, 2) NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+`
+
+	invoiceSequenceTable := `CREATE TABLE IF NOT EXISTS invoice_sequences (
+    year INT PRIMARY KEY,
+    last_number BIGINT NOT NULL          -- Last invoice number issued in the year
+);
+`
+
+	invoiceTable := `CREATE TABLE IF NOT EXISTS invoices (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    number VARCHAR(50) UNIQUE NOT NULL,
+    payment_id UUID UNIQUE NOT NULL REFERENCES payments(id) ON DELETE RESTRICT,
+    order_id UUID,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE RESTRICT,
+    billing_name VARCHAR(255) NOT NULL,
+    billing_address TEXT NOT NULL DEFAULT '',
+    currency VARCHAR(10) NOT NULL,
+    subtotal DECIMAL(10, 2) NOT NULL,
+    discount DECIMAL(10, 2) NOT NULL DEFAULT 0,
+    tax_rate DECIMAL(5, 2) NOT NULL DEFAULT 0,
+    tax DECIMAL(10, 2) NOT NULL DEFAULT 0,
+    total DECIMAL(10, 2) NOT NULL,
+    issued_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+`
+
+	invoiceLineTable := `CREATE TABLE IF NOT EXISTS invoice_lines (
+    invoice_id UUID NOT NULL REFERENCES invoices(id) ON DELETE CASCADE,
+    position INT NOT NULL,
+    description VARCHAR(255) NOT NULL,
+    quantity INT NOT NULL DEFAULT 1,
+    unit_price DECIMAL(10, 2) NOT NULL,
+    amount DECIMAL(10, 2) NOT NULL,
+    PRIMARY KEY (invoice_id, position)
+);
+`
+
+	ledgerTransactionTable := `CREATE TABLE IF NOT EXISTS ledger_transactions (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    reference_type VARCHAR(20) NOT NULL, -- "payment", "refund", "payout" or "payout_reversal"
+    reference_id UUID NOT NULL,
+    description VARCHAR(255),
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (reference_type, reference_id)
+);
+`
+
+	ledgerEntryTable := `CREATE TABLE IF NOT EXISTS ledger_entries (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    transaction_id UUID NOT NULL REFERENCES ledger_transactions(id) ON DELETE RESTRICT,
+    account VARCHAR(30) NOT NULL,        -- "cash", "platform_fees" or "creator_payable"
+    owner_id UUID NOT NULL,              -- Coach or instructor, the nil UUID for the platform
+    currency VARCHAR(10) NOT NULL,
+    debit DECIMAL(12, 2) NOT NULL DEFAULT 0,
+    credit DECIMAL(12, 2) NOT NULL DEFAULT 0
+);
+CREATE INDEX IF NOT EXISTS ledger_entries_owner_idx ON ledger_entries (owner_id, account);
+`
+
+	payoutTable := `CREATE TABLE IF NOT EXISTS payouts (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE RESTRICT,
+    amount DECIMAL(12, 2) NOT NULL,
+    currency VARCHAR(10) NOT NULL,
+    status VARCHAR(20) NOT NULL,         -- "pending", "paid" or "failed"
+    reference VARCHAR(255) NOT NULL DEFAULT '',
+    period VARCHAR(10) NOT NULL DEFAULT '',  -- Day the payout was scheduled; empty for payouts scheduled before it was recorded
+    paid_at TIMESTAMP,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+`
+
+	reconciliationTable := `CREATE TABLE IF NOT EXISTS reconciliations (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    gateway VARCHAR(50) NOT NULL DEFAULT '',
+    file_name VARCHAR(255) NOT NULL,
+    period_start TIMESTAMP NOT NULL,
+    period_end TIMESTAMP NOT NULL,
+    row_count INT NOT NULL DEFAULT 0,
+    matched_count INT NOT NULL DEFAULT 0,
+    discrepancy_count INT NOT NULL DEFAULT 0,
+    created_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+`
+
+	reconciliationDiscrepancyTable := `CREATE TABLE IF NOT EXISTS reconciliation_discrepancies (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    reconciliation_id UUID NOT NULL REFERENCES reconciliations(id) ON DELETE CASCADE,
+    type VARCHAR(30) NOT NULL,           -- "missing_payment", "missing_settlement", "duplicated" or "amount_mismatch"
+    transaction_id VARCHAR(100) NOT NULL,
+    payment_id UUID REFERENCES payments(id) ON DELETE SET NULL,
+    expected_amount DECIMAL(10, 2) NOT NULL DEFAULT 0,
+    settled_amount DECIMAL(10, 2) NOT NULL DEFAULT 0,
+    currency VARCHAR(10) NOT NULL,
+    occurred_at TIMESTAMP NOT NULL,
+    details TEXT NOT NULL DEFAULT ''
+);
+`
+
+	spaceMemberTable := `CREATE TABLE IF NOT EXISTS space_members (
+    space_id UUID NOT NULL REFERENCES spaces(id) ON DELETE CASCADE,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    role VARCHAR(20) NOT NULL,           -- "owner", "co_coach" or "member"
+    invited_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    joined_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (space_id, user_id)
+);
+`
+
+	spaceInvitationTable := `CREATE TABLE IF NOT EXISTS space_invitations (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    space_id UUID NOT NULL REFERENCES spaces(id) ON DELETE CASCADE,
+    token VARCHAR(64) UNIQUE NOT NULL,
+    role VARCHAR(20) NOT NULL,           -- Role granted on joining
+    max_uses INT NOT NULL DEFAULT 0,     -- 0 for unlimited
+    use_count INT NOT NULL DEFAULT 0,
+    created_by UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    expires_at TIMESTAMP NOT NULL,
+    revoked_at TIMESTAMP,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+`
+
+	// Discussion threads of the community area of spaces
+	spacePostTable := `CREATE TABLE IF NOT EXISTS space_posts (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    space_id UUID NOT NULL REFERENCES spaces(id) ON DELETE CASCADE,
+    author_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    title VARCHAR(255) NOT NULL,
+    body TEXT NOT NULL,
+    pinned_at TIMESTAMP,                 -- Set while the post is pinned by the coach
+    hidden_at TIMESTAMP,                 -- Set while the post is hidden by a moderator
+    hidden_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    hidden_reason TEXT NOT NULL DEFAULT '',
+    edited_at TIMESTAMP,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    deleted_at TIMESTAMP
+);
+CREATE INDEX IF NOT EXISTS space_posts_space_idx ON space_posts (space_id);
+`
+
+	spacePostCommentTable := `CREATE TABLE IF NOT EXISTS space_post_comments (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    post_id UUID NOT NULL REFERENCES space_posts(id) ON DELETE CASCADE,
+    parent_id UUID REFERENCES space_post_comments(id) ON DELETE CASCADE, -- Comment replied to
+    author_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    body TEXT NOT NULL,
+    hidden_at TIMESTAMP,
+    hidden_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    hidden_reason TEXT NOT NULL DEFAULT '',
+    edited_at TIMESTAMP,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    deleted_at TIMESTAMP
+);
+CREATE INDEX IF NOT EXISTS space_post_comments_post_idx ON space_post_comments (post_id);
+`
+
+	spacePostReactionTable := `CREATE TABLE IF NOT EXISTS space_post_reactions (
+    target_type VARCHAR(20) NOT NULL,    -- "post" or "comment"
+    target_id UUID NOT NULL,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    emoji VARCHAR(32) NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (target_type, target_id, user_id, emoji)
+);
+`
+
+	// Previous contents of edited posts and comments
+	spacePostRevisionTable := `CREATE TABLE IF NOT EXISTS space_post_revisions (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    target_type VARCHAR(20) NOT NULL,
+    target_id UUID NOT NULL,
+    title VARCHAR(255) NOT NULL DEFAULT '',
+    body TEXT NOT NULL,
+    edited_by UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    edited_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+CREATE INDEX IF NOT EXISTS space_post_revisions_target_idx ON space_post_revisions (target_type, target_id);
+`
+
+	notificationTable := `CREATE TABLE IF NOT EXISTS notifications (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    category VARCHAR(30) NOT NULL DEFAULT 'space', -- Category users choose their notifications by
+    type VARCHAR(50) NOT NULL,
+    title VARCHAR(255) NOT NULL,
+    body TEXT NOT NULL DEFAULT '',
+    link TEXT NOT NULL DEFAULT '',       -- Path of the resource the notification is about
+    read_at TIMESTAMP,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+CREATE INDEX IF NOT EXISTS notifications_user_idx ON notifications (user_id, created_at);
+`
+
+	// Categories of notifications users turned on or off per channel; missing rows use the defaults
+	notificationPreferenceTable := `CREATE TABLE IF NOT EXISTS notification_preferences (
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    category VARCHAR(30) NOT NULL,
+    channel VARCHAR(20) NOT NULL,        -- "in_app" or "email"
+    enabled BOOLEAN NOT NULL,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (user_id, category, channel)
+);
+`
+
+	// Private conversations between users
+	conversationTable := `CREATE TABLE IF NOT EXISTS conversations (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    title VARCHAR(255) NOT NULL DEFAULT '',
+    is_group BOOLEAN NOT NULL DEFAULT FALSE,
+    direct_key VARCHAR(80) UNIQUE,       -- Pair of users of a direct conversation, NULL for groups
+    created_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP  -- Time of the last message
+);
+`
+
+	conversationParticipantTable := `CREATE TABLE IF NOT EXISTS conversation_participants (
+    conversation_id UUID NOT NULL REFERENCES conversations(id) ON DELETE CASCADE,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    last_read_at TIMESTAMP,              -- Read receipt: messages sent until then were read
+    joined_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (conversation_id, user_id)
+);
+CREATE INDEX IF NOT EXISTS conversation_participants_user_idx ON conversation_participants (user_id);
+`
+
+	messageTable := `CREATE TABLE IF NOT EXISTS messages (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    conversation_id UUID NOT NULL REFERENCES conversations(id) ON DELETE CASCADE,
+    sender_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    body TEXT NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+CREATE INDEX IF NOT EXISTS messages_conversation_idx ON messages (conversation_id, created_at, id);
+`
+
+	// Emails are queued here and sent by a background job, which retries failures with backoff
+	emailOutboxTable := `CREATE TABLE IF NOT EXISTS email_outbox (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    recipient VARCHAR(255) NOT NULL,
+    subject VARCHAR(255) NOT NULL,
+    html_body TEXT NOT NULL,
+    text_body TEXT NOT NULL DEFAULT '',
+    template VARCHAR(50) NOT NULL,       -- Template the email was rendered from
+    status VARCHAR(20) NOT NULL,         -- "pending", "sent", "failed" or "suppressed"
+    attempts INT NOT NULL DEFAULT 0,
+    last_error TEXT NOT NULL DEFAULT '',
+    next_attempt_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    sent_at TIMESTAMP,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+CREATE INDEX IF NOT EXISTS email_outbox_due_idx ON email_outbox (next_attempt_at) WHERE status = 'pending';
+`
+
+	// Addresses that bounced, complained or were blocked by an admin; stored lowercase
+	emailSuppressionTable := `CREATE TABLE IF NOT EXISTS email_suppressions (
+    email VARCHAR(255) PRIMARY KEY,
+    reason VARCHAR(20) NOT NULL,         -- "bounce", "complaint" or "manual"
+    detail TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
 `
 
 	// Create tokens table
@@ -120,13 +506,105 @@ func CreateTables(db *sql.DB) error {
 		expires_at TIMESTAMP NOT NULL,
 		created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
 		updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
-		deleted_at TIMESTAMP NULL
-	);`
+		deleted_at TIMESTAMP NULL,
+		user_agent VARCHAR(512) NOT NULL DEFAULT '',
+		ip VARCHAR(45) NOT NULL DEFAULT '',
+		device_label VARCHAR(100) NOT NULL DEFAULT '',
+		last_seen_at TIMESTAMP NULL
+	);
+CREATE INDEX IF NOT EXISTS tokens_user_id_idx ON tokens (user_id);
+`
+
+	// Single-use tokens emailed to users, such as email verification and password reset links; only their hash is stored
+	oneTimeTokenTable := `CREATE TABLE IF NOT EXISTS one_time_tokens (
+    id UUID PRIMARY KEY,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    purpose VARCHAR(30) NOT NULL,        -- "email_verification", "password_reset" or "login_challenge"
+    token_hash VARCHAR(64) UNIQUE NOT NULL,
+    expires_at TIMESTAMP NOT NULL,
+    used_at TIMESTAMP,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+CREATE INDEX IF NOT EXISTS one_time_tokens_user_id_idx ON one_time_tokens (user_id, purpose, created_at);
+`
+
+	// Consecutive failed sign-ins per account and per IP address
+	loginThrottleTable := `CREATE TABLE IF NOT EXISTS login_throttles (
+    scope VARCHAR(10) NOT NULL,          -- "account" or "ip"
+    subject VARCHAR(255) NOT NULL,       -- lower-cased email or IP address
+    failures INT NOT NULL DEFAULT 0,
+    last_failed_at TIMESTAMP NOT NULL,
+    locked_until TIMESTAMP,
+    PRIMARY KEY (scope, subject)
+);
+`
+
+	// TOTP authenticators; the secret is encrypted with a key derived from the token secret
+	twoFactorTable := `CREATE TABLE IF NOT EXISTS user_two_factors (
+    user_id UUID PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+    secret TEXT NOT NULL,
+    confirmed_at TIMESTAMP,              -- NULL until a first code is checked
+    last_used_step BIGINT NOT NULL DEFAULT 0,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+`
+
+	// Single-use codes signing in users who lost their authenticator; only their hash is stored
+	recoveryCodeTable := `CREATE TABLE IF NOT EXISTS recovery_codes (
+    id UUID PRIMARY KEY,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    code_hash VARCHAR(64) NOT NULL,
+    used_at TIMESTAMP,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+CREATE INDEX IF NOT EXISTS recovery_codes_user_id_idx ON recovery_codes (user_id);
+`
+
+	// Accounts at OpenID Connect identity providers users sign in with
+	userIdentityTable := `CREATE TABLE IF NOT EXISTS user_identities (
+    id UUID PRIMARY KEY,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    provider VARCHAR(50) NOT NULL,
+    subject VARCHAR(255) NOT NULL,       -- stable ID of the account at the provider
+    email VARCHAR(255) NOT NULL DEFAULT '',
+    last_login_at TIMESTAMP,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (provider, subject)
+);
+CREATE INDEX IF NOT EXISTS user_identities_user_id_idx ON user_identities (user_id);
+`
+
+	// OpenID Connect sign-ins waiting for the user to come back from the provider
+	oidcStateTable := `CREATE TABLE IF NOT EXISTS oidc_states (
+    state_hash VARCHAR(64) PRIMARY KEY,
+    provider VARCHAR(50) NOT NULL,
+    nonce VARCHAR(100) NOT NULL,
+    code_verifier VARCHAR(128) NOT NULL,
+    expires_at TIMESTAMP NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+`
+
+	// Personal API keys of users; only their hash is stored
+	apiKeyTable := `CREATE TABLE IF NOT EXISTS api_keys (
+    id UUID PRIMARY KEY,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    name VARCHAR(100) NOT NULL,
+    prefix VARCHAR(20) NOT NULL,         -- start of the key, shown to recognize it
+    key_hash VARCHAR(64) UNIQUE NOT NULL,
+    scopes TEXT[] NOT NULL,
+    expires_at TIMESTAMP NOT NULL,
+    last_used_at TIMESTAMP,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+CREATE INDEX IF NOT EXISTS api_keys_user_id_idx ON api_keys (user_id);
+`
 
 	// Create roles table
 	roleTable := `CREATE TABLE IF NOT EXISTS roles (
 		id SERIAL PRIMARY KEY,
-		name VARCHAR(255) UNIQUE NOT NULL
+		name VARCHAR(255) UNIQUE NOT NULL,
+		require_two_factor BOOLEAN NOT NULL DEFAULT FALSE
 	);`
 
 	// Create permissions table
@@ -150,13 +628,70 @@ func CreateTables(db *sql.DB) error {
 	);`
 
 	// Execute the table creation queries
-	queries := []string{userTable, tokenTable, roleTable, permissionTable, userRoleTable, userPermissionTable, courseTable, spaceTable, meetingTable, paymentTable}
+	queries := []string{userTable, tokenTable, oneTimeTokenTable, loginThrottleTable, twoFactorTable, recoveryCodeTable, userIdentityTable, oidcStateTable, apiKeyTable, roleTable, permissionTable, userRoleTable, userPermissionTable, courseTable, spaceTable, meetingTable, paymentTable, refundTable, orderTable, orderLineTable, enrollmentTable, planTable, subscriptionTable, couponTable, couponRedemptionTable, invoiceSequenceTable, invoiceTable, invoiceLineTable, ledgerTransactionTable, ledgerEntryTable, payoutTable, reconciliationTable, reconciliationDiscrepancyTable, spaceMemberTable, spaceInvitationTable, spacePostTable, spacePostCommentTable, spacePostReactionTable, spacePostRevisionTable, notificationTable, notificationPreferenceTable, conversationTable, conversationParticipantTable, messageTable, emailOutboxTable, emailSuppressionTable}
 	for _, query := range queries {
 		if _, err := db.Exec(query); err != nil {
 			return fmt.Errorf("failed to create table: %v", err)
 		}
 	}
 
+	// Add columns introduced after the tables were first created
+	alterations := []string{
+		`ALTER TABLE courses ADD COLUMN IF NOT EXISTS price DECIMAL(10, 2) NOT NULL DEFAULT 0`,
+		`ALTER TABLE courses ADD COLUMN IF NOT EXISTS currency VARCHAR(10) NOT NULL DEFAULT 'USD'`,
+		`ALTER TABLE spaces ADD COLUMN IF NOT EXISTS membership_price DECIMAL(10, 2) NOT NULL DEFAULT 0`,
+		`ALTER TABLE spaces ADD COLUMN IF NOT EXISTS currency VARCHAR(10) NOT NULL DEFAULT 'USD'`,
+		// payments.user_id used to reference courses(id), which rejected every checkout payment
+		`ALTER TABLE payments DROP CONSTRAINT IF EXISTS payments_user_id_fkey`,
+		`ALTER TABLE payments ADD CONSTRAINT payments_user_id_fkey FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE`,
+		`ALTER TABLE orders ADD COLUMN IF NOT EXISTS subtotal DECIMAL(10, 2) NOT NULL DEFAULT 0`,
+		`ALTER TABLE orders ADD COLUMN IF NOT EXISTS discount DECIMAL(10, 2) NOT NULL DEFAULT 0`,
+		`ALTER TABLE orders ADD COLUMN IF NOT EXISTS coupon_code VARCHAR(50) NOT NULL DEFAULT ''`,
+		`ALTER TABLE orders ADD COLUMN IF NOT EXISTS billing_name VARCHAR(255) NOT NULL DEFAULT ''`,
+		`ALTER TABLE orders ADD COLUMN IF NOT EXISTS billing_address TEXT NOT NULL DEFAULT ''`,
+		// The member count is now derived from space_members
+		`ALTER TABLE spaces DROP COLUMN IF EXISTS member_count`,
+		// Coaches own the spaces created before memberships were recorded
+		`INSERT INTO space_members (space_id, user_id, role) SELECT id, coach_id, 'owner' FROM spaces WHERE coach_id IS NOT NULL ON CONFLICT DO NOTHING`,
+		// Courses and meetings belong to spaces, whose counts are derived from them
+		`ALTER TABLE courses ADD COLUMN IF NOT EXISTS space_id UUID REFERENCES spaces(id) ON DELETE SET NULL`,
+		`ALTER TABLE courses ADD COLUMN IF NOT EXISTS members_only BOOLEAN NOT NULL DEFAULT FALSE`,
+		`ALTER TABLE meetings ADD COLUMN IF NOT EXISTS space_id UUID REFERENCES spaces(id) ON DELETE SET NULL`,
+		`ALTER TABLE meetings ADD COLUMN IF NOT EXISTS members_only BOOLEAN NOT NULL DEFAULT FALSE`,
+		`CREATE INDEX IF NOT EXISTS idx_courses_space_id ON courses(space_id)`,
+		`CREATE INDEX IF NOT EXISTS idx_meetings_space_id ON meetings(space_id)`,
+		`ALTER TABLE spaces DROP COLUMN IF EXISTS session_count`,
+		`ALTER TABLE spaces DROP COLUMN IF EXISTS course_count`,
+		`ALTER TABLE notifications ADD COLUMN IF NOT EXISTS category VARCHAR(30) NOT NULL DEFAULT 'space'`,
+		`ALTER TABLE roles ADD COLUMN IF NOT EXISTS require_two_factor BOOLEAN NOT NULL DEFAULT FALSE`,
+		`ALTER TABLE subscriptions ADD COLUMN IF NOT EXISTS locked_until TIMESTAMP`,
+		// Every instance schedules payouts, so a creator is paid once per currency and day whichever runs first
+		`ALTER TABLE payouts ADD COLUMN IF NOT EXISTS period VARCHAR(10) NOT NULL DEFAULT ''`,
+		`CREATE UNIQUE INDEX IF NOT EXISTS payouts_period_idx ON payouts (user_id, currency, period) WHERE period <> ''`,
+		`ALTER TABLE tokens ADD COLUMN IF NOT EXISTS user_agent VARCHAR(512) NOT NULL DEFAULT ''`,
+		`ALTER TABLE tokens ADD COLUMN IF NOT EXISTS ip VARCHAR(45) NOT NULL DEFAULT ''`,
+		`ALTER TABLE tokens ADD COLUMN IF NOT EXISTS device_label VARCHAR(100) NOT NULL DEFAULT ''`,
+		`ALTER TABLE tokens ADD COLUMN IF NOT EXISTS last_seen_at TIMESTAMP NULL`,
+		`ALTER TABLE users ADD COLUMN IF NOT EXISTS locale VARCHAR(35) NOT NULL DEFAULT ''`,
+		// Accounts created before emails were verified are trusted
+		`DO $$ BEGIN
+			IF NOT EXISTS (SELECT 1 FROM information_schema.columns WHERE table_name = 'users' AND column_name = 'email_verified_at') THEN
+				ALTER TABLE users ADD COLUMN email_verified_at TIMESTAMP;
+				UPDATE users SET email_verified_at = created_at;
+			END IF;
+		END $$`,
+	}
+	for _, query := range alterations {
+		if _, err := db.Exec(query); err != nil {
+			return fmt.Errorf("failed to alter table: %v", err)
+		}
+	}
+
+	// Seed the roles the application checks for
+	if _, err := db.Exec(`INSERT INTO roles (name) VALUES ('admin') ON CONFLICT (name) DO NOTHING`); err != nil {
+		return fmt.Errorf("failed to seed roles: %v", err)
+	}
+
 	log.Println("Successfully created all tables")
 	return nil
 }
@@ -0,0 +1,49 @@
+package job
+
+import (
+	"log"
+	"sync"
+	"time"
+)
+
+// Scheduler runs background jobs at fixed intervals until it is stopped
+type Scheduler struct {
+	stop chan struct{}
+	wg   sync.WaitGroup
+}
+
+// NewScheduler creates a new Scheduler
+func NewScheduler() *Scheduler {
+	return &Scheduler{stop: make(chan struct{})}
+}
+
+// Every runs fn once immediately and then every interval in its own goroutine.
+// Errors are logged and the job keeps running.
+func (s *Scheduler) Every(name string, interval time.Duration, fn func() error) {
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			if err := fn(); err != nil {
+				log.Printf("Job %s failed: %v", name, err)
+			}
+
+			select {
+			case <-ticker.C:
+			case <-s.stop:
+				return
+			}
+		}
+	}()
+	log.Printf("Scheduled job %s every %s", name, interval)
+}
+
+// Stop signals every job to stop and waits for running executions to finish
+func (s *Scheduler) Stop() {
+	close(s.stop)
+	s.wg.Wait()
+}
@@ -0,0 +1,34 @@
+package mail
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// FileMailer writes emails as .eml files to a directory instead of sending them, for development
+type FileMailer struct {
+	dir string
+}
+
+// NewFileMailer creates a new FileMailer writing to dir, which is created when missing
+func NewFileMailer(dir string) (*FileMailer, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create mail directory %s: %v", dir, err)
+	}
+	return &FileMailer{dir: dir}, nil
+}
+
+// Send implements Mailer.
+func (m *FileMailer) Send(message *Message) error {
+	data, err := message.Bytes()
+	if err != nil {
+		return err
+	}
+
+	recipient := strings.NewReplacer("@", "_at_", "/", "_", "\\", "_").Replace(message.To)
+	name := fmt.Sprintf("%s-%s.eml", time.Now().Format("20060102T150405.000000000"), recipient)
+	return os.WriteFile(filepath.Join(m.dir, name), data, 0o644)
+}
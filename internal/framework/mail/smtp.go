@@ -0,0 +1,37 @@
+package mail
+
+import (
+	"net"
+	netmail "net/mail"
+	"net/smtp"
+)
+
+// SMTPMailer sends emails through an SMTP server, with STARTTLS when the server offers it
+type SMTPMailer struct {
+	addr string
+	auth smtp.Auth
+}
+
+// NewSMTPMailer creates a new SMTPMailer; no authentication is attempted without a username
+func NewSMTPMailer(host, port, username, password string) *SMTPMailer {
+	mailer := &SMTPMailer{addr: net.JoinHostPort(host, port)}
+	if username != "" {
+		mailer.auth = smtp.PlainAuth("", username, password, host)
+	}
+	return mailer
+}
+
+// Send implements Mailer.
+func (m *SMTPMailer) Send(message *Message) error {
+	data, err := message.Bytes()
+	if err != nil {
+		return err
+	}
+
+	// The envelope sender is the bare address of a "Name <address>" sender
+	sender := message.From
+	if address, err := netmail.ParseAddress(message.From); err == nil {
+		sender = address.Address
+	}
+	return smtp.SendMail(m.addr, m.auth, sender, []string{message.To}, data)
+}
@@ -0,0 +1,106 @@
+package mail
+
+import (
+	"bytes"
+	"embed"
+	"fmt"
+	htmltemplate "html/template"
+	"io/fs"
+	"strings"
+	texttemplate "text/template"
+)
+
+// templateFS holds a layout shared by every email and, per locale, one file per email defining its
+// "subject", "text" and "body" blocks
+//
+//go:embed templates
+var templateFS embed.FS
+
+// Rendered is an email template rendered for a recipient
+type Rendered struct {
+	Subject string
+	HTML    string
+	Text    string
+}
+
+// emailTemplate is an email parsed for HTML, into the layout, and for plain text
+type emailTemplate struct {
+	html *htmltemplate.Template
+	text *texttemplate.Template
+}
+
+// Templates renders the emails of the application in the language of their recipient
+type Templates struct {
+	defaultLocale string
+	locales       map[string]map[string]*emailTemplate // Locale, then email name
+}
+
+// NewTemplates parses the embedded templates; emails missing in a locale use the default locale
+func NewTemplates(defaultLocale string) (*Templates, error) {
+	t := &Templates{defaultLocale: normalizeLocale(defaultLocale), locales: map[string]map[string]*emailTemplate{}}
+
+	files, err := fs.Glob(templateFS, "templates/*/*.html")
+	if err != nil {
+		return nil, err
+	}
+	for _, file := range files {
+		parts := strings.Split(strings.TrimSuffix(file, ".html"), "/")
+		locale, name := parts[1], parts[2]
+
+		html, err := htmltemplate.ParseFS(templateFS, "templates/layout.html", file)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse email template %s: %v", file, err)
+		}
+		text, err := texttemplate.ParseFS(templateFS, file)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse email template %s: %v", file, err)
+		}
+
+		if t.locales[locale] == nil {
+			t.locales[locale] = map[string]*emailTemplate{}
+		}
+		t.locales[locale][name] = &emailTemplate{html: html, text: text}
+	}
+
+	if len(t.locales[t.defaultLocale]) == 0 {
+		return nil, fmt.Errorf("no email templates for default locale %q", t.defaultLocale)
+	}
+	return t, nil
+}
+
+// Render renders an email in a locale such as "fr" or "fr-CA", falling back to the default locale
+func (t *Templates) Render(name, locale string, data interface{}) (*Rendered, error) {
+	tmpl, ok := t.locales[normalizeLocale(locale)][name]
+	if !ok {
+		tmpl, ok = t.locales[t.defaultLocale][name]
+	}
+	if !ok {
+		return nil, fmt.Errorf("unknown email template %q", name)
+	}
+
+	var subject, text, html bytes.Buffer
+	if err := tmpl.text.ExecuteTemplate(&subject, "subject", data); err != nil {
+		return nil, err
+	}
+	if err := tmpl.text.ExecuteTemplate(&text, "text", data); err != nil {
+		return nil, err
+	}
+	if err := tmpl.html.ExecuteTemplate(&html, "layout.html", data); err != nil {
+		return nil, err
+	}
+
+	return &Rendered{
+		Subject: strings.TrimSpace(subject.String()),
+		HTML:    html.String(),
+		Text:    strings.TrimSpace(text.String()),
+	}, nil
+}
+
+// normalizeLocale keeps the language of a locale: "fr-CA" and "fr_CA" become "fr"
+func normalizeLocale(locale string) string {
+	locale = strings.ToLower(strings.TrimSpace(locale))
+	if i := strings.IndexAny(locale, "-_"); i >= 0 {
+		locale = locale[:i]
+	}
+	return locale
+}
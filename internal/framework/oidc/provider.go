@@ -0,0 +1,384 @@
+package oidc
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"dalabio/pkg/config"
+
+	"github.com/golang-jwt/jwt/v4"
+)
+
+// keyRefreshInterval is how often the signing keys of a provider may be fetched again for an unknown key ID,
+// so that tokens with made-up key IDs cannot make the server hammer the provider
+const keyRefreshInterval = time.Minute
+
+// ErrInvalidIDToken is returned for an ID token that is malformed, forged, expired or issued to another client
+var ErrInvalidIDToken = errors.New("invalid ID token")
+
+// Claims are the claims of an ID token the application relies on
+type Claims struct {
+	jwt.RegisteredClaims
+	Nonce             string       `json:"nonce"`
+	Email             string       `json:"email"`
+	EmailVerified     flexibleBool `json:"email_verified"`
+	Name              string       `json:"name"`
+	GivenName         string       `json:"given_name"`
+	FamilyName        string       `json:"family_name"`
+	PreferredUsername string       `json:"preferred_username"`
+	Locale            string       `json:"locale"`
+}
+
+// flexibleBool accepts the booleans some providers send as strings
+type flexibleBool bool
+
+func (b *flexibleBool) UnmarshalJSON(data []byte) error {
+	switch strings.Trim(string(data), `"`) {
+	case "true":
+		*b = true
+	case "false", "null":
+		*b = false
+	default:
+		return fmt.Errorf("invalid boolean %s", data)
+	}
+	return nil
+}
+
+// Provider is an OpenID Connect identity provider users sign in with, through the authorization code flow with PKCE.
+// Its endpoints and signing keys are discovered on first use.
+type Provider struct {
+	config config.OIDCProviderConfig
+	client *http.Client
+
+	mu            sync.Mutex
+	endpoints     *discovery
+	keys          map[string]crypto.PublicKey
+	keysFetchedAt time.Time
+}
+
+// discovery is the part of the discovery document of a provider the flow uses
+type discovery struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	JWKSURI               string `json:"jwks_uri"`
+}
+
+// NewProvider creates a provider from its configuration
+func NewProvider(cfg config.OIDCProviderConfig, client *http.Client) *Provider {
+	return &Provider{config: cfg, client: client}
+}
+
+// Name identifies the provider in the URLs
+func (p *Provider) Name() string {
+	return p.config.Name
+}
+
+// AuthorizationURL returns the URL of the provider's sign-in page
+func (p *Provider) AuthorizationURL(state, nonce, codeChallenge string) (string, error) {
+	endpoints, err := p.discover()
+	if err != nil {
+		return "", err
+	}
+
+	query := url.Values{}
+	query.Set("response_type", "code")
+	query.Set("client_id", p.config.ClientID)
+	query.Set("redirect_uri", p.config.RedirectURL)
+	query.Set("scope", strings.Join(p.config.Scopes, " "))
+	query.Set("state", state)
+	query.Set("nonce", nonce)
+	query.Set("code_challenge", codeChallenge)
+	query.Set("code_challenge_method", "S256")
+
+	separator := "?"
+	if strings.Contains(endpoints.AuthorizationEndpoint, "?") {
+		separator = "&"
+	}
+	return endpoints.AuthorizationEndpoint + separator + query.Encode(), nil
+}
+
+// Exchange redeems an authorization code and returns the claims of the verified ID token. The nonce must be the
+// one sent with the authorization request, which proves the token was issued for it.
+func (p *Provider) Exchange(code, codeVerifier, nonce string) (*Claims, error) {
+	endpoints, err := p.discover()
+	if err != nil {
+		return nil, err
+	}
+
+	form := url.Values{}
+	form.Set("grant_type", "authorization_code")
+	form.Set("code", code)
+	form.Set("redirect_uri", p.config.RedirectURL)
+	form.Set("code_verifier", codeVerifier)
+	if p.config.ClientSecret == "" {
+		// Public clients identify themselves in the body
+		form.Set("client_id", p.config.ClientID)
+	}
+	request, err := http.NewRequest(http.MethodPost, endpoints.TokenEndpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	request.Header.Set("Accept", "application/json")
+	if p.config.ClientSecret != "" {
+		request.SetBasicAuth(url.QueryEscape(p.config.ClientID), url.QueryEscape(p.config.ClientSecret))
+	}
+
+	var tokens struct {
+		IDToken          string `json:"id_token"`
+		Error            string `json:"error"`
+		ErrorDescription string `json:"error_description"`
+	}
+	status, err := p.doJSON(request, &tokens)
+	if err != nil {
+		return nil, fmt.Errorf("failed to redeem authorization code: %w", err)
+	}
+	if status != http.StatusOK || tokens.IDToken == "" {
+		return nil, fmt.Errorf("provider %s refused the authorization code: %s %s", p.config.Name, tokens.Error, tokens.ErrorDescription)
+	}
+
+	claims, err := p.verify(tokens.IDToken, endpoints)
+	if err != nil {
+		return nil, err
+	}
+	if claims.Nonce != nonce {
+		return nil, ErrInvalidIDToken
+	}
+	return claims, nil
+}
+
+// verify checks the signature, issuer, audience and lifetime of an ID token
+func (p *Provider) verify(idToken string, endpoints *discovery) (*Claims, error) {
+	var claims Claims
+	parsed, err := jwt.ParseWithClaims(idToken, &claims, func(token *jwt.Token) (interface{}, error) {
+		kid, _ := token.Header["kid"].(string)
+		return p.key(kid, endpoints)
+	}, jwt.WithValidMethods([]string{"RS256", "RS384", "RS512", "PS256", "ES256", "ES384", "EdDSA"}))
+	if err != nil || !parsed.Valid {
+		return nil, ErrInvalidIDToken
+	}
+	if claims.Issuer != endpoints.Issuer || !claims.VerifyAudience(p.config.ClientID, true) ||
+		claims.ExpiresAt == nil || claims.Subject == "" {
+		return nil, ErrInvalidIDToken
+	}
+	return &claims, nil
+}
+
+// discover fetches the discovery document of the provider once
+func (p *Provider) discover() (*discovery, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.endpoints != nil {
+		return p.endpoints, nil
+	}
+
+	request, err := http.NewRequest(http.MethodGet, p.config.Issuer+"/.well-known/openid-configuration", nil)
+	if err != nil {
+		return nil, err
+	}
+	var endpoints discovery
+	status, err := p.doJSON(request, &endpoints)
+	if err != nil || status != http.StatusOK {
+		return nil, fmt.Errorf("failed to discover provider %s: status %d, %v", p.config.Name, status, err)
+	}
+	// A document announcing another issuer could be used to accept that issuer's tokens
+	if strings.TrimSuffix(endpoints.Issuer, "/") != p.config.Issuer {
+		return nil, fmt.Errorf("provider %s announces issuer %q instead of %q", p.config.Name, endpoints.Issuer, p.config.Issuer)
+	}
+	if endpoints.AuthorizationEndpoint == "" || endpoints.TokenEndpoint == "" || endpoints.JWKSURI == "" {
+		return nil, fmt.Errorf("provider %s has an incomplete discovery document", p.config.Name)
+	}
+
+	p.endpoints = &endpoints
+	return p.endpoints, nil
+}
+
+// key returns the public key of the provider with an ID, fetching the keys again when it is unknown,
+// as providers rotate them
+func (p *Provider) key(kid string, endpoints *discovery) (crypto.PublicKey, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if key, ok := p.lookupKey(kid); ok {
+		return key, nil
+	}
+	if time.Since(p.keysFetchedAt) < keyRefreshInterval {
+		return nil, fmt.Errorf("unknown signing key %q", kid)
+	}
+
+	request, err := http.NewRequest(http.MethodGet, endpoints.JWKSURI, nil)
+	if err != nil {
+		return nil, err
+	}
+	var document struct {
+		Keys []jwk `json:"keys"`
+	}
+	status, err := p.doJSON(request, &document)
+	if err != nil || status != http.StatusOK {
+		return nil, fmt.Errorf("failed to fetch signing keys of provider %s: status %d, %v", p.config.Name, status, err)
+	}
+
+	p.keys = make(map[string]crypto.PublicKey)
+	for _, key := range document.Keys {
+		if key.Use != "" && key.Use != "sig" {
+			continue
+		}
+		if public, err := key.publicKey(); err == nil {
+			p.keys[key.Kid] = public
+		}
+	}
+	p.keysFetchedAt = time.Now()
+
+	if key, ok := p.lookupKey(kid); ok {
+		return key, nil
+	}
+	return nil, fmt.Errorf("unknown signing key %q", kid)
+}
+
+// lookupKey finds a cached key; tokens without a key ID are accepted when the provider has a single key
+func (p *Provider) lookupKey(kid string) (crypto.PublicKey, bool) {
+	if key, ok := p.keys[kid]; ok {
+		return key, true
+	}
+	if kid == "" && len(p.keys) == 1 {
+		for _, key := range p.keys {
+			return key, true
+		}
+	}
+	return nil, false
+}
+
+// doJSON sends a request and decodes its JSON answer, returning its status
+func (p *Provider) doJSON(request *http.Request, target interface{}) (int, error) {
+	response, err := p.client.Do(request)
+	if err != nil {
+		return 0, err
+	}
+	defer response.Body.Close()
+
+	if err := json.NewDecoder(io.LimitReader(response.Body, 1<<20)).Decode(target); err != nil {
+		return response.StatusCode, err
+	}
+	return response.StatusCode, nil
+}
+
+// jwk is a public key of a JWKS document (RFC 7517)
+type jwk struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+func (k jwk) publicKey() (crypto.PublicKey, error) {
+	decode := base64.RawURLEncoding.DecodeString
+	switch k.Kty {
+	case "RSA":
+		n, err := decode(k.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := decode(k.E)
+		if err != nil {
+			return nil, err
+		}
+		return &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}, nil
+	case "EC":
+		var curve elliptic.Curve
+		switch k.Crv {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		default:
+			return nil, fmt.Errorf("unsupported curve %q", k.Crv)
+		}
+		x, err := decode(k.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := decode(k.Y)
+		if err != nil {
+			return nil, err
+		}
+		return &ecdsa.PublicKey{Curve: curve, X: new(big.Int).SetBytes(x), Y: new(big.Int).SetBytes(y)}, nil
+	case "OKP":
+		if k.Crv != "Ed25519" {
+			return nil, fmt.Errorf("unsupported curve %q", k.Crv)
+		}
+		x, err := decode(k.X)
+		if err != nil || len(x) != ed25519.PublicKeySize {
+			return nil, fmt.Errorf("invalid Ed25519 key")
+		}
+		return ed25519.PublicKey(x), nil
+	default:
+		return nil, fmt.Errorf("unsupported key type %q", k.Kty)
+	}
+}
+
+// Providers resolves the configured identity providers by name
+type Providers struct {
+	providers map[string]*Provider
+}
+
+// NewProviders creates a registry of identity providers
+func NewProviders(providers ...*Provider) *Providers {
+	registry := &Providers{providers: make(map[string]*Provider)}
+	for _, provider := range providers {
+		registry.providers[strings.ToLower(provider.Name())] = provider
+	}
+	return registry
+}
+
+// Get returns the provider with a name
+func (p *Providers) Get(name string) (*Provider, bool) {
+	provider, ok := p.providers[strings.ToLower(name)]
+	return provider, ok
+}
+
+// Names lists the configured providers in alphabetical order
+func (p *Providers) Names() []string {
+	names := make([]string, 0, len(p.providers))
+	for name := range p.providers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// RandomString returns a random URL-safe string, for states, nonces and PKCE code verifiers
+func RandomString() (string, error) {
+	random := make([]byte, 32)
+	if _, err := rand.Read(random); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(random), nil
+}
+
+// CodeChallenge derives the S256 PKCE code challenge of a code verifier (RFC 7636)
+func CodeChallenge(codeVerifier string) string {
+	sum := sha256.Sum256([]byte(codeVerifier))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
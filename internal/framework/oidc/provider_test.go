@@ -0,0 +1,222 @@
+package oidc
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"dalabio/pkg/config"
+
+	"github.com/golang-jwt/jwt/v4"
+)
+
+const (
+	testClientID = "dalabio"
+	testKeyID    = "test-key"
+	testNonce    = "nonce"
+	testVerifier = "verifier"
+)
+
+// mockProvider is an OpenID Connect provider answering the token request with the ID token of its claims
+type mockProvider struct {
+	server *httptest.Server
+	key    *rsa.PrivateKey
+	issuer string // Issuer announced in the discovery document, the server URL when empty
+
+	// Answer of the token endpoint
+	claims    jwt.MapClaims
+	signingBy *rsa.PrivateKey // Key signing the ID token, key when nil
+	kid       string
+	status    int
+
+	// Last token request
+	form url.Values
+}
+
+func newMockProvider(t *testing.T) *mockProvider {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("generating key: %v", err)
+	}
+	mock := &mockProvider{key: key, kid: testKeyID, status: http.StatusOK}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		issuer := mock.issuer
+		if issuer == "" {
+			issuer = mock.server.URL
+		}
+		json.NewEncoder(w).Encode(discovery{
+			Issuer:                issuer,
+			AuthorizationEndpoint: mock.server.URL + "/authorize",
+			TokenEndpoint:         mock.server.URL + "/token",
+			JWKSURI:               mock.server.URL + "/jwks",
+		})
+	})
+	mux.HandleFunc("/jwks", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]interface{}{"keys": []jwk{{
+			Kty: "RSA",
+			Kid: testKeyID,
+			Use: "sig",
+			N:   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+			E:   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+		}}})
+	})
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		mock.form = r.PostForm
+		if mock.status != http.StatusOK {
+			w.WriteHeader(mock.status)
+			json.NewEncoder(w).Encode(map[string]string{"error": "invalid_grant"})
+			return
+		}
+
+		signer := mock.signingBy
+		if signer == nil {
+			signer = key
+		}
+		token := jwt.NewWithClaims(jwt.SigningMethodRS256, mock.claims)
+		token.Header["kid"] = mock.kid
+		idToken, err := token.SignedString(signer)
+		if err != nil {
+			t.Errorf("signing ID token: %v", err)
+		}
+		json.NewEncoder(w).Encode(map[string]string{"id_token": idToken})
+	})
+
+	mock.server = httptest.NewServer(mux)
+	t.Cleanup(mock.server.Close)
+	return mock
+}
+
+func (m *mockProvider) provider() *Provider {
+	return NewProvider(config.OIDCProviderConfig{
+		Name:        "mock",
+		Issuer:      m.server.URL,
+		ClientID:    testClientID,
+		RedirectURL: "http://localhost:8080/users/oidc/mock/callback",
+		Scopes:      []string{"openid", "email"},
+	}, m.server.Client())
+}
+
+// validClaims are the claims of an ID token the provider accepts
+func (m *mockProvider) validClaims() jwt.MapClaims {
+	now := time.Now()
+	return jwt.MapClaims{
+		"iss":            m.server.URL,
+		"sub":            "subject",
+		"aud":            testClientID,
+		"iat":            now.Unix(),
+		"exp":            now.Add(time.Hour).Unix(),
+		"nonce":          testNonce,
+		"email":          "ada@example.com",
+		"email_verified": "true",
+	}
+}
+
+func TestExchange(t *testing.T) {
+	otherKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("generating key: %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		prepare func(m *mockProvider)
+		wantErr bool
+	}{
+		{name: "valid ID token", prepare: func(m *mockProvider) {}},
+		{name: "audience among several", prepare: func(m *mockProvider) {
+			m.claims["aud"] = []string{"other", testClientID}
+		}},
+		{name: "nonce mismatch", prepare: func(m *mockProvider) { m.claims["nonce"] = "other" }, wantErr: true},
+		{name: "missing nonce", prepare: func(m *mockProvider) { delete(m.claims, "nonce") }, wantErr: true},
+		{name: "audience mismatch", prepare: func(m *mockProvider) { m.claims["aud"] = "other" }, wantErr: true},
+		{name: "issuer mismatch", prepare: func(m *mockProvider) { m.claims["iss"] = "https://evil.test" }, wantErr: true},
+		{name: "expired", prepare: func(m *mockProvider) { m.claims["exp"] = time.Now().Add(-time.Minute).Unix() }, wantErr: true},
+		{name: "missing expiry", prepare: func(m *mockProvider) { delete(m.claims, "exp") }, wantErr: true},
+		{name: "missing subject", prepare: func(m *mockProvider) { delete(m.claims, "sub") }, wantErr: true},
+		{name: "signed by another key", prepare: func(m *mockProvider) { m.signingBy = otherKey }, wantErr: true},
+		{name: "unknown key ID", prepare: func(m *mockProvider) { m.kid = "other-key" }, wantErr: true},
+		{name: "code refused", prepare: func(m *mockProvider) { m.status = http.StatusBadRequest }, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mock := newMockProvider(t)
+			mock.claims = mock.validClaims()
+			tt.prepare(mock)
+
+			claims, err := mock.provider().Exchange("code", testVerifier, testNonce)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("Exchange accepted the ID token: %+v", claims)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Exchange: %v", err)
+			}
+			if claims.Subject != "subject" || claims.Email != "ada@example.com" || !bool(claims.EmailVerified) {
+				t.Errorf("Exchange returned %+v", claims)
+			}
+			if mock.form.Get("code_verifier") != testVerifier || mock.form.Get("client_id") != testClientID {
+				t.Errorf("token request sent %v", mock.form)
+			}
+		})
+	}
+}
+
+func TestDiscoveryIssuerMismatch(t *testing.T) {
+	mock := newMockProvider(t)
+	mock.issuer = "https://evil.test"
+
+	if _, err := mock.provider().AuthorizationURL("state", testNonce, CodeChallenge(testVerifier)); err == nil {
+		t.Error("AuthorizationURL trusted a discovery document announcing another issuer")
+	}
+}
+
+func TestAuthorizationURL(t *testing.T) {
+	mock := newMockProvider(t)
+
+	authorizationURL, err := mock.provider().AuthorizationURL("state", testNonce, CodeChallenge(testVerifier))
+	if err != nil {
+		t.Fatalf("AuthorizationURL: %v", err)
+	}
+	parsed, err := url.Parse(authorizationURL)
+	if err != nil {
+		t.Fatalf("parsing %s: %v", authorizationURL, err)
+	}
+
+	query := parsed.Query()
+	want := map[string]string{
+		"response_type":         "code",
+		"client_id":             testClientID,
+		"state":                 "state",
+		"nonce":                 testNonce,
+		"code_challenge":        CodeChallenge(testVerifier),
+		"code_challenge_method": "S256",
+		"scope":                 "openid email",
+	}
+	for name, value := range want {
+		if got := query.Get(name); got != value {
+			t.Errorf("%s = %q, want %q", name, got, value)
+		}
+	}
+}
+
+func TestCodeChallenge(t *testing.T) {
+	// Example of RFC 7636 appendix B
+	got := CodeChallenge("dBjftJeZ4CVP-mB92K27uhbUJU1p1r_wW1gFWFOEjXk")
+	if want := "E9Melhoa2OwvFrEMTJguCHaoeK1t8URWbuGJSstw-cM"; got != want {
+		t.Errorf("CodeChallenge = %s, want %s", got, want)
+	}
+}
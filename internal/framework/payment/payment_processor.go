@@ -1 +1,91 @@
 package payment
+
+import (
+	"strings"
+
+	"github.com/gofrs/uuid"
+)
+
+// Processor is the abstraction over an external payment gateway (Stripe, PayPal, ...)
+type Processor interface {
+	// CreateIntent starts a payment for the given amount and returns the gateway's transaction ID
+	CreateIntent(amount float64, currency string, reference string) (string, error)
+
+	// Charge collects an off-session payment (e.g., a subscription renewal) and returns the gateway's transaction ID
+	Charge(amount float64, currency string, reference string) (string, error)
+
+	// Captured tells whether the gateway has collected the money of a transaction started with CreateIntent
+	Captured(transactionID string) (bool, error)
+
+	// Refund returns money for a captured transaction and returns the gateway's refund reference
+	Refund(transactionID string, amount float64, currency string) (string, error)
+}
+
+// Gateways resolves the processor responsible for a payment from its PaymentGateway name
+type Gateways struct {
+	processors map[string]Processor
+	fallback   Processor
+}
+
+// NewGateways creates a registry that uses fallback for gateways without a registered processor
+func NewGateways(fallback Processor) *Gateways {
+	return &Gateways{
+		processors: make(map[string]Processor),
+		fallback:   fallback,
+	}
+}
+
+// Register associates a processor with a gateway name (case-insensitive)
+func (g *Gateways) Register(name string, processor Processor) {
+	g.processors[strings.ToLower(name)] = processor
+}
+
+// Get returns the processor for the given gateway name
+func (g *Gateways) Get(name string) Processor {
+	if processor, ok := g.processors[strings.ToLower(name)]; ok {
+		return processor
+	}
+	return g.fallback
+}
+
+// ManualProcessor handles payments settled outside the platform (cash, bank transfer).
+// It performs no remote call and only issues a local reference.
+type ManualProcessor struct{}
+
+// NewManualProcessor creates a new ManualProcessor
+func NewManualProcessor() *ManualProcessor {
+	return &ManualProcessor{}
+}
+
+// CreateIntent implements Processor.
+func (p *ManualProcessor) CreateIntent(amount float64, currency string, reference string) (string, error) {
+	ref, err := uuid.NewV4()
+	if err != nil {
+		return "", err
+	}
+	return "manual_pi_" + ref.String(), nil
+}
+
+// Charge implements Processor.
+func (p *ManualProcessor) Charge(amount float64, currency string, reference string) (string, error) {
+	ref, err := uuid.NewV4()
+	if err != nil {
+		return "", err
+	}
+	return "manual_ch_" + ref.String(), nil
+}
+
+// Captured implements Processor. Manual payments are settled outside the platform, so they count as
+// collected once an administrator confirms them.
+func (p *ManualProcessor) Captured(transactionID string) (bool, error) {
+	return true, nil
+}
+
+// Refund implements Processor.
+func (p *ManualProcessor) Refund(transactionID string, amount float64, currency string) (string, error) {
+	ref, err := uuid.NewV4()
+	if err != nil {
+		return "", err
+	}
+	return "manual_re_" + ref.String(), nil
+}
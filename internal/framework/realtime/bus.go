@@ -0,0 +1,176 @@
+package realtime
+
+import (
+	"database/sql"
+	"encoding/json"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/gofrs/uuid"
+	"github.com/lib/pq"
+)
+
+// subscriberBuffer is how many events a client can lag behind before events are dropped for it
+const subscriberBuffer = 16
+
+// notifyChannel is the PostgreSQL channel the instances exchange domain events on
+const notifyChannel = "dalabio_events"
+
+// maxNotifyPayload keeps payloads under the 8000 bytes PostgreSQL accepts for a notification
+const maxNotifyPayload = 7900
+
+// Event is pushed to the connected clients of a user
+type Event struct {
+	Type string      `json:"type"` // e.g., "message.created"
+	Data interface{} `json:"data"`
+}
+
+// Audience restricts who may receive a domain event; the zero value reaches every authenticated user
+type Audience struct {
+	UserIDs     []uuid.UUID `json:"user_ids,omitempty"`     // Only these users, when set
+	SpaceID     *uuid.UUID  `json:"space_id,omitempty"`     // Space the event belongs to
+	MembersOnly bool        `json:"members_only,omitempty"` // Only the members of the space
+}
+
+// Envelope carries a domain event on the bus together with its audience
+type Envelope struct {
+	Origin   string   `json:"origin"` // Instance that published the event
+	Event    Event    `json:"event"`
+	Audience Audience `json:"audience"`
+}
+
+// Bus broadcasts domain events to the subscribers of this instance and, through PostgreSQL LISTEN/NOTIFY,
+// to the subscribers of every other instance sharing the database
+type Bus struct {
+	db          *sql.DB
+	origin      string
+	mu          sync.RWMutex
+	subscribers map[chan Envelope]struct{}
+	listener    *pq.Listener
+	stop        chan struct{}
+	wg          sync.WaitGroup
+}
+
+// NewBus creates a Bus notifying the other instances through db; a nil db keeps events in-process
+func NewBus(db *sql.DB) *Bus {
+	origin, err := uuid.NewV4()
+	if err != nil {
+		log.Printf("Failed to generate event bus origin: %v", err)
+	}
+	return &Bus{
+		db:          db,
+		origin:      origin.String(),
+		subscribers: make(map[chan Envelope]struct{}),
+		stop:        make(chan struct{}),
+	}
+}
+
+// Listen receives the events published by the other instances until the bus is closed
+func (b *Bus) Listen(connStr string) error {
+	b.listener = pq.NewListener(connStr, 10*time.Second, time.Minute, func(event pq.ListenerEventType, err error) {
+		if err != nil {
+			log.Printf("Event bus listener: %v", err)
+		}
+	})
+	if err := b.listener.Listen(notifyChannel); err != nil {
+		b.listener.Close()
+		b.listener = nil
+		return err
+	}
+
+	b.wg.Add(1)
+	go func() {
+		defer b.wg.Done()
+
+		for {
+			select {
+			case notification := <-b.listener.Notify:
+				// A nil notification follows a reconnection; events sent meanwhile are lost
+				if notification == nil {
+					continue
+				}
+				var envelope Envelope
+				if err := json.Unmarshal([]byte(notification.Extra), &envelope); err != nil {
+					log.Printf("Ignoring malformed event: %v", err)
+					continue
+				}
+				if envelope.Origin != b.origin {
+					b.deliver(envelope)
+				}
+			case <-time.After(90 * time.Second):
+				// Detect broken connections while no event comes
+				go b.listener.Ping()
+			case <-b.stop:
+				return
+			}
+		}
+	}()
+	log.Printf("Listening for events on channel %s", notifyChannel)
+	return nil
+}
+
+// Close stops listening for the events of the other instances
+func (b *Bus) Close() {
+	close(b.stop)
+	b.wg.Wait()
+	if b.listener != nil {
+		b.listener.Close()
+	}
+}
+
+// Subscribe registers a subscriber of every event. The returned function unregisters it and closes the channel.
+func (b *Bus) Subscribe() (<-chan Envelope, func()) {
+	envelopes := make(chan Envelope, subscriberBuffer)
+
+	b.mu.Lock()
+	b.subscribers[envelopes] = struct{}{}
+	b.mu.Unlock()
+
+	var once sync.Once
+	return envelopes, func() {
+		once.Do(func() {
+			b.mu.Lock()
+			delete(b.subscribers, envelopes)
+			b.mu.Unlock()
+			close(envelopes)
+		})
+	}
+}
+
+// Publish delivers an event to the subscribers of this instance and notifies the other instances.
+// Events too large for a notification only reach this instance.
+func (b *Bus) Publish(event Event, audience Audience) {
+	envelope := Envelope{Origin: b.origin, Event: event, Audience: audience}
+	b.deliver(envelope)
+
+	if b.db == nil {
+		return
+	}
+
+	payload, err := json.Marshal(envelope)
+	if err != nil {
+		log.Printf("Failed to encode event %s: %v", event.Type, err)
+		return
+	}
+	if len(payload) > maxNotifyPayload {
+		log.Printf("Event %s is too large to notify the other instances (%d bytes)", event.Type, len(payload))
+		return
+	}
+	if _, err := b.db.Exec(`SELECT pg_notify($1, $2)`, notifyChannel, string(payload)); err != nil {
+		log.Printf("Failed to notify event %s: %v", event.Type, err)
+	}
+}
+
+// deliver hands an event to every subscriber; subscribers too slow to keep up miss it
+func (b *Bus) deliver(envelope Envelope) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	for envelopes := range b.subscribers {
+		select {
+		case envelopes <- envelope:
+		default:
+		}
+	}
+}
@@ -0,0 +1,94 @@
+package controller
+
+import (
+	"dalabio/internal/entity"
+	"dalabio/internal/service"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gofrs/uuid"
+)
+
+// APIKeyController handles the personal API keys of users
+type APIKeyController struct {
+	apiKeyService service.APIKeyService
+}
+
+// NewAPIKeyController creates a new API key controller
+func NewAPIKeyController(apiKeyService service.APIKeyService) *APIKeyController {
+	return &APIKeyController{apiKeyService: apiKeyService}
+}
+
+// createAPIKeyRequest is the body accepted to create an API key
+type createAPIKeyRequest struct {
+	Name      string     `json:"name" binding:"required"`
+	Scopes    []string   `json:"scopes" binding:"required"`
+	ExpiresAt *time.Time `json:"expires_at"` // 90 days from now when omitted
+}
+
+// createAPIKeyResponse shows the key once, when it is created
+type createAPIKeyResponse struct {
+	*entity.APIKey
+	Key string `json:"key"`
+}
+
+// CreateAPIKey creates an API key for the authenticated user
+func (ac *APIKeyController) CreateAPIKey(ctx *gin.Context) {
+	userID, ok := authenticatedUser(ctx)
+	if !ok {
+		return
+	}
+	var req createAPIKeyRequest
+	if !bindJSON(ctx, &req) {
+		return
+	}
+
+	key, value, err := ac.apiKeyService.CreateAPIKey(userID, req.Name, req.Scopes, req.ExpiresAt)
+	if err != nil {
+		log.Printf("Error creating API key: %v", err)
+		ctx.Error(err)
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, createAPIKeyResponse{APIKey: key, Key: value})
+}
+
+// ListAPIKeys lists the API keys of the authenticated user
+func (ac *APIKeyController) ListAPIKeys(ctx *gin.Context) {
+	userID, ok := authenticatedUser(ctx)
+	if !ok {
+		return
+	}
+
+	keys, err := ac.apiKeyService.ListAPIKeys(userID)
+	if err != nil {
+		log.Printf("Error listing API keys: %v", err)
+		ctx.Error(err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"api_keys": keys})
+}
+
+// RevokeAPIKey revokes an API key of the authenticated user
+func (ac *APIKeyController) RevokeAPIKey(ctx *gin.Context) {
+	userID, ok := authenticatedUser(ctx)
+	if !ok {
+		return
+	}
+	keyID, err := uuid.FromString(ctx.Param("id"))
+	if err != nil {
+		ctx.Error(service.NewError(service.ErrValidation, "invalid API key ID"))
+		return
+	}
+
+	if err := ac.apiKeyService.RevokeAPIKey(userID, keyID); err != nil {
+		log.Printf("Error revoking API key: %v", err)
+		ctx.Error(err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"message": "API key revoked successfully"})
+}
@@ -0,0 +1,18 @@
+package controller
+
+import (
+	"dalabio/internal/service"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gofrs/uuid"
+)
+
+// authenticatedUser reads the user ID set by the AuthMiddleware
+func authenticatedUser(ctx *gin.Context) (uuid.UUID, bool) {
+	userID, exists := ctx.Get("userID")
+	if !exists {
+		ctx.Error(service.NewError(service.ErrUnauthorized, "user ID is required"))
+		return uuid.Nil, false
+	}
+	return userID.(uuid.UUID), true
+}
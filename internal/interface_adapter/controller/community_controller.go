@@ -1 +1,565 @@
 package controller
+
+import (
+	"dalabio/internal/entity"
+	"dalabio/internal/service"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gofrs/uuid"
+)
+
+// CommunityController struct that defines the controller of the space discussions with its service
+type CommunityController struct {
+	communityService service.CommunityService
+}
+
+// NewCommunityController creates a new CommunityController instance
+func NewCommunityController(communityService service.CommunityService) *CommunityController {
+	return &CommunityController{communityService: communityService}
+}
+
+// postRequest is the body accepted when creating or editing a post
+type postRequest struct {
+	Title string `json:"title" binding:"required"`
+	Body  string `json:"body" binding:"required"`
+}
+
+// commentRequest is the body accepted when creating or editing a comment
+type commentRequest struct {
+	Body     string     `json:"body" binding:"required"`
+	ParentID *uuid.UUID `json:"parent_id"` // Comment replied to, omitted for top-level comments
+}
+
+// hideRequest is the optional body accepted when hiding a post or comment
+type hideRequest struct {
+	Reason string `json:"reason"`
+}
+
+// reactionRequest is the body accepted when reacting to a post or comment
+type reactionRequest struct {
+	Emoji string `json:"emoji" binding:"required"`
+}
+
+// postResponse is a post as shown to clients, without the moderator who hid it
+type postResponse struct {
+	ID             uuid.UUID      `json:"id"`
+	SpaceID        uuid.UUID      `json:"space_id"`
+	AuthorID       uuid.UUID      `json:"author_id"`
+	AuthorUsername string         `json:"author_username"`
+	Title          string         `json:"title"`
+	Body           string         `json:"body"`
+	PinnedAt       *time.Time     `json:"pinned_at,omitempty"`
+	HiddenAt       *time.Time     `json:"hidden_at,omitempty"`
+	HiddenReason   string         `json:"hidden_reason,omitempty"`
+	CommentCount   int            `json:"comment_count"`
+	Reactions      map[string]int `json:"reactions"`
+	EditedAt       *time.Time     `json:"edited_at,omitempty"`
+	CreatedAt      time.Time      `json:"created_at"`
+	UpdatedAt      time.Time      `json:"updated_at"`
+}
+
+func newPostResponse(post *entity.Post) *postResponse {
+	return &postResponse{
+		ID:             post.ID,
+		SpaceID:        post.SpaceID,
+		AuthorID:       post.AuthorID,
+		AuthorUsername: post.AuthorUsername,
+		Title:          post.Title,
+		Body:           post.Body,
+		PinnedAt:       post.PinnedAt,
+		HiddenAt:       post.HiddenAt,
+		HiddenReason:   post.HiddenReason,
+		CommentCount:   post.CommentCount,
+		Reactions:      post.Reactions,
+		EditedAt:       post.EditedAt,
+		CreatedAt:      post.CreatedAt,
+		UpdatedAt:      post.UpdatedAt,
+	}
+}
+
+func newPostResponses(posts []*entity.Post) []*postResponse {
+	responses := make([]*postResponse, 0, len(posts))
+	for _, post := range posts {
+		responses = append(responses, newPostResponse(post))
+	}
+	return responses
+}
+
+// commentResponse is a comment and its replies as shown to clients, without the moderator who hid it
+type commentResponse struct {
+	ID             uuid.UUID          `json:"id"`
+	PostID         uuid.UUID          `json:"post_id"`
+	ParentID       *uuid.UUID         `json:"parent_id,omitempty"`
+	AuthorID       uuid.UUID          `json:"author_id"`
+	AuthorUsername string             `json:"author_username"`
+	Body           string             `json:"body"`
+	HiddenAt       *time.Time         `json:"hidden_at,omitempty"`
+	HiddenReason   string             `json:"hidden_reason,omitempty"`
+	Reactions      map[string]int     `json:"reactions"`
+	EditedAt       *time.Time         `json:"edited_at,omitempty"`
+	CreatedAt      time.Time          `json:"created_at"`
+	UpdatedAt      time.Time          `json:"updated_at"`
+	DeletedAt      *time.Time         `json:"deleted_at,omitempty"` // Deleted comments stay as placeholders while they have replies
+	Replies        []*commentResponse `json:"replies,omitempty"`
+}
+
+func newCommentResponse(comment *entity.Comment) *commentResponse {
+	response := &commentResponse{
+		ID:             comment.ID,
+		PostID:         comment.PostID,
+		ParentID:       comment.ParentID,
+		AuthorID:       comment.AuthorID,
+		AuthorUsername: comment.AuthorUsername,
+		Body:           comment.Body,
+		HiddenAt:       comment.HiddenAt,
+		HiddenReason:   comment.HiddenReason,
+		Reactions:      comment.Reactions,
+		EditedAt:       comment.EditedAt,
+		CreatedAt:      comment.CreatedAt,
+		UpdatedAt:      comment.UpdatedAt,
+		DeletedAt:      comment.DeletedAt,
+	}
+	if len(comment.Replies) > 0 {
+		response.Replies = newCommentResponses(comment.Replies)
+	}
+	return response
+}
+
+func newCommentResponses(comments []*entity.Comment) []*commentResponse {
+	responses := make([]*commentResponse, 0, len(comments))
+	for _, comment := range comments {
+		responses = append(responses, newCommentResponse(comment))
+	}
+	return responses
+}
+
+// revisionResponse is the content a post or a comment had before one of its edits
+type revisionResponse struct {
+	ID         uuid.UUID `json:"id"`
+	TargetType string    `json:"target_type"`
+	TargetID   uuid.UUID `json:"target_id"`
+	Title      string    `json:"title,omitempty"`
+	Body       string    `json:"body"`
+	EditedBy   uuid.UUID `json:"edited_by"`
+	EditedAt   time.Time `json:"edited_at"`
+}
+
+func newRevisionResponses(revisions []*entity.Revision) []*revisionResponse {
+	responses := make([]*revisionResponse, 0, len(revisions))
+	for _, revision := range revisions {
+		responses = append(responses, &revisionResponse{
+			ID:         revision.ID,
+			TargetType: revision.TargetType,
+			TargetID:   revision.TargetID,
+			Title:      revision.Title,
+			Body:       revision.Body,
+			EditedBy:   revision.EditedBy,
+			EditedAt:   revision.EditedAt,
+		})
+	}
+	return responses
+}
+
+// postAndUser parses the space and post IDs from the URL and reads the authenticated user ID
+func postAndUser(ctx *gin.Context) (uuid.UUID, uuid.UUID, uuid.UUID, bool) {
+	spaceID, userID, ok := spaceAndUser(ctx)
+	if !ok {
+		return uuid.Nil, uuid.Nil, uuid.Nil, false
+	}
+
+	postID, err := uuid.FromString(ctx.Param("postId"))
+	if err != nil {
+		ctx.Error(service.NewError(service.ErrValidation, "invalid post ID"))
+		return uuid.Nil, uuid.Nil, uuid.Nil, false
+	}
+
+	return spaceID, postID, userID, true
+}
+
+// commentID parses the comment ID from the URL
+func commentID(ctx *gin.Context) (uuid.UUID, bool) {
+	commentID, err := uuid.FromString(ctx.Param("commentId"))
+	if err != nil {
+		ctx.Error(service.NewError(service.ErrValidation, "invalid comment ID"))
+		return uuid.Nil, false
+	}
+	return commentID, true
+}
+
+// GetPosts lists the posts of a space
+func (cc *CommunityController) GetPosts(ctx *gin.Context) {
+	spaceID, userID, ok := spaceAndUser(ctx)
+	if !ok {
+		return
+	}
+
+	posts, err := cc.communityService.GetPosts(spaceID, userID)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, newPostResponses(posts))
+}
+
+// CreatePost starts a discussion in a space
+func (cc *CommunityController) CreatePost(ctx *gin.Context) {
+	spaceID, userID, ok := spaceAndUser(ctx)
+	if !ok {
+		return
+	}
+
+	var request postRequest
+	if !bindJSON(ctx, &request) {
+		return
+	}
+
+	post, err := cc.communityService.CreatePost(spaceID, userID, request.Title, request.Body)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, newPostResponse(post))
+}
+
+// GetPost returns a post of a space
+func (cc *CommunityController) GetPost(ctx *gin.Context) {
+	spaceID, postID, userID, ok := postAndUser(ctx)
+	if !ok {
+		return
+	}
+
+	post, err := cc.communityService.GetPost(spaceID, postID, userID)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, newPostResponse(post))
+}
+
+// EditPost changes the title and body of a post
+func (cc *CommunityController) EditPost(ctx *gin.Context) {
+	spaceID, postID, userID, ok := postAndUser(ctx)
+	if !ok {
+		return
+	}
+
+	var request postRequest
+	if !bindJSON(ctx, &request) {
+		return
+	}
+
+	post, err := cc.communityService.EditPost(spaceID, postID, userID, request.Title, request.Body)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, newPostResponse(post))
+}
+
+// DeletePost deletes a post
+func (cc *CommunityController) DeletePost(ctx *gin.Context) {
+	spaceID, postID, userID, ok := postAndUser(ctx)
+	if !ok {
+		return
+	}
+
+	if err := cc.communityService.DeletePost(spaceID, postID, userID); err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"message": "Post deleted successfully"})
+}
+
+// GetPostHistory lists the previous versions of a post
+func (cc *CommunityController) GetPostHistory(ctx *gin.Context) {
+	spaceID, postID, userID, ok := postAndUser(ctx)
+	if !ok {
+		return
+	}
+
+	revisions, err := cc.communityService.GetPostHistory(spaceID, postID, userID)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, newRevisionResponses(revisions))
+}
+
+// PinPost pins a post on top of the discussions of a space
+func (cc *CommunityController) PinPost(ctx *gin.Context) {
+	cc.pin(ctx, true)
+}
+
+// UnpinPost unpins a post
+func (cc *CommunityController) UnpinPost(ctx *gin.Context) {
+	cc.pin(ctx, false)
+}
+
+func (cc *CommunityController) pin(ctx *gin.Context, pinned bool) {
+	spaceID, postID, userID, ok := postAndUser(ctx)
+	if !ok {
+		return
+	}
+
+	if err := cc.communityService.PinPost(spaceID, postID, userID, pinned); err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"message": "Post updated successfully"})
+}
+
+// HidePost hides a post from the members of a space
+func (cc *CommunityController) HidePost(ctx *gin.Context) {
+	cc.hidePost(ctx, true)
+}
+
+// UnhidePost shows a hidden post again
+func (cc *CommunityController) UnhidePost(ctx *gin.Context) {
+	cc.hidePost(ctx, false)
+}
+
+func (cc *CommunityController) hidePost(ctx *gin.Context, hidden bool) {
+	spaceID, postID, userID, ok := postAndUser(ctx)
+	if !ok {
+		return
+	}
+
+	request, ok := bindHideRequest(ctx)
+	if !ok {
+		return
+	}
+
+	if err := cc.communityService.HidePost(spaceID, postID, userID, hidden, request.Reason); err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"message": "Post updated successfully"})
+}
+
+// GetComments lists the comments of a post as threads
+func (cc *CommunityController) GetComments(ctx *gin.Context) {
+	spaceID, postID, userID, ok := postAndUser(ctx)
+	if !ok {
+		return
+	}
+
+	comments, err := cc.communityService.GetComments(spaceID, postID, userID)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, newCommentResponses(comments))
+}
+
+// AddComment comments a post or replies to a comment
+func (cc *CommunityController) AddComment(ctx *gin.Context) {
+	spaceID, postID, userID, ok := postAndUser(ctx)
+	if !ok {
+		return
+	}
+
+	var request commentRequest
+	if !bindJSON(ctx, &request) {
+		return
+	}
+
+	comment, err := cc.communityService.AddComment(spaceID, postID, userID, request.ParentID, request.Body)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, newCommentResponse(comment))
+}
+
+// EditComment changes the body of a comment
+func (cc *CommunityController) EditComment(ctx *gin.Context) {
+	spaceID, postID, userID, ok := postAndUser(ctx)
+	if !ok {
+		return
+	}
+	commentID, ok := commentID(ctx)
+	if !ok {
+		return
+	}
+
+	var request commentRequest
+	if !bindJSON(ctx, &request) {
+		return
+	}
+
+	comment, err := cc.communityService.EditComment(spaceID, postID, commentID, userID, request.Body)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, newCommentResponse(comment))
+}
+
+// DeleteComment deletes a comment
+func (cc *CommunityController) DeleteComment(ctx *gin.Context) {
+	spaceID, postID, userID, ok := postAndUser(ctx)
+	if !ok {
+		return
+	}
+	commentID, ok := commentID(ctx)
+	if !ok {
+		return
+	}
+
+	if err := cc.communityService.DeleteComment(spaceID, postID, commentID, userID); err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"message": "Comment deleted successfully"})
+}
+
+// GetCommentHistory lists the previous versions of a comment
+func (cc *CommunityController) GetCommentHistory(ctx *gin.Context) {
+	spaceID, postID, userID, ok := postAndUser(ctx)
+	if !ok {
+		return
+	}
+	commentID, ok := commentID(ctx)
+	if !ok {
+		return
+	}
+
+	revisions, err := cc.communityService.GetCommentHistory(spaceID, postID, commentID, userID)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, newRevisionResponses(revisions))
+}
+
+// HideComment hides a comment from the members of a space
+func (cc *CommunityController) HideComment(ctx *gin.Context) {
+	cc.hideComment(ctx, true)
+}
+
+// UnhideComment shows a hidden comment again
+func (cc *CommunityController) UnhideComment(ctx *gin.Context) {
+	cc.hideComment(ctx, false)
+}
+
+func (cc *CommunityController) hideComment(ctx *gin.Context, hidden bool) {
+	spaceID, postID, userID, ok := postAndUser(ctx)
+	if !ok {
+		return
+	}
+	commentID, ok := commentID(ctx)
+	if !ok {
+		return
+	}
+
+	request, ok := bindHideRequest(ctx)
+	if !ok {
+		return
+	}
+
+	if err := cc.communityService.HideComment(spaceID, postID, commentID, userID, hidden, request.Reason); err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"message": "Comment updated successfully"})
+}
+
+// AddPostReaction reacts to a post
+func (cc *CommunityController) AddPostReaction(ctx *gin.Context) {
+	cc.addReaction(ctx, false)
+}
+
+// RemovePostReaction removes a reaction from a post
+func (cc *CommunityController) RemovePostReaction(ctx *gin.Context) {
+	cc.removeReaction(ctx, false)
+}
+
+// AddCommentReaction reacts to a comment
+func (cc *CommunityController) AddCommentReaction(ctx *gin.Context) {
+	cc.addReaction(ctx, true)
+}
+
+// RemoveCommentReaction removes a reaction from a comment
+func (cc *CommunityController) RemoveCommentReaction(ctx *gin.Context) {
+	cc.removeReaction(ctx, true)
+}
+
+func (cc *CommunityController) addReaction(ctx *gin.Context, onComment bool) {
+	spaceID, postID, userID, ok := postAndUser(ctx)
+	if !ok {
+		return
+	}
+	target, ok := reactionTarget(ctx, onComment)
+	if !ok {
+		return
+	}
+
+	var request reactionRequest
+	if !bindJSON(ctx, &request) {
+		return
+	}
+
+	if err := cc.communityService.AddReaction(spaceID, postID, target, userID, request.Emoji); err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"message": "Reaction added successfully"})
+}
+
+func (cc *CommunityController) removeReaction(ctx *gin.Context, onComment bool) {
+	spaceID, postID, userID, ok := postAndUser(ctx)
+	if !ok {
+		return
+	}
+	target, ok := reactionTarget(ctx, onComment)
+	if !ok {
+		return
+	}
+
+	if err := cc.communityService.RemoveReaction(spaceID, postID, target, userID, ctx.Param("emoji")); err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"message": "Reaction removed successfully"})
+}
+
+// reactionTarget returns the comment reacted to, or nil when reacting to the post itself
+func reactionTarget(ctx *gin.Context, onComment bool) (*uuid.UUID, bool) {
+	if !onComment {
+		return nil, true
+	}
+	commentID, ok := commentID(ctx)
+	if !ok {
+		return nil, false
+	}
+	return &commentID, true
+}
+
+// bindHideRequest reads the optional moderation reason
+func bindHideRequest(ctx *gin.Context) (hideRequest, bool) {
+	var request hideRequest
+	if ctx.Request.ContentLength > 0 {
+		if !bindJSON(ctx, &request) {
+			return request, false
+		}
+	}
+	return request, true
+}
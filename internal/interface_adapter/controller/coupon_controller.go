@@ -0,0 +1,181 @@
+package controller
+
+import (
+	"dalabio/internal/entity"
+	"dalabio/internal/service"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gofrs/uuid"
+)
+
+// CouponController struct that defines the coupon controller with its service
+type CouponController struct {
+	couponService service.CouponService
+}
+
+// NewCouponController creates a new CouponController instance
+func NewCouponController(couponService service.CouponService) *CouponController {
+	return &CouponController{couponService: couponService}
+}
+
+// couponRequest is the body accepted to create or update a coupon; redemptions are counted by the server
+type couponRequest struct {
+	Code           string      `json:"code" binding:"required"`
+	DiscountType   string      `json:"discount_type" binding:"required"`
+	Value          float64     `json:"value" binding:"required"`
+	Currency       string      `json:"currency"`
+	ExpiresAt      *time.Time  `json:"expires_at"`
+	MaxRedemptions int         `json:"max_redemptions"`
+	PerUserLimit   int         `json:"per_user_limit"`
+	CourseIDs      []uuid.UUID `json:"course_ids"`
+	SpaceIDs       []uuid.UUID `json:"space_ids"`
+	Active         bool        `json:"active"`
+}
+
+// couponResponse is a coupon as shown to clients, without its redemption count
+type couponResponse struct {
+	ID             uuid.UUID   `json:"id"`
+	Code           string      `json:"code"`
+	DiscountType   string      `json:"discount_type"`
+	Value          float64     `json:"value"`
+	Currency       string      `json:"currency,omitempty"`
+	ExpiresAt      *time.Time  `json:"expires_at,omitempty"`
+	MaxRedemptions int         `json:"max_redemptions"`
+	PerUserLimit   int         `json:"per_user_limit"`
+	CourseIDs      []uuid.UUID `json:"course_ids,omitempty"`
+	SpaceIDs       []uuid.UUID `json:"space_ids,omitempty"`
+	Active         bool        `json:"active"`
+	CreatedAt      time.Time   `json:"created_at"`
+	UpdatedAt      time.Time   `json:"updated_at"`
+}
+
+func newCouponResponse(coupon *entity.Coupon) *couponResponse {
+	return &couponResponse{
+		ID:             coupon.ID,
+		Code:           coupon.Code,
+		DiscountType:   coupon.DiscountType,
+		Value:          coupon.Value,
+		Currency:       coupon.Currency,
+		ExpiresAt:      coupon.ExpiresAt,
+		MaxRedemptions: coupon.MaxRedemptions,
+		PerUserLimit:   coupon.PerUserLimit,
+		CourseIDs:      coupon.CourseIDs,
+		SpaceIDs:       coupon.SpaceIDs,
+		Active:         coupon.Active,
+		CreatedAt:      coupon.CreatedAt,
+		UpdatedAt:      coupon.UpdatedAt,
+	}
+}
+
+func newCouponResponses(coupons []*entity.Coupon) []*couponResponse {
+	responses := make([]*couponResponse, 0, len(coupons))
+	for _, coupon := range coupons {
+		responses = append(responses, newCouponResponse(coupon))
+	}
+	return responses
+}
+
+// coupon maps the request onto a coupon with the given ID
+func (req *couponRequest) coupon(couponID uuid.UUID) *entity.Coupon {
+	return &entity.Coupon{
+		ID:             couponID,
+		Code:           req.Code,
+		DiscountType:   req.DiscountType,
+		Value:          req.Value,
+		Currency:       req.Currency,
+		ExpiresAt:      req.ExpiresAt,
+		MaxRedemptions: req.MaxRedemptions,
+		PerUserLimit:   req.PerUserLimit,
+		CourseIDs:      req.CourseIDs,
+		SpaceIDs:       req.SpaceIDs,
+		Active:         req.Active,
+	}
+}
+
+// CreateCoupon handles the creation of a new coupon
+func (cc *CouponController) CreateCoupon(ctx *gin.Context) {
+	var req couponRequest
+
+	if !bindJSON(ctx, &req) {
+		return
+	}
+
+	createdCoupon, err := cc.couponService.CreateCoupon(req.coupon(uuid.Nil))
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, newCouponResponse(createdCoupon))
+}
+
+// UpdateCoupon handles the update of an existing coupon
+func (cc *CouponController) UpdateCoupon(ctx *gin.Context) {
+	var req couponRequest
+
+	// Parse and validate coupon ID from URL
+	couponID, err := uuid.FromString(ctx.Param("id"))
+	if err != nil {
+		ctx.Error(service.NewError(service.ErrValidation, "invalid coupon ID"))
+		return
+	}
+
+	if !bindJSON(ctx, &req) {
+		return
+	}
+
+	if err := cc.couponService.UpdateCoupon(req.coupon(couponID)); err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"message": "Coupon updated successfully"})
+}
+
+// DeleteCoupon handles the deletion of a coupon by ID
+func (cc *CouponController) DeleteCoupon(ctx *gin.Context) {
+	// Parse and validate coupon ID from URL
+	couponID, err := uuid.FromString(ctx.Param("id"))
+	if err != nil {
+		ctx.Error(service.NewError(service.ErrValidation, "invalid coupon ID"))
+		return
+	}
+
+	if err := cc.couponService.DeleteCoupon(couponID); err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"message": "Coupon deleted successfully"})
+}
+
+// GetCouponByID handles retrieving a coupon by its ID
+func (cc *CouponController) GetCouponByID(ctx *gin.Context) {
+	// Parse and validate coupon ID from URL
+	couponID, err := uuid.FromString(ctx.Param("id"))
+	if err != nil {
+		ctx.Error(service.NewError(service.ErrValidation, "invalid coupon ID"))
+		return
+	}
+
+	coupon, err := cc.couponService.GetCouponByID(couponID)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, newCouponResponse(coupon))
+}
+
+// GetAllCoupons handles retrieving every coupon
+func (cc *CouponController) GetAllCoupons(ctx *gin.Context) {
+	coupons, err := cc.couponService.GetAllCoupons()
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, newCouponResponses(coupons))
+}
@@ -4,6 +4,7 @@ import (
 	"dalabio/internal/entity"
 	"dalabio/internal/service"
 	"net/http"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/gofrs/uuid"
@@ -19,19 +20,100 @@ func NewCourseController(courseService service.CourseService) *CourseController
 	return &CourseController{courseService: courseService}
 }
 
+// courseRequest is the body accepted to create or update a course.
+// The instructor, enrollment count and version are set by the server.
+type courseRequest struct {
+	Title       string     `json:"title" binding:"required"`
+	Description string     `json:"description"`
+	Duration    string     `json:"duration"`
+	Category    string     `json:"category"`
+	SpaceID     *uuid.UUID `json:"space_id"`
+	MembersOnly bool       `json:"members_only"`
+	ContentURL  []string   `json:"content_url"`
+	Outline     string     `json:"outline"`
+	Status      string     `json:"status"`
+	Price       float64    `json:"price"`
+	Currency    string     `json:"currency"`
+}
+
+// course maps the request onto a course with the given ID
+func (req *courseRequest) course(courseID uuid.UUID) *entity.Course {
+	return &entity.Course{
+		ID:          courseID,
+		Title:       req.Title,
+		Description: req.Description,
+		Duration:    req.Duration,
+		Category:    req.Category,
+		SpaceID:     req.SpaceID,
+		MembersOnly: req.MembersOnly,
+		ContentURL:  req.ContentURL,
+		Outline:     req.Outline,
+		Status:      req.Status,
+		Price:       req.Price,
+		Currency:    req.Currency,
+	}
+}
+
+// courseResponse is a course as shown to clients, without its version and deletion time
+type courseResponse struct {
+	ID            uuid.UUID  `json:"id"`
+	Title         string     `json:"title"`
+	Description   string     `json:"description"`
+	Duration      string     `json:"duration"`
+	Category      string     `json:"category"`
+	InstructorID  uuid.UUID  `json:"instructor_id"`
+	SpaceID       *uuid.UUID `json:"space_id,omitempty"`
+	MembersOnly   bool       `json:"members_only"`
+	EnrolledCount int        `json:"enrolled_count"`
+	ContentURL    []string   `json:"content_url"`
+	Outline       string     `json:"outline,omitempty"`
+	Status        string     `json:"status"`
+	Price         float64    `json:"price"`
+	Currency      string     `json:"currency"`
+	CreatedAt     time.Time  `json:"created_at"`
+	UpdatedAt     time.Time  `json:"updated_at"`
+}
+
+func newCourseResponse(course *entity.Course) *courseResponse {
+	return &courseResponse{
+		ID:            course.ID,
+		Title:         course.Title,
+		Description:   course.Description,
+		Duration:      course.Duration,
+		Category:      course.Category,
+		InstructorID:  course.InstructorID,
+		SpaceID:       course.SpaceID,
+		MembersOnly:   course.MembersOnly,
+		EnrolledCount: course.EnrolledCount,
+		ContentURL:    course.ContentURL,
+		Outline:       course.Outline,
+		Status:        course.Status,
+		Price:         course.Price,
+		Currency:      course.Currency,
+		CreatedAt:     course.CreatedAt,
+		UpdatedAt:     course.UpdatedAt,
+	}
+}
+
+func newCourseResponses(courses []*entity.Course) []*courseResponse {
+	responses := make([]*courseResponse, 0, len(courses))
+	for _, course := range courses {
+		responses = append(responses, newCourseResponse(course))
+	}
+	return responses
+}
+
 // CreateCourse handles the creation of a new course
 func (c *CourseController) CreateCourse(ctx *gin.Context) {
-	var course entity.Course
-	// Bind JSON to the course struct, which now includes ContentURL as []string
-	if err := ctx.ShouldBindJSON(&course); err != nil {
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	var course courseRequest
+	// Bind JSON to the request struct, which includes ContentURL as []string
+	if !bindJSON(ctx, &course) {
 		return
 	}
 
 	// Get the user ID from the request context set by the AuthMiddleware
-	instructorID, exists := ctx.Get("userID")
-	if !exists {
-		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "Instructor ID is required"})
+	instructorID, ok := authenticatedUser(ctx)
+	if !ok {
 		return
 	}
 
@@ -44,41 +126,45 @@ func (c *CourseController) CreateCourse(ctx *gin.Context) {
 		course.Outline,
 		course.ContentURL, // Pass the ContentURL slice directly
 		course.Status,
-		course.EnrolledCount,
-		course.Version,
-		instructorID.(uuid.UUID), // Cast to uuid.UUID, assuming instructorID is stored as a UUID
+		course.Price,
+		course.Currency,
+		instructorID,
+		course.SpaceID,
+		course.MembersOnly,
 	)
 	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		ctx.Error(err)
 		return
 	}
 
-	ctx.JSON(http.StatusOK, createdCourse)
+	ctx.JSON(http.StatusOK, newCourseResponse(createdCourse))
 }
 
 // UpdateCourse handles the update of an existing course
 func (cc *CourseController) UpdateCourse(ctx *gin.Context) {
-	var course entity.Course
+	var req courseRequest
 
 	// Parse and validate course ID from URL
 	courseIdParam := ctx.Param("id")
 	courseID, err := uuid.FromString(courseIdParam)
 	if err != nil {
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid course ID"})
+		ctx.Error(service.NewError(service.ErrValidation, "invalid course ID"))
 		return
 	}
 
-	// Bind JSON input to course struct
-	if err := ctx.ShouldBindJSON(&course); err != nil {
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	// Bind JSON input to the request struct
+	if !bindJSON(ctx, &req) {
 		return
 	}
 
-	course.ID = courseID
+	userID, ok := authenticatedUser(ctx)
+	if !ok {
+		return
+	}
 
 	// Call service to update course
-	if err := cc.courseService.UpdateCourse(&course); err != nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	if err := cc.courseService.UpdateCourse(req.course(courseID), userID); err != nil {
+		ctx.Error(err)
 		return
 	}
 
@@ -91,13 +177,18 @@ func (cc *CourseController) DeleteCourse(ctx *gin.Context) {
 	courseIdParam := ctx.Param("id")
 	courseID, err := uuid.FromString(courseIdParam)
 	if err != nil {
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid course ID"})
+		ctx.Error(service.NewError(service.ErrValidation, "invalid course ID"))
+		return
+	}
+
+	userID, ok := authenticatedUser(ctx)
+	if !ok {
 		return
 	}
 
 	// Call service to delete course
-	if err := cc.courseService.DeleteCourse(courseID); err != nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	if err := cc.courseService.DeleteCourse(courseID, userID); err != nil {
+		ctx.Error(err)
 		return
 	}
 
@@ -110,28 +201,53 @@ func (cc *CourseController) GetCourseByID(ctx *gin.Context) {
 	courseIdParam := ctx.Param("id")
 	courseID, err := uuid.FromString(courseIdParam)
 	if err != nil {
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid course ID"})
+		ctx.Error(service.NewError(service.ErrValidation, "invalid course ID"))
+		return
+	}
+
+	userID, ok := authenticatedUser(ctx)
+	if !ok {
 		return
 	}
 
 	// Call service to get course
-	course, err := cc.courseService.GetCourseByID(courseID)
+	course, err := cc.courseService.GetCourseByID(courseID, userID)
 	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		ctx.Error(err)
 		return
 	}
 
-	ctx.JSON(http.StatusOK, course)
+	ctx.JSON(http.StatusOK, newCourseResponse(course))
 }
 
 func (cc *CourseController) GetAllCourses(ctx *gin.Context) {
+	userID, ok := authenticatedUser(ctx)
+	if !ok {
+		return
+	}
 
 	// Call service to get courses
-	courses, err := cc.courseService.GetAllCourses()
+	courses, err := cc.courseService.GetAllCourses(userID)
 	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		ctx.Error(err)
 		return
 	}
 	// respond success
-	ctx.JSON(http.StatusOK, courses)
+	ctx.JSON(http.StatusOK, newCourseResponses(courses))
+}
+
+// GetSpaceCourses lists the courses of a space the authenticated user can see
+func (cc *CourseController) GetSpaceCourses(ctx *gin.Context) {
+	spaceID, userID, ok := spaceAndUser(ctx)
+	if !ok {
+		return
+	}
+
+	courses, err := cc.courseService.GetSpaceCourses(spaceID, userID)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, newCourseResponses(courses))
 }
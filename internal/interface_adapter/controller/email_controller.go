@@ -0,0 +1,90 @@
+package controller
+
+import (
+	"crypto/subtle"
+	"dalabio/internal/service"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// EmailController struct that defines the email deliverability controller with its service
+type EmailController struct {
+	emailService  service.EmailService
+	webhookSecret string
+}
+
+// NewEmailController creates a new EmailController instance; bounce reports are refused without a webhook secret
+func NewEmailController(emailService service.EmailService, webhookSecret string) *EmailController {
+	return &EmailController{emailService: emailService, webhookSecret: webhookSecret}
+}
+
+// bounceRequest is a bounce or complaint reported by the mail provider
+type bounceRequest struct {
+	Email  string `json:"email" binding:"required"`
+	Type   string `json:"type" binding:"required"` // "hard", "soft" or "complaint"
+	Detail string `json:"detail"`                  // Diagnostic given by the receiving server
+}
+
+// suppressRequest is the body accepted when an admin suppresses an address
+type suppressRequest struct {
+	Email  string `json:"email" binding:"required"`
+	Detail string `json:"detail"`
+}
+
+// RecordBounce receives the bounces and complaints of the mail provider, authenticated by the X-Webhook-Secret header
+func (ec *EmailController) RecordBounce(ctx *gin.Context) {
+	secret := ctx.GetHeader("X-Webhook-Secret")
+	if ec.webhookSecret == "" || subtle.ConstantTimeCompare([]byte(secret), []byte(ec.webhookSecret)) != 1 {
+		ctx.Error(service.NewError(service.ErrUnauthorized, "invalid webhook secret"))
+		return
+	}
+
+	var request bounceRequest
+	if !bindJSON(ctx, &request) {
+		return
+	}
+
+	if err := ec.emailService.RecordBounce(request.Email, request.Type, request.Detail); err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"message": "Bounce recorded"})
+}
+
+// GetSuppressions lists the addresses no email is sent to
+func (ec *EmailController) GetSuppressions(ctx *gin.Context) {
+	suppressions, err := ec.emailService.GetSuppressions()
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, suppressions)
+}
+
+// Suppress stops every email to an address
+func (ec *EmailController) Suppress(ctx *gin.Context) {
+	var request suppressRequest
+	if !bindJSON(ctx, &request) {
+		return
+	}
+
+	if err := ec.emailService.Suppress(request.Email, request.Detail); err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, gin.H{"message": "Address suppressed"})
+}
+
+// Unsuppress allows emails to an address again
+func (ec *EmailController) Unsuppress(ctx *gin.Context) {
+	if err := ec.emailService.Unsuppress(ctx.Param("email")); err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"message": "Address unsuppressed"})
+}
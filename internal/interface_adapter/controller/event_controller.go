@@ -0,0 +1,69 @@
+package controller
+
+import (
+	"dalabio/internal/framework/realtime"
+	"dalabio/internal/service"
+	"dalabio/pkg/middleware"
+	"io"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// streamKeepAlive is how often an idle event stream is pinged so proxies keep it open
+const streamKeepAlive = 30 * time.Second
+
+// streamTokenCheck is how often an event stream checks that its token was neither revoked nor expired meanwhile
+const streamTokenCheck = time.Minute
+
+// EventController struct that defines the real-time events controller with its service
+type EventController struct {
+	eventService service.EventService
+}
+
+// NewEventController creates a new EventController instance
+func NewEventController(eventService service.EventService) *EventController {
+	return &EventController{eventService: eventService}
+}
+
+// Stream delivers the domain events the authenticated user may see as Server-Sent Events
+func (ec *EventController) Stream(ctx *gin.Context) {
+	userID, ok := authenticatedUser(ctx)
+	if !ok {
+		return
+	}
+
+	events, unsubscribe := ec.eventService.Subscribe(userID)
+	defer unsubscribe()
+
+	streamEvents(ctx, events)
+}
+
+// streamEvents writes events as Server-Sent Events until the client disconnects or the token of the stream
+// is revoked, by signing out or changing the password, or expires
+func streamEvents(ctx *gin.Context, events <-chan realtime.Event) {
+	keepAlive := time.NewTicker(streamKeepAlive)
+	defer keepAlive.Stop()
+	tokenCheck := time.NewTicker(streamTokenCheck)
+	defer tokenCheck.Stop()
+
+	ctx.Header("Cache-Control", "no-cache")
+	ctx.Header("X-Accel-Buffering", "no")
+	ctx.Stream(func(w io.Writer) bool {
+		select {
+		case event, open := <-events:
+			if !open {
+				return false
+			}
+			ctx.SSEvent(event.Type, event.Data)
+			return true
+		case <-keepAlive.C:
+			ctx.SSEvent("ping", "")
+			return true
+		case <-tokenCheck.C:
+			return middleware.TokenValid(ctx)
+		case <-ctx.Request.Context().Done():
+			return false
+		}
+	})
+}
@@ -0,0 +1,65 @@
+package controller
+
+import (
+	"dalabio/internal/service"
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gofrs/uuid"
+)
+
+// InvoiceController struct that defines the invoice controller with its service
+type InvoiceController struct {
+	invoiceService service.InvoiceService
+}
+
+// NewInvoiceController creates a new InvoiceController instance
+func NewInvoiceController(invoiceService service.InvoiceService) *InvoiceController {
+	return &InvoiceController{invoiceService: invoiceService}
+}
+
+// GetPaymentInvoice returns the invoice of a payment as JSON, HTML or PDF depending on the format query parameter
+func (ic *InvoiceController) GetPaymentInvoice(ctx *gin.Context) {
+
+	// Parse and validate payment ID from URL
+	paymentID, err := uuid.FromString(ctx.Param("id"))
+	if err != nil {
+		ctx.Error(service.NewError(service.ErrValidation, "invalid payment ID"))
+		return
+	}
+
+	format := ctx.DefaultQuery("format", service.InvoiceFormatJSON)
+	if format != service.InvoiceFormatJSON && format != service.InvoiceFormatHTML && format != service.InvoiceFormatPDF {
+		ctx.Error(service.NewError(service.ErrValidation, "invalid format, expected json, html or pdf"))
+		return
+	}
+
+	// Get the user ID from the request context set by the AuthMiddleware
+	userID, ok := authenticatedUser(ctx)
+	if !ok {
+		return
+	}
+
+	invoice, err := ic.invoiceService.GetInvoiceForPayment(paymentID, userID)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	if format == service.InvoiceFormatJSON {
+		ctx.JSON(http.StatusOK, invoice)
+		return
+	}
+
+	body, contentType, err := ic.invoiceService.RenderInvoice(invoice, format)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	if format == service.InvoiceFormatPDF {
+		ctx.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", invoice.Number+".pdf"))
+	}
+	ctx.Data(http.StatusOK, contentType, body)
+}
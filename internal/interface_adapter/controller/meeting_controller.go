@@ -4,6 +4,7 @@ import (
 	"dalabio/internal/entity"
 	"dalabio/internal/service"
 	"net/http"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/gofrs/uuid"
@@ -21,14 +22,90 @@ func NewMeetingController(meetingService service.MeetingService) *MeetingControl
 	}
 }
 
+// meetingResponse is a meeting as shown to clients, without its deletion time
+type meetingResponse struct {
+	ID              uuid.UUID   `json:"id"`
+	Title           string      `json:"title"`
+	Description     string      `json:"description,omitempty"`
+	Duration        string      `json:"duration"`
+	StartTime       time.Time   `json:"start_time"`
+	EndTime         time.Time   `json:"end_time"`
+	Location        string      `json:"location,omitempty"`
+	AttendeeIDs     []uuid.UUID `json:"attendee_ids,omitempty"`
+	AttendeeNames   []string    `json:"attendee_names,omitempty"`
+	AttendeeEmails  []string    `json:"attendee_emails,omitempty"`
+	AttendeeStatus  []string    `json:"attendee_status,omitempty"`
+	MeetingType     string      `json:"meeting_type"`
+	Status          string      `json:"status"`
+	JoinURL         []string    `json:"join_url,omitempty"`
+	MaximumCapacity int         `json:"maximum_capacity,omitempty"`
+	SpaceID         *uuid.UUID  `json:"space_id,omitempty"`
+	MembersOnly     bool        `json:"members_only"`
+	CreatedAt       time.Time   `json:"created_at"`
+	UpdatedAt       time.Time   `json:"updated_at"`
+}
+
+func newMeetingResponse(meeting *entity.Meeting) *meetingResponse {
+	return &meetingResponse{
+		ID:              meeting.ID,
+		Title:           meeting.Title,
+		Description:     meeting.Description,
+		Duration:        meeting.Duration,
+		StartTime:       meeting.StartTime,
+		EndTime:         meeting.EndTime,
+		Location:        meeting.Location,
+		AttendeeIDs:     meeting.AttendeeIDs,
+		AttendeeNames:   meeting.AttendeeNames,
+		AttendeeEmails:  meeting.AttendeeEmails,
+		AttendeeStatus:  meeting.AttendeeStatus,
+		MeetingType:     meeting.MeetingType,
+		Status:          meeting.Status,
+		JoinURL:         meeting.JoinURL,
+		MaximumCapacity: meeting.MaximumCapacity,
+		SpaceID:         meeting.SpaceID,
+		MembersOnly:     meeting.MembersOnly,
+		CreatedAt:       meeting.CreatedAt,
+		UpdatedAt:       meeting.UpdatedAt,
+	}
+}
+
+func newMeetingResponses(meetings []*entity.Meeting) []*meetingResponse {
+	responses := make([]*meetingResponse, 0, len(meetings))
+	for _, meeting := range meetings {
+		responses = append(responses, newMeetingResponse(meeting))
+	}
+	return responses
+}
+
 // GetAllMeetings returns all meetings
 func (mc *MeetingController) GetAllMeetings(ctx *gin.Context) {
-	meetings, err := mc.meetingService.GetAllMeetings()
+	userID, ok := authenticatedUser(ctx)
+	if !ok {
+		return
+	}
+
+	meetings, err := mc.meetingService.GetAllMeetings(userID)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+	ctx.JSON(http.StatusOK, newMeetingResponses(meetings))
+}
+
+// GetSpaceMeetings lists the meetings of a space the authenticated user can see
+func (mc *MeetingController) GetSpaceMeetings(ctx *gin.Context) {
+	spaceID, userID, ok := spaceAndUser(ctx)
+	if !ok {
+		return
+	}
+
+	meetings, err := mc.meetingService.GetSpaceMeetings(spaceID, userID)
 	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		ctx.Error(err)
 		return
 	}
-	ctx.JSON(http.StatusOK, meetings)
+
+	ctx.JSON(http.StatusOK, newMeetingResponses(meetings))
 }
 
 // GetMeetingByID returns a meeting by its ID
@@ -39,64 +116,120 @@ func (mc *MeetingController) GetMeetingByID(ctx *gin.Context) {
 	meetingID, err := uuid.FromString(meetingIdParam)
 
 	if err != nil {
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid meeting ID"})
+		ctx.Error(service.NewError(service.ErrValidation, "invalid meeting ID"))
+		return
+	}
+
+	userID, ok := authenticatedUser(ctx)
+	if !ok {
 		return
 	}
 
 	//call services
-	meeting, err := mc.meetingService.GetMeetingByID(meetingID)
+	meeting, err := mc.meetingService.GetMeetingByID(meetingID, userID)
 	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		ctx.Error(err)
 		return
 	}
-	ctx.JSON(http.StatusOK, meeting)
+	ctx.JSON(http.StatusOK, newMeetingResponse(meeting))
+
+}
 
+// meetingRequest is the body accepted to create or update a meeting
+type meetingRequest struct {
+	Title           string      `json:"title" binding:"required"`
+	Description     string      `json:"description"`
+	Duration        string      `json:"duration" binding:"required"`
+	StartTime       time.Time   `json:"start_time" binding:"required"`
+	EndTime         time.Time   `json:"end_time" binding:"required"`
+	Location        string      `json:"location"`
+	AttendeeIDs     []uuid.UUID `json:"attendee_ids"`
+	AttendeeNames   []string    `json:"attendee_names"`
+	AttendeeEmails  []string    `json:"attendee_emails"`
+	AttendeeStatus  []string    `json:"attendee_status"`
+	MeetingType     string      `json:"meeting_type"`
+	Status          string      `json:"status"` // Set to "cancelled" to cancel the meeting
+	JoinURL         []string    `json:"join_url"`
+	MaximumCapacity int         `json:"maximum_capacity"`
+	SpaceID         *uuid.UUID  `json:"space_id"`
+	MembersOnly     bool        `json:"members_only"`
+}
+
+// meeting maps the request onto a meeting with the given ID
+func (req *meetingRequest) meeting(meetingID uuid.UUID) *entity.Meeting {
+	return &entity.Meeting{
+		ID:              meetingID,
+		Title:           req.Title,
+		Description:     req.Description,
+		Duration:        req.Duration,
+		StartTime:       req.StartTime,
+		EndTime:         req.EndTime,
+		Location:        req.Location,
+		AttendeeIDs:     req.AttendeeIDs,
+		AttendeeNames:   req.AttendeeNames,
+		AttendeeEmails:  req.AttendeeEmails,
+		AttendeeStatus:  req.AttendeeStatus,
+		MeetingType:     req.MeetingType,
+		Status:          req.Status,
+		JoinURL:         req.JoinURL,
+		MaximumCapacity: req.MaximumCapacity,
+		SpaceID:         req.SpaceID,
+		MembersOnly:     req.MembersOnly,
+	}
 }
 
 // CreateMeeting creates a new meeting
 func (mc *MeetingController) CreateMeeting(ctx *gin.Context) {
 
-	// Bind JSON input to meeting struct
-	var meeting entity.Meeting
+	// Bind JSON input to the request struct
+	var meeting meetingRequest
 
-	if err := ctx.ShouldBindJSON(&meeting); err != nil {
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	if !bindJSON(ctx, &meeting) {
+		return
+	}
+
+	userID, ok := authenticatedUser(ctx)
+	if !ok {
 		return
 	}
 
 	// Call service to create meeting
-	createMeeting, err := mc.meetingService.CreateMeeting(meeting.Title, meeting.Description, meeting.Duration, meeting.Location, meeting.MeetingType, meeting.Status, meeting.AttendeeIDs, meeting.AttendeeNames, meeting.AttendeeEmails, meeting.AttendeeStatus, meeting.JoinURL, meeting.MaximumCapacity)
+	createMeeting, err := mc.meetingService.CreateMeeting(meeting.Title, meeting.Description, meeting.Duration, meeting.StartTime, meeting.EndTime, meeting.Location, meeting.MeetingType, meeting.Status, meeting.AttendeeIDs, meeting.AttendeeNames, meeting.AttendeeEmails, meeting.AttendeeStatus, meeting.JoinURL, meeting.MaximumCapacity, meeting.SpaceID, meeting.MembersOnly, userID)
 
 	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		ctx.Error(err)
 		return
 	}
 	// respon with created meeting
-	ctx.JSON(http.StatusOK, createMeeting)
+	ctx.JSON(http.StatusOK, newMeetingResponse(createMeeting))
 }
 
 // UpdateMeeting handles the update of an existing meeting
 func (mc *MeetingController) UpdateMeeting(ctx *gin.Context) {
-	var Meeting entity.Meeting
+	var req meetingRequest
 
 	// Parse and validate meeting ID from URL
 	meetingIdParam := ctx.Param("id")
 	meetingID, err := uuid.FromString(meetingIdParam)
 
 	if err != nil {
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid meeting ID"})
+		ctx.Error(service.NewError(service.ErrValidation, "invalid meeting ID"))
+		return
+	}
+
+	// Bind JSON input to the request struct
+	if !bindJSON(ctx, &req) {
 		return
 	}
 
-	// Bind JSON input to meeting struct
-	if err := ctx.ShouldBindJSON(&Meeting); err != nil {
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	userID, ok := authenticatedUser(ctx)
+	if !ok {
 		return
 	}
-	Meeting.ID = meetingID
+
 	// Call service to update meeting
-	if err := mc.meetingService.UpdateMeeting(&Meeting); err != nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	if err := mc.meetingService.UpdateMeeting(req.meeting(meetingID), userID); err != nil {
+		ctx.Error(err)
 		return
 	}
 
@@ -112,13 +245,18 @@ func (mc *MeetingController) DeleteMeeting(ctx *gin.Context) {
 	meetingID, err := uuid.FromString(meetingIdParam)
 
 	if err != nil {
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid meeting ID"})
+		ctx.Error(service.NewError(service.ErrValidation, "invalid meeting ID"))
+		return
+	}
+
+	userID, ok := authenticatedUser(ctx)
+	if !ok {
 		return
 	}
 
 	// Call service to delete meeting
-	if err := mc.meetingService.DeleteMeeting(meetingID); err != nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	if err := mc.meetingService.DeleteMeeting(meetingID, userID); err != nil {
+		ctx.Error(err)
 		return
 	}
 
@@ -0,0 +1,213 @@
+package controller
+
+import (
+	"dalabio/internal/service"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gofrs/uuid"
+)
+
+// MessageController struct that defines the direct messaging controller with its service
+type MessageController struct {
+	messageService service.MessageService
+}
+
+// NewMessageController creates a new MessageController instance
+func NewMessageController(messageService service.MessageService) *MessageController {
+	return &MessageController{messageService: messageService}
+}
+
+// startConversationRequest is the body accepted when starting a conversation
+type startConversationRequest struct {
+	ParticipantIDs []uuid.UUID `json:"participant_ids" binding:"required"` // One user for a direct conversation, several for a group
+	Title          string      `json:"title"`                              // Optional name of a group
+}
+
+// sendMessageRequest is the body accepted when sending a message
+type sendMessageRequest struct {
+	Body string `json:"body" binding:"required"`
+}
+
+// markReadRequest is the optional body of the read endpoint
+type markReadRequest struct {
+	MessageID *uuid.UUID `json:"message_id"` // Last message read, the whole conversation when omitted
+}
+
+// conversationAndUser parses the conversation ID from the URL and reads the authenticated user ID
+func conversationAndUser(ctx *gin.Context) (uuid.UUID, uuid.UUID, bool) {
+	conversationID, err := uuid.FromString(ctx.Param("id"))
+	if err != nil {
+		ctx.Error(service.NewError(service.ErrValidation, "invalid conversation ID"))
+		return uuid.Nil, uuid.Nil, false
+	}
+
+	userID, ok := authenticatedUser(ctx)
+	if !ok {
+		return uuid.Nil, uuid.Nil, false
+	}
+	return conversationID, userID, true
+}
+
+// StartConversation opens a conversation with one or several users
+func (mc *MessageController) StartConversation(ctx *gin.Context) {
+	userID, ok := authenticatedUser(ctx)
+	if !ok {
+		return
+	}
+
+	var request startConversationRequest
+	if !bindJSON(ctx, &request) {
+		return
+	}
+
+	conversation, err := mc.messageService.StartConversation(userID, request.ParticipantIDs, request.Title)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, conversation)
+}
+
+// GetConversations lists the conversations of the authenticated user
+func (mc *MessageController) GetConversations(ctx *gin.Context) {
+	userID, ok := authenticatedUser(ctx)
+	if !ok {
+		return
+	}
+
+	conversations, err := mc.messageService.GetConversations(userID)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, conversations)
+}
+
+// GetUnreadCount returns the number of unread messages of the authenticated user
+func (mc *MessageController) GetUnreadCount(ctx *gin.Context) {
+	userID, ok := authenticatedUser(ctx)
+	if !ok {
+		return
+	}
+
+	count, err := mc.messageService.CountUnread(userID)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"unread_count": count})
+}
+
+// GetConversation returns a conversation of the authenticated user
+func (mc *MessageController) GetConversation(ctx *gin.Context) {
+	conversationID, userID, ok := conversationAndUser(ctx)
+	if !ok {
+		return
+	}
+
+	conversation, err := mc.messageService.GetConversation(conversationID, userID)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, conversation)
+}
+
+// GetMessages returns a page of the history of a conversation; ?before=<next_cursor>&limit=50
+func (mc *MessageController) GetMessages(ctx *gin.Context) {
+	conversationID, userID, ok := conversationAndUser(ctx)
+	if !ok {
+		return
+	}
+
+	var before *uuid.UUID
+	if cursor := ctx.Query("before"); cursor != "" {
+		messageID, err := uuid.FromString(cursor)
+		if err != nil {
+			ctx.Error(service.NewError(service.ErrValidation, "invalid cursor"))
+			return
+		}
+		before = &messageID
+	}
+
+	limit := 0
+	if value := ctx.Query("limit"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil {
+			ctx.Error(service.NewError(service.ErrValidation, "invalid limit"))
+			return
+		}
+		limit = parsed
+	}
+
+	messages, next, err := mc.messageService.GetMessages(conversationID, userID, before, limit)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"messages": messages, "next_cursor": next})
+}
+
+// SendMessage posts a message to a conversation
+func (mc *MessageController) SendMessage(ctx *gin.Context) {
+	conversationID, userID, ok := conversationAndUser(ctx)
+	if !ok {
+		return
+	}
+
+	var request sendMessageRequest
+	if !bindJSON(ctx, &request) {
+		return
+	}
+
+	message, err := mc.messageService.SendMessage(conversationID, userID, request.Body)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, message)
+}
+
+// MarkRead moves the read receipt of the authenticated user
+func (mc *MessageController) MarkRead(ctx *gin.Context) {
+	conversationID, userID, ok := conversationAndUser(ctx)
+	if !ok {
+		return
+	}
+
+	var request markReadRequest
+	if ctx.Request.ContentLength > 0 {
+		if !bindJSON(ctx, &request) {
+			return
+		}
+	}
+
+	participant, err := mc.messageService.MarkRead(conversationID, userID, request.MessageID)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, participant)
+}
+
+// Stream delivers new messages and read receipts as Server-Sent Events until the client disconnects
+func (mc *MessageController) Stream(ctx *gin.Context) {
+	userID, ok := authenticatedUser(ctx)
+	if !ok {
+		return
+	}
+
+	events, unsubscribe := mc.messageService.Subscribe(userID)
+	defer unsubscribe()
+
+	streamEvents(ctx, events)
+}
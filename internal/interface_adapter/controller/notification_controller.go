@@ -0,0 +1,140 @@
+package controller
+
+import (
+	"dalabio/internal/entity"
+	"dalabio/internal/service"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gofrs/uuid"
+)
+
+// NotificationController struct that defines the notification center controller with its service
+type NotificationController struct {
+	notificationService service.NotificationService
+}
+
+// NewNotificationController creates a new NotificationController instance
+func NewNotificationController(notificationService service.NotificationService) *NotificationController {
+	return &NotificationController{notificationService: notificationService}
+}
+
+// preferenceRequest turns a category of notifications on or off for a channel
+type preferenceRequest struct {
+	Category string `json:"category" binding:"required"`
+	Channel  string `json:"channel" binding:"required"`
+	Enabled  bool   `json:"enabled"`
+}
+
+// updatePreferencesRequest is the body accepted when changing notification preferences
+type updatePreferencesRequest struct {
+	Preferences []*preferenceRequest `json:"preferences" binding:"required,dive"`
+}
+
+// GetNotifications returns the inbox of the authenticated user; ?unread=true&limit=50
+func (nc *NotificationController) GetNotifications(ctx *gin.Context) {
+	userID, ok := authenticatedUser(ctx)
+	if !ok {
+		return
+	}
+
+	unreadOnly := ctx.Query("unread") == "true"
+
+	limit := 0
+	if value := ctx.Query("limit"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil {
+			ctx.Error(service.NewError(service.ErrValidation, "invalid limit"))
+			return
+		}
+		limit = parsed
+	}
+
+	notifications, unread, err := nc.notificationService.GetNotifications(userID, unreadOnly, limit)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"notifications": notifications, "unread_count": unread})
+}
+
+// MarkRead marks a notification of the authenticated user as read
+func (nc *NotificationController) MarkRead(ctx *gin.Context) {
+	notificationID, err := uuid.FromString(ctx.Param("id"))
+	if err != nil {
+		ctx.Error(service.NewError(service.ErrValidation, "invalid notification ID"))
+		return
+	}
+
+	userID, ok := authenticatedUser(ctx)
+	if !ok {
+		return
+	}
+
+	if err := nc.notificationService.MarkRead(userID, notificationID); err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"message": "Notification marked as read"})
+}
+
+// MarkAllRead marks every notification of the authenticated user as read
+func (nc *NotificationController) MarkAllRead(ctx *gin.Context) {
+	userID, ok := authenticatedUser(ctx)
+	if !ok {
+		return
+	}
+
+	count, err := nc.notificationService.MarkAllRead(userID)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"marked": count})
+}
+
+// GetPreferences returns the notification preferences of the authenticated user
+func (nc *NotificationController) GetPreferences(ctx *gin.Context) {
+	userID, ok := authenticatedUser(ctx)
+	if !ok {
+		return
+	}
+
+	preferences, err := nc.notificationService.GetPreferences(userID)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, preferences)
+}
+
+// UpdatePreferences turns categories of notifications on or off per channel
+func (nc *NotificationController) UpdatePreferences(ctx *gin.Context) {
+	userID, ok := authenticatedUser(ctx)
+	if !ok {
+		return
+	}
+
+	var request updatePreferencesRequest
+	if !bindJSON(ctx, &request) {
+		return
+	}
+
+	changes := make([]*entity.NotificationPreference, 0, len(request.Preferences))
+	for _, preference := range request.Preferences {
+		changes = append(changes, &entity.NotificationPreference{Category: preference.Category, Channel: preference.Channel, Enabled: preference.Enabled})
+	}
+
+	preferences, err := nc.notificationService.UpdatePreferences(userID, changes)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, preferences)
+}
@@ -0,0 +1,127 @@
+package controller
+
+import (
+	"crypto/subtle"
+	"dalabio/internal/service"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gofrs/uuid"
+)
+
+// oidcStateCookie binds a sign-in to the browser that started it, so that an attacker cannot complete
+// their own sign-in in the browser of a victim with a callback link
+const oidcStateCookie = "oidc_state"
+
+// OIDCController handles the sign-ins with OpenID Connect identity providers
+type OIDCController struct {
+	oidcService service.OIDCService
+}
+
+// NewOIDCController creates a new OIDC controller
+func NewOIDCController(oidcService service.OIDCService) *OIDCController {
+	return &OIDCController{oidcService: oidcService}
+}
+
+// ListProviders lists the identity providers users can sign in with
+func (oc *OIDCController) ListProviders(ctx *gin.Context) {
+	ctx.JSON(http.StatusOK, gin.H{"providers": oc.oidcService.Providers()})
+}
+
+// Authorize starts a sign-in and returns the URL of the provider's sign-in page to send the user to
+func (oc *OIDCController) Authorize(ctx *gin.Context) {
+	authorization, err := oc.oidcService.StartLogin(ctx.Param("provider"))
+	if err != nil {
+		log.Printf("Error starting OIDC sign-in: %v", err)
+		ctx.Error(err)
+		return
+	}
+
+	// Lax, since the provider sends the browser back with a top-level navigation from another site
+	ctx.SetSameSite(http.SameSiteLaxMode)
+	ctx.SetCookie(oidcStateCookie, authorization.State, int(time.Until(authorization.ExpiresAt).Seconds()),
+		oidcCookiePath(ctx.Param("provider")), "", ctx.Request.TLS != nil, true)
+
+	ctx.JSON(http.StatusOK, authorization)
+}
+
+// oidcCookiePath limits the state cookie to the callback of the provider
+func oidcCookiePath(provider string) string {
+	return "/users/oidc/" + provider + "/callback"
+}
+
+// Callback completes a sign-in with the code and state the provider sent back
+func (oc *OIDCController) Callback(ctx *gin.Context) {
+	// The provider reports a cancelled or refused sign-in instead of a code
+	if providerError := ctx.Query("error"); providerError != "" {
+		message := "sign-in was not completed: " + providerError
+		if description := ctx.Query("error_description"); description != "" {
+			message += " (" + description + ")"
+		}
+		ctx.Error(service.NewError(service.ErrUnauthorized, message))
+		return
+	}
+	code, state := ctx.Query("code"), ctx.Query("state")
+	if code == "" || state == "" {
+		ctx.Error(service.NewError(service.ErrValidation, "code and state are required"))
+		return
+	}
+
+	// The state must come back to the browser that started the sign-in; the cookie is single use like the state
+	cookie, err := ctx.Cookie(oidcStateCookie)
+	ctx.SetSameSite(http.SameSiteLaxMode)
+	ctx.SetCookie(oidcStateCookie, "", -1, oidcCookiePath(ctx.Param("provider")), "", ctx.Request.TLS != nil, true)
+	if err != nil || subtle.ConstantTimeCompare([]byte(cookie), []byte(state)) != 1 {
+		ctx.Error(service.ErrInvalidOIDCState)
+		return
+	}
+
+	authentication, err := oc.oidcService.CompleteLogin(ctx.Param("provider"), code, state)
+	if err != nil {
+		log.Printf("Error completing OIDC sign-in: %v", err)
+		ctx.Error(err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, newAuthenticationResponse(authentication))
+}
+
+// ListIdentities lists the identities linked to the authenticated user
+func (oc *OIDCController) ListIdentities(ctx *gin.Context) {
+	userID, ok := authenticatedUser(ctx)
+	if !ok {
+		return
+	}
+
+	identities, err := oc.oidcService.ListIdentities(userID)
+	if err != nil {
+		log.Printf("Error listing identities: %v", err)
+		ctx.Error(err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"identities": identities})
+}
+
+// Unlink removes an identity from the authenticated user
+func (oc *OIDCController) Unlink(ctx *gin.Context) {
+	userID, ok := authenticatedUser(ctx)
+	if !ok {
+		return
+	}
+	identityID, err := uuid.FromString(ctx.Param("id"))
+	if err != nil {
+		ctx.Error(service.NewError(service.ErrValidation, "invalid identity ID"))
+		return
+	}
+
+	if err := oc.oidcService.Unlink(userID, identityID); err != nil {
+		log.Printf("Error unlinking identity: %v", err)
+		ctx.Error(err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"message": "Identity unlinked successfully"})
+}
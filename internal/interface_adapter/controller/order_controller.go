@@ -0,0 +1,207 @@
+package controller
+
+import (
+	"dalabio/internal/entity"
+	"dalabio/internal/service"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gofrs/uuid"
+)
+
+// OrderController struct that defines the order controller with its service
+type OrderController struct {
+	orderService service.OrderService
+}
+
+// NewOrderController creates a new OrderController instance
+func NewOrderController(orderService service.OrderService) *OrderController {
+	return &OrderController{orderService: orderService}
+}
+
+// checkoutItemRequest is an item to buy; its price and description come from the catalogue
+type checkoutItemRequest struct {
+	ItemType string    `json:"item_type" binding:"required"` // "course" or "space_membership"
+	ItemID   uuid.UUID `json:"item_id" binding:"required"`
+}
+
+// checkoutRequest is the body accepted by the checkout endpoint
+type checkoutRequest struct {
+	Items          []*checkoutItemRequest `json:"items" binding:"required,min=1,dive"`
+	CouponCode     string                 `json:"coupon_code"`
+	BillingName    string                 `json:"billing_name"`
+	BillingAddress string                 `json:"billing_address"`
+	PaymentMethod  string                 `json:"payment_method" binding:"required"`
+	PaymentGateway string                 `json:"payment_gateway"`
+}
+
+// orderResponse is an order as shown to the user who placed it
+type orderResponse struct {
+	ID             uuid.UUID            `json:"id"`
+	Status         string               `json:"status"`
+	Currency       string               `json:"currency"`
+	Subtotal       float64              `json:"subtotal"`
+	Discount       float64              `json:"discount"`
+	CouponCode     string               `json:"coupon_code,omitempty"`
+	Total          float64              `json:"total"`
+	BillingName    string               `json:"billing_name,omitempty"`
+	BillingAddress string               `json:"billing_address,omitempty"`
+	Lines          []*orderLineResponse `json:"lines"`
+	CreatedAt      time.Time            `json:"created_at"`
+	UpdatedAt      time.Time            `json:"updated_at"`
+}
+
+// orderLineResponse is an item of an order
+type orderLineResponse struct {
+	ID          uuid.UUID `json:"id"`
+	ItemType    string    `json:"item_type"`
+	ItemID      uuid.UUID `json:"item_id"`
+	Description string    `json:"description"`
+	UnitPrice   float64   `json:"unit_price"`
+	Quantity    int       `json:"quantity"`
+	Amount      float64   `json:"amount"`
+}
+
+func newOrderResponse(order *entity.Order) *orderResponse {
+	response := &orderResponse{
+		ID:             order.ID,
+		Status:         order.Status,
+		Currency:       order.Currency,
+		Subtotal:       order.Subtotal,
+		Discount:       order.Discount,
+		CouponCode:     order.CouponCode,
+		Total:          order.Total,
+		BillingName:    order.BillingName,
+		BillingAddress: order.BillingAddress,
+		Lines:          make([]*orderLineResponse, 0, len(order.Lines)),
+		CreatedAt:      order.CreatedAt,
+		UpdatedAt:      order.UpdatedAt,
+	}
+	for _, line := range order.Lines {
+		response.Lines = append(response.Lines, &orderLineResponse{
+			ID:          line.ID,
+			ItemType:    line.ItemType,
+			ItemID:      line.ItemID,
+			Description: line.Description,
+			UnitPrice:   line.UnitPrice,
+			Quantity:    line.Quantity,
+			Amount:      line.Amount,
+		})
+	}
+	return response
+}
+
+func newOrderResponses(orders []*entity.Order) []*orderResponse {
+	responses := make([]*orderResponse, 0, len(orders))
+	for _, order := range orders {
+		responses = append(responses, newOrderResponse(order))
+	}
+	return responses
+}
+
+// checkoutResponse is the order placed at checkout with the payment to complete, none for free orders
+type checkoutResponse struct {
+	Order   *orderResponse   `json:"order"`
+	Payment *paymentResponse `json:"payment"`
+}
+
+// Checkout creates an order for the requested items and a payment intent for its total
+func (oc *OrderController) Checkout(ctx *gin.Context) {
+	var request checkoutRequest
+
+	if !bindJSON(ctx, &request) {
+		return
+	}
+
+	// Get the user ID from the request context set by the AuthMiddleware
+	userID, ok := authenticatedUser(ctx)
+	if !ok {
+		return
+	}
+
+	lines := make([]*entity.OrderLine, 0, len(request.Items))
+	for _, item := range request.Items {
+		lines = append(lines, &entity.OrderLine{ItemType: item.ItemType, ItemID: item.ItemID})
+	}
+
+	order, payment, err := oc.orderService.Checkout(userID, lines, request.CouponCode, request.BillingName, request.BillingAddress, request.PaymentMethod, request.PaymentGateway)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	response := checkoutResponse{Order: newOrderResponse(order)}
+	if payment != nil {
+		response.Payment = newPaymentResponse(payment)
+	}
+	ctx.JSON(http.StatusCreated, response)
+}
+
+// GetOrderByID returns an order of the authenticated user
+func (oc *OrderController) GetOrderByID(ctx *gin.Context) {
+	// Parse and validate order ID from URL
+	orderIdParam := ctx.Param("id")
+	orderID, err := uuid.FromString(orderIdParam)
+	if err != nil {
+		ctx.Error(service.NewError(service.ErrValidation, "invalid order ID"))
+		return
+	}
+
+	userID, ok := authenticatedUser(ctx)
+	if !ok {
+		return
+	}
+
+	order, err := oc.orderService.GetOrderByID(orderID)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	// Orders are private to the user who placed them
+	if order.UserID != userID {
+		ctx.Error(service.NewError(service.ErrNotFound, "order not found"))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, newOrderResponse(order))
+}
+
+// CancelOrder cancels a pending order of the authenticated user
+func (oc *OrderController) CancelOrder(ctx *gin.Context) {
+	orderID, err := uuid.FromString(ctx.Param("id"))
+	if err != nil {
+		ctx.Error(service.NewError(service.ErrValidation, "invalid order ID"))
+		return
+	}
+
+	userID, ok := authenticatedUser(ctx)
+	if !ok {
+		return
+	}
+
+	order, err := oc.orderService.CancelOrder(orderID, userID)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, newOrderResponse(order))
+}
+
+// GetMyOrders returns the orders placed by the authenticated user
+func (oc *OrderController) GetMyOrders(ctx *gin.Context) {
+	userID, ok := authenticatedUser(ctx)
+	if !ok {
+		return
+	}
+
+	orders, err := oc.orderService.GetOrdersByUser(userID)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, newOrderResponses(orders))
+}
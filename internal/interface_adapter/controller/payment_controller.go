@@ -4,6 +4,7 @@ import (
 	"dalabio/internal/entity"
 	"dalabio/internal/service"
 	"net/http"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/gofrs/uuid"
@@ -19,23 +20,122 @@ func NewPaymentController(paymentService service.PaymentService) *PaymentControl
 		paymentService: paymentService}
 }
 
+// createPaymentRequest is the body accepted to record a payment of the authenticated user.
+// Payments start pending; their status changes through updates and refunds.
+type createPaymentRequest struct {
+	OrderID        uuid.UUID `json:"order_id"`
+	Amount         float64   `json:"amount" binding:"required"`
+	Currency       string    `json:"currency" binding:"required"`
+	PaymentMethod  string    `json:"payment_method" binding:"required"`
+	TransactionID  string    `json:"transaction_id"`
+	PaymentGateway string    `json:"payment_gateway"`
+	Notes          string    `json:"notes"`
+}
+
+// updatePaymentRequest is the body accepted to correct the details of a payment. Its amount and status
+// are owned by the gateway.
+type updatePaymentRequest struct {
+	Currency       string `json:"currency" binding:"required"`
+	PaymentMethod  string `json:"payment_method" binding:"required"`
+	TransactionID  string `json:"transaction_id"`
+	PaymentGateway string `json:"payment_gateway"`
+	Notes          string `json:"notes"`
+}
+
+// refundRequest is the body accepted to refund a payment
+type refundRequest struct {
+	Amount float64 `json:"amount" binding:"required"`
+	Reason string  `json:"reason" binding:"required"`
+}
+
+// paymentResponse is a payment as shown to clients, without the references and notes of the gateway
+type paymentResponse struct {
+	ID            uuid.UUID         `json:"id"`
+	UserID        uuid.UUID         `json:"user_id"`
+	OrderID       uuid.UUID         `json:"order_id"`
+	Amount        float64           `json:"amount"`
+	Currency      string            `json:"currency"`
+	PaymentMethod string            `json:"payment_method"`
+	Status        string            `json:"status"`
+	PaymentDate   time.Time         `json:"payment_date"`
+	CreatedAt     time.Time         `json:"created_at"`
+	UpdatedAt     time.Time         `json:"updated_at"`
+	Refunds       []*refundResponse `json:"refunds,omitempty"`
+}
+
+func newPaymentResponse(payment *entity.Payment) *paymentResponse {
+	response := &paymentResponse{
+		ID:            payment.ID,
+		UserID:        payment.UserID,
+		OrderID:       payment.OrderID,
+		Amount:        payment.Amount,
+		Currency:      payment.Currency,
+		PaymentMethod: payment.PaymentMethod,
+		Status:        payment.Status,
+		PaymentDate:   payment.PaymentDate,
+		CreatedAt:     payment.CreatedAt,
+		UpdatedAt:     payment.UpdatedAt,
+	}
+	for _, refund := range payment.Refunds {
+		response.Refunds = append(response.Refunds, newRefundResponse(refund))
+	}
+	return response
+}
+
+func newPaymentResponses(payments []*entity.Payment) []*paymentResponse {
+	responses := make([]*paymentResponse, 0, len(payments))
+	for _, payment := range payments {
+		responses = append(responses, newPaymentResponse(payment))
+	}
+	return responses
+}
+
+// refundResponse is a refund as shown to clients, without the reference of the gateway
+type refundResponse struct {
+	ID        uuid.UUID `json:"id"`
+	PaymentID uuid.UUID `json:"payment_id"`
+	Amount    float64   `json:"amount"`
+	Currency  string    `json:"currency"`
+	Reason    string    `json:"reason"`
+	Status    string    `json:"status"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+func newRefundResponse(refund *entity.Refund) *refundResponse {
+	return &refundResponse{
+		ID:        refund.ID,
+		PaymentID: refund.PaymentID,
+		Amount:    refund.Amount,
+		Currency:  refund.Currency,
+		Reason:    refund.Reason,
+		Status:    refund.Status,
+		CreatedAt: refund.CreatedAt,
+		UpdatedAt: refund.UpdatedAt,
+	}
+}
+
 func (pc *PaymentController) CreatePayment(ctx *gin.Context) {
 
-	var payment entity.Payment
+	var payment createPaymentRequest
+
+	if !bindJSON(ctx, &payment) {
+		return
+	}
 
-	if err := ctx.ShouldBindJSON(&payment); err != nil {
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	userID, ok := authenticatedUser(ctx)
+	if !ok {
 		return
 	}
 
-	paymentRequest, err := pc.paymentService.CreatePayment(payment.UserID, payment.OrderID, payment.Amount, payment.Currency, payment.PaymentMethod, payment.TransactionID, payment.Status, payment.PaymentGateway, payment.Notes)
+	paymentRequest, err := pc.paymentService.CreatePayment(userID, payment.OrderID, payment.Amount, payment.Currency, payment.PaymentMethod, payment.TransactionID, service.PaymentStatusPending, payment.PaymentGateway, payment.Notes)
 
 	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		ctx.Error(err)
 		return
 	}
 
-	ctx.JSON(http.StatusCreated, paymentRequest)
+	ctx.JSON(http.StatusCreated, newPaymentResponse(paymentRequest))
 
 }
 
@@ -46,55 +146,61 @@ func (pc *PaymentController) GetPaymentByID(ctx *gin.Context) {
 	paymentID, err := uuid.FromString(paymentIdParam)
 
 	if err != nil {
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid payment ID"})
+		ctx.Error(service.NewError(service.ErrValidation, "invalid payment ID"))
 		return
 	}
 
 	// Call service to get payment
 	payment, err := pc.paymentService.GetPaymentByID(paymentID)
 	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		ctx.Error(err)
 		return
 	}
 
-	ctx.JSON(http.StatusOK, payment)
+	ctx.JSON(http.StatusOK, newPaymentResponse(payment))
 }
 
 func (pc *PaymentController) GetAllPayments(ctx *gin.Context) {
 
 	payments, err := pc.paymentService.GetAllPayments()
 	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		ctx.Error(err)
 		return
 	}
 
-	ctx.JSON(http.StatusOK, payments)
+	ctx.JSON(http.StatusOK, newPaymentResponses(payments))
 }
 
 func (pc *PaymentController) UpdatePayment(ctx *gin.Context) {
 
-	var payment entity.Payment
+	var req updatePaymentRequest
 	// Parse and validate payment ID from URL
 	paymentIdParam := ctx.Param("id")
 	paymentID, err := uuid.FromString(paymentIdParam)
 
 	if err != nil {
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid payment ID"})
+		ctx.Error(service.NewError(service.ErrValidation, "invalid payment ID"))
 		return
 	}
 
-	// Bind JSON input to payment struct
+	// Bind JSON input to the request struct
 
-	if err := ctx.ShouldBindJSON(&payment); err != nil {
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	if !bindJSON(ctx, &req) {
 		return
 	}
 
-	payment.ID = paymentID
+	payment := &entity.Payment{
+		ID:             paymentID,
+		Currency:       req.Currency,
+		PaymentMethod:  req.PaymentMethod,
+		TransactionID:  req.TransactionID,
+		PaymentGateway: req.PaymentGateway,
+		Notes:          req.Notes,
+	}
 
 	// Call service to update payment
-	if err := pc.paymentService.UpdatePayment(&payment); err != nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	if err := pc.paymentService.UpdatePayment(payment); err != nil {
+		ctx.Error(err)
 		return
 	}
 
@@ -102,6 +208,23 @@ func (pc *PaymentController) UpdatePayment(ctx *gin.Context) {
 
 }
 
+// ConfirmPayment completes a pending payment that its gateway has collected
+func (pc *PaymentController) ConfirmPayment(ctx *gin.Context) {
+	paymentID, err := uuid.FromString(ctx.Param("id"))
+	if err != nil {
+		ctx.Error(service.NewError(service.ErrValidation, "invalid payment ID"))
+		return
+	}
+
+	payment, err := pc.paymentService.ConfirmPayment(paymentID)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, newPaymentResponse(payment))
+}
+
 func (pc *PaymentController) DeletePayment(ctx *gin.Context) {
 
 	// Parse and validate payment ID from URL
@@ -109,16 +232,49 @@ func (pc *PaymentController) DeletePayment(ctx *gin.Context) {
 	paymentID, err := uuid.FromString(paymentIdParam)
 
 	if err != nil {
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid payment ID"})
+		ctx.Error(service.NewError(service.ErrValidation, "invalid payment ID"))
 		return
 	}
 
 	// Call service to delete payment
 	if err := pc.paymentService.DeletePayment(paymentID); err != nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		ctx.Error(err)
 		return
 	}
 
 	ctx.JSON(http.StatusOK, gin.H{"message": "Payment Deleted Sucessfull"})
 
 }
+
+func (pc *PaymentController) RefundPayment(ctx *gin.Context) {
+
+	var refund refundRequest
+	// Parse and validate payment ID from URL
+	paymentIdParam := ctx.Param("id")
+	paymentID, err := uuid.FromString(paymentIdParam)
+
+	if err != nil {
+		ctx.Error(service.NewError(service.ErrValidation, "invalid payment ID"))
+		return
+	}
+
+	// Bind JSON input (amount and reason) to the request struct
+	if !bindJSON(ctx, &refund) {
+		return
+	}
+
+	userID, ok := authenticatedUser(ctx)
+	if !ok {
+		return
+	}
+
+	// Call service to refund payment
+	createdRefund, err := pc.paymentService.RefundPayment(paymentID, refund.Amount, refund.Reason, userID)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, newRefundResponse(createdRefund))
+
+}
@@ -0,0 +1,150 @@
+package controller
+
+import (
+	"dalabio/internal/entity"
+	"dalabio/internal/service"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gofrs/uuid"
+)
+
+// PayoutController struct that defines the payout controller with its service
+type PayoutController struct {
+	payoutService service.PayoutService
+}
+
+// NewPayoutController creates a new PayoutController instance
+func NewPayoutController(payoutService service.PayoutService) *PayoutController {
+	return &PayoutController{payoutService: payoutService}
+}
+
+// updatePayoutRequest is the body accepted when an admin settles a payout
+type updatePayoutRequest struct {
+	Status    string `json:"status" binding:"required"` // "paid" or "failed"
+	Reference string `json:"reference"`                 // Transfer reference of the bank or payment provider
+}
+
+// payoutResponse is a payout as shown to clients, without the day it was scheduled for
+type payoutResponse struct {
+	ID        uuid.UUID  `json:"id"`
+	UserID    uuid.UUID  `json:"user_id"`
+	Amount    float64    `json:"amount"`
+	Currency  string     `json:"currency"`
+	Status    string     `json:"status"`
+	Reference string     `json:"reference,omitempty"`
+	PaidAt    *time.Time `json:"paid_at,omitempty"`
+	CreatedAt time.Time  `json:"created_at"`
+	UpdatedAt time.Time  `json:"updated_at"`
+}
+
+func newPayoutResponse(payout *entity.Payout) *payoutResponse {
+	return &payoutResponse{
+		ID:        payout.ID,
+		UserID:    payout.UserID,
+		Amount:    payout.Amount,
+		Currency:  payout.Currency,
+		Status:    payout.Status,
+		Reference: payout.Reference,
+		PaidAt:    payout.PaidAt,
+		CreatedAt: payout.CreatedAt,
+		UpdatedAt: payout.UpdatedAt,
+	}
+}
+
+func newPayoutResponses(payouts []*entity.Payout) []*payoutResponse {
+	responses := make([]*payoutResponse, 0, len(payouts))
+	for _, payout := range payouts {
+		responses = append(responses, newPayoutResponse(payout))
+	}
+	return responses
+}
+
+// earningsResponse is the earnings report of a coach or instructor
+type earningsResponse struct {
+	Balances []*entity.EarningsBalance `json:"balances"`
+	Periods  []*entity.EarningsPeriod  `json:"periods"`
+	Payouts  []*payoutResponse         `json:"payouts"`
+}
+
+// GetMyEarnings returns the earnings of the authenticated coach or instructor.
+// The period (day, week, month or year, default month) groups the activity between
+// the from and to dates (YYYY-MM-DD), which default to the last twelve months.
+func (pc *PayoutController) GetMyEarnings(ctx *gin.Context) {
+
+	// Get the user ID from the request context set by the AuthMiddleware
+	userID, ok := authenticatedUser(ctx)
+	if !ok {
+		return
+	}
+
+	to := time.Now()
+	if value := ctx.Query("to"); value != "" {
+		date, err := time.Parse("2006-01-02", value)
+		if err != nil {
+			ctx.Error(service.NewError(service.ErrValidation, "invalid to date, expected YYYY-MM-DD"))
+			return
+		}
+		// Include the whole end day
+		to = date.AddDate(0, 0, 1)
+	}
+
+	from := to.AddDate(-1, 0, 0)
+	if value := ctx.Query("from"); value != "" {
+		date, err := time.Parse("2006-01-02", value)
+		if err != nil {
+			ctx.Error(service.NewError(service.ErrValidation, "invalid from date, expected YYYY-MM-DD"))
+			return
+		}
+		from = date
+	}
+
+	earnings, err := pc.payoutService.GetEarnings(userID, ctx.DefaultQuery("period", service.EarningsPeriodMonth), from, to)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, earningsResponse{
+		Balances: earnings.Balances,
+		Periods:  earnings.Periods,
+		Payouts:  newPayoutResponses(earnings.Payouts),
+	})
+}
+
+// GetAllPayouts returns every payout
+func (pc *PayoutController) GetAllPayouts(ctx *gin.Context) {
+
+	payouts, err := pc.payoutService.GetAllPayouts()
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, newPayoutResponses(payouts))
+}
+
+// UpdatePayout marks a pending payout as paid or failed
+func (pc *PayoutController) UpdatePayout(ctx *gin.Context) {
+
+	// Parse and validate payout ID from URL
+	payoutID, err := uuid.FromString(ctx.Param("id"))
+	if err != nil {
+		ctx.Error(service.NewError(service.ErrValidation, "invalid payout ID"))
+		return
+	}
+
+	var request updatePayoutRequest
+	if !bindJSON(ctx, &request) {
+		return
+	}
+
+	payout, err := pc.payoutService.UpdatePayoutStatus(payoutID, request.Status, request.Reference)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, newPayoutResponse(payout))
+}
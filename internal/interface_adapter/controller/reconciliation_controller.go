@@ -0,0 +1,128 @@
+package controller
+
+import (
+	"dalabio/internal/service"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gofrs/uuid"
+)
+
+// ReconciliationController struct that defines the reconciliation controller with its service
+type ReconciliationController struct {
+	reconciliationService service.ReconciliationService
+}
+
+// NewReconciliationController creates a new ReconciliationController instance
+func NewReconciliationController(reconciliationService service.ReconciliationService) *ReconciliationController {
+	return &ReconciliationController{reconciliationService: reconciliationService}
+}
+
+// parseDateRange reads the required from and to dates (YYYY-MM-DD); the to date is inclusive
+func parseDateRange(from, to string) (time.Time, time.Time, error) {
+	start, err := time.Parse("2006-01-02", from)
+	if err != nil {
+		return time.Time{}, time.Time{}, errors.New("invalid from date, expected YYYY-MM-DD")
+	}
+	end, err := time.Parse("2006-01-02", to)
+	if err != nil {
+		return time.Time{}, time.Time{}, errors.New("invalid to date, expected YYYY-MM-DD")
+	}
+	return start, end.AddDate(0, 0, 1), nil
+}
+
+// Reconcile imports a settlement CSV uploaded as the "file" form field and compares it with
+// the payments made between the "from" and "to" form dates, optionally for one "gateway"
+func (rc *ReconciliationController) Reconcile(ctx *gin.Context) {
+
+	from, to, err := parseDateRange(ctx.PostForm("from"), ctx.PostForm("to"))
+	if err != nil {
+		ctx.Error(service.NewError(service.ErrValidation, err.Error()))
+		return
+	}
+
+	header, err := ctx.FormFile("file")
+	if err != nil {
+		ctx.Error(service.NewError(service.ErrValidation, "settlement file is required"))
+		return
+	}
+
+	file, err := header.Open()
+	if err != nil {
+		ctx.Error(service.NewError(service.ErrValidation, err.Error()))
+		return
+	}
+	defer file.Close()
+
+	// Get the user ID from the request context set by the AuthMiddleware
+	userID, ok := authenticatedUser(ctx)
+	if !ok {
+		return
+	}
+
+	reconciliation, err := rc.reconciliationService.Reconcile(header.Filename, file, ctx.PostForm("gateway"), from, to, userID)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, reconciliation)
+}
+
+// GetReconciliationByID returns a reconciliation run and its discrepancies
+func (rc *ReconciliationController) GetReconciliationByID(ctx *gin.Context) {
+
+	// Parse and validate reconciliation ID from URL
+	reconciliationID, err := uuid.FromString(ctx.Param("id"))
+	if err != nil {
+		ctx.Error(service.NewError(service.ErrValidation, "invalid reconciliation ID"))
+		return
+	}
+
+	reconciliation, err := rc.reconciliationService.GetReconciliationByID(reconciliationID)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, reconciliation)
+}
+
+// GetAllReconciliations returns every reconciliation run
+func (rc *ReconciliationController) GetAllReconciliations(ctx *gin.Context) {
+
+	reconciliations, err := rc.reconciliationService.GetAllReconciliations()
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, reconciliations)
+}
+
+// ExportCSV downloads the payments and discrepancies between the from and to query dates as CSV
+func (rc *ReconciliationController) ExportCSV(ctx *gin.Context) {
+
+	from, to, err := parseDateRange(ctx.Query("from"), ctx.Query("to"))
+	if err != nil {
+		ctx.Error(service.NewError(service.ErrValidation, err.Error()))
+		return
+	}
+
+	ctx.Header("Content-Type", "text/csv; charset=utf-8")
+	ctx.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", fmt.Sprintf("payments-%s-%s.csv", ctx.Query("from"), ctx.Query("to"))))
+
+	if err := rc.reconciliationService.ExportCSV(ctx.Writer, ctx.Query("gateway"), from, to); err != nil {
+		// Nothing has been written when the data could not be loaded
+		if !ctx.Writer.Written() {
+			ctx.Writer.Header().Del("Content-Disposition")
+			ctx.Writer.Header().Set("Content-Type", "application/json; charset=utf-8")
+			ctx.Error(err)
+			return
+		}
+		ctx.Error(err)
+	}
+}
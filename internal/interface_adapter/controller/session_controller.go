@@ -0,0 +1,91 @@
+package controller
+
+import (
+	"dalabio/internal/entity"
+	"dalabio/internal/service"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gofrs/uuid"
+)
+
+// SessionController handles the devices users are signed in on
+type SessionController struct {
+	sessionService service.SessionService
+}
+
+// NewSessionController creates a new session controller
+func NewSessionController(sessionService service.SessionService) *SessionController {
+	return &SessionController{sessionService: sessionService}
+}
+
+// sessionResponse describes a session without its token
+type sessionResponse struct {
+	ID          uuid.UUID  `json:"id"`
+	DeviceLabel string     `json:"device_label"`
+	UserAgent   string     `json:"user_agent"`
+	IP          string     `json:"ip"`
+	CreatedAt   time.Time  `json:"created_at"`
+	LastSeenAt  *time.Time `json:"last_seen_at,omitempty"`
+	ExpiresAt   time.Time  `json:"expires_at"`
+	Current     bool       `json:"current"` // The session of the request
+}
+
+func newSessionResponse(token *entity.Token, currentID interface{}) sessionResponse {
+	return sessionResponse{
+		ID:          token.ID,
+		DeviceLabel: token.DeviceLabel,
+		UserAgent:   token.UserAgent,
+		IP:          token.IP,
+		CreatedAt:   token.CreatedAt,
+		LastSeenAt:  token.LastSeenAt,
+		ExpiresAt:   token.ExpiresAt,
+		Current:     currentID == token.ID,
+	}
+}
+
+// ListSessions lists the sessions of the authenticated user
+func (sc *SessionController) ListSessions(ctx *gin.Context) {
+	userID, ok := authenticatedUser(ctx)
+	if !ok {
+		return
+	}
+
+	sessions, err := sc.sessionService.ListSessions(userID)
+	if err != nil {
+		log.Printf("Error listing sessions: %v", err)
+		ctx.Error(err)
+		return
+	}
+
+	// Requests made with API keys have no current session
+	currentID, _ := ctx.Get("sessionID")
+	response := make([]sessionResponse, 0, len(sessions))
+	for _, session := range sessions {
+		response = append(response, newSessionResponse(session, currentID))
+	}
+	ctx.JSON(http.StatusOK, gin.H{"sessions": response})
+}
+
+// RevokeSession signs the authenticated user out of one of their sessions
+func (sc *SessionController) RevokeSession(ctx *gin.Context) {
+	userID, ok := authenticatedUser(ctx)
+	if !ok {
+		return
+	}
+	sessionID, err := uuid.FromString(ctx.Param("id"))
+	if err != nil {
+		ctx.Error(service.NewError(service.ErrValidation, "invalid session ID"))
+		return
+	}
+
+	if err := sc.sessionService.RevokeSession(userID, sessionID); err != nil {
+		log.Printf("Error revoking session: %v", err)
+		ctx.Error(err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"message": "Session revoked successfully"})
+}
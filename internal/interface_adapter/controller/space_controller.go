@@ -18,27 +18,41 @@ func NewSpaceController(spaceService service.SpaceService) *SpaceController {
 	return &SpaceController{spaceService: spaceService}
 }
 
+// spaceRequest is the body accepted to create or update a space.
+// The coach is the user creating the space and the counts are derived by the server.
+type spaceRequest struct {
+	Name            string  `json:"name" binding:"required"`
+	Description     string  `json:"description"`
+	Active          bool    `json:"active"`
+	MembershipPrice float64 `json:"membership_price"`
+	Currency        string  `json:"currency"`
+}
+
 // CreateSpace handles the creation of a new space
 func (sc *SpaceController) CreateSpace(ctx *gin.Context) {
-	var space entity.Space
+	var space spaceRequest
 
-	// Bind JSON to the spcae struct,
-	if err := ctx.ShouldBindJSON(&space); err != nil {
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	// Bind JSON to the request struct
+	if !bindJSON(ctx, &space) {
 		return
 	}
+
+	coachID, ok := authenticatedUser(ctx)
+	if !ok {
+		return
+	}
+
 	createSpace, err := sc.spaceService.CreateSpace(
 		space.Name,
 		space.Description,
-		space.CoachID,
-		space.MemberCount,
-		space.SessionCount,
-		space.CourseCount,
+		coachID,
 		space.Active,
+		space.MembershipPrice,
+		space.Currency,
 	)
 
 	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		ctx.Error(err)
 		return
 	}
 
@@ -47,26 +61,37 @@ func (sc *SpaceController) CreateSpace(ctx *gin.Context) {
 
 // UpdateCourse handles the update of an existing space
 func (sc *SpaceController) UpdateSpace(ctx *gin.Context) {
-	var space entity.Space
+	var req spaceRequest
 
 	// Parse and validate space ID from URL
 	spaceIdParms := ctx.Param("id")
 	spaceID, err := uuid.FromString(spaceIdParms)
 
 	if err != nil {
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid space ID"})
+		ctx.Error(service.NewError(service.ErrValidation, "invalid space ID"))
+		return
+	}
+	// Bind JSON input to the request struct
+	if !bindJSON(ctx, &req) {
 		return
 	}
-	// Bind JSON input to space struct
-	if err := ctx.ShouldBindJSON(&space); err != nil {
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+
+	userID, ok := authenticatedUser(ctx)
+	if !ok {
 		return
 	}
 
-	space.ID = spaceID
+	space := &entity.Space{
+		ID:              spaceID,
+		Name:            req.Name,
+		Description:     req.Description,
+		Active:          req.Active,
+		MembershipPrice: req.MembershipPrice,
+		Currency:        req.Currency,
+	}
 	// Call service to update space
-	if err := sc.spaceService.UpdateSpace(&space); err != nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	if err := sc.spaceService.UpdateSpace(space, userID); err != nil {
+		ctx.Error(err)
 		return
 	}
 
@@ -80,13 +105,18 @@ func (sc *SpaceController) DeleteSpace(ctx *gin.Context) {
 	spaceID, err := uuid.FromString(spaceIdParms)
 
 	if err != nil {
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid space ID"})
+		ctx.Error(service.NewError(service.ErrValidation, "invalid space ID"))
 		return
 	}
 
-	// Call service to update space
-	if err := sc.spaceService.DeleteSpace(spaceID); err != nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	userID, ok := authenticatedUser(ctx)
+	if !ok {
+		return
+	}
+
+	// Call service to delete space
+	if err := sc.spaceService.DeleteSpace(spaceID, userID); err != nil {
+		ctx.Error(err)
 		return
 	}
 
@@ -100,13 +130,13 @@ func (sc *SpaceController) GetSpaceByID(ctx *gin.Context) {
 	spaceID, err := uuid.FromString(spaceIdParms)
 
 	if err != nil {
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid space ID"})
+		ctx.Error(service.NewError(service.ErrValidation, "invalid space ID"))
 		return
 	}
 	// Call service to update space
 	space, err := sc.spaceService.GetSpaceByID(spaceID)
 	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		ctx.Error(err)
 	}
 	ctx.JSON(http.StatusOK, space)
 
@@ -117,7 +147,7 @@ func (sc *SpaceController) GetAllSpaces(ctx *gin.Context) {
 	// Call service to update space
 	spaces, err := sc.spaceService.GetAllSpaces()
 	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		ctx.Error(err)
 	}
 	ctx.JSON(http.StatusOK, spaces)
 }
@@ -0,0 +1,217 @@
+package controller
+
+import (
+	"dalabio/internal/service"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gofrs/uuid"
+)
+
+// SpaceMemberController struct that defines the space member controller with its service
+type SpaceMemberController struct {
+	memberService service.SpaceMemberService
+}
+
+// NewSpaceMemberController creates a new SpaceMemberController instance
+func NewSpaceMemberController(memberService service.SpaceMemberService) *SpaceMemberController {
+	return &SpaceMemberController{memberService: memberService}
+}
+
+// joinSpaceRequest is the optional body of the join endpoint
+type joinSpaceRequest struct {
+	Token string `json:"token"` // Token of an invitation link
+}
+
+// inviteRequest is the body accepted when creating an invitation link
+type inviteRequest struct {
+	Role           string `json:"role"`             // "member" (default) or "co_coach"
+	ExpiresInHours int    `json:"expires_in_hours"` // Defaults to 7 days
+	MaxUses        int    `json:"max_uses"`         // 0 for unlimited
+}
+
+// changeRoleRequest is the body accepted when changing the role of a member
+type changeRoleRequest struct {
+	Role string `json:"role" binding:"required"`
+}
+
+// spaceAndUser parses the space ID from the URL and reads the user ID set by the AuthMiddleware
+func spaceAndUser(ctx *gin.Context) (uuid.UUID, uuid.UUID, bool) {
+	spaceID, err := uuid.FromString(ctx.Param("id"))
+	if err != nil {
+		ctx.Error(service.NewError(service.ErrValidation, "invalid space ID"))
+		return uuid.Nil, uuid.Nil, false
+	}
+
+	userID, ok := authenticatedUser(ctx)
+	if !ok {
+		return uuid.Nil, uuid.Nil, false
+	}
+
+	return spaceID, userID, true
+}
+
+// GetMembers lists the members of a space
+func (mc *SpaceMemberController) GetMembers(ctx *gin.Context) {
+	spaceID, userID, ok := spaceAndUser(ctx)
+	if !ok {
+		return
+	}
+
+	members, err := mc.memberService.GetMembers(spaceID, userID)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, members)
+}
+
+// Join adds the authenticated user to a space, with an invitation token when one is given
+func (mc *SpaceMemberController) Join(ctx *gin.Context) {
+	spaceID, userID, ok := spaceAndUser(ctx)
+	if !ok {
+		return
+	}
+
+	// The token can come from the invitation link query string or from the body
+	request := joinSpaceRequest{Token: ctx.Query("token")}
+	if ctx.Request.ContentLength > 0 {
+		if !bindJSON(ctx, &request) {
+			return
+		}
+	}
+
+	member, err := mc.memberService.Join(spaceID, userID, request.Token)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, member)
+}
+
+// Leave removes the authenticated user from a space
+func (mc *SpaceMemberController) Leave(ctx *gin.Context) {
+	spaceID, userID, ok := spaceAndUser(ctx)
+	if !ok {
+		return
+	}
+
+	if err := mc.memberService.Leave(spaceID, userID); err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"message": "Left space successfully"})
+}
+
+// RemoveMember removes another member from a space
+func (mc *SpaceMemberController) RemoveMember(ctx *gin.Context) {
+	spaceID, requesterID, ok := spaceAndUser(ctx)
+	if !ok {
+		return
+	}
+
+	memberID, err := uuid.FromString(ctx.Param("userId"))
+	if err != nil {
+		ctx.Error(service.NewError(service.ErrValidation, "invalid user ID"))
+		return
+	}
+
+	if err := mc.memberService.RemoveMember(spaceID, requesterID, memberID); err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"message": "Member removed successfully"})
+}
+
+// ChangeRole promotes a member to co-coach or demotes a co-coach to member
+func (mc *SpaceMemberController) ChangeRole(ctx *gin.Context) {
+	spaceID, requesterID, ok := spaceAndUser(ctx)
+	if !ok {
+		return
+	}
+
+	memberID, err := uuid.FromString(ctx.Param("userId"))
+	if err != nil {
+		ctx.Error(service.NewError(service.ErrValidation, "invalid user ID"))
+		return
+	}
+
+	var request changeRoleRequest
+	if !bindJSON(ctx, &request) {
+		return
+	}
+
+	member, err := mc.memberService.ChangeRole(spaceID, requesterID, memberID, request.Role)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, member)
+}
+
+// Invite creates an invitation link for a space
+func (mc *SpaceMemberController) Invite(ctx *gin.Context) {
+	spaceID, requesterID, ok := spaceAndUser(ctx)
+	if !ok {
+		return
+	}
+
+	var request inviteRequest
+	if !bindJSON(ctx, &request) {
+		return
+	}
+
+	invitation, err := mc.memberService.Invite(spaceID, requesterID, request.Role, time.Duration(request.ExpiresInHours)*time.Hour, request.MaxUses)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, gin.H{
+		"invitation": invitation,
+		"link":       "/spaces/" + spaceID.String() + "/members/join?token=" + invitation.Token,
+	})
+}
+
+// GetInvitations lists the invitation links of a space
+func (mc *SpaceMemberController) GetInvitations(ctx *gin.Context) {
+	spaceID, requesterID, ok := spaceAndUser(ctx)
+	if !ok {
+		return
+	}
+
+	invitations, err := mc.memberService.GetInvitations(spaceID, requesterID)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, invitations)
+}
+
+// RevokeInvitation disables an invitation link
+func (mc *SpaceMemberController) RevokeInvitation(ctx *gin.Context) {
+	spaceID, requesterID, ok := spaceAndUser(ctx)
+	if !ok {
+		return
+	}
+
+	invitationID, err := uuid.FromString(ctx.Param("invitationId"))
+	if err != nil {
+		ctx.Error(service.NewError(service.ErrValidation, "invalid invitation ID"))
+		return
+	}
+
+	if err := mc.memberService.RevokeInvitation(spaceID, requesterID, invitationID); err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"message": "Invitation revoked successfully"})
+}
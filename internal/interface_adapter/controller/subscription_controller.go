@@ -0,0 +1,181 @@
+package controller
+
+import (
+	"dalabio/internal/entity"
+	"dalabio/internal/service"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gofrs/uuid"
+)
+
+// SubscriptionController struct that defines the subscription controller with its service
+type SubscriptionController struct {
+	subscriptionService service.SubscriptionService
+}
+
+// NewSubscriptionController creates a new SubscriptionController instance
+func NewSubscriptionController(subscriptionService service.SubscriptionService) *SubscriptionController {
+	return &SubscriptionController{subscriptionService: subscriptionService}
+}
+
+// subscribeRequest is the body accepted when subscribing to a plan
+type subscribeRequest struct {
+	PlanID         uuid.UUID `json:"plan_id" binding:"required"`
+	PaymentMethod  string    `json:"payment_method" binding:"required"`
+	PaymentGateway string    `json:"payment_gateway"`
+}
+
+// planRequest is the body accepted to create or update a plan; new plans are always active
+type planRequest struct {
+	Name      string  `json:"name" binding:"required"`
+	Price     float64 `json:"price" binding:"required"`
+	Currency  string  `json:"currency" binding:"required"`
+	Interval  string  `json:"interval" binding:"required"`
+	TrialDays int     `json:"trial_days"`
+	Active    *bool   `json:"active"` // Left as it is when omitted
+}
+
+// CreatePlan handles the creation of a plan for a space
+func (sc *SubscriptionController) CreatePlan(ctx *gin.Context) {
+	var plan planRequest
+
+	// Parse and validate space ID from URL
+	spaceID, err := uuid.FromString(ctx.Param("id"))
+	if err != nil {
+		ctx.Error(service.NewError(service.ErrValidation, "invalid space ID"))
+		return
+	}
+
+	if !bindJSON(ctx, &plan) {
+		return
+	}
+
+	userID, ok := authenticatedUser(ctx)
+	if !ok {
+		return
+	}
+
+	createdPlan, err := sc.subscriptionService.CreatePlan(userID, spaceID, plan.Name, plan.Price, plan.Currency, plan.Interval, plan.TrialDays)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, createdPlan)
+}
+
+// UpdatePlan handles the update of a plan of a space
+func (sc *SubscriptionController) UpdatePlan(ctx *gin.Context) {
+	var req planRequest
+
+	// Parse and validate plan ID from URL
+	planID, err := uuid.FromString(ctx.Param("planId"))
+	if err != nil {
+		ctx.Error(service.NewError(service.ErrValidation, "invalid plan ID"))
+		return
+	}
+
+	if !bindJSON(ctx, &req) {
+		return
+	}
+
+	userID, ok := authenticatedUser(ctx)
+	if !ok {
+		return
+	}
+
+	plan := &entity.Plan{
+		ID:        planID,
+		Name:      req.Name,
+		Price:     req.Price,
+		Currency:  req.Currency,
+		Interval:  req.Interval,
+		TrialDays: req.TrialDays,
+	}
+	if err := sc.subscriptionService.UpdatePlan(userID, plan, req.Active); err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"message": "Plan updated successfully"})
+}
+
+// GetPlansBySpace returns the plans of a space
+func (sc *SubscriptionController) GetPlansBySpace(ctx *gin.Context) {
+	// Parse and validate space ID from URL
+	spaceID, err := uuid.FromString(ctx.Param("id"))
+	if err != nil {
+		ctx.Error(service.NewError(service.ErrValidation, "invalid space ID"))
+		return
+	}
+
+	plans, err := sc.subscriptionService.GetPlansBySpace(spaceID)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, plans)
+}
+
+// Subscribe subscribes the authenticated user to a plan
+func (sc *SubscriptionController) Subscribe(ctx *gin.Context) {
+	var request subscribeRequest
+
+	if !bindJSON(ctx, &request) {
+		return
+	}
+
+	userID, ok := authenticatedUser(ctx)
+	if !ok {
+		return
+	}
+
+	subscription, err := sc.subscriptionService.Subscribe(userID, request.PlanID, request.PaymentMethod, request.PaymentGateway)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, subscription)
+}
+
+// CancelSubscription cancels a subscription of the authenticated user at the end of its period
+func (sc *SubscriptionController) CancelSubscription(ctx *gin.Context) {
+	// Parse and validate subscription ID from URL
+	subscriptionID, err := uuid.FromString(ctx.Param("id"))
+	if err != nil {
+		ctx.Error(service.NewError(service.ErrValidation, "invalid subscription ID"))
+		return
+	}
+
+	userID, ok := authenticatedUser(ctx)
+	if !ok {
+		return
+	}
+
+	subscription, err := sc.subscriptionService.CancelSubscription(userID, subscriptionID)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, subscription)
+}
+
+// GetMySubscriptions returns the subscriptions of the authenticated user
+func (sc *SubscriptionController) GetMySubscriptions(ctx *gin.Context) {
+	userID, ok := authenticatedUser(ctx)
+	if !ok {
+		return
+	}
+
+	subscriptions, err := sc.subscriptionService.GetSubscriptionsByUser(userID)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, subscriptions)
+}
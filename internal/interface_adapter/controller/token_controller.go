@@ -0,0 +1,66 @@
+package controller
+
+import (
+	"dalabio/internal/service"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// TokenController handles the renewal and verification of access tokens
+type TokenController struct {
+	tokenService service.TokenService
+}
+
+// NewTokenController creates a new token controller
+func NewTokenController(tokenService service.TokenService) *TokenController {
+	return &TokenController{tokenService: tokenService}
+}
+
+// refreshTokenRequest is the body accepted to renew an access token
+type refreshTokenRequest struct {
+	RefreshToken string `json:"refresh_token" binding:"required"`
+}
+
+// tokenResponse carries an access token, and the refresh token renewing it when access tokens are signed
+type tokenResponse struct {
+	Token            string     `json:"token"`
+	ExpiresAt        time.Time  `json:"expires_at"`
+	RefreshToken     string     `json:"refresh_token,omitempty"`
+	RefreshExpiresAt *time.Time `json:"refresh_expires_at,omitempty"`
+}
+
+func newTokenResponse(tokens *service.IssuedTokens) tokenResponse {
+	response := tokenResponse{Token: tokens.Access.Token, ExpiresAt: tokens.Access.ExpiresAt}
+	if tokens.Refresh != nil {
+		response.RefreshToken = tokens.Refresh.Token
+		response.RefreshExpiresAt = &tokens.Refresh.ExpiresAt
+	}
+	return response
+}
+
+// Refresh exchanges a refresh token for a new access token and a new refresh token
+func (tc *TokenController) Refresh(ctx *gin.Context) {
+	var req refreshTokenRequest
+	if !bindJSON(ctx, &req) {
+		return
+	}
+
+	tokens, err := tc.tokenService.Refresh(req.RefreshToken)
+	if err != nil {
+		log.Printf("Error refreshing token: %v", err)
+		ctx.Error(err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, newTokenResponse(tokens))
+}
+
+// JWKS publishes the public keys verifying access tokens
+func (tc *TokenController) JWKS(ctx *gin.Context) {
+	// Retired keys stay listed until their tokens expire, so caches only need to be short
+	ctx.Header("Cache-Control", "public, max-age=300")
+	ctx.JSON(http.StatusOK, tc.tokenService.JWKS())
+}
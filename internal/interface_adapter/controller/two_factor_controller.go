@@ -0,0 +1,145 @@
+package controller
+
+import (
+	"dalabio/internal/service"
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// TwoFactorController handles the two-factor authentication settings of users
+type TwoFactorController struct {
+	twoFactorService service.TwoFactorService
+}
+
+// NewTwoFactorController creates a new two-factor controller
+func NewTwoFactorController(twoFactorService service.TwoFactorService) *TwoFactorController {
+	return &TwoFactorController{twoFactorService: twoFactorService}
+}
+
+// twoFactorCodeRequest is the body carrying a code of the authenticator app, or a recovery code where accepted
+type twoFactorCodeRequest struct {
+	Code string `json:"code" binding:"required"`
+}
+
+// roleTwoFactorRequest is the body accepted to enforce two-factor authentication for a role
+type roleTwoFactorRequest struct {
+	Required *bool `json:"required" binding:"required"`
+}
+
+// GetStatus tells the authenticated user whether their two-factor authentication is enabled
+func (tc *TwoFactorController) GetStatus(ctx *gin.Context) {
+	userID, ok := authenticatedUser(ctx)
+	if !ok {
+		return
+	}
+
+	status, err := tc.twoFactorService.Status(userID)
+	if err != nil {
+		log.Printf("Error getting two-factor status: %v", err)
+		ctx.Error(err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, status)
+}
+
+// Enroll generates the secret the authenticated user adds to their authenticator app
+func (tc *TwoFactorController) Enroll(ctx *gin.Context) {
+	userID, ok := authenticatedUser(ctx)
+	if !ok {
+		return
+	}
+
+	enrollment, err := tc.twoFactorService.Enroll(userID)
+	if err != nil {
+		log.Printf("Error enrolling two-factor authenticator: %v", err)
+		ctx.Error(err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, enrollment)
+}
+
+// Confirm enables two-factor authentication with a first code and returns the recovery codes
+func (tc *TwoFactorController) Confirm(ctx *gin.Context) {
+	userID, ok := authenticatedUser(ctx)
+	if !ok {
+		return
+	}
+
+	var req twoFactorCodeRequest
+	if !bindJSON(ctx, &req) {
+		return
+	}
+
+	codes, err := tc.twoFactorService.Confirm(userID, req.Code)
+	if err != nil {
+		log.Printf("Error confirming two-factor authenticator: %v", err)
+		ctx.Error(err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"message": "Two-factor authentication enabled", "recovery_codes": codes})
+}
+
+// Disable turns two-factor authentication off for the authenticated user
+func (tc *TwoFactorController) Disable(ctx *gin.Context) {
+	userID, ok := authenticatedUser(ctx)
+	if !ok {
+		return
+	}
+
+	var req twoFactorCodeRequest
+	if !bindJSON(ctx, &req) {
+		return
+	}
+
+	if err := tc.twoFactorService.Disable(userID, req.Code); err != nil {
+		log.Printf("Error disabling two-factor authentication: %v", err)
+		ctx.Error(err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"message": "Two-factor authentication disabled"})
+}
+
+// RegenerateRecoveryCodes replaces the recovery codes of the authenticated user
+func (tc *TwoFactorController) RegenerateRecoveryCodes(ctx *gin.Context) {
+	userID, ok := authenticatedUser(ctx)
+	if !ok {
+		return
+	}
+
+	var req twoFactorCodeRequest
+	if !bindJSON(ctx, &req) {
+		return
+	}
+
+	codes, err := tc.twoFactorService.RegenerateRecoveryCodes(userID, req.Code)
+	if err != nil {
+		log.Printf("Error regenerating recovery codes: %v", err)
+		ctx.Error(err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"recovery_codes": codes})
+}
+
+// SetRoleRequirement makes two-factor authentication mandatory or optional for the holders of a role
+func (tc *TwoFactorController) SetRoleRequirement(ctx *gin.Context) {
+	var req roleTwoFactorRequest
+	if !bindJSON(ctx, &req) {
+		return
+	}
+
+	role := ctx.Param("name")
+	if err := tc.twoFactorService.SetRoleRequirement(role, *req.Required); err != nil {
+		log.Printf("Error updating two-factor requirement of role: %v", err)
+		ctx.Error(err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"role": role, "require_two_factor": *req.Required})
+}
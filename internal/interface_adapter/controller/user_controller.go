@@ -3,8 +3,11 @@ package controller
 import (
 	"dalabio/internal/entity"
 	"dalabio/internal/service"
+	"errors"
 	"log"
 	"net/http"
+	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/gofrs/uuid"
@@ -20,105 +23,428 @@ func NewUserController(userService service.UserService) *UserController {
 	return &UserController{userService: userService}
 }
 
+// registerUserRequest is the body accepted to create an account
+type registerUserRequest struct {
+	Username  string `json:"username" binding:"required"`
+	Email     string `json:"email" binding:"required"`
+	Password  string `json:"password" binding:"required"`
+	FirstName string `json:"first_name"`
+	LastName  string `json:"last_name"`
+	Locale    string `json:"locale"` // Language of the emails, the Accept-Language header when omitted
+}
+
+// authenticateRequest is the body accepted to sign in
+type authenticateRequest struct {
+	Email    string `json:"email" binding:"required"`
+	Password string `json:"password" binding:"required"`
+}
+
+// verifyTwoFactorRequest is the body accepted to give the second factor of a sign-in
+type verifyTwoFactorRequest struct {
+	Challenge string `json:"challenge" binding:"required"`
+	Code      string `json:"code" binding:"required"` // Code of the authenticator app or recovery code
+}
+
+// setupTwoFactorRequest is the body accepted to enroll an authenticator during a sign-in
+type setupTwoFactorRequest struct {
+	Challenge string `json:"challenge" binding:"required"`
+}
+
+// updateUserRequest is the body accepted to edit a profile; the password has its own endpoints
+type updateUserRequest struct {
+	Username  string `json:"username" binding:"required"`
+	Email     string `json:"email" binding:"required"`
+	FirstName string `json:"first_name"`
+	LastName  string `json:"last_name"`
+	Locale    string `json:"locale"` // Language of the emails, unchanged when omitted
+}
+
+// updateProfileRequest is the body accepted to edit the profile of the authenticated user
+type updateProfileRequest struct {
+	updateUserRequest
+	CurrentPassword string `json:"current_password"` // Required to change the email
+}
+
+// userResponse is a user as shown to clients, without their password hash
+type userResponse struct {
+	ID              uuid.UUID  `json:"id"`
+	Username        string     `json:"username"`
+	Email           string     `json:"email"`
+	FirstName       string     `json:"first_name"`
+	LastName        string     `json:"last_name"`
+	IsActive        bool       `json:"is_active"`
+	Locale          string     `json:"locale,omitempty"`
+	EmailVerifiedAt *time.Time `json:"email_verified_at,omitempty"`
+	LastLogin       *time.Time `json:"last_login,omitempty"`
+	CreatedAt       time.Time  `json:"created_at"`
+	UpdatedAt       time.Time  `json:"updated_at"`
+}
+
+func newUserResponse(user *entity.User) *userResponse {
+	response := &userResponse{
+		ID:              user.ID,
+		Username:        user.Username,
+		Email:           user.Email,
+		FirstName:       user.FirstName,
+		LastName:        user.LastName,
+		IsActive:        user.IsActive,
+		Locale:          user.Locale,
+		EmailVerifiedAt: user.EmailVerifiedAt,
+		CreatedAt:       user.CreatedAt,
+		UpdatedAt:       user.UpdatedAt,
+	}
+	if !user.LastLogin.IsZero() {
+		response.LastLogin = &user.LastLogin
+	}
+	return response
+}
+
+func newUserResponses(users []*entity.User) []*userResponse {
+	responses := make([]*userResponse, 0, len(users))
+	for _, user := range users {
+		responses = append(responses, newUserResponse(user))
+	}
+	return responses
+}
+
+// authenticateResponse carries the tokens issued on sign in
+type authenticateResponse struct {
+	tokenResponse
+	User          *userResponse `json:"user"`
+	RecoveryCodes []string      `json:"recovery_codes,omitempty"` // Shown once, when the authenticator was set up while signing in
+}
+
+// loginChallengeResponse asks a user who entered their password for their second factor
+type loginChallengeResponse struct {
+	TwoFactorRequired bool      `json:"two_factor_required"`
+	SetupRequired     bool      `json:"setup_required"` // The authenticator must be enrolled first, with POST /users/authenticate/2fa/setup
+	Challenge         string    `json:"challenge"`
+	ExpiresAt         time.Time `json:"expires_at"`
+}
+
+func newAuthenticationResponse(authentication *service.Authentication) interface{} {
+	if challenge := authentication.Challenge; challenge != nil {
+		return loginChallengeResponse{
+			TwoFactorRequired: true,
+			SetupRequired:     challenge.SetupRequired,
+			Challenge:         challenge.Token,
+			ExpiresAt:         challenge.ExpiresAt,
+		}
+	}
+	return authenticateResponse{
+		tokenResponse: newTokenResponse(authentication.Tokens),
+		User:          newUserResponse(authentication.User),
+		RecoveryCodes: authentication.RecoveryCodes,
+	}
+}
+
+// acceptedLocale returns the first language of an Accept-Language header, which browsers list by preference,
+// or an empty string when it names none
+func acceptedLocale(header string) string {
+	for _, part := range strings.Split(header, ",") {
+		tag := strings.TrimSpace(strings.SplitN(part, ";", 2)[0])
+		if service.IsLocale(tag) {
+			return tag
+		}
+	}
+	return ""
+}
+
 // CreateUser creates a new user
 func (uc *UserController) RegisterUser(c *gin.Context) {
-	var user entity.User
+	var req registerUserRequest
 
-	// Bind incoming JSON to the user struct
-	if err := c.ShouldBindJSON(&user); err != nil {
-		log.Printf("Error binding JSON: %v", err)
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	// Bind incoming JSON to the request struct
+	if !bindJSON(c, &req) {
 		return
 	}
 
-	log.Printf("Bound User Struct: %+v", user)
+	// Emails follow the language of the browser unless the client chose one
+	locale := req.Locale
+	if locale == "" {
+		locale = acceptedLocale(c.GetHeader("Accept-Language"))
+	}
 
 	// Call the service layer to handle user registration
-	createdUser, err := uc.userService.RegisterUser(user.Username, user.Email, user.Password, user.FirstName, user.LastName)
+	createdUser, err := uc.userService.RegisterUser(req.Username, req.Email, req.Password, req.FirstName, req.LastName, locale)
 	if err != nil {
 		log.Printf("Error registering user: %v", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		c.Error(err)
 		return
 	}
 
-	c.JSON(http.StatusOK, createdUser)
+	c.JSON(http.StatusOK, newUserResponse(createdUser))
 }
 
 // Authenticate User
 func (uc *UserController) AuthenticateUser(c *gin.Context) {
-	var user entity.User
+	var req authenticateRequest
 
-	// Bind incoming JSON to the user struct
-	if err := c.ShouldBindJSON(&user); err != nil {
-		log.Printf("Error binding JSON: %v", err)
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	// Bind incoming JSON to the request struct
+	if !bindJSON(c, &req) {
 		return
 	}
 
-	log.Printf("Bound User Struct: %+v", user)
-
 	// Call the service layer to handle user authentication
-	authenticatedUser, err := uc.userService.AuthenticateUser(user.Email, user.Password)
+	authentication, err := uc.userService.AuthenticateUser(req.Email, req.Password, c.ClientIP())
 	if err != nil {
 		log.Printf("Error authenticating user: %v", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, newAuthenticationResponse(authentication))
+}
+
+// VerifyTwoFactor completes a sign-in with the code of the authenticator app or a recovery code
+func (uc *UserController) VerifyTwoFactor(c *gin.Context) {
+	var req verifyTwoFactorRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	authentication, err := uc.userService.VerifyTwoFactor(req.Challenge, req.Code, c.ClientIP())
+	if err != nil {
+		log.Printf("Error verifying two-factor code: %v", err)
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, newAuthenticationResponse(authentication))
+}
+
+// SetupTwoFactor enrolls the authenticator of a user whose role requires one, in the middle of their sign-in
+func (uc *UserController) SetupTwoFactor(c *gin.Context) {
+	var req setupTwoFactorRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	enrollment, err := uc.userService.SetupTwoFactor(req.Challenge)
+	if err != nil {
+		log.Printf("Error setting up two-factor authentication: %v", err)
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, enrollment)
+}
+
+// VerifyEmail verifies the email of a user with the token of the link they were emailed
+func (uc *UserController) VerifyEmail(c *gin.Context) {
+	token := c.Query("token")
+	if token == "" {
+		c.Error(service.NewError(service.ErrValidation, "token is required"))
+		return
+	}
+
+	user, err := uc.userService.VerifyEmail(token)
+	if errors.Is(err, service.ErrInvalidVerificationToken) {
+		c.Error(service.NewError(service.ErrValidation, err.Error()))
+		return
+	}
+	if err != nil {
+		log.Printf("Error verifying email: %v", err)
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Email verified successfully", "email_verified_at": user.EmailVerifiedAt})
+}
+
+type resendVerificationRequest struct {
+	Email string `json:"email" binding:"required"`
+}
+
+// ResendVerification emails a new verification link
+func (uc *UserController) ResendVerification(c *gin.Context) {
+	var req resendVerificationRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	err := uc.userService.ResendVerification(req.Email)
+	if err != nil {
+		log.Printf("Error resending verification email: %v", err)
+		c.Error(err)
+		return
+	}
+
+	// The same answer whether or not the email has an account
+	c.JSON(http.StatusAccepted, gin.H{"message": "If the account exists and is not verified yet, a new verification email was sent"})
+}
+
+type forgotPasswordRequest struct {
+	Email string `json:"email" binding:"required"`
+}
+
+// ForgotPassword emails a password reset link
+func (uc *UserController) ForgotPassword(c *gin.Context) {
+	var req forgotPasswordRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	if err := uc.userService.ForgotPassword(req.Email); err != nil {
+		log.Printf("Error sending password reset email: %v", err)
+		c.Error(err)
+		return
+	}
+
+	// The same answer whether or not the email has an account
+	c.JSON(http.StatusAccepted, gin.H{"message": "If the account exists, a password reset email was sent"})
+}
+
+type resetPasswordRequest struct {
+	Token    string `json:"token" binding:"required"`
+	Password string `json:"password" binding:"required"`
+}
+
+// ResetPassword sets a new password with the token of a password reset link
+func (uc *UserController) ResetPassword(c *gin.Context) {
+	var req resetPasswordRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	err := uc.userService.ResetPassword(req.Token, req.Password)
+	if errors.Is(err, service.ErrInvalidResetToken) {
+		c.Error(service.NewError(service.ErrValidation, err.Error()))
 		return
 	}
+	if err != nil {
+		log.Printf("Error resetting password: %v", err)
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Password reset successfully"})
+}
 
-	c.JSON(http.StatusOK, authenticatedUser)
+type changePasswordRequest struct {
+	CurrentPassword string `json:"current_password" binding:"required"`
+	NewPassword     string `json:"new_password" binding:"required"`
 }
 
-// update user
+// ChangePassword changes the password of the authenticated user, who must sign in again
+func (uc *UserController) ChangePassword(c *gin.Context) {
+	userID, ok := authenticatedUser(c)
+	if !ok {
+		return
+	}
+
+	var req changePasswordRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	err := uc.userService.ChangePassword(userID, req.CurrentPassword, req.NewPassword)
+	if err != nil {
+		log.Printf("Error changing password: %v", err)
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Password changed successfully, please sign in again"})
+}
+
+// UpdateProfile edits the profile of the authenticated user
+func (uc *UserController) UpdateProfile(c *gin.Context) {
+	userID, ok := authenticatedUser(c)
+	if !ok {
+		return
+	}
+
+	var req updateProfileRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	user := &entity.User{
+		ID:        userID,
+		Username:  req.Username,
+		Email:     req.Email,
+		FirstName: req.FirstName,
+		LastName:  req.LastName,
+		Locale:    req.Locale,
+	}
+	if err := uc.userService.UpdateProfile(user, req.CurrentPassword); err != nil {
+		log.Printf("Error updating profile: %v", err)
+		c.Error(err)
+		return
+	}
+
+	updated, err := uc.userService.GetUserByID(userID)
+	if err != nil {
+		log.Printf("Error reloading user: %v", err)
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Profile updated successfully", "user": newUserResponse(updated)})
+}
+
+// update user, for admins
 func (uc *UserController) UpdateUser(c *gin.Context) {
-	var user entity.User
+	var req updateUserRequest
 
 	// Get the user ID from the URL parameter
 	userIDParam := c.Param("id")
 	userID, err := uuid.FromString(userIDParam)
 	if err != nil {
 		log.Printf("Invalid user ID: %v", err)
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		c.Error(service.NewError(service.ErrValidation, "invalid user ID"))
 		return
 	}
 
-	// Bind incoming JSON to the user struct
-	if err := c.ShouldBindJSON(&user); err != nil {
-		log.Printf("Error binding JSON: %v", err)
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input data"})
+	// Bind incoming JSON to the request struct
+	if !bindJSON(c, &req) {
 		return
 	}
 
-	log.Printf("Bound User Struct: %+v", user)
-
-	// Set the user ID from the params into the user struct
-	user.ID = userID
+	// The ID comes from the URL, never from the body
+	user := &entity.User{
+		ID:        userID,
+		Username:  req.Username,
+		Email:     req.Email,
+		FirstName: req.FirstName,
+		LastName:  req.LastName,
+		Locale:    req.Locale,
+	}
 
 	// Call the service layer to handle user update
-	if err := uc.userService.UpdateUser(&user); err != nil {
+	if err := uc.userService.UpdateUser(user); err != nil {
 		log.Printf("Error updating user: %v", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		c.Error(err)
+		return
+	}
+
+	updated, err := uc.userService.GetUserByID(userID)
+	if err != nil {
+		log.Printf("Error reloading user: %v", err)
+		c.Error(err)
 		return
 	}
 
 	// Respond with success
-	c.JSON(http.StatusOK, gin.H{"message": "User updated successfully", "user": user})
+	c.JSON(http.StatusOK, gin.H{"message": "User updated successfully", "user": newUserResponse(updated)})
 }
 
-// delete user
+// delete user, for admins
 func (uc *UserController) DeleteUser(c *gin.Context) {
 	// Get the user ID from the URL parameter
 	userIDParam := c.Param("id")
 	userID, err := uuid.FromString(userIDParam)
 	if err != nil {
 		log.Printf("Invalid user ID: %v", err)
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		c.Error(service.NewError(service.ErrValidation, "invalid user ID"))
 		return
 	}
 
 	// Call the service layer to handle user deletion
 	if err := uc.userService.DeleteUser(userID); err != nil {
 		log.Printf("Error deleting user: %v", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		c.Error(err)
 		return
 	}
 
@@ -126,6 +452,23 @@ func (uc *UserController) DeleteUser(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"message": "User deleted successfully"})
 }
 
+// UnlockUser lifts the lockout of an account locked after too many failed sign-ins
+func (uc *UserController) UnlockUser(c *gin.Context) {
+	userID, err := uuid.FromString(c.Param("id"))
+	if err != nil {
+		c.Error(service.NewError(service.ErrValidation, "invalid user ID"))
+		return
+	}
+
+	if err := uc.userService.UnlockUser(userID); err != nil {
+		log.Printf("Error unlocking user: %v", err)
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "User unlocked successfully"})
+}
+
 func (uc *UserController) GetUserByID(c *gin.Context) {
 
 	// Get the user ID from the URL parameter
@@ -133,7 +476,7 @@ func (uc *UserController) GetUserByID(c *gin.Context) {
 	userID, err := uuid.FromString(userIDParam)
 	if err != nil {
 		log.Printf("Invalid user ID: %v", err)
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		c.Error(service.NewError(service.ErrValidation, "invalid user ID"))
 		return
 	}
 
@@ -141,12 +484,12 @@ func (uc *UserController) GetUserByID(c *gin.Context) {
 	user, err := uc.userService.GetUserByID(userID)
 	if err != nil {
 		log.Printf("Error deleting user: %v", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		c.Error(err)
 		return
 	}
 
 	// Respond with success
-	c.JSON(http.StatusOK, gin.H{"user": user})
+	c.JSON(http.StatusOK, gin.H{"user": newUserResponse(user)})
 }
 
 func (uc *UserController) ListUsers(c *gin.Context) {
@@ -155,10 +498,10 @@ func (uc *UserController) ListUsers(c *gin.Context) {
 	users, err := uc.userService.ListUsers()
 	if err != nil {
 		log.Printf("Error deleting user: %v", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		c.Error(err)
 		return
 	}
 
 	// Respond with success
-	c.JSON(http.StatusOK, gin.H{"users": users})
+	c.JSON(http.StatusOK, gin.H{"users": newUserResponses(users)})
 }
@@ -0,0 +1,76 @@
+package controller
+
+import (
+	"dalabio/internal/service"
+	"dalabio/pkg/validation"
+	"encoding/json"
+	"errors"
+	"reflect"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
+)
+
+func init() {
+	// Name fields by their JSON keys in binding errors, as clients know them
+	if engine, ok := binding.Validator.Engine().(*validator.Validate); ok {
+		engine.RegisterTagNameFunc(func(field reflect.StructField) string {
+			name := strings.SplitN(field.Tag.Get("json"), ",", 2)[0]
+			if name == "-" {
+				return ""
+			}
+			return name
+		})
+	}
+}
+
+// bindJSON binds the body of a request. A rejected body is recorded as *validation.Errors listing every invalid field,
+// which ErrorMiddleware answers with 422, like a body that is not JSON at all.
+func bindJSON(ctx *gin.Context, request interface{}) bool {
+	err := ctx.ShouldBindJSON(request)
+	if err == nil {
+		return true
+	}
+
+	var bindingErrors validator.ValidationErrors
+	var typeError *json.UnmarshalTypeError
+	switch {
+	case errors.As(err, &bindingErrors):
+		fields := make([]validation.FieldError, 0, len(bindingErrors))
+		for _, fieldError := range bindingErrors {
+			fields = append(fields, bindingFieldError(fieldError))
+		}
+		ctx.Error(&validation.Errors{Fields: fields})
+	case errors.As(err, &typeError):
+		ctx.Error(&validation.Errors{Fields: []validation.FieldError{{
+			Field:   typeError.Field,
+			Code:    validation.CodeInvalid,
+			Message: "must be a " + typeError.Type.String(),
+		}}})
+	default:
+		ctx.Error(service.NewError(service.ErrValidation, "request body must be valid JSON: "+err.Error()))
+	}
+	return false
+}
+
+// bindingFieldError translates a failed binding tag into a field error
+func bindingFieldError(fieldError validator.FieldError) validation.FieldError {
+	// The namespace starts with the name of the request struct, e.g. "checkoutRequest.items[0].item_id"
+	field := fieldError.Namespace()
+	if dot := strings.IndexByte(field, '.'); dot >= 0 {
+		field = field[dot+1:]
+	}
+
+	switch fieldError.Tag() {
+	case "required":
+		return validation.FieldError{Field: field, Code: validation.CodeRequired, Message: "is required"}
+	case "min":
+		return validation.FieldError{Field: field, Code: validation.CodeTooShort, Message: "must have at least " + fieldError.Param() + " elements"}
+	case "email":
+		return validation.FieldError{Field: field, Code: validation.CodeInvalidEmail, Message: "must be a valid email address"}
+	default:
+		return validation.FieldError{Field: field, Code: validation.CodeInvalid, Message: "failed the " + fieldError.Tag() + " check"}
+	}
+}
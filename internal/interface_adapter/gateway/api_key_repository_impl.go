@@ -0,0 +1,115 @@
+package gateway
+
+import (
+	"dalabio/internal/entity"
+	"dalabio/internal/repository"
+	"database/sql"
+	"log"
+	"time"
+
+	"github.com/gofrs/uuid"
+	"github.com/lib/pq"
+)
+
+type apiKeyRepositoryImpl struct {
+	db *sql.DB
+}
+
+// NewAPIKeyRepository creates a new instance of APIKeyRepository.
+func NewAPIKeyRepository(db *sql.DB) repository.APIKeyRepository {
+	return &apiKeyRepositoryImpl{db: db}
+}
+
+const apiKeyColumns = `id, user_id, name, prefix, key_hash, scopes, expires_at, last_used_at, created_at`
+
+func scanAPIKey(row interface{ Scan(...interface{}) error }) (*entity.APIKey, error) {
+	var key entity.APIKey
+	err := row.Scan(&key.ID, &key.UserID, &key.Name, &key.Prefix, &key.KeyHash, pq.Array(&key.Scopes),
+		&key.ExpiresAt, &key.LastUsedAt, &key.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &key, nil
+}
+
+// Create implements repository.APIKeyRepository.
+func (r *apiKeyRepositoryImpl) Create(key *entity.APIKey) error {
+	id, err := uuid.NewV4()
+	if err != nil {
+		return err
+	}
+	key.ID = id
+	key.CreatedAt = time.Now()
+
+	query := `INSERT INTO api_keys (` + apiKeyColumns + `) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)`
+	if _, err := r.db.Exec(query, key.ID, key.UserID, key.Name, key.Prefix, key.KeyHash, pq.Array(key.Scopes),
+		key.ExpiresAt, key.LastUsedAt, key.CreatedAt); err != nil {
+		log.Printf("Error inserting API key: %v", err)
+		return storeError(err)
+	}
+	return nil
+}
+
+// FindByHash implements repository.APIKeyRepository.
+func (r *apiKeyRepositoryImpl) FindByHash(keyHash string, now time.Time) (*entity.APIKey, error) {
+	row := r.db.QueryRow(`SELECT `+apiKeyColumns+` FROM api_keys WHERE key_hash = $1 AND expires_at > $2`, keyHash, now)
+	key, err := scanAPIKey(row)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, notFound("API key not found")
+		}
+		log.Printf("Error fetching API key: %v", err)
+		return nil, err
+	}
+	return key, nil
+}
+
+// ListByUserID implements repository.APIKeyRepository.
+func (r *apiKeyRepositoryImpl) ListByUserID(userID uuid.UUID) ([]*entity.APIKey, error) {
+	rows, err := r.db.Query(`SELECT `+apiKeyColumns+` FROM api_keys WHERE user_id = $1 ORDER BY created_at DESC`, userID)
+	if err != nil {
+		log.Printf("Error fetching API keys of user %v: %v", userID, err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	keys := []*entity.APIKey{}
+	for rows.Next() {
+		key, err := scanAPIKey(rows)
+		if err != nil {
+			log.Printf("Error scanning API key: %v", err)
+			return nil, err
+		}
+		keys = append(keys, key)
+	}
+	return keys, rows.Err()
+}
+
+// Delete implements repository.APIKeyRepository.
+func (r *apiKeyRepositoryImpl) Delete(userID, id uuid.UUID) error {
+	result, err := r.db.Exec(`DELETE FROM api_keys WHERE id = $1 AND user_id = $2`, id, userID)
+	if err != nil {
+		log.Printf("Error deleting API key %v: %v", id, err)
+		return storeError(err)
+	}
+	return expectRow(result, "API key not found")
+}
+
+// DeleteByUserID implements repository.APIKeyRepository.
+func (r *apiKeyRepositoryImpl) DeleteByUserID(userID uuid.UUID) error {
+	if _, err := r.db.Exec(`DELETE FROM api_keys WHERE user_id = $1`, userID); err != nil {
+		log.Printf("Error deleting API keys of user %v: %v", userID, err)
+		return storeError(err)
+	}
+	return nil
+}
+
+// RecordUse implements repository.APIKeyRepository.
+func (r *apiKeyRepositoryImpl) RecordUse(id uuid.UUID, at, since time.Time) error {
+	if _, err := r.db.Exec(`UPDATE api_keys SET last_used_at = $2 WHERE id = $1 AND (last_used_at IS NULL OR last_used_at < $3)`,
+		id, at, since); err != nil {
+		log.Printf("Error recording use of API key %v: %v", id, err)
+		return storeError(err)
+	}
+	return nil
+}
@@ -0,0 +1,54 @@
+package gateway
+
+import (
+	"dalabio/internal/entity"
+	"dalabio/internal/repository"
+	"dalabio/pkg/utils"
+	"log"
+	"strings"
+	"time"
+)
+
+// apiKeyUseInterval is how often the last use of an API key is recorded, so that busy scripts do not
+// write on every request
+const apiKeyUseInterval = time.Minute
+
+// apiKeyTokenRepositoryImpl finds API keys as well as the tokens of signed in users.
+type apiKeyTokenRepositoryImpl struct {
+	repository.TokenRepository
+	keys repository.APIKeyRepository
+}
+
+// NewAPIKeyTokenRepository creates a TokenRepository accepting the API keys of keys besides the tokens of tokens.
+func NewAPIKeyTokenRepository(tokens repository.TokenRepository, keys repository.APIKeyRepository) repository.TokenRepository {
+	return &apiKeyTokenRepositoryImpl{TokenRepository: tokens, keys: keys}
+}
+
+// FindByToken implements repository.TokenRepository. API keys are returned with their scopes and their use is recorded.
+func (r *apiKeyTokenRepositoryImpl) FindByToken(token string) (*entity.Token, error) {
+	if !strings.HasPrefix(token, entity.APIKeyPrefix) {
+		return r.TokenRepository.FindByToken(token)
+	}
+
+	now := time.Now()
+	key, err := r.keys.FindByHash(utils.HashToken(token), now)
+	if err != nil {
+		return nil, err
+	}
+	if err := r.keys.RecordUse(key.ID, now, now.Add(-apiKeyUseInterval)); err != nil {
+		log.Printf("Failed to record use of API key %v: %v", key.ID, err)
+	}
+
+	scopes := key.Scopes
+	if scopes == nil {
+		scopes = []string{}
+	}
+	return &entity.Token{
+		ID:        key.ID,
+		UserID:    key.UserID,
+		Token:     token,
+		ExpiresAt: key.ExpiresAt,
+		CreatedAt: key.CreatedAt,
+		Scopes:    scopes,
+	}, nil
+}
@@ -1 +1,392 @@
 package gateway
+
+import (
+	"dalabio/internal/entity"
+	"dalabio/internal/repository"
+	"database/sql"
+	"log"
+	"time"
+
+	"github.com/gofrs/uuid"
+	"github.com/lib/pq"
+)
+
+type communityRepositoryImpl struct {
+	db *sql.DB
+}
+
+// NewCommunityRepository creates a new instance of CommunityRepository.
+func NewCommunityRepository(db *sql.DB) repository.CommunityRepository {
+	return &communityRepositoryImpl{db: db}
+}
+
+const postColumns = `p.id, p.space_id, p.author_id, u.username, p.title, p.body, p.pinned_at, p.hidden_at, p.hidden_by, p.hidden_reason, p.edited_at, p.created_at, p.updated_at,
+	(SELECT COUNT(*) FROM space_post_comments c WHERE c.post_id = p.id AND c.deleted_at IS NULL AND c.hidden_at IS NULL)
+	FROM space_posts p JOIN users u ON u.id = p.author_id`
+
+// scanPost scans a row selected with postColumns
+func scanPost(row interface{ Scan(...interface{}) error }) (*entity.Post, error) {
+	var post entity.Post
+	err := row.Scan(
+		&post.ID,
+		&post.SpaceID,
+		&post.AuthorID,
+		&post.AuthorUsername,
+		&post.Title,
+		&post.Body,
+		&post.PinnedAt,
+		&post.HiddenAt,
+		&post.HiddenBy,
+		&post.HiddenReason,
+		&post.EditedAt,
+		&post.CreatedAt,
+		&post.UpdatedAt,
+		&post.CommentCount,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &post, nil
+}
+
+const commentColumns = `c.id, c.post_id, c.parent_id, c.author_id, u.username, c.body, c.hidden_at, c.hidden_by, c.hidden_reason, c.edited_at, c.created_at, c.updated_at, c.deleted_at
+	FROM space_post_comments c JOIN users u ON u.id = c.author_id`
+
+// scanComment scans a row selected with commentColumns
+func scanComment(row interface{ Scan(...interface{}) error }) (*entity.Comment, error) {
+	var comment entity.Comment
+	err := row.Scan(
+		&comment.ID,
+		&comment.PostID,
+		&comment.ParentID,
+		&comment.AuthorID,
+		&comment.AuthorUsername,
+		&comment.Body,
+		&comment.HiddenAt,
+		&comment.HiddenBy,
+		&comment.HiddenReason,
+		&comment.EditedAt,
+		&comment.CreatedAt,
+		&comment.UpdatedAt,
+		&comment.DeletedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &comment, nil
+}
+
+// CreatePost implements repository.CommunityRepository.
+func (r *communityRepositoryImpl) CreatePost(post *entity.Post) error {
+	post.CreatedAt = time.Now()
+	post.UpdatedAt = post.CreatedAt
+
+	query := `INSERT INTO space_posts (id, space_id, author_id, title, body, created_at, updated_at) VALUES ($1, $2, $3, $4, $5, $6, $7)`
+	if _, err := r.db.Exec(query, post.ID, post.SpaceID, post.AuthorID, post.Title, post.Body, post.CreatedAt, post.UpdatedAt); err != nil {
+		log.Printf("Error inserting post: %v, query: %s", err, query)
+		return storeError(err)
+	}
+
+	return nil
+}
+
+// UpdatePost implements repository.CommunityRepository.
+func (r *communityRepositoryImpl) UpdatePost(post *entity.Post, revision *entity.Revision) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := insertRevision(tx, revision); err != nil {
+		return err
+	}
+
+	now := time.Now()
+	result, err := tx.Exec(`UPDATE space_posts SET title = $2, body = $3, edited_at = $4, updated_at = $4 WHERE id = $1 AND deleted_at IS NULL`,
+		post.ID, post.Title, post.Body, now)
+	if err != nil {
+		log.Printf("Error updating post with ID: %v, error: %v", post.ID, err)
+		return storeError(err)
+	}
+	if err := expectRow(result, "post not found"); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	post.EditedAt = &now
+	post.UpdatedAt = now
+	return nil
+}
+
+// DeletePost implements repository.CommunityRepository.
+func (r *communityRepositoryImpl) DeletePost(postID uuid.UUID) error {
+	result, err := r.db.Exec(`UPDATE space_posts SET deleted_at = CURRENT_TIMESTAMP WHERE id = $1 AND deleted_at IS NULL`, postID)
+	if err != nil {
+		log.Printf("Error deleting post with ID: %v, error: %v", postID, err)
+		return storeError(err)
+	}
+	return expectRow(result, "post not found")
+}
+
+// GetPostByID implements repository.CommunityRepository.
+func (r *communityRepositoryImpl) GetPostByID(postID uuid.UUID) (*entity.Post, error) {
+	post, err := scanPost(r.db.QueryRow(`SELECT `+postColumns+` WHERE p.id = $1 AND p.deleted_at IS NULL`, postID))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, notFound("post not found")
+		}
+		log.Printf("Error retrieving post by ID: %v", err)
+		return nil, err
+	}
+	return post, nil
+}
+
+// GetPostsBySpaceID implements repository.CommunityRepository.
+func (r *communityRepositoryImpl) GetPostsBySpaceID(spaceID uuid.UUID) ([]*entity.Post, error) {
+	rows, err := r.db.Query(`SELECT `+postColumns+` WHERE p.space_id = $1 AND p.deleted_at IS NULL
+	ORDER BY p.pinned_at DESC NULLS LAST, p.created_at DESC`, spaceID)
+	if err != nil {
+		log.Printf("Error retrieving posts of space %v: %v", spaceID, err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	posts := []*entity.Post{}
+	for rows.Next() {
+		post, err := scanPost(rows)
+		if err != nil {
+			log.Printf("Error scanning post: %v", err)
+			return nil, err
+		}
+		posts = append(posts, post)
+	}
+
+	return posts, rows.Err()
+}
+
+// SetPostPinned implements repository.CommunityRepository.
+func (r *communityRepositoryImpl) SetPostPinned(postID uuid.UUID, pinned bool) error {
+	result, err := r.db.Exec(`UPDATE space_posts SET pinned_at = CASE WHEN $2 THEN COALESCE(pinned_at, CURRENT_TIMESTAMP) END
+	WHERE id = $1 AND deleted_at IS NULL`, postID, pinned)
+	if err != nil {
+		log.Printf("Error pinning post with ID: %v, error: %v", postID, err)
+		return storeError(err)
+	}
+	return expectRow(result, "post not found")
+}
+
+// SetPostHidden implements repository.CommunityRepository.
+func (r *communityRepositoryImpl) SetPostHidden(postID uuid.UUID, hiddenBy *uuid.UUID, reason string) error {
+	result, err := r.db.Exec(`UPDATE space_posts SET hidden_at = CASE WHEN $2::uuid IS NULL THEN NULL ELSE CURRENT_TIMESTAMP END, hidden_by = $2, hidden_reason = $3
+	WHERE id = $1 AND deleted_at IS NULL`, postID, hiddenBy, reason)
+	if err != nil {
+		log.Printf("Error hiding post with ID: %v, error: %v", postID, err)
+		return storeError(err)
+	}
+	return expectRow(result, "post not found")
+}
+
+// CreateComment implements repository.CommunityRepository.
+func (r *communityRepositoryImpl) CreateComment(comment *entity.Comment) error {
+	comment.CreatedAt = time.Now()
+	comment.UpdatedAt = comment.CreatedAt
+
+	query := `INSERT INTO space_post_comments (id, post_id, parent_id, author_id, body, created_at, updated_at) VALUES ($1, $2, $3, $4, $5, $6, $7)`
+	if _, err := r.db.Exec(query, comment.ID, comment.PostID, comment.ParentID, comment.AuthorID, comment.Body, comment.CreatedAt, comment.UpdatedAt); err != nil {
+		log.Printf("Error inserting comment: %v, query: %s", err, query)
+		return storeError(err)
+	}
+
+	return nil
+}
+
+// UpdateComment implements repository.CommunityRepository.
+func (r *communityRepositoryImpl) UpdateComment(comment *entity.Comment, revision *entity.Revision) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := insertRevision(tx, revision); err != nil {
+		return err
+	}
+
+	now := time.Now()
+	result, err := tx.Exec(`UPDATE space_post_comments SET body = $2, edited_at = $3, updated_at = $3 WHERE id = $1 AND deleted_at IS NULL`,
+		comment.ID, comment.Body, now)
+	if err != nil {
+		log.Printf("Error updating comment with ID: %v, error: %v", comment.ID, err)
+		return storeError(err)
+	}
+	if err := expectRow(result, "comment not found"); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	comment.EditedAt = &now
+	comment.UpdatedAt = now
+	return nil
+}
+
+// DeleteComment implements repository.CommunityRepository.
+func (r *communityRepositoryImpl) DeleteComment(commentID uuid.UUID) error {
+	result, err := r.db.Exec(`UPDATE space_post_comments SET deleted_at = CURRENT_TIMESTAMP WHERE id = $1 AND deleted_at IS NULL`, commentID)
+	if err != nil {
+		log.Printf("Error deleting comment with ID: %v, error: %v", commentID, err)
+		return storeError(err)
+	}
+	return expectRow(result, "comment not found")
+}
+
+// GetCommentByID implements repository.CommunityRepository.
+func (r *communityRepositoryImpl) GetCommentByID(commentID uuid.UUID) (*entity.Comment, error) {
+	comment, err := scanComment(r.db.QueryRow(`SELECT `+commentColumns+` WHERE c.id = $1 AND c.deleted_at IS NULL`, commentID))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, notFound("comment not found")
+		}
+		log.Printf("Error retrieving comment by ID: %v", err)
+		return nil, err
+	}
+	return comment, nil
+}
+
+// GetCommentsByPostID implements repository.CommunityRepository.
+func (r *communityRepositoryImpl) GetCommentsByPostID(postID uuid.UUID) ([]*entity.Comment, error) {
+	rows, err := r.db.Query(`SELECT `+commentColumns+` WHERE c.post_id = $1 ORDER BY c.created_at`, postID)
+	if err != nil {
+		log.Printf("Error retrieving comments of post %v: %v", postID, err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	comments := []*entity.Comment{}
+	for rows.Next() {
+		comment, err := scanComment(rows)
+		if err != nil {
+			log.Printf("Error scanning comment: %v", err)
+			return nil, err
+		}
+		comments = append(comments, comment)
+	}
+
+	return comments, rows.Err()
+}
+
+// SetCommentHidden implements repository.CommunityRepository.
+func (r *communityRepositoryImpl) SetCommentHidden(commentID uuid.UUID, hiddenBy *uuid.UUID, reason string) error {
+	result, err := r.db.Exec(`UPDATE space_post_comments SET hidden_at = CASE WHEN $2::uuid IS NULL THEN NULL ELSE CURRENT_TIMESTAMP END, hidden_by = $2, hidden_reason = $3
+	WHERE id = $1 AND deleted_at IS NULL`, commentID, hiddenBy, reason)
+	if err != nil {
+		log.Printf("Error hiding comment with ID: %v, error: %v", commentID, err)
+		return storeError(err)
+	}
+	return expectRow(result, "comment not found")
+}
+
+// AddReaction implements repository.CommunityRepository.
+func (r *communityRepositoryImpl) AddReaction(reaction *entity.Reaction) error {
+	reaction.CreatedAt = time.Now()
+
+	query := `INSERT INTO space_post_reactions (target_type, target_id, user_id, emoji, created_at) VALUES ($1, $2, $3, $4, $5)
+	ON CONFLICT (target_type, target_id, user_id, emoji) DO NOTHING`
+	if _, err := r.db.Exec(query, reaction.TargetType, reaction.TargetID, reaction.UserID, reaction.Emoji, reaction.CreatedAt); err != nil {
+		log.Printf("Error inserting reaction: %v, query: %s", err, query)
+		return storeError(err)
+	}
+
+	return nil
+}
+
+// RemoveReaction implements repository.CommunityRepository.
+func (r *communityRepositoryImpl) RemoveReaction(targetType string, targetID, userID uuid.UUID, emoji string) error {
+	result, err := r.db.Exec(`DELETE FROM space_post_reactions WHERE target_type = $1 AND target_id = $2 AND user_id = $3 AND emoji = $4`,
+		targetType, targetID, userID, emoji)
+	if err != nil {
+		log.Printf("Error removing reaction from %s %v: %v", targetType, targetID, err)
+		return storeError(err)
+	}
+	return expectRow(result, "reaction not found")
+}
+
+// GetReactionCounts implements repository.CommunityRepository.
+func (r *communityRepositoryImpl) GetReactionCounts(targetType string, targetIDs []uuid.UUID) (map[uuid.UUID]map[string]int, error) {
+	counts := map[uuid.UUID]map[string]int{}
+	if len(targetIDs) == 0 {
+		return counts, nil
+	}
+
+	rows, err := r.db.Query(`SELECT target_id, emoji, COUNT(*) FROM space_post_reactions
+	WHERE target_type = $1 AND target_id = ANY($2) GROUP BY target_id, emoji`, targetType, pq.Array(targetIDs))
+	if err != nil {
+		log.Printf("Error counting reactions: %v", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var targetID uuid.UUID
+		var emoji string
+		var count int
+		if err := rows.Scan(&targetID, &emoji, &count); err != nil {
+			return nil, err
+		}
+		if counts[targetID] == nil {
+			counts[targetID] = map[string]int{}
+		}
+		counts[targetID][emoji] = count
+	}
+
+	return counts, rows.Err()
+}
+
+// GetRevisions implements repository.CommunityRepository.
+func (r *communityRepositoryImpl) GetRevisions(targetType string, targetID uuid.UUID) ([]*entity.Revision, error) {
+	rows, err := r.db.Query(`SELECT id, target_type, target_id, title, body, edited_by, edited_at FROM space_post_revisions
+	WHERE target_type = $1 AND target_id = $2 ORDER BY edited_at`, targetType, targetID)
+	if err != nil {
+		log.Printf("Error retrieving revisions of %s %v: %v", targetType, targetID, err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	revisions := []*entity.Revision{}
+	for rows.Next() {
+		var revision entity.Revision
+		if err := rows.Scan(&revision.ID, &revision.TargetType, &revision.TargetID, &revision.Title, &revision.Body, &revision.EditedBy, &revision.EditedAt); err != nil {
+			return nil, err
+		}
+		revisions = append(revisions, &revision)
+	}
+
+	return revisions, rows.Err()
+}
+
+// insertRevision stores the previous content of an edited post or comment
+func insertRevision(tx *sql.Tx, revision *entity.Revision) error {
+	query := `INSERT INTO space_post_revisions (id, target_type, target_id, title, body, edited_by, edited_at) VALUES ($1, $2, $3, $4, $5, $6, $7)`
+	if _, err := tx.Exec(query, revision.ID, revision.TargetType, revision.TargetID, revision.Title, revision.Body, revision.EditedBy, revision.EditedAt); err != nil {
+		log.Printf("Error inserting revision: %v, query: %s", err, query)
+		return storeError(err)
+	}
+	return nil
+}
+
+// expectRow fails with an error of kind repository.ErrNotFound when a statement did not affect any row
+func expectRow(result sql.Result, message string) error {
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		log.Printf("Error fetching rows affected: %v", err)
+		return err
+	}
+	if rowsAffected == 0 {
+		return notFound(message)
+	}
+	return nil
+}
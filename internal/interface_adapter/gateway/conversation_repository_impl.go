@@ -0,0 +1,300 @@
+package gateway
+
+import (
+	"dalabio/internal/entity"
+	"dalabio/internal/repository"
+	"database/sql"
+	"log"
+	"time"
+
+	"github.com/gofrs/uuid"
+	"github.com/lib/pq"
+)
+
+type conversationRepositoryImpl struct {
+	db *sql.DB
+}
+
+// NewConversationRepository creates a new instance of ConversationRepository.
+func NewConversationRepository(db *sql.DB) repository.ConversationRepository {
+	return &conversationRepositoryImpl{db: db}
+}
+
+const messageColumns = `id, conversation_id, sender_id, body, created_at`
+
+// scanMessage scans a row selected with messageColumns
+func scanMessage(row interface{ Scan(...interface{}) error }) (*entity.Message, error) {
+	var message entity.Message
+	if err := row.Scan(&message.ID, &message.ConversationID, &message.SenderID, &message.Body, &message.CreatedAt); err != nil {
+		return nil, err
+	}
+	return &message, nil
+}
+
+// Create implements repository.ConversationRepository.
+func (r *conversationRepositoryImpl) Create(conversation *entity.Conversation, directKey string, participantIDs []uuid.UUID) (*entity.Conversation, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	now := time.Now()
+	query := `INSERT INTO conversations (id, title, is_group, direct_key, created_by, created_at, updated_at)
+	VALUES ($1, $2, $3, NULLIF($4, ''), $5, $6, $6)
+	ON CONFLICT (direct_key) DO NOTHING`
+	result, err := tx.Exec(query, conversation.ID, conversation.Title, conversation.IsGroup, directKey, conversation.CreatedBy, now)
+	if err != nil {
+		log.Printf("Error inserting conversation: %v, query: %s", err, query)
+		return nil, storeError(err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return nil, err
+	}
+	if rowsAffected == 0 {
+		// The two users already have a direct conversation
+		var existingID uuid.UUID
+		if err := tx.QueryRow(`SELECT id FROM conversations WHERE direct_key = $1`, directKey).Scan(&existingID); err != nil {
+			log.Printf("Error fetching direct conversation %s: %v", directKey, err)
+			return nil, err
+		}
+		tx.Rollback()
+		return r.GetByID(existingID)
+	}
+
+	for _, participantID := range participantIDs {
+		if _, err := tx.Exec(`INSERT INTO conversation_participants (conversation_id, user_id, joined_at) VALUES ($1, $2, $3)`,
+			conversation.ID, participantID, now); err != nil {
+			log.Printf("Error adding participant %v to conversation %v: %v", participantID, conversation.ID, err)
+			return nil, storeError(err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return r.GetByID(conversation.ID)
+}
+
+// GetByID implements repository.ConversationRepository.
+func (r *conversationRepositoryImpl) GetByID(conversationID uuid.UUID) (*entity.Conversation, error) {
+	var conversation entity.Conversation
+	err := r.db.QueryRow(`SELECT id, title, is_group, created_by, created_at, updated_at FROM conversations WHERE id = $1`, conversationID).Scan(
+		&conversation.ID, &conversation.Title, &conversation.IsGroup, &conversation.CreatedBy, &conversation.CreatedAt, &conversation.UpdatedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, notFound("conversation not found")
+		}
+		log.Printf("Error retrieving conversation by ID: %v", err)
+		return nil, err
+	}
+
+	if err := r.loadDetails([]*entity.Conversation{&conversation}); err != nil {
+		return nil, err
+	}
+	return &conversation, nil
+}
+
+// GetByUserID implements repository.ConversationRepository.
+func (r *conversationRepositoryImpl) GetByUserID(userID uuid.UUID) ([]*entity.Conversation, error) {
+	query := `SELECT c.id, c.title, c.is_group, c.created_by, c.created_at, c.updated_at,
+		(SELECT COUNT(*) FROM messages m WHERE m.conversation_id = c.id AND m.sender_id <> $1
+		 AND (p.last_read_at IS NULL OR m.created_at > p.last_read_at))
+	FROM conversations c JOIN conversation_participants p ON p.conversation_id = c.id AND p.user_id = $1
+	ORDER BY c.updated_at DESC`
+	rows, err := r.db.Query(query, userID)
+	if err != nil {
+		log.Printf("Error retrieving conversations of user %v: %v", userID, err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	conversations := []*entity.Conversation{}
+	for rows.Next() {
+		var conversation entity.Conversation
+		if err := rows.Scan(&conversation.ID, &conversation.Title, &conversation.IsGroup, &conversation.CreatedBy,
+			&conversation.CreatedAt, &conversation.UpdatedAt, &conversation.UnreadCount); err != nil {
+			log.Printf("Error scanning conversation: %v", err)
+			return nil, err
+		}
+		conversations = append(conversations, &conversation)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if err := r.loadDetails(conversations); err != nil {
+		return nil, err
+	}
+	return conversations, nil
+}
+
+// loadDetails fills in the participants and the last message of conversations
+func (r *conversationRepositoryImpl) loadDetails(conversations []*entity.Conversation) error {
+	if len(conversations) == 0 {
+		return nil
+	}
+
+	byID := make(map[uuid.UUID]*entity.Conversation, len(conversations))
+	ids := make([]uuid.UUID, len(conversations))
+	for i, conversation := range conversations {
+		conversation.Participants = []*entity.ConversationParticipant{}
+		byID[conversation.ID] = conversation
+		ids[i] = conversation.ID
+	}
+
+	rows, err := r.db.Query(`SELECT p.conversation_id, p.user_id, u.username, p.last_read_at, p.joined_at
+	FROM conversation_participants p JOIN users u ON u.id = p.user_id
+	WHERE p.conversation_id = ANY($1) ORDER BY p.joined_at`, pq.Array(ids))
+	if err != nil {
+		log.Printf("Error retrieving conversation participants: %v", err)
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var participant entity.ConversationParticipant
+		if err := rows.Scan(&participant.ConversationID, &participant.UserID, &participant.Username, &participant.LastReadAt, &participant.JoinedAt); err != nil {
+			return err
+		}
+		conversation := byID[participant.ConversationID]
+		conversation.Participants = append(conversation.Participants, &participant)
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	messages, err := r.db.Query(`SELECT DISTINCT ON (conversation_id) `+messageColumns+` FROM messages
+	WHERE conversation_id = ANY($1) ORDER BY conversation_id, created_at DESC, id DESC`, pq.Array(ids))
+	if err != nil {
+		log.Printf("Error retrieving last messages: %v", err)
+		return err
+	}
+	defer messages.Close()
+
+	for messages.Next() {
+		message, err := scanMessage(messages)
+		if err != nil {
+			return err
+		}
+		byID[message.ConversationID].LastMessage = message
+	}
+
+	return messages.Err()
+}
+
+// GetParticipant implements repository.ConversationRepository.
+func (r *conversationRepositoryImpl) GetParticipant(conversationID, userID uuid.UUID) (*entity.ConversationParticipant, error) {
+	var participant entity.ConversationParticipant
+	err := r.db.QueryRow(`SELECT p.conversation_id, p.user_id, u.username, p.last_read_at, p.joined_at
+	FROM conversation_participants p JOIN users u ON u.id = p.user_id
+	WHERE p.conversation_id = $1 AND p.user_id = $2`, conversationID, userID).Scan(
+		&participant.ConversationID, &participant.UserID, &participant.Username, &participant.LastReadAt, &participant.JoinedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, notFound("participant not found")
+		}
+		log.Printf("Error fetching participant %v of conversation %v: %v", userID, conversationID, err)
+		return nil, err
+	}
+	return &participant, nil
+}
+
+// CreateMessage implements repository.ConversationRepository.
+func (r *conversationRepositoryImpl) CreateMessage(message *entity.Message) error {
+	message.CreatedAt = time.Now()
+
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	query := `INSERT INTO messages (` + messageColumns + `) VALUES ($1, $2, $3, $4, $5)`
+	if _, err := tx.Exec(query, message.ID, message.ConversationID, message.SenderID, message.Body, message.CreatedAt); err != nil {
+		log.Printf("Error inserting message: %v, query: %s", err, query)
+		return storeError(err)
+	}
+
+	if _, err := tx.Exec(`UPDATE conversations SET updated_at = $2 WHERE id = $1`, message.ConversationID, message.CreatedAt); err != nil {
+		log.Printf("Error updating conversation %v: %v", message.ConversationID, err)
+		return storeError(err)
+	}
+
+	// Senders have read their own messages
+	if _, err := tx.Exec(`UPDATE conversation_participants SET last_read_at = GREATEST(last_read_at, $3)
+	WHERE conversation_id = $1 AND user_id = $2`, message.ConversationID, message.SenderID, message.CreatedAt); err != nil {
+		log.Printf("Error updating read receipt of %v: %v", message.SenderID, err)
+		return storeError(err)
+	}
+
+	return tx.Commit()
+}
+
+// GetMessage implements repository.ConversationRepository.
+func (r *conversationRepositoryImpl) GetMessage(messageID uuid.UUID) (*entity.Message, error) {
+	message, err := scanMessage(r.db.QueryRow(`SELECT `+messageColumns+` FROM messages WHERE id = $1`, messageID))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, notFound("message not found")
+		}
+		log.Printf("Error fetching message with ID %v: %v", messageID, err)
+		return nil, err
+	}
+	return message, nil
+}
+
+// GetMessages implements repository.ConversationRepository.
+func (r *conversationRepositoryImpl) GetMessages(conversationID uuid.UUID, before *uuid.UUID, limit int) ([]*entity.Message, error) {
+	query := `SELECT ` + messageColumns + ` FROM messages
+	WHERE conversation_id = $1
+	AND ($2::uuid IS NULL OR (created_at, id) < (SELECT created_at, id FROM messages WHERE id = $2))
+	ORDER BY created_at DESC, id DESC
+	LIMIT $3`
+	rows, err := r.db.Query(query, conversationID, before, limit)
+	if err != nil {
+		log.Printf("Error retrieving messages of conversation %v: %v", conversationID, err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	messages := []*entity.Message{}
+	for rows.Next() {
+		message, err := scanMessage(rows)
+		if err != nil {
+			log.Printf("Error scanning message: %v", err)
+			return nil, err
+		}
+		messages = append(messages, message)
+	}
+
+	return messages, rows.Err()
+}
+
+// MarkRead implements repository.ConversationRepository.
+func (r *conversationRepositoryImpl) MarkRead(conversationID, userID uuid.UUID, upTo *uuid.UUID) (*entity.ConversationParticipant, error) {
+	query := `UPDATE conversation_participants p SET last_read_at = GREATEST(p.last_read_at, m.created_at)
+	FROM (SELECT MAX(created_at) AS created_at FROM messages WHERE conversation_id = $1 AND ($3::uuid IS NULL OR id = $3)) m
+	WHERE p.conversation_id = $1 AND p.user_id = $2 AND m.created_at IS NOT NULL`
+	if _, err := r.db.Exec(query, conversationID, userID, upTo); err != nil {
+		log.Printf("Error marking conversation %v as read by %v: %v", conversationID, userID, err)
+		return nil, storeError(err)
+	}
+
+	return r.GetParticipant(conversationID, userID)
+}
+
+// CountUnread implements repository.ConversationRepository.
+func (r *conversationRepositoryImpl) CountUnread(userID uuid.UUID) (int, error) {
+	var count int
+	query := `SELECT COUNT(*) FROM messages m
+	JOIN conversation_participants p ON p.conversation_id = m.conversation_id AND p.user_id = $1
+	WHERE m.sender_id <> $1 AND (p.last_read_at IS NULL OR m.created_at > p.last_read_at)`
+	if err := r.db.QueryRow(query, userID).Scan(&count); err != nil {
+		log.Printf("Error counting unread messages of %v: %v", userID, err)
+		return 0, err
+	}
+	return count, nil
+}
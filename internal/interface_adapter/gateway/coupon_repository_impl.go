@@ -0,0 +1,285 @@
+package gateway
+
+import (
+	"dalabio/internal/entity"
+	"dalabio/internal/repository"
+	"database/sql"
+	"log"
+	"time"
+
+	"github.com/gofrs/uuid"
+	"github.com/lib/pq"
+)
+
+type couponRepositoryImpl struct {
+	db *sql.DB
+}
+
+// NewCouponRepository creates a new instance of CouponRepository.
+func NewCouponRepository(db *sql.DB) repository.CouponRepository {
+	return &couponRepositoryImpl{db: db}
+}
+
+const couponColumns = `id, code, discount_type, value, currency, expires_at, max_redemptions, per_user_limit, redemption_count,
+	course_ids, space_ids, active, created_at, updated_at`
+
+// scanCoupon scans a row selected with couponColumns
+func scanCoupon(row interface{ Scan(...interface{}) error }) (*entity.Coupon, error) {
+	var coupon entity.Coupon
+	err := row.Scan(
+		&coupon.ID,
+		&coupon.Code,
+		&coupon.DiscountType,
+		&coupon.Value,
+		&coupon.Currency,
+		&coupon.ExpiresAt,
+		&coupon.MaxRedemptions,
+		&coupon.PerUserLimit,
+		&coupon.RedemptionCount,
+		pq.Array(&coupon.CourseIDs),
+		pq.Array(&coupon.SpaceIDs),
+		&coupon.Active,
+		&coupon.CreatedAt,
+		&coupon.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &coupon, nil
+}
+
+// Create implements repository.CouponRepository.
+func (r *couponRepositoryImpl) Create(coupon *entity.Coupon) error {
+	now := time.Now()
+	coupon.CreatedAt = now
+	coupon.UpdatedAt = now
+
+	query := `INSERT INTO coupons (` + couponColumns + `)
+	VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14)`
+	_, err := r.db.Exec(query,
+		coupon.ID,
+		coupon.Code,
+		coupon.DiscountType,
+		coupon.Value,
+		coupon.Currency,
+		coupon.ExpiresAt,
+		coupon.MaxRedemptions,
+		coupon.PerUserLimit,
+		coupon.RedemptionCount,
+		pq.Array(coupon.CourseIDs),
+		pq.Array(coupon.SpaceIDs),
+		coupon.Active,
+		coupon.CreatedAt,
+		coupon.UpdatedAt,
+	)
+	if err != nil {
+		log.Printf("Error inserting coupon: %v, query: %s", err, query)
+		return err
+	}
+
+	return nil
+}
+
+// Update implements repository.CouponRepository.
+// The redemption count is owned by Redeem and never overwritten here.
+func (r *couponRepositoryImpl) Update(coupon *entity.Coupon) error {
+	query := `UPDATE coupons
+	SET code = $2, discount_type = $3, value = $4, currency = $5, expires_at = $6, max_redemptions = $7, per_user_limit = $8,
+	    course_ids = $9, space_ids = $10, active = $11, updated_at = CURRENT_TIMESTAMP
+	WHERE id = $1`
+	result, err := r.db.Exec(query,
+		coupon.ID,
+		coupon.Code,
+		coupon.DiscountType,
+		coupon.Value,
+		coupon.Currency,
+		coupon.ExpiresAt,
+		coupon.MaxRedemptions,
+		coupon.PerUserLimit,
+		pq.Array(coupon.CourseIDs),
+		pq.Array(coupon.SpaceIDs),
+		coupon.Active,
+	)
+	if err != nil {
+		log.Printf("Error updating coupon with ID: %v, error: %v", coupon.ID, err)
+		return storeError(err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		log.Printf("Error fetching rows affected: %v", err)
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return notFound("coupon not found")
+	}
+
+	return nil
+}
+
+// Delete implements repository.CouponRepository.
+func (r *couponRepositoryImpl) Delete(couponID uuid.UUID) error {
+	result, err := r.db.Exec(`DELETE FROM coupons WHERE id = $1`, couponID)
+	if err != nil {
+		log.Printf("Error deleting coupon with ID: %v, error: %v", couponID, err)
+		return storeError(err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		log.Printf("Error fetching rows affected: %v", err)
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return notFound("coupon not found")
+	}
+
+	return nil
+}
+
+// GetdByID implements repository.CouponRepository.
+func (r *couponRepositoryImpl) GetdByID(couponID uuid.UUID) (*entity.Coupon, error) {
+	coupon, err := scanCoupon(r.db.QueryRow(`SELECT `+couponColumns+` FROM coupons WHERE id = $1`, couponID))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, notFound("coupon not found")
+		}
+		log.Printf("Error fetching coupon with ID: %v, error: %v", couponID, err)
+		return nil, err
+	}
+
+	return coupon, nil
+}
+
+// GetByCode implements repository.CouponRepository.
+func (r *couponRepositoryImpl) GetByCode(code string) (*entity.Coupon, error) {
+	coupon, err := scanCoupon(r.db.QueryRow(`SELECT `+couponColumns+` FROM coupons WHERE code = $1`, code))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, notFound("coupon not found")
+		}
+		log.Printf("Error fetching coupon with code: %v, error: %v", code, err)
+		return nil, err
+	}
+
+	return coupon, nil
+}
+
+// GetAll implements repository.CouponRepository.
+func (r *couponRepositoryImpl) GetAll() ([]*entity.Coupon, error) {
+	rows, err := r.db.Query(`SELECT ` + couponColumns + ` FROM coupons ORDER BY created_at DESC`)
+	if err != nil {
+		log.Printf("Error fetching coupons: %v", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	var coupons []*entity.Coupon
+	for rows.Next() {
+		coupon, err := scanCoupon(rows)
+		if err != nil {
+			log.Printf("Error scanning coupon: %v", err)
+			return nil, err
+		}
+		coupons = append(coupons, coupon)
+	}
+
+	if err = rows.Err(); err != nil {
+		log.Printf("Error iterating over coupons: %v", err)
+		return nil, err
+	}
+
+	return coupons, nil
+}
+
+// CountUserRedemptions implements repository.CouponRepository.
+func (r *couponRepositoryImpl) CountUserRedemptions(couponID, userID uuid.UUID) (int, error) {
+	var count int
+	err := r.db.QueryRow(`SELECT COUNT(*) FROM coupon_redemptions WHERE coupon_id = $1 AND user_id = $2`, couponID, userID).Scan(&count)
+	if err != nil {
+		log.Printf("Error counting redemptions of coupon: %v, error: %v", couponID, err)
+		return 0, err
+	}
+	return count, nil
+}
+
+// Redeem implements repository.CouponRepository.
+// The coupon row is locked so concurrent checkouts cannot exceed its limits.
+func (r *couponRepositoryImpl) Redeem(redemption *entity.CouponRedemption) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		log.Printf("Error starting redemption transaction: %v", err)
+		return err
+	}
+	defer tx.Rollback()
+
+	var maxRedemptions, perUserLimit, redemptionCount int
+	err = tx.QueryRow(`SELECT max_redemptions, per_user_limit, redemption_count FROM coupons WHERE id = $1 FOR UPDATE`, redemption.CouponID).
+		Scan(&maxRedemptions, &perUserLimit, &redemptionCount)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return notFound("coupon not found")
+		}
+		log.Printf("Error locking coupon with ID: %v, error: %v", redemption.CouponID, err)
+		return err
+	}
+
+	if maxRedemptions > 0 && redemptionCount >= maxRedemptions {
+		return conflict("coupon has reached its maximum number of redemptions")
+	}
+
+	if perUserLimit > 0 {
+		var userCount int
+		err = tx.QueryRow(`SELECT COUNT(*) FROM coupon_redemptions WHERE coupon_id = $1 AND user_id = $2`, redemption.CouponID, redemption.UserID).Scan(&userCount)
+		if err != nil {
+			log.Printf("Error counting redemptions of coupon: %v, error: %v", redemption.CouponID, err)
+			return err
+		}
+		if userCount >= perUserLimit {
+			return conflict("coupon has already been used the maximum number of times by this user")
+		}
+	}
+
+	redemption.CreatedAt = time.Now()
+	query := `INSERT INTO coupon_redemptions (id, coupon_id, user_id, order_id, discount, created_at) VALUES ($1, $2, $3, $4, $5, $6)`
+	if _, err := tx.Exec(query, redemption.ID, redemption.CouponID, redemption.UserID, redemption.OrderID, redemption.Discount, redemption.CreatedAt); err != nil {
+		log.Printf("Error inserting coupon redemption: %v, query: %s", err, query)
+		return storeError(err)
+	}
+
+	if _, err := tx.Exec(`UPDATE coupons SET redemption_count = redemption_count + 1 WHERE id = $1`, redemption.CouponID); err != nil {
+		log.Printf("Error incrementing redemptions of coupon: %v, error: %v", redemption.CouponID, err)
+		return storeError(err)
+	}
+
+	return tx.Commit()
+}
+
+// ReleaseByOrderID implements repository.CouponRepository.
+func (r *couponRepositoryImpl) ReleaseByOrderID(orderID uuid.UUID) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		log.Printf("Error starting release transaction: %v", err)
+		return err
+	}
+	defer tx.Rollback()
+
+	var couponID uuid.UUID
+	err = tx.QueryRow(`DELETE FROM coupon_redemptions WHERE order_id = $1 RETURNING coupon_id`, orderID).Scan(&couponID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil
+		}
+		log.Printf("Error releasing redemption of order: %v, error: %v", orderID, err)
+		return err
+	}
+
+	if _, err := tx.Exec(`UPDATE coupons SET redemption_count = redemption_count - 1 WHERE id = $1 AND redemption_count > 0`, couponID); err != nil {
+		log.Printf("Error decrementing redemptions of coupon: %v, error: %v", couponID, err)
+		return storeError(err)
+	}
+
+	return tx.Commit()
+}
@@ -4,7 +4,6 @@ import (
 	"dalabio/internal/entity"
 	"dalabio/internal/repository"
 	"database/sql"
-	"fmt"
 	"log"
 
 	"github.com/gofrs/uuid"
@@ -15,6 +14,39 @@ type CourseRepositoryImpl struct {
 	db *sql.DB
 }
 
+// courseColumns lists the columns read by scanCourse, in order
+const courseColumns = `id, title, description, duration, version, category, instructor_id, space_id, members_only,
+		       enrolled_count, content_url, outline, status, price, currency, created_at, updated_at, deleted_at`
+
+// scanCourse reads a course selected with courseColumns
+func scanCourse(row interface{ Scan(...interface{}) error }) (*entity.Course, error) {
+	var course entity.Course
+	err := row.Scan(
+		&course.ID,
+		&course.Title,
+		&course.Description,
+		&course.Duration,
+		&course.Version,
+		&course.Category,
+		&course.InstructorID,
+		&course.SpaceID,
+		&course.MembersOnly,
+		&course.EnrolledCount,
+		pq.Array(&course.ContentURL), // Use pq.Array for TEXT[] in PostgreSQL
+		&course.Outline,
+		&course.Status,
+		&course.Price,
+		&course.Currency,
+		&course.CreatedAt,
+		&course.UpdatedAt,
+		&course.DeletedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &course, nil
+}
+
 //  factory function to create an instance of CourseRepository
 
 func NewCourseRepository(db *sql.DB) repository.CourseRepository {
@@ -26,14 +58,14 @@ func (r *CourseRepositoryImpl) Create(course *entity.Course) error {
 	log.Printf("Inserting course: %+v", course)
 
 	// SQL Query to insert the course into the database
-	query := `INSERT INTO courses (id, title, description, duration, version, category, instructor_id, enrolled_count, content_url, outline, status, created_at, updated_at)
-	VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13)`
+	query := `INSERT INTO courses (id, title, description, duration, version, category, instructor_id, space_id, members_only, enrolled_count, content_url, outline, status, price, currency, created_at, updated_at)
+	VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17)`
 
 	// Use pq.Array() to pass the slice of strings as a PostgreSQL array
-	result, err := r.db.Exec(query, course.ID, course.Title, course.Description, course.Duration, course.Version, course.Category, course.InstructorID, course.EnrolledCount, pq.Array(course.ContentURL), course.Outline, course.Status, course.CreatedAt, course.UpdatedAt)
+	result, err := r.db.Exec(query, course.ID, course.Title, course.Description, course.Duration, course.Version, course.Category, course.InstructorID, course.SpaceID, course.MembersOnly, course.EnrolledCount, pq.Array(course.ContentURL), course.Outline, course.Status, course.Price, course.Currency, course.CreatedAt, course.UpdatedAt)
 	if err != nil {
 		log.Printf("Error inserting course: %v, query: %s", err, query)
-		return err
+		return storeError(err)
 	}
 
 	// Check rows affected
@@ -52,14 +84,14 @@ func (r *CourseRepositoryImpl) Update(course *entity.Course) error {
 	// Define the SQL update query
 	result, err := r.db.Exec(`
     UPDATE courses 
-    SET title = $2, description = $3, duration = $4, version = $5, category = $6, enrolled_count = $7, content_url = $8, status = $9, updated_at = CURRENT_TIMESTAMP 
+    SET title = $2, description = $3, duration = $4, version = $5, category = $6, enrolled_count = $7, content_url = $8, status = $9, price = $10, currency = $11, space_id = $12, members_only = $13, updated_at = CURRENT_TIMESTAMP 
     WHERE id = $1`,
-		course.ID, course.Title, course.Description, course.Duration, course.Version, course.Category, course.EnrolledCount, pq.Array(course.ContentURL), course.Status)
+		course.ID, course.Title, course.Description, course.Duration, course.Version, course.Category, course.EnrolledCount, pq.Array(course.ContentURL), course.Status, course.Price, course.Currency, course.SpaceID, course.MembersOnly)
 	log.Printf("ContentURL: %+v", course.ContentURL)
 
 	if err != nil {
 		log.Printf("Error updating course with ID: %v, error: %v", course.ID, err)
-		return err
+		return storeError(err)
 	}
 
 	// If no rows were affected, it means the course was not found
@@ -83,48 +115,37 @@ func (r *CourseRepositoryImpl) Update(course *entity.Course) error {
 func (r *CourseRepositoryImpl) GetdByID(courseID uuid.UUID) (*entity.Course, error) {
 	// Define the Course entity to store the result
 	//  var course = entity.Course{}
-	var course entity.Course
-	query := "SELECT id, title, description, duration, version, category, instructor_id, enrolled_count, content_url, outline, status, created_at, updated_at, deleted_at FROM courses WHERE id = $1"
-	err := r.db.QueryRow(query, courseID).Scan(
-		&course.ID,
-		&course.Title,
-		&course.Description,
-		&course.Duration,
-		&course.Version,
-		&course.Category,
-		&course.InstructorID,
-		&course.EnrolledCount,
-		pq.Array(&course.ContentURL), // Use pq.Array for TEXT[] in PostgreSQL
-		&course.Outline,
-		&course.Status,
-		&course.CreatedAt,
-		&course.UpdatedAt,
-		&course.DeletedAt,
-	)
+	course, err := scanCourse(r.db.QueryRow("SELECT "+courseColumns+" FROM courses WHERE id = $1", courseID))
 
 	//Check for errors in retrieving the course
 	if err != nil {
 		if err == sql.ErrNoRows {
 			log.Printf("No course found with ID: %v", courseID)
-			return nil, fmt.Errorf("course Not Found")
+			return nil, notFound("course not found")
 		}
 		log.Printf("Error retrieving course by ID: %v", err)
+		return nil, err
 	}
 
 	// Return the Course if found
-	return &course, nil
+	return course, nil
 
 }
 
 func (r *CourseRepositoryImpl) GetAll() ([]*entity.Course, error) {
+	return r.query(`SELECT ` + courseColumns + ` FROM courses`)
+}
+
+// GetBySpaceID implements repository.CourseRepository.
+func (r *CourseRepositoryImpl) GetBySpaceID(spaceID uuid.UUID) ([]*entity.Course, error) {
+	return r.query(`SELECT `+courseColumns+` FROM courses WHERE space_id = $1 AND deleted_at IS NULL ORDER BY created_at`, spaceID)
+}
+
+// query runs a select of courseColumns and scans every row
+func (r *CourseRepositoryImpl) query(query string, args ...interface{}) ([]*entity.Course, error) {
 	// Define the Course slice to store the results
 	var courses []*entity.Course
-	query := `
-		SELECT id, title, description, duration, version, category, instructor_id, 
-		       enrolled_count, content_url, outline, status, created_at, updated_at, deleted_at 
-		FROM courses
-	`
-	rows, err := r.db.Query(query)
+	rows, err := r.db.Query(query, args...)
 	if err != nil {
 		log.Printf("Error retrieving courses: %v", err)
 		return nil, err
@@ -132,30 +153,14 @@ func (r *CourseRepositoryImpl) GetAll() ([]*entity.Course, error) {
 	defer rows.Close()
 
 	for rows.Next() {
-		var course entity.Course
-		err := rows.Scan(
-			&course.ID,
-			&course.Title,
-			&course.Description,
-			&course.Duration,
-			&course.Version,
-			&course.Category,
-			&course.InstructorID,
-			&course.EnrolledCount,
-			pq.Array(&course.ContentURL), // Use pq.Array for TEXT[] in PostgreSQL
-			&course.Outline,
-			&course.Status,
-			&course.CreatedAt,
-			&course.UpdatedAt,
-			&course.DeletedAt,
-		)
+		course, err := scanCourse(rows)
 		if err != nil {
 			log.Printf("Error scanning course: %v", err)
 			return nil, err
 		}
 
 		// Append a pointer to the course to the courses slice
-		courses = append(courses, &course)
+		courses = append(courses, course)
 	}
 
 	// Check for errors encountered during iteration
@@ -173,7 +178,7 @@ func (r *CourseRepositoryImpl) Delete(courseID uuid.UUID) error {
 	result, err := r.db.Exec(query, courseID)
 	if err != nil {
 		log.Printf("Error deleting course with ID: %v, error: %v", courseID, err)
-		return err
+		return storeError(err)
 	}
 	// Check how many rows were affected by the delete
 	rowsAffected, err := result.RowsAffected()
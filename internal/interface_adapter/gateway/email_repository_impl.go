@@ -0,0 +1,146 @@
+package gateway
+
+import (
+	"dalabio/internal/entity"
+	"dalabio/internal/repository"
+	"database/sql"
+	"log"
+	"time"
+)
+
+type emailRepositoryImpl struct {
+	db *sql.DB
+}
+
+// NewEmailRepository creates a new instance of EmailRepository.
+func NewEmailRepository(db *sql.DB) repository.EmailRepository {
+	return &emailRepositoryImpl{db: db}
+}
+
+const outboxEmailColumns = `id, recipient, subject, html_body, text_body, template, status, attempts, last_error, next_attempt_at, sent_at, created_at, updated_at`
+
+// scanOutboxEmail scans a row selected with outboxEmailColumns
+func scanOutboxEmail(row interface{ Scan(...interface{}) error }) (*entity.OutboxEmail, error) {
+	var email entity.OutboxEmail
+	if err := row.Scan(&email.ID, &email.Recipient, &email.Subject, &email.HTMLBody, &email.TextBody, &email.Template, &email.Status,
+		&email.Attempts, &email.LastError, &email.NextAttemptAt, &email.SentAt, &email.CreatedAt, &email.UpdatedAt); err != nil {
+		return nil, err
+	}
+	return &email, nil
+}
+
+// Enqueue implements repository.EmailRepository.
+func (r *emailRepositoryImpl) Enqueue(email *entity.OutboxEmail) error {
+	now := time.Now()
+	email.CreatedAt = now
+	email.UpdatedAt = now
+	if email.NextAttemptAt.IsZero() {
+		email.NextAttemptAt = now
+	}
+
+	query := `INSERT INTO email_outbox (` + outboxEmailColumns + `) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13)`
+	if _, err := r.db.Exec(query, email.ID, email.Recipient, email.Subject, email.HTMLBody, email.TextBody, email.Template, email.Status,
+		email.Attempts, email.LastError, email.NextAttemptAt, email.SentAt, email.CreatedAt, email.UpdatedAt); err != nil {
+		log.Printf("Error inserting email: %v, query: %s", err, query)
+		return storeError(err)
+	}
+
+	return nil
+}
+
+// ClaimDue implements repository.EmailRepository.
+func (r *emailRepositoryImpl) ClaimDue(now, leaseUntil time.Time, limit int) ([]*entity.OutboxEmail, error) {
+	query := `UPDATE email_outbox SET next_attempt_at = $2
+	WHERE id IN (
+		SELECT id FROM email_outbox WHERE status = 'pending' AND next_attempt_at <= $1
+		ORDER BY next_attempt_at LIMIT $3 FOR UPDATE SKIP LOCKED
+	)
+	RETURNING ` + outboxEmailColumns
+	rows, err := r.db.Query(query, now, leaseUntil, limit)
+	if err != nil {
+		log.Printf("Error claiming due emails: %v", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	emails := []*entity.OutboxEmail{}
+	for rows.Next() {
+		email, err := scanOutboxEmail(rows)
+		if err != nil {
+			log.Printf("Error scanning email: %v", err)
+			return nil, err
+		}
+		emails = append(emails, email)
+	}
+
+	return emails, rows.Err()
+}
+
+// UpdateDelivery implements repository.EmailRepository.
+func (r *emailRepositoryImpl) UpdateDelivery(email *entity.OutboxEmail) error {
+	email.UpdatedAt = time.Now()
+
+	query := `UPDATE email_outbox SET status = $2, attempts = $3, last_error = $4, next_attempt_at = $5, sent_at = $6, updated_at = $7 WHERE id = $1`
+	result, err := r.db.Exec(query, email.ID, email.Status, email.Attempts, email.LastError, email.NextAttemptAt, email.SentAt, email.UpdatedAt)
+	if err != nil {
+		log.Printf("Error updating email: %v, query: %s", err, query)
+		return storeError(err)
+	}
+	return expectRow(result, "email not found")
+}
+
+// IsSuppressed implements repository.EmailRepository.
+func (r *emailRepositoryImpl) IsSuppressed(email string) (bool, error) {
+	var suppressed bool
+	if err := r.db.QueryRow(`SELECT EXISTS (SELECT 1 FROM email_suppressions WHERE email = LOWER($1))`, email).Scan(&suppressed); err != nil {
+		log.Printf("Error checking suppression of %s: %v", email, err)
+		return false, err
+	}
+	return suppressed, nil
+}
+
+// Suppress implements repository.EmailRepository.
+func (r *emailRepositoryImpl) Suppress(suppression *entity.EmailSuppression) error {
+	suppression.CreatedAt = time.Now()
+
+	query := `INSERT INTO email_suppressions (email, reason, detail, created_at) VALUES (LOWER($1), $2, $3, $4)
+	ON CONFLICT (email) DO NOTHING`
+	if _, err := r.db.Exec(query, suppression.Email, suppression.Reason, suppression.Detail, suppression.CreatedAt); err != nil {
+		log.Printf("Error suppressing email: %v, query: %s", err, query)
+		return storeError(err)
+	}
+
+	return nil
+}
+
+// Unsuppress implements repository.EmailRepository.
+func (r *emailRepositoryImpl) Unsuppress(email string) error {
+	result, err := r.db.Exec(`DELETE FROM email_suppressions WHERE email = LOWER($1)`, email)
+	if err != nil {
+		log.Printf("Error removing suppression of %s: %v", email, err)
+		return storeError(err)
+	}
+	return expectRow(result, "suppression not found")
+}
+
+// GetSuppressions implements repository.EmailRepository.
+func (r *emailRepositoryImpl) GetSuppressions() ([]*entity.EmailSuppression, error) {
+	rows, err := r.db.Query(`SELECT email, reason, detail, created_at FROM email_suppressions ORDER BY created_at DESC`)
+	if err != nil {
+		log.Printf("Error retrieving suppressions: %v", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	suppressions := []*entity.EmailSuppression{}
+	for rows.Next() {
+		var suppression entity.EmailSuppression
+		if err := rows.Scan(&suppression.Email, &suppression.Reason, &suppression.Detail, &suppression.CreatedAt); err != nil {
+			log.Printf("Error scanning suppression: %v", err)
+			return nil, err
+		}
+		suppressions = append(suppressions, &suppression)
+	}
+
+	return suppressions, rows.Err()
+}
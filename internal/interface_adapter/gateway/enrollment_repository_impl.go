@@ -0,0 +1,85 @@
+package gateway
+
+import (
+	"dalabio/internal/entity"
+	"dalabio/internal/repository"
+	"database/sql"
+	"log"
+	"time"
+
+	"github.com/gofrs/uuid"
+)
+
+type enrollmentRepositoryImpl struct {
+	db *sql.DB
+}
+
+// NewEnrollmentRepository creates a new instance of EnrollmentRepository.
+func NewEnrollmentRepository(db *sql.DB) repository.EnrollmentRepository {
+	return &enrollmentRepositoryImpl{db: db}
+}
+
+// Create implements repository.EnrollmentRepository.
+// A new course enrollment also increments the enrolled_count of the course.
+func (r *enrollmentRepositoryImpl) Create(enrollment *entity.Enrollment) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		log.Printf("Error starting enrollment transaction: %v", err)
+		return err
+	}
+	defer tx.Rollback()
+
+	enrollment.CreatedAt = time.Now()
+
+	query := `INSERT INTO enrollments (id, user_id, item_type, item_id, order_id, created_at)
+	VALUES ($1, $2, $3, $4, $5, $6)
+	ON CONFLICT (user_id, item_type, item_id) DO NOTHING`
+	result, err := tx.Exec(query, enrollment.ID, enrollment.UserID, enrollment.ItemType, enrollment.ItemID, enrollment.OrderID, enrollment.CreatedAt)
+	if err != nil {
+		log.Printf("Error inserting enrollment: %v, query: %s", err, query)
+		return storeError(err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		log.Printf("Error fetching rows affected: %v", err)
+		return err
+	}
+
+	if rowsAffected > 0 && enrollment.ItemType == "course" {
+		if _, err := tx.Exec(`UPDATE courses SET enrolled_count = enrolled_count + 1 WHERE id = $1`, enrollment.ItemID); err != nil {
+			log.Printf("Error incrementing enrolled count of course: %v, error: %v", enrollment.ItemID, err)
+			return storeError(err)
+		}
+	}
+
+	return tx.Commit()
+}
+
+// GetByUserID implements repository.EnrollmentRepository.
+func (r *enrollmentRepositoryImpl) GetByUserID(userID uuid.UUID) ([]*entity.Enrollment, error) {
+	query := `SELECT id, user_id, item_type, item_id, order_id, created_at FROM enrollments WHERE user_id = $1 ORDER BY created_at`
+	rows, err := r.db.Query(query, userID)
+	if err != nil {
+		log.Printf("Error fetching enrollments of user: %v, error: %v", userID, err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	var enrollments []*entity.Enrollment
+	for rows.Next() {
+		var enrollment entity.Enrollment
+		if err := rows.Scan(&enrollment.ID, &enrollment.UserID, &enrollment.ItemType, &enrollment.ItemID, &enrollment.OrderID, &enrollment.CreatedAt); err != nil {
+			log.Printf("Error scanning enrollment: %v", err)
+			return nil, err
+		}
+		enrollments = append(enrollments, &enrollment)
+	}
+
+	if err = rows.Err(); err != nil {
+		log.Printf("Error iterating over enrollments: %v", err)
+		return nil, err
+	}
+
+	return enrollments, nil
+}
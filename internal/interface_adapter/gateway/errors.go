@@ -0,0 +1,58 @@
+package gateway
+
+import (
+	"dalabio/internal/repository"
+	"errors"
+	"log"
+
+	"github.com/lib/pq"
+)
+
+// PostgreSQL codes of the constraint violations reported as conflicts
+const (
+	pqForeignKeyViolation = "23503"
+	pqUniqueViolation     = "23505"
+)
+
+// repositoryError is an error of a repository kind with a message of its own
+type repositoryError struct {
+	kind    error
+	message string
+}
+
+func (e *repositoryError) Error() string {
+	return e.message
+}
+
+func (e *repositoryError) Unwrap() error {
+	return e.kind
+}
+
+// notFound returns an error of kind repository.ErrNotFound
+func notFound(message string) error {
+	return &repositoryError{kind: repository.ErrNotFound, message: message}
+}
+
+// conflict returns an error of kind repository.ErrConflict, for a change the current state of the store refuses
+func conflict(message string) error {
+	return &repositoryError{kind: repository.ErrConflict, message: message}
+}
+
+// storeError maps the constraint violations of PostgreSQL to repository.ErrConflict and returns other errors as they are
+func storeError(err error) error {
+	var pqErr *pq.Error
+	if !errors.As(err, &pqErr) {
+		return err
+	}
+
+	// The detail names the key and its value, e.g. "Key (email)=(ada@example.com) already exists.", which clients must not see
+	switch pqErr.Code {
+	case pqUniqueViolation:
+		log.Printf("Unique violation on %s: %s", pqErr.Constraint, pqErr.Detail)
+		return &repositoryError{kind: repository.ErrConflict, message: "the resource already exists"}
+	case pqForeignKeyViolation:
+		log.Printf("Foreign key violation on %s: %s", pqErr.Constraint, pqErr.Detail)
+		return &repositoryError{kind: repository.ErrConflict, message: "the resource refers to or is referred to by another resource"}
+	}
+	return err
+}
@@ -0,0 +1,117 @@
+package gateway
+
+import (
+	"dalabio/internal/entity"
+	"dalabio/internal/repository"
+	"database/sql"
+	"fmt"
+	"log"
+
+	"github.com/gofrs/uuid"
+)
+
+type invoiceRepositoryImpl struct {
+	db *sql.DB
+}
+
+// NewInvoiceRepository creates a new instance of InvoiceRepository.
+func NewInvoiceRepository(db *sql.DB) repository.InvoiceRepository {
+	return &invoiceRepositoryImpl{db: db}
+}
+
+// Create implements repository.InvoiceRepository.
+// The yearly counter row stays locked until the invoice is committed, so concurrent
+// payments are numbered one after the other and a failed insert rolls the counter
+// back instead of leaving a gap.
+func (r *invoiceRepositoryImpl) Create(invoice *entity.Invoice, prefix string) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		log.Printf("Error starting invoice transaction: %v", err)
+		return err
+	}
+	defer tx.Rollback()
+
+	year := invoice.IssuedAt.Year()
+
+	var sequence int64
+	err = tx.QueryRow(`INSERT INTO invoice_sequences (year, last_number) VALUES ($1, 1)
+	ON CONFLICT (year) DO UPDATE SET last_number = invoice_sequences.last_number + 1
+	RETURNING last_number`, year).Scan(&sequence)
+	if err != nil {
+		log.Printf("Error allocating invoice number: %v", err)
+		return err
+	}
+	invoice.Number = fmt.Sprintf("%s-%d-%06d", prefix, year, sequence)
+
+	query := `INSERT INTO invoices (id, number, payment_id, order_id, user_id, billing_name, billing_address, currency, subtotal, discount, tax_rate, tax, total, issued_at)
+	VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14)`
+	_, err = tx.Exec(query, invoice.ID, invoice.Number, invoice.PaymentID, invoice.OrderID, invoice.UserID, invoice.BillingName, invoice.BillingAddress,
+		invoice.Currency, invoice.Subtotal, invoice.Discount, invoice.TaxRate, invoice.Tax, invoice.Total, invoice.IssuedAt)
+	if err != nil {
+		log.Printf("Error inserting invoice: %v, query: %s", err, query)
+		return storeError(err)
+	}
+
+	lineQuery := `INSERT INTO invoice_lines (invoice_id, position, description, quantity, unit_price, amount) VALUES ($1, $2, $3, $4, $5, $6)`
+	for i, line := range invoice.Lines {
+		if _, err := tx.Exec(lineQuery, invoice.ID, i, line.Description, line.Quantity, line.UnitPrice, line.Amount); err != nil {
+			log.Printf("Error inserting invoice line: %v, query: %s", err, lineQuery)
+			return storeError(err)
+		}
+	}
+
+	return tx.Commit()
+}
+
+// GetByPaymentID implements repository.InvoiceRepository.
+func (r *invoiceRepositoryImpl) GetByPaymentID(paymentID uuid.UUID) (*entity.Invoice, error) {
+	var invoice entity.Invoice
+	query := `SELECT id, number, payment_id, order_id, user_id, billing_name, billing_address, currency, subtotal, discount, tax_rate, tax, total, issued_at
+	FROM invoices WHERE payment_id = $1`
+	err := r.db.QueryRow(query, paymentID).Scan(
+		&invoice.ID,
+		&invoice.Number,
+		&invoice.PaymentID,
+		&invoice.OrderID,
+		&invoice.UserID,
+		&invoice.BillingName,
+		&invoice.BillingAddress,
+		&invoice.Currency,
+		&invoice.Subtotal,
+		&invoice.Discount,
+		&invoice.TaxRate,
+		&invoice.Tax,
+		&invoice.Total,
+		&invoice.IssuedAt,
+	)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, notFound("invoice not found")
+		}
+		log.Printf("Error fetching invoice of payment: %v, error: %v", paymentID, err)
+		return nil, err
+	}
+
+	rows, err := r.db.Query(`SELECT description, quantity, unit_price, amount FROM invoice_lines WHERE invoice_id = $1 ORDER BY position`, invoice.ID)
+	if err != nil {
+		log.Printf("Error fetching lines of invoice: %v, error: %v", invoice.ID, err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var line entity.InvoiceLine
+		if err := rows.Scan(&line.Description, &line.Quantity, &line.UnitPrice, &line.Amount); err != nil {
+			log.Printf("Error scanning invoice line: %v", err)
+			return nil, err
+		}
+		invoice.Lines = append(invoice.Lines, &line)
+	}
+
+	if err = rows.Err(); err != nil {
+		log.Printf("Error iterating over invoice lines: %v", err)
+		return nil, err
+	}
+
+	return &invoice, nil
+}
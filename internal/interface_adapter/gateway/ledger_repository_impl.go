@@ -0,0 +1,197 @@
+package gateway
+
+import (
+	"dalabio/internal/entity"
+	"dalabio/internal/repository"
+	"database/sql"
+	"log"
+	"time"
+
+	"github.com/gofrs/uuid"
+)
+
+type ledgerRepositoryImpl struct {
+	db *sql.DB
+}
+
+// NewLedgerRepository creates a new instance of LedgerRepository.
+func NewLedgerRepository(db *sql.DB) repository.LedgerRepository {
+	return &ledgerRepositoryImpl{db: db}
+}
+
+// insertLedgerTransaction stores a transaction and its entries within tx.
+// The reference is unique, so posting the same payment, refund or payout twice is a no-op.
+func insertLedgerTransaction(tx *sql.Tx, transaction *entity.LedgerTransaction) error {
+	transaction.CreatedAt = time.Now()
+
+	query := `INSERT INTO ledger_transactions (id, reference_type, reference_id, description, created_at)
+	VALUES ($1, $2, $3, $4, $5) ON CONFLICT (reference_type, reference_id) DO NOTHING`
+	result, err := tx.Exec(query, transaction.ID, transaction.ReferenceType, transaction.ReferenceID, transaction.Description, transaction.CreatedAt)
+	if err != nil {
+		log.Printf("Error inserting ledger transaction: %v, query: %s", err, query)
+		return storeError(err)
+	}
+
+	if rowsAffected, err := result.RowsAffected(); err != nil {
+		return err
+	} else if rowsAffected == 0 {
+		log.Printf("Ledger transaction for %s %s already posted", transaction.ReferenceType, transaction.ReferenceID)
+		return nil
+	}
+
+	entryQuery := `INSERT INTO ledger_entries (id, transaction_id, account, owner_id, currency, debit, credit) VALUES ($1, $2, $3, $4, $5, $6, $7)`
+	for _, entry := range transaction.Entries {
+		entry.TransactionID = transaction.ID
+		if _, err := tx.Exec(entryQuery, entry.ID, entry.TransactionID, entry.Account, entry.OwnerID, entry.Currency, entry.Debit, entry.Credit); err != nil {
+			log.Printf("Error inserting ledger entry: %v, query: %s", err, entryQuery)
+			return storeError(err)
+		}
+	}
+
+	return nil
+}
+
+// Post implements repository.LedgerRepository.
+func (r *ledgerRepositoryImpl) Post(transaction *entity.LedgerTransaction) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		log.Printf("Error starting ledger transaction: %v", err)
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := insertLedgerTransaction(tx, transaction); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// GetByReference implements repository.LedgerRepository.
+func (r *ledgerRepositoryImpl) GetByReference(referenceType string, referenceID uuid.UUID) (*entity.LedgerTransaction, error) {
+	var transaction entity.LedgerTransaction
+	query := `SELECT id, reference_type, reference_id, description, created_at FROM ledger_transactions WHERE reference_type = $1 AND reference_id = $2`
+	err := r.db.QueryRow(query, referenceType, referenceID).Scan(
+		&transaction.ID,
+		&transaction.ReferenceType,
+		&transaction.ReferenceID,
+		&transaction.Description,
+		&transaction.CreatedAt,
+	)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, notFound("ledger transaction not found")
+		}
+		log.Printf("Error fetching ledger transaction of %s: %v, error: %v", referenceType, referenceID, err)
+		return nil, err
+	}
+
+	rows, err := r.db.Query(`SELECT id, transaction_id, account, owner_id, currency, debit, credit FROM ledger_entries WHERE transaction_id = $1`, transaction.ID)
+	if err != nil {
+		log.Printf("Error fetching entries of ledger transaction: %v, error: %v", transaction.ID, err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var entry entity.LedgerEntry
+		if err := rows.Scan(&entry.ID, &entry.TransactionID, &entry.Account, &entry.OwnerID, &entry.Currency, &entry.Debit, &entry.Credit); err != nil {
+			log.Printf("Error scanning ledger entry: %v", err)
+			return nil, err
+		}
+		transaction.Entries = append(transaction.Entries, &entry)
+	}
+
+	if err = rows.Err(); err != nil {
+		log.Printf("Error iterating over ledger entries: %v", err)
+		return nil, err
+	}
+
+	return &transaction, nil
+}
+
+// balanceQuery sums the creator_payable account per owner and currency; $1 is the start of the hold period
+const balanceQuery = `SELECT e.owner_id, e.currency,
+	COALESCE(SUM(e.credit - e.debit), 0),
+	COALESCE(SUM(CASE WHEN t.created_at > $1 THEN e.credit ELSE 0 END), 0)
+	FROM ledger_entries e JOIN ledger_transactions t ON t.id = e.transaction_id
+	WHERE e.account = 'creator_payable'`
+
+func (r *ledgerRepositoryImpl) queryBalances(query string, args ...interface{}) ([]*entity.EarningsBalance, error) {
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		log.Printf("Error fetching balances: %v", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	var balances []*entity.EarningsBalance
+	for rows.Next() {
+		var balance entity.EarningsBalance
+		if err := rows.Scan(&balance.UserID, &balance.Currency, &balance.Balance, &balance.OnHold); err != nil {
+			log.Printf("Error scanning balance: %v", err)
+			return nil, err
+		}
+		// Refunds of held sales are debited right away, so the hold can exceed what is left
+		if balance.OnHold > balance.Balance {
+			balance.OnHold = balance.Balance
+		}
+		balance.Available = balance.Balance - balance.OnHold
+		balances = append(balances, &balance)
+	}
+
+	if err = rows.Err(); err != nil {
+		log.Printf("Error iterating over balances: %v", err)
+		return nil, err
+	}
+
+	return balances, nil
+}
+
+// GetBalances implements repository.LedgerRepository.
+func (r *ledgerRepositoryImpl) GetBalances(userID uuid.UUID, holdSince time.Time) ([]*entity.EarningsBalance, error) {
+	return r.queryBalances(balanceQuery+` AND e.owner_id = $2 GROUP BY e.owner_id, e.currency ORDER BY e.currency`, holdSince, userID)
+}
+
+// GetPayableBalances implements repository.LedgerRepository.
+func (r *ledgerRepositoryImpl) GetPayableBalances(holdSince time.Time) ([]*entity.EarningsBalance, error) {
+	return r.queryBalances(balanceQuery+` GROUP BY e.owner_id, e.currency ORDER BY e.owner_id, e.currency`, holdSince)
+}
+
+// GetEarnings implements repository.LedgerRepository.
+func (r *ledgerRepositoryImpl) GetEarnings(userID uuid.UUID, period string, from, to time.Time) ([]*entity.EarningsPeriod, error) {
+	query := `SELECT date_trunc($2, t.created_at) AS period, e.currency,
+	COALESCE(SUM(CASE WHEN t.reference_type = 'payment' THEN e.credit ELSE 0 END), 0),
+	COALESCE(SUM(CASE WHEN e.account = 'platform_fees' THEN e.credit - e.debit ELSE 0 END), 0),
+	COALESCE(SUM(CASE WHEN t.reference_type = 'refund' THEN e.debit ELSE 0 END), 0),
+	COALESCE(SUM(CASE WHEN e.account = 'creator_payable' AND t.reference_type IN ('payment', 'refund') THEN e.credit - e.debit ELSE 0 END), 0),
+	COALESCE(SUM(CASE WHEN t.reference_type IN ('payout', 'payout_reversal') THEN e.debit - e.credit ELSE 0 END), 0)
+	FROM ledger_entries e JOIN ledger_transactions t ON t.id = e.transaction_id
+	WHERE e.owner_id = $1 AND e.account IN ('platform_fees', 'creator_payable') AND t.created_at >= $3 AND t.created_at < $4
+	GROUP BY period, e.currency
+	ORDER BY period, e.currency`
+
+	rows, err := r.db.Query(query, userID, period, from, to)
+	if err != nil {
+		log.Printf("Error fetching earnings of user: %v, error: %v", userID, err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	var periods []*entity.EarningsPeriod
+	for rows.Next() {
+		var earnings entity.EarningsPeriod
+		if err := rows.Scan(&earnings.Period, &earnings.Currency, &earnings.Gross, &earnings.Fees, &earnings.Refunds, &earnings.Net, &earnings.PaidOut); err != nil {
+			log.Printf("Error scanning earnings: %v", err)
+			return nil, err
+		}
+		periods = append(periods, &earnings)
+	}
+
+	if err = rows.Err(); err != nil {
+		log.Printf("Error iterating over earnings: %v", err)
+		return nil, err
+	}
+
+	return periods, nil
+}
@@ -0,0 +1,78 @@
+package gateway
+
+import (
+	"dalabio/internal/entity"
+	"dalabio/internal/repository"
+	"database/sql"
+	"log"
+	"time"
+)
+
+type loginThrottleRepositoryImpl struct {
+	db *sql.DB
+}
+
+// NewLoginThrottleRepository creates a new instance of LoginThrottleRepository.
+func NewLoginThrottleRepository(db *sql.DB) repository.LoginThrottleRepository {
+	return &loginThrottleRepositoryImpl{db: db}
+}
+
+const loginThrottleColumns = `scope, subject, failures, last_failed_at, locked_until`
+
+func scanLoginThrottle(row interface{ Scan(...interface{}) error }) (*entity.LoginThrottle, error) {
+	var throttle entity.LoginThrottle
+	if err := row.Scan(&throttle.Scope, &throttle.Subject, &throttle.Failures, &throttle.LastFailedAt, &throttle.LockedUntil); err != nil {
+		return nil, err
+	}
+	return &throttle, nil
+}
+
+// Get implements repository.LoginThrottleRepository.
+func (r *loginThrottleRepositoryImpl) Get(scope, subject string) (*entity.LoginThrottle, error) {
+	throttle, err := scanLoginThrottle(r.db.QueryRow(`SELECT `+loginThrottleColumns+` FROM login_throttles WHERE scope = $1 AND subject = $2`, scope, subject))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, notFound("login throttle not found")
+		}
+		log.Printf("Error fetching login throttle of %s %s: %v", scope, subject, err)
+		return nil, err
+	}
+	return throttle, nil
+}
+
+// RecordFailure implements repository.LoginThrottleRepository.
+func (r *loginThrottleRepositoryImpl) RecordFailure(scope, subject string, at, resetBefore time.Time) (*entity.LoginThrottle, error) {
+	// A single statement so that concurrent failures are all counted
+	query := `INSERT INTO login_throttles (scope, subject, failures, last_failed_at) VALUES ($1, $2, 1, $3)
+	ON CONFLICT (scope, subject) DO UPDATE SET
+		failures = CASE WHEN login_throttles.last_failed_at < $4 THEN 1 ELSE login_throttles.failures + 1 END,
+		locked_until = CASE WHEN login_throttles.last_failed_at < $4 THEN NULL ELSE login_throttles.locked_until END,
+		last_failed_at = $3
+	RETURNING ` + loginThrottleColumns
+
+	throttle, err := scanLoginThrottle(r.db.QueryRow(query, scope, subject, at, resetBefore))
+	if err != nil {
+		log.Printf("Error recording failed sign-in of %s %s: %v", scope, subject, err)
+		return nil, storeError(err)
+	}
+	return throttle, nil
+}
+
+// Lock implements repository.LoginThrottleRepository.
+func (r *loginThrottleRepositoryImpl) Lock(scope, subject string, until time.Time) error {
+	result, err := r.db.Exec(`UPDATE login_throttles SET locked_until = $3 WHERE scope = $1 AND subject = $2`, scope, subject, until)
+	if err != nil {
+		log.Printf("Error locking %s %s: %v", scope, subject, err)
+		return storeError(err)
+	}
+	return expectRow(result, "login throttle not found")
+}
+
+// Reset implements repository.LoginThrottleRepository.
+func (r *loginThrottleRepositoryImpl) Reset(scope, subject string) error {
+	if _, err := r.db.Exec(`DELETE FROM login_throttles WHERE scope = $1 AND subject = $2`, scope, subject); err != nil {
+		log.Printf("Error resetting login throttle of %s %s: %v", scope, subject, err)
+		return storeError(err)
+	}
+	return nil
+}
@@ -4,7 +4,6 @@ import (
 	"dalabio/internal/entity"
 	"dalabio/internal/repository"
 	"database/sql"
-	"fmt"
 	"log"
 	"strings"
 
@@ -16,16 +15,49 @@ type MeetingRepositoryImpl struct {
 	db *sql.DB
 }
 
+// meetingColumns lists the columns read by scanMeeting, in order
+const meetingColumns = `id, title, description, duration, start_time, end_time, location, attendee_ids, attendee_names, attendee_emails, attendee_status, meeting_type, status, join_url, maximum_capacity, space_id, members_only, created_at, updated_at`
+
+// scanMeeting reads a meeting selected with meetingColumns
+func scanMeeting(row interface{ Scan(...interface{}) error }) (*entity.Meeting, error) {
+	var meeting entity.Meeting
+	err := row.Scan(
+		&meeting.ID,
+		&meeting.Title,
+		&meeting.Description,
+		&meeting.Duration,
+		&meeting.StartTime,
+		&meeting.EndTime,
+		&meeting.Location,
+		pq.Array(&meeting.AttendeeIDs),
+		pq.Array(&meeting.AttendeeNames),
+		pq.Array(&meeting.AttendeeEmails),
+		pq.Array(&meeting.AttendeeStatus),
+		&meeting.MeetingType,
+		&meeting.Status,
+		pq.Array(&meeting.JoinURL),
+		&meeting.MaximumCapacity,
+		&meeting.SpaceID,
+		&meeting.MembersOnly,
+		&meeting.CreatedAt,
+		&meeting.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &meeting, nil
+}
+
 // Create implements repository.MeetingRepository.
 func (r *MeetingRepositoryImpl) Create(meeting *entity.Meeting) error {
-	query := ` INSERT INTO meetings (id, title, description, duration, start_time, end_time, location,  attendee_ids, attendee_names, attendee_emails, attendee_status, meeting_type, status, join_url, maximum_capacity, created_at, updated_at)
-VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17)
+	query := ` INSERT INTO meetings (id, title, description, duration, start_time, end_time, location,  attendee_ids, attendee_names, attendee_emails, attendee_status, meeting_type, status, join_url, maximum_capacity, space_id, members_only, created_at, updated_at)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18, $19)
 	`
-	result, err := r.db.Exec(query, meeting.ID, meeting.Title, meeting.Description, meeting.Duration, meeting.StartTime, meeting.EndTime, meeting.Location, pq.Array(meeting.AttendeeIDs), pq.Array(meeting.AttendeeNames), pq.Array(meeting.AttendeeEmails), pq.Array(meeting.AttendeeStatus), meeting.MeetingType, meeting.Status, pq.Array(meeting.JoinURL), meeting.MaximumCapacity, meeting.CreatedAt, meeting.UpdatedAt)
+	result, err := r.db.Exec(query, meeting.ID, meeting.Title, meeting.Description, meeting.Duration, meeting.StartTime, meeting.EndTime, meeting.Location, pq.Array(meeting.AttendeeIDs), pq.Array(meeting.AttendeeNames), pq.Array(meeting.AttendeeEmails), pq.Array(meeting.AttendeeStatus), meeting.MeetingType, meeting.Status, pq.Array(meeting.JoinURL), meeting.MaximumCapacity, meeting.SpaceID, meeting.MembersOnly, meeting.CreatedAt, meeting.UpdatedAt)
 
 	if err != nil {
 		log.Printf("Error inserting course: %v, query: %s", err, query)
-		return err
+		return storeError(err)
 	}
 
 	rowsaffected, err := result.RowsAffected()
@@ -60,6 +92,8 @@ func (r *MeetingRepositoryImpl) Update(meeting *entity.Meeting) error {
         status = $13, 
         join_url = $14, 
         maximum_capacity = $15, 
+        space_id = $16, 
+        members_only = $17, 
         updated_at = CURRENT_TIMESTAMP
     WHERE id = $1;`
 
@@ -83,6 +117,8 @@ func (r *MeetingRepositoryImpl) Update(meeting *entity.Meeting) error {
 		meeting.Status,                   // $13
 		joinURL,                          // $14
 		meeting.MaximumCapacity,          // $15
+		meeting.SpaceID,                  // $16
+		meeting.MembersOnly,              // $17
 	)
 
 	if err != nil {
@@ -110,49 +146,36 @@ func (r *MeetingRepositoryImpl) Update(meeting *entity.Meeting) error {
 // Get implements repository.MeetingRepository.
 func (r *MeetingRepositoryImpl) GetdByID(meetingID uuid.UUID) (*entity.Meeting, error) {
 
-	var meeting entity.Meeting
-	// SQL Query to insert the course into the database
-	query := `SELECT id, title, description, duration, start_time, end_time, location,  attendee_ids, attendee_names, attendee_emails, attendee_status, meeting_type, status, join_url, maximum_capacity, created_at, updated_at  FROM meetings WHERE id = $1`
-
-	err := r.db.QueryRow(query, meetingID).Scan(
-		&meeting.ID,
-		&meeting.Title,
-		&meeting.Description,
-		&meeting.Duration,
-		&meeting.StartTime,
-		&meeting.EndTime,
-		&meeting.Location,
-
-		pq.Array(&meeting.AttendeeIDs),
-		pq.Array(&meeting.AttendeeNames),
-		pq.Array(&meeting.AttendeeEmails),
-		pq.Array(&meeting.AttendeeStatus),
-		&meeting.MeetingType,
-		&meeting.Status,
-		pq.Array(&meeting.JoinURL),
-		&meeting.MaximumCapacity,
-		&meeting.CreatedAt,
-		&meeting.UpdatedAt,
-	)
+	meeting, err := scanMeeting(r.db.QueryRow(`SELECT `+meetingColumns+` FROM meetings WHERE id = $1`, meetingID))
 
 	// If no rows were returned, it means the course was not found
 	if err != nil {
 		if err == sql.ErrNoRows {
 			log.Printf("No course found with ID: %v", meetingID)
-			return nil, fmt.Errorf("course Not Found")
+			return nil, notFound("meeting not found")
 		}
 		log.Printf("Error retrieving course by ID: %v", err)
+		return nil, err
 	}
 
-	return &meeting, nil
+	return meeting, nil
 }
 
 // GetAll implements repository.MeetingRepository.
 func (r *MeetingRepositoryImpl) GetAll() ([]*entity.Meeting, error) {
+	return r.query(`SELECT ` + meetingColumns + ` FROM meetings`)
+}
+
+// GetBySpaceID implements repository.MeetingRepository.
+func (r *MeetingRepositoryImpl) GetBySpaceID(spaceID uuid.UUID) ([]*entity.Meeting, error) {
+	return r.query(`SELECT `+meetingColumns+` FROM meetings WHERE space_id = $1 AND deleted_at IS NULL ORDER BY start_time`, spaceID)
+}
+
+// query runs a select of meetingColumns and scans every row
+func (r *MeetingRepositoryImpl) query(query string, args ...interface{}) ([]*entity.Meeting, error) {
 	var meetings []*entity.Meeting
 
-	query := `SELECT id, title, description, duration, start_time, end_time, location, attendee_ids, attendee_names, attendee_emails, attendee_status, meeting_type, status, join_url, maximum_capacity, created_at, updated_at FROM meetings`
-	rows, err := r.db.Query(query)
+	rows, err := r.db.Query(query, args...)
 	if err != nil {
 		log.Printf("Error retrieving meetings: %v", err)
 		return nil, err // Return nil on error
@@ -160,32 +183,12 @@ func (r *MeetingRepositoryImpl) GetAll() ([]*entity.Meeting, error) {
 	defer rows.Close()
 
 	for rows.Next() {
-		var meeting entity.Meeting
-		err := rows.Scan(
-			&meeting.ID,
-			&meeting.Title,
-			&meeting.Description,
-			&meeting.Duration,
-			&meeting.StartTime,
-			&meeting.EndTime,
-			&meeting.Location,
-			pq.Array(&meeting.AttendeeIDs),
-			pq.Array(&meeting.AttendeeNames),
-			pq.Array(&meeting.AttendeeEmails),
-			pq.Array(&meeting.AttendeeStatus),
-			&meeting.MeetingType,
-			&meeting.Status,
-			pq.Array(&meeting.JoinURL),
-			&meeting.MaximumCapacity,
-			&meeting.CreatedAt,
-			&meeting.UpdatedAt,
-		)
-
+		meeting, err := scanMeeting(rows)
 		if err != nil {
 			log.Printf("Error scanning meeting: %v", err)
 			return nil, err // Return nil on error
 		}
-		meetings = append(meetings, &meeting)
+		meetings = append(meetings, meeting)
 	}
 
 	if err = rows.Err(); err != nil {
@@ -205,7 +208,7 @@ func (r *MeetingRepositoryImpl) Delete(meetingID uuid.UUID) error {
 	result, err := r.db.Exec(query, meetingID)
 	if err != nil {
 		log.Printf("Error deleting course with ID: %v, error: %v", meetingID, err)
-		return err
+		return storeError(err)
 	}
 	// Check how many rows were affected by the delete
 	rowsAffected, err := result.RowsAffected()
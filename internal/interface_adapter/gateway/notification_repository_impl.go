@@ -0,0 +1,142 @@
+package gateway
+
+import (
+	"dalabio/internal/entity"
+	"dalabio/internal/repository"
+	"database/sql"
+	"log"
+	"time"
+
+	"github.com/gofrs/uuid"
+	"github.com/lib/pq"
+)
+
+type notificationRepositoryImpl struct {
+	db *sql.DB
+}
+
+// NewNotificationRepository creates a new instance of NotificationRepository.
+func NewNotificationRepository(db *sql.DB) repository.NotificationRepository {
+	return &notificationRepositoryImpl{db: db}
+}
+
+const notificationColumns = `id, user_id, category, type, title, body, link, read_at, created_at`
+
+const notificationPreferenceColumns = `user_id, category, channel, enabled, updated_at`
+
+// Create implements repository.NotificationRepository.
+func (r *notificationRepositoryImpl) Create(notification *entity.Notification) error {
+	notification.CreatedAt = time.Now()
+
+	query := `INSERT INTO notifications (id, user_id, category, type, title, body, link, created_at) VALUES ($1, $2, $3, $4, $5, $6, $7, $8)`
+	if _, err := r.db.Exec(query, notification.ID, notification.UserID, notification.Category, notification.Type, notification.Title, notification.Body, notification.Link, notification.CreatedAt); err != nil {
+		log.Printf("Error inserting notification: %v, query: %s", err, query)
+		return storeError(err)
+	}
+
+	return nil
+}
+
+// GetByUserID implements repository.NotificationRepository.
+func (r *notificationRepositoryImpl) GetByUserID(userID uuid.UUID, unreadOnly bool, limit int) ([]*entity.Notification, error) {
+	query := `SELECT ` + notificationColumns + ` FROM notifications
+	WHERE user_id = $1 AND (NOT $2 OR read_at IS NULL)
+	ORDER BY created_at DESC, id DESC
+	LIMIT $3`
+	rows, err := r.db.Query(query, userID, unreadOnly, limit)
+	if err != nil {
+		log.Printf("Error retrieving notifications of user %v: %v", userID, err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	notifications := []*entity.Notification{}
+	for rows.Next() {
+		var notification entity.Notification
+		if err := rows.Scan(&notification.ID, &notification.UserID, &notification.Category, &notification.Type, &notification.Title,
+			&notification.Body, &notification.Link, &notification.ReadAt, &notification.CreatedAt); err != nil {
+			log.Printf("Error scanning notification: %v", err)
+			return nil, err
+		}
+		notifications = append(notifications, &notification)
+	}
+
+	return notifications, rows.Err()
+}
+
+// CountUnread implements repository.NotificationRepository.
+func (r *notificationRepositoryImpl) CountUnread(userID uuid.UUID) (int, error) {
+	var count int
+	if err := r.db.QueryRow(`SELECT COUNT(*) FROM notifications WHERE user_id = $1 AND read_at IS NULL`, userID).Scan(&count); err != nil {
+		log.Printf("Error counting unread notifications of %v: %v", userID, err)
+		return 0, err
+	}
+	return count, nil
+}
+
+// MarkRead implements repository.NotificationRepository.
+func (r *notificationRepositoryImpl) MarkRead(userID, notificationID uuid.UUID) error {
+	result, err := r.db.Exec(`UPDATE notifications SET read_at = COALESCE(read_at, $3) WHERE id = $1 AND user_id = $2`,
+		notificationID, userID, time.Now())
+	if err != nil {
+		log.Printf("Error marking notification %v as read: %v", notificationID, err)
+		return storeError(err)
+	}
+	return expectRow(result, "notification not found")
+}
+
+// MarkAllRead implements repository.NotificationRepository.
+func (r *notificationRepositoryImpl) MarkAllRead(userID uuid.UUID) (int64, error) {
+	result, err := r.db.Exec(`UPDATE notifications SET read_at = $2 WHERE user_id = $1 AND read_at IS NULL`, userID, time.Now())
+	if err != nil {
+		log.Printf("Error marking notifications of %v as read: %v", userID, err)
+		return 0, storeError(err)
+	}
+	return result.RowsAffected()
+}
+
+// GetPreferences implements repository.NotificationRepository.
+func (r *notificationRepositoryImpl) GetPreferences(userID uuid.UUID) ([]*entity.NotificationPreference, error) {
+	return r.queryPreferences(`SELECT `+notificationPreferenceColumns+` FROM notification_preferences WHERE user_id = $1`, userID)
+}
+
+// GetPreferencesByCategory implements repository.NotificationRepository.
+func (r *notificationRepositoryImpl) GetPreferencesByCategory(category string, userIDs []uuid.UUID) ([]*entity.NotificationPreference, error) {
+	return r.queryPreferences(`SELECT `+notificationPreferenceColumns+` FROM notification_preferences
+	WHERE category = $1 AND user_id = ANY($2)`, category, pq.Array(userIDs))
+}
+
+func (r *notificationRepositoryImpl) queryPreferences(query string, args ...interface{}) ([]*entity.NotificationPreference, error) {
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		log.Printf("Error retrieving notification preferences: %v", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	preferences := []*entity.NotificationPreference{}
+	for rows.Next() {
+		var preference entity.NotificationPreference
+		if err := rows.Scan(&preference.UserID, &preference.Category, &preference.Channel, &preference.Enabled, &preference.UpdatedAt); err != nil {
+			log.Printf("Error scanning notification preference: %v", err)
+			return nil, err
+		}
+		preferences = append(preferences, &preference)
+	}
+
+	return preferences, rows.Err()
+}
+
+// SetPreference implements repository.NotificationRepository.
+func (r *notificationRepositoryImpl) SetPreference(preference *entity.NotificationPreference) error {
+	preference.UpdatedAt = time.Now()
+
+	query := `INSERT INTO notification_preferences (` + notificationPreferenceColumns + `) VALUES ($1, $2, $3, $4, $5)
+	ON CONFLICT (user_id, category, channel) DO UPDATE SET enabled = EXCLUDED.enabled, updated_at = EXCLUDED.updated_at`
+	if _, err := r.db.Exec(query, preference.UserID, preference.Category, preference.Channel, preference.Enabled, preference.UpdatedAt); err != nil {
+		log.Printf("Error saving notification preference: %v, query: %s", err, query)
+		return storeError(err)
+	}
+
+	return nil
+}
@@ -0,0 +1,96 @@
+package gateway
+
+import (
+	"dalabio/internal/entity"
+	"dalabio/internal/repository"
+	"database/sql"
+	"log"
+	"time"
+
+	"github.com/gofrs/uuid"
+)
+
+type oneTimeTokenRepositoryImpl struct {
+	db *sql.DB
+}
+
+// NewOneTimeTokenRepository creates a new instance of OneTimeTokenRepository.
+func NewOneTimeTokenRepository(db *sql.DB) repository.OneTimeTokenRepository {
+	return &oneTimeTokenRepositoryImpl{db: db}
+}
+
+const oneTimeTokenColumns = `id, user_id, purpose, token_hash, expires_at, used_at, created_at`
+
+// Create implements repository.OneTimeTokenRepository.
+func (r *oneTimeTokenRepositoryImpl) Create(token *entity.OneTimeToken) error {
+	token.CreatedAt = time.Now()
+
+	query := `INSERT INTO one_time_tokens (` + oneTimeTokenColumns + `) VALUES ($1, $2, $3, $4, $5, $6, $7)`
+	if _, err := r.db.Exec(query, token.ID, token.UserID, token.Purpose, token.TokenHash, token.ExpiresAt, token.UsedAt, token.CreatedAt); err != nil {
+		log.Printf("Error inserting one-time token: %v, query: %s", err, query)
+		return storeError(err)
+	}
+
+	return nil
+}
+
+// Consume implements repository.OneTimeTokenRepository.
+func (r *oneTimeTokenRepositoryImpl) Consume(purpose, tokenHash string, now time.Time) (*entity.OneTimeToken, error) {
+	// A single statement so that a token used twice at the same time is only accepted once
+	query := `UPDATE one_time_tokens SET used_at = $3
+	WHERE purpose = $1 AND token_hash = $2 AND used_at IS NULL AND expires_at > $3
+	RETURNING ` + oneTimeTokenColumns
+
+	var token entity.OneTimeToken
+	err := r.db.QueryRow(query, purpose, tokenHash, now).Scan(&token.ID, &token.UserID, &token.Purpose, &token.TokenHash,
+		&token.ExpiresAt, &token.UsedAt, &token.CreatedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, notFound("token not found")
+		}
+		log.Printf("Error consuming one-time token: %v", err)
+		return nil, err
+	}
+
+	return &token, nil
+}
+
+// Find implements repository.OneTimeTokenRepository.
+func (r *oneTimeTokenRepositoryImpl) Find(purpose, tokenHash string, now time.Time) (*entity.OneTimeToken, error) {
+	query := `SELECT ` + oneTimeTokenColumns + ` FROM one_time_tokens
+	WHERE purpose = $1 AND token_hash = $2 AND used_at IS NULL AND expires_at > $3`
+
+	var token entity.OneTimeToken
+	err := r.db.QueryRow(query, purpose, tokenHash, now).Scan(&token.ID, &token.UserID, &token.Purpose, &token.TokenHash,
+		&token.ExpiresAt, &token.UsedAt, &token.CreatedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, notFound("token not found")
+		}
+		log.Printf("Error fetching one-time token: %v", err)
+		return nil, err
+	}
+
+	return &token, nil
+}
+
+// InvalidateAll implements repository.OneTimeTokenRepository.
+func (r *oneTimeTokenRepositoryImpl) InvalidateAll(userID uuid.UUID, purpose string, now time.Time) error {
+	if _, err := r.db.Exec(`UPDATE one_time_tokens SET used_at = $3 WHERE user_id = $1 AND purpose = $2 AND used_at IS NULL`,
+		userID, purpose, now); err != nil {
+		log.Printf("Error invalidating %s tokens of user %v: %v", purpose, userID, err)
+		return storeError(err)
+	}
+	return nil
+}
+
+// CountCreatedSince implements repository.OneTimeTokenRepository.
+func (r *oneTimeTokenRepositoryImpl) CountCreatedSince(userID uuid.UUID, purpose string, since time.Time) (int, error) {
+	var count int
+	if err := r.db.QueryRow(`SELECT COUNT(*) FROM one_time_tokens WHERE user_id = $1 AND purpose = $2 AND created_at >= $3`,
+		userID, purpose, since).Scan(&count); err != nil {
+		log.Printf("Error counting %s tokens of user %v: %v", purpose, userID, err)
+		return 0, err
+	}
+	return count, nil
+}
@@ -0,0 +1,239 @@
+package gateway
+
+import (
+	"dalabio/internal/entity"
+	"dalabio/internal/repository"
+	"database/sql"
+	"log"
+	"time"
+
+	"github.com/gofrs/uuid"
+)
+
+type orderRepositoryImpl struct {
+	db *sql.DB
+}
+
+// NewOrderRepository creates a new instance of OrderRepository.
+func NewOrderRepository(db *sql.DB) repository.OrderRepository {
+	return &orderRepositoryImpl{db: db}
+}
+
+// Create implements repository.OrderRepository.
+func (r *orderRepositoryImpl) Create(order *entity.Order) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		log.Printf("Error starting order transaction: %v", err)
+		return err
+	}
+	defer tx.Rollback()
+
+	now := time.Now()
+	order.CreatedAt = now
+	order.UpdatedAt = now
+
+	query := `INSERT INTO orders (id, user_id, status, currency, subtotal, discount, coupon_code, total, billing_name, billing_address, created_at, updated_at)
+	VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)`
+	if _, err := tx.Exec(query, order.ID, order.UserID, order.Status, order.Currency, order.Subtotal, order.Discount, order.CouponCode, order.Total, order.BillingName, order.BillingAddress, order.CreatedAt, order.UpdatedAt); err != nil {
+		log.Printf("Error inserting order: %v, query: %s", err, query)
+		return storeError(err)
+	}
+
+	lineQuery := `INSERT INTO order_lines (id, order_id, item_type, item_id, description, unit_price, quantity, amount)
+	VALUES ($1, $2, $3, $4, $5, $6, $7, $8)`
+	for _, line := range order.Lines {
+		if _, err := tx.Exec(lineQuery, line.ID, order.ID, line.ItemType, line.ItemID, line.Description, line.UnitPrice, line.Quantity, line.Amount); err != nil {
+			log.Printf("Error inserting order line: %v, query: %s", err, lineQuery)
+			return storeError(err)
+		}
+	}
+
+	return tx.Commit()
+}
+
+// GetdByID implements repository.OrderRepository.
+func (r *orderRepositoryImpl) GetdByID(orderID uuid.UUID) (*entity.Order, error) {
+	var order entity.Order
+	query := `SELECT id, user_id, status, currency, subtotal, discount, coupon_code, total, billing_name, billing_address, created_at, updated_at FROM orders WHERE id = $1`
+	err := r.db.QueryRow(query, orderID).Scan(
+		&order.ID,
+		&order.UserID,
+		&order.Status,
+		&order.Currency,
+		&order.Subtotal,
+		&order.Discount,
+		&order.CouponCode,
+		&order.Total,
+		&order.BillingName,
+		&order.BillingAddress,
+		&order.CreatedAt,
+		&order.UpdatedAt,
+	)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			log.Printf("No order found with ID: %v", orderID)
+			return nil, notFound("order not found")
+		}
+		log.Printf("Error fetching order with ID: %v, error: %v", orderID, err)
+		return nil, err
+	}
+
+	lines, err := r.getLines(orderID)
+	if err != nil {
+		return nil, err
+	}
+	order.Lines = lines
+
+	return &order, nil
+}
+
+// GetByUserID implements repository.OrderRepository.
+func (r *orderRepositoryImpl) GetByUserID(userID uuid.UUID) ([]*entity.Order, error) {
+	query := `SELECT id, user_id, status, currency, subtotal, discount, coupon_code, total, billing_name, billing_address, created_at, updated_at FROM orders WHERE user_id = $1 ORDER BY created_at DESC`
+	rows, err := r.db.Query(query, userID)
+	if err != nil {
+		log.Printf("Error fetching orders of user: %v, error: %v", userID, err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	var orders []*entity.Order
+	for rows.Next() {
+		var order entity.Order
+		err := rows.Scan(
+			&order.ID,
+			&order.UserID,
+			&order.Status,
+			&order.Currency,
+			&order.Subtotal,
+			&order.Discount,
+			&order.CouponCode,
+			&order.Total,
+			&order.BillingName,
+			&order.BillingAddress,
+			&order.CreatedAt,
+			&order.UpdatedAt,
+		)
+		if err != nil {
+			log.Printf("Error scanning order: %v", err)
+			return nil, err
+		}
+		orders = append(orders, &order)
+	}
+
+	if err = rows.Err(); err != nil {
+		log.Printf("Error iterating over orders: %v", err)
+		return nil, err
+	}
+
+	for _, order := range orders {
+		lines, err := r.getLines(order.ID)
+		if err != nil {
+			return nil, err
+		}
+		order.Lines = lines
+	}
+
+	return orders, nil
+}
+
+// UpdateStatus implements repository.OrderRepository.
+func (r *orderRepositoryImpl) UpdateStatus(orderID uuid.UUID, status string) error {
+	query := `UPDATE orders SET status = $2, updated_at = CURRENT_TIMESTAMP WHERE id = $1`
+	result, err := r.db.Exec(query, orderID, status)
+	if err != nil {
+		log.Printf("Error updating order with ID: %v, error: %v", orderID, err)
+		return storeError(err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		log.Printf("Error fetching rows affected: %v", err)
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return notFound("order not found")
+	}
+
+	return nil
+}
+
+// TransitionStatus implements repository.OrderRepository.
+func (r *orderRepositoryImpl) TransitionStatus(orderID uuid.UUID, from, to string) (bool, error) {
+	query := `UPDATE orders SET status = $3, updated_at = CURRENT_TIMESTAMP WHERE id = $1 AND status = $2`
+	result, err := r.db.Exec(query, orderID, from, to)
+	if err != nil {
+		log.Printf("Error updating order with ID: %v, error: %v", orderID, err)
+		return false, storeError(err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		log.Printf("Error fetching rows affected: %v", err)
+		return false, err
+	}
+
+	return rowsAffected > 0, nil
+}
+
+// GetIDsCreatedBefore implements repository.OrderRepository.
+func (r *orderRepositoryImpl) GetIDsCreatedBefore(status string, before time.Time) ([]uuid.UUID, error) {
+	query := `SELECT id FROM orders WHERE status = $1 AND created_at < $2 ORDER BY created_at`
+	rows, err := r.db.Query(query, status, before)
+	if err != nil {
+		log.Printf("Error fetching orders: %v, query: %s", err, query)
+		return nil, err
+	}
+	defer rows.Close()
+
+	var orderIDs []uuid.UUID
+	for rows.Next() {
+		var orderID uuid.UUID
+		if err := rows.Scan(&orderID); err != nil {
+			log.Printf("Error scanning order ID: %v", err)
+			return nil, err
+		}
+		orderIDs = append(orderIDs, orderID)
+	}
+
+	if err = rows.Err(); err != nil {
+		log.Printf("Error iterating over orders: %v", err)
+		return nil, err
+	}
+
+	return orderIDs, nil
+}
+
+// getLines loads the lines of an order
+func (r *orderRepositoryImpl) getLines(orderID uuid.UUID) ([]*entity.OrderLine, error) {
+	query := `SELECT id, order_id, item_type, item_id, description, unit_price, quantity, amount FROM order_lines WHERE order_id = $1`
+	rows, err := r.db.Query(query, orderID)
+	if err != nil {
+		log.Printf("Error fetching lines of order: %v, error: %v", orderID, err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	var lines []*entity.OrderLine
+	for rows.Next() {
+		var line entity.OrderLine
+		err := rows.Scan(
+			&line.ID,
+			&line.OrderID,
+			&line.ItemType,
+			&line.ItemID,
+			&line.Description,
+			&line.UnitPrice,
+			&line.Quantity,
+			&line.Amount,
+		)
+		if err != nil {
+			log.Printf("Error scanning order line: %v", err)
+			return nil, err
+		}
+		lines = append(lines, &line)
+	}
+
+	return lines, rows.Err()
+}
@@ -4,8 +4,8 @@ import (
 	"dalabio/internal/entity"
 	"dalabio/internal/repository"
 	"database/sql"
-	"errors"
 	"log"
+	"time"
 
 	"github.com/gofrs/uuid"
 )
@@ -19,13 +19,13 @@ func (r *PaymentRepositoryImpl) Create(payment *entity.Payment) error {
 	//query  insert
 
 	query := `INSERT INTO payments(id, user_id, order_id, amount, currency, payment_method, transaction_id, status, payment_gateway, payment_date,  notes, created_at, updated_at)
-	VALUES($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13)
+	VALUES($1, $2, $3, $4, $5, $6, NULLIF($7, ''), $8, $9, $10, $11, $12, $13)
 	`
 	result, err := r.db.Exec(query, payment.ID, payment.UserID, payment.OrderID, payment.Amount, payment.Currency, payment.PaymentMethod, payment.TransactionID, payment.Status, payment.PaymentGateway, payment.PaymentDate, payment.Notes, payment.CreatedAt, payment.UpdatedAt)
 
 	if err != nil {
 		log.Printf("Error inserting course: %v, query: %s", err, query)
-		return err
+		return storeError(err)
 	}
 
 	rowsAffected, err := result.RowsAffected()
@@ -74,7 +74,7 @@ func (r *PaymentRepositoryImpl) GetAll() ([]*entity.Payment, error) {
 	var payments []*entity.Payment
 	//query select
 
-	query := ` SELECT id, user_id, order_id, amount, currency, payment_method, transaction_id, status, payment_gateway, payment_date,notes, created_at, updated_at FROM payments`
+	query := ` SELECT id, user_id, order_id, amount, currency, payment_method, COALESCE(transaction_id, ''), status, payment_gateway, payment_date,notes, created_at, updated_at FROM payments`
 	rows, err := r.db.Query(query)
 
 	if err != nil {
@@ -115,7 +115,7 @@ func (r *PaymentRepositoryImpl) GetAll() ([]*entity.Payment, error) {
 func (r *PaymentRepositoryImpl) GetdByID(paymentID uuid.UUID) (*entity.Payment, error) {
 	var payment entity.Payment
 	// query select ID
-	query := `SELECT id, user_id, order_id, amount, currency, payment_method, transaction_id, status, payment_gateway, payment_date,  notes, created_at, updated_at FROM payments WHERE id = $1`
+	query := `SELECT id, user_id, order_id, amount, currency, payment_method, COALESCE(transaction_id, ''), status, payment_gateway, payment_date,  notes, created_at, updated_at FROM payments WHERE id = $1`
 	err := r.db.QueryRow(query, paymentID).Scan(
 		&payment.ID,
 		&payment.UserID,
@@ -135,7 +135,7 @@ func (r *PaymentRepositoryImpl) GetdByID(paymentID uuid.UUID) (*entity.Payment,
 	if err != nil {
 		if err == sql.ErrNoRows {
 			log.Printf("Error fetching course with ID: %v, error: %v", paymentID, err)
-			return nil, errors.New("payment not found")
+			return nil, notFound("payment not found")
 		}
 
 		log.Printf("Error fetching course with ID: %v, error: %v", paymentID, err)
@@ -149,7 +149,7 @@ func (r *PaymentRepositoryImpl) Update(payment *entity.Payment) error {
 	//query update
 	query := `UPDATE
 		payments
-		SET user_id = $2, order_id = $3, amount = $4, currency = $5, payment_method = $6, transaction_id = $7, status = $8, payment_gateway = $9, payment_date = $10,  notes = $11, updated_at = CURRENT_TIMESTAMP
+		SET user_id = $2, order_id = $3, amount = $4, currency = $5, payment_method = $6, transaction_id = NULLIF($7, ''), status = $8, payment_gateway = $9, payment_date = $10,  notes = $11, updated_at = CURRENT_TIMESTAMP
 		WHERE id = $1 
 		`
 	result, err := r.db.Exec(query,
@@ -184,3 +184,88 @@ func (r *PaymentRepositoryImpl) Update(payment *entity.Payment) error {
 func NewPaymentRepository(db *sql.DB) repository.PaymentRepository {
 	return &PaymentRepositoryImpl{db: db}
 }
+
+// GetByTransactionID implements repository.PaymentRepository.
+func (r *PaymentRepositoryImpl) GetByTransactionID(transactionID string) (*entity.Payment, error) {
+	var payment entity.Payment
+	query := `SELECT id, user_id, order_id, amount, currency, payment_method, COALESCE(transaction_id, ''), status, payment_gateway, payment_date, notes, created_at, updated_at FROM payments WHERE transaction_id = $1`
+	err := r.db.QueryRow(query, transactionID).Scan(
+		&payment.ID,
+		&payment.UserID,
+		&payment.OrderID,
+		&payment.Amount,
+		&payment.Currency,
+		&payment.PaymentMethod,
+		&payment.TransactionID,
+		&payment.Status,
+		&payment.PaymentGateway,
+		&payment.PaymentDate,
+		&payment.Notes,
+		&payment.CreatedAt,
+		&payment.UpdatedAt,
+	)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, notFound("payment not found")
+		}
+		log.Printf("Error fetching payment with transaction ID: %v, error: %v", transactionID, err)
+		return nil, err
+	}
+
+	return &payment, nil
+}
+
+// GetByDateRange implements repository.PaymentRepository.
+func (r *PaymentRepositoryImpl) GetByDateRange(from, to time.Time) ([]*entity.Payment, error) {
+	query := `SELECT id, user_id, order_id, amount, currency, payment_method, COALESCE(transaction_id, ''), status, payment_gateway, payment_date, notes, created_at, updated_at
+	FROM payments WHERE payment_date >= $1 AND payment_date < $2 ORDER BY payment_date`
+	rows, err := r.db.Query(query, from, to)
+	if err != nil {
+		log.Printf("Error fetching payments: %v, query: %s", err, query)
+		return nil, err
+	}
+	defer rows.Close()
+
+	var payments []*entity.Payment
+	for rows.Next() {
+		var payment entity.Payment
+		err := rows.Scan(
+			&payment.ID,
+			&payment.UserID,
+			&payment.OrderID,
+			&payment.Amount,
+			&payment.Currency,
+			&payment.PaymentMethod,
+			&payment.TransactionID,
+			&payment.Status,
+			&payment.PaymentGateway,
+			&payment.PaymentDate,
+			&payment.Notes,
+			&payment.CreatedAt,
+			&payment.UpdatedAt,
+		)
+		if err != nil {
+			log.Printf("Error scanning payment: %v", err)
+			return nil, err
+		}
+		payments = append(payments, &payment)
+	}
+
+	if err = rows.Err(); err != nil {
+		log.Printf("Error iterating over payments: %v", err)
+		return nil, err
+	}
+
+	return payments, nil
+}
+
+// UpdateStatusByOrderID implements repository.PaymentRepository.
+func (r *PaymentRepositoryImpl) UpdateStatusByOrderID(orderID uuid.UUID, from, to string) error {
+	query := `UPDATE payments SET status = $3, updated_at = CURRENT_TIMESTAMP WHERE order_id = $1 AND status = $2`
+	if _, err := r.db.Exec(query, orderID, from, to); err != nil {
+		log.Printf("Error updating payments of order with ID: %v, error: %v", orderID, err)
+		return storeError(err)
+	}
+
+	return nil
+}
@@ -0,0 +1,154 @@
+package gateway
+
+import (
+	"dalabio/internal/entity"
+	"dalabio/internal/repository"
+	"database/sql"
+	"log"
+	"time"
+
+	"github.com/gofrs/uuid"
+)
+
+type payoutRepositoryImpl struct {
+	db *sql.DB
+}
+
+// NewPayoutRepository creates a new instance of PayoutRepository.
+func NewPayoutRepository(db *sql.DB) repository.PayoutRepository {
+	return &payoutRepositoryImpl{db: db}
+}
+
+const payoutColumns = `id, user_id, amount, currency, status, reference, period, paid_at, created_at, updated_at`
+
+// scanPayout scans a row selected with payoutColumns
+func scanPayout(row interface{ Scan(...interface{}) error }) (*entity.Payout, error) {
+	var payout entity.Payout
+	err := row.Scan(
+		&payout.ID,
+		&payout.UserID,
+		&payout.Amount,
+		&payout.Currency,
+		&payout.Status,
+		&payout.Reference,
+		&payout.Period,
+		&payout.PaidAt,
+		&payout.CreatedAt,
+		&payout.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &payout, nil
+}
+
+// Create implements repository.PayoutRepository.
+func (r *payoutRepositoryImpl) Create(payout *entity.Payout, transaction *entity.LedgerTransaction) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		log.Printf("Error starting payout transaction: %v", err)
+		return err
+	}
+	defer tx.Rollback()
+
+	now := time.Now()
+	payout.CreatedAt = now
+	payout.UpdatedAt = now
+
+	query := `INSERT INTO payouts (` + payoutColumns + `) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)`
+	_, err = tx.Exec(query, payout.ID, payout.UserID, payout.Amount, payout.Currency, payout.Status, payout.Reference, payout.Period, payout.PaidAt, payout.CreatedAt, payout.UpdatedAt)
+	if err != nil {
+		log.Printf("Error inserting payout: %v, query: %s", err, query)
+		return storeError(err)
+	}
+
+	if err := insertLedgerTransaction(tx, transaction); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// Update implements repository.PayoutRepository.
+func (r *payoutRepositoryImpl) Update(payout *entity.Payout, transaction *entity.LedgerTransaction) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		log.Printf("Error starting payout transaction: %v", err)
+		return err
+	}
+	defer tx.Rollback()
+
+	payout.UpdatedAt = time.Now()
+
+	query := `UPDATE payouts SET status = $2, reference = $3, paid_at = $4, updated_at = $5 WHERE id = $1`
+	result, err := tx.Exec(query, payout.ID, payout.Status, payout.Reference, payout.PaidAt, payout.UpdatedAt)
+	if err != nil {
+		log.Printf("Error updating payout with ID: %v, error: %v", payout.ID, err)
+		return storeError(err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return notFound("payout not found")
+	}
+
+	if transaction != nil {
+		if err := insertLedgerTransaction(tx, transaction); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// GetdByID implements repository.PayoutRepository.
+func (r *payoutRepositoryImpl) GetdByID(payoutID uuid.UUID) (*entity.Payout, error) {
+	payout, err := scanPayout(r.db.QueryRow(`SELECT `+payoutColumns+` FROM payouts WHERE id = $1`, payoutID))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, notFound("payout not found")
+		}
+		log.Printf("Error fetching payout with ID: %v, error: %v", payoutID, err)
+		return nil, err
+	}
+	return payout, nil
+}
+
+// GetByUserID implements repository.PayoutRepository.
+func (r *payoutRepositoryImpl) GetByUserID(userID uuid.UUID) ([]*entity.Payout, error) {
+	return r.query(`SELECT `+payoutColumns+` FROM payouts WHERE user_id = $1 ORDER BY created_at DESC`, userID)
+}
+
+// GetAll implements repository.PayoutRepository.
+func (r *payoutRepositoryImpl) GetAll() ([]*entity.Payout, error) {
+	return r.query(`SELECT ` + payoutColumns + ` FROM payouts ORDER BY created_at DESC`)
+}
+
+func (r *payoutRepositoryImpl) query(query string, args ...interface{}) ([]*entity.Payout, error) {
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		log.Printf("Error fetching payouts: %v", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	var payouts []*entity.Payout
+	for rows.Next() {
+		payout, err := scanPayout(rows)
+		if err != nil {
+			log.Printf("Error scanning payout: %v", err)
+			return nil, err
+		}
+		payouts = append(payouts, payout)
+	}
+
+	if err = rows.Err(); err != nil {
+		log.Printf("Error iterating over payouts: %v", err)
+		return nil, err
+	}
+
+	return payouts, nil
+}
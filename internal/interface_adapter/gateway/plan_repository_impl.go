@@ -0,0 +1,129 @@
+package gateway
+
+import (
+	"dalabio/internal/entity"
+	"dalabio/internal/repository"
+	"database/sql"
+	"log"
+	"time"
+
+	"github.com/gofrs/uuid"
+)
+
+type planRepositoryImpl struct {
+	db *sql.DB
+}
+
+// NewPlanRepository creates a new instance of PlanRepository.
+func NewPlanRepository(db *sql.DB) repository.PlanRepository {
+	return &planRepositoryImpl{db: db}
+}
+
+// Create implements repository.PlanRepository.
+func (r *planRepositoryImpl) Create(plan *entity.Plan) error {
+	now := time.Now()
+	plan.CreatedAt = now
+	plan.UpdatedAt = now
+
+	query := `INSERT INTO plans (id, space_id, name, price, currency, interval, trial_days, active, created_at, updated_at)
+	VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)`
+	_, err := r.db.Exec(query, plan.ID, plan.SpaceID, plan.Name, plan.Price, plan.Currency, plan.Interval, plan.TrialDays, plan.Active, plan.CreatedAt, plan.UpdatedAt)
+	if err != nil {
+		log.Printf("Error inserting plan: %v, query: %s", err, query)
+		return storeError(err)
+	}
+
+	return nil
+}
+
+// Update implements repository.PlanRepository.
+func (r *planRepositoryImpl) Update(plan *entity.Plan) error {
+	query := `UPDATE plans SET name = $2, price = $3, currency = $4, interval = $5, trial_days = $6, active = $7, updated_at = CURRENT_TIMESTAMP
+	WHERE id = $1`
+	result, err := r.db.Exec(query, plan.ID, plan.Name, plan.Price, plan.Currency, plan.Interval, plan.TrialDays, plan.Active)
+	if err != nil {
+		log.Printf("Error updating plan with ID: %v, error: %v", plan.ID, err)
+		return storeError(err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		log.Printf("Error fetching rows affected: %v", err)
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return notFound("plan not found")
+	}
+
+	return nil
+}
+
+// GetdByID implements repository.PlanRepository.
+func (r *planRepositoryImpl) GetdByID(planID uuid.UUID) (*entity.Plan, error) {
+	var plan entity.Plan
+	query := `SELECT id, space_id, name, price, currency, interval, trial_days, active, created_at, updated_at FROM plans WHERE id = $1`
+	err := r.db.QueryRow(query, planID).Scan(
+		&plan.ID,
+		&plan.SpaceID,
+		&plan.Name,
+		&plan.Price,
+		&plan.Currency,
+		&plan.Interval,
+		&plan.TrialDays,
+		&plan.Active,
+		&plan.CreatedAt,
+		&plan.UpdatedAt,
+	)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			log.Printf("No plan found with ID: %v", planID)
+			return nil, notFound("plan not found")
+		}
+		log.Printf("Error fetching plan with ID: %v, error: %v", planID, err)
+		return nil, err
+	}
+
+	return &plan, nil
+}
+
+// GetBySpaceID implements repository.PlanRepository.
+func (r *planRepositoryImpl) GetBySpaceID(spaceID uuid.UUID) ([]*entity.Plan, error) {
+	query := `SELECT id, space_id, name, price, currency, interval, trial_days, active, created_at, updated_at
+	FROM plans WHERE space_id = $1 ORDER BY price`
+	rows, err := r.db.Query(query, spaceID)
+	if err != nil {
+		log.Printf("Error fetching plans of space: %v, error: %v", spaceID, err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	var plans []*entity.Plan
+	for rows.Next() {
+		var plan entity.Plan
+		err := rows.Scan(
+			&plan.ID,
+			&plan.SpaceID,
+			&plan.Name,
+			&plan.Price,
+			&plan.Currency,
+			&plan.Interval,
+			&plan.TrialDays,
+			&plan.Active,
+			&plan.CreatedAt,
+			&plan.UpdatedAt,
+		)
+		if err != nil {
+			log.Printf("Error scanning plan: %v", err)
+			return nil, err
+		}
+		plans = append(plans, &plan)
+	}
+
+	if err = rows.Err(); err != nil {
+		log.Printf("Error iterating over plans: %v", err)
+		return nil, err
+	}
+
+	return plans, nil
+}
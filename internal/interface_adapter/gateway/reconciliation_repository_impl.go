@@ -0,0 +1,167 @@
+package gateway
+
+import (
+	"dalabio/internal/entity"
+	"dalabio/internal/repository"
+	"database/sql"
+	"log"
+	"time"
+
+	"github.com/gofrs/uuid"
+)
+
+type reconciliationRepositoryImpl struct {
+	db *sql.DB
+}
+
+// NewReconciliationRepository creates a new instance of ReconciliationRepository.
+func NewReconciliationRepository(db *sql.DB) repository.ReconciliationRepository {
+	return &reconciliationRepositoryImpl{db: db}
+}
+
+const reconciliationColumns = `id, gateway, file_name, period_start, period_end, row_count, matched_count, discrepancy_count, created_by, created_at`
+
+const discrepancyColumns = `id, reconciliation_id, type, transaction_id, payment_id, expected_amount, settled_amount, currency, occurred_at, details`
+
+// scanReconciliation scans a row selected with reconciliationColumns
+func scanReconciliation(row interface{ Scan(...interface{}) error }) (*entity.Reconciliation, error) {
+	var reconciliation entity.Reconciliation
+	err := row.Scan(
+		&reconciliation.ID,
+		&reconciliation.Gateway,
+		&reconciliation.FileName,
+		&reconciliation.PeriodStart,
+		&reconciliation.PeriodEnd,
+		&reconciliation.RowCount,
+		&reconciliation.MatchedCount,
+		&reconciliation.DiscrepancyCount,
+		&reconciliation.CreatedBy,
+		&reconciliation.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &reconciliation, nil
+}
+
+// Create implements repository.ReconciliationRepository.
+func (r *reconciliationRepositoryImpl) Create(reconciliation *entity.Reconciliation) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		log.Printf("Error starting reconciliation transaction: %v", err)
+		return err
+	}
+	defer tx.Rollback()
+
+	reconciliation.CreatedAt = time.Now()
+
+	query := `INSERT INTO reconciliations (` + reconciliationColumns + `) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)`
+	_, err = tx.Exec(query, reconciliation.ID, reconciliation.Gateway, reconciliation.FileName, reconciliation.PeriodStart, reconciliation.PeriodEnd,
+		reconciliation.RowCount, reconciliation.MatchedCount, reconciliation.DiscrepancyCount, reconciliation.CreatedBy, reconciliation.CreatedAt)
+	if err != nil {
+		log.Printf("Error inserting reconciliation: %v, query: %s", err, query)
+		return storeError(err)
+	}
+
+	discrepancyQuery := `INSERT INTO reconciliation_discrepancies (` + discrepancyColumns + `) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)`
+	for _, discrepancy := range reconciliation.Discrepancies {
+		discrepancy.ReconciliationID = reconciliation.ID
+		_, err := tx.Exec(discrepancyQuery, discrepancy.ID, discrepancy.ReconciliationID, discrepancy.Type, discrepancy.TransactionID, discrepancy.PaymentID,
+			discrepancy.ExpectedAmount, discrepancy.SettledAmount, discrepancy.Currency, discrepancy.OccurredAt, discrepancy.Details)
+		if err != nil {
+			log.Printf("Error inserting reconciliation discrepancy: %v, query: %s", err, discrepancyQuery)
+			return storeError(err)
+		}
+	}
+
+	return tx.Commit()
+}
+
+// GetdByID implements repository.ReconciliationRepository.
+func (r *reconciliationRepositoryImpl) GetdByID(reconciliationID uuid.UUID) (*entity.Reconciliation, error) {
+	reconciliation, err := scanReconciliation(r.db.QueryRow(`SELECT `+reconciliationColumns+` FROM reconciliations WHERE id = $1`, reconciliationID))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, notFound("reconciliation not found")
+		}
+		log.Printf("Error fetching reconciliation with ID: %v, error: %v", reconciliationID, err)
+		return nil, err
+	}
+
+	reconciliation.Discrepancies, err = r.queryDiscrepancies(`SELECT `+discrepancyColumns+` FROM reconciliation_discrepancies WHERE reconciliation_id = $1 ORDER BY occurred_at`, reconciliationID)
+	if err != nil {
+		return nil, err
+	}
+
+	return reconciliation, nil
+}
+
+// GetAll implements repository.ReconciliationRepository.
+func (r *reconciliationRepositoryImpl) GetAll() ([]*entity.Reconciliation, error) {
+	rows, err := r.db.Query(`SELECT ` + reconciliationColumns + ` FROM reconciliations ORDER BY created_at DESC`)
+	if err != nil {
+		log.Printf("Error fetching reconciliations: %v", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	var reconciliations []*entity.Reconciliation
+	for rows.Next() {
+		reconciliation, err := scanReconciliation(rows)
+		if err != nil {
+			log.Printf("Error scanning reconciliation: %v", err)
+			return nil, err
+		}
+		reconciliations = append(reconciliations, reconciliation)
+	}
+
+	if err = rows.Err(); err != nil {
+		log.Printf("Error iterating over reconciliations: %v", err)
+		return nil, err
+	}
+
+	return reconciliations, nil
+}
+
+// GetDiscrepanciesByDateRange implements repository.ReconciliationRepository.
+func (r *reconciliationRepositoryImpl) GetDiscrepanciesByDateRange(from, to time.Time) ([]*entity.ReconciliationDiscrepancy, error) {
+	return r.queryDiscrepancies(`SELECT `+discrepancyColumns+` FROM reconciliation_discrepancies WHERE occurred_at >= $1 AND occurred_at < $2 ORDER BY occurred_at`, from, to)
+}
+
+func (r *reconciliationRepositoryImpl) queryDiscrepancies(query string, args ...interface{}) ([]*entity.ReconciliationDiscrepancy, error) {
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		log.Printf("Error fetching reconciliation discrepancies: %v", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	var discrepancies []*entity.ReconciliationDiscrepancy
+	for rows.Next() {
+		var discrepancy entity.ReconciliationDiscrepancy
+		err := rows.Scan(
+			&discrepancy.ID,
+			&discrepancy.ReconciliationID,
+			&discrepancy.Type,
+			&discrepancy.TransactionID,
+			&discrepancy.PaymentID,
+			&discrepancy.ExpectedAmount,
+			&discrepancy.SettledAmount,
+			&discrepancy.Currency,
+			&discrepancy.OccurredAt,
+			&discrepancy.Details,
+		)
+		if err != nil {
+			log.Printf("Error scanning reconciliation discrepancy: %v", err)
+			return nil, err
+		}
+		discrepancies = append(discrepancies, &discrepancy)
+	}
+
+	if err = rows.Err(); err != nil {
+		log.Printf("Error iterating over reconciliation discrepancies: %v", err)
+		return nil, err
+	}
+
+	return discrepancies, nil
+}
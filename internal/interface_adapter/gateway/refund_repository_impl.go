@@ -0,0 +1,128 @@
+package gateway
+
+import (
+	"dalabio/internal/entity"
+	"dalabio/internal/repository"
+	"database/sql"
+	"log"
+	"time"
+
+	"github.com/gofrs/uuid"
+)
+
+type refundRepositoryImpl struct {
+	db *sql.DB
+}
+
+// NewRefundRepository creates a new instance of RefundRepository.
+func NewRefundRepository(db *sql.DB) repository.RefundRepository {
+	return &refundRepositoryImpl{db: db}
+}
+
+// Create implements repository.RefundRepository.
+// The payment row is locked while the refunded total is checked so that
+// concurrent refunds can never add up to more than the captured amount.
+func (r *refundRepositoryImpl) Create(refund *entity.Refund) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		log.Printf("Error starting refund transaction: %v", err)
+		return err
+	}
+	defer tx.Rollback()
+
+	// The balance is compared on the DECIMAL columns, where floats would reject refunds adding up exactly to the
+	// captured amount. Failed refunds never left the gateway, so they do not count against the balance.
+	var refundable bool
+	query := `SELECT p.amount - COALESCE((SELECT SUM(r.amount) FROM refunds r WHERE r.payment_id = p.id AND r.status <> 'failed'), 0) >= $2::DECIMAL(10, 2)
+	FROM payments p WHERE p.id = $1 FOR UPDATE`
+	err = tx.QueryRow(query, refund.PaymentID, refund.Amount).Scan(&refundable)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return notFound("payment not found")
+		}
+		log.Printf("Error locking payment with ID: %v, error: %v", refund.PaymentID, err)
+		return err
+	}
+	if !refundable {
+		return conflict("refund amount exceeds the refundable balance of the payment")
+	}
+
+	now := time.Now()
+	refund.CreatedAt = now
+	refund.UpdatedAt = now
+
+	query = `INSERT INTO refunds (id, payment_id, amount, currency, reason, status, gateway_refund_id, requested_by, created_at, updated_at)
+	VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)`
+	_, err = tx.Exec(query, refund.ID, refund.PaymentID, refund.Amount, refund.Currency, refund.Reason, refund.Status, refund.GatewayRefundID, refund.RequestedBy, refund.CreatedAt, refund.UpdatedAt)
+	if err != nil {
+		log.Printf("Error inserting refund: %v, query: %s", err, query)
+		return storeError(err)
+	}
+
+	return tx.Commit()
+}
+
+// Update implements repository.RefundRepository.
+func (r *refundRepositoryImpl) Update(refund *entity.Refund) error {
+	query := `UPDATE refunds SET status = $2, gateway_refund_id = $3, updated_at = CURRENT_TIMESTAMP WHERE id = $1`
+
+	result, err := r.db.Exec(query, refund.ID, refund.Status, refund.GatewayRefundID)
+	if err != nil {
+		log.Printf("Error updating refund with ID: %v, error: %v", refund.ID, err)
+		return storeError(err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		log.Printf("Error fetching rows affected: %v", err)
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return notFound("refund not found")
+	}
+
+	return nil
+}
+
+// GetByPaymentID implements repository.RefundRepository.
+func (r *refundRepositoryImpl) GetByPaymentID(paymentID uuid.UUID) ([]*entity.Refund, error) {
+	query := `SELECT id, payment_id, amount, currency, reason, status, gateway_refund_id, requested_by, created_at, updated_at
+	FROM refunds WHERE payment_id = $1 ORDER BY created_at`
+
+	rows, err := r.db.Query(query, paymentID)
+	if err != nil {
+		log.Printf("Error fetching refunds for payment with ID: %v, error: %v", paymentID, err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	var refunds []*entity.Refund
+	for rows.Next() {
+		var refund entity.Refund
+		err := rows.Scan(
+			&refund.ID,
+			&refund.PaymentID,
+			&refund.Amount,
+			&refund.Currency,
+			&refund.Reason,
+			&refund.Status,
+			&refund.GatewayRefundID,
+			&refund.RequestedBy,
+			&refund.CreatedAt,
+			&refund.UpdatedAt,
+		)
+		if err != nil {
+			log.Printf("Error scanning refund: %v", err)
+			return nil, err
+		}
+		refunds = append(refunds, &refund)
+	}
+
+	if err = rows.Err(); err != nil {
+		log.Printf("Error iterating over refunds: %v", err)
+		return nil, err
+	}
+
+	return refunds, nil
+}
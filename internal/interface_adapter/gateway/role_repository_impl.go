@@ -1 +1,64 @@
 package gateway
+
+import (
+	"dalabio/internal/repository"
+	"database/sql"
+	"log"
+
+	"github.com/gofrs/uuid"
+)
+
+// roleRepositoryImpl is the implementation of RoleRepository.
+type roleRepositoryImpl struct {
+	db *sql.DB
+}
+
+// NewRoleRepository creates a new instance of RoleRepository.
+func NewRoleRepository(db *sql.DB) repository.RoleRepository {
+	return &roleRepositoryImpl{db: db}
+}
+
+// GetRoleNamesByUserID implements repository.RoleRepository.
+func (r *roleRepositoryImpl) GetRoleNamesByUserID(userID uuid.UUID) ([]string, error) {
+	query := `SELECT roles.name FROM roles JOIN user_roles ON user_roles.role_id = roles.id WHERE user_roles.user_id = $1`
+	rows, err := r.db.Query(query, userID)
+	if err != nil {
+		log.Printf("Error fetching roles of user: %v, error: %v", userID, err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	var names []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			log.Printf("Error scanning role: %v", err)
+			return nil, err
+		}
+		names = append(names, name)
+	}
+
+	return names, rows.Err()
+}
+
+// RequiresTwoFactor implements repository.RoleRepository.
+func (r *roleRepositoryImpl) RequiresTwoFactor(userID uuid.UUID) (bool, error) {
+	query := `SELECT EXISTS (SELECT 1 FROM roles JOIN user_roles ON user_roles.role_id = roles.id
+	WHERE user_roles.user_id = $1 AND roles.require_two_factor)`
+	var required bool
+	if err := r.db.QueryRow(query, userID).Scan(&required); err != nil {
+		log.Printf("Error checking two-factor requirement of user: %v, error: %v", userID, err)
+		return false, err
+	}
+	return required, nil
+}
+
+// SetTwoFactorRequired implements repository.RoleRepository.
+func (r *roleRepositoryImpl) SetTwoFactorRequired(role string, required bool) error {
+	result, err := r.db.Exec(`UPDATE roles SET require_two_factor = $2 WHERE name = $1`, role, required)
+	if err != nil {
+		log.Printf("Error updating two-factor requirement of role %s: %v", role, err)
+		return storeError(err)
+	}
+	return expectRow(result, "role not found")
+}
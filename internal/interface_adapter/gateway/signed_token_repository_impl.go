@@ -0,0 +1,48 @@
+package gateway
+
+import (
+	"dalabio/internal/entity"
+	"dalabio/internal/repository"
+	"dalabio/pkg/jwtauth"
+
+	"github.com/gofrs/uuid"
+)
+
+// signedTokenRepositoryImpl verifies signed access tokens instead of looking them up, and keeps the refresh
+// tokens in the database.
+type signedTokenRepositoryImpl struct {
+	repository.TokenRepository
+	keys *jwtauth.KeySet
+}
+
+// NewSignedTokenRepository creates a TokenRepository finding signed access tokens without a database round trip.
+// The other methods manage the refresh tokens stored by refreshTokens.
+func NewSignedTokenRepository(refreshTokens repository.TokenRepository, keys *jwtauth.KeySet) repository.TokenRepository {
+	return &signedTokenRepositoryImpl{TokenRepository: refreshTokens, keys: keys}
+}
+
+// FindByToken implements repository.TokenRepository. Refresh tokens are not access tokens and are never found.
+func (r *signedTokenRepositoryImpl) FindByToken(token string) (*entity.Token, error) {
+	claims, err := r.keys.Parse(token)
+	if err != nil {
+		return nil, notFound("token not found")
+	}
+	userID, err := uuid.FromString(claims.Subject)
+	if err != nil {
+		return nil, notFound("token not found")
+	}
+	sessionID, _ := uuid.FromString(claims.SessionID)
+
+	roles := claims.Roles
+	if roles == nil {
+		roles = []string{}
+	}
+	return &entity.Token{
+		ID:        sessionID,
+		UserID:    userID,
+		Token:     token,
+		ExpiresAt: claims.ExpiresAt.Time,
+		CreatedAt: claims.IssuedAt.Time,
+		Roles:     roles,
+	}, nil
+}
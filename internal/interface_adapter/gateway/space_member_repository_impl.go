@@ -0,0 +1,276 @@
+package gateway
+
+import (
+	"dalabio/internal/entity"
+	"dalabio/internal/repository"
+	"database/sql"
+	"log"
+	"time"
+
+	"github.com/gofrs/uuid"
+)
+
+type spaceMemberRepositoryImpl struct {
+	db *sql.DB
+}
+
+// NewSpaceMemberRepository creates a new instance of SpaceMemberRepository.
+func NewSpaceMemberRepository(db *sql.DB) repository.SpaceMemberRepository {
+	return &spaceMemberRepositoryImpl{db: db}
+}
+
+const spaceInvitationColumns = `id, space_id, token, role, max_uses, use_count, created_by, expires_at, revoked_at, created_at`
+
+// scanSpaceInvitation scans a row selected with spaceInvitationColumns
+func scanSpaceInvitation(row interface{ Scan(...interface{}) error }) (*entity.SpaceInvitation, error) {
+	var invitation entity.SpaceInvitation
+	err := row.Scan(
+		&invitation.ID,
+		&invitation.SpaceID,
+		&invitation.Token,
+		&invitation.Role,
+		&invitation.MaxUses,
+		&invitation.UseCount,
+		&invitation.CreatedBy,
+		&invitation.ExpiresAt,
+		&invitation.RevokedAt,
+		&invitation.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &invitation, nil
+}
+
+// Add implements repository.SpaceMemberRepository.
+func (r *spaceMemberRepositoryImpl) Add(member *entity.SpaceMember) error {
+	member.JoinedAt = time.Now()
+
+	query := `INSERT INTO space_members (space_id, user_id, role, invited_by, joined_at) VALUES ($1, $2, $3, $4, $5)
+	ON CONFLICT (space_id, user_id) DO NOTHING`
+	if _, err := r.db.Exec(query, member.SpaceID, member.UserID, member.Role, member.InvitedBy, member.JoinedAt); err != nil {
+		log.Printf("Error inserting space member: %v, query: %s", err, query)
+		return storeError(err)
+	}
+
+	return nil
+}
+
+// Remove implements repository.SpaceMemberRepository.
+func (r *spaceMemberRepositoryImpl) Remove(spaceID, userID uuid.UUID) error {
+	result, err := r.db.Exec(`DELETE FROM space_members WHERE space_id = $1 AND user_id = $2`, spaceID, userID)
+	if err != nil {
+		log.Printf("Error removing member %v from space %v: %v", userID, spaceID, err)
+		return storeError(err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return notFound("space member not found")
+	}
+
+	return nil
+}
+
+// UpdateRole implements repository.SpaceMemberRepository.
+func (r *spaceMemberRepositoryImpl) UpdateRole(spaceID, userID uuid.UUID, role string) error {
+	result, err := r.db.Exec(`UPDATE space_members SET role = $3 WHERE space_id = $1 AND user_id = $2`, spaceID, userID, role)
+	if err != nil {
+		log.Printf("Error updating role of member %v in space %v: %v", userID, spaceID, err)
+		return storeError(err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return notFound("space member not found")
+	}
+
+	return nil
+}
+
+// Get implements repository.SpaceMemberRepository.
+func (r *spaceMemberRepositoryImpl) Get(spaceID, userID uuid.UUID) (*entity.SpaceMember, error) {
+	var member entity.SpaceMember
+	query := `SELECT space_id, user_id, role, invited_by, joined_at FROM space_members WHERE space_id = $1 AND user_id = $2`
+	err := r.db.QueryRow(query, spaceID, userID).Scan(&member.SpaceID, &member.UserID, &member.Role, &member.InvitedBy, &member.JoinedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, notFound("space member not found")
+		}
+		log.Printf("Error fetching member %v of space %v: %v", userID, spaceID, err)
+		return nil, err
+	}
+
+	return &member, nil
+}
+
+// GetBySpaceID implements repository.SpaceMemberRepository.
+func (r *spaceMemberRepositoryImpl) GetBySpaceID(spaceID uuid.UUID) ([]*entity.SpaceMember, error) {
+	query := `SELECT m.space_id, m.user_id, u.username, m.role, m.invited_by, m.joined_at
+	FROM space_members m JOIN users u ON u.id = m.user_id
+	WHERE m.space_id = $1
+	ORDER BY CASE m.role WHEN 'owner' THEN 0 WHEN 'co_coach' THEN 1 ELSE 2 END, m.joined_at`
+	rows, err := r.db.Query(query, spaceID)
+	if err != nil {
+		log.Printf("Error fetching members of space %v: %v", spaceID, err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	var members []*entity.SpaceMember
+	for rows.Next() {
+		var member entity.SpaceMember
+		if err := rows.Scan(&member.SpaceID, &member.UserID, &member.Username, &member.Role, &member.InvitedBy, &member.JoinedAt); err != nil {
+			log.Printf("Error scanning space member: %v", err)
+			return nil, err
+		}
+		members = append(members, &member)
+	}
+
+	if err = rows.Err(); err != nil {
+		log.Printf("Error iterating over space members: %v", err)
+		return nil, err
+	}
+
+	return members, nil
+}
+
+// ShareSpace implements repository.SpaceMemberRepository.
+func (r *spaceMemberRepositoryImpl) ShareSpace(userID, otherID uuid.UUID) (bool, error) {
+	var shared bool
+	query := `SELECT EXISTS (SELECT 1 FROM space_members a JOIN space_members b ON b.space_id = a.space_id
+	WHERE a.user_id = $1 AND b.user_id = $2)`
+	if err := r.db.QueryRow(query, userID, otherID).Scan(&shared); err != nil {
+		log.Printf("Error checking common spaces of %v and %v: %v", userID, otherID, err)
+		return false, err
+	}
+	return shared, nil
+}
+
+// CreateInvitation implements repository.SpaceMemberRepository.
+func (r *spaceMemberRepositoryImpl) CreateInvitation(invitation *entity.SpaceInvitation) error {
+	invitation.CreatedAt = time.Now()
+
+	query := `INSERT INTO space_invitations (` + spaceInvitationColumns + `) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)`
+	_, err := r.db.Exec(query, invitation.ID, invitation.SpaceID, invitation.Token, invitation.Role, invitation.MaxUses, invitation.UseCount,
+		invitation.CreatedBy, invitation.ExpiresAt, invitation.RevokedAt, invitation.CreatedAt)
+	if err != nil {
+		log.Printf("Error inserting space invitation: %v, query: %s", err, query)
+		return storeError(err)
+	}
+
+	return nil
+}
+
+// GetInvitationsBySpaceID implements repository.SpaceMemberRepository.
+func (r *spaceMemberRepositoryImpl) GetInvitationsBySpaceID(spaceID uuid.UUID) ([]*entity.SpaceInvitation, error) {
+	rows, err := r.db.Query(`SELECT `+spaceInvitationColumns+` FROM space_invitations WHERE space_id = $1 ORDER BY created_at DESC`, spaceID)
+	if err != nil {
+		log.Printf("Error fetching invitations of space %v: %v", spaceID, err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	var invitations []*entity.SpaceInvitation
+	for rows.Next() {
+		invitation, err := scanSpaceInvitation(rows)
+		if err != nil {
+			log.Printf("Error scanning space invitation: %v", err)
+			return nil, err
+		}
+		invitations = append(invitations, invitation)
+	}
+
+	if err = rows.Err(); err != nil {
+		log.Printf("Error iterating over space invitations: %v", err)
+		return nil, err
+	}
+
+	return invitations, nil
+}
+
+// RevokeInvitation implements repository.SpaceMemberRepository.
+func (r *spaceMemberRepositoryImpl) RevokeInvitation(spaceID, invitationID uuid.UUID) error {
+	result, err := r.db.Exec(`UPDATE space_invitations SET revoked_at = CURRENT_TIMESTAMP WHERE id = $1 AND space_id = $2 AND revoked_at IS NULL`, invitationID, spaceID)
+	if err != nil {
+		log.Printf("Error revoking space invitation %v: %v", invitationID, err)
+		return storeError(err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return notFound("space invitation not found")
+	}
+
+	return nil
+}
+
+// RedeemInvitation implements repository.SpaceMemberRepository.
+func (r *spaceMemberRepositoryImpl) RedeemInvitation(spaceID uuid.UUID, token string, userID uuid.UUID) (*entity.SpaceMember, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		log.Printf("Error starting invitation transaction: %v", err)
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	invitation, err := scanSpaceInvitation(tx.QueryRow(`SELECT `+spaceInvitationColumns+` FROM space_invitations WHERE space_id = $1 AND token = $2 FOR UPDATE`, spaceID, token))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, notFound("invitation not found")
+		}
+		log.Printf("Error locking space invitation: %v", err)
+		return nil, err
+	}
+
+	switch {
+	case invitation.RevokedAt != nil:
+		return nil, conflict("invitation has been revoked")
+	case time.Now().After(invitation.ExpiresAt):
+		return nil, conflict("invitation has expired")
+	case invitation.MaxUses > 0 && invitation.UseCount >= invitation.MaxUses:
+		return nil, conflict("invitation has already been used")
+	}
+
+	member := &entity.SpaceMember{
+		SpaceID:   spaceID,
+		UserID:    userID,
+		Role:      invitation.Role,
+		InvitedBy: &invitation.CreatedBy,
+		JoinedAt:  time.Now(),
+	}
+
+	result, err := tx.Exec(`INSERT INTO space_members (space_id, user_id, role, invited_by, joined_at) VALUES ($1, $2, $3, $4, $5)
+	ON CONFLICT (space_id, user_id) DO NOTHING`, member.SpaceID, member.UserID, member.Role, member.InvitedBy, member.JoinedAt)
+	if err != nil {
+		log.Printf("Error inserting space member: %v", err)
+		return nil, storeError(err)
+	}
+
+	// Existing members do not use up the invitation
+	if rowsAffected, err := result.RowsAffected(); err != nil {
+		return nil, err
+	} else if rowsAffected == 0 {
+		return nil, conflict("user is already a member of the space")
+	}
+
+	if _, err := tx.Exec(`UPDATE space_invitations SET use_count = use_count + 1 WHERE id = $1`, invitation.ID); err != nil {
+		log.Printf("Error counting use of space invitation %v: %v", invitation.ID, err)
+		return nil, storeError(err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return member, nil
+}
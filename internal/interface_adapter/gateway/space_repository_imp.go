@@ -4,7 +4,6 @@ import (
 	"dalabio/internal/entity"
 	"dalabio/internal/repository"
 	"database/sql"
-	"fmt"
 	"log"
 	"time"
 
@@ -15,6 +14,15 @@ type spaceRepositoryImpl struct {
 	db *sql.DB
 }
 
+// spaceMemberCount selects the number of members of a space from space_members
+const spaceMemberCount = `(SELECT COUNT(*) FROM space_members m WHERE m.space_id = spaces.id)`
+
+// spaceSessionCount selects the number of meetings hosted in a space
+const spaceSessionCount = `(SELECT COUNT(*) FROM meetings mt WHERE mt.space_id = spaces.id AND mt.deleted_at IS NULL)`
+
+// spaceCourseCount selects the number of courses offered in a space
+const spaceCourseCount = `(SELECT COUNT(*) FROM courses c WHERE c.space_id = spaces.id AND c.deleted_at IS NULL)`
+
 func NewSpaceRepository(db *sql.DB) repository.SpaceRepository {
 	return &spaceRepositoryImpl{db: db}
 }
@@ -24,13 +32,13 @@ func (r *spaceRepositoryImpl) Create(space *entity.Space) error {
 
 	// Prepare the SQL statement
 
-	query := `INSERT INTO spaces (id, name, description, coach_id, member_count, session_count, course_count, active, created_at, updated_at)
-	   VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)`
+	query := `INSERT INTO spaces (id, name, description, coach_id, active, membership_price, currency, created_at, updated_at)
+	   VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)`
 
-	result, err := r.db.Exec(query, space.ID, space.Name, space.Description, space.CoachID, space.MemberCount, space.SessionCount, space.CourseCount, space.Active, time.Now(), time.Now())
+	result, err := r.db.Exec(query, space.ID, space.Name, space.Description, space.CoachID, space.Active, space.MembershipPrice, space.Currency, time.Now(), time.Now())
 	if err != nil {
 		log.Printf("Error inserting course: %v, query: %s", err, query)
-		return err
+		return storeError(err)
 	}
 
 	rowsAffected, err := result.RowsAffected()
@@ -52,7 +60,7 @@ func (r *spaceRepositoryImpl) Delete(spaceID uuid.UUID) error {
 	result, err := r.db.Exec(query, spaceID)
 	if err != nil {
 		log.Printf("Error deleting course with ID: %v, error: %v", spaceID, err)
-		return err
+		return storeError(err)
 	}
 	rowsAffected, err := result.RowsAffected()
 	if err != nil {
@@ -75,13 +83,13 @@ func (r *spaceRepositoryImpl) GetdByID(spaceID uuid.UUID) (*entity.Space, error)
 	var space = entity.Space{}
 
 	// Define the Space entity to store the result
-	err := r.db.QueryRow(`	SELECT id, name, description, coach_id, member_count, session_count, course_count, active, created_at, updated_at
-	FROM spaces WHERE id = $1`, spaceID).Scan(&space.ID, &space.Name, &space.Description, &space.CoachID, &space.MemberCount, &space.SessionCount, &space.CourseCount, &space.Active, &space.CreatedAt, &space.UpdatedAt)
+	err := r.db.QueryRow(`	SELECT id, name, description, coach_id, `+spaceMemberCount+`, `+spaceSessionCount+`, `+spaceCourseCount+`, active, membership_price, currency, created_at, updated_at
+	FROM spaces WHERE id = $1`, spaceID).Scan(&space.ID, &space.Name, &space.Description, &space.CoachID, &space.MemberCount, &space.SessionCount, &space.CourseCount, &space.Active, &space.MembershipPrice, &space.Currency, &space.CreatedAt, &space.UpdatedAt)
 
 	if err != nil {
 		if err == sql.ErrNoRows {
 			log.Printf("No course found with ID: %v", spaceID)
-			return nil, fmt.Errorf("course Not Found")
+			return nil, notFound("space not found")
 		}
 		log.Printf("Error retrieving course by ID: %v", err)
 	}
@@ -95,8 +103,8 @@ func (r *spaceRepositoryImpl) GetAll() ([]*entity.Space, error) {
 
 	// Prepare the SQL statement
 	query := `
-		SELECT id, name, description, coach_id, member_count, session_count, 
-		       course_count, active, created_at, updated_at 
+		SELECT id, name, description, coach_id, ` + spaceMemberCount + `, ` + spaceSessionCount + `, 
+		       ` + spaceCourseCount + `, active, membership_price, currency, created_at, updated_at 
 		FROM spaces
 	`
 
@@ -120,6 +128,8 @@ func (r *spaceRepositoryImpl) GetAll() ([]*entity.Space, error) {
 			&space.SessionCount,
 			&space.CourseCount,
 			&space.Active,
+			&space.MembershipPrice,
+			&space.Currency,
 			&space.CreatedAt,
 			&space.UpdatedAt,
 		)
@@ -145,13 +155,13 @@ func (r *spaceRepositoryImpl) Update(space *entity.Space) error {
 	// Prepare the SQL statement
 
 	query := `UPDATE spaces
-	   SET name = $1, description = $2, coach_id = $3, member_count = $4, session_count = $5, course_count = $6, active = $7, updated_at = $8
-	   WHERE id = $9`
+	   SET name = $1, description = $2, coach_id = $3, active = $4, membership_price = $5, currency = $6, updated_at = $7
+	   WHERE id = $8`
 
-	result, err := r.db.Exec(query, space.Name, space.Description, space.CoachID, space.MemberCount, space.SessionCount, space.CourseCount, space.Active, time.Now(), space.ID)
+	result, err := r.db.Exec(query, space.Name, space.Description, space.CoachID, space.Active, space.MembershipPrice, space.Currency, time.Now(), space.ID)
 	if err != nil {
 		log.Printf("Error updating course with ID: %v, error: %v", space.ID, err)
-		return err
+		return storeError(err)
 	}
 	rowsAffected, err := result.RowsAffected()
 	if err != nil {
@@ -0,0 +1,182 @@
+package gateway
+
+import (
+	"dalabio/internal/entity"
+	"dalabio/internal/repository"
+	"database/sql"
+	"log"
+	"time"
+
+	"github.com/gofrs/uuid"
+)
+
+type subscriptionRepositoryImpl struct {
+	db *sql.DB
+}
+
+// NewSubscriptionRepository creates a new instance of SubscriptionRepository.
+func NewSubscriptionRepository(db *sql.DB) repository.SubscriptionRepository {
+	return &subscriptionRepositoryImpl{db: db}
+}
+
+const subscriptionColumns = `id, user_id, plan_id, space_id, status, payment_method, payment_gateway, current_period_start, current_period_end,
+	trial_end, cancel_at_period_end, cancelled_at, failed_attempts, next_retry_at, created_at, updated_at`
+
+// scanSubscription scans a row selected with subscriptionColumns
+func scanSubscription(row interface{ Scan(...interface{}) error }) (*entity.Subscription, error) {
+	var subscription entity.Subscription
+	err := row.Scan(
+		&subscription.ID,
+		&subscription.UserID,
+		&subscription.PlanID,
+		&subscription.SpaceID,
+		&subscription.Status,
+		&subscription.PaymentMethod,
+		&subscription.PaymentGateway,
+		&subscription.CurrentPeriodStart,
+		&subscription.CurrentPeriodEnd,
+		&subscription.TrialEnd,
+		&subscription.CancelAtPeriodEnd,
+		&subscription.CancelledAt,
+		&subscription.FailedAttempts,
+		&subscription.NextRetryAt,
+		&subscription.CreatedAt,
+		&subscription.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &subscription, nil
+}
+
+// Create implements repository.SubscriptionRepository.
+func (r *subscriptionRepositoryImpl) Create(subscription *entity.Subscription) error {
+	now := time.Now()
+	subscription.CreatedAt = now
+	subscription.UpdatedAt = now
+
+	query := `INSERT INTO subscriptions (` + subscriptionColumns + `)
+	VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16)`
+	_, err := r.db.Exec(query,
+		subscription.ID,
+		subscription.UserID,
+		subscription.PlanID,
+		subscription.SpaceID,
+		subscription.Status,
+		subscription.PaymentMethod,
+		subscription.PaymentGateway,
+		subscription.CurrentPeriodStart,
+		subscription.CurrentPeriodEnd,
+		subscription.TrialEnd,
+		subscription.CancelAtPeriodEnd,
+		subscription.CancelledAt,
+		subscription.FailedAttempts,
+		subscription.NextRetryAt,
+		subscription.CreatedAt,
+		subscription.UpdatedAt,
+	)
+	if err != nil {
+		log.Printf("Error inserting subscription: %v, query: %s", err, query)
+		return err
+	}
+
+	return nil
+}
+
+// Update implements repository.SubscriptionRepository.
+func (r *subscriptionRepositoryImpl) Update(subscription *entity.Subscription) error {
+	query := `UPDATE subscriptions
+	SET status = $2, current_period_start = $3, current_period_end = $4, trial_end = $5, cancel_at_period_end = $6,
+	    cancelled_at = $7, failed_attempts = $8, next_retry_at = $9, locked_until = NULL, updated_at = CURRENT_TIMESTAMP
+	WHERE id = $1`
+	result, err := r.db.Exec(query,
+		subscription.ID,
+		subscription.Status,
+		subscription.CurrentPeriodStart,
+		subscription.CurrentPeriodEnd,
+		subscription.TrialEnd,
+		subscription.CancelAtPeriodEnd,
+		subscription.CancelledAt,
+		subscription.FailedAttempts,
+		subscription.NextRetryAt,
+	)
+	if err != nil {
+		log.Printf("Error updating subscription with ID: %v, error: %v", subscription.ID, err)
+		return storeError(err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		log.Printf("Error fetching rows affected: %v", err)
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return notFound("subscription not found")
+	}
+
+	return nil
+}
+
+// GetdByID implements repository.SubscriptionRepository.
+func (r *subscriptionRepositoryImpl) GetdByID(subscriptionID uuid.UUID) (*entity.Subscription, error) {
+	query := `SELECT ` + subscriptionColumns + ` FROM subscriptions WHERE id = $1`
+	subscription, err := scanSubscription(r.db.QueryRow(query, subscriptionID))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			log.Printf("No subscription found with ID: %v", subscriptionID)
+			return nil, notFound("subscription not found")
+		}
+		log.Printf("Error fetching subscription with ID: %v, error: %v", subscriptionID, err)
+		return nil, err
+	}
+
+	return subscription, nil
+}
+
+// GetByUserID implements repository.SubscriptionRepository.
+func (r *subscriptionRepositoryImpl) GetByUserID(userID uuid.UUID) ([]*entity.Subscription, error) {
+	query := `SELECT ` + subscriptionColumns + ` FROM subscriptions WHERE user_id = $1 ORDER BY created_at DESC`
+	return r.query(query, userID)
+}
+
+// ClaimDue implements repository.SubscriptionRepository.
+func (r *subscriptionRepositoryImpl) ClaimDue(now, leaseUntil time.Time) ([]*entity.Subscription, error) {
+	query := `UPDATE subscriptions SET locked_until = $2
+	WHERE id IN (
+		SELECT id FROM subscriptions
+		WHERE ((status IN ('trialing', 'active') AND current_period_end <= $1)
+		    OR (status = 'past_due' AND next_retry_at <= $1))
+		  AND (locked_until IS NULL OR locked_until <= $1)
+		FOR UPDATE SKIP LOCKED
+	)
+	RETURNING ` + subscriptionColumns
+	return r.query(query, now, leaseUntil)
+}
+
+// query runs a select over subscriptionColumns and scans every row
+func (r *subscriptionRepositoryImpl) query(query string, args ...interface{}) ([]*entity.Subscription, error) {
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		log.Printf("Error fetching subscriptions: %v, query: %s", err, query)
+		return nil, err
+	}
+	defer rows.Close()
+
+	var subscriptions []*entity.Subscription
+	for rows.Next() {
+		subscription, err := scanSubscription(rows)
+		if err != nil {
+			log.Printf("Error scanning subscription: %v", err)
+			return nil, err
+		}
+		subscriptions = append(subscriptions, subscription)
+	}
+
+	if err = rows.Err(); err != nil {
+		log.Printf("Error iterating over subscriptions: %v", err)
+		return nil, err
+	}
+
+	return subscriptions, nil
+}
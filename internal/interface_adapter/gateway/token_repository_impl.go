@@ -4,9 +4,10 @@ import (
 	"dalabio/internal/entity"
 	"dalabio/internal/repository"
 	"database/sql"
-	"errors"
 	"log"
 	"time"
+
+	"github.com/gofrs/uuid"
 )
 
 // tokenRepositoryImpl is the implementation of TokenRepository.
@@ -14,6 +15,19 @@ type tokenRepositoryImpl struct {
 	db *sql.DB
 }
 
+// tokenColumns are the columns read into entity.Token, in the order scanToken expects
+const tokenColumns = `id, user_id, token, expires_at, created_at, updated_at, user_agent, ip, device_label, last_seen_at`
+
+// scanToken reads the tokenColumns of a row
+func scanToken(row interface{ Scan(...interface{}) error }) (*entity.Token, error) {
+	t := &entity.Token{}
+	err := row.Scan(&t.ID, &t.UserID, &t.Token, &t.ExpiresAt, &t.CreatedAt, &t.UpdatedAt, &t.UserAgent, &t.IP, &t.DeviceLabel, &t.LastSeenAt)
+	if err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
 // NewTokenRepository creates a new instance of TokenRepository.
 func NewTokenRepository(db *sql.DB) repository.TokenRepository {
 	return &tokenRepositoryImpl{db: db}
@@ -21,14 +35,13 @@ func NewTokenRepository(db *sql.DB) repository.TokenRepository {
 
 // FindByToken retrieves a token by its value.
 func (r *tokenRepositoryImpl) FindByToken(token string) (*entity.Token, error) {
-	t := &entity.Token{}
-	query := `SELECT id, user_id, token, expires_at, created_at, updated_at FROM tokens WHERE token = $1`
+	query := `SELECT ` + tokenColumns + ` FROM tokens WHERE token = $1`
 	row := r.db.QueryRow(query, token)
 
-	err := row.Scan(&t.ID, &t.UserID, &t.Token, &t.ExpiresAt, &t.CreatedAt, &t.UpdatedAt)
+	t, err := scanToken(row)
 	if err != nil {
 		if err == sql.ErrNoRows {
-			return nil, errors.New("token not found")
+			return nil, notFound("token not found")
 		}
 		return nil, err
 	}
@@ -43,7 +56,7 @@ func (r *tokenRepositoryImpl) Create(token *entity.Token) error {
 	result, err := r.db.Exec(query, token.ID, token.UserID, token.Token, token.ExpiresAt, time.Now(), time.Now())
 	if err != nil {
 		log.Printf("Error inserting token: %v", err)
-		return err
+		return storeError(err)
 	}
 
 	rowsAffected, err := result.RowsAffected()
@@ -55,3 +68,75 @@ func (r *tokenRepositoryImpl) Create(token *entity.Token) error {
 	log.Printf("Rows affected: %d", rowsAffected)
 	return nil
 }
+
+// Rotate implements repository.TokenRepository.
+func (r *tokenRepositoryImpl) Rotate(token, newToken string, expiresAt, now time.Time) (*entity.Token, error) {
+	// A single statement so that a token rotated twice at the same time is only accepted once
+	query := `UPDATE tokens SET token = $2, expires_at = $3, updated_at = $4 WHERE token = $1 AND expires_at > $4
+	RETURNING ` + tokenColumns
+
+	t, err := scanToken(r.db.QueryRow(query, token, newToken, expiresAt, now))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, notFound("token not found")
+		}
+		log.Printf("Error rotating token: %v", err)
+		return nil, err
+	}
+
+	return t, nil
+}
+
+// DeleteByUserID revokes every token of a user.
+func (r *tokenRepositoryImpl) DeleteByUserID(userID uuid.UUID) error {
+	if _, err := r.db.Exec(`DELETE FROM tokens WHERE user_id = $1`, userID); err != nil {
+		log.Printf("Error deleting tokens of user %v: %v", userID, err)
+		return storeError(err)
+	}
+	return nil
+}
+
+// ListByUserID implements repository.TokenRepository.
+func (r *tokenRepositoryImpl) ListByUserID(userID uuid.UUID, now time.Time) ([]*entity.Token, error) {
+	query := `SELECT ` + tokenColumns + ` FROM tokens WHERE user_id = $1 AND expires_at > $2
+	ORDER BY COALESCE(last_seen_at, created_at) DESC`
+	rows, err := r.db.Query(query, userID, now)
+	if err != nil {
+		log.Printf("Error fetching tokens of user %v: %v", userID, err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	tokens := []*entity.Token{}
+	for rows.Next() {
+		t, err := scanToken(rows)
+		if err != nil {
+			log.Printf("Error scanning token: %v", err)
+			return nil, err
+		}
+		tokens = append(tokens, t)
+	}
+	return tokens, rows.Err()
+}
+
+// Delete implements repository.TokenRepository.
+func (r *tokenRepositoryImpl) Delete(userID, id uuid.UUID) error {
+	result, err := r.db.Exec(`DELETE FROM tokens WHERE id = $1 AND user_id = $2`, id, userID)
+	if err != nil {
+		log.Printf("Error deleting token %v: %v", id, err)
+		return storeError(err)
+	}
+	return expectRow(result, "session not found")
+}
+
+// Touch implements repository.TokenRepository.
+func (r *tokenRepositoryImpl) Touch(id uuid.UUID, userAgent, ip, deviceLabel string, at, since time.Time) error {
+	// A device change is recorded at once, the same device at most once per interval
+	query := `UPDATE tokens SET user_agent = $2, ip = $3, device_label = $4, last_seen_at = $5
+	WHERE id = $1 AND (last_seen_at IS NULL OR last_seen_at < $6 OR user_agent <> $2 OR ip <> $3)`
+	if _, err := r.db.Exec(query, id, userAgent, ip, deviceLabel, at, since); err != nil {
+		log.Printf("Error recording use of token %v: %v", id, err)
+		return storeError(err)
+	}
+	return nil
+}
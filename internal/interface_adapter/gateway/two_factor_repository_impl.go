@@ -0,0 +1,139 @@
+package gateway
+
+import (
+	"dalabio/internal/entity"
+	"dalabio/internal/repository"
+	"database/sql"
+	"log"
+	"time"
+
+	"github.com/gofrs/uuid"
+)
+
+type twoFactorRepositoryImpl struct {
+	db *sql.DB
+}
+
+// NewTwoFactorRepository creates a new instance of TwoFactorRepository.
+func NewTwoFactorRepository(db *sql.DB) repository.TwoFactorRepository {
+	return &twoFactorRepositoryImpl{db: db}
+}
+
+// Get implements repository.TwoFactorRepository.
+func (r *twoFactorRepositoryImpl) Get(userID uuid.UUID) (*entity.TwoFactor, error) {
+	var twoFactor entity.TwoFactor
+	err := r.db.QueryRow(`SELECT user_id, secret, confirmed_at, last_used_step, created_at FROM user_two_factors WHERE user_id = $1`, userID).
+		Scan(&twoFactor.UserID, &twoFactor.Secret, &twoFactor.ConfirmedAt, &twoFactor.LastUsedStep, &twoFactor.CreatedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, notFound("two-factor authenticator not found")
+		}
+		log.Printf("Error fetching two-factor authenticator of user %v: %v", userID, err)
+		return nil, err
+	}
+	return &twoFactor, nil
+}
+
+// Save implements repository.TwoFactorRepository.
+func (r *twoFactorRepositoryImpl) Save(twoFactor *entity.TwoFactor) error {
+	twoFactor.CreatedAt = time.Now()
+
+	query := `INSERT INTO user_two_factors (user_id, secret, confirmed_at, last_used_step, created_at) VALUES ($1, $2, NULL, 0, $3)
+	ON CONFLICT (user_id) DO UPDATE SET secret = EXCLUDED.secret, confirmed_at = NULL, last_used_step = 0, created_at = EXCLUDED.created_at`
+	if _, err := r.db.Exec(query, twoFactor.UserID, twoFactor.Secret, twoFactor.CreatedAt); err != nil {
+		log.Printf("Error saving two-factor authenticator of user %v: %v", twoFactor.UserID, err)
+		return storeError(err)
+	}
+	twoFactor.ConfirmedAt = nil
+	twoFactor.LastUsedStep = 0
+	return nil
+}
+
+// Confirm implements repository.TwoFactorRepository.
+func (r *twoFactorRepositoryImpl) Confirm(userID uuid.UUID, step int64, at time.Time) error {
+	result, err := r.db.Exec(`UPDATE user_two_factors SET confirmed_at = $3, last_used_step = $2 WHERE user_id = $1 AND confirmed_at IS NULL`, userID, step, at)
+	if err != nil {
+		log.Printf("Error confirming two-factor authenticator of user %v: %v", userID, err)
+		return storeError(err)
+	}
+	return expectRow(result, "unconfirmed two-factor authenticator not found")
+}
+
+// UseStep implements repository.TwoFactorRepository.
+func (r *twoFactorRepositoryImpl) UseStep(userID uuid.UUID, step int64) error {
+	// A single statement so that a code sent twice at the same time is only accepted once
+	result, err := r.db.Exec(`UPDATE user_two_factors SET last_used_step = $2 WHERE user_id = $1 AND last_used_step < $2`, userID, step)
+	if err != nil {
+		log.Printf("Error recording two-factor code of user %v: %v", userID, err)
+		return storeError(err)
+	}
+	return expectRow(result, "two-factor code already used")
+}
+
+// Delete implements repository.TwoFactorRepository.
+func (r *twoFactorRepositoryImpl) Delete(userID uuid.UUID) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`DELETE FROM recovery_codes WHERE user_id = $1`, userID); err != nil {
+		log.Printf("Error deleting recovery codes of user %v: %v", userID, err)
+		return storeError(err)
+	}
+	result, err := tx.Exec(`DELETE FROM user_two_factors WHERE user_id = $1`, userID)
+	if err != nil {
+		log.Printf("Error deleting two-factor authenticator of user %v: %v", userID, err)
+		return storeError(err)
+	}
+	if err := expectRow(result, "two-factor authenticator not found"); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// ReplaceRecoveryCodes implements repository.TwoFactorRepository.
+func (r *twoFactorRepositoryImpl) ReplaceRecoveryCodes(userID uuid.UUID, codes []*entity.RecoveryCode) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`DELETE FROM recovery_codes WHERE user_id = $1`, userID); err != nil {
+		log.Printf("Error deleting recovery codes of user %v: %v", userID, err)
+		return storeError(err)
+	}
+	for _, code := range codes {
+		code.CreatedAt = time.Now()
+		if _, err := tx.Exec(`INSERT INTO recovery_codes (id, user_id, code_hash, created_at) VALUES ($1, $2, $3, $4)`,
+			code.ID, userID, code.CodeHash, code.CreatedAt); err != nil {
+			log.Printf("Error inserting recovery code of user %v: %v", userID, err)
+			return storeError(err)
+		}
+	}
+
+	return tx.Commit()
+}
+
+// UseRecoveryCode implements repository.TwoFactorRepository.
+func (r *twoFactorRepositoryImpl) UseRecoveryCode(userID uuid.UUID, codeHash string, at time.Time) error {
+	result, err := r.db.Exec(`UPDATE recovery_codes SET used_at = $3 WHERE user_id = $1 AND code_hash = $2 AND used_at IS NULL`, userID, codeHash, at)
+	if err != nil {
+		log.Printf("Error using recovery code of user %v: %v", userID, err)
+		return storeError(err)
+	}
+	return expectRow(result, "recovery code not found")
+}
+
+// CountRecoveryCodes implements repository.TwoFactorRepository.
+func (r *twoFactorRepositoryImpl) CountRecoveryCodes(userID uuid.UUID) (int, error) {
+	var count int
+	if err := r.db.QueryRow(`SELECT COUNT(*) FROM recovery_codes WHERE user_id = $1 AND used_at IS NULL`, userID).Scan(&count); err != nil {
+		log.Printf("Error counting recovery codes of user %v: %v", userID, err)
+		return 0, err
+	}
+	return count, nil
+}
@@ -0,0 +1,141 @@
+package gateway
+
+import (
+	"dalabio/internal/entity"
+	"dalabio/internal/repository"
+	"database/sql"
+	"log"
+	"time"
+
+	"github.com/gofrs/uuid"
+)
+
+type userIdentityRepositoryImpl struct {
+	db *sql.DB
+}
+
+// NewUserIdentityRepository creates a new instance of UserIdentityRepository.
+func NewUserIdentityRepository(db *sql.DB) repository.UserIdentityRepository {
+	return &userIdentityRepositoryImpl{db: db}
+}
+
+const userIdentityColumns = `id, user_id, provider, subject, email, last_login_at, created_at`
+
+func scanUserIdentity(scanner interface{ Scan(...interface{}) error }) (*entity.UserIdentity, error) {
+	var identity entity.UserIdentity
+	err := scanner.Scan(&identity.ID, &identity.UserID, &identity.Provider, &identity.Subject, &identity.Email,
+		&identity.LastLoginAt, &identity.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &identity, nil
+}
+
+// Create implements repository.UserIdentityRepository.
+func (r *userIdentityRepositoryImpl) Create(identity *entity.UserIdentity) error {
+	id, err := uuid.NewV4()
+	if err != nil {
+		return err
+	}
+	identity.ID = id
+	identity.CreatedAt = time.Now()
+
+	query := `INSERT INTO user_identities (` + userIdentityColumns + `) VALUES ($1, $2, $3, $4, $5, $6, $7)`
+	if _, err := r.db.Exec(query, identity.ID, identity.UserID, identity.Provider, identity.Subject, identity.Email,
+		identity.LastLoginAt, identity.CreatedAt); err != nil {
+		log.Printf("Error inserting user identity: %v", err)
+		return storeError(err)
+	}
+	return nil
+}
+
+// FindByProviderSubject implements repository.UserIdentityRepository.
+func (r *userIdentityRepositoryImpl) FindByProviderSubject(provider, subject string) (*entity.UserIdentity, error) {
+	row := r.db.QueryRow(`SELECT `+userIdentityColumns+` FROM user_identities WHERE provider = $1 AND subject = $2`, provider, subject)
+	identity, err := scanUserIdentity(row)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, notFound("identity not found")
+		}
+		log.Printf("Error fetching %s identity: %v", provider, err)
+		return nil, err
+	}
+	return identity, nil
+}
+
+// ListByUserID implements repository.UserIdentityRepository.
+func (r *userIdentityRepositoryImpl) ListByUserID(userID uuid.UUID) ([]*entity.UserIdentity, error) {
+	rows, err := r.db.Query(`SELECT `+userIdentityColumns+` FROM user_identities WHERE user_id = $1 ORDER BY created_at`, userID)
+	if err != nil {
+		log.Printf("Error fetching identities of user %v: %v", userID, err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	identities := []*entity.UserIdentity{}
+	for rows.Next() {
+		identity, err := scanUserIdentity(rows)
+		if err != nil {
+			log.Printf("Error scanning user identity: %v", err)
+			return nil, err
+		}
+		identities = append(identities, identity)
+	}
+	return identities, rows.Err()
+}
+
+// RecordLogin implements repository.UserIdentityRepository.
+func (r *userIdentityRepositoryImpl) RecordLogin(id uuid.UUID, email string, at time.Time) error {
+	result, err := r.db.Exec(`UPDATE user_identities SET email = $2, last_login_at = $3 WHERE id = $1`, id, email, at)
+	if err != nil {
+		log.Printf("Error recording login with identity %v: %v", id, err)
+		return storeError(err)
+	}
+	return expectRow(result, "identity not found")
+}
+
+// Delete implements repository.UserIdentityRepository.
+func (r *userIdentityRepositoryImpl) Delete(userID, id uuid.UUID) error {
+	result, err := r.db.Exec(`DELETE FROM user_identities WHERE id = $1 AND user_id = $2`, id, userID)
+	if err != nil {
+		log.Printf("Error deleting identity %v: %v", id, err)
+		return storeError(err)
+	}
+	return expectRow(result, "identity not found")
+}
+
+// CreateState implements repository.UserIdentityRepository.
+func (r *userIdentityRepositoryImpl) CreateState(state *entity.OIDCState) error {
+	state.CreatedAt = time.Now()
+
+	query := `INSERT INTO oidc_states (state_hash, provider, nonce, code_verifier, expires_at, created_at) VALUES ($1, $2, $3, $4, $5, $6)`
+	if _, err := r.db.Exec(query, state.StateHash, state.Provider, state.Nonce, state.CodeVerifier, state.ExpiresAt, state.CreatedAt); err != nil {
+		log.Printf("Error inserting OIDC state: %v", err)
+		return storeError(err)
+	}
+
+	// Sign-ins abandoned at the provider are never consumed
+	if _, err := r.db.Exec(`DELETE FROM oidc_states WHERE expires_at < $1`, state.CreatedAt); err != nil {
+		log.Printf("Error deleting expired OIDC states: %v", err)
+	}
+	return nil
+}
+
+// ConsumeState implements repository.UserIdentityRepository.
+func (r *userIdentityRepositoryImpl) ConsumeState(provider, stateHash string, now time.Time) (*entity.OIDCState, error) {
+	// A single statement so that a state sent back twice at the same time is only accepted once
+	query := `DELETE FROM oidc_states WHERE provider = $1 AND state_hash = $2 AND expires_at > $3
+	RETURNING state_hash, provider, nonce, code_verifier, expires_at, created_at`
+
+	var state entity.OIDCState
+	err := r.db.QueryRow(query, provider, stateHash, now).Scan(&state.StateHash, &state.Provider, &state.Nonce,
+		&state.CodeVerifier, &state.ExpiresAt, &state.CreatedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, notFound("sign-in state not found")
+		}
+		log.Printf("Error consuming OIDC state: %v", err)
+		return nil, err
+	}
+	return &state, nil
+}
@@ -4,12 +4,11 @@ import (
 	"dalabio/internal/entity"
 	"dalabio/internal/repository"
 	"database/sql"
-	"errors"
-	"fmt"
 	"log"
 	"time"
 
 	"github.com/gofrs/uuid"
+	"github.com/lib/pq"
 )
 
 // userRepositoryImpl is the implementation of UserRepository.
@@ -17,12 +16,24 @@ type userRepositoryImpl struct {
 	db *sql.DB
 }
 
-// Delete implements repository.UserRepository.
 // NewUserRepository creates a new instance of UserRepositoryImpl.
 func NewUserRepository(db *sql.DB) repository.UserRepository {
 	return &userRepositoryImpl{db: db}
 }
 
+const userColumns = `id, username, email, password, first_name, last_name, is_active, locale, email_verified_at, last_login, created_at, updated_at`
+
+func scanUser(row interface{ Scan(...interface{}) error }) (*entity.User, error) {
+	var user entity.User
+	var lastLogin sql.NullTime // Users who never signed in have none
+	if err := row.Scan(&user.ID, &user.Username, &user.Email, &user.Password, &user.FirstName, &user.LastName,
+		&user.IsActive, &user.Locale, &user.EmailVerifiedAt, &lastLogin, &user.CreatedAt, &user.UpdatedAt); err != nil {
+		return nil, err
+	}
+	user.LastLogin = lastLogin.Time
+	return &user, nil
+}
+
 // Create inserts a new user into the database.
 
 func (r *userRepositoryImpl) Create(user *entity.User) error {
@@ -34,16 +45,16 @@ func (r *userRepositoryImpl) Create(user *entity.User) error {
 	}
 	user.ID = newUUID
 
-	log.Printf("Inserting User: ID=%s, Username=%s, Email=%s, Password=%s, FirstName=%s, LastName=%s, IsActive=%v\n",
-		user.ID, user.Username, user.Email, user.Password, user.FirstName, user.LastName, user.IsActive)
+	log.Printf("Inserting User: ID=%s, Username=%s, Email=%s, FirstName=%s, LastName=%s, IsActive=%v\n",
+		user.ID, user.Username, user.Email, user.FirstName, user.LastName, user.IsActive)
 
-	query := `INSERT INTO users (id, username, email, password, first_name, last_name, is_active, created_at, updated_at)
-              VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)`
+	query := `INSERT INTO users (id, username, email, password, first_name, last_name, is_active, locale, created_at, updated_at)
+              VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)`
 
-	result, err := r.db.Exec(query, user.ID, user.Username, user.Email, user.Password, user.FirstName, user.LastName, user.IsActive, time.Now(), time.Now())
+	result, err := r.db.Exec(query, user.ID, user.Username, user.Email, user.Password, user.FirstName, user.LastName, user.IsActive, user.Locale, time.Now(), time.Now())
 	if err != nil {
 		log.Printf("Error inserting user: %v", err)
-		return err
+		return storeError(err)
 	}
 
 	rowsAffected, err := result.RowsAffected()
@@ -54,17 +65,13 @@ func (r *userRepositoryImpl) Create(user *entity.User) error {
 		log.Printf("Rows affected: %d", rowsAffected)
 	}
 
-	var insertedUser entity.User
-	err = r.db.QueryRow(`SELECT id, username, email, password, first_name, last_name, is_active, created_at, updated_at
-                         FROM users WHERE email = $1`, user.Email).Scan(
-		&insertedUser.ID, &insertedUser.Username, &insertedUser.Email, &insertedUser.Password,
-		&insertedUser.FirstName, &insertedUser.LastName, &insertedUser.IsActive, &insertedUser.CreatedAt, &insertedUser.UpdatedAt)
+	insertedUser, err := scanUser(r.db.QueryRow(`SELECT `+userColumns+` FROM users WHERE email = $1`, user.Email))
 	if err != nil {
 		log.Printf("Error retrieving inserted user: %v", err)
 		return err
 	}
 
-	log.Printf("Inserted User: %+v", insertedUser)
+	log.Printf("Inserted User: ID=%s, CreatedAt=%s", insertedUser.ID, insertedUser.CreatedAt)
 
 	return nil
 }
@@ -73,14 +80,16 @@ func (r *userRepositoryImpl) Create(user *entity.User) error {
 func (r *userRepositoryImpl) Update(user *entity.User) error {
 	// Define the SQL update query
 	query := `UPDATE users
-			  SET username = $1, email = $2, password = $3, first_name = $4, last_name = $5, is_active = $6, updated_at = $7
-			  WHERE id = $8`
+			  SET username = $1, email = $2, password = $3, first_name = $4, last_name = $5, is_active = $6, updated_at = $7,
+			      email_verified_at = $8, locale = $9
+			  WHERE id = $10`
 
 	// Execute the update query with the user data
-	result, err := r.db.Exec(query, user.Username, user.Email, user.Password, user.FirstName, user.LastName, user.IsActive, time.Now(), user.ID)
+	result, err := r.db.Exec(query, user.Username, user.Email, user.Password, user.FirstName, user.LastName, user.IsActive, time.Now(),
+		user.EmailVerifiedAt, user.Locale, user.ID)
 	if err != nil {
 		log.Printf("Error updating user with ID: %v, error: %v", user.ID, err)
-		return err
+		return storeError(err)
 	}
 
 	// Check how many rows were affected by the update
@@ -93,7 +102,7 @@ func (r *userRepositoryImpl) Update(user *entity.User) error {
 	// If no rows were affected, it means the user was not found
 	if rowsAffected == 0 {
 		log.Printf("No user found with ID: %v", user.ID)
-		return fmt.Errorf("user not found")
+		return notFound("user not found")
 	}
 
 	log.Printf("Updated user with ID: %v", user.ID)
@@ -109,7 +118,7 @@ func (r *userRepositoryImpl) Delete(userID uuid.UUID) error {
 	result, err := r.db.Exec(query, userID)
 	if err != nil {
 		log.Printf("Error deleting user with ID: %v, error: %v", userID, err)
-		return err
+		return storeError(err)
 	}
 
 	// Check how many rows were affected by the delete
@@ -122,7 +131,7 @@ func (r *userRepositoryImpl) Delete(userID uuid.UUID) error {
 	// If no rows were affected, it means the user was not found
 	if rowsAffected == 0 {
 		log.Printf("No user found with ID: %v", userID)
-		return fmt.Errorf("user not found")
+		return notFound("user not found")
 	}
 
 	log.Printf("Deleted user with ID: %v", userID)
@@ -131,39 +140,30 @@ func (r *userRepositoryImpl) Delete(userID uuid.UUID) error {
 
 // FindByID finds a user by their ID.
 func (r *userRepositoryImpl) FindByID(userID uuid.UUID) (*entity.User, error) {
-	// Define the user entity to store the result
-	var user entity.User
-
 	// Fetch the user from the database using the provided ID
-	err := r.db.QueryRow(`SELECT id, username, email, password, first_name, last_name, is_active, created_at, updated_at
-						 FROM users WHERE id = $1`, userID).Scan(
-		&user.ID, &user.Username, &user.Email, &user.Password,
-		&user.FirstName, &user.LastName, &user.IsActive, &user.CreatedAt, &user.UpdatedAt)
+	user, err := scanUser(r.db.QueryRow(`SELECT `+userColumns+` FROM users WHERE id = $1`, userID))
 
 	// Check for errors in retrieving the user
 	if err != nil {
 		if err == sql.ErrNoRows {
 			log.Printf("No user found with ID: %v", userID)
-			return nil, fmt.Errorf("user not found")
+			return nil, notFound("user not found")
 		}
 		log.Printf("Error retrieving user by ID: %v", err)
 		return nil, err
 	}
 
 	// Return the user if found
-	return &user, nil
+	return user, nil
 }
 
 // FindByEmail finds a user by their email.
 func (r *userRepositoryImpl) FindByEmail(email string) (*entity.User, error) {
-	user := &entity.User{}
-	query := "SELECT id, username, email, password, first_name, last_name, is_active, created_at, updated_at FROM users WHERE email = $1"
-	row := r.db.QueryRow(query, email)
-
-	err := row.Scan(&user.ID, &user.Username, &user.Email, &user.Password, &user.FirstName, &user.LastName, &user.IsActive, &user.CreatedAt, &user.UpdatedAt)
+	query := "SELECT " + userColumns + " FROM users WHERE email = $1"
+	user, err := scanUser(r.db.QueryRow(query, email))
 	if err != nil {
 		if err == sql.ErrNoRows {
-			return nil, errors.New("user not found")
+			return nil, notFound("user not found")
 		}
 		return nil, err
 	}
@@ -175,7 +175,7 @@ func (r *userRepositoryImpl) FindByEmail(email string) (*entity.User, error) {
 func (r *userRepositoryImpl) ListAll() ([]*entity.User, error) {
 
 	// Fetch all users from the database
-	rows, err := r.db.Query("SELECT id, username, email, password, first_name, last_name, is_active, created_at, updated_at FROM users")
+	rows, err := r.db.Query("SELECT " + userColumns + " FROM users")
 	if err != nil {
 		return nil, err
 	}
@@ -184,15 +184,69 @@ func (r *userRepositoryImpl) ListAll() ([]*entity.User, error) {
 	// Scan the rows into a slice of User structs
 	var users []*entity.User
 	for rows.Next() {
-		var user entity.User
-		err := rows.Scan(&user.ID, &user.Username, &user.Email, &user.Password, &user.FirstName, &user.LastName, &user.IsActive, &user.CreatedAt, &user.UpdatedAt)
+		user, err := scanUser(rows)
 		if err != nil {
 			return nil, err
 		}
-		users = append(users, &user)
+		users = append(users, user)
 	}
 
 	// Check for any errors during the scan
 	return users, nil
 
 }
+
+// FindByUsernames finds the users having one of the given usernames.
+func (r *userRepositoryImpl) FindByUsernames(usernames []string) ([]*entity.User, error) {
+	users := []*entity.User{}
+	if len(usernames) == 0 {
+		return users, nil
+	}
+
+	rows, err := r.db.Query("SELECT "+userColumns+" FROM users WHERE username = ANY($1)", pq.Array(usernames))
+	if err != nil {
+		log.Printf("Error retrieving users by username: %v", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		user, err := scanUser(rows)
+		if err != nil {
+			return nil, err
+		}
+		users = append(users, user)
+	}
+
+	return users, rows.Err()
+}
+
+// MarkEmailVerified records when a user verified their email.
+func (r *userRepositoryImpl) MarkEmailVerified(userID uuid.UUID, at time.Time) error {
+	result, err := r.db.Exec(`UPDATE users SET email_verified_at = COALESCE(email_verified_at, $2), updated_at = $2 WHERE id = $1`, userID, at)
+	if err != nil {
+		log.Printf("Error marking email of user %v as verified: %v", userID, err)
+		return storeError(err)
+	}
+	return expectRow(result, "user not found")
+}
+
+// UpdateLastLogin records when a user last signed in.
+func (r *userRepositoryImpl) UpdateLastLogin(userID uuid.UUID, at time.Time) error {
+	result, err := r.db.Exec(`UPDATE users SET last_login = $2 WHERE id = $1`, userID, at)
+	if err != nil {
+		log.Printf("Error updating last login of user %v: %v", userID, err)
+		return storeError(err)
+	}
+	return expectRow(result, "user not found")
+}
+
+// UpdatePassword replaces the password hash of a user.
+func (r *userRepositoryImpl) UpdatePassword(userID uuid.UUID, passwordHash string) error {
+	result, err := r.db.Exec(`UPDATE users SET password = $2, updated_at = $3 WHERE id = $1`, userID, passwordHash, time.Now())
+	if err != nil {
+		log.Printf("Error updating password of user %v: %v", userID, err)
+		return storeError(err)
+	}
+	return expectRow(result, "user not found")
+}
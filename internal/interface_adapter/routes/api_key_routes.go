@@ -0,0 +1,22 @@
+package routes
+
+import (
+	"dalabio/internal/interface_adapter/controller"
+	"dalabio/internal/repository"
+	"dalabio/pkg/middleware"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RegisterAPIKeyRoutes sets up the routes for managing personal API keys.
+func RegisterAPIKeyRoutes(router *gin.Engine, apiKeyController *controller.APIKeyController, tokenRepo repository.TokenRepository) {
+	authMiddleware := middleware.AuthMiddleware(tokenRepo)
+
+	// A key could otherwise mint a key with more scopes than its own, or outlive its own revocation
+	apiKeyGroup := router.Group("/users/me/api-keys", authMiddleware, middleware.RejectAPIKeys())
+	{
+		apiKeyGroup.POST("", apiKeyController.CreateAPIKey)       // Route for creating an API key
+		apiKeyGroup.GET("", apiKeyController.ListAPIKeys)         // Route for listing the API keys of the authenticated user
+		apiKeyGroup.DELETE("/:id", apiKeyController.RevokeAPIKey) // Route for revoking an API key
+	}
+}
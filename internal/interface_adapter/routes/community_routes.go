@@ -0,0 +1,43 @@
+package routes
+
+import (
+	"dalabio/internal/interface_adapter/controller"
+	"dalabio/internal/repository"
+	"dalabio/pkg/middleware"
+
+	"github.com/gin-gonic/gin"
+)
+
+func RegisterCommunityRoutes(router *gin.Engine, communityController *controller.CommunityController, tokenRepo repository.TokenRepository) {
+	authMiddleware := middleware.AuthMiddleware(tokenRepo)
+
+	postGroup := router.Group("/spaces/:id/posts")
+	{
+		postGroup.Use(authMiddleware)
+		{
+			postGroup.GET("", communityController.GetPosts)
+			postGroup.POST("", communityController.CreatePost)
+			postGroup.GET("/:postId", communityController.GetPost)
+			postGroup.PUT("/:postId", communityController.EditPost)
+			postGroup.DELETE("/:postId", communityController.DeletePost)
+			postGroup.GET("/:postId/history", communityController.GetPostHistory)
+			postGroup.POST("/:postId/pin", communityController.PinPost)
+			postGroup.DELETE("/:postId/pin", communityController.UnpinPost)
+			postGroup.POST("/:postId/hide", communityController.HidePost)
+			postGroup.DELETE("/:postId/hide", communityController.UnhidePost)
+			postGroup.POST("/:postId/reactions", communityController.AddPostReaction)
+			postGroup.DELETE("/:postId/reactions/:emoji", communityController.RemovePostReaction)
+
+			postGroup.GET("/:postId/comments", communityController.GetComments)
+			postGroup.POST("/:postId/comments", communityController.AddComment)
+			postGroup.PUT("/:postId/comments/:commentId", communityController.EditComment)
+			postGroup.DELETE("/:postId/comments/:commentId", communityController.DeleteComment)
+			postGroup.GET("/:postId/comments/:commentId/history", communityController.GetCommentHistory)
+			postGroup.POST("/:postId/comments/:commentId/hide", communityController.HideComment)
+			postGroup.DELETE("/:postId/comments/:commentId/hide", communityController.UnhideComment)
+			postGroup.POST("/:postId/comments/:commentId/reactions", communityController.AddCommentReaction)
+			postGroup.DELETE("/:postId/comments/:commentId/reactions/:emoji", communityController.RemoveCommentReaction)
+		}
+	}
+
+}
@@ -0,0 +1,29 @@
+package routes
+
+import (
+	"dalabio/internal/entity"
+	"dalabio/internal/interface_adapter/controller"
+	"dalabio/internal/repository"
+	"dalabio/pkg/middleware"
+
+	"github.com/gin-gonic/gin"
+)
+
+func RegisterCouponRoutes(router *gin.Engine, couponController *controller.CouponController, tokenRepo repository.TokenRepository, roleRepo repository.RoleRepository) {
+	authMiddleware := middleware.AuthMiddleware(tokenRepo)
+	adminMiddleware := middleware.RequireRole(roleRepo, entity.RoleAdmin)
+
+	// Coupons are managed by admins only
+	couponGroup := router.Group("/coupons")
+	{
+		couponGroup.Use(authMiddleware, adminMiddleware)
+		{
+			couponGroup.POST("", couponController.CreateCoupon)
+			couponGroup.PUT("/:id", couponController.UpdateCoupon)
+			couponGroup.DELETE("/:id", couponController.DeleteCoupon)
+			couponGroup.GET("/:id", couponController.GetCouponByID)
+			couponGroup.GET("", couponController.GetAllCoupons)
+		}
+	}
+
+}
@@ -26,4 +26,13 @@ func RegisterCoursesRoutes(router *gin.Engine, courseController *controller.Cour
 		}
 	}
 
+	// Courses offered in a space
+	spaceCourseGroup := router.Group("/spaces/:id/courses")
+	{
+		spaceCourseGroup.Use(authMiddleware)
+		{
+			spaceCourseGroup.GET("", courseController.GetSpaceCourses)
+		}
+	}
+
 }
@@ -0,0 +1,29 @@
+package routes
+
+import (
+	"dalabio/internal/entity"
+	"dalabio/internal/interface_adapter/controller"
+	"dalabio/internal/repository"
+	"dalabio/pkg/middleware"
+
+	"github.com/gin-gonic/gin"
+)
+
+func RegisterEmailRoutes(router *gin.Engine, emailController *controller.EmailController, tokenRepo repository.TokenRepository, roleRepo repository.RoleRepository) {
+	authMiddleware := middleware.AuthMiddleware(tokenRepo)
+	adminMiddleware := middleware.RequireRole(roleRepo, entity.RoleAdmin)
+
+	// Called by the mail provider, authenticated by a shared secret instead of a user token
+	router.POST("/emails/bounces", emailController.RecordBounce)
+
+	suppressionGroup := router.Group("/emails/suppressions")
+	{
+		suppressionGroup.Use(authMiddleware, adminMiddleware)
+		{
+			suppressionGroup.GET("", emailController.GetSuppressions)
+			suppressionGroup.POST("", emailController.Suppress)
+			suppressionGroup.DELETE("/:email", emailController.Unsuppress)
+		}
+	}
+
+}
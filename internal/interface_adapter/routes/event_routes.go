@@ -0,0 +1,22 @@
+package routes
+
+import (
+	"dalabio/internal/interface_adapter/controller"
+	"dalabio/internal/repository"
+	"dalabio/pkg/middleware"
+
+	"github.com/gin-gonic/gin"
+)
+
+func RegisterEventRoutes(router *gin.Engine, eventController *controller.EventController, tokenRepo repository.TokenRepository) {
+	authMiddleware := middleware.AuthMiddleware(tokenRepo)
+
+	eventGroup := router.Group("/events")
+	{
+		eventGroup.Use(authMiddleware)
+		{
+			eventGroup.GET("", eventController.Stream)
+		}
+	}
+
+}
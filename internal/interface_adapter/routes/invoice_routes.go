@@ -0,0 +1,22 @@
+package routes
+
+import (
+	"dalabio/internal/interface_adapter/controller"
+	"dalabio/internal/repository"
+	"dalabio/pkg/middleware"
+
+	"github.com/gin-gonic/gin"
+)
+
+func RegisterInvoiceRoutes(router *gin.Engine, invoiceController *controller.InvoiceController, tokenRepo repository.TokenRepository) {
+	authMiddleware := middleware.AuthMiddleware(tokenRepo)
+
+	invoiceGroup := router.Group("/payments")
+	{
+		invoiceGroup.Use(authMiddleware)
+		{
+			invoiceGroup.GET("/:id/invoice", invoiceController.GetPaymentInvoice)
+		}
+	}
+
+}
@@ -23,4 +23,13 @@ func RegisterMeetingRoutes(router *gin.Engine, meetingController *controller.Mee
 		}
 	}
 
+	// Meetings hosted in a space
+	spaceMeetingGroup := router.Group("/spaces/:id/meetings")
+	{
+		spaceMeetingGroup.Use(authMiddleware)
+		{
+			spaceMeetingGroup.GET("", meetingController.GetSpaceMeetings)
+		}
+	}
+
 }
@@ -0,0 +1,29 @@
+package routes
+
+import (
+	"dalabio/internal/interface_adapter/controller"
+	"dalabio/internal/repository"
+	"dalabio/pkg/middleware"
+
+	"github.com/gin-gonic/gin"
+)
+
+func RegisterMessageRoutes(router *gin.Engine, messageController *controller.MessageController, tokenRepo repository.TokenRepository) {
+	authMiddleware := middleware.AuthMiddleware(tokenRepo)
+
+	conversationGroup := router.Group("/conversations")
+	{
+		conversationGroup.Use(authMiddleware)
+		{
+			conversationGroup.POST("", messageController.StartConversation)
+			conversationGroup.GET("", messageController.GetConversations)
+			conversationGroup.GET("/unread", messageController.GetUnreadCount)
+			conversationGroup.GET("/stream", messageController.Stream)
+			conversationGroup.GET("/:id", messageController.GetConversation)
+			conversationGroup.GET("/:id/messages", messageController.GetMessages)
+			conversationGroup.POST("/:id/messages", messageController.SendMessage)
+			conversationGroup.POST("/:id/read", messageController.MarkRead)
+		}
+	}
+
+}
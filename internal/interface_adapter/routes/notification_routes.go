@@ -0,0 +1,26 @@
+package routes
+
+import (
+	"dalabio/internal/interface_adapter/controller"
+	"dalabio/internal/repository"
+	"dalabio/pkg/middleware"
+
+	"github.com/gin-gonic/gin"
+)
+
+func RegisterNotificationRoutes(router *gin.Engine, notificationController *controller.NotificationController, tokenRepo repository.TokenRepository) {
+	authMiddleware := middleware.AuthMiddleware(tokenRepo)
+
+	notificationGroup := router.Group("/notifications")
+	{
+		notificationGroup.Use(authMiddleware)
+		{
+			notificationGroup.GET("", notificationController.GetNotifications)
+			notificationGroup.POST("/read-all", notificationController.MarkAllRead)
+			notificationGroup.POST("/:id/read", notificationController.MarkRead)
+			notificationGroup.GET("/preferences", notificationController.GetPreferences)
+			notificationGroup.PUT("/preferences", notificationController.UpdatePreferences)
+		}
+	}
+
+}
@@ -0,0 +1,29 @@
+package routes
+
+import (
+	"dalabio/internal/interface_adapter/controller"
+	"dalabio/internal/repository"
+	"dalabio/pkg/middleware"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RegisterOIDCRoutes sets up the routes for signing in with OpenID Connect identity providers.
+func RegisterOIDCRoutes(router *gin.Engine, oidcController *controller.OIDCController, tokenRepo repository.TokenRepository) {
+	authMiddleware := middleware.AuthMiddleware(tokenRepo)
+
+	// Public routes
+	oidcGroup := router.Group("/users/oidc")
+	{
+		oidcGroup.GET("/providers", oidcController.ListProviders)        // Route for listing the identity providers
+		oidcGroup.POST("/:provider/authorize", oidcController.Authorize) // Route for starting a sign-in with a provider
+		oidcGroup.GET("/:provider/callback", oidcController.Callback)    // Route the provider sends the user back to
+	}
+
+	// Protected routes
+	identityGroup := router.Group("/users/me/identities", authMiddleware)
+	{
+		identityGroup.GET("", oidcController.ListIdentities) // Route for listing the identities of the authenticated user
+		identityGroup.DELETE("/:id", oidcController.Unlink)  // Route for unlinking an identity
+	}
+}
@@ -0,0 +1,25 @@
+package routes
+
+import (
+	"dalabio/internal/interface_adapter/controller"
+	"dalabio/internal/repository"
+	"dalabio/pkg/middleware"
+
+	"github.com/gin-gonic/gin"
+)
+
+func RegisterOrderRoutes(router *gin.Engine, orderController *controller.OrderController, tokenRepo repository.TokenRepository) {
+	authMiddleware := middleware.AuthMiddleware(tokenRepo)
+
+	orderGroup := router.Group("/orders")
+	{
+		orderGroup.Use(authMiddleware)
+		{
+			orderGroup.POST("/checkout", orderController.Checkout)
+			orderGroup.GET("/:id", orderController.GetOrderByID)
+			orderGroup.POST("/:id/cancel", orderController.CancelOrder)
+			orderGroup.GET("", orderController.GetMyOrders)
+		}
+	}
+
+}
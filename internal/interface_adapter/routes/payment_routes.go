@@ -1,6 +1,7 @@
 package routes
 
 import (
+	"dalabio/internal/entity"
 	"dalabio/internal/interface_adapter/controller"
 	"dalabio/internal/repository"
 	"dalabio/pkg/middleware"
@@ -8,18 +9,21 @@ import (
 	"github.com/gin-gonic/gin"
 )
 
-func RegisterPaymentRoutes(router *gin.Engine, spaceController *controller.PaymentController, tokenRepo repository.TokenRepository) {
+func RegisterPaymentRoutes(router *gin.Engine, spaceController *controller.PaymentController, tokenRepo repository.TokenRepository, roleRepo repository.RoleRepository) {
 	AuthMiddleware := middleware.AuthMiddleware(tokenRepo)
+	adminMiddleware := middleware.RequireRole(roleRepo, entity.RoleAdmin)
 
 	spaceGroup := router.Group("/payments")
 	{
 		spaceGroup.Use(AuthMiddleware)
 		{
 			spaceGroup.POST("", spaceController.CreatePayment)
-			spaceGroup.PUT("/:id", spaceController.UpdatePayment)
-			spaceGroup.DELETE("/:id", spaceController.DeletePayment)
+			spaceGroup.PUT("/:id", adminMiddleware, spaceController.UpdatePayment)
+			spaceGroup.POST("/:id/confirm", adminMiddleware, spaceController.ConfirmPayment)
+			spaceGroup.DELETE("/:id", adminMiddleware, spaceController.DeletePayment)
 			spaceGroup.GET("/:id", spaceController.GetPaymentByID)
 			spaceGroup.GET("", spaceController.GetAllPayments)
+			spaceGroup.POST("/:id/refunds", adminMiddleware, spaceController.RefundPayment)
 
 		}
 	}
@@ -0,0 +1,34 @@
+package routes
+
+import (
+	"dalabio/internal/entity"
+	"dalabio/internal/interface_adapter/controller"
+	"dalabio/internal/repository"
+	"dalabio/pkg/middleware"
+
+	"github.com/gin-gonic/gin"
+)
+
+func RegisterPayoutRoutes(router *gin.Engine, payoutController *controller.PayoutController, tokenRepo repository.TokenRepository, roleRepo repository.RoleRepository) {
+	authMiddleware := middleware.AuthMiddleware(tokenRepo)
+	adminMiddleware := middleware.RequireRole(roleRepo, entity.RoleAdmin)
+
+	meGroup := router.Group("/me")
+	{
+		meGroup.Use(authMiddleware)
+		{
+			meGroup.GET("/earnings", payoutController.GetMyEarnings)
+		}
+	}
+
+	// Payouts are settled by admins once the transfer has been made
+	payoutGroup := router.Group("/payouts")
+	{
+		payoutGroup.Use(authMiddleware, adminMiddleware)
+		{
+			payoutGroup.GET("", payoutController.GetAllPayouts)
+			payoutGroup.PUT("/:id", payoutController.UpdatePayout)
+		}
+	}
+
+}
@@ -0,0 +1,28 @@
+package routes
+
+import (
+	"dalabio/internal/entity"
+	"dalabio/internal/interface_adapter/controller"
+	"dalabio/internal/repository"
+	"dalabio/pkg/middleware"
+
+	"github.com/gin-gonic/gin"
+)
+
+func RegisterReconciliationRoutes(router *gin.Engine, reconciliationController *controller.ReconciliationController, tokenRepo repository.TokenRepository, roleRepo repository.RoleRepository) {
+	authMiddleware := middleware.AuthMiddleware(tokenRepo)
+	adminMiddleware := middleware.RequireRole(roleRepo, entity.RoleAdmin)
+
+	// Reconciliation is a finance task reserved to admins
+	reconciliationGroup := router.Group("/reconciliations")
+	{
+		reconciliationGroup.Use(authMiddleware, adminMiddleware)
+		{
+			reconciliationGroup.POST("", reconciliationController.Reconcile)
+			reconciliationGroup.GET("/export", reconciliationController.ExportCSV)
+			reconciliationGroup.GET("/:id", reconciliationController.GetReconciliationByID)
+			reconciliationGroup.GET("", reconciliationController.GetAllReconciliations)
+		}
+	}
+
+}
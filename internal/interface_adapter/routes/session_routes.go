@@ -0,0 +1,20 @@
+package routes
+
+import (
+	"dalabio/internal/interface_adapter/controller"
+	"dalabio/internal/repository"
+	"dalabio/pkg/middleware"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RegisterSessionRoutes sets up the routes for managing the sessions of the authenticated user.
+func RegisterSessionRoutes(router *gin.Engine, sessionController *controller.SessionController, tokenRepo repository.TokenRepository) {
+	authMiddleware := middleware.AuthMiddleware(tokenRepo)
+
+	sessionGroup := router.Group("/users/me/sessions", authMiddleware, middleware.RejectAPIKeys())
+	{
+		sessionGroup.GET("", sessionController.ListSessions)         // Route for listing the sessions of the authenticated user
+		sessionGroup.DELETE("/:id", sessionController.RevokeSession) // Route for revoking a session
+	}
+}
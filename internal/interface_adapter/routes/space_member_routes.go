@@ -0,0 +1,29 @@
+package routes
+
+import (
+	"dalabio/internal/interface_adapter/controller"
+	"dalabio/internal/repository"
+	"dalabio/pkg/middleware"
+
+	"github.com/gin-gonic/gin"
+)
+
+func RegisterSpaceMemberRoutes(router *gin.Engine, memberController *controller.SpaceMemberController, tokenRepo repository.TokenRepository) {
+	authMiddleware := middleware.AuthMiddleware(tokenRepo)
+
+	memberGroup := router.Group("/spaces/:id/members")
+	{
+		memberGroup.Use(authMiddleware)
+		{
+			memberGroup.GET("", memberController.GetMembers)
+			memberGroup.POST("/join", memberController.Join)
+			memberGroup.POST("/leave", memberController.Leave)
+			memberGroup.POST("/invitations", memberController.Invite)
+			memberGroup.GET("/invitations", memberController.GetInvitations)
+			memberGroup.DELETE("/invitations/:invitationId", memberController.RevokeInvitation)
+			memberGroup.PUT("/:userId", memberController.ChangeRole)
+			memberGroup.DELETE("/:userId", memberController.RemoveMember)
+		}
+	}
+
+}
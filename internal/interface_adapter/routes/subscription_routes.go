@@ -0,0 +1,35 @@
+package routes
+
+import (
+	"dalabio/internal/interface_adapter/controller"
+	"dalabio/internal/repository"
+	"dalabio/pkg/middleware"
+
+	"github.com/gin-gonic/gin"
+)
+
+func RegisterSubscriptionRoutes(router *gin.Engine, subscriptionController *controller.SubscriptionController, tokenRepo repository.TokenRepository) {
+	authMiddleware := middleware.AuthMiddleware(tokenRepo)
+
+	// Plans are managed by the coach of the space they belong to
+	planGroup := router.Group("/spaces/:id/plans")
+	{
+		planGroup.Use(authMiddleware)
+		{
+			planGroup.POST("", subscriptionController.CreatePlan)
+			planGroup.PUT("/:planId", subscriptionController.UpdatePlan)
+			planGroup.GET("", subscriptionController.GetPlansBySpace)
+		}
+	}
+
+	subscriptionGroup := router.Group("/subscriptions")
+	{
+		subscriptionGroup.Use(authMiddleware)
+		{
+			subscriptionGroup.POST("", subscriptionController.Subscribe)
+			subscriptionGroup.POST("/:id/cancel", subscriptionController.CancelSubscription)
+			subscriptionGroup.GET("", subscriptionController.GetMySubscriptions)
+		}
+	}
+
+}
@@ -0,0 +1,14 @@
+package routes
+
+import (
+	"dalabio/internal/interface_adapter/controller"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RegisterTokenRoutes sets up the routes for renewing and verifying access tokens.
+func RegisterTokenRoutes(router *gin.Engine, tokenController *controller.TokenController) {
+	// Public routes, the refresh token authenticates the request
+	router.POST("/users/token/refresh", tokenController.Refresh) // Route for renewing an access token
+	router.GET("/.well-known/jwks.json", tokenController.JWKS)   // Route for the public keys verifying signed access tokens
+}
@@ -0,0 +1,32 @@
+package routes
+
+import (
+	"dalabio/internal/entity"
+	"dalabio/internal/interface_adapter/controller"
+	"dalabio/internal/repository"
+	"dalabio/pkg/middleware"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RegisterTwoFactorRoutes sets up the routes for two-factor authentication settings.
+func RegisterTwoFactorRoutes(router *gin.Engine, twoFactorController *controller.TwoFactorController, tokenRepo repository.TokenRepository, roleRepo repository.RoleRepository) {
+	authMiddleware := middleware.AuthMiddleware(tokenRepo)
+	adminMiddleware := middleware.RequireRole(roleRepo, entity.RoleAdmin)
+
+	// An API key must not be able to enroll an authenticator its owner does not have
+	twoFactorGroup := router.Group("/users/me/2fa", authMiddleware, middleware.RejectAPIKeys())
+	{
+		twoFactorGroup.GET("", twoFactorController.GetStatus)                               // Route for the two-factor status of the authenticated user
+		twoFactorGroup.POST("", twoFactorController.Enroll)                                 // Route for generating a new authenticator secret
+		twoFactorGroup.POST("/confirm", twoFactorController.Confirm)                        // Route for enabling the authenticator with a first code
+		twoFactorGroup.DELETE("", twoFactorController.Disable)                              // Route for disabling two-factor authentication
+		twoFactorGroup.POST("/recovery-codes", twoFactorController.RegenerateRecoveryCodes) // Route for replacing the recovery codes
+	}
+
+	// Admin-only routes
+	roleGroup := router.Group("/roles", authMiddleware, adminMiddleware)
+	{
+		roleGroup.PUT("/:name/two-factor", twoFactorController.SetRoleRequirement) // Route for enforcing two-factor authentication for a role
+	}
+}
@@ -1,6 +1,7 @@
 package routes
 
 import (
+	"dalabio/internal/entity"
 	"dalabio/internal/interface_adapter/controller"
 	"dalabio/internal/repository"
 	"dalabio/pkg/middleware"
@@ -9,24 +10,36 @@ import (
 )
 
 // RegisterUserRoutes sets up the routes for user-related operations.
-func RegisterUserRoutes(router *gin.Engine, userController *controller.UserController, tokenRepo repository.TokenRepository) {
+func RegisterUserRoutes(router *gin.Engine, userController *controller.UserController, tokenRepo repository.TokenRepository, roleRepo repository.RoleRepository) {
 	// Apply middleware to protect certain routes
 	authMiddleware := middleware.AuthMiddleware(tokenRepo)
+	adminMiddleware := middleware.RequireRole(roleRepo, entity.RoleAdmin)
+	signedInMiddleware := middleware.RejectAPIKeys() // The account itself is only managed by its signed in user
 
 	// User-related routes
 	userGroup := router.Group("/users")
 	{
 		// Public routes
-		userGroup.POST("", userController.RegisterUser)                  // Route for user registration
-		userGroup.POST("/authenticate", userController.AuthenticateUser) // Route for user authentication
+		userGroup.POST("", userController.RegisterUser)                          // Route for user registration
+		userGroup.POST("/authenticate", userController.AuthenticateUser)         // Route for user authentication
+		userGroup.POST("/authenticate/2fa", userController.VerifyTwoFactor)      // Route for giving the second factor of a sign-in
+		userGroup.POST("/authenticate/2fa/setup", userController.SetupTwoFactor) // Route for enrolling a required authenticator while signing in
+		userGroup.GET("/verify", userController.VerifyEmail)                     // Route for the link of the verification email
+		userGroup.POST("/verify/resend", userController.ResendVerification)      // Route for requesting a new verification email
+		userGroup.POST("/password/forgot", userController.ForgotPassword)        // Route for requesting a password reset email
+		userGroup.POST("/password/reset", userController.ResetPassword)          // Route for choosing a new password with the emailed token
 
 		// Protected routes (require valid authentication)
 		userGroup.Use(authMiddleware) // Apply middleware here without additional braces
 		{
-			userGroup.PUT("/:id", userController.UpdateUser)    // Route for updating user information (protected)
-			userGroup.DELETE("/:id", userController.DeleteUser) // Route for deactivating a user (protected)
-			userGroup.GET("/:id", userController.GetUserByID)   // Route for getting a user by ID (protected)
-			userGroup.GET("", userController.ListUsers)         // Route for listing all users (protected)
+			userGroup.POST("/me/password", signedInMiddleware, userController.ChangePassword) // Route for changing the password of the authenticated user
+			userGroup.PUT("/me", signedInMiddleware, userController.UpdateProfile)            // Route for editing the profile of the authenticated user
+			userGroup.PUT("/:id", adminMiddleware, userController.UpdateUser)                 // Route for updating any user (admins)
+			userGroup.DELETE("/:id", adminMiddleware, userController.DeleteUser)              // Route for deleting a user (admins)
+			userGroup.GET("/:id", userController.GetUserByID)                                 // Route for getting a user by ID (protected)
+			userGroup.GET("", userController.ListUsers)                                       // Route for listing all users (protected)
+
+			userGroup.POST("/:id/unlock", adminMiddleware, userController.UnlockUser) // Route for lifting the lockout of an account (admins)
 		}
 	}
 }
@@ -0,0 +1,27 @@
+package repository
+
+import (
+	"dalabio/internal/entity"
+	"time"
+
+	"github.com/gofrs/uuid"
+)
+
+type APIKeyRepository interface {
+	Create(key *entity.APIKey) error
+
+	// FindByHash returns the unexpired key with the given hash, or an error of kind ErrNotFound
+	FindByHash(keyHash string, now time.Time) (*entity.APIKey, error)
+
+	// ListByUserID returns the keys of a user, newest first
+	ListByUserID(userID uuid.UUID) ([]*entity.APIKey, error)
+
+	// Delete revokes a key of a user
+	Delete(userID, id uuid.UUID) error
+
+	// DeleteByUserID revokes every key of a user
+	DeleteByUserID(userID uuid.UUID) error
+
+	// RecordUse records when a key was used, unless it was already recorded since a time
+	RecordUse(id uuid.UUID, at, since time.Time) error
+}
@@ -1 +1,44 @@
 package repository
+
+import (
+	"dalabio/internal/entity"
+
+	"github.com/gofrs/uuid"
+)
+
+type CommunityRepository interface {
+	CreatePost(post *entity.Post) error
+
+	// UpdatePost saves the new title and body of a post along with the revision holding the previous ones
+	UpdatePost(post *entity.Post, revision *entity.Revision) error
+
+	// DeletePost soft deletes a post
+	DeletePost(postID uuid.UUID) error
+	GetPostByID(postID uuid.UUID) (*entity.Post, error)
+
+	// GetPostsBySpaceID returns the posts of a space, pinned posts first and then newest first
+	GetPostsBySpaceID(spaceID uuid.UUID) ([]*entity.Post, error)
+	SetPostPinned(postID uuid.UUID, pinned bool) error
+
+	// SetPostHidden hides a post on behalf of a moderator, or shows it again when hiddenBy is nil
+	SetPostHidden(postID uuid.UUID, hiddenBy *uuid.UUID, reason string) error
+
+	CreateComment(comment *entity.Comment) error
+	UpdateComment(comment *entity.Comment, revision *entity.Revision) error
+	DeleteComment(commentID uuid.UUID) error
+	GetCommentByID(commentID uuid.UUID) (*entity.Comment, error)
+
+	// GetCommentsByPostID returns every comment of a post, deleted ones included, oldest first
+	GetCommentsByPostID(postID uuid.UUID) ([]*entity.Comment, error)
+	SetCommentHidden(commentID uuid.UUID, hiddenBy *uuid.UUID, reason string) error
+
+	// AddReaction records a reaction; reacting twice with the same emoji has no effect
+	AddReaction(reaction *entity.Reaction) error
+	RemoveReaction(targetType string, targetID, userID uuid.UUID, emoji string) error
+
+	// GetReactionCounts returns the number of reactions per emoji of each target
+	GetReactionCounts(targetType string, targetIDs []uuid.UUID) (map[uuid.UUID]map[string]int, error)
+
+	// GetRevisions returns the edit history of a post or comment, oldest first
+	GetRevisions(targetType string, targetID uuid.UUID) ([]*entity.Revision, error)
+}
@@ -0,0 +1,38 @@
+package repository
+
+import (
+	"dalabio/internal/entity"
+
+	"github.com/gofrs/uuid"
+)
+
+type ConversationRepository interface {
+	// Create stores a conversation and its participants. A direct conversation has a key identifying
+	// its pair of users, and creating it again returns the existing one instead.
+	Create(conversation *entity.Conversation, directKey string, participantIDs []uuid.UUID) (*entity.Conversation, error)
+
+	// GetByID returns a conversation with its participants
+	GetByID(conversationID uuid.UUID) (*entity.Conversation, error)
+
+	// GetByUserID returns the conversations of a user, most recently active first,
+	// with their participants, last message and the number of messages the user has not read
+	GetByUserID(userID uuid.UUID) ([]*entity.Conversation, error)
+
+	// GetParticipant returns the participation of a user in a conversation
+	GetParticipant(conversationID, userID uuid.UUID) (*entity.ConversationParticipant, error)
+
+	// CreateMessage stores a message, marks the conversation as active and as read by the sender
+	CreateMessage(message *entity.Message) error
+
+	GetMessage(messageID uuid.UUID) (*entity.Message, error)
+
+	// GetMessages returns up to limit messages of a conversation sent before the given message, newest first
+	GetMessages(conversationID uuid.UUID, before *uuid.UUID, limit int) ([]*entity.Message, error)
+
+	// MarkRead moves the read receipt of a user up to the given message, or to the last message when nil,
+	// and returns the resulting read time; a receipt never moves backwards
+	MarkRead(conversationID, userID uuid.UUID, upTo *uuid.UUID) (*entity.ConversationParticipant, error)
+
+	// CountUnread returns the number of messages sent to a user that they have not read
+	CountUnread(userID uuid.UUID) (int, error)
+}
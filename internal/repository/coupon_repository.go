@@ -0,0 +1,25 @@
+package repository
+
+import (
+	"dalabio/internal/entity"
+
+	"github.com/gofrs/uuid"
+)
+
+type CouponRepository interface {
+	Create(coupon *entity.Coupon) error
+	Update(coupon *entity.Coupon) error
+	Delete(couponID uuid.UUID) error
+	GetdByID(couponID uuid.UUID) (*entity.Coupon, error)
+	GetByCode(code string) (*entity.Coupon, error)
+	GetAll() ([]*entity.Coupon, error)
+
+	// CountUserRedemptions returns how many times a user has redeemed a coupon
+	CountUserRedemptions(couponID, userID uuid.UUID) (int, error)
+
+	// Redeem records a redemption, failing if the coupon's total or per-user limit is reached
+	Redeem(redemption *entity.CouponRedemption) error
+
+	// ReleaseByOrderID cancels the redemption made for an order that was never paid
+	ReleaseByOrderID(orderID uuid.UUID) error
+}
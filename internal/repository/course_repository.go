@@ -13,4 +13,7 @@ type CourseRepository interface {
 	Delete(courseID uuid.UUID) error
 	GetdByID(courseID uuid.UUID) (*entity.Course, error)
 	GetAll() ([]*entity.Course, error)
+
+	// GetBySpaceID returns the courses offered in a space, oldest first
+	GetBySpaceID(spaceID uuid.UUID) ([]*entity.Course, error)
 }
@@ -0,0 +1,29 @@
+package repository
+
+import (
+	"dalabio/internal/entity"
+	"time"
+)
+
+type EmailRepository interface {
+	// Enqueue adds an email to the outbox
+	Enqueue(email *entity.OutboxEmail) error
+
+	// ClaimDue returns up to limit pending emails due at now and postpones them until leaseUntil,
+	// so that another instance processing the outbox meanwhile skips them
+	ClaimDue(now, leaseUntil time.Time, limit int) ([]*entity.OutboxEmail, error)
+
+	// UpdateDelivery records the outcome of an attempt to send an email
+	UpdateDelivery(email *entity.OutboxEmail) error
+
+	IsSuppressed(email string) (bool, error)
+
+	// Suppress adds an address to the suppression list, keeping the first reason it was added for
+	Suppress(suppression *entity.EmailSuppression) error
+
+	// Unsuppress removes an address from the suppression list
+	Unsuppress(email string) error
+
+	// GetSuppressions returns the suppression list, newest first
+	GetSuppressions() ([]*entity.EmailSuppression, error)
+}
@@ -0,0 +1,15 @@
+package repository
+
+import (
+	"dalabio/internal/entity"
+
+	"github.com/gofrs/uuid"
+)
+
+type EnrollmentRepository interface {
+	// Create grants access to an item; granting the same item twice is a no-op
+	Create(enrollment *entity.Enrollment) error
+
+	// GetByUserID returns every item a user has access to
+	GetByUserID(userID uuid.UUID) ([]*entity.Enrollment, error)
+}
@@ -0,0 +1,12 @@
+package repository
+
+import "errors"
+
+// Kinds of failure reported by the repositories. Gateways wrap them, so that callers tell them apart with errors.Is.
+var (
+	// ErrNotFound is returned when the requested row does not exist
+	ErrNotFound = errors.New("not found")
+
+	// ErrConflict is returned when a write breaks a unique or foreign key constraint, or a limit the stored state enforces
+	ErrConflict = errors.New("conflict")
+)
@@ -0,0 +1,15 @@
+package repository
+
+import (
+	"dalabio/internal/entity"
+
+	"github.com/gofrs/uuid"
+)
+
+type InvoiceRepository interface {
+	// Create assigns the next invoice number and stores the invoice in the same transaction
+	Create(invoice *entity.Invoice, prefix string) error
+
+	// GetByPaymentID returns the invoice issued for a payment
+	GetByPaymentID(paymentID uuid.UUID) (*entity.Invoice, error)
+}
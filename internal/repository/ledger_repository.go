@@ -0,0 +1,25 @@
+package repository
+
+import (
+	"dalabio/internal/entity"
+	"time"
+
+	"github.com/gofrs/uuid"
+)
+
+type LedgerRepository interface {
+	// Post stores a transaction and its entries; a reference that was already posted is left unchanged
+	Post(transaction *entity.LedgerTransaction) error
+
+	// GetByReference returns the transaction posted for a payment, refund or payout
+	GetByReference(referenceType string, referenceID uuid.UUID) (*entity.LedgerTransaction, error)
+
+	// GetBalances returns the payable balances of a user per currency; credits posted after holdSince are on hold
+	GetBalances(userID uuid.UUID, holdSince time.Time) ([]*entity.EarningsBalance, error)
+
+	// GetPayableBalances returns the payable balances of every user per currency
+	GetPayableBalances(holdSince time.Time) ([]*entity.EarningsBalance, error)
+
+	// GetEarnings returns the activity of a user grouped by day, week, month or year between from and to
+	GetEarnings(userID uuid.UUID, period string, from, to time.Time) ([]*entity.EarningsPeriod, error)
+}
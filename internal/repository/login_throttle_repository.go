@@ -0,0 +1,21 @@
+package repository
+
+import (
+	"dalabio/internal/entity"
+	"time"
+)
+
+type LoginThrottleRepository interface {
+	// Get returns the throttle of a subject, or an error of kind ErrNotFound when it never failed to sign in
+	Get(scope, subject string) (*entity.LoginThrottle, error)
+
+	// RecordFailure counts a failed sign-in and returns the throttle. The count starts over when the
+	// previous failure happened before resetBefore.
+	RecordFailure(scope, subject string, at, resetBefore time.Time) (*entity.LoginThrottle, error)
+
+	// Lock refuses the sign-ins of a subject until the given time
+	Lock(scope, subject string, until time.Time) error
+
+	// Reset forgets the failures of a subject and lifts its lockout
+	Reset(scope, subject string) error
+}
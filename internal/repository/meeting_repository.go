@@ -21,4 +21,7 @@ type MeetingRepository interface {
 
 	// GetMeetings returns all meetings
 	GetAll() ([]*entity.Meeting, error)
+
+	// GetBySpaceID returns the meetings hosted in a space, earliest first
+	GetBySpaceID(spaceID uuid.UUID) ([]*entity.Meeting, error)
 }
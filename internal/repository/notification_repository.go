@@ -0,0 +1,32 @@
+package repository
+
+import (
+	"dalabio/internal/entity"
+
+	"github.com/gofrs/uuid"
+)
+
+type NotificationRepository interface {
+	Create(notification *entity.Notification) error
+
+	// GetByUserID returns the latest notifications of a user, newest first
+	GetByUserID(userID uuid.UUID, unreadOnly bool, limit int) ([]*entity.Notification, error)
+
+	// CountUnread returns the number of notifications the user has not read
+	CountUnread(userID uuid.UUID) (int, error)
+
+	// MarkRead marks a notification of the user as read
+	MarkRead(userID, notificationID uuid.UUID) error
+
+	// MarkAllRead marks every notification of the user as read and returns how many were unread
+	MarkAllRead(userID uuid.UUID) (int64, error)
+
+	// GetPreferences returns the preferences a user has set
+	GetPreferences(userID uuid.UUID) ([]*entity.NotificationPreference, error)
+
+	// GetPreferencesByCategory returns the preferences several users have set for a category
+	GetPreferencesByCategory(category string, userIDs []uuid.UUID) ([]*entity.NotificationPreference, error)
+
+	// SetPreference creates or replaces a preference of a user
+	SetPreference(preference *entity.NotificationPreference) error
+}
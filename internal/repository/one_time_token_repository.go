@@ -0,0 +1,24 @@
+package repository
+
+import (
+	"dalabio/internal/entity"
+	"time"
+
+	"github.com/gofrs/uuid"
+)
+
+type OneTimeTokenRepository interface {
+	Create(token *entity.OneTimeToken) error
+
+	// Consume marks the unused and unexpired token with the given hash as used and returns it
+	Consume(purpose, tokenHash string, now time.Time) (*entity.OneTimeToken, error)
+
+	// Find returns the unused and unexpired token with the given hash without using it
+	Find(purpose, tokenHash string, now time.Time) (*entity.OneTimeToken, error)
+
+	// InvalidateAll marks every unused token of a user for a purpose as used
+	InvalidateAll(userID uuid.UUID, purpose string, now time.Time) error
+
+	// CountCreatedSince counts the tokens issued to a user for a purpose since a time
+	CountCreatedSince(userID uuid.UUID, purpose string, since time.Time) (int, error)
+}
@@ -0,0 +1,29 @@
+package repository
+
+import (
+	"dalabio/internal/entity"
+	"time"
+
+	"github.com/gofrs/uuid"
+)
+
+type OrderRepository interface {
+	// Create stores an order together with its lines
+	Create(order *entity.Order) error
+
+	// GetdByID returns an order and its lines
+	GetdByID(orderID uuid.UUID) (*entity.Order, error)
+
+	// GetByUserID returns the orders placed by a user
+	GetByUserID(userID uuid.UUID) ([]*entity.Order, error)
+
+	// UpdateStatus changes the status of an order
+	UpdateStatus(orderID uuid.UUID, status string) error
+
+	// TransitionStatus changes the status of an order only if it still is from, and reports whether it did,
+	// so that an order cannot be both paid and cancelled
+	TransitionStatus(orderID uuid.UUID, from, to string) (bool, error)
+
+	// GetIDsCreatedBefore returns the IDs of the orders with a status created before a time
+	GetIDsCreatedBefore(status string, before time.Time) ([]uuid.UUID, error)
+}
@@ -2,6 +2,7 @@ package repository
 
 import (
 	"dalabio/internal/entity"
+	"time"
 
 	"github.com/gofrs/uuid"
 )
@@ -12,4 +13,13 @@ type PaymentRepository interface {
 	GetAll() ([]*entity.Payment, error)
 	Update(payment *entity.Payment) error
 	Delete(paymentID uuid.UUID) error
+
+	// GetByTransactionID returns the payment recorded for a gateway transaction
+	GetByTransactionID(transactionID string) (*entity.Payment, error)
+
+	// GetByDateRange returns the payments made in [from, to), oldest first
+	GetByDateRange(from, to time.Time) ([]*entity.Payment, error)
+
+	// UpdateStatusByOrderID moves the payments of an order that have the status from to the status to
+	UpdateStatusByOrderID(orderID uuid.UUID, from, to string) error
 }
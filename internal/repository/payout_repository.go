@@ -0,0 +1,23 @@
+package repository
+
+import (
+	"dalabio/internal/entity"
+
+	"github.com/gofrs/uuid"
+)
+
+type PayoutRepository interface {
+	// Create stores a payout together with the ledger transaction moving its amount out of the balance
+	Create(payout *entity.Payout, transaction *entity.LedgerTransaction) error
+
+	// Update updates the status of a payout, posting the optional ledger transaction in the same database transaction
+	Update(payout *entity.Payout, transaction *entity.LedgerTransaction) error
+
+	GetdByID(payoutID uuid.UUID) (*entity.Payout, error)
+
+	// GetByUserID returns the payouts of a coach or instructor, newest first
+	GetByUserID(userID uuid.UUID) ([]*entity.Payout, error)
+
+	// GetAll returns every payout, newest first
+	GetAll() ([]*entity.Payout, error)
+}
@@ -0,0 +1,14 @@
+package repository
+
+import (
+	"dalabio/internal/entity"
+
+	"github.com/gofrs/uuid"
+)
+
+type PlanRepository interface {
+	Create(plan *entity.Plan) error
+	Update(plan *entity.Plan) error
+	GetdByID(planID uuid.UUID) (*entity.Plan, error)
+	GetBySpaceID(spaceID uuid.UUID) ([]*entity.Plan, error)
+}
@@ -0,0 +1,22 @@
+package repository
+
+import (
+	"dalabio/internal/entity"
+	"time"
+
+	"github.com/gofrs/uuid"
+)
+
+type ReconciliationRepository interface {
+	// Create stores a reconciliation run together with its discrepancies
+	Create(reconciliation *entity.Reconciliation) error
+
+	// GetdByID returns a reconciliation run and its discrepancies
+	GetdByID(reconciliationID uuid.UUID) (*entity.Reconciliation, error)
+
+	// GetAll returns every reconciliation run without discrepancies, newest first
+	GetAll() ([]*entity.Reconciliation, error)
+
+	// GetDiscrepanciesByDateRange returns the discrepancies that occurred in [from, to), oldest first
+	GetDiscrepanciesByDateRange(from, to time.Time) ([]*entity.ReconciliationDiscrepancy, error)
+}
@@ -0,0 +1,18 @@
+package repository
+
+import (
+	"dalabio/internal/entity"
+
+	"github.com/gofrs/uuid"
+)
+
+type RefundRepository interface {
+	// Create stores a refund, failing if the refunds of the payment would exceed the captured amount
+	Create(refund *entity.Refund) error
+
+	// Update updates the status and gateway reference of a refund
+	Update(refund *entity.Refund) error
+
+	// GetByPaymentID returns the refund history of a payment
+	GetByPaymentID(paymentID uuid.UUID) ([]*entity.Refund, error)
+}
@@ -1 +1,16 @@
 package repository
+
+import (
+	"github.com/gofrs/uuid"
+)
+
+type RoleRepository interface {
+	// GetRoleNamesByUserID returns the names of the roles granted to a user
+	GetRoleNamesByUserID(userID uuid.UUID) ([]string, error)
+
+	// RequiresTwoFactor tells whether one of the roles granted to a user enforces two-factor authentication
+	RequiresTwoFactor(userID uuid.UUID) (bool, error)
+
+	// SetTwoFactorRequired makes two-factor authentication mandatory or optional for the holders of a role
+	SetTwoFactorRequired(role string, required bool) error
+}
@@ -0,0 +1,39 @@
+package repository
+
+import (
+	"dalabio/internal/entity"
+
+	"github.com/gofrs/uuid"
+)
+
+type SpaceMemberRepository interface {
+	// Add adds a user to a space; adding an existing member keeps their current role
+	Add(member *entity.SpaceMember) error
+
+	// Remove removes a user from a space
+	Remove(spaceID, userID uuid.UUID) error
+
+	// UpdateRole changes the role of a member
+	UpdateRole(spaceID, userID uuid.UUID, role string) error
+
+	// Get returns the membership of a user in a space
+	Get(spaceID, userID uuid.UUID) (*entity.SpaceMember, error)
+
+	// GetBySpaceID returns the members of a space, owner and co-coaches first
+	GetBySpaceID(spaceID uuid.UUID) ([]*entity.SpaceMember, error)
+
+	// ShareSpace reports whether two users are members of at least one common space
+	ShareSpace(userID, otherID uuid.UUID) (bool, error)
+
+	CreateInvitation(invitation *entity.SpaceInvitation) error
+
+	// GetInvitationsBySpaceID returns the invitations of a space, newest first
+	GetInvitationsBySpaceID(spaceID uuid.UUID) ([]*entity.SpaceInvitation, error)
+
+	// RevokeInvitation disables an invitation of a space
+	RevokeInvitation(spaceID, invitationID uuid.UUID) error
+
+	// RedeemInvitation adds the user to the space of a valid invitation and counts the use;
+	// the invitation row is locked so concurrent joins cannot exceed its maximum uses
+	RedeemInvitation(spaceID uuid.UUID, token string, userID uuid.UUID) (*entity.SpaceMember, error)
+}
@@ -0,0 +1,19 @@
+package repository
+
+import (
+	"dalabio/internal/entity"
+	"time"
+
+	"github.com/gofrs/uuid"
+)
+
+type SubscriptionRepository interface {
+	Create(subscription *entity.Subscription) error
+	Update(subscription *entity.Subscription) error
+	GetdByID(subscriptionID uuid.UUID) (*entity.Subscription, error)
+	GetByUserID(userID uuid.UUID) ([]*entity.Subscription, error)
+
+	// ClaimDue returns the subscriptions whose period has ended or whose dunning retry is due at now and hides
+	// them from other instances until leaseUntil, so that only one instance charges them. Update releases them.
+	ClaimDue(now, leaseUntil time.Time) ([]*entity.Subscription, error)
+}
@@ -2,9 +2,27 @@ package repository
 
 import (
 	"dalabio/internal/entity"
+	"time"
+
+	"github.com/gofrs/uuid"
 )
 
 type TokenRepository interface {
 	FindByToken(token string) (*entity.Token, error)
 	Create(token *entity.Token) error
+
+	// Rotate replaces the value and expiry of an unexpired token, so that the old value can only be used once
+	Rotate(token, newToken string, expiresAt, now time.Time) (*entity.Token, error)
+
+	// DeleteByUserID revokes every token of a user
+	DeleteByUserID(userID uuid.UUID) error
+
+	// ListByUserID returns the unexpired tokens of a user, most recently used first
+	ListByUserID(userID uuid.UUID, now time.Time) ([]*entity.Token, error)
+
+	// Delete revokes a token of a user
+	Delete(userID, id uuid.UUID) error
+
+	// Touch records the device a token is used from, unless the same device was already recorded since a time
+	Touch(id uuid.UUID, userAgent, ip, deviceLabel string, at, since time.Time) error
 }
@@ -0,0 +1,35 @@
+package repository
+
+import (
+	"dalabio/internal/entity"
+	"time"
+
+	"github.com/gofrs/uuid"
+)
+
+type TwoFactorRepository interface {
+	// Get returns the authenticator of a user, or an error of kind ErrNotFound when they have none
+	Get(userID uuid.UUID) (*entity.TwoFactor, error)
+
+	// Save stores a new unconfirmed authenticator, replacing the one the user had
+	Save(twoFactor *entity.TwoFactor) error
+
+	// Confirm enables the authenticator of a user and records the period of the code that confirmed it
+	Confirm(userID uuid.UUID, step int64, at time.Time) error
+
+	// UseStep records the period of an accepted code. It fails with an error of kind ErrNotFound
+	// when a code of that period or a later one was already accepted.
+	UseStep(userID uuid.UUID, step int64) error
+
+	// Delete removes the authenticator and the recovery codes of a user
+	Delete(userID uuid.UUID) error
+
+	// ReplaceRecoveryCodes discards the recovery codes of a user and stores new ones
+	ReplaceRecoveryCodes(userID uuid.UUID, codes []*entity.RecoveryCode) error
+
+	// UseRecoveryCode marks an unused recovery code of a user as used, or fails with an error of kind ErrNotFound
+	UseRecoveryCode(userID uuid.UUID, codeHash string, at time.Time) error
+
+	// CountRecoveryCodes counts the unused recovery codes of a user
+	CountRecoveryCodes(userID uuid.UUID) (int, error)
+}
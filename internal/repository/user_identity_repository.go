@@ -0,0 +1,30 @@
+package repository
+
+import (
+	"dalabio/internal/entity"
+	"time"
+
+	"github.com/gofrs/uuid"
+)
+
+type UserIdentityRepository interface {
+	Create(identity *entity.UserIdentity) error
+
+	// FindByProviderSubject returns the identity of an account at a provider, or an error of kind ErrNotFound
+	FindByProviderSubject(provider, subject string) (*entity.UserIdentity, error)
+
+	// ListByUserID returns the identities linked to a user
+	ListByUserID(userID uuid.UUID) ([]*entity.UserIdentity, error)
+
+	// RecordLogin records when a user last signed in with an identity and the email the provider gave
+	RecordLogin(id uuid.UUID, email string, at time.Time) error
+
+	// Delete unlinks an identity from its user
+	Delete(userID, id uuid.UUID) error
+
+	// CreateState stores a sign-in waiting for the user to come back from the provider
+	CreateState(state *entity.OIDCState) error
+
+	// ConsumeState deletes the unexpired sign-in with the given state hash and returns it
+	ConsumeState(provider, stateHash string, now time.Time) (*entity.OIDCState, error)
+}
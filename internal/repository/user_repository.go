@@ -2,6 +2,7 @@ package repository
 
 import (
 	"dalabio/internal/entity"
+	"time"
 
 	"github.com/gofrs/uuid"
 )
@@ -13,4 +14,16 @@ type UserRepository interface {
 	FindByID(userID uuid.UUID) (*entity.User, error)
 	FindByEmail(email string) (*entity.User, error)
 	ListAll() ([]*entity.User, error)
+
+	// FindByUsernames returns the users having one of the given usernames, unknown ones are skipped
+	FindByUsernames(usernames []string) ([]*entity.User, error)
+
+	// MarkEmailVerified records when a user verified their email, keeping the first time
+	MarkEmailVerified(userID uuid.UUID, at time.Time) error
+
+	// UpdatePassword replaces the password hash of a user
+	UpdatePassword(userID uuid.UUID, passwordHash string) error
+
+	// UpdateLastLogin records when a user last signed in
+	UpdateLastLogin(userID uuid.UUID, at time.Time) error
 }
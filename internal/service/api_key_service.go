@@ -0,0 +1,121 @@
+package service
+
+import (
+	"fmt"
+	"log"
+	"time"
+
+	"dalabio/internal/entity"
+	"dalabio/internal/repository"
+	"dalabio/pkg/utils"
+	"dalabio/pkg/validation"
+
+	"github.com/gofrs/uuid"
+)
+
+// APIKeyService manages the personal API keys users give to their scripts and integrations.
+type APIKeyService interface {
+	// CreateAPIKey issues a key to a user. The key is only returned now; afterwards only its prefix is known.
+	// Without an expiry the key expires after defaultAPIKeyLifetime.
+	CreateAPIKey(userID uuid.UUID, name string, scopes []string, expiresAt *time.Time) (*entity.APIKey, string, error)
+
+	// ListAPIKeys returns the keys of a user
+	ListAPIKeys(userID uuid.UUID) ([]*entity.APIKey, error)
+
+	// RevokeAPIKey deletes a key of a user, which stops working at once
+	RevokeAPIKey(userID, keyID uuid.UUID) error
+}
+
+// Lifetimes of API keys; they always expire, so that a forgotten key does not work forever
+const (
+	defaultAPIKeyLifetime = 90 * 24 * time.Hour
+	maxAPIKeyLifetime     = 365 * 24 * time.Hour
+)
+
+// apiKeyPrefixLength is the number of characters of a key shown in listings
+const apiKeyPrefixLength = 12
+
+// apiKeyServiceImpl is the implementation of APIKeyService.
+type apiKeyServiceImpl struct {
+	repo repository.APIKeyRepository
+}
+
+// NewAPIKeyService creates a new APIKeyService instance.
+func NewAPIKeyService(apiKeyRepo repository.APIKeyRepository) APIKeyService {
+	return &apiKeyServiceImpl{repo: apiKeyRepo}
+}
+
+// CreateAPIKey implements APIKeyService.
+func (s *apiKeyServiceImpl) CreateAPIKey(userID uuid.UUID, name string, scopes []string, expiresAt *time.Time) (*entity.APIKey, string, error) {
+	now := time.Now()
+	expiry := now.Add(defaultAPIKeyLifetime)
+	if expiresAt != nil {
+		expiry = *expiresAt
+	}
+
+	v := validation.New()
+	v.Length("name", name, 1, 100)
+	if v.Check(len(scopes) > 0, "scopes", validation.CodeRequired, "is required") {
+		for i, scope := range scopes {
+			v.OneOf(fmt.Sprintf("scopes[%d]", i), scope, entity.APIKeyScopeRead, entity.APIKeyScopeWrite, entity.APIKeyScopeAdmin)
+		}
+	}
+	if v.Check(expiry.After(now), "expires_at", validation.CodeInvalid, "must be in the future") {
+		v.Check(!expiry.After(now.Add(maxAPIKeyLifetime)), "expires_at", validation.CodeTooLarge, "must be at most a year from now")
+	}
+	if err := v.Err(); err != nil {
+		return nil, "", err
+	}
+
+	secret, err := utils.GenerateToken(32)
+	if err != nil {
+		return nil, "", err
+	}
+	value := entity.APIKeyPrefix + secret
+
+	key := &entity.APIKey{
+		UserID:    userID,
+		Name:      name,
+		Prefix:    value[:apiKeyPrefixLength],
+		KeyHash:   utils.HashToken(value),
+		Scopes:    uniqueScopes(scopes),
+		ExpiresAt: expiry,
+	}
+	if err := s.repo.Create(key); err != nil {
+		return nil, "", fmt.Errorf("failed to save API key of user with ID %s: %w", userID, err)
+	}
+	log.Printf("Created API key %s for user with ID %s", key.ID, userID)
+
+	return key, value, nil
+}
+
+// uniqueScopes drops the scopes listed twice, keeping their order
+func uniqueScopes(scopes []string) []string {
+	unique := make([]string, 0, len(scopes))
+	seen := make(map[string]bool, len(scopes))
+	for _, scope := range scopes {
+		if !seen[scope] {
+			seen[scope] = true
+			unique = append(unique, scope)
+		}
+	}
+	return unique
+}
+
+// ListAPIKeys implements APIKeyService.
+func (s *apiKeyServiceImpl) ListAPIKeys(userID uuid.UUID) ([]*entity.APIKey, error) {
+	keys, err := s.repo.ListByUserID(userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get API keys of user with ID %s: %w", userID, err)
+	}
+	return keys, nil
+}
+
+// RevokeAPIKey implements APIKeyService.
+func (s *apiKeyServiceImpl) RevokeAPIKey(userID, keyID uuid.UUID) error {
+	if err := s.repo.Delete(userID, keyID); err != nil {
+		return fmt.Errorf("failed to revoke API key %s: %w", keyID, err)
+	}
+	log.Printf("Revoked API key %s of user with ID %s", keyID, userID)
+	return nil
+}
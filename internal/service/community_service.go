@@ -1 +1,792 @@
 package service
+
+import (
+	"dalabio/internal/entity"
+	"dalabio/internal/framework/realtime"
+	"dalabio/internal/repository"
+	"fmt"
+	"log"
+	"regexp"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/gofrs/uuid"
+)
+
+// mentionPattern matches @username mentions, leaving out the domain of email addresses
+var mentionPattern = regexp.MustCompile(`(?:^|[^A-Za-z0-9_.@])@([A-Za-z0-9_][A-Za-z0-9_.-]*)`)
+
+// maxEmojiLength is the longest emoji accepted as a reaction
+const maxEmojiLength = 32
+
+// mentionExcerptLength is how much of the text is quoted in a mention notification
+const mentionExcerptLength = 140
+
+type CommunityService interface {
+
+	// GetPosts returns the posts of a space, pinned posts first
+	GetPosts(spaceID, requesterID uuid.UUID) ([]*entity.Post, error)
+
+	// CreatePost starts a discussion thread in a space, notifying the members mentioned in it
+	CreatePost(spaceID, authorID uuid.UUID, title, body string) (*entity.Post, error)
+
+	GetPost(spaceID, postID, requesterID uuid.UUID) (*entity.Post, error)
+
+	// EditPost changes a post, keeping its previous content in the edit history; only the author can edit
+	EditPost(spaceID, postID, requesterID uuid.UUID, title, body string) (*entity.Post, error)
+
+	// DeletePost deletes a post on behalf of its author or a moderator
+	DeletePost(spaceID, postID, requesterID uuid.UUID) error
+
+	// PinPost pins or unpins a post; only the owner and co-coaches can pin
+	PinPost(spaceID, postID, requesterID uuid.UUID, pinned bool) error
+
+	// HidePost hides a post from the members or shows it again; only moderators can hide
+	HidePost(spaceID, postID, requesterID uuid.UUID, hidden bool, reason string) error
+
+	GetPostHistory(spaceID, postID, requesterID uuid.UUID) ([]*entity.Revision, error)
+
+	// GetComments returns the comments of a post as threads of replies
+	GetComments(spaceID, postID, requesterID uuid.UUID) ([]*entity.Comment, error)
+
+	// AddComment comments a post or, with a parent, replies to another comment
+	AddComment(spaceID, postID, authorID uuid.UUID, parentID *uuid.UUID, body string) (*entity.Comment, error)
+
+	EditComment(spaceID, postID, commentID, requesterID uuid.UUID, body string) (*entity.Comment, error)
+	DeleteComment(spaceID, postID, commentID, requesterID uuid.UUID) error
+	HideComment(spaceID, postID, commentID, requesterID uuid.UUID, hidden bool, reason string) error
+	GetCommentHistory(spaceID, postID, commentID, requesterID uuid.UUID) ([]*entity.Revision, error)
+
+	// AddReaction reacts to a post, or to one of its comments when commentID is set
+	AddReaction(spaceID, postID uuid.UUID, commentID *uuid.UUID, userID uuid.UUID, emoji string) error
+	RemoveReaction(spaceID, postID uuid.UUID, commentID *uuid.UUID, userID uuid.UUID, emoji string) error
+}
+
+type communityServiceImpl struct {
+	repo          repository.CommunityRepository
+	memberRepo    repository.SpaceMemberRepository
+	userRepo      repository.UserRepository
+	roleRepo      repository.RoleRepository
+	notifications NotificationService
+	events        *realtime.Bus
+}
+
+func NewCommunityService(communityRepo repository.CommunityRepository, memberRepo repository.SpaceMemberRepository, userRepo repository.UserRepository, roleRepo repository.RoleRepository, notifications NotificationService, events *realtime.Bus) CommunityService {
+	return &communityServiceImpl{
+		repo:          communityRepo,
+		memberRepo:    memberRepo,
+		userRepo:      userRepo,
+		roleRepo:      roleRepo,
+		notifications: notifications,
+		events:        events,
+	}
+}
+
+// communityViewer is what a user may do in the community area of a space
+type communityViewer struct {
+	userID    uuid.UUID
+	member    *entity.SpaceMember // nil for admins who did not join the space
+	moderator bool                // Owners, co-coaches and admins can hide and delete any content
+}
+
+// coach reports whether the viewer runs the space
+func (v *communityViewer) coach() bool {
+	return v.member != nil && (v.member.Role == entity.SpaceRoleOwner || v.member.Role == entity.SpaceRoleCoCoach)
+}
+
+// sees reports whether the viewer can read content hidden by a moderator
+func (v *communityViewer) sees(authorID uuid.UUID, hiddenAt *time.Time) bool {
+	return hiddenAt == nil || v.moderator || authorID == v.userID
+}
+
+// viewer checks that the user can read the community of a space: its members and the admins can
+func (s *communityServiceImpl) viewer(spaceID, userID uuid.UUID) (*communityViewer, error) {
+	viewer := &communityViewer{userID: userID}
+	if member, err := s.memberRepo.Get(spaceID, userID); err == nil {
+		viewer.member = member
+		viewer.moderator = viewer.coach()
+	}
+
+	if !viewer.moderator {
+		roles, err := s.roleRepo.GetRoleNamesByUserID(userID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get roles of user with ID %s: %w", userID, err)
+		}
+		viewer.moderator = hasRole(roles, entity.RoleAdmin)
+	}
+
+	if viewer.member == nil && !viewer.moderator {
+		return nil, ErrMembersOnly
+	}
+	return viewer, nil
+}
+
+// participant checks that the user can write in the community of a space, which needs a membership
+func (s *communityServiceImpl) participant(spaceID, userID uuid.UUID) (*communityViewer, error) {
+	viewer, err := s.viewer(spaceID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if viewer.member == nil {
+		return nil, ErrMembersOnly
+	}
+	return viewer, nil
+}
+
+// post loads a post of the space, failing when the viewer cannot see it
+func (s *communityServiceImpl) post(spaceID, postID uuid.UUID, viewer *communityViewer) (*entity.Post, error) {
+	post, err := s.repo.GetPostByID(postID)
+	if err != nil || post.SpaceID != spaceID || !viewer.sees(post.AuthorID, post.HiddenAt) {
+		return nil, newError(ErrNotFound, "could not find post with ID %s in space with ID %s", postID, spaceID)
+	}
+	return post, nil
+}
+
+// comment loads a comment of the post, failing when the viewer cannot see it
+func (s *communityServiceImpl) comment(post *entity.Post, commentID uuid.UUID, viewer *communityViewer) (*entity.Comment, error) {
+	comment, err := s.repo.GetCommentByID(commentID)
+	if err != nil || comment.PostID != post.ID || !viewer.sees(comment.AuthorID, comment.HiddenAt) {
+		return nil, newError(ErrNotFound, "could not find comment with ID %s on post with ID %s", commentID, post.ID)
+	}
+	return comment, nil
+}
+
+// GetPosts implements CommunityService.
+func (s *communityServiceImpl) GetPosts(spaceID, requesterID uuid.UUID) ([]*entity.Post, error) {
+	viewer, err := s.viewer(spaceID, requesterID)
+	if err != nil {
+		return nil, err
+	}
+
+	posts, err := s.repo.GetPostsBySpaceID(spaceID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get posts of space with ID %s: %w", spaceID, err)
+	}
+
+	visible := []*entity.Post{}
+	for _, post := range posts {
+		if viewer.sees(post.AuthorID, post.HiddenAt) {
+			visible = append(visible, post)
+		}
+	}
+
+	if err := s.countPostReactions(visible...); err != nil {
+		return nil, err
+	}
+	return visible, nil
+}
+
+// CreatePost implements CommunityService.
+func (s *communityServiceImpl) CreatePost(spaceID, authorID uuid.UUID, title, body string) (*entity.Post, error) {
+	title, body = strings.TrimSpace(title), strings.TrimSpace(body)
+	if title == "" || body == "" {
+		return nil, newError(ErrValidation, "a post needs a title and a body")
+	}
+
+	if _, err := s.participant(spaceID, authorID); err != nil {
+		return nil, err
+	}
+
+	neoPost, err := uuid.NewV4()
+	if err != nil {
+		return nil, err
+	}
+
+	post := &entity.Post{
+		ID:        neoPost,
+		SpaceID:   spaceID,
+		AuthorID:  authorID,
+		Title:     title,
+		Body:      body,
+		Reactions: map[string]int{},
+	}
+	if err := s.repo.CreatePost(post); err != nil {
+		return nil, fmt.Errorf("failed to create post: %w", err)
+	}
+
+	s.notifyMentions(spaceID, authorID, postLink(post), "", title+"\n"+body)
+
+	// Discussions are only open to the members of the space
+	s.events.Publish(realtime.Event{Type: EventPostCreated, Data: PostEvent{
+		PostID:   post.ID,
+		SpaceID:  spaceID,
+		AuthorID: authorID,
+		Title:    title,
+	}}, realtime.Audience{SpaceID: &spaceID, MembersOnly: true})
+	s.notifyNewPost(post)
+	return post, nil
+}
+
+// GetPost implements CommunityService.
+func (s *communityServiceImpl) GetPost(spaceID, postID, requesterID uuid.UUID) (*entity.Post, error) {
+	viewer, err := s.viewer(spaceID, requesterID)
+	if err != nil {
+		return nil, err
+	}
+
+	post, err := s.post(spaceID, postID, viewer)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.countPostReactions(post); err != nil {
+		return nil, err
+	}
+	return post, nil
+}
+
+// EditPost implements CommunityService.
+func (s *communityServiceImpl) EditPost(spaceID, postID, requesterID uuid.UUID, title, body string) (*entity.Post, error) {
+	title, body = strings.TrimSpace(title), strings.TrimSpace(body)
+	if title == "" || body == "" {
+		return nil, newError(ErrValidation, "a post needs a title and a body")
+	}
+
+	viewer, err := s.participant(spaceID, requesterID)
+	if err != nil {
+		return nil, err
+	}
+	post, err := s.post(spaceID, postID, viewer)
+	if err != nil {
+		return nil, err
+	}
+	if post.AuthorID != requesterID {
+		return nil, ErrNotAuthor
+	}
+
+	revision, err := newRevision(entity.CommunityTargetPost, post.ID, post.Title, post.Body, requesterID)
+	if err != nil {
+		return nil, err
+	}
+
+	previous := post.Title + "\n" + post.Body
+	post.Title, post.Body = title, body
+	if err := s.repo.UpdatePost(post, revision); err != nil {
+		return nil, fmt.Errorf("failed to update post with ID %s: %w", postID, err)
+	}
+
+	// Only the users mentioned by the edit are notified
+	s.notifyMentions(spaceID, requesterID, postLink(post), previous, title+"\n"+body)
+
+	if err := s.countPostReactions(post); err != nil {
+		return nil, err
+	}
+	return post, nil
+}
+
+// DeletePost implements CommunityService.
+func (s *communityServiceImpl) DeletePost(spaceID, postID, requesterID uuid.UUID) error {
+	viewer, err := s.viewer(spaceID, requesterID)
+	if err != nil {
+		return err
+	}
+	post, err := s.post(spaceID, postID, viewer)
+	if err != nil {
+		return err
+	}
+	if post.AuthorID != requesterID && !viewer.moderator {
+		return ErrSpaceContentForbidden
+	}
+
+	if err := s.repo.DeletePost(postID); err != nil {
+		return fmt.Errorf("failed to delete post with ID %s: %w", postID, err)
+	}
+
+	log.Printf("User %s deleted post %s of space %s", requesterID, postID, spaceID)
+	return nil
+}
+
+// PinPost implements CommunityService.
+func (s *communityServiceImpl) PinPost(spaceID, postID, requesterID uuid.UUID, pinned bool) error {
+	viewer, err := s.viewer(spaceID, requesterID)
+	if err != nil {
+		return err
+	}
+	if !viewer.coach() {
+		return ErrSpaceContentForbidden
+	}
+	if _, err := s.post(spaceID, postID, viewer); err != nil {
+		return err
+	}
+
+	if err := s.repo.SetPostPinned(postID, pinned); err != nil {
+		return fmt.Errorf("failed to pin post with ID %s: %w", postID, err)
+	}
+	return nil
+}
+
+// HidePost implements CommunityService.
+func (s *communityServiceImpl) HidePost(spaceID, postID, requesterID uuid.UUID, hidden bool, reason string) error {
+	viewer, err := s.viewer(spaceID, requesterID)
+	if err != nil {
+		return err
+	}
+	if !viewer.moderator {
+		return ErrSpaceContentForbidden
+	}
+	if _, err := s.post(spaceID, postID, viewer); err != nil {
+		return err
+	}
+
+	hiddenBy, reason := moderation(requesterID, hidden, reason)
+	if err := s.repo.SetPostHidden(postID, hiddenBy, reason); err != nil {
+		return fmt.Errorf("failed to hide post with ID %s: %w", postID, err)
+	}
+
+	log.Printf("User %s set hidden=%v on post %s of space %s", requesterID, hidden, postID, spaceID)
+	return nil
+}
+
+// GetPostHistory implements CommunityService.
+func (s *communityServiceImpl) GetPostHistory(spaceID, postID, requesterID uuid.UUID) ([]*entity.Revision, error) {
+	viewer, err := s.viewer(spaceID, requesterID)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := s.post(spaceID, postID, viewer); err != nil {
+		return nil, err
+	}
+
+	revisions, err := s.repo.GetRevisions(entity.CommunityTargetPost, postID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get history of post with ID %s: %w", postID, err)
+	}
+	return revisions, nil
+}
+
+// GetComments implements CommunityService.
+func (s *communityServiceImpl) GetComments(spaceID, postID, requesterID uuid.UUID) ([]*entity.Comment, error) {
+	viewer, err := s.viewer(spaceID, requesterID)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := s.post(spaceID, postID, viewer); err != nil {
+		return nil, err
+	}
+
+	comments, err := s.repo.GetCommentsByPostID(postID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get comments of post with ID %s: %w", postID, err)
+	}
+
+	if err := s.countCommentReactions(comments); err != nil {
+		return nil, err
+	}
+	return threads(comments, viewer), nil
+}
+
+// AddComment implements CommunityService.
+func (s *communityServiceImpl) AddComment(spaceID, postID, authorID uuid.UUID, parentID *uuid.UUID, body string) (*entity.Comment, error) {
+	body = strings.TrimSpace(body)
+	if body == "" {
+		return nil, newError(ErrValidation, "a comment cannot be empty")
+	}
+
+	viewer, err := s.participant(spaceID, authorID)
+	if err != nil {
+		return nil, err
+	}
+	post, err := s.post(spaceID, postID, viewer)
+	if err != nil {
+		return nil, err
+	}
+	if parentID != nil {
+		if _, err := s.comment(post, *parentID, viewer); err != nil {
+			return nil, err
+		}
+	}
+
+	neoComment, err := uuid.NewV4()
+	if err != nil {
+		return nil, err
+	}
+
+	comment := &entity.Comment{
+		ID:        neoComment,
+		PostID:    post.ID,
+		ParentID:  parentID,
+		AuthorID:  authorID,
+		Body:      body,
+		Reactions: map[string]int{},
+	}
+	if err := s.repo.CreateComment(comment); err != nil {
+		return nil, fmt.Errorf("failed to create comment: %w", err)
+	}
+
+	s.notifyMentions(spaceID, authorID, commentLink(post, comment), "", body)
+	return comment, nil
+}
+
+// EditComment implements CommunityService.
+func (s *communityServiceImpl) EditComment(spaceID, postID, commentID, requesterID uuid.UUID, body string) (*entity.Comment, error) {
+	body = strings.TrimSpace(body)
+	if body == "" {
+		return nil, newError(ErrValidation, "a comment cannot be empty")
+	}
+
+	viewer, err := s.participant(spaceID, requesterID)
+	if err != nil {
+		return nil, err
+	}
+	post, err := s.post(spaceID, postID, viewer)
+	if err != nil {
+		return nil, err
+	}
+	comment, err := s.comment(post, commentID, viewer)
+	if err != nil {
+		return nil, err
+	}
+	if comment.AuthorID != requesterID {
+		return nil, ErrNotAuthor
+	}
+
+	revision, err := newRevision(entity.CommunityTargetComment, comment.ID, "", comment.Body, requesterID)
+	if err != nil {
+		return nil, err
+	}
+
+	previous := comment.Body
+	comment.Body = body
+	if err := s.repo.UpdateComment(comment, revision); err != nil {
+		return nil, fmt.Errorf("failed to update comment with ID %s: %w", commentID, err)
+	}
+
+	s.notifyMentions(spaceID, requesterID, commentLink(post, comment), previous, body)
+
+	if err := s.countCommentReactions([]*entity.Comment{comment}); err != nil {
+		return nil, err
+	}
+	return comment, nil
+}
+
+// DeleteComment implements CommunityService.
+func (s *communityServiceImpl) DeleteComment(spaceID, postID, commentID, requesterID uuid.UUID) error {
+	viewer, err := s.viewer(spaceID, requesterID)
+	if err != nil {
+		return err
+	}
+	post, err := s.post(spaceID, postID, viewer)
+	if err != nil {
+		return err
+	}
+	comment, err := s.comment(post, commentID, viewer)
+	if err != nil {
+		return err
+	}
+	if comment.AuthorID != requesterID && !viewer.moderator {
+		return ErrSpaceContentForbidden
+	}
+
+	if err := s.repo.DeleteComment(commentID); err != nil {
+		return fmt.Errorf("failed to delete comment with ID %s: %w", commentID, err)
+	}
+
+	log.Printf("User %s deleted comment %s of post %s", requesterID, commentID, postID)
+	return nil
+}
+
+// HideComment implements CommunityService.
+func (s *communityServiceImpl) HideComment(spaceID, postID, commentID, requesterID uuid.UUID, hidden bool, reason string) error {
+	viewer, err := s.viewer(spaceID, requesterID)
+	if err != nil {
+		return err
+	}
+	if !viewer.moderator {
+		return ErrSpaceContentForbidden
+	}
+	post, err := s.post(spaceID, postID, viewer)
+	if err != nil {
+		return err
+	}
+	if _, err := s.comment(post, commentID, viewer); err != nil {
+		return err
+	}
+
+	hiddenBy, reason := moderation(requesterID, hidden, reason)
+	if err := s.repo.SetCommentHidden(commentID, hiddenBy, reason); err != nil {
+		return fmt.Errorf("failed to hide comment with ID %s: %w", commentID, err)
+	}
+
+	log.Printf("User %s set hidden=%v on comment %s of post %s", requesterID, hidden, commentID, postID)
+	return nil
+}
+
+// GetCommentHistory implements CommunityService.
+func (s *communityServiceImpl) GetCommentHistory(spaceID, postID, commentID, requesterID uuid.UUID) ([]*entity.Revision, error) {
+	viewer, err := s.viewer(spaceID, requesterID)
+	if err != nil {
+		return nil, err
+	}
+	post, err := s.post(spaceID, postID, viewer)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := s.comment(post, commentID, viewer); err != nil {
+		return nil, err
+	}
+
+	revisions, err := s.repo.GetRevisions(entity.CommunityTargetComment, commentID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get history of comment with ID %s: %w", commentID, err)
+	}
+	return revisions, nil
+}
+
+// AddReaction implements CommunityService.
+func (s *communityServiceImpl) AddReaction(spaceID, postID uuid.UUID, commentID *uuid.UUID, userID uuid.UUID, emoji string) error {
+	reaction, err := s.reaction(spaceID, postID, commentID, userID, emoji)
+	if err != nil {
+		return err
+	}
+
+	if err := s.repo.AddReaction(reaction); err != nil {
+		return fmt.Errorf("failed to add reaction: %w", err)
+	}
+	return nil
+}
+
+// RemoveReaction implements CommunityService.
+func (s *communityServiceImpl) RemoveReaction(spaceID, postID uuid.UUID, commentID *uuid.UUID, userID uuid.UUID, emoji string) error {
+	reaction, err := s.reaction(spaceID, postID, commentID, userID, emoji)
+	if err != nil {
+		return err
+	}
+
+	if err := s.repo.RemoveReaction(reaction.TargetType, reaction.TargetID, userID, reaction.Emoji); err != nil {
+		return fmt.Errorf("failed to remove reaction: %w", err)
+	}
+	return nil
+}
+
+// reaction checks that the user can react to the post or comment and builds the reaction
+func (s *communityServiceImpl) reaction(spaceID, postID uuid.UUID, commentID *uuid.UUID, userID uuid.UUID, emoji string) (*entity.Reaction, error) {
+	emoji = strings.TrimSpace(emoji)
+	if emoji == "" || utf8.RuneCountInString(emoji) > maxEmojiLength {
+		return nil, newError(ErrValidation, "a reaction must be between 1 and %d characters", maxEmojiLength)
+	}
+
+	viewer, err := s.participant(spaceID, userID)
+	if err != nil {
+		return nil, err
+	}
+	post, err := s.post(spaceID, postID, viewer)
+	if err != nil {
+		return nil, err
+	}
+
+	reaction := &entity.Reaction{TargetType: entity.CommunityTargetPost, TargetID: post.ID, UserID: userID, Emoji: emoji}
+	if commentID != nil {
+		if _, err := s.comment(post, *commentID, viewer); err != nil {
+			return nil, err
+		}
+		reaction.TargetType, reaction.TargetID = entity.CommunityTargetComment, *commentID
+	}
+	return reaction, nil
+}
+
+// countPostReactions fills in the reaction counts of posts
+func (s *communityServiceImpl) countPostReactions(posts ...*entity.Post) error {
+	ids := make([]uuid.UUID, len(posts))
+	for i, post := range posts {
+		ids[i] = post.ID
+	}
+
+	counts, err := s.repo.GetReactionCounts(entity.CommunityTargetPost, ids)
+	if err != nil {
+		return fmt.Errorf("failed to count reactions: %w", err)
+	}
+	for _, post := range posts {
+		post.Reactions = reactionsOf(counts, post.ID)
+	}
+	return nil
+}
+
+// countCommentReactions fills in the reaction counts of comments
+func (s *communityServiceImpl) countCommentReactions(comments []*entity.Comment) error {
+	ids := make([]uuid.UUID, len(comments))
+	for i, comment := range comments {
+		ids[i] = comment.ID
+	}
+
+	counts, err := s.repo.GetReactionCounts(entity.CommunityTargetComment, ids)
+	if err != nil {
+		return fmt.Errorf("failed to count reactions: %w", err)
+	}
+	for _, comment := range comments {
+		comment.Reactions = reactionsOf(counts, comment.ID)
+	}
+	return nil
+}
+
+// notifyMentions notifies the members of the space mentioned in text but not already in previous.
+// Failures are logged, a post is not rejected because a notification could not be sent.
+func (s *communityServiceImpl) notifyMentions(spaceID, authorID uuid.UUID, link, previous, text string) {
+	already := mentions(previous)
+	var usernames []string
+	for username := range mentions(text) {
+		if !already[username] {
+			usernames = append(usernames, username)
+		}
+	}
+	if len(usernames) == 0 {
+		return
+	}
+
+	author, err := s.userRepo.FindByID(authorID)
+	if err != nil {
+		log.Printf("Error loading author %s of mentions: %v", authorID, err)
+		return
+	}
+	users, err := s.userRepo.FindByUsernames(usernames)
+	if err != nil {
+		log.Printf("Error loading mentioned users: %v", err)
+		return
+	}
+
+	mentioned := []uuid.UUID{}
+	for _, user := range users {
+		if user.ID == authorID {
+			continue
+		}
+		// Mentions cannot be used to reach people outside of the space
+		if _, err := s.memberRepo.Get(spaceID, user.ID); err != nil {
+			continue
+		}
+		mentioned = append(mentioned, user.ID)
+	}
+
+	s.notifications.Notify(entity.Notification{
+		Category: entity.NotificationCategorySpace,
+		Type:     entity.NotificationMention,
+		Title:    "@" + author.Username + " mentioned you",
+		Body:     excerpt(text, mentionExcerptLength),
+		Link:     link,
+	}, mentioned...)
+}
+
+// notifyNewPost tells the members of a space, except its author, that a discussion started
+func (s *communityServiceImpl) notifyNewPost(post *entity.Post) {
+	members, err := s.memberRepo.GetBySpaceID(post.SpaceID)
+	if err != nil {
+		log.Printf("Error loading members of space %s to notify: %v", post.SpaceID, err)
+		return
+	}
+
+	recipients := []uuid.UUID{}
+	for _, member := range members {
+		if member.UserID != post.AuthorID {
+			recipients = append(recipients, member.UserID)
+		}
+	}
+
+	s.notifications.Notify(entity.Notification{
+		Category: entity.NotificationCategorySpace,
+		Type:     entity.NotificationSpacePost,
+		Title:    "New discussion: " + post.Title,
+		Body:     excerpt(post.Body, mentionExcerptLength),
+		Link:     postLink(post),
+	}, recipients...)
+}
+
+// threads arranges comments into trees of replies. Deleted comments, and hidden ones the viewer
+// cannot read, lose their body and are only kept as placeholders for their visible replies.
+func threads(comments []*entity.Comment, viewer *communityViewer) []*entity.Comment {
+	byID := make(map[uuid.UUID]*entity.Comment, len(comments))
+	for _, comment := range comments {
+		byID[comment.ID] = comment
+	}
+
+	roots := []*entity.Comment{}
+	for _, comment := range comments {
+		if comment.ParentID != nil {
+			if parent, ok := byID[*comment.ParentID]; ok {
+				parent.Replies = append(parent.Replies, comment)
+				continue
+			}
+		}
+		roots = append(roots, comment)
+	}
+
+	var prune func(comments []*entity.Comment) []*entity.Comment
+	prune = func(comments []*entity.Comment) []*entity.Comment {
+		kept := []*entity.Comment{}
+		for _, comment := range comments {
+			comment.Replies = prune(comment.Replies)
+			visible := comment.DeletedAt == nil && viewer.sees(comment.AuthorID, comment.HiddenAt)
+			if !visible {
+				comment.Body = ""
+				comment.HiddenReason = ""
+				if len(comment.Replies) == 0 {
+					continue
+				}
+			}
+			kept = append(kept, comment)
+		}
+		return kept
+	}
+	return prune(roots)
+}
+
+// mentions returns the usernames mentioned in a text
+func mentions(text string) map[string]bool {
+	usernames := map[string]bool{}
+	for _, match := range mentionPattern.FindAllStringSubmatch(text, -1) {
+		// A mention at the end of a sentence does not include the full stop
+		username := strings.TrimRight(match[1], ".-")
+		if username != "" {
+			usernames[username] = true
+		}
+	}
+	return usernames
+}
+
+// newRevision records the content of a post or comment before an edit
+func newRevision(targetType string, targetID uuid.UUID, title, body string, editedBy uuid.UUID) (*entity.Revision, error) {
+	neoRevision, err := uuid.NewV4()
+	if err != nil {
+		return nil, err
+	}
+	return &entity.Revision{
+		ID:         neoRevision,
+		TargetType: targetType,
+		TargetID:   targetID,
+		Title:      title,
+		Body:       body,
+		EditedBy:   editedBy,
+		EditedAt:   time.Now(),
+	}, nil
+}
+
+// moderation returns who hides content and why, or nothing when the content is shown again
+func moderation(moderatorID uuid.UUID, hidden bool, reason string) (*uuid.UUID, string) {
+	if !hidden {
+		return nil, ""
+	}
+	return &moderatorID, strings.TrimSpace(reason)
+}
+
+// reactionsOf returns the reaction counts of a target, never nil
+func reactionsOf(counts map[uuid.UUID]map[string]int, targetID uuid.UUID) map[string]int {
+	if reactions, ok := counts[targetID]; ok {
+		return reactions
+	}
+	return map[string]int{}
+}
+
+// excerpt shortens text to at most length characters
+func excerpt(text string, length int) string {
+	text = strings.TrimSpace(text)
+	if utf8.RuneCountInString(text) <= length {
+		return text
+	}
+	return string([]rune(text)[:length]) + "…"
+}
+
+func postLink(post *entity.Post) string {
+	return "/spaces/" + post.SpaceID.String() + "/posts/" + post.ID.String()
+}
+
+func commentLink(post *entity.Post, comment *entity.Comment) string {
+	return postLink(post) + "/comments#" + comment.ID.String()
+}
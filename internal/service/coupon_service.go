@@ -0,0 +1,245 @@
+package service
+
+import (
+	"dalabio/internal/entity"
+	"dalabio/internal/repository"
+	"dalabio/pkg/validation"
+	"fmt"
+	"log"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/gofrs/uuid"
+)
+
+// Coupon discount types
+const (
+	CouponTypePercentage = "percentage"
+	CouponTypeFixed      = "fixed"
+)
+
+type CouponService interface {
+
+	// CreateCoupon creates a new coupon
+	CreateCoupon(coupon *entity.Coupon) (*entity.Coupon, error)
+
+	// UpdateCoupon updates an existing coupon
+	UpdateCoupon(coupon *entity.Coupon) error
+
+	// DeleteCoupon deletes a coupon
+	DeleteCoupon(couponID uuid.UUID) error
+
+	// GetCouponByID gets a coupon by ID
+	GetCouponByID(couponID uuid.UUID) (*entity.Coupon, error)
+
+	// GetAllCoupons gets all coupons
+	GetAllCoupons() ([]*entity.Coupon, error)
+
+	// ValidateCoupon checks that a user may apply a coupon to the given lines and returns the discount it grants
+	ValidateCoupon(code string, userID uuid.UUID, lines []*entity.OrderLine, currency string) (*entity.Coupon, float64, error)
+
+	// RedeemCoupon records the use of a coupon on an order
+	RedeemCoupon(couponID, userID, orderID uuid.UUID, discount float64) error
+
+	// ReleaseCoupon gives back the redemption made for an order that was never paid
+	ReleaseCoupon(orderID uuid.UUID) error
+}
+
+type couponServiceImpl struct {
+	repo repository.CouponRepository
+}
+
+func NewCouponService(couponRepo repository.CouponRepository) CouponService {
+	return &couponServiceImpl{repo: couponRepo}
+}
+
+// normalizeCoupon upper-cases the code and checks the discount settings of a coupon
+func normalizeCoupon(coupon *entity.Coupon) error {
+	coupon.Code = strings.ToUpper(strings.TrimSpace(coupon.Code))
+	coupon.Currency = strings.ToUpper(coupon.Currency)
+
+	v := validation.New()
+	v.Length("code", coupon.Code, 1, 50)
+	v.NotNegative("max_redemptions", float64(coupon.MaxRedemptions))
+	v.NotNegative("per_user_limit", float64(coupon.PerUserLimit))
+
+	if v.OneOf("discount_type", coupon.DiscountType, CouponTypePercentage, CouponTypeFixed) && v.Positive("value", coupon.Value) {
+		switch coupon.DiscountType {
+		case CouponTypePercentage:
+			v.Check(coupon.Value <= 100, "value", validation.CodeTooLarge, "must be at most 100 percent")
+		case CouponTypeFixed:
+			v.Currency("currency", coupon.Currency)
+		}
+	}
+
+	return v.Err()
+}
+
+// CreateCoupon implements CouponService.
+func (s *couponServiceImpl) CreateCoupon(coupon *entity.Coupon) (*entity.Coupon, error) {
+	if err := normalizeCoupon(coupon); err != nil {
+		return nil, err
+	}
+
+	if _, err := s.repo.GetByCode(coupon.Code); err == nil {
+		return nil, newError(ErrConflict, "coupon with code %s already exists", coupon.Code)
+	}
+
+	neoCoupon, err := uuid.NewV4()
+	if err != nil {
+		return nil, err
+	}
+	coupon.ID = neoCoupon
+	coupon.RedemptionCount = 0
+
+	if err := s.repo.Create(coupon); err != nil {
+		return nil, fmt.Errorf("failed to create coupon: %w", err)
+	}
+
+	return coupon, nil
+}
+
+// UpdateCoupon implements CouponService.
+func (s *couponServiceImpl) UpdateCoupon(coupon *entity.Coupon) error {
+	if _, err := s.repo.GetdByID(coupon.ID); err != nil {
+		return fmt.Errorf("could not find coupon with ID %s: %w", coupon.ID, err)
+	}
+
+	if err := normalizeCoupon(coupon); err != nil {
+		return err
+	}
+
+	if err := s.repo.Update(coupon); err != nil {
+		return fmt.Errorf("failed to update coupon with ID %s: %w", coupon.ID, err)
+	}
+
+	return nil
+}
+
+// DeleteCoupon implements CouponService.
+func (s *couponServiceImpl) DeleteCoupon(couponID uuid.UUID) error {
+	if err := s.repo.Delete(couponID); err != nil {
+		return fmt.Errorf("failed to delete coupon with ID %s: %w", couponID, err)
+	}
+
+	log.Printf("Successfully deleted coupon with ID %s", couponID)
+	return nil
+}
+
+// GetCouponByID implements CouponService.
+func (s *couponServiceImpl) GetCouponByID(couponID uuid.UUID) (*entity.Coupon, error) {
+	coupon, err := s.repo.GetdByID(couponID)
+	if err != nil {
+		return nil, fmt.Errorf("could not find coupon with ID %s: %w", couponID, err)
+	}
+
+	return coupon, nil
+}
+
+// GetAllCoupons implements CouponService.
+func (s *couponServiceImpl) GetAllCoupons() ([]*entity.Coupon, error) {
+	coupons, err := s.repo.GetAll()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get all coupons: %w", err)
+	}
+
+	return coupons, nil
+}
+
+// appliesTo reports whether a coupon covers an order line
+func appliesTo(coupon *entity.Coupon, line *entity.OrderLine) bool {
+	if len(coupon.CourseIDs) == 0 && len(coupon.SpaceIDs) == 0 {
+		return true
+	}
+
+	ids := coupon.CourseIDs
+	if line.ItemType != OrderItemCourse {
+		ids = coupon.SpaceIDs
+	}
+	for _, id := range ids {
+		if id == line.ItemID {
+			return true
+		}
+	}
+	return false
+}
+
+// ValidateCoupon implements CouponService.
+func (s *couponServiceImpl) ValidateCoupon(code string, userID uuid.UUID, lines []*entity.OrderLine, currency string) (*entity.Coupon, float64, error) {
+	coupon, err := s.repo.GetByCode(strings.ToUpper(strings.TrimSpace(code)))
+	if err != nil {
+		return nil, 0, newError(ErrValidation, "invalid coupon code %s", code)
+	}
+
+	if !coupon.Active {
+		return nil, 0, newError(ErrValidation, "coupon %s is not active", coupon.Code)
+	}
+	if coupon.ExpiresAt != nil && coupon.ExpiresAt.Before(time.Now()) {
+		return nil, 0, newError(ErrValidation, "coupon %s has expired", coupon.Code)
+	}
+	if coupon.MaxRedemptions > 0 && coupon.RedemptionCount >= coupon.MaxRedemptions {
+		return nil, 0, newError(ErrValidation, "coupon %s has reached its maximum number of redemptions", coupon.Code)
+	}
+	if coupon.PerUserLimit > 0 {
+		used, err := s.repo.CountUserRedemptions(coupon.ID, userID)
+		if err != nil {
+			return nil, 0, err
+		}
+		if used >= coupon.PerUserLimit {
+			return nil, 0, newError(ErrValidation, "coupon %s has already been used the maximum number of times", coupon.Code)
+		}
+	}
+	if coupon.DiscountType == CouponTypeFixed && coupon.Currency != currency {
+		return nil, 0, newError(ErrValidation, "coupon %s cannot be used with %s", coupon.Code, currency)
+	}
+
+	var eligible float64
+	for _, line := range lines {
+		if appliesTo(coupon, line) {
+			eligible += line.Amount
+		}
+	}
+	if eligible == 0 {
+		return nil, 0, newError(ErrValidation, "coupon %s does not apply to any item of the order", coupon.Code)
+	}
+
+	discount := coupon.Value
+	if coupon.DiscountType == CouponTypePercentage {
+		discount = eligible * coupon.Value / 100
+	}
+	// A discount never makes the eligible items cost less than nothing
+	discount = math.Min(discount, eligible)
+
+	return coupon, math.Round(discount*100) / 100, nil
+}
+
+// RedeemCoupon implements CouponService.
+func (s *couponServiceImpl) RedeemCoupon(couponID, userID, orderID uuid.UUID, discount float64) error {
+	neoRedemption, err := uuid.NewV4()
+	if err != nil {
+		return err
+	}
+
+	redemption := &entity.CouponRedemption{
+		ID:       neoRedemption,
+		CouponID: couponID,
+		UserID:   userID,
+		OrderID:  orderID,
+		Discount: discount,
+	}
+	if err := s.repo.Redeem(redemption); err != nil {
+		return fmt.Errorf("failed to redeem coupon: %w", err)
+	}
+
+	return nil
+}
+
+// ReleaseCoupon implements CouponService.
+func (s *couponServiceImpl) ReleaseCoupon(orderID uuid.UUID) error {
+	if err := s.repo.ReleaseByOrderID(orderID); err != nil {
+		return fmt.Errorf("failed to release coupon of order with ID %s: %w", orderID, err)
+	}
+
+	return nil
+}
@@ -3,6 +3,7 @@ package service
 import (
 	"dalabio/internal/entity"
 	"dalabio/internal/repository"
+	"dalabio/pkg/validation"
 	"fmt"
 	"log"
 	"time"
@@ -12,61 +13,107 @@ import (
 
 // CourseService interface
 type CourseService interface {
-	CreateCourse(Title, Description, Duration, Category, Outline string, ContentURLs []string, Status string, EnrolledCount int, version uuid.UUID, instructorID uuid.UUID) (*entity.Course, error)
-	UpdateCourse(course *entity.Course) error
-	DeleteCourse(courseID uuid.UUID) error
-	GetCourseByID(courseID uuid.UUID) (*entity.Course, error)
-	GetAllCourses() ([]*entity.Course, error)
+	CreateCourse(Title, Description, Duration, Category, Outline string, ContentURLs []string, Status string, Price float64, Currency string, instructorID uuid.UUID, spaceID *uuid.UUID, membersOnly bool) (*entity.Course, error)
+	UpdateCourse(course *entity.Course, requesterID uuid.UUID) error
+	DeleteCourse(courseID, requesterID uuid.UUID) error
+	GetCourseByID(courseID, requesterID uuid.UUID) (*entity.Course, error)
+	GetAllCourses(requesterID uuid.UUID) ([]*entity.Course, error)
+
+	// GetSpaceCourses returns the courses of a space, hiding member-only courses from non-members
+	GetSpaceCourses(spaceID, requesterID uuid.UUID) ([]*entity.Course, error)
 }
 
 // courseServiceImpl struct implementing CourseService
 type courseServiceImpl struct {
-	repo      repository.CourseRepository
-	tokenRepo repository.TokenRepository
+	repo       repository.CourseRepository
+	memberRepo repository.SpaceMemberRepository
+	tokenRepo  repository.TokenRepository
 }
 
 // NewCourseService creates a new instance of CourseService
-func NewCourseService(coureRepo repository.CourseRepository, tokenRepo repository.TokenRepository) CourseService {
+func NewCourseService(coureRepo repository.CourseRepository, memberRepo repository.SpaceMemberRepository, tokenRepo repository.TokenRepository) CourseService {
 	return &courseServiceImpl{
-		repo:      coureRepo,
-		tokenRepo: tokenRepo,
+		repo:       coureRepo,
+		memberRepo: memberRepo,
+		tokenRepo:  tokenRepo,
 	}
 }
 
 // GetAllCourses implements CourseService.
-func (s *courseServiceImpl) GetAllCourses() ([]*entity.Course, error) {
+func (s *courseServiceImpl) GetAllCourses(requesterID uuid.UUID) ([]*entity.Course, error) {
 	course, err := s.repo.GetAll()
 
 	if err != nil {
-		return nil, fmt.Errorf("failed to get all courses: %v", err)
+		return nil, fmt.Errorf("failed to get all courses: %w", err)
 	}
 
-	return course, nil
+	return s.visible(course, requesterID), nil
+
+}
+
+// GetSpaceCourses implements CourseService.
+func (s *courseServiceImpl) GetSpaceCourses(spaceID, requesterID uuid.UUID) ([]*entity.Course, error) {
+	courses, err := s.repo.GetBySpaceID(spaceID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get courses of space with ID %s: %w", spaceID, err)
+	}
+
+	return s.visible(courses, requesterID), nil
+}
 
+// visible filters out the member-only courses of spaces the requester is not a member of
+func (s *courseServiceImpl) visible(courses []*entity.Course, requesterID uuid.UUID) []*entity.Course {
+	visibility := newSpaceVisibility(s.memberRepo, requesterID)
+	result := []*entity.Course{}
+	for _, course := range courses {
+		if visibility.canSee(course.SpaceID, course.MembersOnly) {
+			result = append(result, course)
+		}
+	}
+	return result
 }
 
-func (s *courseServiceImpl) CreateCourse(Title, Description, Duration, Category, Outline string, ContentURLs []string, Status string, EnrolledCount int, version uuid.UUID, instructorID uuid.UUID) (*entity.Course, error) {
+func (s *courseServiceImpl) CreateCourse(Title, Description, Duration, Category, Outline string, ContentURLs []string, Status string, Price float64, Currency string, instructorID uuid.UUID, spaceID *uuid.UUID, membersOnly bool) (*entity.Course, error) {
+	if membersOnly && spaceID == nil {
+		return nil, newError(ErrValidation, "a member-only course must belong to a space")
+	}
+	// Only the people running a space can add courses to it
+	if err := requireSpaceManager(s.memberRepo, spaceID, instructorID); err != nil {
+		return nil, err
+	}
+
 	// Generate a new UUID for the course ID
 	neoCourse, err := uuid.NewV4()
 	if err != nil {
 		return nil, err
 	}
+	version, err := uuid.NewV4()
+	if err != nil {
+		return nil, err
+	}
 
 	// Create a new course instance
 	newCourse := &entity.Course{
-		ID:            neoCourse,
-		Title:         Title,
-		Description:   Description,
-		Duration:      Duration,
-		Version:       version,
-		Category:      Category,
-		InstructorID:  instructorID, // Make sure you pass the instructorID
-		EnrolledCount: EnrolledCount,
-		ContentURL:    ContentURLs,
-		Outline:       Outline,
-		Status:        Status,
-		CreatedAt:     time.Now(), // Set created_at
-		UpdatedAt:     time.Now(), // Set updated_at
+		ID:           neoCourse,
+		Title:        Title,
+		Description:  Description,
+		Duration:     Duration,
+		Version:      version,
+		Category:     Category,
+		InstructorID: instructorID, // Make sure you pass the instructorID
+		SpaceID:      spaceID,
+		MembersOnly:  membersOnly,
+		ContentURL:   ContentURLs,
+		Outline:      Outline,
+		Status:       Status,
+		Price:        Price,
+		Currency:     Currency,
+		CreatedAt:    time.Now(), // Set created_at
+		UpdatedAt:    time.Now(), // Set updated_at
+	}
+
+	if err := validateCourse(newCourse); err != nil {
+		return nil, err
 	}
 
 	// Log the new course creation attempt
@@ -75,35 +122,69 @@ func (s *courseServiceImpl) CreateCourse(Title, Description, Duration, Category,
 	// Save the new course to the repository
 	err = s.repo.Create(newCourse)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create course: %v", err)
+		return nil, fmt.Errorf("failed to create course: %w", err)
 	}
 
 	return newCourse, nil
 }
 
 // UpdateCourse updates an existing course
-func (s *courseServiceImpl) UpdateCourse(course *entity.Course) error {
-	_, err := s.repo.GetdByID(course.ID)
+func (s *courseServiceImpl) UpdateCourse(course *entity.Course, requesterID uuid.UUID) error {
+	if err := validateCourse(course); err != nil {
+		return err
+	}
+
+	existing, err := s.repo.GetdByID(course.ID)
 	if err != nil {
-		return fmt.Errorf("could not find course with ID %s", course.ID)
+		return fmt.Errorf("could not find course with ID %s: %w", course.ID, err)
+	}
+	if course.MembersOnly && course.SpaceID == nil {
+		return newError(ErrValidation, "a member-only course must belong to a space")
 	}
 
+	// Moving a course in or out of a space needs the right to manage both
+	if err := requireCourseManager(s.memberRepo, existing, requesterID); err != nil {
+		return err
+	}
+	if err := requireSpaceManager(s.memberRepo, course.SpaceID, requesterID); err != nil {
+		return err
+	}
+
+	// Enrollments and authorship are not edited with the course
+	course.InstructorID = existing.InstructorID
+	course.EnrolledCount = existing.EnrolledCount
+	course.Version = existing.Version
+
 	if err := s.repo.Update(course); err != nil {
-		return fmt.Errorf("failed to update course with ID %s: %v", course.ID, err)
+		return fmt.Errorf("failed to update course with ID %s: %w", course.ID, err)
 	}
 
 	return nil
 }
 
+// validateCourse checks the fields of a course set by its instructor
+func validateCourse(course *entity.Course) error {
+	v := validation.New()
+	v.Length("title", course.Title, 1, 200)
+	v.MaxLength("description", course.Description, 10000)
+	v.URLs("content_url", course.ContentURL)
+	v.NotNegative("price", course.Price)
+	validatePriceCurrency(v, course.Price, course.Currency)
+	return v.Err()
+}
+
 // DeleteCourse deletes a course by its ID
-func (s *courseServiceImpl) DeleteCourse(courseID uuid.UUID) error {
-	_, err := s.repo.GetdByID(courseID)
+func (s *courseServiceImpl) DeleteCourse(courseID, requesterID uuid.UUID) error {
+	course, err := s.repo.GetdByID(courseID)
 	if err != nil {
-		return fmt.Errorf("could not find course with ID %s: %v", courseID, err)
+		return fmt.Errorf("could not find course with ID %s: %w", courseID, err)
+	}
+	if err := requireCourseManager(s.memberRepo, course, requesterID); err != nil {
+		return err
 	}
 
 	if err := s.repo.Delete(courseID); err != nil {
-		return fmt.Errorf("failed to delete course with ID %s: %v", courseID, err)
+		return fmt.Errorf("failed to delete course with ID %s: %w", courseID, err)
 	}
 
 	log.Printf("Successfully deleted course with ID %s", courseID)
@@ -111,10 +192,13 @@ func (s *courseServiceImpl) DeleteCourse(courseID uuid.UUID) error {
 }
 
 // GetCourseByID retrieves a course by its ID
-func (s *courseServiceImpl) GetCourseByID(courseID uuid.UUID) (*entity.Course, error) {
+func (s *courseServiceImpl) GetCourseByID(courseID, requesterID uuid.UUID) (*entity.Course, error) {
 	course, err := s.repo.GetdByID(courseID)
 	if err != nil {
-		return nil, fmt.Errorf("could not find course with ID %s: %v", courseID, err)
+		return nil, fmt.Errorf("could not find course with ID %s: %w", courseID, err)
+	}
+	if !newSpaceVisibility(s.memberRepo, requesterID).canSee(course.SpaceID, course.MembersOnly) {
+		return nil, ErrMembersOnly
 	}
 
 	return course, nil
@@ -0,0 +1,357 @@
+package service
+
+import (
+	"dalabio/internal/entity"
+	"dalabio/internal/framework/mail"
+	"dalabio/internal/repository"
+	"dalabio/pkg/config"
+	"fmt"
+	"log"
+	netmail "net/mail"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/gofrs/uuid"
+)
+
+// Outbox email statuses
+const (
+	EmailStatusPending    = "pending"
+	EmailStatusSent       = "sent"
+	EmailStatusFailed     = "failed"     // Given up after maxEmailAttempts
+	EmailStatusSuppressed = "suppressed" // The recipient was suppressed before the email left
+)
+
+// Reasons an address is suppressed for
+const (
+	SuppressionBounce    = "bounce"
+	SuppressionComplaint = "complaint"
+	SuppressionManual    = "manual"
+)
+
+// Bounce kinds reported by the mail provider
+const (
+	BounceHard      = "hard" // The address does not exist, it is suppressed
+	BounceSoft      = "soft" // Temporary failure such as a full mailbox, the address is kept
+	BounceComplaint = "complaint"
+)
+
+// Delivery of the outbox
+const (
+	maxEmailAttempts = 8
+	emailRetryBase   = time.Minute // Delay before the first retry, doubled after each failure
+	emailRetryMax    = 6 * time.Hour
+	emailBatchSize   = 50
+	emailLease       = 5 * time.Minute // How long a claimed email is hidden from other instances
+)
+
+// Email templates
+const (
+	EmailNotification  = "notification"
+	EmailMeetingInvite = "meeting_invite"
+	EmailReceipt       = "receipt"
+	EmailVerifyEmail   = "verify_email"
+	EmailResetPassword = "reset_password"
+	EmailAccountLocked = "account_locked"
+)
+
+type EmailService interface {
+
+	// Send renders a template in the locale of the recipient and queues the email.
+	// Emails to suppressed addresses are dropped.
+	Send(to, template, locale string, data interface{}) error
+
+	// ProcessOutbox sends the queued emails that are due, retrying failures with exponential backoff
+	ProcessOutbox(now time.Time) error
+
+	// RecordBounce handles a bounce or complaint reported by the mail provider
+	RecordBounce(email, kind, detail string) error
+
+	GetSuppressions() ([]*entity.EmailSuppression, error)
+
+	// Suppress stops every email to an address
+	Suppress(email, detail string) error
+
+	// Unsuppress allows emails to an address again
+	Unsuppress(email string) error
+
+	// SendMeetingInvites emails the invitation to a meeting to its attendees
+	SendMeetingInvites(meeting *entity.Meeting)
+
+	// SendNotification emails a notification; it is the NotificationMailer of the notification center
+	SendNotification(user *entity.User, notification *entity.Notification) error
+
+	// PaymentCompleted emails the receipt of a payment
+	PaymentCompleted(payment *entity.Payment) error
+
+	// SendEmailVerification emails the link a user follows to verify their email
+	SendEmailVerification(user *entity.User, token string, validFor time.Duration) error
+
+	// SendPasswordReset emails the link a user follows to choose a new password
+	SendPasswordReset(user *entity.User, token string, validFor time.Duration) error
+
+	// SendAccountLocked tells a user that their account was locked after too many failed sign-ins
+	SendAccountLocked(user *entity.User, failures int, lockedFor time.Duration) error
+}
+
+type emailServiceImpl struct {
+	repo      repository.EmailRepository
+	userRepo  repository.UserRepository
+	mailer    mail.Mailer
+	templates *mail.Templates
+	config    *config.MailConfig
+}
+
+func NewEmailService(emailRepo repository.EmailRepository, userRepo repository.UserRepository, mailer mail.Mailer, templates *mail.Templates, mailConfig *config.MailConfig) EmailService {
+	return &emailServiceImpl{
+		repo:      emailRepo,
+		userRepo:  userRepo,
+		mailer:    mailer,
+		templates: templates,
+		config:    mailConfig,
+	}
+}
+
+// Send implements EmailService.
+func (s *emailServiceImpl) Send(to, template, locale string, data interface{}) error {
+	address, err := netmail.ParseAddress(to)
+	if err != nil {
+		return fmt.Errorf("invalid email address %q: %w", to, err)
+	}
+
+	suppressed, err := s.repo.IsSuppressed(address.Address)
+	if err != nil {
+		return fmt.Errorf("failed to check suppression of %s: %w", address.Address, err)
+	}
+	if suppressed {
+		log.Printf("Not sending %s to suppressed address %s", template, address.Address)
+		return nil
+	}
+
+	if locale == "" {
+		locale = s.config.DefaultLocale
+	}
+	rendered, err := s.templates.Render(template, locale, data)
+	if err != nil {
+		return fmt.Errorf("failed to render email %s: %w", template, err)
+	}
+
+	neoEmail, err := uuid.NewV4()
+	if err != nil {
+		return err
+	}
+
+	email := &entity.OutboxEmail{
+		ID:        neoEmail,
+		Recipient: address.Address,
+		Subject:   rendered.Subject,
+		HTMLBody:  rendered.HTML,
+		TextBody:  rendered.Text,
+		Template:  template,
+		Status:    EmailStatusPending,
+	}
+	if err := s.repo.Enqueue(email); err != nil {
+		return fmt.Errorf("failed to queue email %s: %w", template, err)
+	}
+	return nil
+}
+
+// ProcessOutbox implements EmailService.
+func (s *emailServiceImpl) ProcessOutbox(now time.Time) error {
+	emails, err := s.repo.ClaimDue(now, now.Add(emailLease), emailBatchSize)
+	if err != nil {
+		return fmt.Errorf("failed to claim due emails: %w", err)
+	}
+
+	for _, email := range emails {
+		s.deliver(email, now)
+		if err := s.repo.UpdateDelivery(email); err != nil {
+			log.Printf("Failed to record delivery of email %s: %v", email.ID, err)
+		}
+	}
+	return nil
+}
+
+// deliver attempts to send an email and updates its status with the outcome
+func (s *emailServiceImpl) deliver(email *entity.OutboxEmail, now time.Time) {
+	// The address may have bounced since the email was queued
+	suppressed, err := s.repo.IsSuppressed(email.Recipient)
+	if err == nil && suppressed {
+		email.Status = EmailStatusSuppressed
+		return
+	}
+
+	email.Attempts++
+	err = s.mailer.Send(&mail.Message{
+		From:    s.config.From,
+		To:      email.Recipient,
+		Subject: email.Subject,
+		HTML:    email.HTMLBody,
+		Text:    email.TextBody,
+	})
+	if err == nil {
+		email.Status = EmailStatusSent
+		email.SentAt = &now
+		email.LastError = ""
+		return
+	}
+
+	email.LastError = err.Error()
+	if email.Attempts >= maxEmailAttempts {
+		email.Status = EmailStatusFailed
+		log.Printf("Giving up on email %s to %s after %d attempts: %v", email.ID, email.Recipient, email.Attempts, err)
+		return
+	}
+	email.NextAttemptAt = now.Add(emailRetryDelay(email.Attempts))
+}
+
+// emailRetryDelay is the backoff after a number of failed attempts: 1m, 2m, 4m... up to emailRetryMax
+func emailRetryDelay(attempts int) time.Duration {
+	delay := emailRetryBase
+	for i := 1; i < attempts && delay < emailRetryMax; i++ {
+		delay *= 2
+	}
+	if delay > emailRetryMax {
+		delay = emailRetryMax
+	}
+	return delay
+}
+
+// RecordBounce implements EmailService.
+func (s *emailServiceImpl) RecordBounce(email, kind, detail string) error {
+	switch kind {
+	case BounceSoft:
+		log.Printf("Soft bounce for %s: %s", email, detail)
+		return nil
+	case BounceHard:
+		return s.suppress(email, SuppressionBounce, detail)
+	case BounceComplaint:
+		return s.suppress(email, SuppressionComplaint, detail)
+	default:
+		return newError(ErrValidation, "unknown bounce kind %q", kind)
+	}
+}
+
+// Suppress implements EmailService.
+func (s *emailServiceImpl) Suppress(email, detail string) error {
+	return s.suppress(email, SuppressionManual, detail)
+}
+
+func (s *emailServiceImpl) suppress(email, reason, detail string) error {
+	address, err := netmail.ParseAddress(email)
+	if err != nil {
+		return fmt.Errorf("invalid email address %q: %w", email, err)
+	}
+
+	if err := s.repo.Suppress(&entity.EmailSuppression{Email: address.Address, Reason: reason, Detail: detail}); err != nil {
+		return fmt.Errorf("failed to suppress %s: %w", address.Address, err)
+	}
+	log.Printf("Suppressed %s (%s)", address.Address, reason)
+	return nil
+}
+
+// Unsuppress implements EmailService.
+func (s *emailServiceImpl) Unsuppress(email string) error {
+	if err := s.repo.Unsuppress(strings.TrimSpace(email)); err != nil {
+		return fmt.Errorf("could not find suppression of %s: %w", email, err)
+	}
+	return nil
+}
+
+// GetSuppressions implements EmailService.
+func (s *emailServiceImpl) GetSuppressions() ([]*entity.EmailSuppression, error) {
+	suppressions, err := s.repo.GetSuppressions()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get suppressions: %w", err)
+	}
+	return suppressions, nil
+}
+
+// link turns a path of the application into an absolute URL for emails
+func (s *emailServiceImpl) link(path string) string {
+	if path == "" {
+		return ""
+	}
+	return strings.TrimRight(s.config.BaseURL, "/") + path
+}
+
+// SendNotification implements EmailService.
+func (s *emailServiceImpl) SendNotification(user *entity.User, notification *entity.Notification) error {
+	return s.Send(user.Email, EmailNotification, user.Locale, struct {
+		Name, Title, Body, Link string
+	}{displayName(user), notification.Title, notification.Body, s.link(notification.Link)})
+}
+
+// SendMeetingInvites implements EmailService.
+func (s *emailServiceImpl) SendMeetingInvites(meeting *entity.Meeting) {
+	for i, email := range meeting.AttendeeEmails {
+		name := email
+		if i < len(meeting.AttendeeNames) && meeting.AttendeeNames[i] != "" {
+			name = meeting.AttendeeNames[i]
+		}
+
+		err := s.Send(email, EmailMeetingInvite, s.localeOf(email), struct {
+			Name, Title, Description, Start, Duration, Location, Link string
+			JoinURLs                                                  []string
+		}{name, meeting.Title, meeting.Description, meeting.StartTime.Format("Mon 2 Jan 2006 15:04 MST"), meeting.Duration,
+			meeting.Location, s.link("/meetings/" + meeting.ID.String()), meeting.JoinURL})
+		if err != nil {
+			log.Printf("Failed to invite %s to meeting %s: %v", email, meeting.ID, err)
+		}
+	}
+}
+
+// PaymentCompleted implements PaymentListener.
+func (s *emailServiceImpl) PaymentCompleted(payment *entity.Payment) error {
+	user, err := s.userRepo.FindByID(payment.UserID)
+	if err != nil {
+		return fmt.Errorf("could not find user with ID %s: %w", payment.UserID, err)
+	}
+
+	return s.Send(user.Email, EmailReceipt, user.Locale, struct {
+		Name, Amount, Currency, Date, PaymentID, Method, Link string
+	}{displayName(user), fmt.Sprintf("%.2f", payment.Amount), payment.Currency, payment.PaymentDate.Format("2 Jan 2006"),
+		payment.ID.String(), payment.PaymentMethod, s.link("/payments/" + payment.ID.String() + "/invoice")})
+}
+
+// SendEmailVerification implements EmailService.
+func (s *emailServiceImpl) SendEmailVerification(user *entity.User, token string, validFor time.Duration) error {
+	return s.Send(user.Email, EmailVerifyEmail, user.Locale, struct {
+		Name, Link string
+		Hours      int
+	}{displayName(user), s.link("/users/verify?token=" + url.QueryEscape(token)), int(validFor.Hours())})
+}
+
+// SendPasswordReset implements EmailService.
+func (s *emailServiceImpl) SendPasswordReset(user *entity.User, token string, validFor time.Duration) error {
+	return s.Send(user.Email, EmailResetPassword, user.Locale, struct {
+		Name, Link string
+		Minutes    int
+	}{displayName(user), s.link("/users/password/reset?token=" + url.QueryEscape(token)), int(validFor.Minutes())})
+}
+
+// SendAccountLocked implements EmailService.
+func (s *emailServiceImpl) SendAccountLocked(user *entity.User, failures int, lockedFor time.Duration) error {
+	return s.Send(user.Email, EmailAccountLocked, user.Locale, struct {
+		Name              string
+		Failures, Minutes int
+	}{displayName(user), failures, int(lockedFor.Minutes())})
+}
+
+// localeOf returns the locale of the user an address belongs to, empty for the default locale when it belongs to nobody
+func (s *emailServiceImpl) localeOf(email string) string {
+	user, err := s.userRepo.FindByEmail(email)
+	if err != nil {
+		return ""
+	}
+	return user.Locale
+}
+
+// displayName is how a user is greeted in emails
+func displayName(user *entity.User) string {
+	if user.FirstName != "" {
+		return user.FirstName
+	}
+	return user.Username
+}
@@ -0,0 +1,71 @@
+package service
+
+import (
+	"dalabio/internal/repository"
+	"dalabio/pkg/validation"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Kinds of failure of the services. Every error a service returns wraps at most one of them,
+// which the HTTP layer maps to a status code; errors of no kind are internal errors.
+var (
+	// ErrNotFound is returned when a requested resource does not exist
+	ErrNotFound = repository.ErrNotFound
+
+	// ErrConflict is returned when a request clashes with the current state, e.g. a duplicate email
+	ErrConflict = repository.ErrConflict
+
+	// ErrUnauthorized is returned when the caller could not be authenticated
+	ErrUnauthorized = errors.New("unauthorized")
+
+	// ErrForbidden is returned when the caller is not allowed to do what they asked
+	ErrForbidden = errors.New("forbidden")
+
+	// ErrValidation is returned when a request is invalid; *validation.Errors details the invalid fields
+	ErrValidation = validation.ErrInvalid
+
+	// ErrTooManyRequests is returned when the caller must wait before trying again
+	ErrTooManyRequests = errors.New("too many requests")
+)
+
+// kindError is an error of one of the kinds above with a message of its own
+type kindError struct {
+	kind    error
+	message string
+}
+
+func (e *kindError) Error() string {
+	return e.message
+}
+
+func (e *kindError) Unwrap() error {
+	return e.kind
+}
+
+// newError returns an error of the given kind
+func newError(kind error, format string, args ...interface{}) error {
+	return &kindError{kind: kind, message: fmt.Sprintf(format, args...)}
+}
+
+// retryError is an error of kind ErrTooManyRequests telling when the caller may try again
+type retryError struct {
+	kindError
+	retryAfter time.Duration
+}
+
+// RetryAfter is how long the caller has to wait
+func (e *retryError) RetryAfter() time.Duration {
+	return e.retryAfter
+}
+
+// newRetryError returns an error of kind ErrTooManyRequests that can be retried after a delay
+func newRetryError(retryAfter time.Duration, format string, args ...interface{}) error {
+	return &retryError{kindError: kindError{kind: ErrTooManyRequests, message: fmt.Sprintf(format, args...)}, retryAfter: retryAfter}
+}
+
+// NewError returns an error of the given kind, for the HTTP layer to reject a request that never reaches a service
+func NewError(kind error, message string) error {
+	return &kindError{kind: kind, message: message}
+}
@@ -0,0 +1,127 @@
+package service
+
+import (
+	"dalabio/internal/entity"
+	"dalabio/internal/framework/realtime"
+	"dalabio/internal/repository"
+	"log"
+
+	"github.com/gofrs/uuid"
+)
+
+// Domain events streamed to the clients
+const (
+	EventMeetingUpdated       = "meeting.updated"
+	EventMeetingCancelled     = "meeting.cancelled"
+	EventPostCreated          = "post.created"
+	EventPaymentStatusChanged = "payment.status_changed"
+	EventEnrollmentCreated    = "enrollment.created"
+	EventNotificationCreated  = "notification.created"
+)
+
+// MeetingEvent is the payload of the meeting events
+type MeetingEvent struct {
+	MeetingID uuid.UUID  `json:"meeting_id"`
+	SpaceID   *uuid.UUID `json:"space_id,omitempty"`
+	Title     string     `json:"title"`
+	Status    string     `json:"status"`
+}
+
+// PostEvent is the payload of the post events
+type PostEvent struct {
+	PostID   uuid.UUID `json:"post_id"`
+	SpaceID  uuid.UUID `json:"space_id"`
+	AuthorID uuid.UUID `json:"author_id"`
+	Title    string    `json:"title"`
+}
+
+// PaymentEvent is the payload of the payment events
+type PaymentEvent struct {
+	PaymentID      uuid.UUID `json:"payment_id"`
+	OrderID        uuid.UUID `json:"order_id"`
+	Status         string    `json:"status"`
+	PreviousStatus string    `json:"previous_status"`
+	Amount         float64   `json:"amount"`
+	Currency       string    `json:"currency"`
+}
+
+// EnrollmentEvent is the payload of the enrollment events
+type EnrollmentEvent struct {
+	EnrollmentID uuid.UUID `json:"enrollment_id"`
+	ItemType     string    `json:"item_type"`
+	ItemID       uuid.UUID `json:"item_id"`
+	OrderID      uuid.UUID `json:"order_id"`
+}
+
+// meetingEvent builds a meeting event visible to whoever can see the meeting
+func meetingEvent(eventType string, meeting *entity.Meeting) (realtime.Event, realtime.Audience) {
+	event := realtime.Event{Type: eventType, Data: MeetingEvent{
+		MeetingID: meeting.ID,
+		SpaceID:   meeting.SpaceID,
+		Title:     meeting.Title,
+		Status:    meeting.Status,
+	}}
+	return event, realtime.Audience{SpaceID: meeting.SpaceID, MembersOnly: meeting.MembersOnly}
+}
+
+type EventService interface {
+
+	// Subscribe streams the domain events the user may see; the returned function ends the subscription
+	Subscribe(userID uuid.UUID) (<-chan realtime.Event, func())
+}
+
+type eventServiceImpl struct {
+	bus        *realtime.Bus
+	memberRepo repository.SpaceMemberRepository
+	roleRepo   repository.RoleRepository
+}
+
+func NewEventService(bus *realtime.Bus, memberRepo repository.SpaceMemberRepository, roleRepo repository.RoleRepository) EventService {
+	return &eventServiceImpl{
+		bus:        bus,
+		memberRepo: memberRepo,
+		roleRepo:   roleRepo,
+	}
+}
+
+// Subscribe implements EventService.
+func (s *eventServiceImpl) Subscribe(userID uuid.UUID) (<-chan realtime.Event, func()) {
+	roles, err := s.roleRepo.GetRoleNamesByUserID(userID)
+	if err != nil {
+		log.Printf("Failed to get roles of user with ID %s: %v", userID, err)
+	}
+	admin := hasRole(roles, entity.RoleAdmin)
+
+	envelopes, unsubscribe := s.bus.Subscribe()
+	events := make(chan realtime.Event, cap(envelopes))
+
+	// Forward until unsubscribed, which closes the envelopes and then the events
+	go func() {
+		defer close(events)
+		for envelope := range envelopes {
+			if !s.canReceive(userID, admin, envelope.Audience) {
+				continue
+			}
+			select {
+			case events <- envelope.Event:
+			default:
+			}
+		}
+	}()
+
+	return events, unsubscribe
+}
+
+// canReceive reports whether the user may see an event. Memberships are checked on every event
+// since they change while the stream stays open.
+func (s *eventServiceImpl) canReceive(userID uuid.UUID, admin bool, audience realtime.Audience) bool {
+	if len(audience.UserIDs) > 0 {
+		return addressedTo(audience, userID)
+	}
+
+	if audience.SpaceID == nil || !audience.MembersOnly || admin {
+		return true
+	}
+	_, err := s.memberRepo.Get(*audience.SpaceID, userID)
+	return err == nil
+}
@@ -0,0 +1,190 @@
+package service
+
+import (
+	"dalabio/internal/entity"
+	"dalabio/internal/framework/document"
+	"dalabio/internal/repository"
+	"dalabio/pkg/config"
+	"fmt"
+	"log"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/gofrs/uuid"
+)
+
+// Invoice output formats
+const (
+	InvoiceFormatJSON = "json"
+	InvoiceFormatHTML = "html"
+	InvoiceFormatPDF  = "pdf"
+)
+
+type InvoiceService interface {
+
+	// GetInvoiceForPayment returns the invoice of a payment to its payer or an admin,
+	// issuing it first for payments completed before invoicing existed
+	GetInvoiceForPayment(paymentID, requesterID uuid.UUID) (*entity.Invoice, error)
+
+	// RenderInvoice renders an invoice as HTML or PDF and returns the content type
+	RenderInvoice(invoice *entity.Invoice, format string) ([]byte, string, error)
+
+	// PaymentCompleted issues the invoice of a completed payment
+	PaymentCompleted(payment *entity.Payment) error
+}
+
+type invoiceServiceImpl struct {
+	repo        repository.InvoiceRepository
+	paymentRepo repository.PaymentRepository
+	orderRepo   repository.OrderRepository
+	userRepo    repository.UserRepository
+	roleRepo    repository.RoleRepository
+	config      *config.InvoiceConfig
+}
+
+func NewInvoiceService(invoiceRepo repository.InvoiceRepository, paymentRepo repository.PaymentRepository, orderRepo repository.OrderRepository, userRepo repository.UserRepository, roleRepo repository.RoleRepository, cfg *config.InvoiceConfig) InvoiceService {
+	return &invoiceServiceImpl{
+		repo:        invoiceRepo,
+		paymentRepo: paymentRepo,
+		orderRepo:   orderRepo,
+		userRepo:    userRepo,
+		roleRepo:    roleRepo,
+		config:      cfg,
+	}
+}
+
+// PaymentCompleted implements InvoiceService and PaymentListener.
+func (s *invoiceServiceImpl) PaymentCompleted(payment *entity.Payment) error {
+	_, err := s.issue(payment)
+	return err
+}
+
+// GetInvoiceForPayment implements InvoiceService.
+func (s *invoiceServiceImpl) GetInvoiceForPayment(paymentID, requesterID uuid.UUID) (*entity.Invoice, error) {
+	payment, err := s.paymentRepo.GetdByID(paymentID)
+	if err != nil {
+		return nil, fmt.Errorf("could not find payment with ID %s: %w", paymentID, err)
+	}
+
+	if payment.UserID != requesterID {
+		roles, err := s.roleRepo.GetRoleNamesByUserID(requesterID)
+		if err != nil {
+			return nil, err
+		}
+		if !hasRole(roles, entity.RoleAdmin) {
+			return nil, newError(ErrNotFound, "could not find payment with ID %s", paymentID)
+		}
+	}
+
+	switch payment.Status {
+	case PaymentStatusCompleted, PaymentStatusPartiallyRefunded, PaymentStatusRefunded:
+	default:
+		return nil, newError(ErrConflict, "payment with ID %s is not completed and has no invoice", paymentID)
+	}
+
+	return s.issue(payment)
+}
+
+// RenderInvoice implements InvoiceService.
+func (s *invoiceServiceImpl) RenderInvoice(invoice *entity.Invoice, format string) ([]byte, string, error) {
+	seller := document.Seller{Name: s.config.SellerName, Address: s.config.SellerAddress}
+
+	switch format {
+	case InvoiceFormatHTML:
+		body, err := document.RenderInvoiceHTML(invoice, seller)
+		return body, "text/html; charset=utf-8", err
+	case InvoiceFormatPDF:
+		body, err := document.RenderInvoicePDF(invoice, seller)
+		return body, "application/pdf", err
+	default:
+		return nil, "", newError(ErrValidation, "unsupported invoice format %q", format)
+	}
+}
+
+// issue returns the invoice of a payment, creating it if it does not exist yet
+func (s *invoiceServiceImpl) issue(payment *entity.Payment) (*entity.Invoice, error) {
+	if invoice, err := s.repo.GetByPaymentID(payment.ID); err == nil {
+		return invoice, nil
+	}
+
+	neoInvoice, err := uuid.NewV4()
+	if err != nil {
+		return nil, err
+	}
+
+	invoice := &entity.Invoice{
+		ID:        neoInvoice,
+		PaymentID: payment.ID,
+		OrderID:   payment.OrderID,
+		UserID:    payment.UserID,
+		Currency:  payment.Currency,
+		Total:     payment.Amount,
+		TaxRate:   s.config.TaxRate,
+		IssuedAt:  time.Now(),
+	}
+
+	if payment.OrderID != uuid.Nil {
+		order, err := s.orderRepo.GetdByID(payment.OrderID)
+		if err != nil {
+			return nil, fmt.Errorf("could not find order with ID %s: %w", payment.OrderID, err)
+		}
+		for _, line := range order.Lines {
+			invoice.Lines = append(invoice.Lines, &entity.InvoiceLine{
+				Description: line.Description,
+				Quantity:    line.Quantity,
+				UnitPrice:   line.UnitPrice,
+				Amount:      line.Amount,
+			})
+		}
+		invoice.Discount = order.Discount
+		invoice.BillingName = order.BillingName
+		invoice.BillingAddress = order.BillingAddress
+	} else {
+		description := payment.Notes
+		if description == "" {
+			description = "Payment " + payment.ID.String()
+		}
+		invoice.Lines = []*entity.InvoiceLine{{
+			Description: description,
+			Quantity:    1,
+			UnitPrice:   payment.Amount,
+			Amount:      payment.Amount,
+		}}
+	}
+
+	if invoice.BillingName == "" {
+		if user, err := s.userRepo.FindByID(payment.UserID); err == nil {
+			invoice.BillingName = strings.TrimSpace(user.FirstName + " " + user.LastName)
+			if invoice.BillingName == "" {
+				invoice.BillingName = user.Username
+			}
+		}
+	}
+
+	// Prices are tax-inclusive, so the tax is extracted from the amount paid
+	net := invoice.Total / (1 + invoice.TaxRate/100)
+	invoice.Subtotal = math.Round(net*100) / 100
+	invoice.Tax = math.Round((invoice.Total-invoice.Subtotal)*100) / 100
+
+	if err := s.repo.Create(invoice, s.config.Prefix); err != nil {
+		// Another request may have issued the invoice concurrently
+		if existing, getErr := s.repo.GetByPaymentID(payment.ID); getErr == nil {
+			return existing, nil
+		}
+		return nil, fmt.Errorf("failed to create invoice for payment with ID %s: %w", payment.ID, err)
+	}
+
+	log.Printf("Issued invoice %s for payment %s", invoice.Number, payment.ID)
+	return invoice, nil
+}
+
+// hasRole reports whether role is among the given role names
+func hasRole(roles []string, role string) bool {
+	for _, name := range roles {
+		if name == role {
+			return true
+		}
+	}
+	return false
+}
@@ -2,49 +2,92 @@ package service
 
 import (
 	"dalabio/internal/entity"
+	"dalabio/internal/framework/realtime"
 	"dalabio/internal/repository"
+	"dalabio/pkg/validation"
 	"fmt"
 	"log"
+	"time"
 
 	"github.com/gofrs/uuid"
 )
 
+// MeetingStatusCancelled is the status of a called off meeting
+const MeetingStatusCancelled = "cancelled"
+
 type MeetingService interface {
 	// GetMeeting returns a meeting by its ID
-	GetMeetingByID(meetingID uuid.UUID) (*entity.Meeting, error)
+	GetMeetingByID(meetingID, requesterID uuid.UUID) (*entity.Meeting, error)
 
 	// GetMeetings returns all meetings
-	GetAllMeetings() ([]*entity.Meeting, error)
+	GetAllMeetings(requesterID uuid.UUID) ([]*entity.Meeting, error)
+
+	// GetSpaceMeetings returns the meetings of a space, hiding member-only meetings from non-members
+	GetSpaceMeetings(spaceID, requesterID uuid.UUID) ([]*entity.Meeting, error)
 
 	// CreateMeeting creates a new meeting
-	CreateMeeting(Title, Description, Duration, Location, MeetingType, Status string, AttendeeIDs []uuid.UUID, AttendeeNames []string, AttendeeEmails []string, AttendeeStatus []string, JoinURL []string, MaximumCapacity int) (*entity.Meeting, error)
+	CreateMeeting(Title, Description, Duration string, StartTime, EndTime time.Time, Location, MeetingType, Status string, AttendeeIDs []uuid.UUID, AttendeeNames []string, AttendeeEmails []string, AttendeeStatus []string, JoinURL []string, MaximumCapacity int, SpaceID *uuid.UUID, MembersOnly bool, creatorID uuid.UUID) (*entity.Meeting, error)
 
 	// UpdateMeeting updates an existing meeting
-	UpdateMeeting(meeting *entity.Meeting) error
+	UpdateMeeting(meeting *entity.Meeting, requesterID uuid.UUID) error
 
-	// DeleteMeeting deletes a meeting by its ID
-	DeleteMeeting(meetingID uuid.UUID) error
+	// DeleteMeeting deletes a meeting by its ID; meetings of a space can only be deleted by those who run it
+	DeleteMeeting(meetingID, requesterID uuid.UUID) error
 }
 
 type meetingService struct {
-	repo     repository.MeetingRepository
-	tokenRep repository.TokenRepository
+	repo          repository.MeetingRepository
+	memberRepo    repository.SpaceMemberRepository
+	tokenRep      repository.TokenRepository
+	events        *realtime.Bus
+	notifications NotificationService
+	emails        EmailService
 }
 
 // GetAllMeetings implements MeetingService.
-func (s *meetingService) GetAllMeetings() ([]*entity.Meeting, error) {
+func (s *meetingService) GetAllMeetings(requesterID uuid.UUID) ([]*entity.Meeting, error) {
 	meeting, err := s.repo.GetAll()
 
 	if err != nil {
 		return nil, err
 	}
 
-	return meeting, nil
+	return s.visible(meeting, requesterID), nil
+
+}
+
+// GetSpaceMeetings implements MeetingService.
+func (s *meetingService) GetSpaceMeetings(spaceID, requesterID uuid.UUID) ([]*entity.Meeting, error) {
+	meetings, err := s.repo.GetBySpaceID(spaceID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get meetings of space with ID %s: %w", spaceID, err)
+	}
+
+	return s.visible(meetings, requesterID), nil
+}
 
+// visible filters out the member-only meetings of spaces the requester is not a member of
+func (s *meetingService) visible(meetings []*entity.Meeting, requesterID uuid.UUID) []*entity.Meeting {
+	visibility := newSpaceVisibility(s.memberRepo, requesterID)
+	result := []*entity.Meeting{}
+	for _, meeting := range meetings {
+		if visibility.canSee(meeting.SpaceID, meeting.MembersOnly) {
+			result = append(result, meeting)
+		}
+	}
+	return result
 }
 
 // CreateMeeting implements MeetingService.
-func (s *meetingService) CreateMeeting(Title, Description, Duration, Location, MeetingType, Status string, AttendeeIDs []uuid.UUID, AttendeeNames []string, AttendeeEmails []string, AttendeeStatus []string, JoinURL []string, MaximumCapacity int) (*entity.Meeting, error) {
+func (s *meetingService) CreateMeeting(Title, Description, Duration string, StartTime, EndTime time.Time, Location, MeetingType, Status string, AttendeeIDs []uuid.UUID, AttendeeNames []string, AttendeeEmails []string, AttendeeStatus []string, JoinURL []string, MaximumCapacity int, SpaceID *uuid.UUID, MembersOnly bool, creatorID uuid.UUID) (*entity.Meeting, error) {
+	if MembersOnly && SpaceID == nil {
+		return nil, newError(ErrValidation, "a member-only meeting must belong to a space")
+	}
+	// Only the people running a space can schedule meetings in it
+	if err := requireSpaceManager(s.memberRepo, SpaceID, creatorID); err != nil {
+		return nil, err
+	}
+
 	neoMeeting, err := uuid.NewV4()
 
 	if err != nil {
@@ -56,6 +99,8 @@ func (s *meetingService) CreateMeeting(Title, Description, Duration, Location, M
 		Title:           Title,
 		Description:     Description,
 		Duration:        Duration,
+		StartTime:       StartTime,
+		EndTime:         EndTime,
 		MeetingType:     MeetingType,
 		Status:          Status,
 		Location:        Location,
@@ -65,6 +110,12 @@ func (s *meetingService) CreateMeeting(Title, Description, Duration, Location, M
 		AttendeeStatus:  AttendeeStatus,
 		JoinURL:         JoinURL,
 		MaximumCapacity: MaximumCapacity,
+		SpaceID:         SpaceID,
+		MembersOnly:     MembersOnly,
+	}
+
+	if err := validateMeeting(newMeeting); err != nil {
+		return nil, err
 	}
 
 	log.Printf("Creating course: %+v", neoMeeting)
@@ -75,57 +126,133 @@ func (s *meetingService) CreateMeeting(Title, Description, Duration, Location, M
 		return nil, err
 	}
 
+	s.emails.SendMeetingInvites(newMeeting)
+
 	return newMeeting, nil
 
 }
 
+// validateMeeting checks the fields of a meeting set by its organizer
+func validateMeeting(meeting *entity.Meeting) error {
+	v := validation.New()
+	v.Length("title", meeting.Title, 1, 200)
+	v.MaxLength("description", meeting.Description, 5000)
+	v.TimeRange("start_time", "end_time", meeting.StartTime, meeting.EndTime)
+	v.MaxLength("location", meeting.Location, 500)
+	for i, email := range meeting.AttendeeEmails {
+		v.Email(fmt.Sprintf("attendee_emails[%d]", i), email)
+	}
+	v.URLs("join_url", meeting.JoinURL)
+	v.NotNegative("maximum_capacity", float64(meeting.MaximumCapacity))
+	return v.Err()
+}
+
 // UpdateMeeting implements MeetingService.
-func (s *meetingService) UpdateMeeting(meeting *entity.Meeting) error {
-	_, err := s.repo.GetdByID(meeting.ID)
+func (s *meetingService) UpdateMeeting(meeting *entity.Meeting, requesterID uuid.UUID) error {
+	if err := validateMeeting(meeting); err != nil {
+		return err
+	}
+
+	existing, err := s.repo.GetdByID(meeting.ID)
 
 	if err != nil {
-		return fmt.Errorf("could not find meeeting with ID %s", meeting.ID)
+		return fmt.Errorf("could not find meeeting with ID %s: %w", meeting.ID, err)
+	}
+	if meeting.MembersOnly && meeting.SpaceID == nil {
+		return newError(ErrValidation, "a member-only meeting must belong to a space")
+	}
+
+	// Moving a meeting in or out of a space needs the right to manage both
+	if err := requireSpaceManager(s.memberRepo, existing.SpaceID, requesterID); err != nil {
+		return err
+	}
+	if err := requireSpaceManager(s.memberRepo, meeting.SpaceID, requesterID); err != nil {
+		return err
 	}
 
 	if err := s.repo.Update(meeting); err != nil {
-		return fmt.Errorf("failed to update meeting with ID %s: %v", meeting.ID, err)
+		return fmt.Errorf("failed to update meeting with ID %s: %w", meeting.ID, err)
+	}
+
+	eventType := EventMeetingUpdated
+	if meeting.Status == MeetingStatusCancelled && existing.Status != MeetingStatusCancelled {
+		eventType = EventMeetingCancelled
 	}
+	s.events.Publish(meetingEvent(eventType, meeting))
+	s.notifyAttendees(eventType, meeting, requesterID)
 
 	return nil
 
 }
 
 // DeleteMeeting implements MeetingService.
-func (s *meetingService) DeleteMeeting(meetingID uuid.UUID) error {
+func (s *meetingService) DeleteMeeting(meetingID, requesterID uuid.UUID) error {
 
-	_, err := s.repo.GetdByID(meetingID)
+	meeting, err := s.repo.GetdByID(meetingID)
 	if err != nil {
-		return fmt.Errorf("could not find meeting with ID %s: %v", meetingID, err)
+		return fmt.Errorf("could not find meeting with ID %s: %w", meetingID, err)
+	}
+	if err := requireSpaceManager(s.memberRepo, meeting.SpaceID, requesterID); err != nil {
+		return err
 	}
 
 	if err := s.repo.Delete(meetingID); err != nil {
-		return fmt.Errorf("failed to delete meeting with ID %s: %v", meetingID, err)
+		return fmt.Errorf("failed to delete meeting with ID %s: %w", meetingID, err)
 	}
 
+	meeting.Status = MeetingStatusCancelled
+	s.events.Publish(meetingEvent(EventMeetingCancelled, meeting))
+	s.notifyAttendees(EventMeetingCancelled, meeting, requesterID)
+
 	log.Printf("Successfully deleted meeting with ID %s", meetingID)
 	return nil
 }
 
+// notifyAttendees tells the attendees of a meeting, except whoever changed it, that it was updated or cancelled
+func (s *meetingService) notifyAttendees(eventType string, meeting *entity.Meeting, changedBy uuid.UUID) {
+	attendees := []uuid.UUID{}
+	for _, attendeeID := range meeting.AttendeeIDs {
+		if attendeeID != changedBy {
+			attendees = append(attendees, attendeeID)
+		}
+	}
+
+	notification := entity.Notification{
+		Category: entity.NotificationCategoryMeeting,
+		Type:     entity.NotificationMeetingUpdated,
+		Title:    meeting.Title + " was updated",
+		Body:     "Starts " + meeting.StartTime.Format("Mon 2 Jan 2006 15:04 MST"),
+		Link:     "/meetings/" + meeting.ID.String(),
+	}
+	if eventType == EventMeetingCancelled {
+		notification.Type = entity.NotificationMeetingCancelled
+		notification.Title = meeting.Title + " was cancelled"
+	}
+	s.notifications.Notify(notification, attendees...)
+}
+
 // GetMeetingByID implements MeetingService.
-func (s *meetingService) GetMeetingByID(meetingID uuid.UUID) (*entity.Meeting, error) {
+func (s *meetingService) GetMeetingByID(meetingID, requesterID uuid.UUID) (*entity.Meeting, error) {
 	meeting, err := s.repo.GetdByID(meetingID)
 
 	if err != nil {
-		return nil, fmt.Errorf("could not find meeting with ID %s: %v", meetingID, err)
+		return nil, fmt.Errorf("could not find meeting with ID %s: %w", meetingID, err)
+	}
+	if !newSpaceVisibility(s.memberRepo, requesterID).canSee(meeting.SpaceID, meeting.MembersOnly) {
+		return nil, ErrMembersOnly
 	}
 
 	return meeting, nil
 
 }
 
-func NewMeetingService(meetingRepo repository.MeetingRepository, tokenRep repository.TokenRepository) MeetingService {
+func NewMeetingService(meetingRepo repository.MeetingRepository, memberRepo repository.SpaceMemberRepository, tokenRep repository.TokenRepository, events *realtime.Bus, notifications NotificationService, emails EmailService) MeetingService {
 	return &meetingService{
-		repo:     meetingRepo,
-		tokenRep: tokenRep,
+		repo:          meetingRepo,
+		memberRepo:    memberRepo,
+		tokenRep:      tokenRep,
+		events:        events,
+		notifications: notifications,
+		emails:        emails,
 	}
 }
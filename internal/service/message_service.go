@@ -0,0 +1,331 @@
+package service
+
+import (
+	"dalabio/internal/entity"
+	"dalabio/internal/framework/realtime"
+	"dalabio/internal/repository"
+	"encoding/json"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/gofrs/uuid"
+)
+
+// Limits of conversations and messages
+const (
+	maxGroupParticipants   = 10 // Including the creator of the group
+	maxMessageLength       = 5000
+	defaultMessagePageSize = 50
+	maxMessagePageSize     = 100
+)
+
+// Events pushed to the participants of a conversation
+const (
+	EventMessageCreated   = "message.created"
+	EventConversationRead = "conversation.read"
+)
+
+// MessageEvent is the payload of the message.created events on the bus. The body stays out, since a long message
+// would not fit in a notification to the other instances; the messaging stream loads the message itself.
+type MessageEvent struct {
+	MessageID      uuid.UUID `json:"message_id"`
+	ConversationID uuid.UUID `json:"conversation_id"`
+	SenderID       uuid.UUID `json:"sender_id"`
+}
+
+type MessageService interface {
+
+	// StartConversation opens a direct conversation with a single user, reusing the one they may already have,
+	// or a group conversation with several users. Users can only write to people they share a space with.
+	StartConversation(creatorID uuid.UUID, participantIDs []uuid.UUID, title string) (*entity.Conversation, error)
+
+	// GetConversations returns the conversations of a user with their unread counts
+	GetConversations(userID uuid.UUID) ([]*entity.Conversation, error)
+
+	GetConversation(conversationID, userID uuid.UUID) (*entity.Conversation, error)
+
+	// GetMessages returns a page of history, newest first, and the cursor of the next page, nil on the last one
+	GetMessages(conversationID, userID uuid.UUID, before *uuid.UUID, limit int) ([]*entity.Message, *uuid.UUID, error)
+
+	// SendMessage posts a message and delivers it to the connected participants
+	SendMessage(conversationID, senderID uuid.UUID, body string) (*entity.Message, error)
+
+	// MarkRead records that the user read the conversation up to a message, or entirely when upTo is nil
+	MarkRead(conversationID, userID uuid.UUID, upTo *uuid.UUID) (*entity.ConversationParticipant, error)
+
+	// CountUnread returns the number of messages the user has not read in all their conversations
+	CountUnread(userID uuid.UUID) (int, error)
+
+	// Subscribe streams the messaging events of a user; the returned function ends the subscription
+	Subscribe(userID uuid.UUID) (<-chan realtime.Event, func())
+}
+
+type messageServiceImpl struct {
+	repo       repository.ConversationRepository
+	userRepo   repository.UserRepository
+	memberRepo repository.SpaceMemberRepository
+	events     *realtime.Bus
+}
+
+func NewMessageService(conversationRepo repository.ConversationRepository, userRepo repository.UserRepository, memberRepo repository.SpaceMemberRepository, events *realtime.Bus) MessageService {
+	return &messageServiceImpl{
+		repo:       conversationRepo,
+		userRepo:   userRepo,
+		memberRepo: memberRepo,
+		events:     events,
+	}
+}
+
+// StartConversation implements MessageService.
+func (s *messageServiceImpl) StartConversation(creatorID uuid.UUID, participantIDs []uuid.UUID, title string) (*entity.Conversation, error) {
+	others := []uuid.UUID{}
+	seen := map[uuid.UUID]bool{creatorID: true}
+	for _, participantID := range participantIDs {
+		if !seen[participantID] {
+			seen[participantID] = true
+			others = append(others, participantID)
+		}
+	}
+
+	if len(others) == 0 {
+		return nil, newError(ErrValidation, "a conversation needs at least one other participant")
+	}
+	if len(others)+1 > maxGroupParticipants {
+		return nil, newError(ErrValidation, "a conversation can have at most %d participants", maxGroupParticipants)
+	}
+
+	for _, participantID := range others {
+		if _, err := s.userRepo.FindByID(participantID); err != nil {
+			return nil, fmt.Errorf("could not find user with ID %s: %w", participantID, err)
+		}
+		shared, err := s.memberRepo.ShareSpace(creatorID, participantID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to check the spaces of user with ID %s: %w", participantID, err)
+		}
+		if !shared {
+			return nil, newError(ErrForbidden, "user with ID %s is not a member of any of your spaces", participantID)
+		}
+	}
+
+	neoConversation, err := uuid.NewV4()
+	if err != nil {
+		return nil, err
+	}
+
+	conversation := &entity.Conversation{
+		ID:        neoConversation,
+		Title:     strings.TrimSpace(title),
+		IsGroup:   len(others) > 1,
+		CreatedBy: creatorID,
+	}
+
+	directKey := ""
+	if !conversation.IsGroup {
+		directKey = directConversationKey(creatorID, others[0])
+	}
+
+	created, err := s.repo.Create(conversation, directKey, append([]uuid.UUID{creatorID}, others...))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create conversation: %w", err)
+	}
+	return created, nil
+}
+
+// GetConversations implements MessageService.
+func (s *messageServiceImpl) GetConversations(userID uuid.UUID) ([]*entity.Conversation, error) {
+	conversations, err := s.repo.GetByUserID(userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get conversations of user with ID %s: %w", userID, err)
+	}
+	return conversations, nil
+}
+
+// GetConversation implements MessageService.
+func (s *messageServiceImpl) GetConversation(conversationID, userID uuid.UUID) (*entity.Conversation, error) {
+	return s.conversation(conversationID, userID)
+}
+
+// conversation loads a conversation the user takes part in
+func (s *messageServiceImpl) conversation(conversationID, userID uuid.UUID) (*entity.Conversation, error) {
+	conversation, err := s.repo.GetByID(conversationID)
+	if err != nil {
+		return nil, fmt.Errorf("could not find conversation with ID %s: %w", conversationID, err)
+	}
+	for _, participant := range conversation.Participants {
+		if participant.UserID == userID {
+			return conversation, nil
+		}
+	}
+	return nil, newError(ErrNotFound, "could not find conversation with ID %s", conversationID)
+}
+
+// GetMessages implements MessageService.
+func (s *messageServiceImpl) GetMessages(conversationID, userID uuid.UUID, before *uuid.UUID, limit int) ([]*entity.Message, *uuid.UUID, error) {
+	if _, err := s.repo.GetParticipant(conversationID, userID); err != nil {
+		return nil, nil, newError(ErrNotFound, "could not find conversation with ID %s", conversationID)
+	}
+
+	if limit <= 0 {
+		limit = defaultMessagePageSize
+	}
+	if limit > maxMessagePageSize {
+		limit = maxMessagePageSize
+	}
+
+	// One extra message tells whether there is a next page
+	messages, err := s.repo.GetMessages(conversationID, before, limit+1)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get messages of conversation with ID %s: %w", conversationID, err)
+	}
+
+	var next *uuid.UUID
+	if len(messages) > limit {
+		messages = messages[:limit]
+		next = &messages[limit-1].ID
+	}
+	return messages, next, nil
+}
+
+// SendMessage implements MessageService.
+func (s *messageServiceImpl) SendMessage(conversationID, senderID uuid.UUID, body string) (*entity.Message, error) {
+	body = strings.TrimSpace(body)
+	if body == "" {
+		return nil, newError(ErrValidation, "a message cannot be empty")
+	}
+	if utf8.RuneCountInString(body) > maxMessageLength {
+		return nil, newError(ErrValidation, "a message cannot be longer than %d characters", maxMessageLength)
+	}
+
+	conversation, err := s.conversation(conversationID, senderID)
+	if err != nil {
+		return nil, err
+	}
+
+	neoMessage, err := uuid.NewV4()
+	if err != nil {
+		return nil, err
+	}
+
+	message := &entity.Message{
+		ID:             neoMessage,
+		ConversationID: conversationID,
+		SenderID:       senderID,
+		Body:           body,
+	}
+	if err := s.repo.CreateMessage(message); err != nil {
+		return nil, fmt.Errorf("failed to send message: %w", err)
+	}
+
+	event := realtime.Event{Type: EventMessageCreated, Data: MessageEvent{
+		MessageID:      message.ID,
+		ConversationID: message.ConversationID,
+		SenderID:       message.SenderID,
+	}}
+	s.events.Publish(event, realtime.Audience{UserIDs: participantIDs(conversation)})
+	return message, nil
+}
+
+// MarkRead implements MessageService.
+func (s *messageServiceImpl) MarkRead(conversationID, userID uuid.UUID, upTo *uuid.UUID) (*entity.ConversationParticipant, error) {
+	conversation, err := s.conversation(conversationID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	participant, err := s.repo.MarkRead(conversationID, userID, upTo)
+	if err != nil {
+		return nil, fmt.Errorf("failed to mark conversation with ID %s as read: %w", conversationID, err)
+	}
+
+	// The other participants see the read receipt move
+	s.events.Publish(realtime.Event{Type: EventConversationRead, Data: participant}, realtime.Audience{UserIDs: participantIDs(conversation)})
+	return participant, nil
+}
+
+// CountUnread implements MessageService.
+func (s *messageServiceImpl) CountUnread(userID uuid.UUID) (int, error) {
+	count, err := s.repo.CountUnread(userID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to count unread messages of user with ID %s: %w", userID, err)
+	}
+	return count, nil
+}
+
+// Subscribe implements MessageService.
+func (s *messageServiceImpl) Subscribe(userID uuid.UUID) (<-chan realtime.Event, func()) {
+	envelopes, unsubscribe := s.events.Subscribe()
+	events := make(chan realtime.Event, cap(envelopes))
+
+	// Forward the messaging events addressed to the user until unsubscribed, which closes the envelopes and then the events
+	go func() {
+		defer close(events)
+		for envelope := range envelopes {
+			if !isMessagingEvent(envelope.Event.Type) || !addressedTo(envelope.Audience, userID) {
+				continue
+			}
+			event := envelope.Event
+			if event.Type == EventMessageCreated {
+				message, err := s.createdMessage(event.Data)
+				if err != nil {
+					log.Printf("Failed to load message of event %s: %v", event.Type, err)
+					continue
+				}
+				event.Data = message
+			}
+			select {
+			case events <- event:
+			default:
+			}
+		}
+	}()
+
+	return events, unsubscribe
+}
+
+// createdMessage loads the message a message.created event announces. Events of the other instances come
+// decoded as maps, so their payload is read again as a MessageEvent.
+func (s *messageServiceImpl) createdMessage(data interface{}) (*entity.Message, error) {
+	payload, ok := data.(MessageEvent)
+	if !ok {
+		raw, err := json.Marshal(data)
+		if err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal(raw, &payload); err != nil {
+			return nil, err
+		}
+	}
+	return s.repo.GetMessage(payload.MessageID)
+}
+
+func isMessagingEvent(eventType string) bool {
+	return eventType == EventMessageCreated || eventType == EventConversationRead
+}
+
+// addressedTo reports whether the user is among the recipients an event is restricted to
+func addressedTo(audience realtime.Audience, userID uuid.UUID) bool {
+	for _, recipientID := range audience.UserIDs {
+		if recipientID == userID {
+			return true
+		}
+	}
+	return false
+}
+
+// directConversationKey identifies the direct conversation of two users whoever started it
+func directConversationKey(userID, otherID uuid.UUID) string {
+	ids := []string{userID.String(), otherID.String()}
+	sort.Strings(ids)
+	return ids[0] + ":" + ids[1]
+}
+
+func participantIDs(conversation *entity.Conversation) []uuid.UUID {
+	ids := make([]uuid.UUID, len(conversation.Participants))
+	for i, participant := range conversation.Participants {
+		ids[i] = participant.UserID
+	}
+	return ids
+}
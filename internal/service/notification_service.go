@@ -0,0 +1,233 @@
+package service
+
+import (
+	"dalabio/internal/entity"
+	"dalabio/internal/framework/realtime"
+	"dalabio/internal/repository"
+	"fmt"
+	"log"
+
+	"github.com/gofrs/uuid"
+)
+
+// Limits of the notification inbox
+const (
+	defaultNotificationPageSize = 50
+	maxNotificationPageSize     = 200
+)
+
+type NotificationService interface {
+
+	// Notify sends a notification to users through the channels they enabled for its category.
+	// Failures are logged: the action that caused the notification is not undone because of them.
+	Notify(notification entity.Notification, userIDs ...uuid.UUID)
+
+	// GetNotifications returns the latest notifications of a user and how many are unread
+	GetNotifications(userID uuid.UUID, unreadOnly bool, limit int) ([]*entity.Notification, int, error)
+
+	// MarkRead marks a notification of the user as read
+	MarkRead(userID, notificationID uuid.UUID) error
+
+	// MarkAllRead marks every notification of the user as read and returns how many were unread
+	MarkAllRead(userID uuid.UUID) (int64, error)
+
+	// GetPreferences returns the preference of the user for every category and channel
+	GetPreferences(userID uuid.UUID) ([]*entity.NotificationPreference, error)
+
+	// UpdatePreferences changes some preferences of the user and returns all of them
+	UpdatePreferences(userID uuid.UUID, preferences []*entity.NotificationPreference) ([]*entity.NotificationPreference, error)
+
+	// SetMailer registers how notifications are sent to users who want them by email
+	SetMailer(mailer NotificationMailer)
+}
+
+// NotificationMailer sends the notifications users chose to receive by email
+type NotificationMailer interface {
+	SendNotification(user *entity.User, notification *entity.Notification) error
+}
+
+type notificationServiceImpl struct {
+	repo     repository.NotificationRepository
+	userRepo repository.UserRepository
+	events   *realtime.Bus
+	mailer   NotificationMailer
+}
+
+func NewNotificationService(notificationRepo repository.NotificationRepository, userRepo repository.UserRepository, events *realtime.Bus) NotificationService {
+	return &notificationServiceImpl{
+		repo:     notificationRepo,
+		userRepo: userRepo,
+		events:   events,
+	}
+}
+
+// notificationDefault tells whether a channel is on for a category until the user decides otherwise.
+// Only schedule changes and payments are emailed unless asked for.
+func notificationDefault(category, channel string) bool {
+	if channel == entity.NotificationChannelInApp {
+		return true
+	}
+	return category == entity.NotificationCategoryMeeting || category == entity.NotificationCategoryPayment
+}
+
+// SetMailer implements NotificationService.
+func (s *notificationServiceImpl) SetMailer(mailer NotificationMailer) {
+	s.mailer = mailer
+}
+
+// Notify implements NotificationService.
+func (s *notificationServiceImpl) Notify(notification entity.Notification, userIDs ...uuid.UUID) {
+	if len(userIDs) == 0 {
+		return
+	}
+
+	preferences, err := s.repo.GetPreferencesByCategory(notification.Category, userIDs)
+	if err != nil {
+		log.Printf("Error loading notification preferences: %v", err)
+		return
+	}
+	enabled := map[uuid.UUID]map[string]bool{}
+	for _, preference := range preferences {
+		if enabled[preference.UserID] == nil {
+			enabled[preference.UserID] = map[string]bool{}
+		}
+		enabled[preference.UserID][preference.Channel] = preference.Enabled
+	}
+	wants := func(userID uuid.UUID, channel string) bool {
+		if on, set := enabled[userID][channel]; set {
+			return on
+		}
+		return notificationDefault(notification.Category, channel)
+	}
+
+	for _, userID := range userIDs {
+		neoNotification, err := uuid.NewV4()
+		if err != nil {
+			log.Printf("Error generating notification ID: %v", err)
+			return
+		}
+		delivered := notification
+		delivered.ID = neoNotification
+		delivered.UserID = userID
+
+		if wants(userID, entity.NotificationChannelInApp) {
+			if err := s.repo.Create(&delivered); err != nil {
+				log.Printf("Error notifying %s of %s: %v", userID, notification.Type, err)
+			} else {
+				s.events.Publish(realtime.Event{Type: EventNotificationCreated, Data: &delivered},
+					realtime.Audience{UserIDs: []uuid.UUID{userID}})
+			}
+		}
+
+		if s.mailer != nil && wants(userID, entity.NotificationChannelEmail) {
+			user, err := s.userRepo.FindByID(userID)
+			if err != nil {
+				log.Printf("Error loading user %s to email a notification: %v", userID, err)
+				continue
+			}
+			if err := s.mailer.SendNotification(user, &delivered); err != nil {
+				log.Printf("Error emailing %s to %s: %v", notification.Type, userID, err)
+			}
+		}
+	}
+}
+
+// GetNotifications implements NotificationService.
+func (s *notificationServiceImpl) GetNotifications(userID uuid.UUID, unreadOnly bool, limit int) ([]*entity.Notification, int, error) {
+	if limit <= 0 {
+		limit = defaultNotificationPageSize
+	}
+	if limit > maxNotificationPageSize {
+		limit = maxNotificationPageSize
+	}
+
+	notifications, err := s.repo.GetByUserID(userID, unreadOnly, limit)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to get notifications of user with ID %s: %w", userID, err)
+	}
+
+	unread, err := s.repo.CountUnread(userID)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to count unread notifications of user with ID %s: %w", userID, err)
+	}
+
+	return notifications, unread, nil
+}
+
+// MarkRead implements NotificationService.
+func (s *notificationServiceImpl) MarkRead(userID, notificationID uuid.UUID) error {
+	if err := s.repo.MarkRead(userID, notificationID); err != nil {
+		return fmt.Errorf("could not find notification with ID %s: %w", notificationID, err)
+	}
+	return nil
+}
+
+// MarkAllRead implements NotificationService.
+func (s *notificationServiceImpl) MarkAllRead(userID uuid.UUID) (int64, error) {
+	count, err := s.repo.MarkAllRead(userID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to mark notifications of user with ID %s as read: %w", userID, err)
+	}
+	return count, nil
+}
+
+// GetPreferences implements NotificationService.
+func (s *notificationServiceImpl) GetPreferences(userID uuid.UUID) ([]*entity.NotificationPreference, error) {
+	saved, err := s.repo.GetPreferences(userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get notification preferences of user with ID %s: %w", userID, err)
+	}
+
+	byKey := make(map[string]*entity.NotificationPreference, len(saved))
+	for _, preference := range saved {
+		byKey[preference.Category+"/"+preference.Channel] = preference
+	}
+
+	// Every category and channel is listed, with the default when the user never changed it
+	preferences := []*entity.NotificationPreference{}
+	for _, category := range entity.NotificationCategories {
+		for _, channel := range entity.NotificationChannels {
+			preference, ok := byKey[category+"/"+channel]
+			if !ok {
+				preference = &entity.NotificationPreference{
+					UserID:   userID,
+					Category: category,
+					Channel:  channel,
+					Enabled:  notificationDefault(category, channel),
+				}
+			}
+			preferences = append(preferences, preference)
+		}
+	}
+	return preferences, nil
+}
+
+// UpdatePreferences implements NotificationService.
+func (s *notificationServiceImpl) UpdatePreferences(userID uuid.UUID, preferences []*entity.NotificationPreference) ([]*entity.NotificationPreference, error) {
+	for _, preference := range preferences {
+		if !contains(entity.NotificationCategories, preference.Category) {
+			return nil, newError(ErrValidation, "unknown notification category %q", preference.Category)
+		}
+		if !contains(entity.NotificationChannels, preference.Channel) {
+			return nil, newError(ErrValidation, "unknown notification channel %q", preference.Channel)
+		}
+	}
+
+	for _, preference := range preferences {
+		preference.UserID = userID
+		if err := s.repo.SetPreference(preference); err != nil {
+			return nil, fmt.Errorf("failed to save notification preferences of user with ID %s: %w", userID, err)
+		}
+	}
+
+	return s.GetPreferences(userID)
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,291 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"dalabio/internal/entity"
+	"dalabio/internal/framework/oidc"
+	"dalabio/internal/repository"
+	"dalabio/pkg/utils"
+
+	"github.com/gofrs/uuid"
+)
+
+// OIDCService signs users in with OpenID Connect identity providers and manages the identities linked to them.
+type OIDCService interface {
+	// Providers lists the names of the configured identity providers
+	Providers() []string
+
+	// StartLogin prepares a sign-in with a provider and returns the URL of its sign-in page
+	StartLogin(provider string) (*OIDCAuthorization, error)
+
+	// CompleteLogin redeems the authorization code the provider sent back with the state of StartLogin.
+	// The identity is linked to the user with the same verified email, or to a new user, on its first sign-in.
+	CompleteLogin(provider, code, state string) (*Authentication, error)
+
+	// ListIdentities returns the identities linked to a user
+	ListIdentities(userID uuid.UUID) ([]*entity.UserIdentity, error)
+
+	// Unlink removes an identity from a user, who can no longer sign in with it
+	Unlink(userID, identityID uuid.UUID) error
+}
+
+// OIDCAuthorization is where to send a user to sign in with a provider
+type OIDCAuthorization struct {
+	URL       string    `json:"authorization_url"`
+	State     string    `json:"state"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// oidcStateTTL is how long a user has to sign in at the provider
+const oidcStateTTL = 10 * time.Minute
+
+// ErrInvalidOIDCState is returned for a state that was not issued, expired or was already used
+var ErrInvalidOIDCState = newError(ErrUnauthorized, "invalid or expired sign-in state, please start again")
+
+// oidcServiceImpl is the implementation of OIDCService.
+type oidcServiceImpl struct {
+	providers    *oidc.Providers
+	identityRepo repository.UserIdentityRepository
+	userRepo     repository.UserRepository
+	users        UserService
+}
+
+// NewOIDCService creates a new OIDCService instance.
+func NewOIDCService(providers *oidc.Providers, identityRepo repository.UserIdentityRepository, userRepo repository.UserRepository, userService UserService) OIDCService {
+	return &oidcServiceImpl{
+		providers:    providers,
+		identityRepo: identityRepo,
+		userRepo:     userRepo,
+		users:        userService,
+	}
+}
+
+// Providers implements OIDCService.
+func (s *oidcServiceImpl) Providers() []string {
+	return s.providers.Names()
+}
+
+// StartLogin implements OIDCService.
+func (s *oidcServiceImpl) StartLogin(providerName string) (*OIDCAuthorization, error) {
+	provider, ok := s.providers.Get(providerName)
+	if !ok {
+		return nil, newError(ErrNotFound, "unknown identity provider %q", providerName)
+	}
+
+	// The state ties the answer of the provider to this request, the nonce ties the ID token to it,
+	// and the code verifier proves the code is redeemed by whoever started the sign-in
+	state, err := oidc.RandomString()
+	if err != nil {
+		return nil, err
+	}
+	nonce, err := oidc.RandomString()
+	if err != nil {
+		return nil, err
+	}
+	verifier, err := oidc.RandomString()
+	if err != nil {
+		return nil, err
+	}
+
+	url, err := provider.AuthorizationURL(state, nonce, oidc.CodeChallenge(verifier))
+	if err != nil {
+		return nil, fmt.Errorf("failed to prepare sign-in with %s: %w", provider.Name(), err)
+	}
+
+	pending := &entity.OIDCState{
+		StateHash:    utils.HashToken(state),
+		Provider:     provider.Name(),
+		Nonce:        nonce,
+		CodeVerifier: verifier,
+		ExpiresAt:    time.Now().Add(oidcStateTTL),
+	}
+	if err := s.identityRepo.CreateState(pending); err != nil {
+		return nil, fmt.Errorf("failed to save sign-in state: %w", err)
+	}
+
+	return &OIDCAuthorization{URL: url, State: state, ExpiresAt: pending.ExpiresAt}, nil
+}
+
+// CompleteLogin implements OIDCService.
+func (s *oidcServiceImpl) CompleteLogin(providerName, code, state string) (*Authentication, error) {
+	provider, ok := s.providers.Get(providerName)
+	if !ok {
+		return nil, newError(ErrNotFound, "unknown identity provider %q", providerName)
+	}
+
+	now := time.Now()
+	pending, err := s.identityRepo.ConsumeState(provider.Name(), utils.HashToken(state), now)
+	if errors.Is(err, ErrNotFound) {
+		return nil, ErrInvalidOIDCState
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to check sign-in state: %w", err)
+	}
+
+	claims, err := provider.Exchange(code, pending.CodeVerifier, pending.Nonce)
+	if err != nil {
+		log.Printf("Sign-in with %s failed: %v", provider.Name(), err)
+		return nil, newError(ErrUnauthorized, "sign-in with %s failed", provider.Name())
+	}
+
+	identity, err := s.identityRepo.FindByProviderSubject(provider.Name(), claims.Subject)
+	var user *entity.User
+	switch {
+	case err == nil:
+		if user, err = s.userRepo.FindByID(identity.UserID); err != nil {
+			return nil, fmt.Errorf("could not find user with ID %s: %w", identity.UserID, err)
+		}
+		if err := s.identityRepo.RecordLogin(identity.ID, claims.Email, now); err != nil {
+			log.Printf("Failed to record sign-in with identity %s: %v", identity.ID, err)
+		}
+	case errors.Is(err, ErrNotFound):
+		if user, err = s.link(provider.Name(), claims, now); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("failed to find %s identity: %w", provider.Name(), err)
+	}
+
+	return s.users.StartSession(user)
+}
+
+// link attaches a new identity to the user with its verified email, creating the user when there is none
+func (s *oidcServiceImpl) link(provider string, claims *oidc.Claims, now time.Time) (*entity.User, error) {
+	// Only an email the provider verified proves the identity belongs to the owner of the account
+	if claims.Email == "" || !claims.EmailVerified {
+		return nil, newError(ErrForbidden, "%s did not confirm your email address", provider)
+	}
+
+	user, err := s.userRepo.FindByEmail(claims.Email)
+	switch {
+	case err == nil:
+		// Anyone can register an email they do not own, so an unverified account is never taken over
+		if user.EmailVerifiedAt == nil {
+			return nil, newError(ErrConflict, "an account with this email address is not verified yet, verify it before signing in with %s", provider)
+		}
+	case errors.Is(err, ErrNotFound):
+		if user, err = s.createUser(claims, now); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("failed to find user by email: %w", err)
+	}
+
+	identity := &entity.UserIdentity{
+		UserID:      user.ID,
+		Provider:    provider,
+		Subject:     claims.Subject,
+		Email:       claims.Email,
+		LastLoginAt: &now,
+	}
+	if err := s.identityRepo.Create(identity); err != nil {
+		return nil, fmt.Errorf("failed to link %s identity to user with ID %s: %w", provider, user.ID, err)
+	}
+	log.Printf("Linked %s identity to user with ID %s", provider, user.ID)
+	return user, nil
+}
+
+// createUser registers the user of an identity. They have no usable password until they reset it.
+func (s *oidcServiceImpl) createUser(claims *oidc.Claims, now time.Time) (*entity.User, error) {
+	username, err := s.availableUsername(claims)
+	if err != nil {
+		return nil, err
+	}
+	password, err := utils.GenerateToken(32)
+	if err != nil {
+		return nil, err
+	}
+	hash, err := utils.HashPassword(password)
+	if err != nil {
+		return nil, err
+	}
+
+	user := &entity.User{
+		Username:  username,
+		Email:     claims.Email,
+		Password:  hash,
+		FirstName: claims.GivenName,
+		LastName:  claims.FamilyName,
+		IsActive:  true,
+	}
+	if IsLocale(claims.Locale) {
+		user.Locale = claims.Locale
+	}
+	if err := s.userRepo.Create(user); err != nil {
+		return nil, fmt.Errorf("failed to create user: %w", err)
+	}
+	if err := s.userRepo.MarkEmailVerified(user.ID, now); err != nil {
+		return nil, fmt.Errorf("failed to verify email of user with ID %s: %w", user.ID, err)
+	}
+	user.EmailVerifiedAt = &now
+	return user, nil
+}
+
+// availableUsername derives an unused username from the preferred username or the email of an identity
+func (s *oidcServiceImpl) availableUsername(claims *oidc.Claims) (string, error) {
+	base := usernameFrom(claims.PreferredUsername)
+	if len(base) < 3 {
+		base = usernameFrom(claims.Email)
+	}
+	if len(base) < 3 {
+		base = "user"
+	}
+	if len(base) > 40 {
+		base = base[:40]
+	}
+
+	candidate := base
+	for attempt := 0; attempt < 5; attempt++ {
+		taken, err := s.userRepo.FindByUsernames([]string{candidate})
+		if err != nil {
+			return "", fmt.Errorf("failed to check username: %w", err)
+		}
+		if len(taken) == 0 {
+			return candidate, nil
+		}
+
+		suffix, err := utils.GenerateToken(3)
+		if err != nil {
+			return "", err
+		}
+		candidate = base + "-" + suffix
+	}
+	return "", newError(ErrConflict, "could not find an available username")
+}
+
+// usernameFrom keeps the characters of a name, or of the local part of an email, allowed in usernames
+func usernameFrom(name string) string {
+	if at := strings.IndexByte(name, '@'); at >= 0 {
+		name = name[:at]
+	}
+	var username strings.Builder
+	for _, r := range strings.ToLower(name) {
+		if r >= 'a' && r <= 'z' || r >= '0' && r <= '9' || r == '.' || r == '_' || r == '-' {
+			username.WriteRune(r)
+		}
+	}
+	return username.String()
+}
+
+// ListIdentities implements OIDCService.
+func (s *oidcServiceImpl) ListIdentities(userID uuid.UUID) ([]*entity.UserIdentity, error) {
+	identities, err := s.identityRepo.ListByUserID(userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get identities of user with ID %s: %w", userID, err)
+	}
+	return identities, nil
+}
+
+// Unlink implements OIDCService.
+func (s *oidcServiceImpl) Unlink(userID, identityID uuid.UUID) error {
+	if err := s.identityRepo.Delete(userID, identityID); err != nil {
+		return fmt.Errorf("failed to unlink identity %s: %w", identityID, err)
+	}
+	log.Printf("Unlinked identity %s from user with ID %s", identityID, userID)
+	return nil
+}
@@ -0,0 +1,401 @@
+package service
+
+import (
+	"dalabio/internal/entity"
+	"dalabio/internal/framework/payment"
+	"dalabio/internal/framework/realtime"
+	"dalabio/internal/repository"
+	"fmt"
+	"log"
+	"math"
+	"time"
+
+	"github.com/gofrs/uuid"
+)
+
+// Order item types
+const (
+	OrderItemCourse          = "course"
+	OrderItemSpaceMembership = "space_membership"
+
+	// OrderItemSpaceSubscription bills one period of a subscription plan; access
+	// is governed by the subscription itself rather than by an enrollment
+	OrderItemSpaceSubscription = "space_subscription"
+)
+
+// Order statuses
+const (
+	OrderStatusPending   = "pending"
+	OrderStatusPaid      = "paid"
+	OrderStatusFailed    = "failed"
+	OrderStatusCancelled = "cancelled"
+)
+
+// orderPaymentWindow is how long a pending order waits for its payment before it expires and gives its coupon back
+const orderPaymentWindow = 24 * time.Hour
+
+type OrderService interface {
+
+	// Checkout creates an order for the given items and a pending payment intent for its total
+	Checkout(userID uuid.UUID, lines []*entity.OrderLine, couponCode, billingName, billingAddress, paymentMethod, paymentGateway string) (*entity.Order, *entity.Payment, error)
+
+	// GetOrderByID gets an order by ID
+	GetOrderByID(orderID uuid.UUID) (*entity.Order, error)
+
+	// GetOrdersByUser gets the orders placed by a user
+	GetOrdersByUser(userID uuid.UUID) ([]*entity.Order, error)
+
+	// CancelOrder cancels a pending order of the user, failing its pending payments and giving its coupon back
+	CancelOrder(orderID, userID uuid.UUID) (*entity.Order, error)
+
+	// ExpireOrders cancels the orders still pending after the payment window
+	ExpireOrders(now time.Time) error
+
+	// PaymentCompleted marks the order of a payment as paid and grants access to its items
+	PaymentCompleted(payment *entity.Payment) error
+}
+
+type orderServiceImpl struct {
+	repo           repository.OrderRepository
+	paymentRepo    repository.PaymentRepository
+	enrollmentRepo repository.EnrollmentRepository
+	courseRepo     repository.CourseRepository
+	spaceRepo      repository.SpaceRepository
+	memberRepo     repository.SpaceMemberRepository
+	couponService  CouponService
+	gateways       *payment.Gateways
+	events         *realtime.Bus
+	notifications  NotificationService
+}
+
+func NewOrderService(orderRepo repository.OrderRepository, paymentRepo repository.PaymentRepository, enrollmentRepo repository.EnrollmentRepository, courseRepo repository.CourseRepository, spaceRepo repository.SpaceRepository, memberRepo repository.SpaceMemberRepository, couponService CouponService, gateways *payment.Gateways, events *realtime.Bus, notifications NotificationService) OrderService {
+	return &orderServiceImpl{
+		repo:           orderRepo,
+		paymentRepo:    paymentRepo,
+		enrollmentRepo: enrollmentRepo,
+		courseRepo:     courseRepo,
+		spaceRepo:      spaceRepo,
+		memberRepo:     memberRepo,
+		couponService:  couponService,
+		gateways:       gateways,
+		events:         events,
+		notifications:  notifications,
+	}
+}
+
+// Checkout implements OrderService.
+func (s *orderServiceImpl) Checkout(userID uuid.UUID, lines []*entity.OrderLine, couponCode, billingName, billingAddress, paymentMethod, paymentGateway string) (*entity.Order, *entity.Payment, error) {
+	if len(lines) == 0 {
+		return nil, nil, newError(ErrValidation, "order must contain at least one item")
+	}
+
+	neoOrder, err := uuid.NewV4()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	order := &entity.Order{
+		ID:             neoOrder,
+		UserID:         userID,
+		Status:         OrderStatusPending,
+		BillingName:    billingName,
+		BillingAddress: billingAddress,
+	}
+
+	// Prices are always taken from the catalogue, never from the request
+	for _, line := range lines {
+		currency, err := s.priceLine(line)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		if order.Currency == "" {
+			order.Currency = currency
+		} else if order.Currency != currency {
+			return nil, nil, newError(ErrValidation, "all items of an order must share the same currency")
+		}
+
+		neoLine, err := uuid.NewV4()
+		if err != nil {
+			return nil, nil, err
+		}
+		line.ID = neoLine
+		line.OrderID = order.ID
+		order.Subtotal += line.Amount
+	}
+	order.Subtotal = math.Round(order.Subtotal*100) / 100
+	order.Lines = lines
+
+	var coupon *entity.Coupon
+	if couponCode != "" {
+		coupon, order.Discount, err = s.couponService.ValidateCoupon(couponCode, userID, lines, order.Currency)
+		if err != nil {
+			return nil, nil, err
+		}
+		order.CouponCode = coupon.Code
+	}
+	order.Total = math.Round((order.Subtotal-order.Discount)*100) / 100
+
+	if err := s.repo.Create(order); err != nil {
+		return nil, nil, fmt.Errorf("failed to create order: %w", err)
+	}
+
+	// Redeeming re-checks the coupon limits under a lock, so it can still fail for a valid coupon
+	if coupon != nil {
+		if err := s.couponService.RedeemCoupon(coupon.ID, userID, order.ID, order.Discount); err != nil {
+			if updateErr := s.repo.UpdateStatus(order.ID, OrderStatusFailed); updateErr != nil {
+				log.Printf("Failed to mark order %s as failed: %v", order.ID, updateErr)
+			}
+			return nil, nil, err
+		}
+	}
+
+	// Free orders need no payment and are fulfilled straight away
+	if order.Total == 0 {
+		if err := s.fulfill(order); err != nil {
+			return nil, nil, err
+		}
+		return order, nil, nil
+	}
+
+	transactionID, err := s.gateways.Get(paymentGateway).CreateIntent(order.Total, order.Currency, order.ID.String())
+	if err != nil {
+		if _, abandonErr := s.abandon(order.ID, OrderStatusFailed); abandonErr != nil {
+			log.Printf("Failed to mark order %s as failed: %v", order.ID, abandonErr)
+		}
+		return nil, nil, fmt.Errorf("failed to create payment intent for order with ID %s: %w", order.ID, err)
+	}
+
+	neoPayment, err := uuid.NewV4()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	now := time.Now()
+	newPayment := &entity.Payment{
+		ID:             neoPayment,
+		UserID:         userID,
+		OrderID:        order.ID,
+		Amount:         order.Total,
+		Currency:       order.Currency,
+		PaymentMethod:  paymentMethod,
+		TransactionID:  transactionID,
+		Status:         PaymentStatusPending,
+		PaymentGateway: paymentGateway,
+		CreatedAt:      now,
+		UpdatedAt:      now,
+	}
+
+	if err := s.paymentRepo.Create(newPayment); err != nil {
+		return nil, nil, fmt.Errorf("failed to create payment for order with ID %s: %w", order.ID, err)
+	}
+
+	return order, newPayment, nil
+}
+
+// priceLine fills in the description, price and amount of a line from the course or space it
+// references and returns the currency of that price
+func (s *orderServiceImpl) priceLine(line *entity.OrderLine) (string, error) {
+	var currency string
+
+	switch line.ItemType {
+	case OrderItemCourse:
+		course, err := s.courseRepo.GetdByID(line.ItemID)
+		if err != nil {
+			return "", fmt.Errorf("could not find course with ID %s: %w", line.ItemID, err)
+		}
+		// Courses and memberships are per-user, buying more than one has no meaning
+		line.Quantity = 1
+		line.Description = course.Title
+		line.UnitPrice = course.Price
+		currency = course.Currency
+	case OrderItemSpaceMembership:
+		space, err := s.spaceRepo.GetdByID(line.ItemID)
+		if err != nil {
+			return "", fmt.Errorf("could not find space with ID %s: %w", line.ItemID, err)
+		}
+		if !space.Active {
+			return "", newError(ErrConflict, "space with ID %s is not active", line.ItemID)
+		}
+		line.Quantity = 1
+		line.Description = space.Name + " membership"
+		line.UnitPrice = space.MembershipPrice
+		currency = space.Currency
+	default:
+		return "", newError(ErrValidation, "unknown item type %q", line.ItemType)
+	}
+
+	line.Amount = math.Round(line.UnitPrice*float64(line.Quantity)*100) / 100
+	return currency, nil
+}
+
+// GetOrderByID implements OrderService.
+func (s *orderServiceImpl) GetOrderByID(orderID uuid.UUID) (*entity.Order, error) {
+	order, err := s.repo.GetdByID(orderID)
+	if err != nil {
+		return nil, fmt.Errorf("could not find order with ID %s: %w", orderID, err)
+	}
+
+	return order, nil
+}
+
+// GetOrdersByUser implements OrderService.
+func (s *orderServiceImpl) GetOrdersByUser(userID uuid.UUID) ([]*entity.Order, error) {
+	orders, err := s.repo.GetByUserID(userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get orders of user with ID %s: %w", userID, err)
+	}
+
+	return orders, nil
+}
+
+// CancelOrder implements OrderService.
+func (s *orderServiceImpl) CancelOrder(orderID, userID uuid.UUID) (*entity.Order, error) {
+	order, err := s.repo.GetdByID(orderID)
+	if err != nil {
+		return nil, fmt.Errorf("could not find order with ID %s: %w", orderID, err)
+	}
+
+	// Orders are private to the user who placed them
+	if order.UserID != userID {
+		return nil, newError(ErrNotFound, "could not find order with ID %s", orderID)
+	}
+
+	cancelled, err := s.abandon(order.ID, OrderStatusCancelled)
+	if err != nil {
+		return nil, err
+	}
+	if !cancelled {
+		return nil, newError(ErrConflict, "order with ID %s is %s and can no longer be cancelled", orderID, order.Status)
+	}
+
+	order.Status = OrderStatusCancelled
+	return order, nil
+}
+
+// ExpireOrders implements OrderService.
+func (s *orderServiceImpl) ExpireOrders(now time.Time) error {
+	orderIDs, err := s.repo.GetIDsCreatedBefore(OrderStatusPending, now.Add(-orderPaymentWindow))
+	if err != nil {
+		return fmt.Errorf("failed to get pending orders: %w", err)
+	}
+
+	for _, orderID := range orderIDs {
+		if _, err := s.abandon(orderID, OrderStatusCancelled); err != nil {
+			log.Printf("Failed to expire order %s: %v", orderID, err)
+			continue
+		}
+		log.Printf("Order %s expired without payment", orderID)
+	}
+	return nil
+}
+
+// abandon moves a pending order to the cancelled or failed status, fails its pending payments so they cannot be
+// confirmed any more, and gives its coupon back. It reports false when the order was no longer pending.
+func (s *orderServiceImpl) abandon(orderID uuid.UUID, status string) (bool, error) {
+	abandoned, err := s.repo.TransitionStatus(orderID, OrderStatusPending, status)
+	if err != nil {
+		return false, fmt.Errorf("failed to update order with ID %s: %w", orderID, err)
+	}
+	if !abandoned {
+		return false, nil
+	}
+
+	if err := s.paymentRepo.UpdateStatusByOrderID(orderID, PaymentStatusPending, PaymentStatusFailed); err != nil {
+		return true, fmt.Errorf("failed to fail payments of order with ID %s: %w", orderID, err)
+	}
+	if err := s.couponService.ReleaseCoupon(orderID); err != nil {
+		return true, fmt.Errorf("failed to release coupon of order with ID %s: %w", orderID, err)
+	}
+	return true, nil
+}
+
+// PaymentCompleted implements OrderService and PaymentListener.
+func (s *orderServiceImpl) PaymentCompleted(payment *entity.Payment) error {
+	if payment.OrderID == uuid.Nil {
+		return nil
+	}
+
+	order, err := s.repo.GetdByID(payment.OrderID)
+	if err != nil {
+		return fmt.Errorf("could not find order with ID %s: %w", payment.OrderID, err)
+	}
+
+	if order.Status == OrderStatusPaid {
+		return nil
+	}
+	// A cancelled or failed order gave its coupon back, so its price no longer holds
+	if order.Status != OrderStatusPending {
+		return newError(ErrConflict, "order with ID %s is %s and cannot be paid", order.ID, order.Status)
+	}
+
+	// A payment of another user must never unlock the order, whatever it covers
+	if payment.UserID != order.UserID {
+		return newError(ErrConflict, "payment with ID %s was not made by the user of order with ID %s", payment.ID, order.ID)
+	}
+	if payment.Amount < order.Total || payment.Currency != order.Currency {
+		return newError(ErrConflict, "payment with ID %s does not cover order with ID %s", payment.ID, order.ID)
+	}
+
+	return s.fulfill(order)
+}
+
+// fulfill grants the user access to every item of the order and marks it as paid
+func (s *orderServiceImpl) fulfill(order *entity.Order) error {
+	for _, line := range order.Lines {
+		if line.ItemType == OrderItemSpaceSubscription {
+			continue
+		}
+
+		neoEnrollment, err := uuid.NewV4()
+		if err != nil {
+			return err
+		}
+
+		enrollment := &entity.Enrollment{
+			ID:       neoEnrollment,
+			UserID:   order.UserID,
+			ItemType: line.ItemType,
+			ItemID:   line.ItemID,
+			OrderID:  order.ID,
+		}
+		if err := s.enrollmentRepo.Create(enrollment); err != nil {
+			return fmt.Errorf("failed to enroll user %s in %s %s: %w", order.UserID, line.ItemType, line.ItemID, err)
+		}
+		s.events.Publish(realtime.Event{Type: EventEnrollmentCreated, Data: EnrollmentEvent{
+			EnrollmentID: enrollment.ID,
+			ItemType:     enrollment.ItemType,
+			ItemID:       enrollment.ItemID,
+			OrderID:      enrollment.OrderID,
+		}}, realtime.Audience{UserIDs: []uuid.UUID{order.UserID}})
+		s.notifications.Notify(entity.Notification{
+			Category: entity.NotificationCategoryEnrollment,
+			Type:     entity.NotificationEnrollment,
+			Title:    "You now have access to " + line.Description,
+			Link:     enrollmentLink(line),
+		}, order.UserID)
+
+		if line.ItemType == OrderItemSpaceMembership {
+			member := &entity.SpaceMember{SpaceID: line.ItemID, UserID: order.UserID, Role: entity.SpaceRoleMember}
+			if err := s.memberRepo.Add(member); err != nil {
+				return fmt.Errorf("failed to add user %s to space %s: %w", order.UserID, line.ItemID, err)
+			}
+		}
+	}
+
+	if err := s.repo.UpdateStatus(order.ID, OrderStatusPaid); err != nil {
+		return fmt.Errorf("failed to update order with ID %s: %w", order.ID, err)
+	}
+	order.Status = OrderStatusPaid
+
+	log.Printf("Order %s fulfilled for user %s", order.ID, order.UserID)
+	return nil
+}
+
+// enrollmentLink is the path of the item an order line gave access to
+func enrollmentLink(line *entity.OrderLine) string {
+	if line.ItemType == OrderItemCourse {
+		return "/courses/" + line.ItemID.String()
+	}
+	return "/spaces/" + line.ItemID.String()
+}
@@ -2,9 +2,14 @@ package service
 
 import (
 	"dalabio/internal/entity"
+	"dalabio/internal/framework/payment"
+	"dalabio/internal/framework/realtime"
 	"dalabio/internal/repository"
+	"dalabio/pkg/validation"
 	"fmt"
 	"log"
+	"strings"
+	"time"
 
 	"github.com/gofrs/uuid"
 )
@@ -20,16 +25,126 @@ type PaymentService interface {
 	// GetAllPayments gets all payments
 	GetAllPayments() ([]*entity.Payment, error)
 
-	// UpdatePayment updates a payment
+	// UpdatePayment updates the details of a payment; its amount and status are never taken from the request
 	UpdatePayment(payment *entity.Payment) error
 
+	// ConfirmPayment completes a pending payment once its gateway reports the money as collected
+	ConfirmPayment(paymentID uuid.UUID) (*entity.Payment, error)
+
 	// DeletePayment deletes a payment
 	DeletePayment(paymentID uuid.UUID) error
+
+	// RefundPayment refunds all or part of a completed payment through its gateway
+	RefundPayment(paymentID uuid.UUID, amount float64, reason string, requestedBy uuid.UUID) (*entity.Refund, error)
+
+	// AddPaymentListener registers a listener notified when a payment completes
+	AddPaymentListener(listener PaymentListener)
+
+	// AddRefundListener registers a listener notified when a refund succeeds
+	AddRefundListener(listener RefundListener)
+}
+
+// PaymentListener is notified after a payment reaches the completed status
+type PaymentListener interface {
+	PaymentCompleted(payment *entity.Payment) error
 }
 
+// RefundListener is notified after a refund of a payment succeeds
+type RefundListener interface {
+	PaymentRefunded(payment *entity.Payment, refund *entity.Refund) error
+}
+
+// Payment statuses
+const (
+	PaymentStatusPending           = "pending"
+	PaymentStatusCompleted         = "completed"
+	PaymentStatusFailed            = "failed"
+	PaymentStatusPartiallyRefunded = "partially_refunded"
+	PaymentStatusRefunded          = "refunded"
+)
+
+// Refund statuses
+const (
+	RefundStatusPending   = "pending"
+	RefundStatusSucceeded = "succeeded"
+	RefundStatusFailed    = "failed"
+)
+
 type paymentServiceImpl struct {
-	repo      repository.PaymentRepository
-	repotoken repository.TokenRepository
+	repo            repository.PaymentRepository
+	refundRepo      repository.RefundRepository
+	repotoken       repository.TokenRepository
+	gateways        *payment.Gateways
+	listeners       []PaymentListener
+	refundListeners []RefundListener
+	events          *realtime.Bus
+	notifications   NotificationService
+}
+
+// AddPaymentListener implements PaymentService.
+func (s *paymentServiceImpl) AddPaymentListener(listener PaymentListener) {
+	s.listeners = append(s.listeners, listener)
+}
+
+// AddRefundListener implements PaymentService.
+func (s *paymentServiceImpl) AddRefundListener(listener RefundListener) {
+	s.refundListeners = append(s.refundListeners, listener)
+}
+
+// notifyRefunded informs the refund listeners that a refund has succeeded
+func (s *paymentServiceImpl) notifyRefunded(payment *entity.Payment, refund *entity.Refund) {
+	for _, listener := range s.refundListeners {
+		if err := listener.PaymentRefunded(payment, refund); err != nil {
+			log.Printf("Refund listener failed for refund with ID %s: %v", refund.ID, err)
+		}
+	}
+}
+
+// publishStatus tells the payer that the status of their payment changed
+func (s *paymentServiceImpl) publishStatus(payment *entity.Payment, previousStatus string) {
+	event := realtime.Event{Type: EventPaymentStatusChanged, Data: PaymentEvent{
+		PaymentID:      payment.ID,
+		OrderID:        payment.OrderID,
+		Status:         payment.Status,
+		PreviousStatus: previousStatus,
+		Amount:         payment.Amount,
+		Currency:       payment.Currency,
+	}}
+	s.events.Publish(event, realtime.Audience{UserIDs: []uuid.UUID{payment.UserID}})
+
+	status := strings.ReplaceAll(payment.Status, "_", " ")
+	s.notifications.Notify(entity.Notification{
+		Category: entity.NotificationCategoryPayment,
+		Type:     entity.NotificationPaymentStatus,
+		Title:    "Payment " + status,
+		Body:     fmt.Sprintf("Your payment of %.2f %s is %s.", payment.Amount, payment.Currency, status),
+		Link:     "/payments/" + payment.ID.String(),
+	}, payment.UserID)
+}
+
+// notifyCompleted informs the listeners that a payment has completed
+func (s *paymentServiceImpl) notifyCompleted(payment *entity.Payment) {
+	for _, listener := range s.listeners {
+		if err := listener.PaymentCompleted(payment); err != nil {
+			log.Printf("Payment listener failed for payment with ID %s: %v", payment.ID, err)
+		}
+	}
+}
+
+// validatePayment checks the fields of a payment set by the payer or an administrator
+func validatePayment(payment *entity.Payment) error {
+	v := validation.New()
+	v.Positive("amount", payment.Amount)
+	v.Currency("currency", payment.Currency)
+	v.OneOf("status", payment.Status, PaymentStatusPending, PaymentStatusCompleted, PaymentStatusFailed, PaymentStatusPartiallyRefunded, PaymentStatusRefunded)
+	return v.Err()
+}
+
+// validatePriceCurrency checks the currency of a price; a free item may leave it empty
+func validatePriceCurrency(v *validation.Validator, price float64, currency string) {
+	if price > 0 || currency != "" {
+		v.Currency("currency", currency)
+	}
 }
 
 // DeletePayment implements PaymentService.
@@ -37,11 +152,11 @@ func (s *paymentServiceImpl) DeletePayment(paymentID uuid.UUID) error {
 	_, err := s.repo.GetdByID(paymentID)
 
 	if err != nil {
-		return fmt.Errorf("could not find payment with ID %s: %v", paymentID, err)
+		return fmt.Errorf("could not find payment with ID %s: %w", paymentID, err)
 	}
 
 	if err := s.repo.Delete(paymentID); err != nil {
-		return fmt.Errorf("failed to delete payment with ID %s: %v", paymentID, err)
+		return fmt.Errorf("failed to delete payment with ID %s: %w", paymentID, err)
 	}
 
 	log.Printf("Successfully deleted payment with ID %s", paymentID)
@@ -57,6 +172,7 @@ func (s *paymentServiceImpl) CreatePayment(UserID uuid.UUID, OrderID uuid.UUID,
 			return nil, err
 		}
 
+		now := time.Now()
 		newPayment := &entity.Payment{
 			ID:             neoPayment,
 			UserID:         UserID,
@@ -65,8 +181,15 @@ func (s *paymentServiceImpl) CreatePayment(UserID uuid.UUID, OrderID uuid.UUID,
 			Currency:       Currency,
 			PaymentMethod:  PaymentMethod,
 			TransactionID:  TransactionID,
+			Status:         Status,
 			PaymentGateway: PaymentGateway,
+			PaymentDate:    now,
 			Notes:          Notes,
+			CreatedAt:      now,
+			UpdatedAt:      now,
+		}
+		if err := validatePayment(newPayment); err != nil {
+			return nil, err
 		}
 		log.Printf("insering payment: %v", neoPayment)
 
@@ -76,6 +199,11 @@ func (s *paymentServiceImpl) CreatePayment(UserID uuid.UUID, OrderID uuid.UUID,
 			return nil, err
 		}
 
+		s.publishStatus(newPayment, "")
+		if newPayment.Status == PaymentStatusCompleted {
+			s.notifyCompleted(newPayment)
+		}
+
 		return newPayment, nil
 
 	}
@@ -97,30 +225,163 @@ func (s *paymentServiceImpl) GetPaymentByID(paymentID uuid.UUID) (*entity.Paymen
 	payment, err := s.repo.GetdByID(paymentID)
 
 	if err != nil {
-		return nil, fmt.Errorf("could not find payment with ID %s: %v", paymentID, err)
+		return nil, fmt.Errorf("could not find payment with ID %s: %w", paymentID, err)
 	}
 
+	refunds, err := s.refundRepo.GetByPaymentID(paymentID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get refunds of payment with ID %s: %w", paymentID, err)
+	}
+	payment.Refunds = refunds
+
 	return payment, nil
 }
 
+// RefundPayment implements PaymentService.
+func (s *paymentServiceImpl) RefundPayment(paymentID uuid.UUID, amount float64, reason string, requestedBy uuid.UUID) (*entity.Refund, error) {
+	v := validation.New()
+	v.Positive("amount", amount)
+	v.MaxLength("reason", reason, 500)
+	if err := v.Err(); err != nil {
+		return nil, err
+	}
+
+	payment, err := s.repo.GetdByID(paymentID)
+	if err != nil {
+		return nil, fmt.Errorf("could not find payment with ID %s: %w", paymentID, err)
+	}
+
+	if payment.Status != PaymentStatusCompleted && payment.Status != PaymentStatusPartiallyRefunded {
+		return nil, newError(ErrConflict, "payment with ID %s cannot be refunded in status %q", paymentID, payment.Status)
+	}
+
+	neoRefund, err := uuid.NewV4()
+	if err != nil {
+		return nil, err
+	}
+
+	refund := &entity.Refund{
+		ID:          neoRefund,
+		PaymentID:   paymentID,
+		Amount:      amount,
+		Currency:    payment.Currency,
+		Reason:      reason,
+		Status:      RefundStatusPending,
+		RequestedBy: &requestedBy,
+	}
+
+	// Reserve the amount before calling the gateway so concurrent refunds cannot overdraw the payment
+	if err := s.refundRepo.Create(refund); err != nil {
+		return nil, fmt.Errorf("failed to create refund for payment with ID %s: %w", paymentID, err)
+	}
+
+	gatewayRefundID, err := s.gateways.Get(payment.PaymentGateway).Refund(payment.TransactionID, amount, payment.Currency)
+	if err != nil {
+		refund.Status = RefundStatusFailed
+		if updateErr := s.refundRepo.Update(refund); updateErr != nil {
+			log.Printf("Failed to mark refund %s as failed: %v", refund.ID, updateErr)
+		}
+		return nil, fmt.Errorf("gateway refused refund for payment with ID %s: %w", paymentID, err)
+	}
+
+	refund.Status = RefundStatusSucceeded
+	refund.GatewayRefundID = gatewayRefundID
+	if err := s.refundRepo.Update(refund); err != nil {
+		return nil, fmt.Errorf("failed to update refund with ID %s: %w", refund.ID, err)
+	}
+
+	refunds, err := s.refundRepo.GetByPaymentID(paymentID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get refunds of payment with ID %s: %w", paymentID, err)
+	}
+
+	var refunded float64
+	for _, r := range refunds {
+		if r.Status == RefundStatusSucceeded {
+			refunded += r.Amount
+		}
+	}
+
+	previousStatus := payment.Status
+	payment.Status = PaymentStatusPartiallyRefunded
+	if refunded >= payment.Amount {
+		payment.Status = PaymentStatusRefunded
+	}
+	if err := s.repo.Update(payment); err != nil {
+		return nil, fmt.Errorf("failed to update payment with ID %s: %w", paymentID, err)
+	}
+
+	if payment.Status != previousStatus {
+		s.publishStatus(payment, previousStatus)
+	}
+	s.notifyRefunded(payment, refund)
+
+	log.Printf("Refunded %.2f %s of payment with ID %s", amount, payment.Currency, paymentID)
+	return refund, nil
+}
+
 // UpdatePayment implements PaymentService.
 func (s *paymentServiceImpl) UpdatePayment(payment *entity.Payment) error {
-	_, err := s.repo.GetdByID(payment.ID)
+	existing, err := s.repo.GetdByID(payment.ID)
 
 	if err != nil {
-		return fmt.Errorf("could not find payment with ID %s: %v", payment.ID, err)
+		return fmt.Errorf("could not find payment with ID %s: %w", payment.ID, err)
+	}
+
+	// The payer, order, amount and date of a payment never change; its status only changes through the
+	// gateway, with ConfirmPayment and refunds
+	payment.UserID = existing.UserID
+	payment.OrderID = existing.OrderID
+	payment.Amount = existing.Amount
+	payment.Status = existing.Status
+	payment.PaymentDate = existing.PaymentDate
+
+	if err := validatePayment(payment); err != nil {
+		return err
 	}
 
 	if err := s.repo.Update(payment); err != nil {
-		return fmt.Errorf("failed to update payment with ID %s: %v", payment.ID, err)
+		return fmt.Errorf("failed to update payment with ID %s: %w", payment.ID, err)
 	}
 
 	return nil
 }
 
-func NewPaymentService(paymentRepo repository.PaymentRepository, repotoken repository.TokenRepository) PaymentService {
+// ConfirmPayment implements PaymentService.
+func (s *paymentServiceImpl) ConfirmPayment(paymentID uuid.UUID) (*entity.Payment, error) {
+	payment, err := s.repo.GetdByID(paymentID)
+	if err != nil {
+		return nil, fmt.Errorf("could not find payment with ID %s: %w", paymentID, err)
+	}
+	if payment.Status != PaymentStatusPending {
+		return nil, newError(ErrConflict, "payment with ID %s cannot be confirmed in status %q", paymentID, payment.Status)
+	}
+
+	captured, err := s.gateways.Get(payment.PaymentGateway).Captured(payment.TransactionID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check payment with ID %s with its gateway: %w", paymentID, err)
+	}
+	if !captured {
+		return nil, newError(ErrConflict, "the gateway has not collected payment with ID %s yet", paymentID)
+	}
+
+	payment.Status = PaymentStatusCompleted
+	if err := s.repo.Update(payment); err != nil {
+		return nil, fmt.Errorf("failed to update payment with ID %s: %w", paymentID, err)
+	}
+
+	s.publishStatus(payment, PaymentStatusPending)
+	s.notifyCompleted(payment)
+	return payment, nil
+}
+
+func NewPaymentService(paymentRepo repository.PaymentRepository, refundRepo repository.RefundRepository, repotoken repository.TokenRepository, gateways *payment.Gateways, events *realtime.Bus, notifications NotificationService) PaymentService {
 	return &paymentServiceImpl{
-		repo:      paymentRepo,
-		repotoken: repotoken,
+		repo:          paymentRepo,
+		refundRepo:    refundRepo,
+		repotoken:     repotoken,
+		gateways:      gateways,
+		events:        events,
+		notifications: notifications,
 	}
 }
@@ -0,0 +1,404 @@
+package service
+
+import (
+	"dalabio/internal/entity"
+	"dalabio/internal/repository"
+	"dalabio/pkg/config"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"time"
+
+	"github.com/gofrs/uuid"
+)
+
+// Ledger accounts
+const (
+	LedgerAccountCash           = "cash"            // Money held by the platform
+	LedgerAccountPlatformFees   = "platform_fees"   // Platform revenue, attributed to the creator it was charged to
+	LedgerAccountCreatorPayable = "creator_payable" // Creator share owed by the platform
+)
+
+// Ledger transaction references
+const (
+	LedgerReferencePayment        = "payment"
+	LedgerReferenceRefund         = "refund"
+	LedgerReferencePayout         = "payout"
+	LedgerReferencePayoutReversal = "payout_reversal"
+)
+
+// Payout statuses
+const (
+	PayoutStatusPending = "pending"
+	PayoutStatusPaid    = "paid"
+	PayoutStatusFailed  = "failed"
+)
+
+// Earnings periods
+const (
+	EarningsPeriodDay   = "day"
+	EarningsPeriodWeek  = "week"
+	EarningsPeriodMonth = "month"
+	EarningsPeriodYear  = "year"
+)
+
+type PayoutService interface {
+
+	// GetEarnings returns the balances, payouts and activity per period of a coach or instructor
+	GetEarnings(userID uuid.UUID, period string, from, to time.Time) (*entity.Earnings, error)
+
+	// SchedulePayouts creates a pending payout for every available balance above the minimum
+	SchedulePayouts(now time.Time) error
+
+	// GetAllPayouts returns every payout
+	GetAllPayouts() ([]*entity.Payout, error)
+
+	// UpdatePayoutStatus marks a pending payout as paid or failed; failed payouts return to the balance
+	UpdatePayoutStatus(payoutID uuid.UUID, status string, reference string) (*entity.Payout, error)
+
+	// PaymentCompleted splits a completed payment into platform fees and creator shares
+	PaymentCompleted(payment *entity.Payment) error
+
+	// PaymentRefunded takes the refunded part of a payment back from the platform and the creators
+	PaymentRefunded(payment *entity.Payment, refund *entity.Refund) error
+}
+
+type payoutServiceImpl struct {
+	repo       repository.PayoutRepository
+	ledgerRepo repository.LedgerRepository
+	orderRepo  repository.OrderRepository
+	courseRepo repository.CourseRepository
+	spaceRepo  repository.SpaceRepository
+	config     *config.PayoutConfig
+}
+
+func NewPayoutService(payoutRepo repository.PayoutRepository, ledgerRepo repository.LedgerRepository, orderRepo repository.OrderRepository, courseRepo repository.CourseRepository, spaceRepo repository.SpaceRepository, cfg *config.PayoutConfig) PayoutService {
+	return &payoutServiceImpl{
+		repo:       payoutRepo,
+		ledgerRepo: ledgerRepo,
+		orderRepo:  orderRepo,
+		courseRepo: courseRepo,
+		spaceRepo:  spaceRepo,
+		config:     cfg,
+	}
+}
+
+// PaymentCompleted implements PayoutService and PaymentListener.
+// Each order line is attributed to the instructor of the course or the coach of the space;
+// the coupon discount is spread over the lines in proportion to their amount. Whatever is
+// not attributed to a creator, including rounding, is credited to the platform.
+func (s *payoutServiceImpl) PaymentCompleted(payment *entity.Payment) error {
+	if _, err := s.ledgerRepo.GetByReference(LedgerReferencePayment, payment.ID); err == nil {
+		return nil
+	}
+
+	var owners []uuid.UUID
+	gross := map[uuid.UUID]float64{}
+
+	if payment.OrderID != uuid.Nil {
+		order, err := s.orderRepo.GetdByID(payment.OrderID)
+		if err != nil {
+			return fmt.Errorf("could not find order with ID %s: %w", payment.OrderID, err)
+		}
+		for _, line := range order.Lines {
+			owner := s.creatorOf(line)
+			if owner == uuid.Nil || order.Subtotal <= 0 {
+				continue
+			}
+			if _, ok := gross[owner]; !ok {
+				owners = append(owners, owner)
+			}
+			// The order total is what the catalogue priced, whatever amount was recorded on the payment
+			gross[owner] += line.Amount * order.Total / order.Subtotal
+		}
+	}
+
+	transaction, err := newLedgerTransaction(LedgerReferencePayment, payment.ID, fmt.Sprintf("Payment %s", payment.ID))
+	if err != nil {
+		return err
+	}
+	if err := transaction.add(LedgerAccountCash, uuid.Nil, payment.Currency, payment.Amount, 0); err != nil {
+		return err
+	}
+
+	credited := 0.0
+	for _, owner := range owners {
+		share := cents(gross[owner])
+		fee := cents(share * s.config.PlatformFeePercent / 100)
+		if err := transaction.add(LedgerAccountPlatformFees, owner, payment.Currency, 0, fee); err != nil {
+			return err
+		}
+		if err := transaction.add(LedgerAccountCreatorPayable, owner, payment.Currency, 0, cents(share-fee)); err != nil {
+			return err
+		}
+		credited += share
+	}
+	if err := transaction.balance(LedgerAccountPlatformFees, payment.Currency); err != nil {
+		return err
+	}
+
+	if err := s.ledgerRepo.Post(transaction.LedgerTransaction); err != nil {
+		return fmt.Errorf("failed to post payment with ID %s to the ledger: %w", payment.ID, err)
+	}
+
+	log.Printf("Posted payment %s to the ledger: %.2f %s attributed to %d creators", payment.ID, credited, payment.Currency, len(owners))
+	return nil
+}
+
+// PaymentRefunded implements PayoutService and RefundListener.
+// The entries of the payment are reversed in proportion to the refunded amount.
+func (s *payoutServiceImpl) PaymentRefunded(payment *entity.Payment, refund *entity.Refund) error {
+	posted, err := s.ledgerRepo.GetByReference(LedgerReferencePayment, payment.ID)
+	if err != nil {
+		// Payments completed before the ledger existed were never attributed
+		log.Printf("Payment %s is not in the ledger, skipping refund %s", payment.ID, refund.ID)
+		return nil
+	}
+
+	transaction, err := newLedgerTransaction(LedgerReferenceRefund, refund.ID, fmt.Sprintf("Refund %s of payment %s", refund.ID, payment.ID))
+	if err != nil {
+		return err
+	}
+
+	ratio := refund.Amount / payment.Amount
+	for _, entry := range posted.Entries {
+		if entry.Account == LedgerAccountCash {
+			continue
+		}
+		if err := transaction.add(entry.Account, entry.OwnerID, entry.Currency, cents(entry.Credit*ratio), cents(entry.Debit*ratio)); err != nil {
+			return err
+		}
+	}
+	if err := transaction.add(LedgerAccountCash, uuid.Nil, refund.Currency, 0, refund.Amount); err != nil {
+		return err
+	}
+	if err := transaction.balance(LedgerAccountPlatformFees, refund.Currency); err != nil {
+		return err
+	}
+
+	if err := s.ledgerRepo.Post(transaction.LedgerTransaction); err != nil {
+		return fmt.Errorf("failed to post refund with ID %s to the ledger: %w", refund.ID, err)
+	}
+
+	return nil
+}
+
+// GetEarnings implements PayoutService.
+func (s *payoutServiceImpl) GetEarnings(userID uuid.UUID, period string, from, to time.Time) (*entity.Earnings, error) {
+	switch period {
+	case EarningsPeriodDay, EarningsPeriodWeek, EarningsPeriodMonth, EarningsPeriodYear:
+	default:
+		return nil, newError(ErrValidation, "invalid period %q, expected day, week, month or year", period)
+	}
+
+	if !from.Before(to) {
+		return nil, newError(ErrValidation, "from must be before to")
+	}
+
+	balances, err := s.ledgerRepo.GetBalances(userID, s.holdSince(time.Now()))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get balances of user with ID %s: %w", userID, err)
+	}
+
+	periods, err := s.ledgerRepo.GetEarnings(userID, period, from, to)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get earnings of user with ID %s: %w", userID, err)
+	}
+
+	payouts, err := s.repo.GetByUserID(userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get payouts of user with ID %s: %w", userID, err)
+	}
+
+	return &entity.Earnings{Balances: balances, Periods: periods, Payouts: payouts}, nil
+}
+
+// SchedulePayouts implements PayoutService.
+func (s *payoutServiceImpl) SchedulePayouts(now time.Time) error {
+	balances, err := s.ledgerRepo.GetPayableBalances(s.holdSince(now))
+	if err != nil {
+		return fmt.Errorf("failed to get payable balances: %w", err)
+	}
+
+	// The period makes the payouts of a day unique, so that instances scheduling at the same time
+	// cannot pay the same balance twice
+	period := now.UTC().Format("2006-01-02")
+	for _, balance := range balances {
+		amount := cents(balance.Available)
+		if balance.UserID == uuid.Nil || amount <= 0 || amount < s.config.MinimumAmount {
+			continue
+		}
+
+		neoPayout, err := uuid.NewV4()
+		if err != nil {
+			return err
+		}
+
+		payout := &entity.Payout{
+			ID:       neoPayout,
+			UserID:   balance.UserID,
+			Amount:   amount,
+			Currency: balance.Currency,
+			Status:   PayoutStatusPending,
+			Period:   period,
+		}
+
+		transaction, err := newLedgerTransaction(LedgerReferencePayout, payout.ID, fmt.Sprintf("Payout %s", payout.ID))
+		if err != nil {
+			return err
+		}
+		if err := transaction.add(LedgerAccountCreatorPayable, payout.UserID, payout.Currency, amount, 0); err != nil {
+			return err
+		}
+		if err := transaction.add(LedgerAccountCash, uuid.Nil, payout.Currency, 0, amount); err != nil {
+			return err
+		}
+
+		if err := s.repo.Create(payout, transaction.LedgerTransaction); errors.Is(err, ErrConflict) {
+			log.Printf("Payout of user %s in %s was already scheduled on %s", payout.UserID, payout.Currency, period)
+			continue
+		} else if err != nil {
+			log.Printf("Failed to schedule payout of %.2f %s to user %s: %v", amount, payout.Currency, payout.UserID, err)
+			continue
+		}
+
+		log.Printf("Scheduled payout %s of %.2f %s to user %s", payout.ID, amount, payout.Currency, payout.UserID)
+	}
+
+	return nil
+}
+
+// GetAllPayouts implements PayoutService.
+func (s *payoutServiceImpl) GetAllPayouts() ([]*entity.Payout, error) {
+	return s.repo.GetAll()
+}
+
+// UpdatePayoutStatus implements PayoutService.
+func (s *payoutServiceImpl) UpdatePayoutStatus(payoutID uuid.UUID, status string, reference string) (*entity.Payout, error) {
+	payout, err := s.repo.GetdByID(payoutID)
+	if err != nil {
+		return nil, fmt.Errorf("could not find payout with ID %s: %w", payoutID, err)
+	}
+
+	if payout.Status != PayoutStatusPending {
+		return nil, newError(ErrConflict, "payout with ID %s is already %s", payoutID, payout.Status)
+	}
+
+	var reversal *ledgerTransaction
+	switch status {
+	case PayoutStatusPaid:
+		now := time.Now()
+		payout.PaidAt = &now
+	case PayoutStatusFailed:
+		// The money never left, so the amount is owed to the creator again
+		reversal, err = newLedgerTransaction(LedgerReferencePayoutReversal, payout.ID, fmt.Sprintf("Failed payout %s", payout.ID))
+		if err != nil {
+			return nil, err
+		}
+		if err := reversal.add(LedgerAccountCash, uuid.Nil, payout.Currency, payout.Amount, 0); err != nil {
+			return nil, err
+		}
+		if err := reversal.add(LedgerAccountCreatorPayable, payout.UserID, payout.Currency, 0, payout.Amount); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, newError(ErrValidation, "invalid payout status %q, expected paid or failed", status)
+	}
+
+	payout.Status = status
+	payout.Reference = reference
+
+	var transaction *entity.LedgerTransaction
+	if reversal != nil {
+		transaction = reversal.LedgerTransaction
+	}
+	if err := s.repo.Update(payout, transaction); err != nil {
+		return nil, fmt.Errorf("failed to update payout with ID %s: %w", payoutID, err)
+	}
+
+	return payout, nil
+}
+
+// creatorOf returns the instructor or coach a sold item is attributed to, or uuid.Nil for the platform
+func (s *payoutServiceImpl) creatorOf(line *entity.OrderLine) uuid.UUID {
+	switch line.ItemType {
+	case OrderItemCourse:
+		course, err := s.courseRepo.GetdByID(line.ItemID)
+		if err != nil {
+			log.Printf("Could not find course %s of order line %s, attributing it to the platform: %v", line.ItemID, line.ID, err)
+			return uuid.Nil
+		}
+		return course.InstructorID
+	case OrderItemSpaceMembership, OrderItemSpaceSubscription:
+		space, err := s.spaceRepo.GetdByID(line.ItemID)
+		if err != nil {
+			log.Printf("Could not find space %s of order line %s, attributing it to the platform: %v", line.ItemID, line.ID, err)
+			return uuid.Nil
+		}
+		return space.CoachID
+	}
+	return uuid.Nil
+}
+
+// holdSince returns the start of the refund hold period
+func (s *payoutServiceImpl) holdSince(now time.Time) time.Time {
+	return now.AddDate(0, 0, -s.config.HoldDays)
+}
+
+// ledgerTransaction builds a ledger transaction entry by entry
+type ledgerTransaction struct {
+	*entity.LedgerTransaction
+}
+
+func newLedgerTransaction(referenceType string, referenceID uuid.UUID, description string) (*ledgerTransaction, error) {
+	neoTransaction, err := uuid.NewV4()
+	if err != nil {
+		return nil, err
+	}
+	return &ledgerTransaction{&entity.LedgerTransaction{
+		ID:            neoTransaction,
+		ReferenceType: referenceType,
+		ReferenceID:   referenceID,
+		Description:   description,
+	}}, nil
+}
+
+// add appends an entry, skipping entries without an amount
+func (t *ledgerTransaction) add(account string, ownerID uuid.UUID, currency string, debit, credit float64) error {
+	if debit == 0 && credit == 0 {
+		return nil
+	}
+	neoEntry, err := uuid.NewV4()
+	if err != nil {
+		return err
+	}
+	t.Entries = append(t.Entries, &entity.LedgerEntry{
+		ID:            neoEntry,
+		TransactionID: t.ID,
+		Account:       account,
+		OwnerID:       ownerID,
+		Currency:      currency,
+		Debit:         debit,
+		Credit:        credit,
+	})
+	return nil
+}
+
+// balance posts the difference between debits and credits to a platform account
+func (t *ledgerTransaction) balance(account string, currency string) error {
+	difference := 0.0
+	for _, entry := range t.Entries {
+		difference += entry.Debit - entry.Credit
+	}
+	difference = cents(difference)
+	if difference > 0 {
+		return t.add(account, uuid.Nil, currency, 0, difference)
+	}
+	return t.add(account, uuid.Nil, currency, -difference, 0)
+}
+
+// cents rounds an amount to two decimals
+func cents(amount float64) float64 {
+	return math.Round(amount*100) / 100
+}
@@ -0,0 +1,418 @@
+package service
+
+import (
+	"dalabio/internal/entity"
+	"dalabio/internal/repository"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gofrs/uuid"
+)
+
+// Reconciliation discrepancy types
+const (
+	DiscrepancyMissingPayment    = "missing_payment"    // Settled by the gateway but not recorded in the payments table
+	DiscrepancyMissingSettlement = "missing_settlement" // Recorded as collected but absent from the settlement file
+	DiscrepancyDuplicated        = "duplicated"         // Settled more than once in the same file
+	DiscrepancyAmountMismatch    = "amount_mismatch"    // Settled with another amount or currency than recorded
+)
+
+type ReconciliationService interface {
+
+	// Reconcile imports a settlement CSV and compares it with the payments made between from and to.
+	// The file needs transaction_id, amount and currency columns; settled_at is optional.
+	Reconcile(fileName string, file io.Reader, gateway string, from, to time.Time, createdBy uuid.UUID) (*entity.Reconciliation, error)
+
+	// GetReconciliationByID returns a reconciliation run and its discrepancies
+	GetReconciliationByID(reconciliationID uuid.UUID) (*entity.Reconciliation, error)
+
+	// GetAllReconciliations returns every reconciliation run
+	GetAllReconciliations() ([]*entity.Reconciliation, error)
+
+	// ExportCSV writes the payments made between from and to and the discrepancies flagged for them as CSV
+	ExportCSV(w io.Writer, gateway string, from, to time.Time) error
+}
+
+type reconciliationServiceImpl struct {
+	repo        repository.ReconciliationRepository
+	paymentRepo repository.PaymentRepository
+}
+
+func NewReconciliationService(reconciliationRepo repository.ReconciliationRepository, paymentRepo repository.PaymentRepository) ReconciliationService {
+	return &reconciliationServiceImpl{
+		repo:        reconciliationRepo,
+		paymentRepo: paymentRepo,
+	}
+}
+
+// settlementRow is a transaction reported in a gateway settlement file
+type settlementRow struct {
+	line          int
+	transactionID string
+	amount        float64
+	currency      string
+	settledAt     time.Time
+}
+
+// Reconcile implements ReconciliationService.
+func (s *reconciliationServiceImpl) Reconcile(fileName string, file io.Reader, gateway string, from, to time.Time, createdBy uuid.UUID) (*entity.Reconciliation, error) {
+	if !from.Before(to) {
+		return nil, newError(ErrValidation, "from must be before to")
+	}
+
+	rows, err := parseSettlement(file, from)
+	if err != nil {
+		return nil, err
+	}
+
+	payments, err := s.paymentRepo.GetByDateRange(from, to)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get payments: %w", err)
+	}
+
+	// Payments the gateway should have settled, by transaction ID
+	expected := map[string]*entity.Payment{}
+	for _, payment := range payments {
+		if payment.TransactionID != "" && settled(payment) && matchesGateway(payment, gateway) {
+			expected[payment.TransactionID] = payment
+		}
+	}
+
+	occurrences := map[string]int{}
+	for _, row := range rows {
+		occurrences[row.transactionID]++
+	}
+
+	neoReconciliation, err := uuid.NewV4()
+	if err != nil {
+		return nil, err
+	}
+
+	reconciliation := &entity.Reconciliation{
+		ID:          neoReconciliation,
+		Gateway:     gateway,
+		FileName:    fileName,
+		PeriodStart: from,
+		PeriodEnd:   to,
+		RowCount:    len(rows),
+		CreatedBy:   createdBy,
+	}
+
+	flag := func(kind string, transactionID string, payment *entity.Payment, settledAmount float64, currency string, occurredAt time.Time, details string) error {
+		neoDiscrepancy, err := uuid.NewV4()
+		if err != nil {
+			return err
+		}
+		discrepancy := &entity.ReconciliationDiscrepancy{
+			ID:               neoDiscrepancy,
+			ReconciliationID: reconciliation.ID,
+			Type:             kind,
+			TransactionID:    transactionID,
+			SettledAmount:    settledAmount,
+			Currency:         currency,
+			OccurredAt:       occurredAt,
+			Details:          details,
+		}
+		if payment != nil {
+			discrepancy.PaymentID = &payment.ID
+			discrepancy.ExpectedAmount = payment.Amount
+		}
+		reconciliation.Discrepancies = append(reconciliation.Discrepancies, discrepancy)
+		return nil
+	}
+
+	handled := map[string]bool{}
+	for _, row := range rows {
+		if handled[row.transactionID] {
+			continue
+		}
+		handled[row.transactionID] = true
+
+		payment, ok := expected[row.transactionID]
+		if !ok {
+			// The payment may have been recorded outside the period, e.g. settled the next day
+			if payment, err = s.paymentRepo.GetByTransactionID(row.transactionID); err != nil {
+				payment = nil
+			}
+		}
+		delete(expected, row.transactionID)
+
+		if count := occurrences[row.transactionID]; count > 1 {
+			if err := flag(DiscrepancyDuplicated, row.transactionID, payment, row.amount, row.currency, row.settledAt,
+				fmt.Sprintf("settled %d times in the file", count)); err != nil {
+				return nil, err
+			}
+			continue
+		}
+
+		if payment == nil {
+			if err := flag(DiscrepancyMissingPayment, row.transactionID, nil, row.amount, row.currency, row.settledAt,
+				fmt.Sprintf("line %d has no matching payment", row.line)); err != nil {
+				return nil, err
+			}
+			continue
+		}
+
+		if math.Abs(payment.Amount-row.amount) >= 0.005 || !strings.EqualFold(payment.Currency, row.currency) {
+			if err := flag(DiscrepancyAmountMismatch, row.transactionID, payment, row.amount, row.currency, row.settledAt,
+				fmt.Sprintf("recorded %.2f %s, settled %.2f %s", payment.Amount, payment.Currency, row.amount, row.currency)); err != nil {
+				return nil, err
+			}
+			continue
+		}
+
+		reconciliation.MatchedCount++
+	}
+
+	// Whatever is left was collected according to the payments table but never settled
+	var missing []*entity.Payment
+	for _, payment := range expected {
+		missing = append(missing, payment)
+	}
+	sort.Slice(missing, func(i, j int) bool { return missing[i].PaymentDate.Before(missing[j].PaymentDate) })
+	for _, payment := range missing {
+		if err := flag(DiscrepancyMissingSettlement, payment.TransactionID, payment, 0, payment.Currency, payment.PaymentDate,
+			"payment is not in the settlement file"); err != nil {
+			return nil, err
+		}
+	}
+
+	reconciliation.DiscrepancyCount = len(reconciliation.Discrepancies)
+
+	if err := s.repo.Create(reconciliation); err != nil {
+		return nil, fmt.Errorf("failed to save reconciliation: %w", err)
+	}
+
+	log.Printf("Reconciled %s: %d rows, %d matched, %d discrepancies", fileName, reconciliation.RowCount, reconciliation.MatchedCount, reconciliation.DiscrepancyCount)
+	return reconciliation, nil
+}
+
+// GetReconciliationByID implements ReconciliationService.
+func (s *reconciliationServiceImpl) GetReconciliationByID(reconciliationID uuid.UUID) (*entity.Reconciliation, error) {
+	reconciliation, err := s.repo.GetdByID(reconciliationID)
+	if err != nil {
+		return nil, fmt.Errorf("could not find reconciliation with ID %s: %w", reconciliationID, err)
+	}
+	return reconciliation, nil
+}
+
+// GetAllReconciliations implements ReconciliationService.
+func (s *reconciliationServiceImpl) GetAllReconciliations() ([]*entity.Reconciliation, error) {
+	return s.repo.GetAll()
+}
+
+// ExportCSV implements ReconciliationService.
+// Every payment of the period is written with the discrepancies flagged for it, followed by
+// the settled transactions that have no payment at all.
+func (s *reconciliationServiceImpl) ExportCSV(w io.Writer, gateway string, from, to time.Time) error {
+	if !from.Before(to) {
+		return newError(ErrValidation, "from must be before to")
+	}
+
+	payments, err := s.paymentRepo.GetByDateRange(from, to)
+	if err != nil {
+		return fmt.Errorf("failed to get payments: %w", err)
+	}
+
+	discrepancies, err := s.repo.GetDiscrepanciesByDateRange(from, to)
+	if err != nil {
+		return fmt.Errorf("failed to get discrepancies: %w", err)
+	}
+
+	reconciliations, err := s.repo.GetAll()
+	if err != nil {
+		return fmt.Errorf("failed to get reconciliations: %w", err)
+	}
+	gateways := map[uuid.UUID]string{}
+	for _, reconciliation := range reconciliations {
+		gateways[reconciliation.ID] = reconciliation.Gateway
+	}
+
+	byPayment := map[uuid.UUID][]*entity.ReconciliationDiscrepancy{}
+	var unmatched []*entity.ReconciliationDiscrepancy
+	for _, discrepancy := range discrepancies {
+		if discrepancy.PaymentID != nil {
+			byPayment[*discrepancy.PaymentID] = append(byPayment[*discrepancy.PaymentID], discrepancy)
+		} else if gateway == "" || strings.EqualFold(gateways[discrepancy.ReconciliationID], gateway) {
+			unmatched = append(unmatched, discrepancy)
+		}
+	}
+
+	writer := csv.NewWriter(w)
+	if err := writer.Write([]string{"record_type", "payment_id", "transaction_id", "date", "gateway", "payment_method", "status", "currency", "amount", "settled_amount", "discrepancies", "details"}); err != nil {
+		return err
+	}
+
+	for _, payment := range payments {
+		if !matchesGateway(payment, gateway) {
+			continue
+		}
+
+		// A payment may have been flagged by several runs; list each kind once
+		var kinds, details []string
+		settledAmount := ""
+		seen := map[string]bool{}
+		for _, discrepancy := range byPayment[payment.ID] {
+			if !seen[discrepancy.Type] {
+				seen[discrepancy.Type] = true
+				kinds = append(kinds, discrepancy.Type)
+				details = append(details, discrepancy.Details)
+			}
+			if discrepancy.Type == DiscrepancyAmountMismatch || discrepancy.Type == DiscrepancyDuplicated {
+				settledAmount = formatAmount(discrepancy.SettledAmount)
+			}
+		}
+
+		err := writer.Write([]string{
+			"payment",
+			payment.ID.String(),
+			csvText(payment.TransactionID),
+			payment.PaymentDate.Format(time.RFC3339),
+			csvText(payment.PaymentGateway),
+			csvText(payment.PaymentMethod),
+			csvText(payment.Status),
+			csvText(payment.Currency),
+			formatAmount(payment.Amount),
+			settledAmount,
+			strings.Join(kinds, ";"),
+			csvText(strings.Join(details, "; ")),
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	for _, discrepancy := range unmatched {
+		err := writer.Write([]string{
+			"settlement",
+			"",
+			csvText(discrepancy.TransactionID),
+			discrepancy.OccurredAt.Format(time.RFC3339),
+			csvText(gateways[discrepancy.ReconciliationID]),
+			"",
+			"",
+			csvText(discrepancy.Currency),
+			"",
+			formatAmount(discrepancy.SettledAmount),
+			discrepancy.Type,
+			csvText(discrepancy.Details),
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	writer.Flush()
+	return writer.Error()
+}
+
+// parseSettlement reads the rows of a settlement CSV; rows without a settlement date default to from
+func parseSettlement(file io.Reader, from time.Time) ([]*settlementRow, error) {
+	reader := csv.NewReader(file)
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err == io.EOF {
+		return nil, newError(ErrValidation, "settlement file is empty")
+	}
+	if err != nil {
+		return nil, settlementReadError(1, err)
+	}
+
+	columns := map[string]int{}
+	for i, name := range header {
+		columns[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	for _, required := range []string{"transaction_id", "amount", "currency"} {
+		if _, ok := columns[required]; !ok {
+			return nil, newError(ErrValidation, "settlement file has no %s column", required)
+		}
+	}
+	settledAtColumn, hasSettledAt := columns["settled_at"]
+
+	var rows []*settlementRow
+	for line := 2; ; line++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, settlementReadError(line, err)
+		}
+
+		row := &settlementRow{
+			line:          line,
+			transactionID: strings.TrimSpace(record[columns["transaction_id"]]),
+			currency:      strings.ToUpper(strings.TrimSpace(record[columns["currency"]])),
+			settledAt:     from,
+		}
+		if row.transactionID == "" {
+			return nil, newError(ErrValidation, "settlement line %d has no transaction ID", line)
+		}
+
+		// ParseFloat also reads "NaN" and "Inf", which no gateway settles
+		row.amount, err = strconv.ParseFloat(strings.TrimSpace(record[columns["amount"]]), 64)
+		if err != nil || math.IsNaN(row.amount) || math.IsInf(row.amount, 0) {
+			return nil, newError(ErrValidation, "settlement line %d: invalid amount", line)
+		}
+
+		if hasSettledAt {
+			if value := strings.TrimSpace(record[settledAtColumn]); value != "" {
+				if row.settledAt, err = time.Parse(time.RFC3339, value); err != nil {
+					if row.settledAt, err = time.Parse("2006-01-02", value); err != nil {
+						return nil, newError(ErrValidation, "settlement line %d has an invalid settled_at date", line)
+					}
+				}
+			}
+		}
+
+		rows = append(rows, row)
+	}
+
+	return rows, nil
+}
+
+// settlementReadError reports a malformed settlement line, such as one with the wrong number of fields, as invalid
+// input, and any other failure to read the file as is
+func settlementReadError(line int, err error) error {
+	var parseErr *csv.ParseError
+	if errors.As(err, &parseErr) {
+		return newError(ErrValidation, "settlement line %d: %v", line, parseErr.Err)
+	}
+	return fmt.Errorf("failed to read settlement line %d: %w", line, err)
+}
+
+// settled reports whether the gateway should have settled the payment
+func settled(payment *entity.Payment) bool {
+	switch payment.Status {
+	case PaymentStatusCompleted, PaymentStatusPartiallyRefunded, PaymentStatusRefunded:
+		return true
+	}
+	return false
+}
+
+// matchesGateway reports whether the payment went through gateway, any gateway matching the empty name
+func matchesGateway(payment *entity.Payment, gateway string) bool {
+	return gateway == "" || strings.EqualFold(payment.PaymentGateway, gateway)
+}
+
+func formatAmount(amount float64) string {
+	return strconv.FormatFloat(amount, 'f', 2, 64)
+}
+
+// csvText neutralises a text cell that spreadsheets would run as a formula, such as a transaction ID
+// starting with "=" taken from a settlement file, by prefixing it with a quote
+func csvText(value string) string {
+	if value != "" && strings.ContainsRune("=+-@\t\r", rune(value[0])) {
+		return "'" + value
+	}
+	return value
+}
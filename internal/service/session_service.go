@@ -0,0 +1,50 @@
+package service
+
+import (
+	"fmt"
+	"log"
+	"time"
+
+	"dalabio/internal/entity"
+	"dalabio/internal/repository"
+
+	"github.com/gofrs/uuid"
+)
+
+// SessionService lets users see the devices they are signed in on and sign them out.
+type SessionService interface {
+	// ListSessions returns the unexpired sessions of a user, most recently used first
+	ListSessions(userID uuid.UUID) ([]*entity.Token, error)
+
+	// RevokeSession signs a user out of one session. With signed access tokens, the session can no longer be
+	// refreshed and its last access token works until it expires.
+	RevokeSession(userID, sessionID uuid.UUID) error
+}
+
+// sessionServiceImpl is the implementation of SessionService.
+type sessionServiceImpl struct {
+	tokenRepo repository.TokenRepository
+}
+
+// NewSessionService creates a new SessionService instance.
+func NewSessionService(tokenRepo repository.TokenRepository) SessionService {
+	return &sessionServiceImpl{tokenRepo: tokenRepo}
+}
+
+// ListSessions implements SessionService.
+func (s *sessionServiceImpl) ListSessions(userID uuid.UUID) ([]*entity.Token, error) {
+	sessions, err := s.tokenRepo.ListByUserID(userID, time.Now())
+	if err != nil {
+		return nil, fmt.Errorf("failed to get sessions of user with ID %s: %w", userID, err)
+	}
+	return sessions, nil
+}
+
+// RevokeSession implements SessionService.
+func (s *sessionServiceImpl) RevokeSession(userID, sessionID uuid.UUID) error {
+	if err := s.tokenRepo.Delete(userID, sessionID); err != nil {
+		return fmt.Errorf("failed to revoke session %s: %w", sessionID, err)
+	}
+	log.Printf("Revoked session %s of user with ID %s", sessionID, userID)
+	return nil
+}
@@ -0,0 +1,431 @@
+package service
+
+import (
+	"dalabio/internal/entity"
+	"dalabio/internal/repository"
+	"dalabio/pkg/utils"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/gofrs/uuid"
+)
+
+// defaultInvitationTTL is how long an invitation link stays valid when no expiry is given
+const defaultInvitationTTL = 7 * 24 * time.Hour
+
+type SpaceMemberService interface {
+
+	// GetMembers returns the members of a space to one of its members
+	GetMembers(spaceID, requesterID uuid.UUID) ([]*entity.SpaceMember, error)
+
+	// Join adds the user to a space, either with an invitation token or because the space is
+	// free or the user bought a membership or subscription
+	Join(spaceID, userID uuid.UUID, token string) (*entity.SpaceMember, error)
+
+	// Leave removes the user from a space; the owner cannot leave their own space
+	Leave(spaceID, userID uuid.UUID) error
+
+	// RemoveMember removes a member; co-coaches can remove members, only the owner can remove co-coaches
+	RemoveMember(spaceID, requesterID, userID uuid.UUID) error
+
+	// ChangeRole turns a member into a co-coach or back; only the owner can change roles
+	ChangeRole(spaceID, requesterID, userID uuid.UUID, role string) (*entity.SpaceMember, error)
+
+	// Invite creates an invitation link granting role, valid for ttl and maxUses joins (0 for unlimited)
+	Invite(spaceID, requesterID uuid.UUID, role string, ttl time.Duration, maxUses int) (*entity.SpaceInvitation, error)
+
+	// GetInvitations returns the invitations of a space to its owner and co-coaches
+	GetInvitations(spaceID, requesterID uuid.UUID) ([]*entity.SpaceInvitation, error)
+
+	// RevokeInvitation disables an invitation link
+	RevokeInvitation(spaceID, requesterID, invitationID uuid.UUID) error
+}
+
+type spaceMemberServiceImpl struct {
+	repo           repository.SpaceMemberRepository
+	spaceRepo      repository.SpaceRepository
+	planRepo       repository.PlanRepository
+	enrollmentRepo repository.EnrollmentRepository
+	subRepo        repository.SubscriptionRepository
+	notifications  NotificationService
+}
+
+func NewSpaceMemberService(memberRepo repository.SpaceMemberRepository, spaceRepo repository.SpaceRepository, planRepo repository.PlanRepository, enrollmentRepo repository.EnrollmentRepository, subscriptionRepo repository.SubscriptionRepository, notifications NotificationService) SpaceMemberService {
+	return &spaceMemberServiceImpl{
+		repo:           memberRepo,
+		spaceRepo:      spaceRepo,
+		planRepo:       planRepo,
+		enrollmentRepo: enrollmentRepo,
+		subRepo:        subscriptionRepo,
+		notifications:  notifications,
+	}
+}
+
+// requireRole returns the membership of the user, failing unless it has one of the given roles
+func (s *spaceMemberServiceImpl) requireRole(spaceID, userID uuid.UUID, roles ...string) (*entity.SpaceMember, error) {
+	member, err := s.repo.Get(spaceID, userID)
+	if err != nil {
+		return nil, newError(ErrForbidden, "user is not a member of space with ID %s", spaceID)
+	}
+	if len(roles) == 0 {
+		return member, nil
+	}
+	for _, role := range roles {
+		if member.Role == role {
+			return member, nil
+		}
+	}
+	return nil, newError(ErrForbidden, "user is not allowed to manage the members of this space")
+}
+
+// GetMembers implements SpaceMemberService.
+func (s *spaceMemberServiceImpl) GetMembers(spaceID, requesterID uuid.UUID) ([]*entity.SpaceMember, error) {
+	if _, err := s.requireRole(spaceID, requesterID); err != nil {
+		return nil, err
+	}
+
+	members, err := s.repo.GetBySpaceID(spaceID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get members of space with ID %s: %w", spaceID, err)
+	}
+
+	return members, nil
+}
+
+// Join implements SpaceMemberService.
+func (s *spaceMemberServiceImpl) Join(spaceID, userID uuid.UUID, token string) (*entity.SpaceMember, error) {
+	if member, err := s.repo.Get(spaceID, userID); err == nil {
+		return member, nil
+	}
+
+	space, err := s.spaceRepo.GetdByID(spaceID)
+	if err != nil {
+		return nil, fmt.Errorf("could not find space with ID %s: %w", spaceID, err)
+	}
+	if !space.Active {
+		return nil, newError(ErrConflict, "space with ID %s is not active", spaceID)
+	}
+
+	if token != "" {
+		member, err := s.repo.RedeemInvitation(spaceID, token, userID)
+		if err != nil {
+			return nil, fmt.Errorf("could not join space with ID %s: %w", spaceID, err)
+		}
+		log.Printf("User %s joined space %s by invitation", userID, spaceID)
+		s.notifyJoined(space, userID)
+		return member, nil
+	}
+
+	allowed, err := s.hasAccess(space, userID)
+	if err != nil {
+		return nil, err
+	}
+	if !allowed {
+		return nil, newError(ErrForbidden, "space with ID %s requires a membership, a subscription or an invitation", spaceID)
+	}
+
+	member := &entity.SpaceMember{SpaceID: spaceID, UserID: userID, Role: entity.SpaceRoleMember}
+	if err := s.repo.Add(member); err != nil {
+		return nil, fmt.Errorf("failed to join space with ID %s: %w", spaceID, err)
+	}
+
+	log.Printf("User %s joined space %s", userID, spaceID)
+	s.notifyJoined(space, userID)
+	return member, nil
+}
+
+// notifyJoined tells the owner and co-coaches of a space that a user joined it
+func (s *spaceMemberServiceImpl) notifyJoined(space *entity.Space, userID uuid.UUID) {
+	members, err := s.repo.GetBySpaceID(space.ID)
+	if err != nil {
+		log.Printf("Error loading members of space %s to notify: %v", space.ID, err)
+		return
+	}
+
+	managers := []uuid.UUID{}
+	for _, member := range members {
+		if member.UserID != userID && (member.Role == entity.SpaceRoleOwner || member.Role == entity.SpaceRoleCoCoach) {
+			managers = append(managers, member.UserID)
+		}
+	}
+
+	s.notifications.Notify(entity.Notification{
+		Category: entity.NotificationCategorySpace,
+		Type:     entity.NotificationSpaceMemberJoin,
+		Title:    "A new member joined " + space.Name,
+		Link:     "/spaces/" + space.ID.String() + "/members",
+	}, managers...)
+}
+
+// hasAccess reports whether the user may join a space without an invitation
+func (s *spaceMemberServiceImpl) hasAccess(space *entity.Space, userID uuid.UUID) (bool, error) {
+	plans, err := s.planRepo.GetBySpaceID(space.ID)
+	if err != nil {
+		return false, fmt.Errorf("failed to get plans of space with ID %s: %w", space.ID, err)
+	}
+
+	paid := space.MembershipPrice > 0
+	for _, plan := range plans {
+		if plan.Active {
+			paid = true
+		}
+	}
+	if !paid {
+		return true, nil
+	}
+
+	enrollments, err := s.enrollmentRepo.GetByUserID(userID)
+	if err != nil {
+		return false, fmt.Errorf("failed to get enrollments of user with ID %s: %w", userID, err)
+	}
+	for _, enrollment := range enrollments {
+		if enrollment.ItemType == OrderItemSpaceMembership && enrollment.ItemID == space.ID {
+			return true, nil
+		}
+	}
+
+	subscriptions, err := s.subRepo.GetByUserID(userID)
+	if err != nil {
+		return false, fmt.Errorf("failed to get subscriptions of user with ID %s: %w", userID, err)
+	}
+	for _, subscription := range subscriptions {
+		if subscription.SpaceID == space.ID && subscriptionGrantsAccess(subscription) {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+// Leave implements SpaceMemberService.
+func (s *spaceMemberServiceImpl) Leave(spaceID, userID uuid.UUID) error {
+	member, err := s.requireRole(spaceID, userID)
+	if err != nil {
+		return err
+	}
+	if member.Role == entity.SpaceRoleOwner {
+		return newError(ErrConflict, "the owner cannot leave their own space")
+	}
+
+	if err := s.repo.Remove(spaceID, userID); err != nil {
+		return fmt.Errorf("failed to leave space with ID %s: %w", spaceID, err)
+	}
+
+	return nil
+}
+
+// RemoveMember implements SpaceMemberService.
+func (s *spaceMemberServiceImpl) RemoveMember(spaceID, requesterID, userID uuid.UUID) error {
+	requester, err := s.requireRole(spaceID, requesterID, entity.SpaceRoleOwner, entity.SpaceRoleCoCoach)
+	if err != nil {
+		return err
+	}
+
+	member, err := s.repo.Get(spaceID, userID)
+	if err != nil {
+		return newError(ErrNotFound, "user with ID %s is not a member of space with ID %s", userID, spaceID)
+	}
+
+	switch {
+	case member.Role == entity.SpaceRoleOwner:
+		return newError(ErrConflict, "the owner cannot be removed from their space")
+	case member.Role == entity.SpaceRoleCoCoach && requester.Role != entity.SpaceRoleOwner:
+		return newError(ErrForbidden, "only the owner can remove a co-coach")
+	}
+
+	if err := s.repo.Remove(spaceID, userID); err != nil {
+		return fmt.Errorf("failed to remove user with ID %s from space with ID %s: %w", userID, spaceID, err)
+	}
+
+	log.Printf("User %s removed %s from space %s", requesterID, userID, spaceID)
+	return nil
+}
+
+// ChangeRole implements SpaceMemberService.
+func (s *spaceMemberServiceImpl) ChangeRole(spaceID, requesterID, userID uuid.UUID, role string) (*entity.SpaceMember, error) {
+	if role != entity.SpaceRoleCoCoach && role != entity.SpaceRoleMember {
+		return nil, newError(ErrValidation, "invalid role %q, expected co_coach or member", role)
+	}
+
+	if _, err := s.requireRole(spaceID, requesterID, entity.SpaceRoleOwner); err != nil {
+		return nil, err
+	}
+
+	member, err := s.repo.Get(spaceID, userID)
+	if err != nil {
+		return nil, newError(ErrNotFound, "user with ID %s is not a member of space with ID %s", userID, spaceID)
+	}
+	if member.Role == entity.SpaceRoleOwner {
+		return nil, newError(ErrConflict, "the role of the owner cannot be changed")
+	}
+
+	if err := s.repo.UpdateRole(spaceID, userID, role); err != nil {
+		return nil, fmt.Errorf("failed to change role of user with ID %s: %w", userID, err)
+	}
+	member.Role = role
+
+	return member, nil
+}
+
+// Invite implements SpaceMemberService.
+func (s *spaceMemberServiceImpl) Invite(spaceID, requesterID uuid.UUID, role string, ttl time.Duration, maxUses int) (*entity.SpaceInvitation, error) {
+	if role == "" {
+		role = entity.SpaceRoleMember
+	}
+	if role != entity.SpaceRoleCoCoach && role != entity.SpaceRoleMember {
+		return nil, newError(ErrValidation, "invalid role %q, expected co_coach or member", role)
+	}
+	if maxUses < 0 {
+		return nil, newError(ErrValidation, "max uses cannot be negative")
+	}
+	if ttl <= 0 {
+		ttl = defaultInvitationTTL
+	}
+
+	requester, err := s.requireRole(spaceID, requesterID, entity.SpaceRoleOwner, entity.SpaceRoleCoCoach)
+	if err != nil {
+		return nil, err
+	}
+	if role == entity.SpaceRoleCoCoach && requester.Role != entity.SpaceRoleOwner {
+		return nil, newError(ErrForbidden, "only the owner can invite co-coaches")
+	}
+
+	neoInvitation, err := uuid.NewV4()
+	if err != nil {
+		return nil, err
+	}
+
+	token, err := utils.GenerateToken(24)
+	if err != nil {
+		return nil, errors.New("failed to generate invitation token")
+	}
+
+	invitation := &entity.SpaceInvitation{
+		ID:        neoInvitation,
+		SpaceID:   spaceID,
+		Token:     token,
+		Role:      role,
+		MaxUses:   maxUses,
+		CreatedBy: requesterID,
+		ExpiresAt: time.Now().Add(ttl),
+	}
+
+	if err := s.repo.CreateInvitation(invitation); err != nil {
+		return nil, fmt.Errorf("failed to create invitation: %w", err)
+	}
+
+	return invitation, nil
+}
+
+// GetInvitations implements SpaceMemberService.
+func (s *spaceMemberServiceImpl) GetInvitations(spaceID, requesterID uuid.UUID) ([]*entity.SpaceInvitation, error) {
+	if _, err := s.requireRole(spaceID, requesterID, entity.SpaceRoleOwner, entity.SpaceRoleCoCoach); err != nil {
+		return nil, err
+	}
+
+	invitations, err := s.repo.GetInvitationsBySpaceID(spaceID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get invitations of space with ID %s: %w", spaceID, err)
+	}
+
+	return invitations, nil
+}
+
+// RevokeInvitation implements SpaceMemberService.
+func (s *spaceMemberServiceImpl) RevokeInvitation(spaceID, requesterID, invitationID uuid.UUID) error {
+	if _, err := s.requireRole(spaceID, requesterID, entity.SpaceRoleOwner, entity.SpaceRoleCoCoach); err != nil {
+		return err
+	}
+
+	if err := s.repo.RevokeInvitation(spaceID, invitationID); err != nil {
+		return fmt.Errorf("failed to revoke invitation with ID %s: %w", invitationID, err)
+	}
+
+	return nil
+}
+
+// subscriptionGrantsAccess reports whether a subscription still entitles its user to the space
+func subscriptionGrantsAccess(subscription *entity.Subscription) bool {
+	switch subscription.Status {
+	case SubscriptionStatusTrialing, SubscriptionStatusActive, SubscriptionStatusPastDue:
+		return true
+	}
+	return false
+}
+
+var (
+	// ErrMembersOnly is returned when a non-member asks for content reserved to the members of a space
+	ErrMembersOnly = newError(ErrForbidden, "this content is only available to members of its space")
+
+	// ErrSpaceContentForbidden is returned when a user who does not run a space adds or edits its content
+	ErrSpaceContentForbidden = newError(ErrForbidden, "only the owner and co-coaches can manage the content of this space")
+
+	// ErrNotSpaceOwner is returned when a user other than the owner changes what a space charges or deletes it
+	ErrNotSpaceOwner = newError(ErrForbidden, "only the owner can do this to the space")
+
+	// ErrNotInstructor is returned when a user other than the instructor manages a course outside any space
+	ErrNotInstructor = newError(ErrForbidden, "only the instructor can manage this course")
+
+	// ErrNotAuthor is returned when a user edits a post or comment written by someone else
+	ErrNotAuthor = newError(ErrForbidden, "only the author can edit this content")
+)
+
+// requireSpaceManager fails unless the user is the owner or a co-coach of the space, when there is one
+func requireSpaceManager(memberRepo repository.SpaceMemberRepository, spaceID *uuid.UUID, userID uuid.UUID) error {
+	if spaceID == nil {
+		return nil
+	}
+	member, err := memberRepo.Get(*spaceID, userID)
+	if err != nil || (member.Role != entity.SpaceRoleOwner && member.Role != entity.SpaceRoleCoCoach) {
+		return ErrSpaceContentForbidden
+	}
+	return nil
+}
+
+// requireSpaceOwner fails unless the user is the owner of the space
+func requireSpaceOwner(memberRepo repository.SpaceMemberRepository, spaceID, userID uuid.UUID) error {
+	member, err := memberRepo.Get(spaceID, userID)
+	if err != nil || member.Role != entity.SpaceRoleOwner {
+		return ErrNotSpaceOwner
+	}
+	return nil
+}
+
+// requireCourseManager fails unless the user runs the space of the course, or is the instructor of a
+// course outside any space
+func requireCourseManager(memberRepo repository.SpaceMemberRepository, course *entity.Course, userID uuid.UUID) error {
+	if course.SpaceID == nil {
+		if course.InstructorID != userID {
+			return ErrNotInstructor
+		}
+		return nil
+	}
+	return requireSpaceManager(memberRepo, course.SpaceID, userID)
+}
+
+// spaceVisibility decides which space content a user can see, looking each membership up once
+type spaceVisibility struct {
+	memberRepo repository.SpaceMemberRepository
+	userID     uuid.UUID
+	members    map[uuid.UUID]bool
+}
+
+func newSpaceVisibility(memberRepo repository.SpaceMemberRepository, userID uuid.UUID) *spaceVisibility {
+	return &spaceVisibility{memberRepo: memberRepo, userID: userID, members: map[uuid.UUID]bool{}}
+}
+
+// canSee reports whether the user can see content of a space; member-only content needs a membership
+func (v *spaceVisibility) canSee(spaceID *uuid.UUID, membersOnly bool) bool {
+	if spaceID == nil || !membersOnly {
+		return true
+	}
+	member, known := v.members[*spaceID]
+	if !known {
+		_, err := v.memberRepo.Get(*spaceID, v.userID)
+		member = err == nil
+		v.members[*spaceID] = member
+	}
+	return member
+}
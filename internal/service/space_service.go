@@ -3,6 +3,7 @@ package service
 import (
 	"dalabio/internal/entity"
 	"dalabio/internal/repository"
+	"dalabio/pkg/validation"
 	"fmt"
 	"log"
 
@@ -12,13 +13,13 @@ import (
 type SpaceService interface {
 
 	// CreateSpace creates a new space
-	CreateSpace(Name, Description string, CoachID uuid.UUID, MemberCount uuid.UUID, SessionCount uuid.UUID, ourseCount uuid.UUID, Active bool) (*entity.Space, error)
+	CreateSpace(Name, Description string, CoachID uuid.UUID, Active bool, MembershipPrice float64, Currency string) (*entity.Space, error)
 
-	// UpdateSpace updates an existing space
-	UpdateSpace(space *entity.Space) error
+	// UpdateSpace updates an existing space for its owner or a co-coach; only the owner can change its membership price
+	UpdateSpace(space *entity.Space, requesterID uuid.UUID) error
 
-	// DeleteSpace deletes a space by its ID
-	DeleteSpace(spaceID uuid.UUID) error
+	// DeleteSpace deletes a space by its ID, for its owner
+	DeleteSpace(spaceID, requesterID uuid.UUID) error
 
 	// GetSpaceByID retrieves a space by its ID
 	GetSpaceByID(spaceID uuid.UUID) (*entity.Space, error)
@@ -29,8 +30,9 @@ type SpaceService interface {
 // SpaceServiceImpl struct implementing CourseService
 
 type spaceServiceImpl struct {
-	repo      repository.SpaceRepository
-	tokenRepo repository.TokenRepository
+	repo       repository.SpaceRepository
+	memberRepo repository.SpaceMemberRepository
+	tokenRepo  repository.TokenRepository
 }
 
 // GetAll implements SpaceService.
@@ -38,7 +40,7 @@ func (s *spaceServiceImpl) GetAllSpaces() ([]*entity.Space, error) {
 
 	spaces, err := s.repo.GetAll()
 	if err != nil {
-		return nil, fmt.Errorf("failed to get all spaces: %v", err)
+		return nil, fmt.Errorf("failed to get all spaces: %w", err)
 	}
 	return spaces, nil
 
@@ -46,15 +48,16 @@ func (s *spaceServiceImpl) GetAllSpaces() ([]*entity.Space, error) {
 
 // NewSpaceService creates a new instance of SpaceService
 
-func NewSpaceService(spaceRepo repository.SpaceRepository, tokenRepo repository.TokenRepository) SpaceService {
+func NewSpaceService(spaceRepo repository.SpaceRepository, memberRepo repository.SpaceMemberRepository, tokenRepo repository.TokenRepository) SpaceService {
 	return &spaceServiceImpl{
-		repo:      spaceRepo,
-		tokenRepo: tokenRepo,
+		repo:       spaceRepo,
+		memberRepo: memberRepo,
+		tokenRepo:  tokenRepo,
 	}
 }
 
 // CreateSpace implements SpaceService.
-func (s *spaceServiceImpl) CreateSpace(Name, Description string, CoachID uuid.UUID, MemberCount uuid.UUID, SessionCount uuid.UUID, ourseCount uuid.UUID, Active bool) (*entity.Space, error) {
+func (s *spaceServiceImpl) CreateSpace(Name, Description string, CoachID uuid.UUID, Active bool, MembershipPrice float64, Currency string) (*entity.Space, error) {
 	// Generate uuid for new space
 	neoSpace, err := uuid.NewV4()
 	if err != nil {
@@ -62,36 +65,59 @@ func (s *spaceServiceImpl) CreateSpace(Name, Description string, CoachID uuid.UU
 	}
 	// Create a new space instance
 	newSpace := &entity.Space{
-		ID:           neoSpace,
-		Name:         Name,
-		Description:  Description,
-		CoachID:      CoachID,
-		MemberCount:  MemberCount,
-		SessionCount: SessionCount,
-		CourseCount:  ourseCount,
-		Active:       Active,
+		ID:              neoSpace,
+		Name:            Name,
+		Description:     Description,
+		CoachID:         CoachID,
+		Active:          Active,
+		MembershipPrice: MembershipPrice,
+		Currency:        Currency,
+	}
+
+	if err := validateSpace(newSpace); err != nil {
+		return nil, err
 	}
 
 	log.Printf("Creatinf Space: %+v", newSpace)
 
 	err = s.repo.Create(newSpace)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create course: %v", err)
+		return nil, fmt.Errorf("failed to create course: %w", err)
 	}
 
+	// The coach owns the space they create
+	owner := &entity.SpaceMember{SpaceID: newSpace.ID, UserID: CoachID, Role: entity.SpaceRoleOwner}
+	if err := s.memberRepo.Add(owner); err != nil {
+		return nil, fmt.Errorf("failed to add owner to space: %w", err)
+	}
+	newSpace.MemberCount = 1
+
 	return newSpace, nil
 
 }
 
+// validateSpace checks the fields of a space set by its coach
+func validateSpace(space *entity.Space) error {
+	v := validation.New()
+	v.Length("name", space.Name, 1, 100)
+	v.MaxLength("description", space.Description, 5000)
+	v.NotNegative("membership_price", space.MembershipPrice)
+	validatePriceCurrency(v, space.MembershipPrice, space.Currency)
+	return v.Err()
+}
+
 // DeleteSpace implements SpaceService.
-func (s *spaceServiceImpl) DeleteSpace(spaceID uuid.UUID) error {
+func (s *spaceServiceImpl) DeleteSpace(spaceID, requesterID uuid.UUID) error {
 
 	_, err := s.repo.GetdByID(spaceID)
 	if err != nil {
-		return fmt.Errorf("could not find space with ID %s", spaceID)
+		return fmt.Errorf("could not find space with ID %s: %w", spaceID, err)
+	}
+	if err := requireSpaceOwner(s.memberRepo, spaceID, requesterID); err != nil {
+		return err
 	}
 	if err := s.repo.Delete(spaceID); err != nil {
-		return fmt.Errorf("failed to delete space with ID %s: %v", spaceID, err)
+		return fmt.Errorf("failed to delete space with ID %s: %w", spaceID, err)
 	}
 
 	log.Printf("Successfully deleted space with ID %s", spaceID)
@@ -104,21 +130,37 @@ func (s *spaceServiceImpl) GetSpaceByID(spaceID uuid.UUID) (*entity.Space, error
 
 	space, err := s.repo.GetdByID(spaceID)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get space with ID %s: %v", spaceID, err)
+		return nil, fmt.Errorf("failed to get space with ID %s: %w", spaceID, err)
 	}
 	return space, nil
 
 }
 
 // UpdateSpace implements SpaceService.
-func (s *spaceServiceImpl) UpdateSpace(space *entity.Space) error {
-	_, err := s.repo.GetdByID(space.ID)
+func (s *spaceServiceImpl) UpdateSpace(space *entity.Space, requesterID uuid.UUID) error {
+	existing, err := s.repo.GetdByID(space.ID)
 	if err != nil {
-		return fmt.Errorf("could not find space with ID %s", space.ID)
+		return fmt.Errorf("could not find space with ID %s: %w", space.ID, err)
+	}
+
+	if err := requireSpaceManager(s.memberRepo, &space.ID, requesterID); err != nil {
+		return err
+	}
+	if space.MembershipPrice != existing.MembershipPrice || space.Currency != existing.Currency {
+		if err := requireSpaceOwner(s.memberRepo, space.ID, requesterID); err != nil {
+			return err
+		}
+	}
+
+	// Ownership is not transferred by editing the space
+	space.CoachID = existing.CoachID
+
+	if err := validateSpace(space); err != nil {
+		return err
 	}
 
 	if err := s.repo.Update(space); err != nil {
-		return fmt.Errorf("failed to update space with ID %s: %v", space.ID, err)
+		return fmt.Errorf("failed to update space with ID %s: %w", space.ID, err)
 	}
 
 	return nil
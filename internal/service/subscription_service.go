@@ -0,0 +1,443 @@
+package service
+
+import (
+	"dalabio/internal/entity"
+	"dalabio/internal/framework/payment"
+	"dalabio/internal/repository"
+	"dalabio/pkg/validation"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/gofrs/uuid"
+)
+
+// Subscription statuses
+const (
+	SubscriptionStatusIncomplete = "incomplete" // Saved before its first charge and left so if that charge fails
+	SubscriptionStatusTrialing   = "trialing"
+	SubscriptionStatusActive     = "active"
+	SubscriptionStatusPastDue    = "past_due"
+	SubscriptionStatusCancelled  = "cancelled"
+	SubscriptionStatusUnpaid     = "unpaid"
+)
+
+// renewalLease is how long a subscription claimed by the renewal job is hidden from the other instances
+const renewalLease = 10 * time.Minute
+
+// dunningSchedule is the delay before each retry of a failed renewal charge.
+// Once every retry has failed the subscription becomes unpaid.
+var dunningSchedule = []time.Duration{
+	24 * time.Hour,
+	3 * 24 * time.Hour,
+	5 * 24 * time.Hour,
+}
+
+type SubscriptionService interface {
+
+	// CreatePlan adds a recurring plan to a space owned by the coach
+	CreatePlan(coachID, spaceID uuid.UUID, Name string, Price float64, Currency, Interval string, TrialDays int) (*entity.Plan, error)
+
+	// UpdatePlan updates a plan of a space owned by the coach; a nil active keeps the plan open or closed as it is
+	UpdatePlan(coachID uuid.UUID, plan *entity.Plan, active *bool) error
+
+	// GetPlansBySpace returns the plans of a space
+	GetPlansBySpace(spaceID uuid.UUID) ([]*entity.Plan, error)
+
+	// Subscribe starts a subscription, charging the first period unless the plan has a trial
+	Subscribe(userID, planID uuid.UUID, PaymentMethod, PaymentGateway string) (*entity.Subscription, error)
+
+	// CancelSubscription stops renewing a subscription once its current period ends
+	CancelSubscription(userID, subscriptionID uuid.UUID) (*entity.Subscription, error)
+
+	// GetSubscriptionsByUser returns the subscriptions of a user
+	GetSubscriptionsByUser(userID uuid.UUID) ([]*entity.Subscription, error)
+
+	// ProcessRenewals renews, retries or ends every subscription due at the given time
+	ProcessRenewals(now time.Time) error
+}
+
+type subscriptionServiceImpl struct {
+	planRepo       repository.PlanRepository
+	repo           repository.SubscriptionRepository
+	spaceRepo      repository.SpaceRepository
+	orderRepo      repository.OrderRepository
+	memberRepo     repository.SpaceMemberRepository
+	paymentService PaymentService
+	gateways       *payment.Gateways
+}
+
+func NewSubscriptionService(planRepo repository.PlanRepository, subscriptionRepo repository.SubscriptionRepository, spaceRepo repository.SpaceRepository, orderRepo repository.OrderRepository, memberRepo repository.SpaceMemberRepository, paymentService PaymentService, gateways *payment.Gateways) SubscriptionService {
+	return &subscriptionServiceImpl{
+		planRepo:       planRepo,
+		repo:           subscriptionRepo,
+		spaceRepo:      spaceRepo,
+		memberRepo:     memberRepo,
+		orderRepo:      orderRepo,
+		paymentService: paymentService,
+		gateways:       gateways,
+	}
+}
+
+// addInterval returns t advanced by one billing interval
+func addInterval(t time.Time, interval string) time.Time {
+	switch interval {
+	case "week":
+		return t.AddDate(0, 0, 7)
+	case "year":
+		return t.AddDate(1, 0, 0)
+	default:
+		return t.AddDate(0, 1, 0)
+	}
+}
+
+// validatePlan checks the billing fields of a plan
+func validatePlan(plan *entity.Plan) error {
+	v := validation.New()
+	v.Length("name", plan.Name, 1, 100)
+	v.Positive("price", plan.Price)
+	v.Currency("currency", plan.Currency)
+	v.OneOf("interval", plan.Interval, "week", "month", "year")
+	v.NotNegative("trial_days", float64(plan.TrialDays))
+	return v.Err()
+}
+
+// checkCoach ensures the user is the coach of the space
+func (s *subscriptionServiceImpl) checkCoach(coachID, spaceID uuid.UUID) error {
+	space, err := s.spaceRepo.GetdByID(spaceID)
+	if err != nil {
+		return fmt.Errorf("could not find space with ID %s: %w", spaceID, err)
+	}
+	if space.CoachID != coachID {
+		return newError(ErrForbidden, "only the coach of the space can manage its plans")
+	}
+	return nil
+}
+
+// CreatePlan implements SubscriptionService.
+func (s *subscriptionServiceImpl) CreatePlan(coachID, spaceID uuid.UUID, Name string, Price float64, Currency, Interval string, TrialDays int) (*entity.Plan, error) {
+	if err := s.checkCoach(coachID, spaceID); err != nil {
+		return nil, err
+	}
+
+	neoPlan, err := uuid.NewV4()
+	if err != nil {
+		return nil, err
+	}
+
+	plan := &entity.Plan{
+		ID:        neoPlan,
+		SpaceID:   spaceID,
+		Name:      Name,
+		Price:     Price,
+		Currency:  Currency,
+		Interval:  Interval,
+		TrialDays: TrialDays,
+		Active:    true,
+	}
+	if err := validatePlan(plan); err != nil {
+		return nil, err
+	}
+
+	if err := s.planRepo.Create(plan); err != nil {
+		return nil, fmt.Errorf("failed to create plan: %w", err)
+	}
+
+	return plan, nil
+}
+
+// UpdatePlan implements SubscriptionService.
+// Price changes apply to existing subscribers from their next renewal.
+func (s *subscriptionServiceImpl) UpdatePlan(coachID uuid.UUID, plan *entity.Plan, active *bool) error {
+	existing, err := s.planRepo.GetdByID(plan.ID)
+	if err != nil {
+		return fmt.Errorf("could not find plan with ID %s: %w", plan.ID, err)
+	}
+
+	if err := s.checkCoach(coachID, existing.SpaceID); err != nil {
+		return err
+	}
+	plan.SpaceID = existing.SpaceID
+	plan.Active = existing.Active
+	if active != nil {
+		plan.Active = *active
+	}
+	if err := validatePlan(plan); err != nil {
+		return err
+	}
+
+	if err := s.planRepo.Update(plan); err != nil {
+		return fmt.Errorf("failed to update plan with ID %s: %w", plan.ID, err)
+	}
+
+	return nil
+}
+
+// GetPlansBySpace implements SubscriptionService.
+func (s *subscriptionServiceImpl) GetPlansBySpace(spaceID uuid.UUID) ([]*entity.Plan, error) {
+	plans, err := s.planRepo.GetBySpaceID(spaceID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get plans of space with ID %s: %w", spaceID, err)
+	}
+
+	return plans, nil
+}
+
+// Subscribe implements SubscriptionService.
+func (s *subscriptionServiceImpl) Subscribe(userID, planID uuid.UUID, PaymentMethod, PaymentGateway string) (*entity.Subscription, error) {
+	plan, err := s.planRepo.GetdByID(planID)
+	if err != nil {
+		return nil, fmt.Errorf("could not find plan with ID %s: %w", planID, err)
+	}
+	if !plan.Active {
+		return nil, newError(ErrConflict, "plan with ID %s is no longer available", planID)
+	}
+
+	// A member pays for a space once, whatever the plan
+	subscriptions, err := s.repo.GetByUserID(userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get subscriptions of user with ID %s: %w", userID, err)
+	}
+	for _, existing := range subscriptions {
+		if existing.SpaceID == plan.SpaceID && isLive(existing) {
+			return nil, newError(ErrConflict, "user already has subscription %s to space with ID %s", existing.ID, plan.SpaceID)
+		}
+	}
+
+	neoSubscription, err := uuid.NewV4()
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	subscription := &entity.Subscription{
+		ID:                 neoSubscription,
+		UserID:             userID,
+		PlanID:             plan.ID,
+		SpaceID:            plan.SpaceID,
+		PaymentMethod:      PaymentMethod,
+		PaymentGateway:     PaymentGateway,
+		CurrentPeriodStart: now,
+	}
+
+	if plan.TrialDays > 0 {
+		// Nothing is charged until the trial ends and the renewal job picks the subscription up
+		trialEnd := now.AddDate(0, 0, plan.TrialDays)
+		subscription.Status = SubscriptionStatusTrialing
+		subscription.TrialEnd = &trialEnd
+		subscription.CurrentPeriodEnd = trialEnd
+
+		if err := s.repo.Create(subscription); err != nil {
+			return nil, fmt.Errorf("failed to create subscription: %w", err)
+		}
+	} else {
+		// The subscription is saved before the card is charged, so that a charge never goes unrecorded.
+		// It stays incomplete, and is never renewed, if the charge fails.
+		subscription.Status = SubscriptionStatusIncomplete
+		subscription.CurrentPeriodEnd = addInterval(now, plan.Interval)
+		if err := s.repo.Create(subscription); err != nil {
+			return nil, fmt.Errorf("failed to create subscription: %w", err)
+		}
+
+		if _, err := s.charge(subscription, plan, now, subscription.CurrentPeriodEnd); err != nil {
+			return nil, err
+		}
+		subscription.Status = SubscriptionStatusActive
+		if err := s.repo.Update(subscription); err != nil {
+			return nil, fmt.Errorf("failed to activate subscription with ID %s: %w", subscription.ID, err)
+		}
+	}
+
+	member := &entity.SpaceMember{SpaceID: subscription.SpaceID, UserID: userID, Role: entity.SpaceRoleMember}
+	if err := s.memberRepo.Add(member); err != nil {
+		log.Printf("Failed to add user %s to space %s: %v", userID, subscription.SpaceID, err)
+	}
+
+	log.Printf("User %s subscribed to plan %s", userID, planID)
+	return subscription, nil
+}
+
+// CancelSubscription implements SubscriptionService.
+func (s *subscriptionServiceImpl) CancelSubscription(userID, subscriptionID uuid.UUID) (*entity.Subscription, error) {
+	subscription, err := s.repo.GetdByID(subscriptionID)
+	if err != nil {
+		return nil, fmt.Errorf("could not find subscription with ID %s: %w", subscriptionID, err)
+	}
+
+	if subscription.UserID != userID {
+		return nil, newError(ErrNotFound, "could not find subscription with ID %s", subscriptionID)
+	}
+
+	switch subscription.Status {
+	case SubscriptionStatusCancelled, SubscriptionStatusUnpaid, SubscriptionStatusIncomplete:
+		return nil, newError(ErrConflict, "subscription with ID %s has already ended", subscriptionID)
+	case SubscriptionStatusPastDue:
+		// A subscription that failed to renew has no paid period left to honour
+		now := time.Now()
+		subscription.Status = SubscriptionStatusCancelled
+		subscription.CancelledAt = &now
+		subscription.NextRetryAt = nil
+		s.endMembership(subscription)
+	default:
+		subscription.CancelAtPeriodEnd = true
+	}
+
+	if err := s.repo.Update(subscription); err != nil {
+		return nil, fmt.Errorf("failed to update subscription with ID %s: %w", subscriptionID, err)
+	}
+
+	return subscription, nil
+}
+
+// GetSubscriptionsByUser implements SubscriptionService.
+func (s *subscriptionServiceImpl) GetSubscriptionsByUser(userID uuid.UUID) ([]*entity.Subscription, error) {
+	subscriptions, err := s.repo.GetByUserID(userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get subscriptions of user with ID %s: %w", userID, err)
+	}
+
+	return subscriptions, nil
+}
+
+// ProcessRenewals implements SubscriptionService.
+func (s *subscriptionServiceImpl) ProcessRenewals(now time.Time) error {
+	subscriptions, err := s.repo.ClaimDue(now, now.Add(renewalLease))
+	if err != nil {
+		return fmt.Errorf("failed to get due subscriptions: %w", err)
+	}
+
+	for _, subscription := range subscriptions {
+		if err := s.renew(subscription, now); err != nil {
+			log.Printf("Failed to renew subscription %s: %v", subscription.ID, err)
+		}
+	}
+
+	return nil
+}
+
+// renew ends, renews or retries a single due subscription
+func (s *subscriptionServiceImpl) renew(subscription *entity.Subscription, now time.Time) error {
+	if subscription.CancelAtPeriodEnd {
+		subscription.Status = SubscriptionStatusCancelled
+		subscription.CancelledAt = &now
+		s.endMembership(subscription)
+		return s.repo.Update(subscription)
+	}
+
+	plan, err := s.planRepo.GetdByID(subscription.PlanID)
+	if err != nil {
+		return fmt.Errorf("could not find plan with ID %s: %w", subscription.PlanID, err)
+	}
+
+	// A regular renewal continues from the end of the paid period; a retry after
+	// a failed charge starts a fresh period so the failed days are not billed
+	periodStart := subscription.CurrentPeriodEnd
+	if subscription.Status == SubscriptionStatusPastDue {
+		periodStart = now
+	}
+	periodEnd := addInterval(periodStart, plan.Interval)
+
+	if _, err := s.charge(subscription, plan, periodStart, periodEnd); err != nil {
+		if subscription.FailedAttempts >= len(dunningSchedule) {
+			subscription.Status = SubscriptionStatusUnpaid
+			subscription.NextRetryAt = nil
+			s.endMembership(subscription)
+			log.Printf("Subscription %s is unpaid after %d failed charges", subscription.ID, subscription.FailedAttempts+1)
+		} else {
+			nextRetry := now.Add(dunningSchedule[subscription.FailedAttempts])
+			subscription.Status = SubscriptionStatusPastDue
+			subscription.NextRetryAt = &nextRetry
+		}
+		subscription.FailedAttempts++
+
+		if updateErr := s.repo.Update(subscription); updateErr != nil {
+			return updateErr
+		}
+		return err
+	}
+
+	subscription.Status = SubscriptionStatusActive
+	subscription.CurrentPeriodStart = periodStart
+	subscription.CurrentPeriodEnd = periodEnd
+	subscription.FailedAttempts = 0
+	subscription.NextRetryAt = nil
+
+	return s.repo.Update(subscription)
+}
+
+// endMembership removes the subscriber from the space once the subscription has ended.
+// Owners and co-coaches keep their place, as they did not join through the subscription.
+func (s *subscriptionServiceImpl) endMembership(subscription *entity.Subscription) {
+	member, err := s.memberRepo.Get(subscription.SpaceID, subscription.UserID)
+	if err != nil || member.Role != entity.SpaceRoleMember {
+		return
+	}
+	if err := s.memberRepo.Remove(subscription.SpaceID, subscription.UserID); err != nil {
+		log.Printf("Failed to remove user %s from space %s: %v", subscription.UserID, subscription.SpaceID, err)
+	}
+}
+
+// charge bills one period of a plan through the gateway, recording the order and the resulting payment
+// whether the charge succeeds or not. It returns the transaction ID of the charge and an error only when
+// the gateway refused it: once the gateway accepted a charge the period is paid, and a failure to record
+// the payment is logged for reconciliation rather than returned, so that the charge is never retried.
+func (s *subscriptionServiceImpl) charge(subscription *entity.Subscription, plan *entity.Plan, periodStart, periodEnd time.Time) (string, error) {
+	neoOrder, err := uuid.NewV4()
+	if err != nil {
+		return "", err
+	}
+	neoLine, err := uuid.NewV4()
+	if err != nil {
+		return "", err
+	}
+
+	order := &entity.Order{
+		ID:       neoOrder,
+		UserID:   subscription.UserID,
+		Status:   OrderStatusPending,
+		Currency: plan.Currency,
+		Subtotal: plan.Price,
+		Total:    plan.Price,
+		Lines: []*entity.OrderLine{{
+			ID:          neoLine,
+			OrderID:     neoOrder,
+			ItemType:    OrderItemSpaceSubscription,
+			ItemID:      subscription.SpaceID,
+			Description: fmt.Sprintf("%s (%s - %s)", plan.Name, periodStart.Format("2006-01-02"), periodEnd.Format("2006-01-02")),
+			UnitPrice:   plan.Price,
+			Quantity:    1,
+			Amount:      plan.Price,
+		}},
+	}
+	if err := s.orderRepo.Create(order); err != nil {
+		return "", fmt.Errorf("failed to create order: %w", err)
+	}
+
+	notes := fmt.Sprintf("subscription %s", subscription.ID)
+
+	transactionID, chargeErr := s.gateways.Get(subscription.PaymentGateway).Charge(plan.Price, plan.Currency, order.ID.String())
+	if chargeErr != nil {
+		if err := s.orderRepo.UpdateStatus(order.ID, OrderStatusFailed); err != nil {
+			log.Printf("Failed to mark order %s as failed: %v", order.ID, err)
+		}
+		if _, err := s.paymentService.CreatePayment(subscription.UserID, order.ID, plan.Price, plan.Currency, subscription.PaymentMethod, "", PaymentStatusFailed, subscription.PaymentGateway, notes+": "+chargeErr.Error()); err != nil {
+			log.Printf("Failed to record failed payment of order %s: %v", order.ID, err)
+		}
+		return "", fmt.Errorf("failed to charge plan with ID %s: %w", plan.ID, chargeErr)
+	}
+
+	// Creating the payment as completed marks the order as paid through the payment listeners
+	if _, err := s.paymentService.CreatePayment(subscription.UserID, order.ID, plan.Price, plan.Currency, subscription.PaymentMethod, transactionID, PaymentStatusCompleted, subscription.PaymentGateway, notes); err != nil {
+		log.Printf("Charge %s of order %s succeeded but its payment could not be recorded: %v", transactionID, order.ID, err)
+	}
+	return transactionID, nil
+}
+
+// isLive reports whether a subscription still gives, or is about to give, access to its space
+func isLive(subscription *entity.Subscription) bool {
+	switch subscription.Status {
+	case SubscriptionStatusTrialing, SubscriptionStatusActive, SubscriptionStatusPastDue:
+		return true
+	}
+	return false
+}
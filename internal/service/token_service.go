@@ -0,0 +1,153 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"dalabio/internal/entity"
+	"dalabio/internal/repository"
+	"dalabio/pkg/config"
+	"dalabio/pkg/jwtauth"
+	"dalabio/pkg/utils"
+
+	"github.com/gofrs/uuid"
+)
+
+// TokenService issues the tokens of signed in users. Access tokens are either opaque tokens stored in the
+// database, or signed JWTs renewed with refresh tokens stored in the database.
+type TokenService interface {
+	// Issue creates the tokens of a user who signed in
+	Issue(userID uuid.UUID) (*IssuedTokens, error)
+
+	// Refresh exchanges a refresh token for a new access token and a new refresh token; the old one stops working
+	Refresh(refreshToken string) (*IssuedTokens, error)
+
+	// JWKS returns the public keys verifying access tokens, none when they are not signed
+	JWKS() jwtauth.JWKS
+}
+
+// IssuedTokens are the tokens given to a user when they sign in
+type IssuedTokens struct {
+	Access  *entity.Token
+	Refresh *entity.Token // Only with signed access tokens
+}
+
+// ErrInvalidRefreshToken is returned for a refresh token that is unknown, expired, revoked or already used
+var ErrInvalidRefreshToken = newError(ErrUnauthorized, "invalid or expired refresh token, please sign in again")
+
+// tokenServiceImpl is the implementation of TokenService.
+type tokenServiceImpl struct {
+	repo     repository.TokenRepository
+	roleRepo repository.RoleRepository
+	keys     *jwtauth.KeySet // nil when access tokens are opaque
+	config   *config.AuthConfig
+}
+
+// NewTokenService creates a new TokenService instance. Access tokens are signed with keys when they are given.
+func NewTokenService(tokenRepo repository.TokenRepository, roleRepo repository.RoleRepository, keys *jwtauth.KeySet, authConfig *config.AuthConfig) TokenService {
+	return &tokenServiceImpl{
+		repo:     tokenRepo,
+		roleRepo: roleRepo,
+		keys:     keys,
+		config:   authConfig,
+	}
+}
+
+// Issue implements TokenService.
+func (s *tokenServiceImpl) Issue(userID uuid.UUID) (*IssuedTokens, error) {
+	now := time.Now()
+	if s.keys == nil {
+		access, err := s.create(userID, s.config.AccessTokenTTL, now)
+		if err != nil {
+			return nil, err
+		}
+		return &IssuedTokens{Access: access}, nil
+	}
+
+	refresh, err := s.create(userID, s.config.RefreshTokenTTL, now)
+	if err != nil {
+		return nil, err
+	}
+	return s.sign(refresh, now)
+}
+
+// Refresh implements TokenService.
+func (s *tokenServiceImpl) Refresh(refreshToken string) (*IssuedTokens, error) {
+	if s.keys == nil {
+		return nil, newError(ErrNotFound, "refresh tokens are only issued with signed access tokens")
+	}
+
+	value, err := utils.GenerateToken(32)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	refresh, err := s.repo.Rotate(refreshToken, value, now.Add(s.config.RefreshTokenTTL), now)
+	if errors.Is(err, ErrNotFound) {
+		return nil, ErrInvalidRefreshToken
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to rotate refresh token: %w", err)
+	}
+	return s.sign(refresh, now)
+}
+
+// JWKS implements TokenService.
+func (s *tokenServiceImpl) JWKS() jwtauth.JWKS {
+	if s.keys == nil {
+		return jwtauth.JWKS{Keys: []jwtauth.JWK{}}
+	}
+	return s.keys.JWKS()
+}
+
+// create stores a new random token of a user
+func (s *tokenServiceImpl) create(userID uuid.UUID, ttl time.Duration, now time.Time) (*entity.Token, error) {
+	id, err := uuid.NewV4()
+	if err != nil {
+		return nil, errors.New("failed to generate token")
+	}
+	value := id.String()
+	if s.keys != nil {
+		// Refresh tokens live long, so they get more entropy than a UUID
+		if value, err = utils.GenerateToken(32); err != nil {
+			return nil, errors.New("failed to generate token")
+		}
+	}
+
+	token := &entity.Token{
+		ID:        id,
+		UserID:    userID,
+		Token:     value,
+		ExpiresAt: now.Add(ttl),
+	}
+	if err := s.repo.Create(token); err != nil {
+		return nil, errors.New("failed to save token")
+	}
+	return token, nil
+}
+
+// sign issues an access token carrying the current roles of the user of a refresh token
+func (s *tokenServiceImpl) sign(refresh *entity.Token, now time.Time) (*IssuedTokens, error) {
+	roles, err := s.roleRepo.GetRoleNamesByUserID(refresh.UserID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get roles of user with ID %s: %w", refresh.UserID, err)
+	}
+	if roles == nil {
+		roles = []string{}
+	}
+
+	signed, err := s.keys.Sign(refresh.UserID.String(), refresh.ID.String(), roles, now, s.config.AccessTokenTTL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to sign access token: %w", err)
+	}
+	access := &entity.Token{
+		ID:        refresh.ID,
+		UserID:    refresh.UserID,
+		Token:     signed,
+		ExpiresAt: now.Add(s.config.AccessTokenTTL),
+		CreatedAt: now,
+		Roles:     roles,
+	}
+	return &IssuedTokens{Access: access, Refresh: refresh}, nil
+}
@@ -0,0 +1,302 @@
+package service
+
+import (
+	"crypto/rand"
+	"encoding/base32"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"dalabio/internal/entity"
+	"dalabio/internal/repository"
+	"dalabio/pkg/config"
+	"dalabio/pkg/totp"
+	"dalabio/pkg/utils"
+
+	"github.com/gofrs/uuid"
+)
+
+// TwoFactorService manages the TOTP authenticators and recovery codes of users.
+type TwoFactorService interface {
+	// Status tells whether a user enabled two-factor authentication and whether one of their roles requires it
+	Status(userID uuid.UUID) (*TwoFactorStatus, error)
+
+	// Enroll generates a new secret for a user. It only protects their sign-ins once confirmed with a first code.
+	Enroll(userID uuid.UUID) (*TwoFactorEnrollment, error)
+
+	// Confirm enables the enrolled authenticator of a user with a first code and returns their recovery codes
+	Confirm(userID uuid.UUID, code string) ([]string, error)
+
+	// Verify checks a code of the enabled authenticator of a user, or one of their recovery codes, and uses it up
+	Verify(userID uuid.UUID, code string) error
+
+	// Disable removes the authenticator of a user who proves they hold it, unless one of their roles requires it
+	Disable(userID uuid.UUID, code string) error
+
+	// RegenerateRecoveryCodes replaces the recovery codes of a user who proves they hold their authenticator
+	RegenerateRecoveryCodes(userID uuid.UUID, code string) ([]string, error)
+
+	// SetRoleRequirement makes two-factor authentication mandatory or optional for the holders of a role
+	SetRoleRequirement(role string, required bool) error
+}
+
+// TwoFactorStatus describes the two-factor authentication of a user
+type TwoFactorStatus struct {
+	Enabled           bool       `json:"enabled"`
+	ConfirmedAt       *time.Time `json:"confirmed_at,omitempty"`
+	Required          bool       `json:"required"` // One of the roles of the user enforces it
+	RecoveryCodesLeft int        `json:"recovery_codes_left"`
+}
+
+// TwoFactorEnrollment is what a user adds to their authenticator app, by scanning the URI as a QR code or typing the secret
+type TwoFactorEnrollment struct {
+	Secret string `json:"secret"`
+	URI    string `json:"uri"`
+}
+
+const (
+	// recoveryCodeCount is the number of recovery codes given to a user
+	recoveryCodeCount = 10
+
+	// totpSkew is the number of periods either side of the current one whose codes are accepted, for clock drift
+	totpSkew = 1
+)
+
+var (
+	// ErrInvalidTwoFactorCode is returned for a code that is neither the current one nor an unused recovery code
+	ErrInvalidTwoFactorCode = newError(ErrUnauthorized, "invalid two-factor authentication code")
+
+	// ErrTwoFactorNotEnabled is returned when a user without a confirmed authenticator is asked for a code
+	ErrTwoFactorNotEnabled = newError(ErrForbidden, "two-factor authentication is not enabled")
+)
+
+// twoFactorServiceImpl is the implementation of TwoFactorService.
+type twoFactorServiceImpl struct {
+	repo     repository.TwoFactorRepository
+	userRepo repository.UserRepository
+	roleRepo repository.RoleRepository
+	key      []byte // Encrypts the secrets at rest
+	issuer   string
+}
+
+// NewTwoFactorService creates a new TwoFactorService instance.
+func NewTwoFactorService(twoFactorRepo repository.TwoFactorRepository, userRepo repository.UserRepository, roleRepo repository.RoleRepository, authConfig *config.AuthConfig) TwoFactorService {
+	return &twoFactorServiceImpl{
+		repo:     twoFactorRepo,
+		userRepo: userRepo,
+		roleRepo: roleRepo,
+		key:      utils.DeriveKey([]byte(authConfig.TokenSecret), "two_factor_secret"),
+		issuer:   authConfig.TwoFactorIssuer,
+	}
+}
+
+// Status implements TwoFactorService.
+func (s *twoFactorServiceImpl) Status(userID uuid.UUID) (*TwoFactorStatus, error) {
+	required, err := s.roleRepo.RequiresTwoFactor(userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check two-factor requirement of user with ID %s: %w", userID, err)
+	}
+	status := &TwoFactorStatus{Required: required}
+
+	twoFactor, err := s.repo.Get(userID)
+	if errors.Is(err, ErrNotFound) {
+		return status, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get two-factor authenticator of user with ID %s: %w", userID, err)
+	}
+	if twoFactor.ConfirmedAt == nil {
+		return status, nil
+	}
+
+	status.Enabled = true
+	status.ConfirmedAt = twoFactor.ConfirmedAt
+	if status.RecoveryCodesLeft, err = s.repo.CountRecoveryCodes(userID); err != nil {
+		return nil, fmt.Errorf("failed to count recovery codes of user with ID %s: %w", userID, err)
+	}
+	return status, nil
+}
+
+// Enroll implements TwoFactorService.
+func (s *twoFactorServiceImpl) Enroll(userID uuid.UUID) (*TwoFactorEnrollment, error) {
+	user, err := s.userRepo.FindByID(userID)
+	if err != nil {
+		return nil, fmt.Errorf("could not find user with ID %s: %w", userID, err)
+	}
+
+	// Replacing an enabled authenticator would let a stolen session turn the second factor off
+	if existing, err := s.repo.Get(userID); err == nil && existing.ConfirmedAt != nil {
+		return nil, newError(ErrConflict, "two-factor authentication is already enabled, disable it first")
+	} else if err != nil && !errors.Is(err, ErrNotFound) {
+		return nil, fmt.Errorf("failed to get two-factor authenticator of user with ID %s: %w", userID, err)
+	}
+
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		return nil, err
+	}
+	sealed, err := utils.Seal(s.key, secret)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encrypt two-factor secret: %w", err)
+	}
+	if err := s.repo.Save(&entity.TwoFactor{UserID: userID, Secret: sealed}); err != nil {
+		return nil, fmt.Errorf("failed to save two-factor authenticator of user with ID %s: %w", userID, err)
+	}
+
+	return &TwoFactorEnrollment{Secret: secret, URI: totp.URI(s.issuer, user.Email, secret)}, nil
+}
+
+// Confirm implements TwoFactorService.
+func (s *twoFactorServiceImpl) Confirm(userID uuid.UUID, code string) ([]string, error) {
+	twoFactor, err := s.repo.Get(userID)
+	if errors.Is(err, ErrNotFound) {
+		return nil, newError(ErrConflict, "no two-factor authenticator is being enrolled")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get two-factor authenticator of user with ID %s: %w", userID, err)
+	}
+	if twoFactor.ConfirmedAt != nil {
+		return nil, newError(ErrConflict, "two-factor authentication is already enabled")
+	}
+
+	now := time.Now()
+	step, ok, err := s.checkCode(twoFactor, code, now)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, ErrInvalidTwoFactorCode
+	}
+	if err := s.repo.Confirm(userID, step, now); err != nil {
+		return nil, fmt.Errorf("failed to confirm two-factor authenticator of user with ID %s: %w", userID, err)
+	}
+	log.Printf("Enabled two-factor authentication of user with ID %s", userID)
+
+	return s.newRecoveryCodes(userID)
+}
+
+// Verify implements TwoFactorService.
+func (s *twoFactorServiceImpl) Verify(userID uuid.UUID, code string) error {
+	twoFactor, err := s.repo.Get(userID)
+	if errors.Is(err, ErrNotFound) {
+		return ErrTwoFactorNotEnabled
+	}
+	if err != nil {
+		return fmt.Errorf("failed to get two-factor authenticator of user with ID %s: %w", userID, err)
+	}
+	if twoFactor.ConfirmedAt == nil {
+		return ErrTwoFactorNotEnabled
+	}
+
+	now := time.Now()
+	step, ok, err := s.checkCode(twoFactor, code, now)
+	if err != nil {
+		return err
+	}
+	if ok {
+		// The code stays valid for the rest of its period, so it is refused once used
+		if err := s.repo.UseStep(userID, step); errors.Is(err, ErrNotFound) {
+			return ErrInvalidTwoFactorCode
+		} else if err != nil {
+			return fmt.Errorf("failed to record two-factor code of user with ID %s: %w", userID, err)
+		}
+		return nil
+	}
+
+	err = s.repo.UseRecoveryCode(userID, utils.HashToken(normalizeRecoveryCode(code)), now)
+	if errors.Is(err, ErrNotFound) {
+		return ErrInvalidTwoFactorCode
+	}
+	if err != nil {
+		return fmt.Errorf("failed to use recovery code of user with ID %s: %w", userID, err)
+	}
+	log.Printf("User with ID %s signed in with a recovery code", userID)
+	return nil
+}
+
+// Disable implements TwoFactorService.
+func (s *twoFactorServiceImpl) Disable(userID uuid.UUID, code string) error {
+	required, err := s.roleRepo.RequiresTwoFactor(userID)
+	if err != nil {
+		return fmt.Errorf("failed to check two-factor requirement of user with ID %s: %w", userID, err)
+	}
+	if required {
+		return newError(ErrForbidden, "two-factor authentication is required for your role")
+	}
+
+	if err := s.Verify(userID, code); err != nil {
+		return err
+	}
+	if err := s.repo.Delete(userID); err != nil {
+		return fmt.Errorf("failed to disable two-factor authentication of user with ID %s: %w", userID, err)
+	}
+	log.Printf("Disabled two-factor authentication of user with ID %s", userID)
+	return nil
+}
+
+// RegenerateRecoveryCodes implements TwoFactorService.
+func (s *twoFactorServiceImpl) RegenerateRecoveryCodes(userID uuid.UUID, code string) ([]string, error) {
+	if err := s.Verify(userID, code); err != nil {
+		return nil, err
+	}
+	return s.newRecoveryCodes(userID)
+}
+
+// SetRoleRequirement implements TwoFactorService.
+func (s *twoFactorServiceImpl) SetRoleRequirement(role string, required bool) error {
+	if err := s.roleRepo.SetTwoFactorRequired(role, required); err != nil {
+		return fmt.Errorf("failed to update two-factor requirement of role %s: %w", role, err)
+	}
+	log.Printf("Two-factor authentication required for role %s: %t", role, required)
+	return nil
+}
+
+// checkCode checks a TOTP code against the secret of an authenticator and returns the period it belongs to
+func (s *twoFactorServiceImpl) checkCode(twoFactor *entity.TwoFactor, code string, now time.Time) (int64, bool, error) {
+	secret, err := utils.Open(s.key, twoFactor.Secret)
+	if err != nil {
+		return 0, false, fmt.Errorf("failed to decrypt two-factor secret of user with ID %s: %w", twoFactor.UserID, err)
+	}
+	step, ok := totp.Verify(secret, code, now, totpSkew)
+	return step, ok, nil
+}
+
+// newRecoveryCodes replaces the recovery codes of a user and returns them; only their hashes are kept
+func (s *twoFactorServiceImpl) newRecoveryCodes(userID uuid.UUID) ([]string, error) {
+	codes := make([]string, 0, recoveryCodeCount)
+	stored := make([]*entity.RecoveryCode, 0, recoveryCodeCount)
+	for i := 0; i < recoveryCodeCount; i++ {
+		code, err := generateRecoveryCode()
+		if err != nil {
+			return nil, err
+		}
+		id, err := uuid.NewV4()
+		if err != nil {
+			return nil, err
+		}
+		codes = append(codes, code)
+		stored = append(stored, &entity.RecoveryCode{ID: id, UserID: userID, CodeHash: utils.HashToken(normalizeRecoveryCode(code))})
+	}
+
+	if err := s.repo.ReplaceRecoveryCodes(userID, stored); err != nil {
+		return nil, fmt.Errorf("failed to save recovery codes of user with ID %s: %w", userID, err)
+	}
+	return codes, nil
+}
+
+// generateRecoveryCode returns a random code of ten base32 characters, shown as "xxxxx-xxxxx"
+func generateRecoveryCode() (string, error) {
+	random := make([]byte, 7)
+	if _, err := rand.Read(random); err != nil {
+		return "", err
+	}
+	code := strings.ToLower(base32.StdEncoding.EncodeToString(random))[:10]
+	return code[:5] + "-" + code[5:], nil
+}
+
+// normalizeRecoveryCode ignores the case, spaces and dashes users may type differently
+func normalizeRecoveryCode(code string) string {
+	return strings.ToLower(strings.NewReplacer("-", "", " ", "").Replace(code))
+}
@@ -4,39 +4,137 @@ import (
 	"errors"
 	"fmt"
 	"log"
+	"regexp"
+	"strings"
 	"time"
 
 	"dalabio/internal/entity"
 	"dalabio/internal/repository" // Import remains the same since the interface is still here
+	"dalabio/pkg/config"
 	"dalabio/pkg/utils"
+	"dalabio/pkg/validation"
 
 	"github.com/gofrs/uuid"
 )
 
 // UserService defines the interface for user-related operations.
 type UserService interface {
-	RegisterUser(username, email, password, first_name, last_name string) (*entity.User, error)
+	// RegisterUser creates an account; locale is the language of the emails sent to the user, the default one when empty
+	RegisterUser(username, email, password, first_name, last_name, locale string) (*entity.User, error)
+	// UpdateUser edits the profile of any user, for admins
 	UpdateUser(user *entity.User) error
+	// UpdateProfile edits the profile of the signed in user; changing their email takes their current password
+	UpdateProfile(user *entity.User, currentPassword string) error
 	// DeactivateUser(userID uint) error
 	// ActivateUser(userID uint) error
 	DeleteUser(userID uuid.UUID) error
 	GetUserByID(userID uuid.UUID) (*entity.User, error)
 	// GetUserByEmail(email string) (*entity.User, error)
 	ListUsers() ([]*entity.User, error)
-	AuthenticateUser(email, password string) (*entity.User, error)
+	// AuthenticateUser checks the credentials of a user signing in from an IP address and issues them their tokens.
+	// Users with two-factor authentication, or whose role requires it, get a login challenge instead.
+	// Failed attempts are throttled per account and per IP address.
+	AuthenticateUser(email, password, ip string) (*Authentication, error)
+
+	// VerifyTwoFactor completes a sign-in with the login challenge and a code of the authenticator or a recovery code.
+	// For a user setting up their authenticator, the code confirms it and the recovery codes are returned.
+	VerifyTwoFactor(challenge, code, ip string) (*Authentication, error)
+
+	// StartSession signs in a user authenticated by other means, such as an identity provider,
+	// with the same second step as AuthenticateUser
+	StartSession(user *entity.User) (*Authentication, error)
+
+	// SetupTwoFactor enrolls an authenticator for a user whose role requires one, during their sign-in
+	SetupTwoFactor(challenge string) (*TwoFactorEnrollment, error)
+
+	// UnlockUser lifts the lockout of an account locked after too many failed sign-ins
+	UnlockUser(userID uuid.UUID) error
+
+	// VerifyEmail consumes an email verification token and marks the email of its user as verified
+	VerifyEmail(token string) (*entity.User, error)
+
+	// ResendVerification emails a new verification link to the unverified account using an email.
+	// Unknown and already verified emails are silently ignored, so that the answer does not tell which accounts exist.
+	ResendVerification(email string) error
+
+	// ForgotPassword emails a password reset link to the account using an email.
+	// Unknown emails are silently ignored, so that the answer does not tell which accounts exist.
+	ForgotPassword(email string) error
+
+	// ResetPassword consumes a password reset token, sets the new password and signs the user out everywhere
+	ResetPassword(token, newPassword string) error
+
+	// ChangePassword replaces the password of a user who knows the current one and signs them out everywhere
+	ChangePassword(userID uuid.UUID, currentPassword, newPassword string) error
 }
 
+// Limits on the verification and password reset emails, so that the endpoints cannot be used to flood a mailbox
+const (
+	oneTimeTokenInterval   = time.Minute
+	maxOneTimeTokensPerDay = 5
+)
+
+const (
+	// loginFailureWindow is how long a failed sign-in counts towards the lockout
+	loginFailureWindow = time.Hour
+
+	// loginChallengeTTL is how long a user has to give their second factor after their password
+	loginChallengeTTL = 5 * time.Minute
+)
+
+// Authentication is the outcome of a sign-in: the tokens, or a challenge while the second factor is pending
+type Authentication struct {
+	User          *entity.User
+	Tokens        *IssuedTokens
+	Challenge     *LoginChallenge
+	RecoveryCodes []string // Given once, when the authenticator was set up during the sign-in
+}
+
+// LoginChallenge is given to a user who entered their password and must now give their second factor
+type LoginChallenge struct {
+	Token         string
+	ExpiresAt     time.Time
+	SetupRequired bool // Their role requires two-factor authentication but they have no authenticator yet
+}
+
+var (
+	// ErrEmailNotVerified is returned when a user authenticates before verifying their email and the policy requires it
+	ErrEmailNotVerified = newError(ErrForbidden, "email address is not verified")
+
+	// ErrInvalidVerificationToken is returned for a verification token that is forged, expired or already used
+	ErrInvalidVerificationToken = errors.New("invalid or expired verification token")
+
+	// ErrTooManyVerificationEmails is returned when verification emails are requested faster than allowed
+	ErrTooManyVerificationEmails = newError(ErrTooManyRequests, "too many verification emails requested, please try again later")
+
+	// ErrInvalidResetToken is returned for a password reset token that is forged, expired or already used
+	ErrInvalidResetToken = errors.New("invalid or expired password reset token")
+
+	// ErrWrongPassword is returned when the current password given to change it is wrong
+	ErrWrongPassword = newError(ErrForbidden, "current password is incorrect")
+
+	// ErrInvalidLoginChallenge is returned for a login challenge that is forged, expired or already used
+	ErrInvalidLoginChallenge = newError(ErrUnauthorized, "invalid or expired login challenge, please sign in again")
+)
+
 // userServiceImpl is the implementation of UserService.
 type userServiceImpl struct {
-	repo      repository.UserRepository
-	tokenRepo repository.TokenRepository
+	repo             repository.UserRepository
+	tokenRepo        repository.TokenRepository
+	apiKeyRepo       repository.APIKeyRepository
+	tokens           TokenService
+	oneTimeTokenRepo repository.OneTimeTokenRepository
+	throttleRepo     repository.LoginThrottleRepository
+	twoFactors       TwoFactorService
+	emails           EmailService
+	config           *config.AuthConfig
 }
 
 // ListUsers implements UserService.
 func (s *userServiceImpl) ListUsers() ([]*entity.User, error) {
 	users, err := s.repo.ListAll()
 	if err != nil {
-		return nil, fmt.Errorf("failed to get all users: %v", err)
+		return nil, fmt.Errorf("failed to get all users: %w", err)
 	}
 
 	return users, nil
@@ -49,7 +147,7 @@ func (s *userServiceImpl) GetUserByID(userID uuid.UUID) (*entity.User, error) {
 
 	user, err := s.repo.FindByID(userID)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get user with ID %s: %v", userID, err)
+		return nil, fmt.Errorf("failed to get user with ID %s: %w", userID, err)
 	}
 
 	return user, nil
@@ -57,17 +155,31 @@ func (s *userServiceImpl) GetUserByID(userID uuid.UUID) (*entity.User, error) {
 }
 
 // NewUserService creates a new UserService instance.
-func NewUserService(userRepo repository.UserRepository, tokenRepo repository.TokenRepository) UserService {
+func NewUserService(userRepo repository.UserRepository, tokenRepo repository.TokenRepository, apiKeyRepo repository.APIKeyRepository, tokenService TokenService, oneTimeTokenRepo repository.OneTimeTokenRepository, throttleRepo repository.LoginThrottleRepository, twoFactorService TwoFactorService, emailService EmailService, authConfig *config.AuthConfig) UserService {
 	return &userServiceImpl{
-		repo:      userRepo,
-		tokenRepo: tokenRepo,
+		repo:             userRepo,
+		tokenRepo:        tokenRepo,
+		apiKeyRepo:       apiKeyRepo,
+		tokens:           tokenService,
+		oneTimeTokenRepo: oneTimeTokenRepo,
+		throttleRepo:     throttleRepo,
+		twoFactors:       twoFactorService,
+		emails:           emailService,
+		config:           authConfig,
 	}
 }
 
-func (s *userServiceImpl) RegisterUser(username, email, password, first_name, last_name string) (*entity.User, error) {
+func (s *userServiceImpl) RegisterUser(username, email, password, first_name, last_name, locale string) (*entity.User, error) {
+	v := validation.New()
+	validateProfile(v, username, email, first_name, last_name, locale)
+	v.Password("password", password)
+	if err := v.Err(); err != nil {
+		return nil, err
+	}
+
 	// Check if the user already exists
 	if _, err := s.repo.FindByEmail(email); err == nil {
-		return nil, errors.New("user already exists")
+		return nil, newError(ErrConflict, "user already exists")
 	}
 
 	// Hash the password using bcrypt
@@ -84,6 +196,7 @@ func (s *userServiceImpl) RegisterUser(username, email, password, first_name, la
 		FirstName: first_name,
 		LastName:  last_name,
 		IsActive:  true,
+		Locale:    locale,
 	}
 
 	// Save the user to the repository
@@ -91,62 +204,546 @@ func (s *userServiceImpl) RegisterUser(username, email, password, first_name, la
 		return nil, err
 	}
 
+	// The account exists even if the email could not be sent, a new one can be requested
+	if err := s.sendVerification(user); err != nil {
+		log.Printf("Failed to send verification email to user with ID %s: %v", user.ID, err)
+	}
+
 	return user, nil
 }
 
+// validateProfile checks the fields of a user that can be edited
+func validateProfile(v *validation.Validator, username, email, firstName, lastName, locale string) {
+	v.Length("username", username, 3, 50)
+	v.Email("email", email)
+	v.MaxLength("first_name", firstName, 100)
+	v.MaxLength("last_name", lastName, 100)
+	v.Check(locale == "" || IsLocale(locale), "locale", validation.CodeInvalid, "must be a language tag such as en or fr-CA")
+}
+
+// localePattern matches language tags such as "fr" or "zh-Hant-TW"
+var localePattern = regexp.MustCompile(`^[A-Za-z]{2,3}([-_][A-Za-z0-9]{2,8})*$`)
+
+// IsLocale reports whether value is a language tag emails can be rendered in
+func IsLocale(value string) bool {
+	return len(value) <= 35 && localePattern.MatchString(value)
+}
+
+// sendVerification issues a verification token to a user and emails it
+func (s *userServiceImpl) sendVerification(user *entity.User) error {
+	token, err := s.issueToken(user.ID, entity.TokenPurposeEmailVerification, s.config.VerificationTTL)
+	if err != nil {
+		return err
+	}
+	return s.emails.SendEmailVerification(user, token, s.config.VerificationTTL)
+}
+
+// issueToken creates a one-time token and returns the signed value to email.
+// Only the hash is stored; the signature rejects forged tokens without a lookup.
+func (s *userServiceImpl) issueToken(userID uuid.UUID, purpose string, ttl time.Duration) (string, error) {
+	value, err := utils.GenerateToken(32)
+	if err != nil {
+		return "", err
+	}
+	neoToken, err := uuid.NewV4()
+	if err != nil {
+		return "", err
+	}
+
+	token := &entity.OneTimeToken{
+		ID:        neoToken,
+		UserID:    userID,
+		Purpose:   purpose,
+		TokenHash: utils.HashToken(value),
+		ExpiresAt: time.Now().Add(ttl),
+	}
+	if err := s.oneTimeTokenRepo.Create(token); err != nil {
+		return "", fmt.Errorf("failed to save %s token: %w", purpose, err)
+	}
+
+	return utils.SignToken([]byte(s.config.TokenSecret), purpose, value), nil
+}
+
+// consumeToken checks the signature of a one-time token and marks it as used
+func (s *userServiceImpl) consumeToken(purpose, token string, now time.Time) (*entity.OneTimeToken, bool) {
+	value, ok := utils.VerifySignedToken([]byte(s.config.TokenSecret), purpose, token)
+	if !ok {
+		return nil, false
+	}
+	consumed, err := s.oneTimeTokenRepo.Consume(purpose, utils.HashToken(value), now)
+	if err != nil {
+		return nil, false
+	}
+	return consumed, true
+}
+
+// tokenRateLimited tells whether a user was sent a one-time token too recently or too often today
+func (s *userServiceImpl) tokenRateLimited(userID uuid.UUID, purpose string, now time.Time) (bool, error) {
+	recent, err := s.oneTimeTokenRepo.CountCreatedSince(userID, purpose, now.Add(-oneTimeTokenInterval))
+	if err != nil {
+		return false, fmt.Errorf("failed to count %s tokens of user with ID %s: %w", purpose, userID, err)
+	}
+	today, err := s.oneTimeTokenRepo.CountCreatedSince(userID, purpose, now.Add(-24*time.Hour))
+	if err != nil {
+		return false, fmt.Errorf("failed to count %s tokens of user with ID %s: %w", purpose, userID, err)
+	}
+	return recent > 0 || today >= maxOneTimeTokensPerDay, nil
+}
+
+// VerifyEmail implements UserService.
+func (s *userServiceImpl) VerifyEmail(token string) (*entity.User, error) {
+	now := time.Now()
+	consumed, ok := s.consumeToken(entity.TokenPurposeEmailVerification, token, now)
+	if !ok {
+		return nil, ErrInvalidVerificationToken
+	}
+
+	if err := s.repo.MarkEmailVerified(consumed.UserID, now); err != nil {
+		return nil, fmt.Errorf("failed to verify email of user with ID %s: %w", consumed.UserID, err)
+	}
+
+	// The links of earlier emails are no longer needed
+	if err := s.oneTimeTokenRepo.InvalidateAll(consumed.UserID, entity.TokenPurposeEmailVerification, now); err != nil {
+		log.Printf("Failed to invalidate verification tokens of user with ID %s: %v", consumed.UserID, err)
+	}
+
+	return s.repo.FindByID(consumed.UserID)
+}
+
+// ResendVerification implements UserService.
+func (s *userServiceImpl) ResendVerification(email string) error {
+	user, err := s.repo.FindByEmail(email)
+	if err != nil || user.EmailVerifiedAt != nil {
+		return nil
+	}
+
+	limited, err := s.tokenRateLimited(user.ID, entity.TokenPurposeEmailVerification, time.Now())
+	if err != nil {
+		return err
+	}
+	if limited {
+		return ErrTooManyVerificationEmails
+	}
+
+	if err := s.sendVerification(user); err != nil {
+		return fmt.Errorf("failed to send verification email: %w", err)
+	}
+	return nil
+}
+
+// ForgotPassword implements UserService.
+func (s *userServiceImpl) ForgotPassword(email string) error {
+	user, err := s.repo.FindByEmail(email)
+	if err != nil {
+		return nil
+	}
+
+	// Answering 429 would tell that the account exists, so extra requests are only dropped
+	limited, err := s.tokenRateLimited(user.ID, entity.TokenPurposePasswordReset, time.Now())
+	if err != nil {
+		return err
+	}
+	if limited {
+		log.Printf("Dropping password reset request for user with ID %s: too many requests", user.ID)
+		return nil
+	}
+
+	token, err := s.issueToken(user.ID, entity.TokenPurposePasswordReset, s.config.PasswordResetTTL)
+	if err != nil {
+		return err
+	}
+	if err := s.emails.SendPasswordReset(user, token, s.config.PasswordResetTTL); err != nil {
+		return fmt.Errorf("failed to send password reset email: %w", err)
+	}
+	return nil
+}
+
+// ResetPassword implements UserService.
+func (s *userServiceImpl) ResetPassword(token, newPassword string) error {
+	v := validation.New()
+	v.Password("password", newPassword)
+	if err := v.Err(); err != nil {
+		return err
+	}
+
+	now := time.Now()
+	consumed, ok := s.consumeToken(entity.TokenPurposePasswordReset, token, now)
+	if !ok {
+		return ErrInvalidResetToken
+	}
+
+	if err := s.setPassword(consumed.UserID, newPassword); err != nil {
+		return err
+	}
+
+	// Other reset links sent meanwhile must not change the password again
+	if err := s.oneTimeTokenRepo.InvalidateAll(consumed.UserID, entity.TokenPurposePasswordReset, now); err != nil {
+		log.Printf("Failed to invalidate password reset tokens of user with ID %s: %v", consumed.UserID, err)
+	}
+
+	// Following the emailed link proves the user owns the address
+	if err := s.repo.MarkEmailVerified(consumed.UserID, now); err != nil {
+		log.Printf("Failed to verify email of user with ID %s: %v", consumed.UserID, err)
+	}
+	return nil
+}
+
+// ChangePassword implements UserService.
+func (s *userServiceImpl) ChangePassword(userID uuid.UUID, currentPassword, newPassword string) error {
+	user, err := s.repo.FindByID(userID)
+	if err != nil {
+		return fmt.Errorf("could not find user with ID %s: %w", userID, err)
+	}
+
+	if !utils.CheckPasswordHash(currentPassword, user.Password) {
+		return ErrWrongPassword
+	}
+	v := validation.New()
+	v.Password("new_password", newPassword)
+	if err := v.Err(); err != nil {
+		return err
+	}
+
+	return s.setPassword(userID, newPassword)
+}
+
+// setPassword hashes and saves a new password, then revokes every token and API key issued with the old one
+func (s *userServiceImpl) setPassword(userID uuid.UUID, password string) error {
+	hash, err := utils.HashPassword(password)
+	if err != nil {
+		return err
+	}
+	if err := s.repo.UpdatePassword(userID, hash); err != nil {
+		return fmt.Errorf("failed to update password of user with ID %s: %w", userID, err)
+	}
+	if err := s.tokenRepo.DeleteByUserID(userID); err != nil {
+		return fmt.Errorf("failed to revoke tokens of user with ID %s: %w", userID, err)
+	}
+	if err := s.apiKeyRepo.DeleteByUserID(userID); err != nil {
+		return fmt.Errorf("failed to revoke API keys of user with ID %s: %w", userID, err)
+	}
+	return nil
+}
+
 // AuthenticateUser authenticates a user by email and password.
-func (s *userServiceImpl) AuthenticateUser(email, password string) (*entity.User, error) {
+func (s *userServiceImpl) AuthenticateUser(email, password, ip string) (*Authentication, error) {
+	now := time.Now()
+	// The account is throttled by the email it signs in with, so unknown emails are throttled like the others
+	account := strings.ToLower(strings.TrimSpace(email))
+	if err := s.checkThrottle(entity.LoginScopeIP, ip, now); err != nil {
+		return nil, err
+	}
+	if err := s.checkThrottle(entity.LoginScopeAccount, account, now); err != nil {
+		return nil, err
+	}
+
 	// Find user by email
 	user, err := s.repo.FindByEmail(email)
 	if err != nil {
-		return nil, errors.New("invalid email or password")
+		s.loginFailed(nil, account, ip, now)
+		return nil, newError(ErrUnauthorized, "invalid email or password")
 	}
 
 	// Check if the password is correct
 	if !utils.CheckPasswordHash(password, user.Password) {
-		return nil, errors.New("invalid email or password")
+		s.loginFailed(user, account, ip, now)
+		return nil, newError(ErrUnauthorized, "invalid email or password")
 	}
 
-	// Generate token (UUID in this case, but you can replace it with JWT or any other mechanism)
-	newToken, err := uuid.NewV4()
+	// Checked after the password so that the answer does not tell which emails have an account
+	if s.config.RequireVerifiedEmail && user.EmailVerifiedAt == nil {
+		return nil, ErrEmailNotVerified
+	}
+
+	return s.startSession(user, account, now)
+}
+
+// StartSession implements UserService.
+func (s *userServiceImpl) StartSession(user *entity.User) (*Authentication, error) {
+	return s.startSession(user, strings.ToLower(user.Email), time.Now())
+}
+
+// startSession asks an authenticated user for their second factor, or issues their tokens when they have none
+func (s *userServiceImpl) startSession(user *entity.User, account string, now time.Time) (*Authentication, error) {
+	// The failed sign-ins are only forgotten once the second factor is given too, so that knowing the password
+	// does not allow guessing codes indefinitely
+	status, err := s.twoFactors.Status(user.ID)
 	if err != nil {
-		return nil, errors.New("failed to generate token")
+		return nil, err
+	}
+	if status.Enabled || status.Required {
+		challenge, err := s.issueToken(user.ID, entity.TokenPurposeLoginChallenge, loginChallengeTTL)
+		if err != nil {
+			return nil, err
+		}
+		return &Authentication{User: user, Challenge: &LoginChallenge{
+			Token:         challenge,
+			ExpiresAt:     now.Add(loginChallengeTTL),
+			SetupRequired: !status.Enabled,
+		}}, nil
 	}
 
-	// Create token entity
-	token := &entity.Token{
-		ID:        newToken,                       // Token ID
-		UserID:    user.ID,                        // Associate token with the user's ID
-		Token:     newToken.String(),              // The actual token string
-		ExpiresAt: time.Now().Add(24 * time.Hour), // Set token expiration (1 day in this example)
+	tokens, err := s.completeLogin(user, account, now)
+	if err != nil {
+		return nil, err
 	}
+	return &Authentication{User: user, Tokens: tokens}, nil
+}
 
-	// Store the token in the database
-	if err := s.tokenRepo.Create(token); err != nil {
-		return nil, errors.New("failed to save token")
+// VerifyTwoFactor implements UserService.
+func (s *userServiceImpl) VerifyTwoFactor(challenge, code, ip string) (*Authentication, error) {
+	now := time.Now()
+	if err := s.checkThrottle(entity.LoginScopeIP, ip, now); err != nil {
+		return nil, err
+	}
+	pending, err := s.findChallenge(challenge, now)
+	if err != nil {
+		return nil, err
+	}
+	user, err := s.repo.FindByID(pending.UserID)
+	if err != nil {
+		return nil, fmt.Errorf("could not find user with ID %s: %w", pending.UserID, err)
+	}
+	account := strings.ToLower(user.Email)
+	if err := s.checkThrottle(entity.LoginScopeAccount, account, now); err != nil {
+		return nil, err
+	}
+
+	status, err := s.twoFactors.Status(user.ID)
+	if err != nil {
+		return nil, err
+	}
+	var recoveryCodes []string
+	if status.Enabled {
+		err = s.twoFactors.Verify(user.ID, code)
+	} else {
+		recoveryCodes, err = s.twoFactors.Confirm(user.ID, code)
+	}
+	if errors.Is(err, ErrInvalidTwoFactorCode) {
+		s.loginFailed(user, account, ip, now)
+		return nil, err
+	}
+	if err != nil {
+		return nil, err
 	}
 
-	// Attach the token to the user entity
+	// The challenge is only used up by a right code, so that a typo does not send the user back to their password
+	if _, err := s.oneTimeTokenRepo.Consume(entity.TokenPurposeLoginChallenge, pending.TokenHash, now); err != nil {
+		return nil, ErrInvalidLoginChallenge
+	}
 
-	// Return the user and the token
-	return user, nil
+	tokens, err := s.completeLogin(user, account, now)
+	if err != nil {
+		return nil, err
+	}
+	return &Authentication{User: user, Tokens: tokens, RecoveryCodes: recoveryCodes}, nil
+}
+
+// SetupTwoFactor implements UserService.
+func (s *userServiceImpl) SetupTwoFactor(challenge string) (*TwoFactorEnrollment, error) {
+	pending, err := s.findChallenge(challenge, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	return s.twoFactors.Enroll(pending.UserID)
+}
+
+// findChallenge checks the signature of a login challenge and returns it without using it
+func (s *userServiceImpl) findChallenge(challenge string, now time.Time) (*entity.OneTimeToken, error) {
+	value, ok := utils.VerifySignedToken([]byte(s.config.TokenSecret), entity.TokenPurposeLoginChallenge, challenge)
+	if !ok {
+		return nil, ErrInvalidLoginChallenge
+	}
+	pending, err := s.oneTimeTokenRepo.Find(entity.TokenPurposeLoginChallenge, utils.HashToken(value), now)
+	if errors.Is(err, ErrNotFound) {
+		return nil, ErrInvalidLoginChallenge
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to find login challenge: %w", err)
+	}
+	return pending, nil
+}
+
+// completeLogin forgets the failed sign-ins of an account, records the login and issues the tokens
+func (s *userServiceImpl) completeLogin(user *entity.User, account string, now time.Time) (*IssuedTokens, error) {
+	if err := s.throttleRepo.Reset(entity.LoginScopeAccount, account); err != nil {
+		log.Printf("Failed to reset failed sign-ins of user with ID %s: %v", user.ID, err)
+	}
+	if err := s.repo.UpdateLastLogin(user.ID, now); err != nil {
+		log.Printf("Failed to record last login of user with ID %s: %v", user.ID, err)
+	}
+	user.LastLogin = now
+
+	tokens, err := s.tokens.Issue(user.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to issue tokens to user with ID %s: %w", user.ID, err)
+	}
+	return tokens, nil
+}
+
+// checkThrottle fails while an account or IP address has to wait after failed sign-ins
+func (s *userServiceImpl) checkThrottle(scope, subject string, now time.Time) error {
+	throttle, err := s.throttleRepo.Get(scope, subject)
+	if errors.Is(err, ErrNotFound) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to check failed sign-ins: %w", err)
+	}
+
+	retryAt := throttle.LastFailedAt.Add(s.loginBackoff(throttle.Failures, s.maxLoginFailures(scope)))
+	if throttle.LockedUntil != nil && throttle.LockedUntil.After(retryAt) {
+		retryAt = *throttle.LockedUntil
+	}
+	if wait := retryAt.Sub(now); wait > 0 {
+		return newRetryError(wait, "too many failed sign-in attempts, please try again in %s", wait.Round(time.Second))
+	}
+	return nil
+}
+
+// loginFailed counts a failed sign-in against the account and the IP address, locking them once they reach
+// their threshold. The user, if the email has an account, is told their account was locked.
+func (s *userServiceImpl) loginFailed(user *entity.User, account, ip string, now time.Time) {
+	resetBefore := now.Add(-loginFailureWindow)
+	for _, subject := range []struct{ scope, value string }{{entity.LoginScopeAccount, account}, {entity.LoginScopeIP, ip}} {
+		throttle, err := s.throttleRepo.RecordFailure(subject.scope, subject.value, now, resetBefore)
+		if err != nil {
+			log.Printf("Failed to record failed sign-in of %s %s: %v", subject.scope, subject.value, err)
+			continue
+		}
+		if throttle.Failures < s.maxLoginFailures(subject.scope) {
+			continue
+		}
+
+		if err := s.throttleRepo.Lock(subject.scope, subject.value, now.Add(s.config.LockoutDuration)); err != nil {
+			log.Printf("Failed to lock %s %s: %v", subject.scope, subject.value, err)
+			continue
+		}
+		log.Printf("Locked %s %s after %d failed sign-ins", subject.scope, subject.value, throttle.Failures)
+		if subject.scope == entity.LoginScopeAccount && user != nil {
+			if err := s.emails.SendAccountLocked(user, throttle.Failures, s.config.LockoutDuration); err != nil {
+				log.Printf("Failed to tell user with ID %s their account was locked: %v", user.ID, err)
+			}
+		}
+	}
+}
+
+// maxLoginFailures is the number of failed sign-ins that locks a subject of the scope
+func (s *userServiceImpl) maxLoginFailures(scope string) int {
+	if scope == entity.LoginScopeIP {
+		return s.config.MaxIPLoginFailures
+	}
+	return s.config.MaxLoginFailures
+}
+
+// loginBackoff is how long a subject waits after its last failed sign-in. The first half of the failures allowed
+// before the lockout are free, then the delay doubles at each failure, up to the lockout duration.
+func (s *userServiceImpl) loginBackoff(failures, maxFailures int) time.Duration {
+	exponent := failures - maxFailures/2 - 1
+	if exponent < 0 {
+		return 0
+	}
+	if exponent > 30 {
+		return s.config.LockoutDuration
+	}
+	backoff := s.config.LoginBackoff << exponent
+	if backoff > s.config.LockoutDuration {
+		return s.config.LockoutDuration
+	}
+	return backoff
+}
+
+// UnlockUser implements UserService.
+func (s *userServiceImpl) UnlockUser(userID uuid.UUID) error {
+	user, err := s.repo.FindByID(userID)
+	if err != nil {
+		return fmt.Errorf("could not find user with ID %s: %w", userID, err)
+	}
+
+	if err := s.throttleRepo.Reset(entity.LoginScopeAccount, strings.ToLower(user.Email)); err != nil {
+		return fmt.Errorf("failed to unlock user with ID %s: %w", userID, err)
+	}
+	log.Printf("Unlocked user with ID %s", userID)
+	return nil
 }
 
 // update user
 
 func (s *userServiceImpl) UpdateUser(user *entity.User) error {
+	existing, err := s.validateUpdate(user)
+	if err != nil {
+		return err
+	}
+	return s.update(existing, user)
+}
+
+// UpdateProfile implements UserService.
+func (s *userServiceImpl) UpdateProfile(user *entity.User, currentPassword string) error {
+	existing, err := s.validateUpdate(user)
+	if err != nil {
+		return err
+	}
+
+	// Whoever controls the email can reset the password, so a stolen session must not be enough to change it
+	if !strings.EqualFold(existing.Email, user.Email) && !utils.CheckPasswordHash(currentPassword, existing.Password) {
+		return ErrWrongPassword
+	}
+	return s.update(existing, user)
+}
+
+// validateUpdate checks the edited profile of a user and returns the user as currently stored
+func (s *userServiceImpl) validateUpdate(user *entity.User) (*entity.User, error) {
+	v := validation.New()
+	validateProfile(v, user.Username, user.Email, user.FirstName, user.LastName, user.Locale)
+	if err := v.Err(); err != nil {
+		return nil, err
+	}
+
 	// Check if the user exists by their ID
-	_, err := s.repo.FindByID(user.ID)
+	existing, err := s.repo.FindByID(user.ID)
 	if err != nil {
 		// If the user does not exist, return the error
-		return fmt.Errorf("could not find user with ID %s", user.ID)
+		return nil, fmt.Errorf("could not find user with ID %s: %w", user.ID, err)
+	}
+	return existing, nil
+}
+
+// update saves the edited profile of a user over the stored one
+func (s *userServiceImpl) update(existing, user *entity.User) error {
+	// Passwords are only changed through ChangePassword and ResetPassword, which hash them
+	user.Password = existing.Password
+	user.IsActive = existing.IsActive
+
+	// Clients unaware of the locale keep the one the user chose
+	if user.Locale == "" {
+		user.Locale = existing.Locale
+	}
+
+	// A new email is unverified until its owner follows the link sent to it
+	emailChanged := !strings.EqualFold(existing.Email, user.Email)
+	user.EmailVerifiedAt = existing.EmailVerifiedAt
+	if emailChanged {
+		user.EmailVerifiedAt = nil
 	}
 
 	// Call the repository to update the user
 	if err := s.repo.Update(user); err != nil {
-		return fmt.Errorf("failed to update user with ID %s: %v", user.ID, err)
+		return fmt.Errorf("failed to update user with ID %s: %w", user.ID, err)
+	}
+	if !emailChanged {
+		return nil
 	}
 
+	// The links emailed to the old address must not verify the new one, nor reset the password anymore
+	now := time.Now()
+	for _, purpose := range []string{entity.TokenPurposeEmailVerification, entity.TokenPurposePasswordReset} {
+		if err := s.oneTimeTokenRepo.InvalidateAll(user.ID, purpose, now); err != nil {
+			return fmt.Errorf("failed to invalidate %s tokens of user with ID %s: %w", purpose, user.ID, err)
+		}
+	}
+	if err := s.sendVerification(user); err != nil {
+		log.Printf("Failed to send verification email to user with ID %s: %v", user.ID, err)
+	}
 	return nil
 }
 
@@ -157,13 +754,13 @@ func (s *userServiceImpl) DeleteUser(userID uuid.UUID) error {
 	if err != nil {
 		// If the user does not exist, return the error
 		log.Printf("Could not find user with ID %s: %v", userID, err)
-		return fmt.Errorf("could not find user with ID %s", userID)
+		return fmt.Errorf("could not find user with ID %s: %w", userID, err)
 	}
 
 	// Call the repository to delete the user
 	if err := s.repo.Delete(userID); err != nil {
 		log.Printf("Failed to delete user with ID %s: %v", userID, err)
-		return fmt.Errorf("failed to delete user with ID %s: %v", userID, err)
+		return fmt.Errorf("failed to delete user with ID %s: %w", userID, err)
 	}
 
 	log.Printf("Successfully deleted user with ID %s", userID)
@@ -3,6 +3,9 @@ package config
 import (
 	"fmt"
 	"os"
+	"strconv"
+	"strings"
+	"time"
 )
 
 // DBConfig holds the database configuration.
@@ -34,3 +37,249 @@ func (c *DBConfig) ConnectionString() string {
 		c.Host, c.Port, c.User, c.Password, c.DBName, c.SSLMode,
 	)
 }
+
+// InvoiceConfig holds the seller details and tax settings printed on invoices.
+type InvoiceConfig struct {
+	Prefix        string
+	SellerName    string
+	SellerAddress string
+	TaxRate       float64 // Percentage included in every price, 0 when prices are tax-free
+}
+
+// LoadInvoiceConfig loads the invoice configuration from environment variables.
+func LoadInvoiceConfig() *InvoiceConfig {
+	cfg := &InvoiceConfig{
+		Prefix:        os.Getenv("INVOICE_PREFIX"),
+		SellerName:    os.Getenv("INVOICE_SELLER_NAME"),
+		SellerAddress: os.Getenv("INVOICE_SELLER_ADDRESS"),
+	}
+	if cfg.Prefix == "" {
+		cfg.Prefix = "INV"
+	}
+	if rate, err := strconv.ParseFloat(os.Getenv("INVOICE_TAX_RATE"), 64); err == nil {
+		cfg.TaxRate = rate
+	}
+	return cfg
+}
+
+// PayoutConfig holds the revenue sharing and payout settings for coaches and instructors.
+type PayoutConfig struct {
+	PlatformFeePercent float64 // Share of each sale kept by the platform
+	HoldDays           int     // Days a sale stays on hold before it can be paid out, to absorb refunds
+	MinimumAmount      float64 // Smallest available balance paid out
+}
+
+// LoadPayoutConfig loads the payout configuration from environment variables.
+func LoadPayoutConfig() *PayoutConfig {
+	cfg := &PayoutConfig{
+		PlatformFeePercent: 20,
+		HoldDays:           14,
+		MinimumAmount:      50,
+	}
+	if fee, err := strconv.ParseFloat(os.Getenv("PAYOUT_PLATFORM_FEE_PERCENT"), 64); err == nil {
+		cfg.PlatformFeePercent = fee
+	}
+	if days, err := strconv.Atoi(os.Getenv("PAYOUT_HOLD_DAYS")); err == nil {
+		cfg.HoldDays = days
+	}
+	if minimum, err := strconv.ParseFloat(os.Getenv("PAYOUT_MINIMUM_AMOUNT"), 64); err == nil {
+		cfg.MinimumAmount = minimum
+	}
+	return cfg
+}
+
+// MailConfig holds the settings of outgoing emails.
+type MailConfig struct {
+	Driver        string // "smtp" to send through an SMTP server, "file" to write emails to Dir
+	Host          string
+	Port          string
+	Username      string
+	Password      string
+	From          string // Sender of every email, e.g. "Dalabio <no-reply@dalabio.com>"
+	Dir           string // Directory the file driver writes emails to
+	BaseURL       string // Public URL of the application, prefixed to the links in emails
+	DefaultLocale string // Language of emails to recipients whose language is unknown
+	WebhookSecret string // Shared with the mail provider to authenticate bounce reports
+}
+
+// LoadMailConfig loads the mail configuration from environment variables.
+func LoadMailConfig() *MailConfig {
+	cfg := &MailConfig{
+		Driver:        os.Getenv("MAIL_DRIVER"),
+		Host:          os.Getenv("MAIL_SMTP_HOST"),
+		Port:          os.Getenv("MAIL_SMTP_PORT"),
+		Username:      os.Getenv("MAIL_SMTP_USERNAME"),
+		Password:      os.Getenv("MAIL_SMTP_PASSWORD"),
+		From:          os.Getenv("MAIL_FROM"),
+		Dir:           os.Getenv("MAIL_DIR"),
+		BaseURL:       os.Getenv("APP_BASE_URL"),
+		DefaultLocale: os.Getenv("MAIL_DEFAULT_LOCALE"),
+		WebhookSecret: os.Getenv("MAIL_WEBHOOK_SECRET"),
+	}
+	if cfg.Driver == "" {
+		cfg.Driver = "file"
+	}
+	if cfg.Port == "" {
+		cfg.Port = "587"
+	}
+	if cfg.From == "" {
+		cfg.From = "Dalabio <no-reply@localhost>"
+	}
+	if cfg.Dir == "" {
+		cfg.Dir = "mail"
+	}
+	if cfg.BaseURL == "" {
+		cfg.BaseURL = "http://localhost:3000"
+	}
+	if cfg.DefaultLocale == "" {
+		cfg.DefaultLocale = "en"
+	}
+	return cfg
+}
+
+// AuthConfig holds the settings of user accounts and authentication.
+type AuthConfig struct {
+	TokenSecret          string        // Signs the tokens emailed to users; a random one is used when empty
+	RequireVerifiedEmail bool          // Refuse to authenticate users until they verified their email
+	VerificationTTL      time.Duration // How long an email verification link stays valid
+	PasswordResetTTL     time.Duration // How long a password reset link stays valid
+	MaxLoginFailures     int           // Failed sign-ins that lock an account
+	MaxIPLoginFailures   int           // Failed sign-ins that lock an IP address
+	LoginBackoff         time.Duration // First delay imposed between failed sign-ins, doubled at each failure
+	LockoutDuration      time.Duration // How long an account or IP address stays locked
+	TwoFactorIssuer      string        // Name authenticator apps show next to the codes
+	TokenMode            string        // TokenModeOpaque or TokenModeJWT
+	AccessTokenTTL       time.Duration // How long an access token stays valid
+	RefreshTokenTTL      time.Duration // How long a refresh token stays valid, in TokenModeJWT
+	JWTIssuer            string        // Issuer claim of signed access tokens
+	JWTKeyFiles          []string      // PEM private keys; the first signs, the others are retired keys still verified
+	JWTAlgorithm         string        // "EdDSA" or "RS256", for the key generated when no key file is set
+}
+
+// Kinds of access tokens
+const (
+	TokenModeOpaque = "opaque" // Random tokens looked up in the database on every request
+	TokenModeJWT    = "jwt"    // Short-lived signed tokens, renewed with refresh tokens kept in the database
+)
+
+// LoadAuthConfig loads the authentication configuration from environment variables.
+func LoadAuthConfig() *AuthConfig {
+	cfg := &AuthConfig{
+		TokenSecret:        os.Getenv("AUTH_TOKEN_SECRET"),
+		VerificationTTL:    48 * time.Hour,
+		PasswordResetTTL:   time.Hour,
+		MaxLoginFailures:   5,
+		MaxIPLoginFailures: 20,
+		LoginBackoff:       time.Second,
+		LockoutDuration:    15 * time.Minute,
+		TwoFactorIssuer:    "Dalabio",
+		TokenMode:          TokenModeOpaque,
+		AccessTokenTTL:     24 * time.Hour,
+		RefreshTokenTTL:    30 * 24 * time.Hour,
+		JWTIssuer:          "dalabio",
+		JWTAlgorithm:       "EdDSA",
+	}
+	if require, err := strconv.ParseBool(os.Getenv("AUTH_REQUIRE_VERIFIED_EMAIL")); err == nil {
+		cfg.RequireVerifiedEmail = require
+	}
+	if ttl, err := time.ParseDuration(os.Getenv("AUTH_VERIFICATION_TTL")); err == nil {
+		cfg.VerificationTTL = ttl
+	}
+	if ttl, err := time.ParseDuration(os.Getenv("AUTH_PASSWORD_RESET_TTL")); err == nil {
+		cfg.PasswordResetTTL = ttl
+	}
+	if max, err := strconv.Atoi(os.Getenv("AUTH_MAX_LOGIN_FAILURES")); err == nil && max > 0 {
+		cfg.MaxLoginFailures = max
+	}
+	if max, err := strconv.Atoi(os.Getenv("AUTH_MAX_IP_LOGIN_FAILURES")); err == nil && max > 0 {
+		cfg.MaxIPLoginFailures = max
+	}
+	if backoff, err := time.ParseDuration(os.Getenv("AUTH_LOGIN_BACKOFF")); err == nil {
+		cfg.LoginBackoff = backoff
+	}
+	if lockout, err := time.ParseDuration(os.Getenv("AUTH_LOCKOUT_DURATION")); err == nil && lockout > 0 {
+		cfg.LockoutDuration = lockout
+	}
+	if issuer := os.Getenv("AUTH_2FA_ISSUER"); issuer != "" {
+		cfg.TwoFactorIssuer = issuer
+	}
+	if mode := os.Getenv("AUTH_TOKEN_MODE"); mode == TokenModeJWT {
+		cfg.TokenMode = mode
+		// Signed tokens cannot be revoked, so they are kept short-lived
+		cfg.AccessTokenTTL = 15 * time.Minute
+	}
+	if ttl, err := time.ParseDuration(os.Getenv("AUTH_ACCESS_TOKEN_TTL")); err == nil && ttl > 0 {
+		cfg.AccessTokenTTL = ttl
+	}
+	if ttl, err := time.ParseDuration(os.Getenv("AUTH_REFRESH_TOKEN_TTL")); err == nil && ttl > 0 {
+		cfg.RefreshTokenTTL = ttl
+	}
+	if issuer := os.Getenv("AUTH_JWT_ISSUER"); issuer != "" {
+		cfg.JWTIssuer = issuer
+	}
+	for _, file := range strings.Split(os.Getenv("AUTH_JWT_KEY_FILES"), ",") {
+		if file = strings.TrimSpace(file); file != "" {
+			cfg.JWTKeyFiles = append(cfg.JWTKeyFiles, file)
+		}
+	}
+	if algorithm := os.Getenv("AUTH_JWT_ALGORITHM"); algorithm != "" {
+		cfg.JWTAlgorithm = algorithm
+	}
+	return cfg
+}
+
+// OIDCProviderConfig holds the settings of an OpenID Connect identity provider users can sign in with.
+type OIDCProviderConfig struct {
+	Name         string // Identifies the provider in the URLs, e.g. "google"
+	Issuer       string // Its discovery document is served under Issuer + "/.well-known/openid-configuration"
+	ClientID     string
+	ClientSecret string // Empty for public clients, which rely on PKCE alone
+	RedirectURL  string // Where the provider sends users back with the authorization code
+	Scopes       []string
+}
+
+// LoadOIDCProviders loads the identity providers listed in OIDC_PROVIDERS, each configured by the variables
+// prefixed with OIDC_ and its upper-cased name, e.g. OIDC_GOOGLE_ISSUER and OIDC_GOOGLE_CLIENT_ID.
+func LoadOIDCProviders() []OIDCProviderConfig {
+	var providers []OIDCProviderConfig
+	for _, name := range strings.Split(os.Getenv("OIDC_PROVIDERS"), ",") {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" {
+			continue
+		}
+
+		prefix := "OIDC_" + strings.ToUpper(strings.ReplaceAll(name, "-", "_")) + "_"
+		provider := OIDCProviderConfig{
+			Name:         name,
+			Issuer:       strings.TrimSuffix(os.Getenv(prefix+"ISSUER"), "/"),
+			ClientID:     os.Getenv(prefix + "CLIENT_ID"),
+			ClientSecret: os.Getenv(prefix + "CLIENT_SECRET"),
+			RedirectURL:  os.Getenv(prefix + "REDIRECT_URL"),
+			Scopes:       strings.Fields(os.Getenv(prefix + "SCOPES")),
+		}
+		if provider.RedirectURL == "" {
+			provider.RedirectURL = "http://localhost:8080/users/oidc/" + name + "/callback"
+		}
+		if len(provider.Scopes) == 0 {
+			provider.Scopes = []string{"openid", "email", "profile"}
+		}
+		providers = append(providers, provider)
+	}
+	return providers
+}
+
+// ServerConfig holds the settings of the HTTP server.
+type ServerConfig struct {
+	TrustedProxies []string // Addresses or CIDRs whose X-Forwarded-For header is believed; none by default
+}
+
+// LoadServerConfig loads the server configuration from environment variables.
+func LoadServerConfig() *ServerConfig {
+	cfg := &ServerConfig{}
+	for _, proxy := range strings.Split(os.Getenv("TRUSTED_PROXIES"), ",") {
+		if proxy = strings.TrimSpace(proxy); proxy != "" {
+			cfg.TrustedProxies = append(cfg.TrustedProxies, proxy)
+		}
+	}
+	return cfg
+}
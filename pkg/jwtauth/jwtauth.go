@@ -0,0 +1,231 @@
+package jwtauth
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+)
+
+// Algorithms of the keys that sign access tokens
+const (
+	AlgorithmRS256 = "RS256"
+	AlgorithmEdDSA = "EdDSA"
+)
+
+// rsaKeyBits is the size of generated RSA keys
+const rsaKeyBits = 2048
+
+// ErrInvalidToken is returned for a token that is malformed, forged, expired or signed by an unknown key
+var ErrInvalidToken = errors.New("invalid or expired access token")
+
+// Claims are carried by access tokens, so that requests are authenticated without a database lookup
+type Claims struct {
+	jwt.RegisteredClaims
+	Roles     []string `json:"roles"`
+	SessionID string   `json:"sid,omitempty"` // ID of the refresh token the access token was issued with
+}
+
+// Key is a private key signing access tokens, identified by the RFC 7638 thumbprint of its public key
+type Key struct {
+	ID        string
+	Algorithm string
+	private   crypto.Signer
+}
+
+// KeySet holds the key signing new access tokens, followed by the retired keys still accepted until the tokens
+// they signed expire. Rotating keys means putting a new key first and dropping the oldest one later.
+type KeySet struct {
+	keys   []*Key
+	issuer string
+}
+
+// NewKeySet creates a key set from its keys, the first of which signs the new tokens
+func NewKeySet(issuer string, keys ...*Key) (*KeySet, error) {
+	if len(keys) == 0 {
+		return nil, errors.New("a key set needs at least one key")
+	}
+	return &KeySet{keys: keys, issuer: issuer}, nil
+}
+
+// LoadKey reads a PEM encoded private key: an RSA key in PKCS #1 or PKCS #8, or an Ed25519 key in PKCS #8
+func LoadKey(path string) (*Key, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("%s is not a PEM file", path)
+	}
+
+	var private interface{}
+	if private, err = x509.ParsePKCS8PrivateKey(block.Bytes); err != nil {
+		if private, err = x509.ParsePKCS1PrivateKey(block.Bytes); err != nil {
+			return nil, fmt.Errorf("%s does not hold a PKCS #1 or PKCS #8 private key", path)
+		}
+	}
+	signer, ok := private.(crypto.Signer)
+	if !ok {
+		return nil, fmt.Errorf("%s holds an unsupported private key", path)
+	}
+	return newKey(signer)
+}
+
+// GenerateKey creates a random key for an algorithm
+func GenerateKey(algorithm string) (*Key, error) {
+	switch algorithm {
+	case AlgorithmRS256:
+		private, err := rsa.GenerateKey(rand.Reader, rsaKeyBits)
+		if err != nil {
+			return nil, err
+		}
+		return newKey(private)
+	case AlgorithmEdDSA:
+		_, private, err := ed25519.GenerateKey(rand.Reader)
+		if err != nil {
+			return nil, err
+		}
+		return newKey(private)
+	default:
+		return nil, fmt.Errorf("unsupported signing algorithm %q", algorithm)
+	}
+}
+
+func newKey(private crypto.Signer) (*Key, error) {
+	key := &Key{private: private}
+	switch private.(type) {
+	case *rsa.PrivateKey:
+		key.Algorithm = AlgorithmRS256
+	case ed25519.PrivateKey:
+		key.Algorithm = AlgorithmEdDSA
+	default:
+		return nil, fmt.Errorf("unsupported private key type %T", private)
+	}
+
+	// The thumbprint hashes the required members of the JWK in lexicographic order, without spaces
+	jwk := key.jwk()
+	var members interface{}
+	if jwk.Kty == "RSA" {
+		members = struct {
+			E   string `json:"e"`
+			Kty string `json:"kty"`
+			N   string `json:"n"`
+		}{jwk.E, jwk.Kty, jwk.N}
+	} else {
+		members = struct {
+			Crv string `json:"crv"`
+			Kty string `json:"kty"`
+			X   string `json:"x"`
+		}{jwk.Crv, jwk.Kty, jwk.X}
+	}
+	canonical, err := json.Marshal(members)
+	if err != nil {
+		return nil, err
+	}
+	sum := sha256.Sum256(canonical)
+	key.ID = base64.RawURLEncoding.EncodeToString(sum[:])
+	return key, nil
+}
+
+// JWK is a public key as published in a JWKS document (RFC 7517)
+type JWK struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid,omitempty"`
+	Use string `json:"use,omitempty"`
+	Alg string `json:"alg,omitempty"`
+	N   string `json:"n,omitempty"`   // RSA modulus
+	E   string `json:"e,omitempty"`   // RSA exponent
+	Crv string `json:"crv,omitempty"` // Curve of an OKP key
+	X   string `json:"x,omitempty"`   // OKP public key
+}
+
+// JWKS is the document listing the public keys that verify access tokens
+type JWKS struct {
+	Keys []JWK `json:"keys"`
+}
+
+func (k *Key) jwk() JWK {
+	jwk := JWK{Kid: k.ID, Use: "sig", Alg: k.Algorithm}
+	switch public := k.private.Public().(type) {
+	case *rsa.PublicKey:
+		jwk.Kty = "RSA"
+		jwk.N = base64.RawURLEncoding.EncodeToString(public.N.Bytes())
+		jwk.E = base64.RawURLEncoding.EncodeToString(big.NewInt(int64(public.E)).Bytes())
+	case ed25519.PublicKey:
+		jwk.Kty = "OKP"
+		jwk.Crv = "Ed25519"
+		jwk.X = base64.RawURLEncoding.EncodeToString(public)
+	}
+	return jwk
+}
+
+func (k *Key) method() jwt.SigningMethod {
+	if k.Algorithm == AlgorithmEdDSA {
+		return jwt.SigningMethodEdDSA
+	}
+	return jwt.SigningMethodRS256
+}
+
+// JWKS returns the public keys of the set, so that other services can verify the tokens too
+func (s *KeySet) JWKS() JWKS {
+	jwks := JWKS{Keys: make([]JWK, 0, len(s.keys))}
+	for _, key := range s.keys {
+		jwks.Keys = append(jwks.Keys, key.jwk())
+	}
+	return jwks
+}
+
+// Sign issues an access token to a user with the current key
+func (s *KeySet) Sign(subject, sessionID string, roles []string, issuedAt time.Time, ttl time.Duration) (string, error) {
+	key := s.keys[0]
+	claims := Claims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			Issuer:    s.issuer,
+			Subject:   subject,
+			IssuedAt:  jwt.NewNumericDate(issuedAt),
+			NotBefore: jwt.NewNumericDate(issuedAt),
+			ExpiresAt: jwt.NewNumericDate(issuedAt.Add(ttl)),
+		},
+		Roles:     roles,
+		SessionID: sessionID,
+	}
+	token := jwt.NewWithClaims(key.method(), claims)
+	token.Header["kid"] = key.ID
+	return token.SignedString(key.private)
+}
+
+// Parse verifies the signature, issuer and lifetime of an access token and returns its claims
+func (s *KeySet) Parse(token string) (*Claims, error) {
+	var claims Claims
+	parsed, err := jwt.ParseWithClaims(token, &claims, func(token *jwt.Token) (interface{}, error) {
+		kid, _ := token.Header["kid"].(string)
+		for _, key := range s.keys {
+			// The algorithm is checked against the key, never taken from the token alone
+			if key.ID == kid && token.Method.Alg() == key.Algorithm {
+				return key.private.Public(), nil
+			}
+		}
+		return nil, fmt.Errorf("unknown signing key %q", kid)
+	}, jwt.WithValidMethods([]string{AlgorithmRS256, AlgorithmEdDSA}))
+	if err != nil || !parsed.Valid {
+		return nil, ErrInvalidToken
+	}
+	// Lifetimes are optional in JWTs, not in access tokens
+	if !claims.VerifyIssuer(s.issuer, true) || claims.ExpiresAt == nil || claims.IssuedAt == nil {
+		return nil, ErrInvalidToken
+	}
+	return &claims, nil
+}
@@ -0,0 +1,209 @@
+package jwtauth
+
+import (
+	"crypto/x509"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+)
+
+const testIssuer = "https://dalabio.test"
+
+func generateKey(t *testing.T, algorithm string) *Key {
+	t.Helper()
+	key, err := GenerateKey(algorithm)
+	if err != nil {
+		t.Fatalf("GenerateKey(%s): %v", algorithm, err)
+	}
+	return key
+}
+
+// signWith signs claims with any key and kid, the way a forger would
+func signWith(t *testing.T, method jwt.SigningMethod, kid string, private interface{}, claims jwt.Claims) string {
+	t.Helper()
+	token := jwt.NewWithClaims(method, claims)
+	if kid != "" {
+		token.Header["kid"] = kid
+	}
+	signed, err := token.SignedString(private)
+	if err != nil {
+		t.Fatalf("signing token: %v", err)
+	}
+	return signed
+}
+
+func validClaims(issuer string, now time.Time) Claims {
+	return Claims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			Issuer:    issuer,
+			Subject:   "user",
+			IssuedAt:  jwt.NewNumericDate(now),
+			NotBefore: jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(time.Hour)),
+		},
+		Roles: []string{"user"},
+	}
+}
+
+func TestParse(t *testing.T) {
+	current := generateKey(t, AlgorithmRS256)
+	retired := generateKey(t, AlgorithmEdDSA)
+	stranger := generateKey(t, AlgorithmRS256)
+	strangerEd := generateKey(t, AlgorithmEdDSA)
+	keys, err := NewKeySet(testIssuer, current, retired)
+	if err != nil {
+		t.Fatalf("NewKeySet: %v", err)
+	}
+	now := time.Now()
+
+	publicDER, err := x509.MarshalPKIXPublicKey(current.private.Public())
+	if err != nil {
+		t.Fatalf("encoding public key: %v", err)
+	}
+	noLifetime := validClaims(testIssuer, now)
+	noLifetime.ExpiresAt = nil
+
+	tests := []struct {
+		name    string
+		token   func() string
+		wantErr bool
+	}{
+		{
+			name: "signed by the current key",
+			token: func() string {
+				token, err := keys.Sign("user", "", []string{"user"}, now, time.Hour)
+				if err != nil {
+					t.Fatalf("Sign: %v", err)
+				}
+				return token
+			},
+		},
+		{
+			name: "signed by a retired key",
+			token: func() string {
+				return signWith(t, jwt.SigningMethodEdDSA, retired.ID, retired.private, validClaims(testIssuer, now))
+			},
+		},
+		{
+			name: "unknown kid",
+			token: func() string {
+				return signWith(t, jwt.SigningMethodRS256, stranger.ID, stranger.private, validClaims(testIssuer, now))
+			},
+			wantErr: true,
+		},
+		{
+			name: "missing kid",
+			token: func() string {
+				return signWith(t, jwt.SigningMethodRS256, "", current.private, validClaims(testIssuer, now))
+			},
+			wantErr: true,
+		},
+		{
+			name: "known kid signed by another key",
+			token: func() string {
+				return signWith(t, jwt.SigningMethodRS256, current.ID, stranger.private, validClaims(testIssuer, now))
+			},
+			wantErr: true,
+		},
+		{
+			name: "algorithm not matching the key of the kid",
+			token: func() string {
+				return signWith(t, jwt.SigningMethodEdDSA, current.ID, strangerEd.private, validClaims(testIssuer, now))
+			},
+			wantErr: true,
+		},
+		{
+			name: "HMAC keyed with the public key",
+			token: func() string {
+				return signWith(t, jwt.SigningMethodHS256, current.ID, publicDER, validClaims(testIssuer, now))
+			},
+			wantErr: true,
+		},
+		{
+			name: "alg none",
+			token: func() string {
+				return signWith(t, jwt.SigningMethodNone, current.ID, jwt.UnsafeAllowNoneSignatureType, validClaims(testIssuer, now))
+			},
+			wantErr: true,
+		},
+		{
+			name: "other issuer",
+			token: func() string {
+				return signWith(t, jwt.SigningMethodRS256, current.ID, current.private, validClaims("https://evil.test", now))
+			},
+			wantErr: true,
+		},
+		{
+			name: "expired",
+			token: func() string {
+				return signWith(t, jwt.SigningMethodRS256, current.ID, current.private, validClaims(testIssuer, now.Add(-2*time.Hour)))
+			},
+			wantErr: true,
+		},
+		{
+			name: "without expiry",
+			token: func() string {
+				return signWith(t, jwt.SigningMethodRS256, current.ID, current.private, noLifetime)
+			},
+			wantErr: true,
+		},
+		{
+			name:    "malformed",
+			token:   func() string { return "not.a.token" },
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			claims, err := keys.Parse(tt.token())
+			if tt.wantErr {
+				if err != ErrInvalidToken {
+					t.Errorf("Parse = %v, %v, want ErrInvalidToken", claims, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Parse: %v", err)
+			}
+			if claims.Subject != "user" || claims.Issuer != testIssuer {
+				t.Errorf("Parse returned subject %q and issuer %q", claims.Subject, claims.Issuer)
+			}
+		})
+	}
+}
+
+func TestJWKS(t *testing.T) {
+	rsaKey := generateKey(t, AlgorithmRS256)
+	edKey := generateKey(t, AlgorithmEdDSA)
+	keys, err := NewKeySet(testIssuer, rsaKey, edKey)
+	if err != nil {
+		t.Fatalf("NewKeySet: %v", err)
+	}
+
+	jwks := keys.JWKS()
+	tests := []struct {
+		key     *Key
+		kty     string
+		present func(JWK) bool
+	}{
+		{rsaKey, "RSA", func(jwk JWK) bool { return jwk.N != "" && jwk.E != "" }},
+		{edKey, "OKP", func(jwk JWK) bool { return jwk.Crv == "Ed25519" && jwk.X != "" }},
+	}
+	if len(jwks.Keys) != len(tests) {
+		t.Fatalf("JWKS has %d keys, want %d", len(jwks.Keys), len(tests))
+	}
+	for i, tt := range tests {
+		jwk := jwks.Keys[i]
+		if jwk.Kid != tt.key.ID || jwk.Alg != tt.key.Algorithm || jwk.Kty != tt.kty || !tt.present(jwk) {
+			t.Errorf("JWKS key %d = %+v, want a %s key with kid %s", i, jwk, tt.kty, tt.key.ID)
+		}
+	}
+}
+
+func TestGenerateKeyUnsupportedAlgorithm(t *testing.T) {
+	if _, err := GenerateKey("HS256"); err == nil {
+		t.Error("GenerateKey accepted HS256")
+	}
+}
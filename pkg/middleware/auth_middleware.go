@@ -1,44 +1,61 @@
 package middleware
 
 import (
+	"dalabio/internal/entity"
 	"dalabio/internal/repository"
+	"dalabio/internal/service"
 	"log"
 	"net/http"
 	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/gofrs/uuid"
 )
 
-// AuthMiddleware verifies the token and protects the routes by checking the token in the database.
+// tokenCheckKey holds, in the context of an authenticated request, the function telling whether its token still holds
+const tokenCheckKey = "tokenCheck"
+
+// sessionTouchInterval is how often the last use of a session is recorded, so that not every request writes
+const sessionTouchInterval = time.Minute
+
+// AuthMiddleware verifies the token and protects the routes by checking the token in the database,
+// or its signature when tokenRepo verifies signed access tokens. API keys are accepted in the X-API-Key header
+// or as bearer tokens, within their scopes.
 func AuthMiddleware(tokenRepo repository.TokenRepository) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		// Get the token from the Authorization header
-		authHeader := c.GetHeader("Authorization")
-		if authHeader == "" {
-			log.Println("Missing Authorization header")
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Authorization token required"})
-			c.Abort()
-			return
-		}
+		// Scripts may send their API key in its own header instead of the Authorization header
+		tokenString, fromAPIKeyHeader := c.GetHeader("X-API-Key"), true
+		if tokenString == "" {
+			fromAPIKeyHeader = false
 
-		// The token is usually in the format "Bearer <token>"
-		parts := strings.Split(authHeader, " ")
-		if len(parts) != 2 || parts[0] != "Bearer" {
-			log.Println("Invalid Authorization format")
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Authorization format must be Bearer <token>"})
-			c.Abort()
-			return
-		}
+			// Get the token from the Authorization header
+			authHeader := c.GetHeader("Authorization")
+			if authHeader == "" {
+				log.Println("Missing Authorization header")
+				c.Error(service.NewError(service.ErrUnauthorized, "authorization token required"))
+				c.Abort()
+				return
+			}
 
-		tokenString := parts[1]
+			// The token is usually in the format "Bearer <token>"
+			parts := strings.Split(authHeader, " ")
+			if len(parts) != 2 || parts[0] != "Bearer" {
+				log.Println("Invalid Authorization format")
+				c.Error(service.NewError(service.ErrUnauthorized, "authorization format must be Bearer <token>"))
+				c.Abort()
+				return
+			}
+
+			tokenString = parts[1]
+		}
 
 		// Fetch the token from the database
 		token, err := tokenRepo.FindByToken(tokenString)
 		if err != nil {
 			// Log the token lookup failure
 			log.Printf("Token lookup failed: %v", err)
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid or expired token"})
+			c.Error(service.NewError(service.ErrUnauthorized, "invalid or expired token"))
 			c.Abort()
 			return
 		}
@@ -46,15 +63,102 @@ func AuthMiddleware(tokenRepo repository.TokenRepository) gin.HandlerFunc {
 		// Check if the token has expired
 		if token.ExpiresAt.Before(time.Now()) {
 			log.Printf("Token expired at: %v", token.ExpiresAt)
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Token expired"})
+			c.Error(service.NewError(service.ErrUnauthorized, "token expired"))
 			c.Abort()
 			return
 		}
 
+		// API keys are limited to their scopes
+		if token.Scopes == nil && fromAPIKeyHeader {
+			c.Error(service.NewError(service.ErrUnauthorized, "invalid API key"))
+			c.Abort()
+			return
+		}
+		if token.Scopes != nil {
+			if !allowedByScopes(c.Request.Method, token.Scopes) {
+				c.Error(service.NewError(service.ErrForbidden, "this API key is not allowed to perform this action"))
+				c.Abort()
+				return
+			}
+			c.Set("apiKeyID", token.ID)
+			c.Set("scopes", token.Scopes)
+		}
+
+		// Sessions of signed in users remember the device they are used from
+		if token.Scopes == nil && token.ID != uuid.Nil {
+			recordDevice(c, tokenRepo, token.ID)
+			c.Set("sessionID", token.ID)
+		}
+
 		// If the token is valid, set the user ID in the request context
 		c.Set("userID", token.UserID)
 
+		// Requests outliving the token, such as event streams, look it up again with TokenValid
+		c.Set(tokenCheckKey, func() bool {
+			current, err := tokenRepo.FindByToken(tokenString)
+			return err == nil && current.ExpiresAt.After(time.Now())
+		})
+
+		// Signed tokens carry the roles, which spares RequireRole a lookup
+		if token.Roles != nil {
+			c.Set("roles", token.Roles)
+		}
+
 		// Proceed to the next handler in the chain
 		c.Next()
 	}
 }
+
+// RejectAPIKeys refuses the requests authenticated with an API key, for the routes that only a signed in user
+// may use, such as managing the API keys themselves. It runs after AuthMiddleware.
+func RejectAPIKeys() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if _, ok := c.Get("apiKeyID"); ok {
+			c.Error(service.NewError(service.ErrForbidden, "API keys cannot be used for this action"))
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
+
+// TokenValid tells whether the token that authenticated the request is still neither revoked nor expired,
+// for long-lived requests such as event streams. A request AuthMiddleware did not authenticate has no valid token.
+func TokenValid(c *gin.Context) bool {
+	check, ok := c.Value(tokenCheckKey).(func() bool)
+	return ok && check()
+}
+
+// recordDevice saves the user agent and IP a session is used from, at most once per sessionTouchInterval
+// unless they change. A failure is logged and does not fail the request.
+func recordDevice(c *gin.Context, tokenRepo repository.TokenRepository, sessionID uuid.UUID) {
+	userAgent := c.Request.UserAgent()
+	if len(userAgent) > maxUserAgentLength {
+		userAgent = strings.ToValidUTF8(userAgent[:maxUserAgentLength], "")
+	}
+
+	now := time.Now()
+	if err := tokenRepo.Touch(sessionID, userAgent, c.ClientIP(), deviceLabel(userAgent), now, now.Add(-sessionTouchInterval)); err != nil {
+		log.Printf("Failed to record device of session %v: %v", sessionID, err)
+	}
+}
+
+// allowedByScopes tells whether the scopes of an API key allow a request: reads need the read or write scope,
+// other requests the write scope
+func allowedByScopes(method string, scopes []string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		return hasScope(scopes, entity.APIKeyScopeRead) || hasScope(scopes, entity.APIKeyScopeWrite)
+	default:
+		return hasScope(scopes, entity.APIKeyScopeWrite)
+	}
+}
+
+func hasScope(scopes []string, scope string) bool {
+	for _, granted := range scopes {
+		if granted == scope {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,58 @@
+package middleware
+
+import "strings"
+
+// maxUserAgentLength is the longest user agent recorded for a session; longer ones are cut
+const maxUserAgentLength = 512
+
+// Browsers and operating systems recognised in user agents, in the order they are tried: several browsers
+// also name the ones they derive from, and Android names Linux
+var (
+	userAgentBrowsers = []struct{ token, name string }{
+		{"Edg", "Edge"},
+		{"OPR/", "Opera"},
+		{"Opera", "Opera"},
+		{"Firefox/", "Firefox"},
+		{"FxiOS/", "Firefox"},
+		{"CriOS/", "Chrome"},
+		{"Chrome/", "Chrome"},
+		{"Safari/", "Safari"},
+		{"curl/", "curl"},
+		{"PostmanRuntime/", "Postman"},
+	}
+	userAgentSystems = []struct{ token, name string }{
+		{"Windows", "Windows"},
+		{"Android", "Android"},
+		{"iPhone", "iOS"},
+		{"iPad", "iPadOS"},
+		{"Mac OS X", "macOS"},
+		{"CrOS", "ChromeOS"},
+		{"Linux", "Linux"},
+	}
+)
+
+// deviceLabel names the browser and operating system of a user agent, such as "Firefox on Linux", so that
+// users recognise their sessions
+func deviceLabel(userAgent string) string {
+	browser := matchUserAgent(userAgent, userAgentBrowsers)
+	system := matchUserAgent(userAgent, userAgentSystems)
+	switch {
+	case browser != "" && system != "":
+		return browser + " on " + system
+	case browser != "":
+		return browser
+	case system != "":
+		return system
+	default:
+		return "Unknown device"
+	}
+}
+
+func matchUserAgent(userAgent string, candidates []struct{ token, name string }) string {
+	for _, candidate := range candidates {
+		if strings.Contains(userAgent, candidate.token) {
+			return candidate.name
+		}
+	}
+	return ""
+}
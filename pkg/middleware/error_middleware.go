@@ -0,0 +1,94 @@
+package middleware
+
+import (
+	"dalabio/internal/service"
+	"dalabio/pkg/validation"
+	"encoding/json"
+	"errors"
+	"log"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// problemContentType is the media type of RFC 7807 error responses
+const problemContentType = "application/problem+json"
+
+// problem is an error response as described by RFC 7807
+type problem struct {
+	Type     string                  `json:"type"`
+	Title    string                  `json:"title"`
+	Status   int                     `json:"status"`
+	Detail   string                  `json:"detail,omitempty"`
+	Instance string                  `json:"instance,omitempty"`
+	Fields   []validation.FieldError `json:"fields,omitempty"` // Every invalid field of a 422 answer
+}
+
+// ErrorMiddleware answers the requests whose handler recorded an error with ctx.Error and wrote nothing.
+// The status comes from the kind of the last error; errors of no kind are internal and their detail is only logged.
+func ErrorMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Next()
+
+		if len(c.Errors) == 0 || c.Writer.Written() {
+			return
+		}
+
+		err := c.Errors.Last().Err
+		status := errorStatus(err)
+		response := problem{
+			Type:     "about:blank",
+			Title:    http.StatusText(status),
+			Status:   status,
+			Detail:   err.Error(),
+			Instance: c.Request.URL.Path,
+		}
+
+		// Tell throttled clients when to come back
+		var retry interface{ RetryAfter() time.Duration }
+		if errors.As(err, &retry) {
+			c.Header("Retry-After", strconv.Itoa(int(math.Ceil(retry.RetryAfter().Seconds()))))
+		}
+
+		var invalid *validation.Errors
+		if errors.As(err, &invalid) {
+			response.Detail = "one or more fields are invalid"
+			response.Fields = invalid.Fields
+		}
+		if status == http.StatusInternalServerError {
+			log.Printf("Internal error on %s %s: %v", c.Request.Method, c.Request.URL.Path, err)
+			response.Detail = "an unexpected error occurred"
+		}
+
+		body, err := json.Marshal(response)
+		if err != nil {
+			log.Printf("Error encoding problem: %v", err)
+			c.Status(http.StatusInternalServerError)
+			return
+		}
+		c.Data(status, problemContentType, body)
+	}
+}
+
+// errorStatus maps the kind of an error to an HTTP status code
+func errorStatus(err error) int {
+	switch {
+	case errors.Is(err, service.ErrValidation):
+		return http.StatusUnprocessableEntity
+	case errors.Is(err, service.ErrNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, service.ErrConflict):
+		return http.StatusConflict
+	case errors.Is(err, service.ErrUnauthorized):
+		return http.StatusUnauthorized
+	case errors.Is(err, service.ErrForbidden):
+		return http.StatusForbidden
+	case errors.Is(err, service.ErrTooManyRequests):
+		return http.StatusTooManyRequests
+	default:
+		return http.StatusInternalServerError
+	}
+}
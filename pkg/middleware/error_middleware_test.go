@@ -0,0 +1,67 @@
+package middleware
+
+import (
+	"dalabio/internal/service"
+	"dalabio/pkg/validation"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestErrorMiddleware(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	fields := []validation.FieldError{
+		{Field: "title", Code: validation.CodeRequired, Message: "is required"},
+		{Field: "price", Code: validation.CodeNotPositive, Message: "must be greater than zero"},
+	}
+
+	tests := []struct {
+		name       string
+		err        error
+		wantStatus int
+		wantDetail string
+		wantFields []validation.FieldError
+	}{
+		{"invalid fields", &validation.Errors{Fields: fields}, http.StatusUnprocessableEntity, "one or more fields are invalid", fields},
+		{"invalid ID", service.NewError(service.ErrValidation, "invalid payment ID"), http.StatusUnprocessableEntity, "invalid payment ID", nil},
+		{"unauthenticated", service.NewError(service.ErrUnauthorized, "token expired"), http.StatusUnauthorized, "token expired", nil},
+		{"forbidden", service.NewError(service.ErrForbidden, "API keys cannot be used for this action"), http.StatusForbidden, "API keys cannot be used for this action", nil},
+		{"internal error", errors.New("connection refused"), http.StatusInternalServerError, "an unexpected error occurred", nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			router := gin.New()
+			router.Use(ErrorMiddleware())
+			router.GET("/resource", func(c *gin.Context) {
+				c.Error(tt.err)
+				c.Abort()
+			})
+
+			recorder := httptest.NewRecorder()
+			router.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/resource", nil))
+
+			if recorder.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d", recorder.Code, tt.wantStatus)
+			}
+			if contentType := recorder.Header().Get("Content-Type"); contentType != problemContentType {
+				t.Errorf("Content-Type = %q, want %q", contentType, problemContentType)
+			}
+			var response problem
+			if err := json.Unmarshal(recorder.Body.Bytes(), &response); err != nil {
+				t.Fatalf("decoding %s: %v", recorder.Body, err)
+			}
+			if response.Status != tt.wantStatus || response.Detail != tt.wantDetail || response.Instance != "/resource" {
+				t.Errorf("problem = %+v", response)
+			}
+			if !reflect.DeepEqual(response.Fields, tt.wantFields) {
+				t.Errorf("fields = %+v, want %+v", response.Fields, tt.wantFields)
+			}
+		})
+	}
+}
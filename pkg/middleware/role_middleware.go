@@ -0,0 +1,55 @@
+package middleware
+
+import (
+	"dalabio/internal/entity"
+	"dalabio/internal/repository"
+	"dalabio/internal/service"
+	"fmt"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gofrs/uuid"
+)
+
+// RequireRole only lets through users holding one of the given roles.
+// It must run after AuthMiddleware, which sets the user ID in the context.
+func RequireRole(roleRepo repository.RoleRepository, roles ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID, exists := c.Get("userID")
+		if !exists {
+			c.Error(service.NewError(service.ErrUnauthorized, "authorization token required"))
+			c.Abort()
+			return
+		}
+
+		// API keys only reach the admin routes with the admin scope, whatever the roles of their user
+		if scopes, ok := c.Value("scopes").([]string); ok && !hasScope(scopes, entity.APIKeyScopeAdmin) {
+			c.Error(service.NewError(service.ErrForbidden, "this API key is not allowed to perform this action"))
+			c.Abort()
+			return
+		}
+
+		// The roles of signed access tokens were set by AuthMiddleware
+		names, ok := c.Value("roles").([]string)
+		if !ok {
+			var err error
+			names, err = roleRepo.GetRoleNamesByUserID(userID.(uuid.UUID))
+			if err != nil {
+				c.Error(fmt.Errorf("could not verify permissions: %w", err))
+				c.Abort()
+				return
+			}
+		}
+
+		for _, name := range names {
+			for _, role := range roles {
+				if name == role {
+					c.Next()
+					return
+				}
+			}
+		}
+
+		c.Error(service.NewError(service.ErrForbidden, "you do not have permission to perform this action"))
+		c.Abort()
+	}
+}
@@ -0,0 +1,77 @@
+package totp
+
+import (
+	"testing"
+	"time"
+)
+
+// rfcSecret is the SHA1 secret of the test vectors of RFC 6238, "12345678901234567890" in base32
+const rfcSecret = "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+
+func TestCode(t *testing.T) {
+	// The vectors of RFC 6238 appendix B have 8 digits; 6 digit codes are their last 6 digits
+	tests := []struct {
+		unix int64
+		want string
+	}{
+		{59, "287082"},
+		{1111111109, "081804"},
+		{1111111111, "050471"},
+		{1234567890, "005924"},
+		{2000000000, "279037"},
+		{20000000000, "353130"},
+	}
+
+	for _, tt := range tests {
+		got, err := Code(rfcSecret, Step(time.Unix(tt.unix, 0)))
+		if err != nil {
+			t.Fatalf("Code at %d: %v", tt.unix, err)
+		}
+		if got != tt.want {
+			t.Errorf("Code at %d = %s, want %s", tt.unix, got, tt.want)
+		}
+	}
+}
+
+func TestCodeLowerCaseSecret(t *testing.T) {
+	got, err := Code("gezdgnbvgy3tqojqgezdgnbvgy3tqojq", Step(time.Unix(59, 0)))
+	if err != nil || got != "287082" {
+		t.Errorf("Code = %q, %v, want 287082", got, err)
+	}
+}
+
+func TestCodeInvalidSecret(t *testing.T) {
+	if _, err := Code("not base32!", 1); err == nil {
+		t.Error("Code accepted a secret that is not base32")
+	}
+}
+
+func TestVerify(t *testing.T) {
+	now := time.Unix(1111111111, 0)
+	step := Step(now)
+
+	tests := []struct {
+		name     string
+		code     string
+		skew     int
+		wantStep int64
+		wantOK   bool
+	}{
+		{"current period", "050471", 0, step, true},
+		{"spaces are ignored", "050 471", 0, step, true},
+		{"previous period within skew", "081804", 1, step - 1, true},
+		{"previous period without skew", "081804", 0, 0, false},
+		{"wrong code", "123456", 1, 0, false},
+		{"too short", "05047", 1, 0, false},
+		{"too long", "0504710", 1, 0, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gotStep, ok := Verify(rfcSecret, tt.code, now, tt.skew)
+			if ok != tt.wantOK || gotStep != tt.wantStep {
+				t.Errorf("Verify(%q, skew %d) = %d, %v, want %d, %v", tt.code, tt.skew, gotStep, ok, tt.wantStep, tt.wantOK)
+			}
+		})
+	}
+}
@@ -1,6 +1,16 @@
 package utils
 
 import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"strings"
+
 	"golang.org/x/crypto/bcrypt"
 )
 
@@ -18,3 +28,90 @@ func CheckPasswordHash(password, hash string) bool {
 	err := bcrypt.CompareHashAndPassword([]byte(hash), []byte(password))
 	return err == nil
 }
+
+// GenerateToken returns a random hex string built from size random bytes.
+func GenerateToken(size int) (string, error) {
+	b := make([]byte, size)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// SignToken appends to a value an HMAC-SHA256 signature binding it to a purpose, as "value.signature".
+func SignToken(secret []byte, purpose, value string) string {
+	return value + "." + tokenSignature(secret, purpose, value)
+}
+
+// VerifySignedToken checks a token built by SignToken for the same purpose and returns its value.
+func VerifySignedToken(secret []byte, purpose, token string) (string, bool) {
+	dot := strings.LastIndexByte(token, '.')
+	if dot <= 0 {
+		return "", false
+	}
+	value, signature := token[:dot], token[dot+1:]
+	if !hmac.Equal([]byte(signature), []byte(tokenSignature(secret, purpose, value))) {
+		return "", false
+	}
+	return value, true
+}
+
+func tokenSignature(secret []byte, purpose, value string) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(purpose + ":" + value))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// HashToken returns the SHA-256 hex digest of a token, which is what gets stored instead of the token.
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// DeriveKey derives from a secret the 256-bit key used for one purpose, so that one secret can serve several.
+func DeriveKey(secret []byte, purpose string) []byte {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(purpose))
+	return mac.Sum(nil)
+}
+
+// Seal encrypts and authenticates a value with AES-256-GCM, returning the nonce and ciphertext in base64.
+func Seal(key []byte, plaintext string) (string, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return "", err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	return base64.RawStdEncoding.EncodeToString(gcm.Seal(nonce, nonce, []byte(plaintext), nil)), nil
+}
+
+// Open decrypts a value sealed by Seal with the same key.
+func Open(key []byte, sealed string) (string, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return "", err
+	}
+	data, err := base64.RawStdEncoding.DecodeString(sealed)
+	if err != nil {
+		return "", err
+	}
+	if len(data) < gcm.NonceSize() {
+		return "", errors.New("sealed value is too short")
+	}
+	plaintext, err := gcm.Open(nil, data[:gcm.NonceSize()], data[gcm.NonceSize():], nil)
+	if err != nil {
+		return "", err
+	}
+	return string(plaintext), nil
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
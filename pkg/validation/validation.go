@@ -0,0 +1,228 @@
+package validation
+
+import (
+	"errors"
+	"fmt"
+	"net/mail"
+	"net/url"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Machine-readable codes of the field errors
+const (
+	CodeRequired        = "required"
+	CodeInvalidEmail    = "invalid_email"
+	CodeTooShort        = "too_short"
+	CodeTooLong         = "too_long"
+	CodeWeakPassword    = "weak_password"
+	CodeNotPositive     = "must_be_positive"
+	CodeNegative        = "must_not_be_negative"
+	CodeTooLarge        = "too_large"
+	CodeInvalidCurrency = "invalid_currency"
+	CodeInvalidURL      = "invalid_url"
+	CodeInvalidRange    = "invalid_range"
+	CodeInvalidChoice   = "invalid_choice"
+	CodeInvalid         = "invalid"
+)
+
+// Password rules; bcrypt ignores everything after 72 bytes
+const (
+	MinPasswordLength = 8
+	MaxPasswordLength = 72
+)
+
+// ErrInvalid is the kind of every *Errors, so that errors.Is(err, ErrInvalid) tells a rejected request
+var ErrInvalid = errors.New("validation failed")
+
+// FieldError describes why the value of one field was rejected
+type FieldError struct {
+	Field   string `json:"field"`
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// Errors lists every invalid field of a request
+type Errors struct {
+	Fields []FieldError `json:"fields"`
+}
+
+func (e *Errors) Error() string {
+	messages := make([]string, 0, len(e.Fields))
+	for _, field := range e.Fields {
+		messages = append(messages, field.Field+": "+field.Message)
+	}
+	return "validation failed: " + strings.Join(messages, "; ")
+}
+
+func (e *Errors) Unwrap() error {
+	return ErrInvalid
+}
+
+// Validator collects the errors of the checks run on a request, so that all of them are reported at once
+type Validator struct {
+	fields []FieldError
+}
+
+// New creates a Validator with no errors
+func New() *Validator {
+	return &Validator{}
+}
+
+// Add records an error on a field
+func (v *Validator) Add(field, code, message string) {
+	v.fields = append(v.fields, FieldError{Field: field, Code: code, Message: message})
+}
+
+// Check records an error on a field unless ok
+func (v *Validator) Check(ok bool, field, code, message string) bool {
+	if !ok {
+		v.Add(field, code, message)
+	}
+	return ok
+}
+
+// Err returns the collected errors as *Errors, or nil when every check passed
+func (v *Validator) Err() error {
+	if len(v.fields) == 0 {
+		return nil
+	}
+	return &Errors{Fields: v.fields}
+}
+
+// Required checks that a string is not blank
+func (v *Validator) Required(field, value string) bool {
+	return v.Check(strings.TrimSpace(value) != "", field, CodeRequired, "is required")
+}
+
+// Length checks that a string has between min and max characters; max 0 means no maximum
+func (v *Validator) Length(field, value string, min, max int) bool {
+	length := utf8.RuneCountInString(strings.TrimSpace(value))
+	if length < min {
+		if min == 1 {
+			v.Add(field, CodeRequired, "is required")
+		} else {
+			v.Add(field, CodeTooShort, fmt.Sprintf("must be at least %d characters long", min))
+		}
+		return false
+	}
+	return v.Check(max == 0 || length <= max, field, CodeTooLong, fmt.Sprintf("must be at most %d characters long", max))
+}
+
+// MaxLength checks that an optional string has at most max characters
+func (v *Validator) MaxLength(field, value string, max int) bool {
+	return v.Check(utf8.RuneCountInString(value) <= max, field, CodeTooLong, fmt.Sprintf("must be at most %d characters long", max))
+}
+
+// Email checks that a string is a bare email address such as "ada@example.com"
+func (v *Validator) Email(field, value string) bool {
+	if !v.Required(field, value) {
+		return false
+	}
+	address, err := mail.ParseAddress(value)
+	ok := err == nil && address.Address == value && strings.Contains(value[strings.LastIndexByte(value, '@'):], ".")
+	return v.Check(ok, field, CodeInvalidEmail, "must be a valid email address")
+}
+
+// Password checks the strength of a new password: its length and a mix of letters and digits
+func (v *Validator) Password(field, value string) bool {
+	if len(value) < MinPasswordLength {
+		v.Add(field, CodeTooShort, fmt.Sprintf("must be at least %d characters long", MinPasswordLength))
+		return false
+	}
+	if len(value) > MaxPasswordLength {
+		v.Add(field, CodeTooLong, fmt.Sprintf("must be at most %d bytes long", MaxPasswordLength))
+		return false
+	}
+
+	var letter, digit bool
+	for _, r := range value {
+		switch {
+		case unicode.IsLetter(r):
+			letter = true
+		case unicode.IsDigit(r):
+			digit = true
+		}
+	}
+	return v.Check(letter && digit, field, CodeWeakPassword, "must contain both letters and digits")
+}
+
+// Positive checks that an amount is greater than zero
+func (v *Validator) Positive(field string, value float64) bool {
+	return v.Check(value > 0, field, CodeNotPositive, "must be greater than zero")
+}
+
+// NotNegative checks that a number is zero or more
+func (v *Validator) NotNegative(field string, value float64) bool {
+	return v.Check(value >= 0, field, CodeNegative, "must not be negative")
+}
+
+// Currency checks that a string is an ISO 4217 currency code; codes are compared upper-case
+func (v *Validator) Currency(field, value string) bool {
+	if !v.Required(field, value) {
+		return false
+	}
+	return v.Check(IsCurrency(value), field, CodeInvalidCurrency, "must be an ISO 4217 currency code such as USD or EUR")
+}
+
+// URL checks that a string is an absolute http or https URL
+func (v *Validator) URL(field, value string) bool {
+	u, err := url.Parse(value)
+	ok := err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != ""
+	return v.Check(ok, field, CodeInvalidURL, "must be an absolute http or https URL")
+}
+
+// URLs checks every URL of a list, naming the fields like "join_url[1]"
+func (v *Validator) URLs(field string, values []string) bool {
+	ok := true
+	for i, value := range values {
+		ok = v.URL(fmt.Sprintf("%s[%d]", field, i), value) && ok
+	}
+	return ok
+}
+
+// TimeRange checks that both times are set and the end comes after the start
+func (v *Validator) TimeRange(startField, endField string, start, end time.Time) bool {
+	ok := v.Check(!start.IsZero(), startField, CodeRequired, "is required")
+	ok = v.Check(!end.IsZero(), endField, CodeRequired, "is required") && ok
+	if !ok {
+		return false
+	}
+	return v.Check(end.After(start), endField, CodeInvalidRange, "must be after "+startField)
+}
+
+// OneOf checks that a string is one of the allowed values
+func (v *Validator) OneOf(field, value string, allowed ...string) bool {
+	for _, candidate := range allowed {
+		if value == candidate {
+			return true
+		}
+	}
+	v.Add(field, CodeInvalidChoice, "must be one of "+strings.Join(allowed, ", "))
+	return false
+}
+
+// IsCurrency reports whether a code is an active ISO 4217 currency code
+func IsCurrency(code string) bool {
+	_, ok := currencies[strings.ToUpper(code)]
+	return ok
+}
+
+// currencies are the active ISO 4217 codes
+var currencies = func() map[string]struct{} {
+	codes := strings.Fields(`
+		AED AFN ALL AMD ANG AOA ARS AUD AWG AZN BAM BBD BDT BGN BHD BIF BMD BND BOB BRL BSD BTN BWP BYN BZD
+		CAD CDF CHF CLP CNY COP CRC CUP CVE CZK DJF DKK DOP DZD EGP ERN ETB EUR FJD FKP GBP GEL GHS GIP GMD
+		GNF GTQ GYD HKD HNL HTG HUF IDR ILS INR IQD IRR ISK JMD JOD JPY KES KGS KHR KMF KPW KRW KWD KYD KZT
+		LAK LBP LKR LRD LSL LYD MAD MDL MGA MKD MMK MNT MOP MRU MUR MVR MWK MXN MYR MZN NAD NGN NIO NOK NPR
+		NZD OMR PAB PEN PGK PHP PKR PLN PYG QAR RON RSD RUB RWF SAR SBD SCR SDG SEK SGD SHP SLE SOS SRD SSP
+		STN SVC SYP SZL THB TJS TMT TND TOP TRY TTD TWD TZS UAH UGX USD UYU UZS VES VND VUV WST XAF XCD XOF
+		XPF YER ZAR ZMW ZWL`)
+	set := make(map[string]struct{}, len(codes))
+	for _, code := range codes {
+		set[code] = struct{}{}
+	}
+	return set
+}()
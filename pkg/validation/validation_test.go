@@ -0,0 +1,154 @@
+package validation
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestValidator(t *testing.T) {
+	start := time.Date(2026, 1, 1, 10, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name  string
+		check func(v *Validator)
+		want  []FieldError // nil when the request is valid
+	}{
+		{
+			name: "valid request",
+			check: func(v *Validator) {
+				v.Required("title", "Yoga")
+				v.Email("email", "ada@example.com")
+				v.Password("password", "s3cretpass")
+				v.Currency("currency", "eur")
+				v.Positive("price", 10)
+				v.URL("url", "https://example.com/meet")
+				v.TimeRange("start_time", "end_time", start, start.Add(time.Hour))
+				v.OneOf("status", "active", "active", "archived")
+			},
+		},
+		{
+			name: "every invalid field is listed in order",
+			check: func(v *Validator) {
+				v.Required("title", "  ")
+				v.Email("email", "ada@example")
+				v.Positive("price", 0)
+			},
+			want: []FieldError{
+				{Field: "title", Code: CodeRequired, Message: "is required"},
+				{Field: "email", Code: CodeInvalidEmail, Message: "must be a valid email address"},
+				{Field: "price", Code: CodeNotPositive, Message: "must be greater than zero"},
+			},
+		},
+		{
+			name:  "missing email is required, not invalid",
+			check: func(v *Validator) { v.Email("email", "") },
+			want:  []FieldError{{Field: "email", Code: CodeRequired, Message: "is required"}},
+		},
+		{
+			name:  "email with a display name",
+			check: func(v *Validator) { v.Email("email", "Ada <ada@example.com>") },
+			want:  []FieldError{{Field: "email", Code: CodeInvalidEmail, Message: "must be a valid email address"}},
+		},
+		{
+			name:  "short password",
+			check: func(v *Validator) { v.Password("password", "abc123") },
+			want:  []FieldError{{Field: "password", Code: CodeTooShort, Message: "must be at least 8 characters long"}},
+		},
+		{
+			name:  "password beyond what bcrypt reads",
+			check: func(v *Validator) { v.Password("password", strings.Repeat("a1", 37)) },
+			want:  []FieldError{{Field: "password", Code: CodeTooLong, Message: "must be at most 72 bytes long"}},
+		},
+		{
+			name:  "password without digits",
+			check: func(v *Validator) { v.Password("password", "onlyletters") },
+			want:  []FieldError{{Field: "password", Code: CodeWeakPassword, Message: "must contain both letters and digits"}},
+		},
+		{
+			name:  "length counts characters, not bytes",
+			check: func(v *Validator) { v.Length("name", "éééé", 2, 4) },
+		},
+		{
+			name:  "too long",
+			check: func(v *Validator) { v.Length("name", "abcde", 2, 4) },
+			want:  []FieldError{{Field: "name", Code: CodeTooLong, Message: "must be at most 4 characters long"}},
+		},
+		{
+			name:  "too short",
+			check: func(v *Validator) { v.Length("name", "a", 2, 4) },
+			want:  []FieldError{{Field: "name", Code: CodeTooShort, Message: "must be at least 2 characters long"}},
+		},
+		{
+			name:  "unknown currency",
+			check: func(v *Validator) { v.Currency("currency", "XYZ") },
+			want:  []FieldError{{Field: "currency", Code: CodeInvalidCurrency, Message: "must be an ISO 4217 currency code such as USD or EUR"}},
+		},
+		{
+			name: "URLs are named by index",
+			check: func(v *Validator) {
+				v.URLs("join_url", []string{"https://example.com", "ftp://example.com", "/relative"})
+			},
+			want: []FieldError{
+				{Field: "join_url[1]", Code: CodeInvalidURL, Message: "must be an absolute http or https URL"},
+				{Field: "join_url[2]", Code: CodeInvalidURL, Message: "must be an absolute http or https URL"},
+			},
+		},
+		{
+			name:  "missing times",
+			check: func(v *Validator) { v.TimeRange("start_time", "end_time", time.Time{}, time.Time{}) },
+			want: []FieldError{
+				{Field: "start_time", Code: CodeRequired, Message: "is required"},
+				{Field: "end_time", Code: CodeRequired, Message: "is required"},
+			},
+		},
+		{
+			name:  "end before start",
+			check: func(v *Validator) { v.TimeRange("start_time", "end_time", start, start.Add(-time.Hour)) },
+			want:  []FieldError{{Field: "end_time", Code: CodeInvalidRange, Message: "must be after start_time"}},
+		},
+		{
+			name:  "not one of the choices",
+			check: func(v *Validator) { v.OneOf("status", "deleted", "active", "archived") },
+			want:  []FieldError{{Field: "status", Code: CodeInvalidChoice, Message: "must be one of active, archived"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v := New()
+			tt.check(v)
+			err := v.Err()
+
+			if tt.want == nil {
+				if err != nil {
+					t.Fatalf("Err = %v, want nil", err)
+				}
+				return
+			}
+
+			var invalid *Errors
+			if !errors.As(err, &invalid) {
+				t.Fatalf("Err = %v, want *Errors", err)
+			}
+			if !errors.Is(err, ErrInvalid) {
+				t.Error("Err is not of kind ErrInvalid")
+			}
+			if !reflect.DeepEqual(invalid.Fields, tt.want) {
+				t.Errorf("Fields = %+v, want %+v", invalid.Fields, tt.want)
+			}
+		})
+	}
+}
+
+func TestErrorsMessage(t *testing.T) {
+	err := &Errors{Fields: []FieldError{
+		{Field: "title", Code: CodeRequired, Message: "is required"},
+		{Field: "price", Code: CodeNotPositive, Message: "must be greater than zero"},
+	}}
+	if want := "validation failed: title: is required; price: must be greater than zero"; err.Error() != want {
+		t.Errorf("Error = %q, want %q", err.Error(), want)
+	}
+}